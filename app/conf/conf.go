@@ -67,6 +67,13 @@ type Config struct {
 	OSS         *OSSConfig    `json:"oss"`
 	Upload      *UploadConfig `json:"upload"`
 	GilAdminAPI *GilAdminAPI  `json:"gil_admin_api"`
+	Classroom   *Classroom    `json:"classroom"`
+}
+
+// Classroom 课堂配置
+type Classroom struct {
+	// CheckBehavior 行为接口是否校验课堂已由当前教师开课，客户端接入开课接口后开启
+	CheckBehavior bool `json:"check_behavior"`
 }
 
 type OSSConfig struct {
@@ -176,6 +183,8 @@ type ConsumerConfig struct {
 	BatchSize      int           `json:"batchSize"`
 	BatchTime      time.Duration `json:"batchTime"`
 	SessionTime    time.Duration `json:"sessionTime"`
+	MaxRetries     int           `json:"maxRetries"`   // 单条消息最大重试次数，超过后写入死信 topic
+	RetryBackoff   time.Duration `json:"retryBackoff"` // 单条消息重试的初始退避时间，毫秒
 }
 
 type Kafka struct {
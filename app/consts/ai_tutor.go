@@ -0,0 +1,51 @@
+package consts
+
+import "time"
+
+// CommunicationMessageType 会话消息类型
+const (
+	CommunicationMessageTypeText        = "text"        // 普通文本
+	CommunicationMessageTypeAIAnswer    = "ai_answer"   // AI 回答学生提问
+	CommunicationMessageTypeAIEscalated = "ai_escalate" // AI 转交教师，内容为给学生的提示
+	CommunicationMessageTypeAIOverride  = "ai_override" // 教师修正 AI 回答，answer_to 为被修正的 AI 消息
+)
+
+// AI 答疑参数
+const (
+	AITutorUserID        = 0                // AI 消息的发送人ID
+	AITutorMinConfidence = 0.6              // 置信度低于该值时转交教师
+	AITutorHistoryLimit  = 20               // 作为上下文的最近消息数
+	AITutorTimeout       = 30 * time.Second // 单次回答的超时时间
+
+	AITutorEscalatedReply = "这个问题已经转给老师，老师会尽快回复你。"
+)
+
+// AITutorEscalationKeywords 学生消息包含这些词时直接转交教师
+var AITutorEscalationKeywords = []string{"找老师", "问老师", "请老师", "转老师", "老师来", "人工"}
+
+// AITutorPrompt AI 答疑系统提示
+type AITutorPrompt struct {
+	Version      string // 版本
+	SystemPrompt string // 系统提示，%s 依次为任务名称、题目内容、参考答案和解析
+}
+
+var (
+	AITutorPromptV1 = AITutorPrompt{
+		Version: "v1",
+		SystemPrompt: `你是一名耐心的学科辅导老师，正在回答学生针对作业题目的提问。
+
+作业：%s
+题目：%s
+参考答案：%s
+解析：%s
+
+回答要求：
+- 引导学生思考，讲清解题思路，不要直接给出最终答案
+- 语言简洁，适合中小学生阅读
+- 与题目和学习无关的问题不要回答
+
+请严格按以下 JSON 格式返回，不要输出其他内容：
+{"answer":"给学生的回答","confidence":0到1之间的小数,"needTeacher":true或false}
+confidence 表示你对回答正确、恰当的把握；题目信息不足、无法确定或学生情绪异常时 needTeacher 为 true。`,
+	}
+)
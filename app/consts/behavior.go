@@ -1,5 +1,11 @@
 package consts
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 // MessageType 消息类型
 type MessageType string
 
@@ -7,6 +13,7 @@ const (
 	MessageTypeTeacherBehavior MessageType = "teacher_behavior"
 	MessageTypeStudentBehavior MessageType = "student_behavior"
 	MessageTypeCommunication   MessageType = "communication"
+	MessageTypeModeration      MessageType = "moderation" // 待审核内容
 )
 
 // CommunicationUserType 会话用户类型
@@ -29,6 +36,49 @@ const (
 	CommunicationSessionTypeOther    CommunicationSessionType = "other"    // 其他
 )
 
+// 学生针对作业题目发起提问时，会话的 target_id 格式为 task#任务ID#布置ID#题目ID
+const (
+	TaskQuestionTargetPrefix    = "task"
+	TaskQuestionSessionPageSize = 5000 // 分页查询布置提问会话时每页的数量
+)
+
+// TaskQuestionTargetID 作业题目提问会话的 target_id
+func TaskQuestionTargetID(taskID, assignID int64, questionID string) string {
+	return TaskAssignTargetIDPrefix(taskID, assignID) + questionID
+}
+
+// TaskAssignTargetIDPrefix 任务布置下全部题目提问会话 target_id 的公共前缀
+func TaskAssignTargetIDPrefix(taskID, assignID int64) string {
+	return fmt.Sprintf("%s%s%d%s%d%s", TaskQuestionTargetPrefix, CombineKey, taskID, CombineKey, assignID, CombineKey)
+}
+
+// ParseTaskQuestionTargetID 从作业题目提问会话的 target_id 中解析题目ID，格式不正确时返回 false
+func ParseTaskQuestionTargetID(targetID string) (string, bool) {
+	parts := strings.Split(targetID, CombineKey)
+	if len(parts) != 4 || parts[0] != TaskQuestionTargetPrefix || parts[3] == "" {
+		return "", false
+	}
+	return parts[3], true
+}
+
+// ParseTaskQuestionTarget 从作业题目提问会话的 target_id 中解析任务ID、布置ID和题目ID，格式不正确时返回 false
+func ParseTaskQuestionTarget(targetID string) (int64, int64, string, bool) {
+	questionID, ok := ParseTaskQuestionTargetID(targetID)
+	if !ok {
+		return 0, 0, "", false
+	}
+	parts := strings.Split(targetID, CombineKey)
+	taskID, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	assignID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return 0, 0, "", false
+	}
+	return taskID, assignID, questionID, true
+}
+
 // 行为类型
 type BehaviorType string
 
@@ -52,6 +102,14 @@ const (
 	BehaviorTypeTaskAttention BehaviorType = "task_attention" // 教师对学生作业进行提醒
 )
 
+// 行为上下文结构版本，写在上下文的 schemaVersion 字段，未填写时为 1
+const (
+	// BehaviorContextSchemaV1 旧版上下文，兼容历史上混用的下划线和驼峰字段名，允许未定义的字段
+	BehaviorContextSchemaV1 int64 = 1
+	// BehaviorContextSchemaV2 只允许入库字段名，拒绝未定义的字段，并校验行为类型的必填字段
+	BehaviorContextSchemaV2 int64 = 2
+)
+
 // 学习类型常量
 const (
 	LearningTypeCourse    = "课程学习" // 课程学习
@@ -103,6 +161,8 @@ const (
 	AttentionRuleNotUsingPadTime            = 5   // 未使用pad时间阈值(分钟)
 	AttentionRuleLearningScoreDecreasePerct = 20  // 学习分下降百分比阈值
 	AttentionRuleVideoPauseTimeSeconds      = 300 // 视频暂停时间阈值(秒)，5分钟
+	AttentionRuleLowAccuracyRate            = 50  // 答题正确率低于此值(%)需要关注
+	AttentionRuleLowAccuracyMinQuestions    = 3   // 判断正确率低的最少答题数
 	AttentionWindowSeconds                  = 60  // 关注时间窗口(秒)
 	AttentionRuleBaseScoreForLongPause      = 5   // 长时间暂停的基础加分
 	AttentionRuleScorePerMinutePaused       = 1   // 每暂停一分钟的加分
@@ -230,3 +290,13 @@ var (
 		"定向提升：针对薄弱环节，错题重做和加练",
 	}
 )
+
+// 学生跨课堂行为画像相关常量
+const (
+	// StudentProfileDefaultDays 未指定开始日期时默认查询的天数，包含结束日期当天
+	StudentProfileDefaultDays = 7
+	// StudentProfileMaxDays 学生行为画像查询的最大时间跨度(天)，覆盖一个学期
+	StudentProfileMaxDays = 184
+	// StudentProfileTaskActionType 作业点赞和提醒没有细分类型，统一归到该类型
+	StudentProfileTaskActionType = "task"
+)
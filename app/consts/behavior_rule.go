@@ -0,0 +1,47 @@
+package consts
+
+// BehaviorRuleOp 行为规则条件运算符
+type BehaviorRuleOp string
+
+const (
+	BehaviorRuleOpEq  BehaviorRuleOp = "eq"  // 等于
+	BehaviorRuleOpNe  BehaviorRuleOp = "ne"  // 不等于
+	BehaviorRuleOpGt  BehaviorRuleOp = "gt"  // 大于
+	BehaviorRuleOpGte BehaviorRuleOp = "gte" // 大于等于
+	BehaviorRuleOpLt  BehaviorRuleOp = "lt"  // 小于
+	BehaviorRuleOpLte BehaviorRuleOp = "lte" // 小于等于
+	BehaviorRuleOpIn  BehaviorRuleOp = "in"  // 属于列表中任一值
+)
+
+// 行为规则可使用的字段，取自学生最新行为及其上下文
+const (
+	BehaviorRuleFieldBehaviorType      = "behavior_type"       // 行为类型
+	BehaviorRuleFieldSubject           = "subject"             // 学科
+	BehaviorRuleFieldLearningType      = "learning_type"       // 学习类型
+	BehaviorRuleFieldVideoStatus       = "video_status"        // 视频状态
+	BehaviorRuleFieldStayDuration      = "stay_duration"       // 停留时长(秒)
+	BehaviorRuleFieldCorrectStreak     = "correct_streak"      // 连续答对次数
+	BehaviorRuleFieldEarlyLearnCount   = "early_learn_count"   // 提前学习次数
+	BehaviorRuleFieldQuestionCount     = "question_count"      // 提问次数
+	BehaviorRuleFieldCorrectAnswers    = "correct_answers"     // 正确答题数
+	BehaviorRuleFieldTotalQuestions    = "total_questions"     // 总题数
+	BehaviorRuleFieldAccuracyRate      = "accuracy_rate"       // 正确率(%)
+	BehaviorRuleFieldPageSwitchCount   = "page_switch_count"   // 切换页面次数
+	BehaviorRuleFieldOtherContentCount = "other_content_count" // 学习其他内容次数
+	BehaviorRuleFieldPauseCount        = "pause_count"         // 停顿操作次数
+)
+
+// 行为规则集配置，内容为 JSON 格式的 dto.BehaviorRuleDocument
+const (
+	BehaviorRuleNacosDataID = "gil-teacher-behavior-rules"
+	BehaviorRuleNacosGroup  = "DEFAULT_GROUP"
+)
+
+// 内置默认学习分模型：学习时长按分钟计分，最多计 30 分钟；正确率每 1% 计 0.7 分，满分 70 分
+const (
+	LearningScoreDefaultMaxScore       = 100 // 学习分上限
+	LearningScoreDefaultStayUnit       = 60  // 学习时长计分单位(秒)
+	LearningScoreDefaultStayCap        = 30  // 学习时长最多计分的单位数
+	LearningScoreDefaultStayWeight     = 1   // 学习时长每单位得分
+	LearningScoreDefaultAccuracyWeight = 0.7 // 正确率每 1% 得分
+)
@@ -15,15 +15,15 @@ const (
 	BehaviorTagTypePause        BehaviorTagType = "pause"        // 停顿操作
 )
 
-// 行为标签提示文本模板
+// 行为标签提示文本模板，{字段名} 替换为行为规则字段值
 const (
 	// 表现优秀的行为提示文本模板（更简洁友好）
-	BehaviorTagTextEarlyLearn    = "提前学习 %d次"
-	BehaviorTagTextQuestion      = "主动提问 %d次"
-	BehaviorTagTextCorrectStreak = "连续答对 %d题"
+	BehaviorTagTextEarlyLearn    = "提前学习 {early_learn_count}次"
+	BehaviorTagTextQuestion      = "主动提问 {question_count}次"
+	BehaviorTagTextCorrectStreak = "连续答对 {correct_streak}题"
 
 	// 需要关注的行为提示文本模板（更明确具体）
-	BehaviorTagTextPageSwitch   = "切换页面 %d次"
-	BehaviorTagTextOtherContent = "浏览其他内容 %d次"
-	BehaviorTagTextPause        = "长时间暂停 %d次"
+	BehaviorTagTextPageSwitch   = "切换页面 {page_switch_count}次"
+	BehaviorTagTextOtherContent = "浏览其他内容 {other_content_count}次"
+	BehaviorTagTextPause        = "长时间暂停 {pause_count}次"
 )
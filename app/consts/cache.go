@@ -25,8 +25,27 @@ const (
 	UserLastReadMessageKey = "user_last_read_message:%s:%d"
 	// 用户最后已读消息过期时间
 	UserLastReadMessageExpire = 7 * 24 * 3600 // 7天
+
+	// 已入库的行为消息，behavior_event:{eventId} => 1，消费重放时据此去重
+	BehaviorEventKey = "behavior_event:%s"
+	// 行为消息去重过期时间，需覆盖 kafka 消息保留时长
+	BehaviorEventExpire = 7 * 24 * 3600 // 7天
+
+	// 同一消息 key 已入库的最新 offset，behavior_applied_offset:{topic}:{partition}:{key} => offset，
+	// 重放死信时跳过比它更旧的消息，过期时间同 BehaviorEventExpire
+	BehaviorAppliedOffsetKey = "behavior_applied_offset:%s:%d:%s"
 )
 
+// 行为消息去重缓存键
+func GetBehaviorEventKey(eventID string) string {
+	return fmt.Sprintf(BehaviorEventKey, eventID)
+}
+
+// 消息 key 已入库的最新 offset 缓存键
+func GetBehaviorAppliedOffsetKey(topic string, partition int32, key string) string {
+	return fmt.Sprintf(BehaviorAppliedOffsetKey, topic, partition, key)
+}
+
 // 用户最后已读消息缓存键
 func GetSessionUserLastReadMessageKey(sessionID string, userID int64) string {
 	return fmt.Sprintf(UserLastReadMessageKey, sessionID, userID)
@@ -61,6 +80,8 @@ const (
 
 	// TeacherScheduleSearchPattern 教师课程表搜索模式，用于在Redis中搜索特定教师的所有课程表
 	TeacherScheduleSearchPattern = "teacher_course:*:%d"
+	// TeacherScheduleAllPattern 所有教师课程表的搜索模式
+	TeacherScheduleAllPattern = "teacher_course:*"
 )
 
 // 教师课程表缓存键
@@ -189,3 +210,29 @@ func GetClassBehaviorPraisedKey(classroomID int64) string {
 func GetEvaluateRecordKey(classroomID, studentID int64, evaluateType string) string {
 	return fmt.Sprintf(EvaluateRecordKey, classroomID, studentID, evaluateType)
 }
+
+// 课堂报告相关缓存键
+const (
+	// ClassroomReportKey 课堂报告生成标记：classroomId:startTime -> reportId，防止多实例重复生成
+	ClassroomReportKey = "classroom_report:%d:%d"
+	// ClassroomReportExpire 课堂报告生成标记过期时间，需要覆盖报告扫描的回溯时间
+	ClassroomReportExpire = 3 * 24 * 3600 // 3天
+)
+
+// GetClassroomReportKey 获取课堂报告生成标记缓存键
+func GetClassroomReportKey(classroomID int64, startTime time.Time) string {
+	return fmt.Sprintf(ClassroomReportKey, classroomID, startTime.Unix())
+}
+
+// 课堂归属相关缓存键
+const (
+	// ClassroomOwnerKey 课堂归属校验缓存：classroomId:teacherId -> 1，只缓存校验通过的结果
+	ClassroomOwnerKey = "classroom_owner:%d:%d"
+	// ClassroomOwnerExpire 课堂归属缓存过期时间
+	ClassroomOwnerExpire = 12 * 3600 // 12小时
+)
+
+// GetClassroomOwnerKey 获取课堂归属校验缓存键
+func GetClassroomOwnerKey(classroomID, teacherID int64) string {
+	return fmt.Sprintf(ClassroomOwnerKey, classroomID, teacherID)
+}
@@ -1,5 +1,7 @@
 package consts
 
+import "time"
+
 // 课堂ID相关常量
 const (
 	// TempClassroomIDBase 临时课堂ID基础值
@@ -48,3 +50,54 @@ func GenerateTempClassroomID(tmpScheduleID int64) int64 {
 	// 实际应用中可能需要添加检查
 	return TempClassroomIDBase + tmpScheduleID
 }
+
+// 课后课堂报告相关常量
+const (
+	// ClassroomReportScanInterval 扫描课程表检查已下课课堂的间隔
+	ClassroomReportScanInterval = time.Minute
+	// ClassroomReportDelay 下课后延迟生成报告的时间，等待课堂行为数据消费入库
+	ClassroomReportDelay = 5 * time.Minute
+	// ClassroomReportLookback 只为最近下课的课堂生成报告，服务停机期间错过的课堂在恢复后补生成
+	ClassroomReportLookback = 24 * time.Hour
+	// ClassroomReportTimeout 单个课堂报告生成超时时间
+	ClassroomReportTimeout = 30 * time.Second
+	// ClassroomReportScanCount 使用 SCAN 遍历课程表缓存时每批返回的 key 数量
+	ClassroomReportScanCount = 200
+)
+
+// 课堂状态，对应 tbl_classroom.status
+const (
+	ClassroomStatusNotStarted int64 = 0 // 未开始
+	ClassroomStatusInProgress int64 = 1 // 进行中
+	ClassroomStatusPaused     int64 = 2 // 已暂停
+	ClassroomStatusEnded      int64 = 3 // 已结束
+)
+
+// ClassroomLifecycleEvent 课堂生命周期事件类型
+type ClassroomLifecycleEvent string
+
+const (
+	ClassroomLifecycleStart  ClassroomLifecycleEvent = "start"  // 开始上课
+	ClassroomLifecyclePause  ClassroomLifecycleEvent = "pause"  // 暂停上课
+	ClassroomLifecycleResume ClassroomLifecycleEvent = "resume" // 暂停后继续上课
+	ClassroomLifecycleEnd    ClassroomLifecycleEvent = "end"    // 结束上课
+)
+
+// ClassroomStartEarly 允许提前开始上课的时间，开课时按此时间窗口匹配当天课表
+const ClassroomStartEarly = 30 * time.Minute
+
+// 课堂反馈相关常量，对应 tbl_classroom_feedback
+const (
+	ClassroomFeedbackCreateTypeStudent int64 = 1 // 学生提交的反馈
+	ClassroomFeedbackCreateTypeTeacher int64 = 2 // 教师提交的反馈
+
+	ClassroomFeedbackStatusPending int64 = 0 // 待处理
+	ClassroomFeedbackStatusHandled int64 = 1 // 已处理
+
+	// ClassroomFeedbackContentMaxLen 反馈内容最大字数
+	ClassroomFeedbackContentMaxLen = 500
+	// ClassroomFeedbackMaxDays 反馈查询和统计的最大时间跨度(天)
+	ClassroomFeedbackMaxDays = 92
+	// ClassroomFeedbackHandleMaxSize 单次标记处理的最大反馈数
+	ClassroomFeedbackHandleMaxSize = 100
+)
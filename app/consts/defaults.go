@@ -12,12 +12,6 @@ const (
 	// TeacherDefaultAPITimeout API请求超时时间（5秒）
 	TeacherDefaultAPITimeout = 5 * time.Second
 
-	// TeacherDefaultRetryInterval API 请求重试间隔（3 秒）
-	TeacherDefaultRetryInterval = 3 * time.Second
-
-	// DefaultMaxRetries 默认最大重试次数
-	DefaultMaxRetries = 3
-
 	// DefaultBufferSize 默认缓冲区大小
 	DefaultBufferSize = 4096
 )
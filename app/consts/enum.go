@@ -316,14 +316,14 @@ const (
 	// 作业任务（20-29）
 	TASK_TYPE_HOMEWORK int64 = 20 // 作业
 	// TASK_TYPE_HOMEWORK_STANDARD int64 = 21 // 标准作业
-	// TASK_TYPE_HOMEWORK_LAYERED  int64 = 22 // 分层作业
-	// TASK_TYPE_HOMEWORK_ANSWER   int64 = 23 // 答题卡作业
-	// TASK_TYPE_HOMEWORK_WRONG    int64 = 24 // 错题作业
+	TASK_TYPE_HOMEWORK_LAYERED int64 = 22 // 分层作业，作为作业任务的子类型
+	TASK_TYPE_HOMEWORK_ANSWER  int64 = 23 // 答题卡作业，作为作业任务的子类型，只保存答案，线下作答后导入
+	TASK_TYPE_HOMEWORK_WRONG   int64 = 24 // 错题作业，作为作业任务的子类型，按学生历史错题生成
 
 	// 测验任务（30-39）
 	TASK_TYPE_TEST int64 = 30 // 测验
 	// TASK_TYPE_TEST_STANDARD int64 = 31 // 标准测验
-	// TASK_TYPE_TEST_ANSWER   int64 = 32 // 答题卡测验
+	TASK_TYPE_TEST_ANSWER int64 = 32 // 答题卡测验，作为测验任务的子类型，只保存答案，线下作答后导入
 
 	// 资源任务（40-49）
 	TASK_TYPE_RESOURCE int64 = 40 // 资源
@@ -347,13 +347,13 @@ const (
 	TASK_TYPE_COURSE_NAME   = "课程"
 	TASK_TYPE_HOMEWORK_NAME = "作业"
 	// TASK_TYPE_HOMEWORK_STANDARD_NAME = "标准作业"
-	// TASK_TYPE_HOMEWORK_LAYERED_NAME  = "分层作业"
-	// TASK_TYPE_HOMEWORK_ANSWER_NAME   = "答题卡作业"
-	// TASK_TYPE_HOMEWORK_WRONG_NAME    = "错题作业"
-	TASK_TYPE_TEST_NAME = "测验"
+	TASK_TYPE_HOMEWORK_LAYERED_NAME = "分层作业"
+	TASK_TYPE_HOMEWORK_ANSWER_NAME  = "答题卡作业"
+	TASK_TYPE_HOMEWORK_WRONG_NAME   = "错题作业"
+	TASK_TYPE_TEST_NAME             = "测验"
 	// TASK_TYPE_TEST_STANDARD_NAME     = "标准测验"
-	// TASK_TYPE_TEST_ANSWER_NAME       = "答题卡测验"
-	TASK_TYPE_RESOURCE_NAME = "资源"
+	TASK_TYPE_TEST_ANSWER_NAME = "答题卡测验"
+	TASK_TYPE_RESOURCE_NAME    = "资源"
 	// TASK_TYPE_RESOURCE_PUBLIC_NAME = "公共资源"
 	// TASK_TYPE_RESOURCE_SCHOOL_NAME   = "校本资源"
 	// TASK_TYPE_RESOURCE_PERSONAL_NAME = "我的资源"
@@ -367,6 +367,36 @@ var TaskTypeNameMap = map[int64]string{
 	TASK_TYPE_RESOURCE: TASK_TYPE_RESOURCE_NAME,
 }
 
+// TaskSubTypeNameMap 任务子类型名称映射
+var TaskSubTypeNameMap = map[int64]string{
+	TASK_TYPE_HOMEWORK_LAYERED: TASK_TYPE_HOMEWORK_LAYERED_NAME,
+	TASK_TYPE_HOMEWORK_WRONG:   TASK_TYPE_HOMEWORK_WRONG_NAME,
+	TASK_TYPE_HOMEWORK_ANSWER:  TASK_TYPE_HOMEWORK_ANSWER_NAME,
+	TASK_TYPE_TEST_ANSWER:      TASK_TYPE_TEST_ANSWER_NAME,
+}
+
+// TaskSubTypeParentMap 任务子类型所属的任务类型
+var TaskSubTypeParentMap = map[int64]int64{
+	TASK_TYPE_HOMEWORK_LAYERED: TASK_TYPE_HOMEWORK,
+	TASK_TYPE_HOMEWORK_WRONG:   TASK_TYPE_HOMEWORK,
+	TASK_TYPE_HOMEWORK_ANSWER:  TASK_TYPE_HOMEWORK,
+	TASK_TYPE_TEST_ANSWER:      TASK_TYPE_TEST,
+}
+
+// IsAnswerCardTask 是否为答题卡任务
+func IsAnswerCardTask(taskSubType int64) bool {
+	return taskSubType == TASK_TYPE_HOMEWORK_ANSWER || taskSubType == TASK_TYPE_TEST_ANSWER
+}
+
+// TaskSubTypeExists 检查任务子类型是否属于指定任务类型，0 表示无子类型
+func TaskSubTypeExists(taskType int64, taskSubType int64) bool {
+	if taskSubType == 0 {
+		return true
+	}
+	parent, ok := TaskSubTypeParentMap[taskSubType]
+	return ok && parent == taskType
+}
+
 // GetTaskTypeName 获取任务类型名称
 func GetTaskTypeName(taskType int64) string {
 	if name, ok := TaskTypeNameMap[taskType]; ok {
@@ -386,29 +416,32 @@ const (
 // 素材资源类型
 const (
 	// 素材资源类型（100-199）内容平台资源>100
-	RESOURCE_TYPE_OTHER     int64 = 100 // 其它资源
-	RESOURCE_TYPE_AI_COURSE int64 = 101 // AI课，内容平台
-	RESOURCE_TYPE_PRACTICE  int64 = 102 // 巩固练习，内容平台
-	RESOURCE_TYPE_QUESTION  int64 = 103 // 试题，内容平台
-	RESOURCE_TYPE_PAPER     int64 = 104 // 试卷，内容平台
+	RESOURCE_TYPE_OTHER       int64 = 100 // 其它资源
+	RESOURCE_TYPE_AI_COURSE   int64 = 101 // AI课，内容平台
+	RESOURCE_TYPE_PRACTICE    int64 = 102 // 巩固练习，内容平台
+	RESOURCE_TYPE_QUESTION    int64 = 103 // 试题，内容平台
+	RESOURCE_TYPE_PAPER       int64 = 104 // 试卷，内容平台
+	RESOURCE_TYPE_ANSWER_CARD int64 = 105 // 答题卡，答题卡任务创建时自动生成
 )
 
 // 素材资源类型名称常量
 const (
-	RESOURCE_TYPE_OTHER_NAME     = "其它资源"
-	RESOURCE_TYPE_AI_COURSE_NAME = "AI课"
-	RESOURCE_TYPE_PRACTICE_NAME  = "巩固练习"
-	RESOURCE_TYPE_QUESTION_NAME  = "试题"
-	RESOURCE_TYPE_PAPER_NAME     = "试卷"
+	RESOURCE_TYPE_OTHER_NAME       = "其它资源"
+	RESOURCE_TYPE_AI_COURSE_NAME   = "AI课"
+	RESOURCE_TYPE_PRACTICE_NAME    = "巩固练习"
+	RESOURCE_TYPE_QUESTION_NAME    = "试题"
+	RESOURCE_TYPE_PAPER_NAME       = "试卷"
+	RESOURCE_TYPE_ANSWER_CARD_NAME = "答题卡"
 )
 
 // ResourceTypeNameMap 素材资源类型名称映射
 var ResourceTypeNameMap = map[int64]string{
-	RESOURCE_TYPE_OTHER:     RESOURCE_TYPE_OTHER_NAME,
-	RESOURCE_TYPE_AI_COURSE: RESOURCE_TYPE_AI_COURSE_NAME,
-	RESOURCE_TYPE_PRACTICE:  RESOURCE_TYPE_PRACTICE_NAME,
-	RESOURCE_TYPE_QUESTION:  RESOURCE_TYPE_QUESTION_NAME,
-	RESOURCE_TYPE_PAPER:     RESOURCE_TYPE_PAPER_NAME,
+	RESOURCE_TYPE_OTHER:       RESOURCE_TYPE_OTHER_NAME,
+	RESOURCE_TYPE_AI_COURSE:   RESOURCE_TYPE_AI_COURSE_NAME,
+	RESOURCE_TYPE_PRACTICE:    RESOURCE_TYPE_PRACTICE_NAME,
+	RESOURCE_TYPE_QUESTION:    RESOURCE_TYPE_QUESTION_NAME,
+	RESOURCE_TYPE_PAPER:       RESOURCE_TYPE_PAPER_NAME,
+	RESOURCE_TYPE_ANSWER_CARD: RESOURCE_TYPE_ANSWER_CARD_NAME,
 }
 
 // GetResourceTypeName 获取素材资源类型名称
@@ -427,7 +460,7 @@ const (
 	QUESTION_TYPE_SINGLE_CHOICE   QuestionType = 1 // 单选题
 	QUESTION_TYPE_MULTIPLE_CHOICE QuestionType = 2 // 多选题
 	QUESTION_TYPE_FILL_BLANK      QuestionType = 3 // 填空题
-	// QUESTION_TYPE_JUDGE           QuestionType = 4 // 判断题
+	QUESTION_TYPE_JUDGE           QuestionType = 4 // 判断题，目前只有答题卡题目使用
 	// QUESTION_TYPE_ANSWER          QuestionType = 5 // 解答题
 )
 
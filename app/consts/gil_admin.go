@@ -1,9 +1,12 @@
 package consts
 
+import "time"
+
 // GilAdminAPI 运营平台 API
 type GilAdminAPI struct {
 	Method string
 	Path   string
+	Policy UpstreamPolicy
 }
 
 // 教师服务相关常量
@@ -34,18 +37,21 @@ var (
 	GetTeacherDetailAPI = GilAdminAPI{
 		Method: "GET",
 		Path:   "/api/v1/teacher/detail_by_token",
+		Policy: UpstreamPolicy{Name: "teacher_detail", Timeout: 3 * time.Second, Idempotent: true, Retries: 2},
 	}
 
 	// 获取学校的学科教材接口
 	GetSchoolMaterialAPI = GilAdminAPI{
 		Method: "GET",
 		Path:   "/internal/api/v1/school/material",
+		Policy: UpstreamPolicy{Name: "school_material", Timeout: 3 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 30 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 
 	// 查询班级学生接口
 	GetClassStudentAPI = GilAdminAPI{
 		Method: "GET",
 		Path:   "/internal/api/v1/class/getStudent",
+		Policy: UpstreamPolicy{Name: "class_student", Timeout: 5 * time.Second, Idempotent: true, Retries: 2},
 	}
 
 	// 查询课程表接口
@@ -58,11 +64,13 @@ var (
 	GetGradeClassInfoAPI = GilAdminAPI{
 		Method: "GET",
 		Path:   "/internal/api/v1/class/classInfo",
+		Policy: UpstreamPolicy{Name: "grade_class_info", Timeout: 3 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 10 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 
 	// 通过学生ID查询学生信息接口
 	GetStudentInfoByIDAPI = GilAdminAPI{
 		Method: "GET",
 		Path:   "/internal/api/v1/class/getStudentWihOrg",
+		Policy: UpstreamPolicy{Name: "student_info", Timeout: 3 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 10 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 )
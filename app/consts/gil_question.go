@@ -23,10 +23,11 @@ var GilQuestionTypeNameMap = map[int64]string{
 	GilQuestionTypeDefault:        "默认题",
 }
 
-// QuestionAPI 题库 API
+// QuestionAPI 题库 API，题库的 POST 接口都是查询，按幂等处理
 type QuestionAPI struct {
 	Method string
 	Path   string
+	Policy UpstreamPolicy
 }
 
 // API 路径常量
@@ -54,47 +55,50 @@ const (
 	QuestionAPICodeQuestionSetNotExist = 300201 // 题集不存在
 )
 
-// API 配置
-var (
-	QuestionAPIDefaultTimeout = 10 * time.Second
-)
-
 var (
 	// 业务树相关
 	QuestionAPIBizTreeList = QuestionAPI{
 		Method: "POST",
 		Path:   QuestionAPIPrefix + "/base/data/biz/tree/list",
+		Policy: UpstreamPolicy{Name: "biz_tree_list", Timeout: 5 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 30 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 	QuestionAPIBizTreeDetail = QuestionAPI{
 		Method: "GET",
 		Path:   QuestionAPIPrefix + "/base/data/biz/tree/detail",
+		Policy: UpstreamPolicy{Name: "biz_tree_detail", Timeout: 5 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 30 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 
 	// 题集、巩固练习相关
 	QuestionAPIPracticeListByID = QuestionAPI{
 		Method: "POST",
 		Path:   QuestionAPIPrefix + "/scene/get/question/set/list",
+		Policy: UpstreamPolicy{Name: "practice_list", Timeout: 5 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 5 * time.Minute, StaleTTL: 2 * time.Hour},
 	}
 	QuestionAPIGetQuestionSetInfo = QuestionAPI{
 		Method: "GET",
 		Path:   QuestionAPIPrefix + "/scene/get/stable/question/set/info",
+		Policy: UpstreamPolicy{Name: "question_set_info", Timeout: 5 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 5 * time.Minute, StaleTTL: 2 * time.Hour},
 	}
 
 	// 题目相关
 	QuestionAPIQuestionEnums = QuestionAPI{
 		Method: "GET",
 		Path:   QuestionAPIPrefix + "/enums/get/consts",
+		Policy: UpstreamPolicy{Name: "question_enums", Timeout: 3 * time.Second, Idempotent: true, Retries: 2, CacheTTL: time.Hour, StaleTTL: 24 * time.Hour},
 	}
 	QuestionAPIQuestionList = QuestionAPI{
 		Method: "POST",
 		Path:   QuestionAPIPrefix + "/resource/get/question/list",
+		Policy: UpstreamPolicy{Name: "question_list", Timeout: 10 * time.Second, Idempotent: true, Retries: 1, CacheTTL: time.Minute, StaleTTL: 10 * time.Minute},
 	}
 	QuestionAPIQuestionDetail = QuestionAPI{
 		Method: "GET",
 		Path:   QuestionAPIPrefix + "/resource/get/question/info",
+		Policy: UpstreamPolicy{Name: "question_detail", Timeout: 5 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 10 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 	QuestionAPIQuestionListByID = QuestionAPI{
 		Method: "POST",
 		Path:   QuestionAPIPrefix + "/resource/get/question/info/list",
+		Policy: UpstreamPolicy{Name: "question_list_by_id", Timeout: 10 * time.Second, Idempotent: true, Retries: 2, CacheTTL: 10 * time.Minute, StaleTTL: 24 * time.Hour},
 	}
 )
@@ -7,12 +7,46 @@ const (
 	KafkaMaxPoolSize       = 10 // 每个 topic 的最大连接数
 
 	KafkaTopicTeacherBehavior = "topic-teacher-behaviors"
+	KafkaTopicStudentBehavior = "topic-student-behaviors"
 	KafkaTopicCommunication   = "topic-communication"
 	KafkaGroupBehavior        = "group-teacher-behaviors"
+	KafkaGroupAITutor         = "group-ai-tutor" // AI 答疑消费组，回答学生提问
+
+	KafkaTopicTaskAnswer = "topic-task-answers" // 学生作答事件
+	KafkaGroupTaskReport = "group-task-report"  // 任务报告聚合消费组
+
+	KafkaTopicClassroomLifecycle = "topic-classroom-lifecycle" // 课堂开始、暂停、结束事件
+
+	KafkaTopicModeration = "topic-content-moderation" // 待审核内容，会话消息之外的用户内容
+	KafkaGroupModeration = "group-content-moderation" // 内容审核消费组
+
+	KafkaGroupMessageSearch = "group-message-search" // 会话消息检索索引消费组
+
+	KafkaDeadLetterTopicSuffix  = "-dlq"                     // 死信 topic 后缀，{原 topic}-dlq
+	KafkaGroupDeadLetterReplay  = "group-dead-letter-replay" // 死信重放消费组，记录重放进度
+	KafkaDefaultRetryBackoff    = 200                        // 单条消息重试的初始退避时间，毫秒，按次数翻倍
+	KafkaMaxRetryBackoff        = 10000                      // 单条消息重试的最大退避时间，毫秒
+	KafkaDeadLetterReasonMaxLen = 1024                       // 死信失败原因最大长度
+	KafkaHeaderReplayOffset     = "x-replay-offset"          // 重放消息的 header，值为消息在原 partition 中的 offset
 )
 
 var (
 	KafkaTopicBehaviors = []string{
 		KafkaTopicTeacherBehavior,
+		KafkaTopicStudentBehavior,
+		KafkaTopicCommunication,
+	}
+	KafkaTopicTaskReports = []string{
+		KafkaTopicTaskAnswer,
+	}
+	KafkaTopicAITutor = []string{
+		KafkaTopicCommunication,
+	}
+	KafkaTopicModerations = []string{
+		KafkaTopicCommunication,
+		KafkaTopicModeration,
+	}
+	KafkaTopicMessageSearch = []string{
+		KafkaTopicCommunication,
 	}
 )
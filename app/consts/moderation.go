@@ -0,0 +1,161 @@
+package consts
+
+import "time"
+
+// ModerationContentType 审核内容类型
+type ModerationContentType string
+
+const (
+	ModerationContentTypeMessage  ModerationContentType = "communication_message" // 会话消息
+	ModerationContentTypeFeedback ModerationContentType = "classroom_feedback"    // 课堂反馈
+)
+
+// ModerationAction 审核结论
+type ModerationAction string
+
+const (
+	ModerationActionPass   ModerationAction = "pass"   // 通过
+	ModerationActionMask   ModerationAction = "mask"   // 屏蔽违规片段后展示
+	ModerationActionReview ModerationAction = "review" // 暂停展示，等待教师审核
+	ModerationActionReject ModerationAction = "reject" // 不予展示
+)
+
+// ModerationSeverity 违规程度，none 低于 low 低于 medium 低于 high
+type ModerationSeverity string
+
+const (
+	ModerationSeverityNone   ModerationSeverity = "none"   // 无风险
+	ModerationSeverityLow    ModerationSeverity = "low"    // 轻微，屏蔽违规片段
+	ModerationSeverityMedium ModerationSeverity = "medium" // 疑似违规，等待人工审核
+	ModerationSeverityHigh   ModerationSeverity = "high"   // 明确违规
+)
+
+// ModerationSeverityLevels 违规程度排序，用于比较
+var ModerationSeverityLevels = map[ModerationSeverity]int{
+	ModerationSeverityNone:   0,
+	ModerationSeverityLow:    1,
+	ModerationSeverityMedium: 2,
+	ModerationSeverityHigh:   3,
+}
+
+// 违规类别
+const (
+	ModerationCategoryNormal   = "normal"    // 正常
+	ModerationCategoryAbuse    = "abuse"     // 辱骂、歧视、人身攻击
+	ModerationCategoryPorn     = "porn"      // 色情低俗
+	ModerationCategoryViolence = "violence"  // 暴力、恐怖
+	ModerationCategoryIllegal  = "illegal"   // 违法犯罪、赌博、毒品
+	ModerationCategoryPolitics = "politics"  // 政治敏感
+	ModerationCategoryAd       = "ad"        // 广告引流
+	ModerationCategoryPrivacy  = "privacy"   // 个人隐私
+	ModerationCategorySelfHarm = "self_harm" // 自伤自残
+	ModerationCategoryOther    = "other"     // 其他
+)
+
+// 审核结论来源
+const (
+	ModerationSourceModel      = "model"      // 大模型审核
+	ModerationSourceDictionary = "dictionary" // 模型不可用时使用本地词典
+	ModerationSourceTeacher    = "teacher"    // 教师审核
+)
+
+// 内容的审核状态，对应课堂反馈的 moderation_status
+const (
+	ModerationStatusPending  int64 = 0 // 待审核，审核完成前正常展示
+	ModerationStatusPassed   int64 = 1 // 通过
+	ModerationStatusMasked   int64 = 2 // 已屏蔽违规片段
+	ModerationStatusHeld     int64 = 3 // 暂停展示，等待教师审核
+	ModerationStatusRejected int64 = 4 // 不予展示
+)
+
+// ModerationActionStatus 审核结论对应的审核状态
+var ModerationActionStatus = map[ModerationAction]int64{
+	ModerationActionPass:   ModerationStatusPassed,
+	ModerationActionMask:   ModerationStatusMasked,
+	ModerationActionReview: ModerationStatusHeld,
+	ModerationActionReject: ModerationStatusRejected,
+}
+
+// 内容审核参数
+const (
+	ModerationTimeout  = 10 * time.Second // 单次模型审核的超时时间
+	ModerationMaskRune = '*'              // 屏蔽违规片段使用的字符
+
+	ModerationHeldContent     = "该内容正在审核中"
+	ModerationRejectedContent = "该内容不符合社区规范，已被屏蔽"
+
+	ModerationReasonMaxLen = 200 // 审核原因最大字数
+
+	ModerationSweepInterval  = time.Minute     // 补偿审核的扫描间隔
+	ModerationSweepDelay     = 5 * time.Minute // 提交超过该时长仍待审核的课堂反馈才补偿，避免和正常投递的审核重复
+	ModerationSweepBatchSize = 100             // 每次扫描补偿审核的最大条数
+)
+
+// ModerationRule 本地审核词典规则，命中关键词或正则时按规则的类别和程度处理
+type ModerationRule struct {
+	Category string
+	Severity ModerationSeverity
+	Keywords []string
+	Patterns []string
+}
+
+// ModerationDictionary 本地审核词典，模型不可用时使用
+var ModerationDictionary = []ModerationRule{
+	{
+		Category: ModerationCategoryPrivacy,
+		Severity: ModerationSeverityLow,
+		Patterns: []string{
+			`1[3-9]\d{9}`,                // 手机号
+			`\d{17}[\dXx]`,               // 身份证号
+			`[\w.+-]+@[\w-]+(\.[\w-]+)+`, // 邮箱
+		},
+	},
+	{
+		Category: ModerationCategoryAbuse,
+		Severity: ModerationSeverityLow,
+		Keywords: []string{"傻逼", "傻x", "煞笔", "脑残", "智障", "废物", "白痴", "滚蛋", "去死"},
+	},
+	{
+		Category: ModerationCategoryAd,
+		Severity: ModerationSeverityMedium,
+		Keywords: []string{"加微信", "加v", "加vx", "加qq", "扫码领取", "代写作业", "刷单", "兼职日结"},
+	},
+	{
+		Category: ModerationCategorySelfHarm,
+		Severity: ModerationSeverityMedium,
+		Keywords: []string{"自杀", "不想活了", "割腕", "跳楼"},
+	},
+	{
+		Category: ModerationCategoryIllegal,
+		Severity: ModerationSeverityHigh,
+		Keywords: []string{"赌博", "博彩", "毒品", "冰毒", "大麻", "枪支"},
+	},
+	{
+		Category: ModerationCategoryPorn,
+		Severity: ModerationSeverityHigh,
+		Keywords: []string{"色情", "黄片", "裸聊", "约炮"},
+	},
+}
+
+// ModerationPrompt 内容审核系统提示
+type ModerationPrompt struct {
+	Version      string // 版本
+	SystemPrompt string // 系统提示
+}
+
+var (
+	ModerationPromptV1 = ModerationPrompt{
+		Version: "v1",
+		SystemPrompt: `你是一名中国网络内容安全审核专员，负责审核中小学师生在学习平台上发送的消息和反馈。请依据国家法律法规、行业规范和平台社区规则，判断内容是否存在违规风险，尤其注意对未成年人的不良影响。
+
+类别 category 只能是以下之一：
+normal（正常）、abuse（辱骂、歧视、人身攻击）、porn（色情低俗）、violence（暴力、恐怖）、illegal（违法犯罪、赌博、毒品）、politics（政治敏感）、ad（广告引流）、privacy（手机号、身份证号、住址等个人隐私）、self_harm（自伤自残倾向）、other（其他）
+
+程度 severity 只能是以下之一：
+none（无风险）、low（轻微，屏蔽违规词句后可以展示）、medium（疑似违规或需要老师关注，需要人工审核）、high（明确严重违规，不得展示）
+
+请严格按以下 JSON 格式返回，不要输出其他内容：
+{"category":"类别","severity":"程度","reason":"简洁说明违规点，无风险时为空","keywords":["原文中需要屏蔽的词句"]}
+keywords 必须是原文中连续出现的片段，无风险时为空数组。`,
+	}
+)
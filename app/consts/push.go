@@ -0,0 +1,46 @@
+package consts
+
+import (
+	"fmt"
+	"time"
+)
+
+// PushMessageType 推送消息类型
+type PushMessageType string
+
+const (
+	PushMessageTypePraise    PushMessageType = "praise"    // 课堂表扬、作业点赞
+	PushMessageTypeAttention PushMessageType = "attention" // 课堂关注提醒
+	PushMessageTypeReminder  PushMessageType = "reminder"  // 作业提醒
+	PushMessageTypeHandled   PushMessageType = "handled"   // 教师处理结果，同步到教师的其他屏幕
+	PushMessageTypeEscalated PushMessageType = "escalated" // AI 答疑转交教师
+)
+
+// 推送通道参数
+const (
+	PushChannel           = "push:channel" // Redis pub/sub 频道，所有实例订阅后投递给本地连接
+	PushOutboxExpire      = 24 * 3600      // 离线消息保留时间，秒
+	PushSeqExpire         = 30 * 24 * 3600 // 消息序号保留时间，长于离线消息，避免序号重置后客户端按旧序号丢弃新消息
+	PushOutboxMaxSize     = 200            // 每个用户最多保留的未确认消息数
+	PushConnBufferSize    = 64             // 单个连接的待发送消息数，超出时丢弃，由客户端重连后补发
+	PushStreamRetry       = 3000           // 客户端断线重连间隔，毫秒
+	PushStreamHeartbeat   = 15 * time.Second
+	PushStreamMaxDuration = 30 * time.Minute // 单个推送连接的最长时间，到期后由客户端重连
+)
+
+const (
+	// 用户未确认的推送消息，push:outbox:{userType}:{userId} => {member:消息, score:序号}
+	PushOutboxKeyFormat = "push:outbox:%s:%d"
+	// 用户推送消息序号，push:seq:{userType}:{userId} => seq
+	PushSeqKeyFormat = "push:seq:%s:%d"
+)
+
+// 用户未确认的推送消息缓存键
+func GetPushOutboxKey(userType CommunicationUserType, userID int64) string {
+	return fmt.Sprintf(PushOutboxKeyFormat, userType, userID)
+}
+
+// 用户推送消息序号缓存键
+func GetPushSeqKey(userType CommunicationUserType, userID int64) string {
+	return fmt.Sprintf(PushSeqKeyFormat, userType, userID)
+}
@@ -0,0 +1,45 @@
+package consts
+
+// 会话消息全文检索
+const (
+	// MessageSearchIndex 会话消息索引，修改映射时升级版本号并重建索引
+	MessageSearchIndex = "gil_teacher_communication_messages_v1"
+
+	MessageSearchMaxPageSize = 50    // 每页最多返回的消息数
+	MessageSearchMaxWindow   = 10000 // 最多可以翻到的结果数，对应索引的 max_result_window
+	MessageSearchTimeout     = 5     // 单次检索的超时时间，秒
+
+	MessageSearchHighlightPreTag   = "<em>"
+	MessageSearchHighlightPostTag  = "</em>"
+	MessageSearchHighlightFragment = 100 // 高亮片段的字数
+	MessageSearchHighlightCount    = 3   // 每条消息最多返回的高亮片段数
+)
+
+// MessageSearchIndexBody 会话消息索引的设置和映射
+// 消息内容使用 IK 分词，写入时细粒度切分，检索时智能切分，需要集群安装 analysis-ik 插件
+const MessageSearchIndexBody = `{
+  "settings": {
+    "max_result_window": 10000
+  },
+  "mappings": {
+    "dynamic": "strict",
+    "properties": {
+      "messageId":   {"type": "keyword"},
+      "sessionId":   {"type": "keyword"},
+      "sessionType": {"type": "keyword"},
+      "schoolId":    {"type": "long"},
+      "classId":     {"type": "long"},
+      "classroomId": {"type": "long"},
+      "courseId":    {"type": "long"},
+      "taskId":      {"type": "long"},
+      "assignId":    {"type": "long"},
+      "questionId":  {"type": "keyword"},
+      "userId":      {"type": "long"},
+      "userType":    {"type": "keyword"},
+      "messageType": {"type": "keyword"},
+      "answerTo":    {"type": "keyword"},
+      "content":     {"type": "text", "analyzer": "ik_max_word", "search_analyzer": "ik_smart"},
+      "createdAt":   {"type": "date"}
+    }
+  }
+}`
@@ -16,7 +16,7 @@ var ExportFields = []string{
 	"accuracyRate",               // 正确率
 	"difficultyDegree",           // 难度
 	"incorrectCount/answerCount", // 错题数/答题数
-	// "answerTime",                 // 答题用时
+	"answerTime",                 // 答题用时
 }
 
 // 支持的排序字段
@@ -35,9 +35,15 @@ var ExportFieldsCN = map[string]string{
 	"accuracyRate":               "正确率",
 	"difficultyDegree":           "答题难度",
 	"incorrectCount/answerCount": "错题/答题",
-	// "answerTime":                 "答题用时",
+	"answerTime":                 "答题用时(秒)",
 }
 
+// 报告导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
 // 检查字段是否在导出字段中
 func IsExportFields(fields []string) bool {
 	for _, f := range fields {
@@ -56,3 +62,105 @@ func ExtractExportFields(fields []string) []string {
 	}
 	return result
 }
+
+// 任务报告聚合参数
+const (
+	TaskReportAttentionAccuracy = 0.6 // 正确率低于该值的题目计为待关注题目，学生计为需关注学生
+)
+
+// 作业报告异步导出任务状态
+const (
+	EXPORT_JOB_STATUS_PENDING   int64 = 1 // 等待执行
+	EXPORT_JOB_STATUS_RUNNING   int64 = 2 // 执行中
+	EXPORT_JOB_STATUS_SUCCEEDED int64 = 3 // 执行成功
+	EXPORT_JOB_STATUS_FAILED    int64 = 4 // 执行失败
+)
+
+// 作业报告异步导出参数
+const (
+	ExportJobWorkerNum         = 4    // 每个实例执行导出的协程数
+	ExportJobQueueSize         = 100  // 等待执行的任务队列长度，队列满时由定时扫描补偿
+	ExportJobMaxItems          = 200  // 单个导出任务最多包含的布置数
+	ExportJobScanInterval      = 30   // 扫描未执行任务的间隔，秒
+	ExportJobLeaseDuration     = 90   // 执行中任务的租约时长，租约过期未续期视为执行中断，秒
+	ExportJobHeartbeatInterval = 30   // 执行中任务续期租约的间隔，秒
+	ExportJobURLExpire         = 1800 // 下载链接有效期，秒
+	ExportJobOSSPath           = "task_report_export"
+)
+
+// 分层作业学生分层方式
+const (
+	TASK_TIER_MODE_MANUAL int64 = 1 // 教师手动指定每个分层的学生
+	TASK_TIER_MODE_AUTO   int64 = 2 // 按学生最近一次作业报告的正确率自动分层
+)
+
+// 分层作业参数
+const (
+	TaskTierNoCommon = 0 // 分层序号为 0 的资源为全部分层共用
+	TaskTierMinNum   = 2 // 最少分层数
+	TaskTierMaxNum   = 5 // 最多分层数
+)
+
+// 错题作业参数
+const (
+	WrongQuestionDefaultNum   = 20 // 每个学生默认最多错题数
+	WrongQuestionMaxNum       = 50 // 每个学生最多错题数上限
+	WrongQuestionMaxSourceNum = 50 // 最多指定的来源任务数
+	WrongQuestionMaxDays      = 90 // 错题作答时间范围最大天数
+)
+
+// 答题卡题目类型
+const (
+	ANSWER_CARD_QUESTION_SINGLE   int64 = 1 // 单选题
+	ANSWER_CARD_QUESTION_MULTIPLE int64 = 2 // 多选题
+	ANSWER_CARD_QUESTION_JUDGE    int64 = 3 // 判断题，选项为 T、F
+)
+
+// 答题卡参数
+const (
+	AnswerCardResourceID     = "answer_card" // 答题卡资源ID，每个答题卡任务只有一个答题卡资源
+	AnswerCardMaxQuestionNum = 200           // 答题卡最多题目数
+	AnswerCardImportMaxRows  = 2000          // 单次导入最多学生数
+	AnswerCardImportMaxSize  = 5 << 20       // 导入文件最大字节数
+)
+
+// 任务状态
+const (
+	TASK_STATUS_DRAFT     int64 = 1 // 草稿，学生不可见，可编辑资源
+	TASK_STATUS_SCHEDULED int64 = 2 // 定时发布，到达发布时间后自动发布
+	TASK_STATUS_PUBLISHED int64 = 3 // 已发布
+	TASK_STATUS_ARCHIVED  int64 = 4 // 已归档，学生和报告中仍可查看，不再自动布置
+)
+
+// TaskVisibleStatus 学生端和作业报告中可见的任务状态
+var TaskVisibleStatus = []int64{TASK_STATUS_PUBLISHED, TASK_STATUS_ARCHIVED}
+
+// 周期布置规则状态
+const (
+	TASK_RECURRENCE_STATUS_ACTIVE   int64 = 1 // 生效中
+	TASK_RECURRENCE_STATUS_STOPPED  int64 = 2 // 教师手动停止
+	TASK_RECURRENCE_STATUS_FINISHED int64 = 3 // 已超过结束日期
+)
+
+// 定时发布和周期布置参数
+const (
+	TaskRecurrenceMaxDays     = 180           // 周期布置规则最长持续天数
+	TaskRecurrenceMaxPerTask  = 20            // 每个任务最多的周期布置规则数
+	TaskScheduleBatchSize     = 100           // 定时任务每批处理的规则数
+	TaskRecurrenceMaxDuration = 7 * 24 * 3600 // 每次布置从开始到截止的最长时间，秒
+)
+
+// 班级共性错题本参数，正确率为 0-1 的小数
+const (
+	ClassErrorBookDefaultAnswerNum   = 5    // 未设置共性错题时，题目答题人数不少于该值才计入
+	ClassErrorBookDefaultCorrectRate = 0.6  // 未设置共性错题时，题目正确率低于该值才计入
+	ClassErrorBookMaxItems           = 2000 // 按知识点浏览时，单个班级学科最多加载的错题数
+	ClassErrorBookMaxPushNum         = 100  // 一次最多推送到新任务的错题数
+)
+
+// 班级共性错题本按讲解状态筛选
+const (
+	ClassErrorBookFilterAll         = 0 // 全部
+	ClassErrorBookFilterUnexplained = 1 // 未讲解
+	ClassErrorBookFilterExplained   = 2 // 已讲解
+)
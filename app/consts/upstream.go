@@ -0,0 +1,32 @@
+package consts
+
+import "time"
+
+// UpstreamPolicy 上游接口的调用策略
+type UpstreamPolicy struct {
+	Name       string        // 接口名，用于指标、熔断和缓存键
+	Timeout    time.Duration // 单次请求超时
+	Idempotent bool          // 是否幂等，只有幂等接口会重试
+	Retries    int           // 失败后的最大重试次数
+	CacheTTL   time.Duration // 缓存有效期，为 0 时不缓存
+	StaleTTL   time.Duration // 缓存过期后，上游失败时仍可返回旧数据的时长
+}
+
+// 上游名称
+const (
+	UpstreamQuestion = "question" // 题库平台
+	UpstreamUcenter  = "ucenter"  // 运营平台用户中心
+)
+
+// 上游调用相关配置
+const (
+	UpstreamRetryBaseDelay = 100 * time.Millisecond // 重试基础间隔，按次数指数增长并加随机抖动
+	UpstreamRetryMaxDelay  = 2 * time.Second        // 重试最大间隔
+
+	UpstreamBreakerFailureThreshold = 5                // 连续失败多少次后熔断
+	UpstreamBreakerOpenDuration     = 30 * time.Second // 熔断持续时间，到期后放行一个探测请求
+
+	UpstreamLocalCacheSize = 2048 // 每个上游的进程内缓存条数
+
+	UpstreamCacheKeyFormat = "upstream:%s:%s:%s" // 上游:接口:请求摘要
+)
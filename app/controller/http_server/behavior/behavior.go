@@ -5,13 +5,14 @@ import (
 	"gil_teacher/app/controller/http_server/response"
 	"gil_teacher/app/core/logger"
 	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/domain/classroom"
 	"gil_teacher/app/middleware"
 	"gil_teacher/app/model/api"
 	"gil_teacher/app/model/dto"
 	"gil_teacher/app/utils"
 
 	"encoding/json"
-	"fmt"
+	"errors"
 	"sort"
 	"time"
 
@@ -21,7 +22,14 @@ import (
 type BehaviorController struct {
 	behaviorHandler       *behavior.BehaviorHandler
 	sessionMessageHandler *behavior.SessionMessageHandler
+	reportHandler         *behavior.ClassroomReportHandler
+	profileHandler        *behavior.StudentProfileHandler
+	classroomHandler      *classroom.ClassroomHandler
+	feedbackHandler       *classroom.ClassroomFeedbackHandler
 	producer              *behavior.BehaviorProducer
+	aiTutor               *behavior.AITutor
+	moderator             *behavior.ContentModerator
+	messageSearcher       *behavior.MessageSearcher
 	teacherMiddleware     *middleware.TeacherMiddleware
 	log                   *logger.ContextLogger
 }
@@ -29,14 +37,28 @@ type BehaviorController struct {
 func NewBehaviorController(
 	behaviorHandler *behavior.BehaviorHandler,
 	sessionMessageHandler *behavior.SessionMessageHandler,
+	reportHandler *behavior.ClassroomReportHandler,
+	profileHandler *behavior.StudentProfileHandler,
+	classroomHandler *classroom.ClassroomHandler,
+	feedbackHandler *classroom.ClassroomFeedbackHandler,
 	producer *behavior.BehaviorProducer,
+	aiTutor *behavior.AITutor,
+	moderator *behavior.ContentModerator,
+	messageSearcher *behavior.MessageSearcher,
 	teacherMiddleware *middleware.TeacherMiddleware,
 	log *logger.ContextLogger,
 ) *BehaviorController {
 	return &BehaviorController{
 		behaviorHandler:       behaviorHandler,
 		sessionMessageHandler: sessionMessageHandler,
+		reportHandler:         reportHandler,
+		profileHandler:        profileHandler,
+		classroomHandler:      classroomHandler,
+		feedbackHandler:       feedbackHandler,
 		producer:              producer,
+		aiTutor:               aiTutor,
+		moderator:             moderator,
+		messageSearcher:       messageSearcher,
 		teacherMiddleware:     teacherMiddleware,
 		log:                   log,
 	}
@@ -58,11 +80,18 @@ func (c *BehaviorController) RecordTeacherBehavior(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if req.ClassroomID != 0 && !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	req.TeacherID = uint64(teacherID)
 	req.SchoolID = uint64(schoolID)
 	if err := c.producer.RecordTeacherBehavior(ctx, &req); err != nil {
 		c.log.Error(ctx, "记录教师行为失败: %+v", err)
+		if errors.Is(err, behavior.ErrInvalidBehaviorContext) {
+			response.ParamError(ctx, response.ERR_BEHAVIOR_CONTEXT)
+			return
+		}
 		response.Err(ctx, response.ERR_KAFKA)
 		return
 	}
@@ -81,6 +110,10 @@ func (c *BehaviorController) RecordStudentBehavior(ctx *gin.Context) {
 
 	if err := c.producer.RecordStudentBehavior(ctx, &req); err != nil {
 		c.log.Error(ctx, "记录学生行为失败: %v", err)
+		if errors.Is(err, behavior.ErrInvalidBehaviorContext) {
+			response.ParamError(ctx, response.ERR_BEHAVIOR_CONTEXT)
+			return
+		}
 		response.Err(ctx, response.ERR_KAFKA)
 		return
 	}
@@ -205,9 +238,19 @@ func (c *BehaviorController) MarkMessageAsRead(ctx *gin.Context) {
 		return
 	}
 
-	userID := int64(0) // TODO 从上下文获取
+	userID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
 	if err := c.sessionMessageHandler.MarkMessageAsRead(ctx, userID, sessionID, messageID); err != nil {
 		c.log.Error(ctx, "标记消息已读失败: %v", err)
+		if errors.Is(err, behavior.ErrSessionMessageNotFound) {
+			response.ParamError(ctx, response.ERR_SESSION_MESSAGE_NOT_FOUND)
+			return
+		}
 		response.SystemError(ctx)
 		return
 	}
@@ -224,7 +267,13 @@ func (c *BehaviorController) GetUnreadMessageCount(ctx *gin.Context) {
 		return
 	}
 
-	userID := int64(0) // TODO 从上下文获取
+	userID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
 	count, err := c.sessionMessageHandler.GetUnreadMessageCount(ctx, userID, sessionID)
 	if err != nil {
 		c.log.Error(ctx, "获取未读消息数量失败: %v", err)
@@ -244,7 +293,13 @@ func (c *BehaviorController) GetUnreadMessageList(ctx *gin.Context) {
 		return
 	}
 
-	userID := int64(0) // TODO 从上下文获取
+	userID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
 	messages, err := c.sessionMessageHandler.GetUnreadMessageList(ctx, userID, sessionID)
 	if err != nil {
 		c.log.Error(ctx, "获取未读消息列表失败: %v", err)
@@ -255,6 +310,150 @@ func (c *BehaviorController) GetUnreadMessageList(ctx *gin.Context) {
 	response.Success(ctx, messages)
 }
 
+// GetSessionInbox 获取教师参与的全部会话，包括最后一条消息、未读数和参与者，按最后活跃时间倒序
+func (c *BehaviorController) GetSessionInbox(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.SessionInboxRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数验证失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	inbox, err := c.sessionMessageHandler.GetInbox(ctx, schoolID, teacherID, req.Page, req.PageSize)
+	if err != nil {
+		c.log.Error(ctx, "获取会话收件箱失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, &api.SessionInboxResponse{
+		List:        inbox.Sessions,
+		UnreadCount: inbox.UnreadCount,
+		PageInfo: &consts.ApiPageResponse{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Total:    inbox.Total,
+		},
+	})
+}
+
+// GetAIAnswers 获取任务布置下 AI 答疑的回答和转交记录，供教师审核
+func (c *BehaviorController) GetAIAnswers(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.AIAnswerListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数验证失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	classIDs, err := c.aiTutor.TaskClassIDs(ctx, schoolID, teacherID, req.TaskID, req.AssignID)
+	if !c.checkAITutorAccess(ctx, classIDs, err) {
+		return
+	}
+
+	answers, err := c.aiTutor.ListTaskAnswers(ctx, req.TaskID, req.AssignID)
+	if err != nil {
+		c.log.Error(ctx, "获取 AI 答疑记录失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, &api.AIAnswerListResponse{List: answers})
+}
+
+// OverrideAIAnswer 教师修正 AI 回答，修正内容作为教师消息发送到会话中
+func (c *BehaviorController) OverrideAIAnswer(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.OverrideAIAnswerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数验证失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	classIDs, err := c.aiTutor.AnswerClassIDs(ctx, schoolID, teacherID, req.SessionID)
+	if !c.checkAITutorAccess(ctx, classIDs, err) {
+		return
+	}
+
+	message, err := c.aiTutor.OverrideAnswer(ctx, schoolID, teacherID, &req)
+	if err != nil {
+		if errors.Is(err, behavior.ErrAITutorAnswer) {
+			response.ParamError(ctx, response.ERR_AI_ANSWER_NOT_FOUND)
+			return
+		}
+		c.log.Error(ctx, "修正 AI 回答失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+	if err := c.producer.SendCommunicationMessage(ctx, message); err != nil {
+		c.log.Error(ctx, "发送修正消息失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, map[string]any{"message_id": message.MessageID})
+}
+
+// 检查教师是否有任务布置班级的权限，无权限时写入响应并返回 false
+func (c *BehaviorController) checkAITutorAccess(ctx *gin.Context, classIDs []int64, err error) bool {
+	switch {
+	case errors.Is(err, behavior.ErrAITutorTask):
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+		return false
+	case errors.Is(err, behavior.ErrAITutorAnswer):
+		response.ParamError(ctx, response.ERR_AI_ANSWER_NOT_FOUND)
+		return false
+	case errors.Is(err, behavior.ErrAITutorForbidden):
+		response.Forbidden(ctx)
+		return false
+	case err != nil:
+		c.log.Error(ctx, "校验 AI 答疑权限失败: %v", err)
+		response.SystemError(ctx)
+		return false
+	}
+	if len(classIDs) > 0 && !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
+		response.Forbidden(ctx)
+		return false
+	}
+	return true
+}
+
 // GetClassLatestBehaviors 获取班级学生最新行为
 func (c *BehaviorController) GetClassLatestBehaviors(ctx *gin.Context) {
 	var req api.GetClassLatestBehaviorsRequest
@@ -262,6 +461,9 @@ func (c *BehaviorController) GetClassLatestBehaviors(ctx *gin.Context) {
 		response.ParamError(ctx, response.ERR_INVALID_CLASSROOM)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	behaviors, err := c.behaviorHandler.GetClassLatestBehaviors(ctx, req.ClassroomID)
 	if err != nil {
@@ -288,6 +490,9 @@ func (c *BehaviorController) GetStudentClassroomDetail(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层获取数据
 	detail, err := c.behaviorHandler.GetStudentClassroomDetail(ctx, &req)
@@ -325,7 +530,21 @@ func convertToAPIBehaviorCategory(student dto.StudentBehaviorCategoryDTO) api.St
 		PageSwitchCount:   student.PageSwitchCount,
 		OtherContentCount: student.OtherContentCount,
 		PauseCount:        student.PauseCount,
+		BehaviorTags:      convertToAPIBehaviorTags(student.BehaviorTags),
+	}
+}
+
+// convertToAPIBehaviorTags 将行为标签转换为API响应格式
+func convertToAPIBehaviorTags(tags []dto.BehaviorTag) []api.BehaviorTag {
+	result := make([]api.BehaviorTag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, api.BehaviorTag{
+			Type:  tag.Type,
+			Count: tag.Count,
+			Text:  tag.Text,
+		})
 	}
+	return result
 }
 
 // convertToAPIBehaviorCategoryList 批量转换学生行为分类列表
@@ -353,9 +572,12 @@ func (c *BehaviorController) GetClassBehaviorCategory(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层获取数据
-	categories, err := c.behaviorHandler.GetClassBehaviorCategory(ctx, req.ClassroomID)
+	categories, err := c.behaviorHandler.GetClassBehaviorCategory(ctx, c.teacherMiddleware.ExtractSchoolID(ctx), req.ClassroomID)
 	if err != nil {
 		c.log.Error(ctx, "获取课堂行为分类列表失败: %v", err)
 		response.SystemError(ctx)
@@ -369,9 +591,6 @@ func (c *BehaviorController) GetClassBehaviorCategory(ctx *gin.Context) {
 	for _, category := range categories {
 		apiCategory := convertToAPIBehaviorCategory(category)
 
-		// 生成行为标签
-		generateBehaviorTags(&apiCategory)
-
 		// 分离表扬标签和提醒标签
 		var praiseTags []api.BehaviorTag
 		var attentionTags []api.BehaviorTag
@@ -432,61 +651,6 @@ func (c *BehaviorController) GetClassBehaviorCategory(ctx *gin.Context) {
 	response.Success(ctx, responseData)
 }
 
-// generateBehaviorTags 生成行为标签
-func generateBehaviorTags(category *api.StudentBehaviorCategory) {
-	category.BehaviorTags = []api.BehaviorTag{}
-
-	// 课堂表现很棒的标签
-	if category.EarlyLearnCount > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypeEarlyLearn),
-			Count: category.EarlyLearnCount,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextEarlyLearn, category.EarlyLearnCount),
-		})
-	}
-
-	if category.QuestionCount > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypeQuestion),
-			Count: category.QuestionCount,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextQuestion, category.QuestionCount),
-		})
-	}
-
-	if category.CorrectStreak > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypeCorrectStreak),
-			Count: category.CorrectStreak,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextCorrectStreak, category.CorrectStreak),
-		})
-	}
-
-	// 需要关注的行为标签
-	if category.PageSwitchCount > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypePageSwitch),
-			Count: category.PageSwitchCount,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextPageSwitch, category.PageSwitchCount),
-		})
-	}
-
-	if category.OtherContentCount > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypeOtherContent),
-			Count: category.OtherContentCount,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextOtherContent, category.OtherContentCount),
-		})
-	}
-
-	if category.PauseCount > 0 {
-		category.BehaviorTags = append(category.BehaviorTags, api.BehaviorTag{
-			Type:  string(consts.BehaviorTagTypePause),
-			Count: category.PauseCount,
-			Text:  fmt.Sprintf(consts.BehaviorTagTextPause, category.PauseCount),
-		})
-	}
-}
-
 // PraiseStudents 表扬学生接口
 func (c *BehaviorController) PraiseStudents(ctx *gin.Context) {
 	// 获取教师ID
@@ -511,6 +675,9 @@ func (c *BehaviorController) PraiseStudents(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层处理表扬
 	req.TeacherID = teacherID
@@ -552,6 +719,9 @@ func (c *BehaviorController) AttentionStudents(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层处理关注
 	result, err := c.behaviorHandler.AttentionStudents(ctx, &req)
@@ -581,9 +751,12 @@ func (c *BehaviorController) GetClassroomLearningScores(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层获取数据
-	scores, err := c.behaviorHandler.GetClassroomLearningScores(ctx, req.ClassroomID)
+	scores, err := c.behaviorHandler.GetClassroomLearningScores(ctx, c.teacherMiddleware.ExtractSchoolID(ctx), req.ClassroomID, time.Time{}, time.Time{})
 	if err != nil {
 		c.log.Error(ctx, "获取课堂学习分列表失败: %v", err)
 		response.SystemError(ctx)
@@ -727,6 +900,10 @@ func (c *BehaviorController) TeacherEvaluateStudent(ctx *gin.Context) {
 
 	// 记录学生行为
 	if err := c.producer.RecordStudentBehavior(ctx, behaviorReq); err != nil {
+		if errors.Is(err, behavior.ErrInvalidBehaviorContext) {
+			response.ParamError(ctx, response.ERR_BEHAVIOR_CONTEXT)
+			return
+		}
 		response.Err(ctx, response.ERR_KAFKA)
 		return
 	}
@@ -751,9 +928,12 @@ func (c *BehaviorController) GetClassroomBehaviorSummary(ctx *gin.Context) {
 		response.ParamError(ctx)
 		return
 	}
+	if !c.checkClassroom(ctx, req.ClassroomID) {
+		return
+	}
 
 	// 调用领域层获取数据
-	summary, err := c.behaviorHandler.GetClassroomBehaviorSummary(ctx, req.ClassroomID)
+	summary, err := c.behaviorHandler.GetClassroomBehaviorSummary(ctx, c.teacherMiddleware.ExtractSchoolID(ctx), req.ClassroomID, time.Time{}, time.Time{})
 	if err != nil {
 		c.log.Error(ctx, "获取课后行为汇总统计失败: %v", err)
 		response.SystemError(ctx)
@@ -763,3 +943,112 @@ func (c *BehaviorController) GetClassroomBehaviorSummary(ctx *gin.Context) {
 	// 返回响应
 	response.Success(ctx, summary)
 }
+
+// ListClassroomReports 查询班级历史课堂报告
+func (c *BehaviorController) ListClassroomReports(ctx *gin.Context) {
+	var req api.ClassroomReportListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.reportHandler.ListClassReports(ctx, schoolID, &req)
+	if err != nil {
+		c.log.Error(ctx, "查询班级课堂报告失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+	response.Success(ctx, result)
+}
+
+// GetClassroomReport 查询课堂报告详情
+func (c *BehaviorController) GetClassroomReport(ctx *gin.Context) {
+	var req api.ClassroomReportDetailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	report, err := c.reportHandler.GetClassroomReport(ctx, schoolID, req.ReportID)
+	if err != nil {
+		c.log.Error(ctx, "查询课堂报告失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+	if report == nil {
+		response.ParamError(ctx, response.ERR_CLASSROOM_REPORT_NOT_FOUND)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, int64(report.ClassID)) {
+		response.Forbidden(ctx)
+		return
+	}
+	response.Success(ctx, report)
+}
+
+// DryRunBehaviorRules 行为规则试运行，评估课堂学生当前行为命中的规则和得分
+func (c *BehaviorController) DryRunBehaviorRules(ctx *gin.Context) {
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.BehaviorRuleDryRunRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	req.SchoolID = schoolID
+	result, err := c.behaviorHandler.DryRunBehaviorRules(ctx, &req)
+	if errors.Is(err, behavior.ErrInvalidBehaviorRuleSet) {
+		c.log.Error(ctx, "行为规则集无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err != nil {
+		c.log.Error(ctx, "行为规则试运行失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, result)
+}
@@ -0,0 +1,89 @@
+package behavior
+
+import (
+	"context"
+	"errors"
+
+	"gil_teacher/app/controller/http_server/response"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	"gil_teacher/app/domain/classroom"
+	"gil_teacher/app/model/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartClassroom 开始上课，暂停中的课堂继续上课
+func (c *BehaviorController) StartClassroom(ctx *gin.Context) {
+	c.changeClassroom(ctx, "开始上课", c.classroomHandler.Start)
+}
+
+// PauseClassroom 暂停上课
+func (c *BehaviorController) PauseClassroom(ctx *gin.Context) {
+	c.changeClassroom(ctx, "暂停上课", c.classroomHandler.Pause)
+}
+
+// EndClassroom 结束上课
+func (c *BehaviorController) EndClassroom(ctx *gin.Context) {
+	c.changeClassroom(ctx, "结束上课", c.classroomHandler.End)
+}
+
+func (c *BehaviorController) changeClassroom(ctx *gin.Context, action string,
+	change func(ctx context.Context, schoolID, teacherID, classroomID int64) (*dao_classroom.Classroom, error)) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.ClassroomLifecycleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx, response.ERR_CLASSROOM_ID_ZERO)
+		return
+	}
+
+	result, err := change(ctx, schoolID, teacherID, req.ClassroomID)
+	if err != nil {
+		c.log.Error(ctx, "%s失败, classroomID: %d, error: %v", action, req.ClassroomID, err)
+		switch {
+		case errors.Is(err, classroom.ErrClassroomNotFound):
+			response.ParamError(ctx, response.ERR_CLASSROOM_NOT_FOUND)
+		case errors.Is(err, classroom.ErrClassroomStatus):
+			response.ParamError(ctx, response.ERR_CLASSROOM_STATUS)
+		default:
+			response.SystemError(ctx)
+		}
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+// checkClassroom 校验课堂已由当前教师开课，校验失败时写入响应并返回 false
+func (c *BehaviorController) checkClassroom(ctx *gin.Context, classroomID uint64) bool {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return false
+	}
+
+	ok, err := c.classroomHandler.CheckBehaviorClassroom(ctx, schoolID, teacherID, int64(classroomID))
+	if err != nil {
+		c.log.Error(ctx, "校验课堂失败, classroomID: %d, error: %v", classroomID, err)
+		response.SystemError(ctx)
+		return false
+	}
+	if !ok {
+		c.log.Warn(ctx, "课堂不存在或不属于当前教师, classroomID: %d, teacherID: %d", classroomID, teacherID)
+		response.ParamError(ctx, response.ERR_CLASSROOM_NOT_FOUND)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package behavior
+
+import (
+	"errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/domain/classroom"
+	"gil_teacher/app/model/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StudentSubmitClassroomFeedback 学生提交课堂反馈，学生端内部接口
+func (c *BehaviorController) StudentSubmitClassroomFeedback(ctx *gin.Context) {
+	var req api.StudentSubmitClassroomFeedbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	c.submitClassroomFeedback(ctx, req.StudentID, req.SchoolID, consts.ClassroomFeedbackCreateTypeStudent, &req.SubmitClassroomFeedbackRequest)
+}
+
+// SubmitClassroomFeedback 教师提交课堂反馈
+func (c *BehaviorController) SubmitClassroomFeedback(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.SubmitClassroomFeedbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	c.submitClassroomFeedback(ctx, teacherID, schoolID, consts.ClassroomFeedbackCreateTypeTeacher, &req)
+}
+
+func (c *BehaviorController) submitClassroomFeedback(ctx *gin.Context, userID, schoolID, createType int64, req *api.SubmitClassroomFeedbackRequest) {
+	feedback, err := c.feedbackHandler.Submit(ctx, userID, schoolID, createType, req)
+	if err != nil {
+		c.log.Error(ctx, "提交课堂反馈失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	// 反馈内容异步审核，投递失败时同步审核，同步审核也失败时由消费端定时补偿
+	moderationReq := behavior.FeedbackModerationRequest(feedback)
+	if err := c.producer.SendModerationRequest(ctx, moderationReq); err != nil {
+		c.log.Warn(ctx, "投递课堂反馈审核失败，同步审核, feedbackID:%d, error:%v", feedback.ID, err)
+		if err := c.moderator.Moderate(ctx, moderationReq); err != nil {
+			c.log.Error(ctx, "同步审核课堂反馈失败, feedbackID:%d, error:%v", feedback.ID, err)
+		}
+	}
+
+	response.Success(ctx, feedback)
+}
+
+// ListClassroomFeedback 按条件分页查询班级的课堂反馈
+func (c *BehaviorController) ListClassroomFeedback(ctx *gin.Context) {
+	var req api.ClassroomFeedbackListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.feedbackHandler.List(ctx, c.teacherMiddleware.ExtractSchoolID(ctx), &req)
+	if err != nil {
+		c.log.Error(ctx, "查询课堂反馈失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+// GetClassroomFeedbackStats 汇总班级在时间范围内的课堂反馈
+func (c *BehaviorController) GetClassroomFeedbackStats(ctx *gin.Context) {
+	var req api.ClassroomFeedbackFilter
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.feedbackHandler.Stats(ctx, c.teacherMiddleware.ExtractSchoolID(ctx), &req)
+	if err != nil {
+		c.log.Error(ctx, "统计课堂反馈失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, result)
+}
+
+// HandleClassroomFeedback 将课堂反馈标记为已处理
+func (c *BehaviorController) HandleClassroomFeedback(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.HandleClassroomFeedbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	// 只能处理有权限班级的反馈
+	classIDs, err := c.feedbackHandler.GetFeedbackClassIDs(ctx, schoolID, req.FeedbackIDs)
+	if err != nil {
+		c.log.Error(ctx, "查询课堂反馈失败: %v", err)
+		if errors.Is(err, classroom.ErrClassroomFeedbackNotFound) {
+			response.ParamError(ctx, response.ERR_CLASSROOM_FEEDBACK_NOT_FOUND)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	count, err := c.feedbackHandler.Handle(ctx, schoolID, teacherID, req.FeedbackIDs)
+	if err != nil {
+		c.log.Error(ctx, "标记课堂反馈已处理失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, map[string]any{"handledCount": count})
+}
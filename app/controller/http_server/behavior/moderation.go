@@ -0,0 +1,138 @@
+package behavior
+
+import (
+	"errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/model/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListHeldContents 分页查询学校中等待教师审核的会话消息和课堂反馈
+func (c *BehaviorController) ListHeldContents(ctx *gin.Context) {
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.ModerationHeldListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	// 只查询教师有权限的班级的内容
+	classIDs := c.teacherMiddleware.ExtractTeacherClassIDs(ctx)
+	audits, total, err := c.moderator.ListHeld(ctx, schoolID, classIDs, req.ContentType, req.Page, req.PageSize)
+	if err != nil {
+		c.log.Error(ctx, "查询待审核内容失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, &api.ModerationHeldListResponse{
+		List: audits,
+		PageInfo: &consts.ApiPageResponse{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Total:    total,
+		},
+	})
+}
+
+// ListModerationAudits 查询内容的全部审核记录
+func (c *BehaviorController) ListModerationAudits(ctx *gin.Context) {
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.ModerationAuditListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	classIDs := c.teacherMiddleware.ExtractTeacherClassIDs(ctx)
+	audits, err := c.moderator.ListAudits(ctx, schoolID, classIDs, req.ContentType, req.ContentID)
+	if err != nil {
+		c.log.Error(ctx, "查询审核记录失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, &api.ModerationAuditListResponse{List: audits})
+}
+
+// ReviewHeldContent 教师审核暂停展示的内容
+func (c *BehaviorController) ReviewHeldContent(ctx *gin.Context) {
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.ModerationReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	// 只能审核有权限的班级的内容
+	classID, err := c.moderator.HeldClassID(ctx, schoolID, req.ContentType, req.ContentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, behavior.ErrModerationNotHeld):
+			response.ParamError(ctx, response.ERR_MODERATION_NOT_HELD)
+		case errors.Is(err, behavior.ErrModerationNoClass):
+			response.Forbidden(ctx)
+		default:
+			c.log.Error(ctx, "查询待审核内容失败: %v", err)
+			response.SystemError(ctx)
+		}
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, classID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	audit, err := c.moderator.Review(ctx, schoolID, teacherID, &req)
+	if err != nil {
+		if errors.Is(err, behavior.ErrModerationNotHeld) {
+			response.ParamError(ctx, response.ERR_MODERATION_NOT_HELD)
+			return
+		}
+		c.log.Error(ctx, "审核内容失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, audit)
+}
@@ -0,0 +1,55 @@
+package behavior
+
+import (
+	"errors"
+
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/model/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchMessages 在教师任职的班级中全文检索会话消息，指定班级时需要有该班级的权限
+func (c *BehaviorController) SearchMessages(ctx *gin.Context) {
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+
+	var req api.MessageSearchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	classIDs := c.teacherMiddleware.ExtractTeacherClassIDs(ctx)
+	if req.ClassID > 0 {
+		if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+			response.Forbidden(ctx)
+			return
+		}
+		classIDs = []int64{req.ClassID}
+	}
+
+	result, err := c.messageSearcher.Search(ctx, schoolID, classIDs, &req)
+	if err != nil {
+		if errors.Is(err, behavior.ErrMessageSearchUnavailable) {
+			response.SystemError(ctx, response.ERR_ELASTICSEARCH)
+			return
+		}
+		c.log.Error(ctx, "检索会话消息失败: %v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	response.Success(ctx, result)
+}
@@ -0,0 +1,42 @@
+package behavior
+
+import (
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/model/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStudentProfile 查询学生在时间范围内跨课堂的行为画像
+func (c *BehaviorController) GetStudentProfile(ctx *gin.Context) {
+	var req api.StudentProfileRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		c.log.Error(ctx, "参数解析失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "参数无效: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		c.log.Error(ctx, "获取教师ID失败: %v", err)
+		response.Unauthorized(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, req.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	profile, err := c.profileHandler.GetStudentProfile(ctx, schoolID, &req)
+	if err != nil {
+		c.log.Error(ctx, "查询学生行为画像失败, studentID: %d, error: %v", req.StudentID, err)
+		response.Err(ctx, response.ERR_CLICKHOUSE)
+		return
+	}
+	response.Success(ctx, profile)
+}
@@ -0,0 +1,228 @@
+package push
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/core/logger"
+	"gil_teacher/app/domain/classroom"
+	"gil_teacher/app/domain/push"
+	"gil_teacher/app/middleware"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+)
+
+// PushController 实时推送，使用 SSE 向学生端和教师端推送表扬、关注和提醒
+type PushController struct {
+	gateway           *push.PushGateway
+	classroomHandler  *classroom.ClassroomHandler
+	teacherMiddleware *middleware.TeacherMiddleware
+	log               *logger.ContextLogger
+}
+
+func NewPushController(
+	gateway *push.PushGateway,
+	classroomHandler *classroom.ClassroomHandler,
+	teacherMiddleware *middleware.TeacherMiddleware,
+	log *logger.ContextLogger,
+) *PushController {
+	return &PushController{
+		gateway:           gateway,
+		classroomHandler:  classroomHandler,
+		teacherMiddleware: teacherMiddleware,
+		log:               log,
+	}
+}
+
+// TeacherStream 教师端推送连接，同步教师在其他屏幕上的处理结果
+func (c *PushController) TeacherStream(ctx *gin.Context) {
+	query, ok := c.bindStreamQuery(ctx)
+	if !ok {
+		return
+	}
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+	if query.ClassroomID > 0 && !c.checkClassroom(ctx, schoolID, teacherID, query.ClassroomID) {
+		return
+	}
+	c.stream(ctx, consts.CommunicationUserTypeTeacher, teacherID, query)
+}
+
+// 教师只能接收自己开过课的课堂，或有班级权限的课堂的广播，校验失败时已写入响应
+func (c *PushController) checkClassroom(ctx *gin.Context, schoolID, teacherID, classroomID int64) bool {
+	ok, err := c.classroomHandler.CheckTeacherClassroom(ctx, schoolID, teacherID, classroomID)
+	if err != nil {
+		c.log.Error(ctx, "校验课堂失败, classroomID: %d, error: %v", classroomID, err)
+		response.SystemError(ctx)
+		return false
+	}
+	if ok {
+		return true
+	}
+
+	classID, err := c.classroomHandler.GetClassID(ctx, classroomID)
+	if err != nil {
+		c.log.Error(ctx, "校验课堂失败, classroomID: %d, error: %v", classroomID, err)
+		response.SystemError(ctx)
+		return false
+	}
+	if classID == 0 || !c.teacherMiddleware.TeacherHasClassPermission(ctx, classID) {
+		c.log.Warn(ctx, "课堂不存在或教师没有班级权限, classroomID: %d, teacherID: %d", classroomID, teacherID)
+		response.ParamError(ctx, response.ERR_CLASSROOM_NOT_FOUND)
+		return false
+	}
+	return true
+}
+
+// TeacherAck 教师端确认推送消息
+func (c *PushController) TeacherAck(ctx *gin.Context) {
+	var req api.PushAckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Validate() != nil {
+		c.log.Error(ctx, "TeacherAck error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	teacherID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+	c.ack(ctx, consts.CommunicationUserTypeTeacher, teacherID, req.Seq)
+}
+
+// StudentStream 学生端推送连接，接收教师的表扬、关注和作业提醒
+func (c *PushController) StudentStream(ctx *gin.Context) {
+	query, ok := c.bindStreamQuery(ctx)
+	if !ok {
+		return
+	}
+	if query.StudentID <= 0 {
+		response.ParamError(ctx)
+		return
+	}
+	c.stream(ctx, consts.CommunicationUserTypeStudent, query.StudentID, query)
+}
+
+// StudentAck 学生端确认推送消息
+func (c *PushController) StudentAck(ctx *gin.Context) {
+	var req api.PushAckRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.Validate() != nil || req.StudentID <= 0 {
+		c.log.Error(ctx, "StudentAck error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	c.ack(ctx, consts.CommunicationUserTypeStudent, req.StudentID, req.Seq)
+}
+
+func (c *PushController) bindStreamQuery(ctx *gin.Context) (*api.PushStreamQuery, bool) {
+	var query api.PushStreamQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		c.log.Error(ctx, "bindStreamQuery error:%v", err)
+		response.ParamError(ctx)
+		return nil, false
+	}
+	if err := query.Validate(); err != nil {
+		c.log.Error(ctx, "bindStreamQuery error:%v", err)
+		response.ParamError(ctx)
+		return nil, false
+	}
+	// EventSource 自动重连时只会携带 Last-Event-ID
+	if query.LastSeq == 0 {
+		query.LastSeq = utils.Atoi64(ctx.GetHeader("Last-Event-ID"))
+	}
+	return &query, true
+}
+
+func (c *PushController) ack(ctx *gin.Context, userType consts.CommunicationUserType, userID, seq int64) {
+	if err := c.gateway.Ack(ctx, userType, userID, seq); err != nil {
+		c.log.Error(ctx, "ack error:%v", err)
+		response.Err(ctx, response.ERR_REDIS)
+		return
+	}
+	response.Success(ctx, nil)
+}
+
+// 先补发未确认消息，再持续推送新消息，直到客户端断开或连接到期
+func (c *PushController) stream(ctx *gin.Context, userType consts.CommunicationUserType, userID int64, query *api.PushStreamQuery) {
+	conn, pending, err := c.gateway.Connect(ctx, userType, userID, query.ClassroomID, query.LastSeq)
+	if err != nil {
+		c.log.Error(ctx, "stream connect error:%v", err)
+		response.Err(ctx, response.ERR_REDIS)
+		return
+	}
+	defer c.gateway.Disconnect(conn)
+
+	header := ctx.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Status(200)
+
+	if _, err := fmt.Fprintf(ctx.Writer, "retry: %d\n\n", consts.PushStreamRetry); err != nil {
+		return
+	}
+	lastSeq := query.LastSeq
+	for _, message := range pending {
+		if err := writeEvent(ctx, message); err != nil {
+			return
+		}
+		lastSeq = max(lastSeq, message.Seq)
+	}
+	ctx.Writer.Flush()
+
+	// 请求上下文受服务超时限制，按客户端连接是否断开判断
+	closed := ctx.Writer.CloseNotify()
+	heartbeat := time.NewTicker(consts.PushStreamHeartbeat)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(consts.PushStreamMaxDuration)
+	defer deadline.Stop()
+
+	for {
+		var err error
+		select {
+		case <-closed:
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(ctx.Writer, ": ping\n\n")
+		case message := <-conn.Messages():
+			// 注册连接和查询离线消息之间发布的消息会重复收到
+			if message.Seq > 0 && message.Seq <= lastSeq {
+				continue
+			}
+			err = writeEvent(ctx, message)
+			lastSeq = max(lastSeq, message.Seq)
+		}
+		if err != nil {
+			c.log.Debug(ctx, "stream write error, userType:%s, userID:%d, error:%v", userType, userID, err)
+			return
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+// 写入一条 SSE 事件，用户消息以序号作为事件 ID，课堂广播没有事件 ID
+func writeEvent(ctx *gin.Context, message *dto.PushMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if message.Seq > 0 {
+		if _, err := fmt.Fprintf(ctx.Writer, "id: %d\n", message.Seq); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(ctx.Writer, "event: %s\ndata: %s\n\n", message.Type, data)
+	return err
+}
@@ -27,13 +27,14 @@ var (
 	ERR_SYSTEM       = Response{Code: 5000001, Message: "系统开小差，请稍后再试"}
 
 	// 底层模块错误，区分错误码，但错误信息不对外暴露过多
-	ERR_POSTGRESQL   = Response{Code: 2000001, Message: "系统开小差，请稍后再试"}
-	ERR_KAFKA        = Response{Code: 2000002, Message: "系统开小差，请稍后再试"}
-	ERR_REDIS        = Response{Code: 2000003, Message: "系统开小差，请稍后再试"}
-	ERR_GIL_QUESTION = Response{Code: 2000004, Message: "系统开小差，请稍后再试"}
-	ERR_GIL_ADMIN    = Response{Code: 2000005, Message: "系统开小差，请稍后再试"}
-	ERR_VOLC_AI      = Response{Code: 2000006, Message: "系统开小差，请稍后再试"}
-	ERR_CLICKHOUSE   = Response{Code: 2000007, Message: "系统开小差，请稍后再试"}
+	ERR_POSTGRESQL    = Response{Code: 2000001, Message: "系统开小差，请稍后再试"}
+	ERR_KAFKA         = Response{Code: 2000002, Message: "系统开小差，请稍后再试"}
+	ERR_REDIS         = Response{Code: 2000003, Message: "系统开小差，请稍后再试"}
+	ERR_GIL_QUESTION  = Response{Code: 2000004, Message: "系统开小差，请稍后再试"}
+	ERR_GIL_ADMIN     = Response{Code: 2000005, Message: "系统开小差，请稍后再试"}
+	ERR_VOLC_AI       = Response{Code: 2000006, Message: "系统开小差，请稍后再试"}
+	ERR_CLICKHOUSE    = Response{Code: 2000007, Message: "系统开小差，请稍后再试"}
+	ERR_ELASTICSEARCH = Response{Code: 2000008, Message: "系统开小差，请稍后再试"}
 
 	// 业务模块错误
 	ERR_INVALID_PAGE                = Response{Code: 2001001, Message: "请选择正确的页码或每页数量"}
@@ -61,12 +62,34 @@ var (
 	ERR_INVALID_TASK_REPORT_SETTING = Response{Code: 2001022, Message: "任务报告配置错误"}
 	ERR_NO_PERMISSION_TO_MODIFY     = Response{Code: 2001023, Message: "无权限修改配置"}
 	ERR_INVALID_STUDENT             = Response{Code: 2001024, Message: "请选择正确的学生"}
+	ERR_INVALID_EXPORT_FORMAT       = Response{Code: 2001025, Message: "不支持的导出格式"}
+	ERR_EXPORT_JOB_NOT_FOUND        = Response{Code: 2001026, Message: "导出任务不存在"}
+	ERR_EXPORT_JOB_NOT_READY        = Response{Code: 2001027, Message: "导出任务未完成"}
+	ERR_INVALID_TASK_TIER           = Response{Code: 2001028, Message: "请设置正确的作业分层"}
+	ERR_TIER_STUDENT_MISSING        = Response{Code: 2001029, Message: "存在未分层的学生"}
+	ERR_INVALID_WRONG_QUESTION      = Response{Code: 2001030, Message: "请设置正确的错题范围"}
+	ERR_NO_WRONG_QUESTION           = Response{Code: 2001031, Message: "所选学生在该范围内没有错题"}
+	ERR_INVALID_ANSWER_CARD         = Response{Code: 2001032, Message: "请设置正确的答题卡答案"}
+	ERR_ANSWER_CARD_TASK            = Response{Code: 2001033, Message: "任务不是答题卡任务或无权限导入"}
+	ERR_INVALID_ANSWER_CARD_FILE    = Response{Code: 2001034, Message: "导入文件格式错误"}
+	ERR_INVALID_TASK_STATUS         = Response{Code: 2001035, Message: "当前任务状态不支持该操作"}
+	ERR_INVALID_TASK_RECURRENCE     = Response{Code: 2001036, Message: "请设置正确的周期布置规则"}
+	ERR_TASK_RECURRENCE_NOT_FOUND   = Response{Code: 2001037, Message: "周期布置规则不存在"}
+	ERR_TASK_CLONE                  = Response{Code: 2001038, Message: "该任务不支持复制"}
+	ERR_INVALID_ERROR_BOOK_ITEM     = Response{Code: 2001039, Message: "请选择正确的共性错题"}
 
 	// 课堂相关错误
-	ERR_INVALID_CLASSROOM   = Response{Code: 2002001, Message: "请选择正确的课堂"}
-	ERR_EMPTY_CLASSROOM     = Response{Code: 2002002, Message: "请选择课堂"}
-	ERR_CLASSROOM_NOT_FOUND = Response{Code: 2002003, Message: "课堂不存在"}
-	ERR_CLASSROOM_ID_ZERO   = Response{Code: 2002004, Message: "课堂ID不能为0"}
+	ERR_INVALID_CLASSROOM            = Response{Code: 2002001, Message: "请选择正确的课堂"}
+	ERR_EMPTY_CLASSROOM              = Response{Code: 2002002, Message: "请选择课堂"}
+	ERR_CLASSROOM_NOT_FOUND          = Response{Code: 2002003, Message: "课堂不存在"}
+	ERR_CLASSROOM_ID_ZERO            = Response{Code: 2002004, Message: "课堂ID不能为0"}
+	ERR_CLASSROOM_REPORT_NOT_FOUND   = Response{Code: 2002005, Message: "课堂报告不存在"}
+	ERR_CLASSROOM_STATUS             = Response{Code: 2002006, Message: "当前课堂状态不支持该操作"}
+	ERR_CLASSROOM_FEEDBACK_NOT_FOUND = Response{Code: 2002007, Message: "课堂反馈不存在"}
+	ERR_BEHAVIOR_CONTEXT             = Response{Code: 2002008, Message: "行为上下文格式不正确"}
+	ERR_SESSION_MESSAGE_NOT_FOUND    = Response{Code: 2002009, Message: "会话消息不存在"}
+	ERR_AI_ANSWER_NOT_FOUND          = Response{Code: 2002010, Message: "AI 回答不存在"}
+	ERR_MODERATION_NOT_HELD          = Response{Code: 2002011, Message: "内容不在待审核状态"}
 )
 
 // Success 成功响应
@@ -3,6 +3,7 @@ package route
 import (
 	"gil_teacher/app/controller/http"
 	"gil_teacher/app/controller/http_server/behavior"
+	"gil_teacher/app/controller/http_server/push"
 	"gil_teacher/app/controller/http_server/resource_favorite"
 	"gil_teacher/app/controller/http_server/schedule"
 	controller_task "gil_teacher/app/controller/http_server/task"
@@ -24,6 +25,7 @@ type HttpRouter struct {
 	behavior          *behavior.BehaviorController
 	schedule          *schedule.ScheduleController
 	taskReport        *controller_task.TaskReportController
+	push              *push.PushController
 	teacherMiddleware *middleware.TeacherMiddleware
 }
 
@@ -37,6 +39,7 @@ func NewHttpRouter(
 	resourceFavorite *resource_favorite.ResourceFavoriteController,
 	behavior *behavior.BehaviorController,
 	schedule *schedule.ScheduleController,
+	push *push.PushController,
 	teacherMiddleware *middleware.TeacherMiddleware,
 ) *HttpRouter {
 	return &HttpRouter{
@@ -49,6 +52,7 @@ func NewHttpRouter(
 		behavior:          behavior,
 		schedule:          schedule,
 		taskReport:        taskReport,
+		push:              push,
 		teacherMiddleware: teacherMiddleware,
 	}
 }
@@ -76,7 +80,10 @@ func (hr *HttpRouter) InitRouter(r *gin.Engine) *gin.Engine {
 		}
 		// 暴露给学生端的内部接口
 		{
-			internalGroup.POST("/student/task/list", hr.task.GetStudentTaskList) // 查询学生的任务列表
+			internalGroup.POST("/student/task/list", hr.task.GetStudentTaskList)                          // 查询学生的任务列表
+			internalGroup.GET("/student/push/stream", hr.push.StudentStream)                              // 学生端实时推送连接（SSE）
+			internalGroup.POST("/student/push/ack", hr.push.StudentAck)                                   // 学生端确认推送消息
+			internalGroup.POST("/student/classroom/feedback", hr.behavior.StudentSubmitClassroomFeedback) // 学生提交课堂反馈
 		}
 	}
 
@@ -125,12 +132,22 @@ func (hr *HttpRouter) InitRouter(r *gin.Engine) *gin.Engine {
 			taskGroup.POST("/question/list/search", hr.task.GetQuestionList)           // 搜索题目列表
 			taskGroup.POST("/question/detail", hr.task.GetQuestionListByIDs)           // 通过ID列表查询题目详情
 
-			taskGroup.GET("/management/detail", hr.task.GetTaskByID)              // 根据 ID 查询单个任务
-			taskGroup.POST("/management/create", hr.task.CreateTask)              // 创建任务
-			taskGroup.POST("/management/update", hr.task.UpdateTask)              // 更新任务名称
-			taskGroup.POST("/management/delete", hr.task.DeleteTask)              // 删除任务
-			taskGroup.POST("/management/assign/update", hr.task.UpdateTaskAssign) // 更新任务分配的时间
-			taskGroup.POST("/management/assign/delete", hr.task.DeleteTaskAssign) // 删除任务分配
+			taskGroup.GET("/management/detail", hr.task.GetTaskByID)                             // 根据 ID 查询单个任务
+			taskGroup.POST("/management/create", hr.task.CreateTask)                             // 创建任务
+			taskGroup.POST("/management/wrong-question/create", hr.task.CreateWrongQuestionTask) // 按历史错题创建错题作业
+			taskGroup.POST("/management/clone", hr.task.CloneTask)                               // 复制任务并布置给其他班级
+			taskGroup.POST("/management/error-book/push", hr.task.PushErrorBookTask)             // 将班级共性错题推送到新的作业任务
+			taskGroup.POST("/management/update", hr.task.UpdateTask)                             // 更新任务名称
+			taskGroup.POST("/management/delete", hr.task.DeleteTask)                             // 删除任务
+			taskGroup.POST("/management/assign/update", hr.task.UpdateTaskAssign)                // 更新任务分配的时间
+			taskGroup.POST("/management/assign/delete", hr.task.DeleteTaskAssign)                // 删除任务分配
+			taskGroup.GET("/management/unpublished/list", hr.task.GetUnpublishedTaskList)        // 查询草稿或定时发布的任务列表
+			taskGroup.POST("/management/draft/update", hr.task.UpdateDraft)                      // 编辑草稿的名称、留言和资源
+			taskGroup.POST("/management/publish", hr.task.PublishTask)                           // 发布草稿，立即发布或定时发布
+			taskGroup.POST("/management/archive", hr.task.ArchiveTask)                           // 归档任务
+			taskGroup.GET("/management/recurrence/list", hr.task.GetTaskRecurrenceList)          // 查询任务的周期布置规则
+			taskGroup.POST("/management/recurrence/create", hr.task.CreateTaskRecurrence)        // 创建周期布置规则
+			taskGroup.POST("/management/recurrence/stop", hr.task.StopTaskRecurrence)            // 停止周期布置规则
 		}
 
 		// 临时选择（试题篮、资源篮）相关路由
@@ -159,13 +176,16 @@ func (hr *HttpRouter) InitRouter(r *gin.Engine) *gin.Engine {
 		// 会话相关
 		sessionGroup := authorized.Group("/session")
 		{
-			sessionGroup.POST("/open", hr.behavior.OpenSession)                  // 创建会话
-			sessionGroup.POST("/message", hr.behavior.SaveMessage)               // 记录会话内容
-			sessionGroup.POST("/close", hr.behavior.CloseSession)                // 关闭会话
-			sessionGroup.GET("/messages", hr.behavior.GetSessionMessages)        // 查询指定会话的全部消息
-			sessionGroup.POST("/message/read", hr.behavior.MarkMessageAsRead)    // 标记消息已读（最后一条消息id）
-			sessionGroup.GET("/unread-count", hr.behavior.GetUnreadMessageCount) // 获取用户指定会话的未读消息数量
-			sessionGroup.GET("/unread-list", hr.behavior.GetUnreadMessageList)   // 获取用户指定会话的未读消息列表
+			sessionGroup.POST("/open", hr.behavior.OpenSession)                    // 创建会话
+			sessionGroup.POST("/message", hr.behavior.SaveMessage)                 // 记录会话内容
+			sessionGroup.POST("/close", hr.behavior.CloseSession)                  // 关闭会话
+			sessionGroup.GET("/messages", hr.behavior.GetSessionMessages)          // 查询指定会话的全部消息
+			sessionGroup.POST("/message/read", hr.behavior.MarkMessageAsRead)      // 标记消息已读（最后一条消息id）
+			sessionGroup.GET("/unread-count", hr.behavior.GetUnreadMessageCount)   // 获取用户指定会话的未读消息数量
+			sessionGroup.GET("/unread-list", hr.behavior.GetUnreadMessageList)     // 获取用户指定会话的未读消息列表
+			sessionGroup.GET("/inbox", hr.behavior.GetSessionInbox)                // 获取用户参与的全部会话和未读数
+			sessionGroup.GET("/ai-answers", hr.behavior.GetAIAnswers)              // 获取任务布置下 AI 答疑的回答和转交记录
+			sessionGroup.POST("/ai-answer/override", hr.behavior.OverrideAIAnswer) // 教师修正 AI 回答
 		}
 		// 行为相关路由
 		behaviorGroup := authorized.Group("/behavior") // 行为相关路由
@@ -176,31 +196,70 @@ func (hr *HttpRouter) InitRouter(r *gin.Engine) *gin.Engine {
 			behaviorGroup.GET("/classroom/messages", hr.behavior.GetClassroomMessages)                // 查询指定课堂的全部消息
 			behaviorGroup.GET("/class/latest-behaviors", hr.behavior.GetClassLatestBehaviors)         // 获取班级学生最新行为
 			behaviorGroup.GET("/student/classroom-detail", hr.behavior.GetStudentClassroomDetail)     // 获取学生课堂详情
+			behaviorGroup.GET("/student/profile", hr.behavior.GetStudentProfile)                      // 获取学生跨课堂行为画像
 			behaviorGroup.GET("/class/behavior-category", hr.behavior.GetClassBehaviorCategory)       // 获取课堂行为分类列表
 			behaviorGroup.GET("/classroom/behavior-summary", hr.behavior.GetClassroomBehaviorSummary) // 获取课后行为汇总统计
+			behaviorGroup.GET("/classroom/reports", hr.behavior.ListClassroomReports)                 // 查询班级历史课堂报告
+			behaviorGroup.GET("/classroom/report", hr.behavior.GetClassroomReport)                    // 查询课堂报告详情
 			behaviorGroup.POST("/praise", hr.behavior.PraiseStudents)                                 // 表扬学生
 			behaviorGroup.POST("/attention", hr.behavior.AttentionStudents)                           // 关注学生
 			behaviorGroup.GET("/classroom/learning-scores", hr.behavior.GetClassroomLearningScores)   // 获取课堂学习分列表
+			behaviorGroup.POST("/rules/dry-run", hr.behavior.DryRunBehaviorRules)                     // 行为规则试运行
+			behaviorGroup.POST("/classroom/start", hr.behavior.StartClassroom)                        // 开始上课，暂停中的课堂继续上课
+			behaviorGroup.POST("/classroom/pause", hr.behavior.PauseClassroom)                        // 暂停上课
+			behaviorGroup.POST("/classroom/end", hr.behavior.EndClassroom)                            // 结束上课
+			behaviorGroup.POST("/classroom/feedback", hr.behavior.SubmitClassroomFeedback)            // 教师提交课堂反馈
+			behaviorGroup.GET("/classroom/feedback/list", hr.behavior.ListClassroomFeedback)          // 按条件查询班级的课堂反馈
+			behaviorGroup.GET("/classroom/feedback/stats", hr.behavior.GetClassroomFeedbackStats)     // 汇总班级的课堂反馈
+			behaviorGroup.POST("/classroom/feedback/handle", hr.behavior.HandleClassroomFeedback)     // 标记课堂反馈已处理
+		}
+
+		// 内容审核
+		moderationGroup := authorized.Group("/moderation")
+		{
+			moderationGroup.GET("/held", hr.behavior.ListHeldContents)       // 查询等待教师审核的内容
+			moderationGroup.GET("/audits", hr.behavior.ListModerationAudits) // 查询内容的审核记录
+			moderationGroup.POST("/review", hr.behavior.ReviewHeldContent)   // 教师审核暂停展示的内容
+		}
+
+		// 会话消息检索
+		searchGroup := authorized.Group("/search")
+		{
+			searchGroup.GET("/messages", hr.behavior.SearchMessages) // 在任职班级中检索会话消息和学生提问
+		}
+
+		// 实时推送
+		pushGroup := authorized.Group("/push")
+		{
+			pushGroup.GET("/stream", hr.push.TeacherStream) // 教师端实时推送连接（SSE）
+			pushGroup.POST("/ack", hr.push.TeacherAck)      // 教师端确认推送消息
 		}
 
 		// 报告相关
 		taskReportGroup := authorized.Group("/task/report")
 		{
-			taskReportGroup.GET("/subject-class/list", hr.taskReport.GetSubjectClassList)    // 获取教师查看作业报告时具备的学科和班级列表
-			taskReportGroup.GET("/latest", hr.taskReport.LatestReport)                       // 查询每个任务类型最近布置的单个作业报告(指定教师)
-			taskReportGroup.GET("/list", hr.taskReport.ListReports)                          // 查询作业报告列表(指定教师)
-			taskReportGroup.GET("/detail", hr.taskReport.GetReportSummaryDetail)             // 查询任务的作业汇总报告(指定班级或小组)
-			taskReportGroup.GET("/answers", hr.taskReport.GetAnswers)                        // 查询任务的作业答题结果(指定班级或小组)
-			taskReportGroup.GET("/export", hr.taskReport.ExportReport)                       // 导出作业报告
-			taskReportGroup.GET("/answer-panel", hr.taskReport.GetAnswerPanel)               // 题目面板，每个题目的正确率，方便老师查看和直接点击跳转
-			taskReportGroup.GET("/suggestion-template", hr.taskReport.GetSuggestionTemplate) // 获取建议消息模板，目前前端写死了
-			taskReportGroup.GET("/student/answers", hr.taskReport.GetStudentAnswers)         // 获取任务指定学生的全部答题结果
-			taskReportGroup.POST("/student/handle", hr.taskReport.HandleStudentReport)       // 对学生作业报告的处理，目前只有：点赞、提醒
-			taskReportGroup.GET("/student/detail", hr.taskReport.GetStudentDetail)           // 作业/点击学生头像/学生详情
-			taskReportGroup.GET("/questions", hr.taskReport.GetQuestions)                    // 查询任务的全部提问记录(指定班级或小组) TODO
-			taskReportGroup.GET("/knowledge/questions", hr.taskReport.GetKnowledgeQuestions) // 获取任务单个知识点的全部提问记录 TODO
-			taskReportGroup.GET("/setting", hr.taskReport.GetReportSetting)                  // 获取报告参数设置
-			taskReportGroup.POST("/setting/update", hr.taskReport.UpdateReportSetting)       // 更新或设置报告参数设置
+			taskReportGroup.GET("/subject-class/list", hr.taskReport.GetSubjectClassList)          // 获取教师查看作业报告时具备的学科和班级列表
+			taskReportGroup.GET("/latest", hr.taskReport.LatestReport)                             // 查询每个任务类型最近布置的单个作业报告(指定教师)
+			taskReportGroup.GET("/list", hr.taskReport.ListReports)                                // 查询作业报告列表(指定教师)
+			taskReportGroup.GET("/detail", hr.taskReport.GetReportSummaryDetail)                   // 查询任务的作业汇总报告(指定班级或小组)
+			taskReportGroup.GET("/answers", hr.taskReport.GetAnswers)                              // 查询任务的作业答题结果(指定班级或小组)
+			taskReportGroup.GET("/export", hr.taskReport.ExportReport)                             // 导出作业报告
+			taskReportGroup.POST("/export/job", hr.taskReport.SubmitExportJob)                     // 提交异步导出任务，支持多个布置打包导出
+			taskReportGroup.GET("/export/job", hr.taskReport.GetExportJob)                         // 查询异步导出任务状态
+			taskReportGroup.GET("/export/job/url", hr.taskReport.GetExportJobURL)                  // 获取异步导出文件的下载地址
+			taskReportGroup.POST("/answer-card/import", hr.taskReport.ImportAnswerCard)            // 上传 csv/xlsx 文件导入答题卡任务的线下作答
+			taskReportGroup.POST("/answer-card/import/batch", hr.taskReport.ImportAnswerCardBatch) // 批量导入答题卡任务的线下作答
+			taskReportGroup.GET("/answer-panel", hr.taskReport.GetAnswerPanel)                     // 题目面板，每个题目的正确率，方便老师查看和直接点击跳转
+			taskReportGroup.GET("/suggestion-template", hr.taskReport.GetSuggestionTemplate)       // 获取建议消息模板，目前前端写死了
+			taskReportGroup.GET("/student/answers", hr.taskReport.GetStudentAnswers)               // 获取任务指定学生的全部答题结果
+			taskReportGroup.POST("/student/handle", hr.taskReport.HandleStudentReport)             // 对学生作业报告的处理，目前只有：点赞、提醒
+			taskReportGroup.GET("/student/detail", hr.taskReport.GetStudentDetail)                 // 作业/点击学生头像/学生详情
+			taskReportGroup.GET("/questions", hr.taskReport.GetQuestions)                          // 查询任务的全部提问记录(指定班级或小组)
+			taskReportGroup.GET("/knowledge/questions", hr.taskReport.GetKnowledgeQuestions)       // 获取任务单个知识点的全部提问记录
+			taskReportGroup.GET("/setting", hr.taskReport.GetReportSetting)                        // 获取报告参数设置
+			taskReportGroup.POST("/setting/update", hr.taskReport.UpdateReportSetting)             // 更新或设置报告参数设置
+			taskReportGroup.GET("/error-book", hr.taskReport.GetErrorBook)                         // 按知识点浏览班级共性错题本
+			taskReportGroup.POST("/error-book/explain", hr.taskReport.MarkErrorBookExplained)      // 标记或取消标记共性错题已讲解
 		}
 	}
 
@@ -2,15 +2,13 @@ package controller_task
 
 // 注意：此文件需要 Go 1.22 或更高版本
 // 主要依赖：
-// - encoding/csv: 用于 CSV 文件生成
 // - net/url: 用于文件名 URL 编码
-// - bytes: 用于缓冲区操作
 // - fmt: 用于字符串格式化
 
 import (
-	"bytes"
-	"encoding/csv"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -31,6 +29,9 @@ import (
 // TaskReportController 作业报告控制器
 type TaskReportController struct {
 	taskReportHandler *task.TaskReportHandler
+	exportJobHandler  *task.TaskExportJobHandler
+	answerCardHandler *task.AnswerCardHandler
+	errorBookHandler  *task.ClassErrorBookHandler
 	log               *logger.ContextLogger
 	teacherMiddleware *middleware.TeacherMiddleware
 	producer          *behavior.BehaviorProducer
@@ -38,12 +39,18 @@ type TaskReportController struct {
 
 func NewTaskReportController(
 	taskReportHandler *task.TaskReportHandler,
+	exportJobHandler *task.TaskExportJobHandler,
+	answerCardHandler *task.AnswerCardHandler,
+	errorBookHandler *task.ClassErrorBookHandler,
 	teacherMiddleware *middleware.TeacherMiddleware,
 	log *logger.ContextLogger,
 	producer *behavior.BehaviorProducer,
 ) *TaskReportController {
 	return &TaskReportController{
 		taskReportHandler: taskReportHandler,
+		exportJobHandler:  exportJobHandler,
+		answerCardHandler: answerCardHandler,
+		errorBookHandler:  errorBookHandler,
 		teacherMiddleware: teacherMiddleware,
 		log:               log,
 		producer:          producer,
@@ -231,7 +238,7 @@ func (c *TaskReportController) GetAnswers(ctx *gin.Context) {
 	response.Success(ctx, answers)
 }
 
-// ExportReport 导出作业报告，csv 格式
+// ExportReport 导出作业报告，支持 csv（默认）和 xlsx 格式
 // 导出指定任务指定班级/小组的作业报告，可指定课程资源
 func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 	// TODO 权限检查
@@ -244,6 +251,13 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 		return
 	}
 
+	format := ctx.DefaultQuery("format", consts.ExportFormatCSV)
+	if format != consts.ExportFormatCSV && format != consts.ExportFormatXLSX {
+		c.log.Error(ctx, "invalid export format: %s", format)
+		response.ParamError(ctx, response.ERR_INVALID_EXPORT_FORMAT)
+		return
+	}
+
 	// 选择的导出字段
 	var exportFields []string
 	fields := ctx.Query("fields")
@@ -259,7 +273,7 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 	}
 
 	sortBy, sortType := consts.SortHandler(ctx.Query("sortBy"), consts.SortType(ctx.Query("sortType")))
-	reportName, report, err := c.taskReportHandler.ExportTaskReport(ctx, &dto.ExportTaskReportQuery{
+	query := &dto.ExportTaskReportQuery{
 		TaskID:       taskId,
 		AssignID:     assignId,
 		ResourceID:   ctx.Query("resourceId"),
@@ -267,10 +281,11 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 		SortBy:       sortBy,
 		SortType:     sortType,
 		Fields:       exportFields,
-	})
+	}
 
+	fileName, content, err := c.taskReportHandler.ExportTaskReportFile(ctx, query, format)
 	if err != nil {
-		c.log.Error(ctx, "ExportTaskReport error:%v", err)
+		c.log.Error(ctx, "ExportTaskReport error:%v, format:%s", err, format)
 		response.Error(ctx, http.StatusBadRequest, response.Response{
 			Code:    response.ERR_SYSTEM.Code,
 			Message: "导出作业报告失败: " + err.Error(),
@@ -278,52 +293,17 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 		return
 	}
 
-	// 使用 bytes.Buffer 构建 CSV 内容
-	var buf bytes.Buffer
-	writer := csv.NewWriter(&buf)
-
-	// 写入 UTF-8 BOM
-	buf.Write([]byte{0xEF, 0xBB, 0xBF})
-
-	// 写入表头
-	if err := writer.Write(report.Meta); err != nil {
-		c.log.Error(ctx, "Write CSV header error:%v", err)
-		response.Error(ctx, http.StatusInternalServerError, response.Response{
-			Code:    response.ERR_SYSTEM.Code,
-			Message: "导出作业报告失败: " + err.Error(),
-		})
-		return
-	}
-
-	// 写入数据行
-	for _, row := range report.Data {
-		if err := writer.Write(row); err != nil {
-			c.log.Error(ctx, "Write CSV row error:%v", err)
-			response.Error(ctx, http.StatusInternalServerError, response.Response{
-				Code:    response.ERR_SYSTEM.Code,
-				Message: "导出作业报告失败: " + err.Error(),
-			})
-			return
-		}
-	}
-
-	// 刷新缓冲区
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		c.log.Error(ctx, "Flush CSV writer error:%v", err)
-		response.Error(ctx, http.StatusInternalServerError, response.Response{
-			Code:    response.ERR_SYSTEM.Code,
-			Message: "导出作业报告失败: " + err.Error(),
-		})
-		return
+	contentType := "text/csv; charset=utf-8"
+	if format == consts.ExportFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	}
 
 	// 设置响应头
-	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Type", contentType)
 
 	// -- 文件名处理开始 --
 	// 原始文件名 (包含中文)
-	originalFilename := reportName + ".csv"
+	originalFilename := fileName
 	// URL 编码后的文件名
 	encodedName := url.QueryEscape(originalFilename)
 
@@ -338,7 +318,7 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 	ctx.Header("Expires", "0")
 
 	// 写入响应
-	if _, err := ctx.Writer.Write(buf.Bytes()); err != nil {
+	if _, err := ctx.Writer.Write(content); err != nil {
 		c.log.Error(ctx, "Write response error:%v", err)
 		response.Error(ctx, http.StatusInternalServerError, response.Response{
 			Code:    response.ERR_SYSTEM.Code,
@@ -350,6 +330,195 @@ func (c *TaskReportController) ExportReport(ctx *gin.Context) {
 	ctx.Status(http.StatusOK)
 }
 
+// SubmitExportJob 提交异步导出任务，多个布置时打包为 zip，完成后通过 GetExportJobURL 获取下载地址
+func (c *TaskReportController) SubmitExportJob(ctx *gin.Context) {
+	var req api.ExportJobSubmitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "SubmitExportJob error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "SubmitExportJob error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	// 导出的布置必须属于当前学校，且教师有布置班级的权限
+	classIDs, err := c.exportJobHandler.ResolveItemClassIDs(ctx, teacherID, schoolID, req.Items)
+	if err != nil {
+		c.exportJobError(ctx, "SubmitExportJob", 0, err)
+		return
+	}
+	if len(classIDs) > 0 && !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	job, err := c.exportJobHandler.Submit(ctx, teacherID, schoolID, &req)
+	if err != nil {
+		c.log.Error(ctx, "SubmitExportJob error:%v", err)
+		response.SystemError(ctx)
+		return
+	}
+	response.Success(ctx, job)
+}
+
+// GetExportJob 查询异步导出任务状态
+func (c *TaskReportController) GetExportJob(ctx *gin.Context) {
+	jobID := utils.Atoi64(ctx.Query("jobId"))
+	if jobID == 0 {
+		response.ParamError(ctx, response.ERR_EXPORT_JOB_NOT_FOUND)
+		return
+	}
+
+	teacherID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	job, err := c.exportJobHandler.GetJob(ctx, teacherID, jobID)
+	if err != nil {
+		c.exportJobError(ctx, "GetExportJob", jobID, err)
+		return
+	}
+	response.Success(ctx, job)
+}
+
+// GetExportJobURL 获取异步导出文件的预签名下载地址
+func (c *TaskReportController) GetExportJobURL(ctx *gin.Context) {
+	jobID := utils.Atoi64(ctx.Query("jobId"))
+	if jobID == 0 {
+		response.ParamError(ctx, response.ERR_EXPORT_JOB_NOT_FOUND)
+		return
+	}
+
+	teacherID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	downloadURL, err := c.exportJobHandler.GetDownloadURL(ctx, teacherID, jobID)
+	if err != nil {
+		c.exportJobError(ctx, "GetExportJobURL", jobID, err)
+		return
+	}
+	response.Success(ctx, downloadURL)
+}
+
+func (c *TaskReportController) exportJobError(ctx *gin.Context, method string, jobID int64, err error) {
+	c.log.Error(ctx, "%s error:%v, jobID:%d", method, err, jobID)
+	switch {
+	case errors.Is(err, task.ErrExportJobNotFound):
+		response.Err(ctx, response.ERR_EXPORT_JOB_NOT_FOUND)
+	case errors.Is(err, task.ErrExportJobNotReady):
+		response.Err(ctx, response.ERR_EXPORT_JOB_NOT_READY)
+	case errors.Is(err, task.ErrExportItemInvalid):
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+	case errors.Is(err, task.ErrExportItemForbidden):
+		response.Forbidden(ctx)
+	default:
+		response.SystemError(ctx)
+	}
+}
+
+// ImportAnswerCard 上传 csv/xlsx 文件导入答题卡任务的线下作答
+// 表单参数 taskId、assignId、file，文件每行第一列为学生ID，之后按题号顺序为作答
+func (c *TaskReportController) ImportAnswerCard(ctx *gin.Context) {
+	taskID := utils.Atoi64(ctx.PostForm("taskId"))
+	assignID := utils.Atoi64(ctx.PostForm("assignId"))
+	if taskID == 0 || assignID == 0 {
+		response.ParamError(ctx, response.ERR_EMPTY_TASK_OR_ASSIGN)
+		return
+	}
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil || fileHeader.Size == 0 || fileHeader.Size > consts.AnswerCardImportMaxSize {
+		c.log.Error(ctx, "ImportAnswerCard invalid file, error:%v", err)
+		response.ParamError(ctx, response.ERR_INVALID_ANSWER_CARD_FILE)
+		return
+	}
+
+	teacherID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.log.Error(ctx, "ImportAnswerCard open file error:%v", err)
+		response.SystemError(ctx)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.log.Error(ctx, "ImportAnswerCard read file error:%v", err)
+		response.SystemError(ctx)
+		return
+	}
+
+	answers, rowNos, err := c.answerCardHandler.ParseFile(fileHeader.Filename, content)
+	if err != nil {
+		c.answerCardError(ctx, "ImportAnswerCard", taskID, err)
+		return
+	}
+	result, err := c.answerCardHandler.Import(ctx, teacherID, taskID, assignID, answers, rowNos)
+	if err != nil {
+		c.answerCardError(ctx, "ImportAnswerCard", taskID, err)
+		return
+	}
+	response.Success(ctx, result)
+}
+
+// ImportAnswerCardBatch 批量导入答题卡任务的线下作答
+func (c *TaskReportController) ImportAnswerCardBatch(ctx *gin.Context) {
+	var req api.AnswerCardImportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "ImportAnswerCardBatch error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "ImportAnswerCardBatch error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	teacherID, _, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.answerCardHandler.Import(ctx, teacherID, req.TaskID, req.AssignID, req.Answers, nil)
+	if err != nil {
+		c.answerCardError(ctx, "ImportAnswerCardBatch", req.TaskID, err)
+		return
+	}
+	response.Success(ctx, result)
+}
+
+func (c *TaskReportController) answerCardError(ctx *gin.Context, method string, taskID int64, err error) {
+	c.log.Error(ctx, "%s error:%v, taskID:%d", method, err, taskID)
+	switch {
+	case errors.Is(err, task.ErrAnswerCardTask):
+		response.ParamError(ctx, response.ERR_ANSWER_CARD_TASK)
+	case errors.Is(err, task.ErrAnswerCardFile):
+		response.ParamError(ctx, response.ERR_INVALID_ANSWER_CARD_FILE)
+	default:
+		response.SystemError(ctx)
+	}
+}
+
 // GetAnswerPanel 获取作业题目面板
 func (c *TaskReportController) GetAnswerPanel(ctx *gin.Context) {
 	// TODO 权限检查
@@ -391,12 +560,67 @@ func (c *TaskReportController) GetSuggestionTemplate(ctx *gin.Context) {
 
 // GetQuestions 查询作业提问记录
 func (c *TaskReportController) GetQuestions(ctx *gin.Context) {
+	var query api.TaskQuestionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		c.log.Error(ctx, "GetQuestions error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := query.Validate(); err != nil {
+		c.log.Error(ctx, "GetQuestions error:%v", err)
+		response.ParamError(ctx, response.ERR_EMPTY_TASK_OR_ASSIGN)
+		return
+	}
 
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.taskReportHandler.GetTaskQuestions(ctx, schoolID, &query)
+	if err != nil {
+		c.taskQuestionsError(ctx, "GetQuestions", query.TaskID, err)
+		return
+	}
+	response.Success(ctx, result)
 }
 
 // GetKnowledgeQuestions 获取作业单个知识点的全部提问记录
 func (c *TaskReportController) GetKnowledgeQuestions(ctx *gin.Context) {
+	var query api.TaskQuestionsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		c.log.Error(ctx, "GetKnowledgeQuestions error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := query.Validate(); err != nil {
+		c.log.Error(ctx, "GetKnowledgeQuestions error:%v", err)
+		response.ParamError(ctx, response.ERR_EMPTY_TASK_OR_ASSIGN)
+		return
+	}
 
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.taskReportHandler.GetKnowledgeQuestions(ctx, schoolID, &query)
+	if err != nil {
+		c.taskQuestionsError(ctx, "GetKnowledgeQuestions", query.TaskID, err)
+		return
+	}
+	response.Success(ctx, result)
+}
+
+func (c *TaskReportController) taskQuestionsError(ctx *gin.Context, method string, taskID int64, err error) {
+	c.log.Error(ctx, "%s error:%v, taskID:%d", method, err, taskID)
+	if errors.Is(err, task.ErrTaskQuestionReport) {
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+		return
+	}
+	response.SystemError(ctx)
 }
 
 // GetStudentAnswers 获取作业指定学生的全部答题结果
@@ -494,6 +718,9 @@ func (c *TaskReportController) HandleStudentReport(ctx *gin.Context) {
 			return
 		}
 	}
+
+	// 实时推送给学生端
+	c.producer.PushStudentReportHandled(ctx, teacherID, &req)
 	response.Success(ctx, nil)
 }
 
@@ -573,3 +800,86 @@ func (c *TaskReportController) UpdateReportSetting(ctx *gin.Context) {
 	}
 	response.Success(ctx, nil)
 }
+
+// GetErrorBook 按知识点浏览班级共性错题本
+func (c *TaskReportController) GetErrorBook(ctx *gin.Context) {
+	var query api.ClassErrorBookQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		c.log.Error(ctx, "GetErrorBook error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := query.Validate(); err != nil {
+		c.log.Error(ctx, "GetErrorBook error:%v", err)
+		response.ParamError(ctx, response.ERR_INVALID_CLASS_OR_SUBJECT)
+		return
+	}
+
+	_, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, query.ClassID) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.errorBookHandler.GetErrorBook(ctx, schoolID, &query)
+	if err != nil {
+		c.log.Error(ctx, "GetErrorBook error:%v", err)
+		response.SystemError(ctx)
+		return
+	}
+	response.Success(ctx, result)
+}
+
+// MarkErrorBookExplained 标记或取消标记共性错题已讲解
+func (c *TaskReportController) MarkErrorBookExplained(ctx *gin.Context) {
+	var req api.ErrorBookExplainRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		c.log.Error(ctx, "MarkErrorBookExplained error:%v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.log.Error(ctx, "MarkErrorBookExplained error:%v", err)
+		response.ParamError(ctx, response.ERR_INVALID_ERROR_BOOK_ITEM)
+		return
+	}
+
+	teacherID, schoolID, err := c.teacherMiddleware.GetTeacherIDInfo(ctx)
+	if err != nil {
+		response.Forbidden(ctx)
+		return
+	}
+
+	items, err := c.errorBookHandler.GetItemsByIDs(ctx, schoolID, req.ItemIDs)
+	if err != nil {
+		c.log.Error(ctx, "MarkErrorBookExplained error:%v", err)
+		if errors.Is(err, task.ErrInvalidErrorBookItem) {
+			response.ParamError(ctx, response.ERR_INVALID_ERROR_BOOK_ITEM)
+			return
+		}
+		response.SystemError(ctx)
+		return
+	}
+
+	// 只能标记有权限的班级的共性错题
+	classIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		classIDs = append(classIDs, item.ClassID)
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	result, err := c.errorBookHandler.MarkExplained(ctx, schoolID, teacherID, items, req.Explained)
+	if err != nil {
+		c.log.Error(ctx, "MarkErrorBookExplained error:%v", err)
+		response.SystemError(ctx)
+		return
+	}
+	response.Success(ctx, result)
+}
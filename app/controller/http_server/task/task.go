@@ -1,10 +1,13 @@
 package controller_task
 
 import (
+	"errors"
+
 	"gil_teacher/app/consts"
 	"gil_teacher/app/controller/http_server/response"
 	"gil_teacher/app/core/logger"
 	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/domain/task"
 	"gil_teacher/app/middleware"
 	"gil_teacher/app/model/api"
 	"gil_teacher/app/model/dto"
@@ -23,6 +26,9 @@ type TaskController struct {
 	log                 *logger.ContextLogger
 	taskService         *task_service.TaskService
 	taskResourceService *task_service.TaskResourceService
+	wrongQuestion       *task_service.WrongQuestionService
+	taskSchedule        *task.TaskScheduleHandler
+	classErrorBook      *task.ClassErrorBookHandler
 	questionAPI         *question_service.Client
 	ucenterService      *admin_service.UcenterClient
 	teacherMiddleware   *middleware.TeacherMiddleware
@@ -34,6 +40,9 @@ func NewTaskController(
 	log *logger.ContextLogger,
 	taskService *task_service.TaskService,
 	taskResourceService *task_service.TaskResourceService,
+	wrongQuestion *task_service.WrongQuestionService,
+	taskSchedule *task.TaskScheduleHandler,
+	classErrorBook *task.ClassErrorBookHandler,
 	questionAPI *question_service.Client,
 	ucenterService *admin_service.UcenterClient,
 	teacherMiddleware *middleware.TeacherMiddleware,
@@ -43,6 +52,9 @@ func NewTaskController(
 		log:                 log,
 		taskService:         taskService,
 		taskResourceService: taskResourceService,
+		wrongQuestion:       wrongQuestion,
+		taskSchedule:        taskSchedule,
+		classErrorBook:      classErrorBook,
 		questionAPI:         questionAPI,
 		ucenterService:      ucenterService,
 		teacherMiddleware:   teacherMiddleware,
@@ -127,6 +139,16 @@ func (c *TaskController) GetTaskByID(ctx *gin.Context) {
 		return
 	}
 	res.Resources = resources
+	// 分层作业查询任务的分层
+	if task.TaskSubType == consts.TASK_TYPE_HOMEWORK_LAYERED {
+		tiers, err := c.taskService.GetTaskTiers(ctx, taskID)
+		if err != nil {
+			c.log.Error(ctx, "获取任务分层失败: %v", err)
+			response.Err(ctx, response.ERR_POSTGRESQL)
+			return
+		}
+		res.Tiers = tiers
+	}
 	// 查询任务的分配
 	assigns, err := c.taskService.GetTaskAssignsByTaskIDs(ctx, []int64{taskID})
 	if err != nil {
@@ -189,6 +211,10 @@ func (c *TaskController) GetStudentTaskList(ctx *gin.Context) {
 		resources := resources[task.Task.TaskID]
 		result.List[i].Resources = make([]*dao_task.TaskResource, 0, len(resources))
 		for _, resource := range resources {
+			// 分层作业只返回共用资源和学生所在分层的资源，错题作业只返回学生自己的资源
+			if !resource.VisibleTo(task.TierNo, reqBody.StudentID) {
+				continue
+			}
 			result.List[i].Resources = append(result.List[i].Resources, resource)
 		}
 	}
@@ -225,43 +251,13 @@ func (c *TaskController) CreateTask(ctx *gin.Context) {
 		return
 	}
 
-	// 检查教师是否具备班级的权限
-	classIDs := []int64{}
-	for _, group := range reqBody.StudentGroups {
-		if group.GroupType == consts.TASK_GROUP_TYPE_CLASS && group.GroupID > 0 {
-			classIDs = append(classIDs, group.GroupID)
-		}
-	}
-	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
-		response.Forbidden(ctx)
+	// 检查教师是否具备班级的权限，类型为班级时，处理 StudentIDs
+	if !c.fillClassStudents(ctx, reqBody.SchoolID, reqBody.StudentGroups) {
 		return
 	}
 
-	// 类型为班级时，处理 StudentIDs
-	classStudentMap, err := c.ucenterService.GetClassStudent(ctx, reqBody.SchoolID, classIDs)
-	if err != nil {
-		c.log.Error(ctx, "获取班级下的学生ID失败: %v", err)
-		response.Err(ctx, response.ERR_GIL_ADMIN)
-		return
-	}
-	for i, group := range reqBody.StudentGroups {
-		if group.GroupType == consts.TASK_GROUP_TYPE_CLASS && group.GroupID > 0 {
-			classInfo, ok := classStudentMap[group.GroupID]
-			if !ok {
-				c.log.Error(ctx, "班级 %d 学生不存在", group.GroupID)
-				response.Err(ctx, response.ERR_GIL_ADMIN)
-				return
-			}
-			studentIDs := make([]int64, 0, len(classInfo.Students))
-			for _, student := range classInfo.Students {
-				studentIDs = append(studentIDs, student.ID)
-			}
-			reqBody.StudentGroups[i].StudentIDs = studentIDs
-		}
-	}
-
 	// 内容平台检查资源是否存在
-	if !c.questionAPI.CheckResourceExist(ctx, reqBody.Resources) {
+	if !c.questionAPI.CheckResourceExist(ctx, reqBody.AllResources()) {
 		c.log.Warn(ctx, "请求的内容平台资源不存在")
 		response.ParamError(ctx, response.ERR_INVALID_RESOURCE)
 		return
@@ -289,6 +285,10 @@ func (c *TaskController) CreateTask(ctx *gin.Context) {
 	err = c.taskService.CreateTask(ctx, &reqBody)
 	if err != nil {
 		c.log.Error(ctx, "创建任务失败: %v", err)
+		if errors.Is(err, task_service.ErrTierStudentMissing) {
+			response.ParamError(ctx, response.ERR_TIER_STUDENT_MISSING)
+			return
+		}
 		response.Err(ctx, response.ERR_POSTGRESQL)
 		return
 	}
@@ -296,6 +296,277 @@ func (c *TaskController) CreateTask(ctx *gin.Context) {
 	response.Success(ctx, nil)
 }
 
+// CreateWrongQuestionTask 按学生历史错题生成错题作业
+func (c *TaskController) CreateWrongQuestionTask(ctx *gin.Context) {
+	// 验证请求参数
+	var reqBody api.CreateWrongQuestionTaskRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Error(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := (&reqBody).Validate(); err != nil {
+		c.log.Error(ctx, "验证请求参数失败: %v", err)
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	phase := c.teacherMiddleware.ExtractTeacherPhase(ctx)
+	schoolID := c.teacherMiddleware.ExtractSchoolID(ctx)
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+
+	// 检查教师是否具备学科的权限
+	if !c.teacherMiddleware.HasTaskCreationSubjectPermission(ctx, reqBody.Subject) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	// 检查教师是否具备班级的权限，类型为班级时，处理 StudentIDs
+	if !c.fillClassStudents(ctx, schoolID, reqBody.StudentGroups) {
+		return
+	}
+
+	// CQC 检查内容是否合规
+	ok, err := c.volcAI.CQC(ctx, reqBody.TaskName+","+reqBody.TeacherComment)
+	if err != nil {
+		response.Err(ctx, response.ERR_VOLC_AI)
+		return
+	}
+	if !ok {
+		response.ParamError(ctx, response.ERR_CQC)
+		return
+	}
+
+	res, err := c.wrongQuestion.CreateWrongQuestionTask(ctx, schoolID, phase, teacherID, &reqBody)
+	if err != nil {
+		c.log.Error(ctx, "创建错题作业失败: %v", err)
+		if errors.Is(err, task_service.ErrNoWrongQuestion) {
+			response.ParamError(ctx, response.ERR_NO_WRONG_QUESTION)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, res)
+}
+
+// CloneTask 复制任务并布置给其他班级
+func (c *TaskController) CloneTask(ctx *gin.Context) {
+	// 验证请求参数
+	var reqBody api.CloneTaskRequestBody
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Error(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := (&reqBody).Validate(); err != nil {
+		c.log.Error(ctx, "验证请求参数失败: %v", err)
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	// 从中间件获取学段、学校 ID 和老师 ID
+	reqBody.Phase = c.teacherMiddleware.ExtractTeacherPhase(ctx)
+	reqBody.SchoolID = c.teacherMiddleware.ExtractSchoolID(ctx)
+	reqBody.CreatorID = c.teacherMiddleware.ExtractTeacherID(ctx)
+
+	// 只能复制自己创建的任务
+	source, err := c.taskService.GetTaskByIDAndCreatorID(ctx, reqBody.TaskID, reqBody.CreatorID)
+	if err != nil {
+		c.log.Error(ctx, "获取任务基本信息失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+	if source == nil {
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+		return
+	}
+
+	// 检查教师是否具备学科的权限
+	if !c.teacherMiddleware.HasTaskCreationSubjectPermission(ctx, source.Subject) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	// 检查教师是否具备班级的权限，类型为班级时，处理 StudentIDs
+	if !c.fillClassStudents(ctx, reqBody.SchoolID, reqBody.StudentGroups) {
+		return
+	}
+
+	resources, err := c.taskResourceService.GetTaskResourcesByTaskID(ctx, source.TaskID)
+	if err != nil {
+		c.log.Error(ctx, "获取任务资源失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+	tiers, err := c.taskService.GetTaskTiers(ctx, source.TaskID)
+	if err != nil {
+		c.log.Error(ctx, "获取任务分层失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	// 内容平台重新检查资源是否存在，答题卡资源由系统生成不需要检查
+	if reqBody.CheckResource {
+		checkResources := make([]api.TaskResource, 0, len(resources))
+		for _, resource := range resources {
+			if resource.ResourceType == consts.RESOURCE_TYPE_ANSWER_CARD {
+				continue
+			}
+			checkResources = append(checkResources, api.TaskResource{
+				ResourceID:   resource.ResourceID,
+				ResourceType: resource.ResourceType,
+			})
+		}
+		if !c.questionAPI.CheckResourceExist(ctx, checkResources) {
+			c.log.Warn(ctx, "请求的内容平台资源不存在")
+			response.ParamError(ctx, response.ERR_INVALID_RESOURCE)
+			return
+		}
+	}
+
+	// CQC 检查内容是否合规
+	if reqBody.TaskName != "" || reqBody.TeacherComment != "" {
+		ok, err := c.volcAI.CQC(ctx, reqBody.TaskName+","+reqBody.TeacherComment)
+		if err != nil {
+			response.Err(ctx, response.ERR_VOLC_AI)
+			return
+		}
+		if !ok {
+			response.ParamError(ctx, response.ERR_CQC)
+			return
+		}
+	}
+
+	taskID, err := c.taskService.CloneTask(ctx, source, resources, tiers, &reqBody)
+	if err != nil {
+		c.log.Error(ctx, "复制任务失败: %v", err)
+		if errors.Is(err, task_service.ErrTaskClone) {
+			response.ParamError(ctx, response.ERR_TASK_CLONE)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, &api.CloneTaskResponse{TaskID: taskID})
+}
+
+// PushErrorBookTask 将选中的班级共性错题推送到新的作业任务
+func (c *TaskController) PushErrorBookTask(ctx *gin.Context) {
+	// 验证请求参数
+	var reqBody api.PushErrorBookTaskRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Error(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := (&reqBody).Validate(); err != nil {
+		c.log.Error(ctx, "验证请求参数失败: %v", err)
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	// 从中间件获取学段、学校 ID 和老师 ID
+	reqBody.Phase = c.teacherMiddleware.ExtractTeacherPhase(ctx)
+	reqBody.SchoolID = c.teacherMiddleware.ExtractSchoolID(ctx)
+	reqBody.CreatorID = c.teacherMiddleware.ExtractTeacherID(ctx)
+
+	// 检查教师是否具备学科的权限
+	if !c.teacherMiddleware.HasTaskCreationSubjectPermission(ctx, reqBody.Subject) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	items, err := c.classErrorBook.GetItems(ctx, reqBody.SchoolID, reqBody.Subject, reqBody.ItemIDs)
+	if err != nil {
+		c.log.Error(ctx, "获取共性错题失败: %v", err)
+		if errors.Is(err, task.ErrInvalidErrorBookItem) {
+			response.ParamError(ctx, response.ERR_INVALID_ERROR_BOOK_ITEM)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	// 只能推送有权限的班级的共性错题
+	sourceClassIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		sourceClassIDs = append(sourceClassIDs, item.ClassID)
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, sourceClassIDs...) {
+		response.Forbidden(ctx)
+		return
+	}
+
+	// 检查教师是否具备班级的权限，类型为班级时，处理 StudentIDs
+	if !c.fillClassStudents(ctx, reqBody.SchoolID, reqBody.StudentGroups) {
+		return
+	}
+
+	// CQC 检查内容是否合规
+	ok, err := c.volcAI.CQC(ctx, reqBody.TaskName+","+reqBody.TeacherComment)
+	if err != nil {
+		response.Err(ctx, response.ERR_VOLC_AI)
+		return
+	}
+	if !ok {
+		response.ParamError(ctx, response.ERR_CQC)
+		return
+	}
+
+	res, err := c.classErrorBook.PushToTask(ctx, items, &reqBody)
+	if err != nil {
+		c.log.Error(ctx, "共性错题推送到新任务失败: %v", err)
+		if errors.Is(err, task.ErrInvalidErrorBookItem) {
+			response.ParamError(ctx, response.ERR_INVALID_ERROR_BOOK_ITEM)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, res)
+}
+
+// 检查教师是否具备布置班级的权限，并填充班级下的学生ID，失败时已写入响应
+func (c *TaskController) fillClassStudents(ctx *gin.Context, schoolID int64, studentGroups []api.StudentGroup) bool {
+	classIDs := []int64{}
+	for _, group := range studentGroups {
+		if group.GroupType == consts.TASK_GROUP_TYPE_CLASS && group.GroupID > 0 {
+			classIDs = append(classIDs, group.GroupID)
+		}
+	}
+	if !c.teacherMiddleware.TeacherHasClassPermission(ctx, classIDs...) {
+		response.Forbidden(ctx)
+		return false
+	}
+
+	classStudentMap, err := c.ucenterService.GetClassStudent(ctx, schoolID, classIDs)
+	if err != nil {
+		c.log.Error(ctx, "获取班级下的学生ID失败: %v", err)
+		response.Err(ctx, response.ERR_GIL_ADMIN)
+		return false
+	}
+	for i, group := range studentGroups {
+		if group.GroupType == consts.TASK_GROUP_TYPE_CLASS && group.GroupID > 0 {
+			classInfo, ok := classStudentMap[group.GroupID]
+			if !ok {
+				c.log.Error(ctx, "班级 %d 学生不存在", group.GroupID)
+				response.Err(ctx, response.ERR_GIL_ADMIN)
+				return false
+			}
+			studentIDs := make([]int64, 0, len(classInfo.Students))
+			for _, student := range classInfo.Students {
+				studentIDs = append(studentIDs, student.ID)
+			}
+			studentGroups[i].StudentIDs = studentIDs
+		}
+	}
+	return true
+}
+
 // DeleteTask 删除任务
 func (c *TaskController) DeleteTask(ctx *gin.Context) {
 	// 验证请求参数
@@ -0,0 +1,232 @@
+package controller_task
+
+import (
+	"errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/controller/http_server/response"
+	"gil_teacher/app/domain/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/service/task_service"
+	"gil_teacher/app/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUnpublishedTaskList 查询教师的草稿或定时发布的任务列表
+func (c *TaskController) GetUnpublishedTaskList(ctx *gin.Context) {
+	var req api.GetUnpublishedTaskListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		response.ParamError(ctx)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	schoolID := c.teacherMiddleware.ExtractSchoolID(ctx)
+	tasks, total, err := c.taskService.GetUnpublishedTasks(ctx, teacherID, schoolID, &req)
+	if err != nil {
+		c.log.Error(ctx, "查询未发布的任务列表失败: %v", err)
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, &api.GetUnpublishedTaskListResponse{
+		List: tasks,
+		ApiPageResponse: &consts.ApiPageResponse{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Total:    total,
+		},
+	})
+}
+
+// UpdateDraft 编辑草稿的名称、留言和资源
+func (c *TaskController) UpdateDraft(ctx *gin.Context) {
+	var reqBody api.UpdateDraftRequestBody
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Warn(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := reqBody.Validate(); err != nil {
+		c.log.Warn(ctx, "验证请求参数失败: %v", err)
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+
+	// 内容平台检查资源是否存在
+	if !c.questionAPI.CheckResourceExist(ctx, reqBody.AllResources()) {
+		c.log.Warn(ctx, "请求的内容平台资源不存在")
+		response.ParamError(ctx, response.ERR_INVALID_RESOURCE)
+		return
+	}
+
+	// CQC 检查内容是否合规
+	if reqBody.TaskName != "" || reqBody.TeacherComment != "" {
+		ok, err := c.volcAI.CQC(ctx, reqBody.TaskName+","+reqBody.TeacherComment)
+		if err != nil {
+			response.Err(ctx, response.ERR_VOLC_AI)
+			return
+		}
+		if !ok {
+			response.ParamError(ctx, response.ERR_CQC)
+			return
+		}
+	}
+
+	if err := c.taskService.UpdateDraft(ctx, teacherID, &reqBody); err != nil {
+		c.log.Error(ctx, "编辑草稿失败: %v", err)
+		if errors.Is(err, task_service.ErrTaskStatus) {
+			response.ParamError(ctx, response.ERR_INVALID_TASK_STATUS)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// PublishTask 发布草稿，可以立即发布或定时发布
+func (c *TaskController) PublishTask(ctx *gin.Context) {
+	var reqBody api.PublishTaskRequestBody
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Warn(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := reqBody.Validate(); err != nil {
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	if err := c.taskService.PublishTask(ctx, teacherID, &reqBody); err != nil {
+		c.log.Error(ctx, "发布草稿失败: %v", err)
+		if errors.Is(err, task_service.ErrTaskStatus) {
+			response.ParamError(ctx, response.ERR_INVALID_TASK_STATUS)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// ArchiveTask 归档已发布的任务
+func (c *TaskController) ArchiveTask(ctx *gin.Context) {
+	var reqBody api.ArchiveTaskRequestBody
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Warn(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	taskIDs := utils.RemoveDuplicateInt64(reqBody.TaskIDs)
+	if len(taskIDs) == 0 {
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	if err := c.taskService.ArchiveTasks(ctx, teacherID, taskIDs); err != nil {
+		c.log.Error(ctx, "归档任务失败: %v", err)
+		if errors.Is(err, task_service.ErrTaskStatus) {
+			response.ParamError(ctx, response.ERR_INVALID_TASK_STATUS)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// CreateTaskRecurrence 创建周期布置规则，按规则在每个布置日为班级生成一次任务布置
+func (c *TaskController) CreateTaskRecurrence(ctx *gin.Context) {
+	var reqBody api.CreateTaskRecurrenceRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Warn(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+	if err := reqBody.Validate(); err != nil {
+		response.ParamError(ctx, *err)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	schoolID := c.teacherMiddleware.ExtractSchoolID(ctx)
+
+	// 检查教师是否具备班级的权限，并记录班级当前的学生
+	groups := []api.StudentGroup{{GroupType: reqBody.GroupType, GroupID: reqBody.GroupID}}
+	if !c.fillClassStudents(ctx, schoolID, groups) {
+		return
+	}
+	reqBody.StudentIDs = groups[0].StudentIDs
+
+	recurrence, err := c.taskSchedule.CreateRecurrence(ctx, teacherID, schoolID, &reqBody)
+	if err != nil {
+		c.log.Error(ctx, "创建周期布置规则失败: %v", err)
+		if errors.Is(err, task.ErrTaskRecurrence) {
+			response.ParamError(ctx, response.ERR_INVALID_TASK_RECURRENCE)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, recurrence)
+}
+
+// StopTaskRecurrence 停止周期布置规则
+func (c *TaskController) StopTaskRecurrence(ctx *gin.Context) {
+	var reqBody api.StopTaskRecurrenceRequest
+	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
+		c.log.Warn(ctx, "绑定请求参数失败: %v", err)
+		response.ParamError(ctx)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	if err := c.taskSchedule.StopRecurrence(ctx, teacherID, reqBody.RecurrenceID); err != nil {
+		c.log.Error(ctx, "停止周期布置规则失败: %v", err)
+		if errors.Is(err, task.ErrTaskRecurrenceNotFound) {
+			response.ParamError(ctx, response.ERR_TASK_RECURRENCE_NOT_FOUND)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, nil)
+}
+
+// GetTaskRecurrenceList 查询任务的周期布置规则
+func (c *TaskController) GetTaskRecurrenceList(ctx *gin.Context) {
+	taskID := utils.Atoi64(ctx.Query("taskId"))
+	if taskID <= 0 {
+		response.ParamError(ctx, response.ERR_INVALID_TASK)
+		return
+	}
+
+	teacherID := c.teacherMiddleware.ExtractTeacherID(ctx)
+	recurrences, err := c.taskSchedule.GetRecurrences(ctx, teacherID, taskID)
+	if err != nil {
+		c.log.Error(ctx, "查询周期布置规则失败: %v", err)
+		if errors.Is(err, task.ErrTaskRecurrence) {
+			response.ParamError(ctx, response.ERR_INVALID_TASK)
+			return
+		}
+		response.Err(ctx, response.ERR_POSTGRESQL)
+		return
+	}
+
+	response.Success(ctx, recurrences)
+}
@@ -9,6 +9,7 @@ import (
 	"gil_teacher/app/controller/grpc_server/user"
 	"gil_teacher/app/controller/http"
 	"gil_teacher/app/controller/http_server/behavior"
+	"gil_teacher/app/controller/http_server/push"
 	"gil_teacher/app/controller/http_server/resource_favorite"
 	"gil_teacher/app/controller/http_server/route"
 	"gil_teacher/app/controller/http_server/schedule"
@@ -36,6 +37,7 @@ var HttpProviderSet = wire.NewSet(
 	behavior.NewBehaviorController,
 	controller_task.NewTaskReportController,
 	schedule.NewScheduleController,
+	push.NewPushController,
 )
 
 var ControllerProviderSet = wire.NewSet(
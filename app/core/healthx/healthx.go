@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"gil_teacher/app/core/envx"
 	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type HealthServer struct {
@@ -28,6 +30,8 @@ func (healthServer *HealthServer) Run() {
 					return
 				}
 			})
+			// 暴露消费进程的 Prometheus 指标，包括 kafka 消费积压、重试和死信
+			http.Handle("/metrics", promhttp.Handler())
 			// 启动服务器
 			fmt.Printf("Health server listening on port %d\n", healthServer.Port)
 
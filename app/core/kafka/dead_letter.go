@@ -0,0 +1,302 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/utils/prometheus"
+
+	"github.com/IBM/sarama"
+)
+
+// MessageError 单条消息处理失败
+// Retryable 为 false 表示消息本身有问题（如无法解析），重试也不会成功，直接写入死信 topic
+type MessageError struct {
+	Msg       *sarama.ConsumerMessage
+	Err       error
+	Retryable bool
+}
+
+func (e *MessageError) Error() string {
+	return fmt.Sprintf("topic:%s partition:%d offset:%d error:%v", e.Msg.Topic, e.Msg.Partition, e.Msg.Offset, e.Err)
+}
+
+func (e *MessageError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError 批次中部分消息处理失败，未列出的消息视为处理成功
+type BatchError []*MessageError
+
+func (e BatchError) Error() string {
+	if len(e) == 0 {
+		return "batch error"
+	}
+	return fmt.Sprintf("%d messages failed, first: %s", len(e), e[0].Error())
+}
+
+// DeadLetterMessage 死信消息，保留原始消息位置和失败原因，便于排查和重放
+type DeadLetterMessage struct {
+	Group     string    `json:"group"`
+	Topic     string    `json:"topic"`
+	Partition int32     `json:"partition"`
+	Offset    int64     `json:"offset"`
+	Key       string    `json:"key,omitempty"`
+	Value     string    `json:"value"`
+	Reason    string    `json:"reason"`
+	Retries   int       `json:"retries"`
+	FailedAt  time.Time `json:"failedAt"`
+}
+
+// DeadLetterTopic 原 topic 对应的死信 topic
+func DeadLetterTopic(topic string) string {
+	return topic + consts.KafkaDeadLetterTopicSuffix
+}
+
+// 批次处理失败后逐条重试，仍然失败或不可重试的消息写入死信 topic
+// 返回 nil 时整批消息都已处理或进入死信，可以提交 offset；未配置死信或写入死信失败时返回错误，本批次不提交
+func (handler *ConsumerGroupHandlerImpl) handleFailure(ctx context.Context, msgs []*sarama.ConsumerMessage, err error) error {
+	if handler.DeadLetter == nil {
+		return err
+	}
+
+	for _, failure := range failedMessages(msgs, err) {
+		retries := 0
+		if failure.Retryable {
+			retries, failure = handler.retry(ctx, failure)
+			if failure == nil {
+				continue
+			}
+		}
+		if err := handler.sendDeadLetter(ctx, failure, retries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 整批失败时所有消息都需要逐条重试
+func failedMessages(msgs []*sarama.ConsumerMessage, err error) []*MessageError {
+	var batchErr BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr
+	}
+
+	failures := make([]*MessageError, 0, len(msgs))
+	for _, msg := range msgs {
+		failures = append(failures, &MessageError{Msg: msg, Err: err, Retryable: true})
+	}
+	return failures
+}
+
+// 按指数退避重试单条消息，成功时返回 nil
+func (handler *ConsumerGroupHandlerImpl) retry(ctx context.Context, failure *MessageError) (int, *MessageError) {
+	maxRetries := handler.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = consts.KafkaMaximumRetryCount
+	}
+
+	retries := 0
+	for retries < maxRetries {
+		time.Sleep(handler.retryBackoff(retries))
+		retries++
+		prometheus.KafkaRetryCounter.WithLabelValues(handler.Group, failure.Msg.Topic).Inc()
+
+		err := handler.ProcMsgList([]*sarama.ConsumerMessage{failure.Msg})
+		if err == nil {
+			handler.Log.Info(ctx, "消息重试成功, topic:%s, partition:%d, offset:%d, retries:%d",
+				failure.Msg.Topic, failure.Msg.Partition, failure.Msg.Offset, retries)
+			return retries, nil
+		}
+
+		failure = failedMessages([]*sarama.ConsumerMessage{failure.Msg}, err)[0]
+		handler.Log.Warn(ctx, "消息重试失败, topic:%s, partition:%d, offset:%d, retries:%d, error:%v",
+			failure.Msg.Topic, failure.Msg.Partition, failure.Msg.Offset, retries, failure.Err)
+		if !failure.Retryable {
+			break
+		}
+	}
+	return retries, failure
+}
+
+// 第 n 次重试前的退避时间，初始退避时间按次数翻倍，不超过最大退避时间
+func (handler *ConsumerGroupHandlerImpl) retryBackoff(retries int) time.Duration {
+	backoff := handler.RetryBackoff
+	if backoff <= 0 {
+		backoff = consts.KafkaDefaultRetryBackoff * time.Millisecond
+	}
+	maxBackoff := consts.KafkaMaxRetryBackoff * time.Millisecond
+	for i := 0; i < retries && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+func (handler *ConsumerGroupHandlerImpl) sendDeadLetter(ctx context.Context, failure *MessageError, retries int) error {
+	reason := fmt.Sprintf("%v", failure.Err)
+	if len(reason) > consts.KafkaDeadLetterReasonMaxLen {
+		reason = reason[:consts.KafkaDeadLetterReasonMaxLen]
+	}
+	deadLetter := &DeadLetterMessage{
+		Group:     handler.Group,
+		Topic:     failure.Msg.Topic,
+		Partition: failure.Msg.Partition,
+		Offset:    failure.Msg.Offset,
+		Key:       string(failure.Msg.Key),
+		Value:     string(failure.Msg.Value),
+		Reason:    reason,
+		Retries:   retries,
+		FailedAt:  time.Now(),
+	}
+	value, err := json.Marshal(deadLetter)
+	if err != nil {
+		return err
+	}
+
+	// 以原 partition 作为 key，同一 partition 的死信保持顺序
+	key := strconv.Itoa(int(failure.Msg.Partition))
+	if err := handler.DeadLetter.ProduceKeyedMsgToKafka(ctx, DeadLetterTopic(failure.Msg.Topic), key, string(value)); err != nil {
+		handler.Log.Error(ctx, "写入死信失败, topic:%s, partition:%d, offset:%d, error:%v",
+			failure.Msg.Topic, failure.Msg.Partition, failure.Msg.Offset, err)
+		return err
+	}
+
+	prometheus.KafkaDeadLetterCounter.WithLabelValues(handler.Group, failure.Msg.Topic).Inc()
+	handler.Log.Error(ctx, "消息写入死信, topic:%s, partition:%d, offset:%d, retries:%d, reason:%s",
+		failure.Msg.Topic, failure.Msg.Partition, failure.Msg.Offset, retries, reason)
+	return nil
+}
+
+// ReplayedOffset 重放消息在原 partition 中的 offset，不是重放消息时返回 false
+// 重放消息写在原 partition 的末尾，消费端需要按原 offset 判断是否比已处理的消息更旧
+func ReplayedOffset(msg *sarama.ConsumerMessage) (int64, bool) {
+	for _, header := range msg.Headers {
+		if header != nil && string(header.Key) == consts.KafkaHeaderReplayOffset {
+			offset, err := strconv.ParseInt(string(header.Value), 10, 64)
+			return offset, err == nil
+		}
+	}
+	return 0, false
+}
+
+// 重放消息写回原 topic 的原 partition 并保留原 key，同一 key 的消息仍由同一 partition 顺序消费，
+// 不依赖生产端的分区策略；header 中记录原 offset
+func replayMessage(deadLetter *DeadLetterMessage) *sarama.ProducerMessage {
+	msg := &sarama.ProducerMessage{
+		Topic:     deadLetter.Topic,
+		Partition: deadLetter.Partition,
+		Value:     sarama.StringEncoder(deadLetter.Value),
+		Headers: []sarama.RecordHeader{{
+			Key:   []byte(consts.KafkaHeaderReplayOffset),
+			Value: []byte(strconv.FormatInt(deadLetter.Offset, 10)),
+		}},
+	}
+	if deadLetter.Key != "" {
+		msg.Key = sarama.StringEncoder(deadLetter.Key)
+	}
+	return msg
+}
+
+// ReplayDeadLetters 将死信 topic 中的消息重新投递回原 topic 的原 partition
+// 从重放消费组上次提交的位置开始，读到启动时的高水位为止，返回重放的消息数
+func ReplayDeadLetters(ctx context.Context, kafkaConf *conf.Kafka, deadLetterTopic string) (int, error) {
+	client, err := newKafkaConsumerClient(kafkaConf)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = client.Close() }()
+
+	// 按消息指定的 partition 写入
+	producer, err := newSaramaSyncProducer(kafkaConf, sarama.NewManualPartitioner)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = producer.Close() }()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = consumer.Close() }()
+
+	offsetManager, err := sarama.NewOffsetManagerFromClient(consts.KafkaGroupDeadLetterReplay, client)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = offsetManager.Close() }()
+
+	partitions, err := client.Partitions(deadLetterTopic)
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, partition := range partitions {
+		count, err := replayPartition(ctx, client, consumer, offsetManager, producer, deadLetterTopic, partition)
+		replayed += count
+		if err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+func replayPartition(ctx context.Context, client sarama.Client, consumer sarama.Consumer, offsetManager sarama.OffsetManager,
+	producer sarama.SyncProducer, deadLetterTopic string, partition int32) (int, error) {
+	highWaterMark, err := client.GetOffset(deadLetterTopic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, err
+	}
+	pom, err := offsetManager.ManagePartition(deadLetterTopic, partition)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = pom.Close() }()
+
+	offset, _ := pom.NextOffset()
+	if offset < 0 {
+		offset = sarama.OffsetOldest
+	}
+	if offset >= highWaterMark {
+		return 0, nil
+	}
+
+	partitionConsumer, err := consumer.ConsumePartition(deadLetterTopic, partition, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = partitionConsumer.Close() }()
+
+	replayed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return replayed, ctx.Err()
+		case err := <-partitionConsumer.Errors():
+			return replayed, err
+		case msg := <-partitionConsumer.Messages():
+			var deadLetter DeadLetterMessage
+			if err := json.Unmarshal(msg.Value, &deadLetter); err != nil {
+				return replayed, fmt.Errorf("解析死信失败, partition:%d, offset:%d: %w", msg.Partition, msg.Offset, err)
+			}
+			if _, _, err := producer.SendMessage(replayMessage(&deadLetter)); err != nil {
+				return replayed, fmt.Errorf("重放死信失败, partition:%d, offset:%d: %w", msg.Partition, msg.Offset, err)
+			}
+			replayed++
+			pom.MarkOffset(msg.Offset+1, "")
+			offsetManager.Commit()
+			if msg.Offset+1 >= highWaterMark {
+				return replayed, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/core/logger"
+)
+
+func TestHandleFailure(t *testing.T) {
+	ctx := context.Background()
+	producer := mocks.NewSyncProducer(t, nil)
+	defer func() { _ = producer.Close() }()
+
+	msgs := []*sarama.ConsumerMessage{
+		{Topic: "topic-a", Partition: 1, Offset: 10, Key: []byte("k1"), Value: []byte("ok-after-retry")},
+		{Topic: "topic-a", Partition: 1, Offset: 11, Key: []byte("k2"), Value: []byte("always-fail")},
+		{Topic: "topic-a", Partition: 1, Offset: 12, Value: []byte("poison")},
+	}
+	attempts := make(map[int64]int)
+	handler := &ConsumerGroupHandlerImpl{
+		Group:        "group-a",
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		ProcMsgList: func(batch []*sarama.ConsumerMessage) error {
+			msg := batch[0]
+			attempts[msg.Offset]++
+			if msg.Offset == 10 {
+				return nil
+			}
+			return errors.New("db unavailable")
+		},
+		DeadLetter: &KafkaProducerClient{Producer: producer, log: logger.NewContextLogger(log.DefaultLogger)},
+		Log:        logger.NewContextLogger(log.DefaultLogger),
+	}
+
+	// 未配置死信时原样返回错误，本批次不提交
+	batchErr := BatchError{
+		{Msg: msgs[0], Err: errors.New("db unavailable"), Retryable: true},
+		{Msg: msgs[1], Err: errors.New("db unavailable"), Retryable: true},
+		{Msg: msgs[2], Err: errors.New("invalid json")},
+	}
+	deadLetter := handler.DeadLetter
+	handler.DeadLetter = nil
+	assert.Equal(t, error(batchErr), handler.handleFailure(ctx, msgs, batchErr))
+	handler.DeadLetter = deadLetter
+
+	// 重试成功的消息不进入死信，重试耗尽和不可重试的消息进入死信并保留原始位置
+	var deadLetters []DeadLetterMessage
+	collect := func(val []byte) error {
+		var deadLetter DeadLetterMessage
+		assert.NoError(t, json.Unmarshal(val, &deadLetter))
+		deadLetters = append(deadLetters, deadLetter)
+		return nil
+	}
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(collect)
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(collect)
+	assert.NoError(t, handler.handleFailure(ctx, msgs, batchErr))
+
+	assert.Equal(t, map[int64]int{10: 1, 11: 2}, attempts)
+	assert.Len(t, deadLetters, 2)
+	assert.Equal(t, int64(11), deadLetters[0].Offset)
+	assert.Equal(t, "k2", deadLetters[0].Key)
+	assert.Equal(t, "always-fail", deadLetters[0].Value)
+	assert.Equal(t, 2, deadLetters[0].Retries)
+	assert.Equal(t, "db unavailable", deadLetters[0].Reason)
+	assert.Equal(t, int64(12), deadLetters[1].Offset)
+	assert.Equal(t, 0, deadLetters[1].Retries)
+	assert.Equal(t, "invalid json", deadLetters[1].Reason)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	handler := &ConsumerGroupHandlerImpl{RetryBackoff: time.Second}
+	assert.Equal(t, time.Second, handler.retryBackoff(0))
+	assert.Equal(t, 4*time.Second, handler.retryBackoff(2))
+	assert.Equal(t, 10*time.Second, handler.retryBackoff(10))
+	assert.Equal(t, "topic-a-dlq", DeadLetterTopic("topic-a"))
+}
+
+func TestReplayMessage(t *testing.T) {
+	msg := replayMessage(&DeadLetterMessage{Topic: "topic-a", Partition: 2, Offset: 11, Key: "k2", Value: "v"})
+	assert.Equal(t, "topic-a", msg.Topic)
+	assert.Equal(t, int32(2), msg.Partition)
+	assert.Equal(t, sarama.StringEncoder("k2"), msg.Key)
+
+	// 消费端从 header 中读取原 offset
+	consumed := &sarama.ConsumerMessage{Topic: "topic-a", Partition: 2, Offset: 30}
+	for i := range msg.Headers {
+		consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+	}
+	offset, ok := ReplayedOffset(consumed)
+	assert.True(t, ok)
+	assert.Equal(t, int64(11), offset)
+
+	// 原消息没有 key 时重放也不指定 key
+	assert.Nil(t, replayMessage(&DeadLetterMessage{Topic: "topic-a", Value: "v"}).Key)
+	_, ok = ReplayedOffset(&sarama.ConsumerMessage{Topic: "topic-a", Offset: 12})
+	assert.False(t, ok)
+}
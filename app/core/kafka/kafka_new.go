@@ -3,11 +3,13 @@ package kafka
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"gil_teacher/app/conf"
 	"gil_teacher/app/core/logger"
+	"gil_teacher/app/utils/prometheus"
 
 	"github.com/IBM/sarama"
 	"github.com/go-kratos/kratos/v2/log"
@@ -22,12 +24,16 @@ func newKafkaSyncProducer(conf *conf.Data) (sarama.SyncProducer, error) {
 	if conf == nil {
 		return nil, fmt.Errorf("kafka config is nil")
 	}
+	// 指定 key 时按 key 哈希分区，未指定时随机分区
+	return newSaramaSyncProducer(conf.Kafka, sarama.NewHashPartitioner)
+}
 
-	kafkaConf := conf.Kafka
+// 按指定的分区策略创建同步生产者
+func newSaramaSyncProducer(kafkaConf *conf.Kafka, partitioner sarama.PartitionerConstructor) (sarama.SyncProducer, error) {
 	saramaConfig := sarama.NewConfig()
 	saramaConfig.Version = sarama.V2_6_2_0
-	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll          // 发送完数据需要leader和follow都确认
-	saramaConfig.Producer.Partitioner = sarama.NewRandomPartitioner // 新选出一个partition
+	saramaConfig.Producer.RequiredAcks = sarama.WaitForAll // 发送完数据需要leader和follow都确认
+	saramaConfig.Producer.Partitioner = partitioner
 	saramaConfig.Producer.Return.Successes = true
 	saramaConfig.Producer.MaxMessageBytes = 5000000
 	//目前阿里云暂时没有开启sasl，主要是考虑到成本问题
@@ -71,11 +77,18 @@ func NewKafkaProducerClient(ctx context.Context, conf *conf.Data, logger *logger
 
 // 先支持单条发，我们的场景也基本都是单条发送，多条消费
 func (kafkaProducerClient *KafkaProducerClient) ProduceMsgToKafka(ctx context.Context, topic string, msgVal string) error {
+	// key不指定，走随机策略，防止数据倾斜
+	return kafkaProducerClient.ProduceKeyedMsgToKafka(ctx, topic, "", msgVal)
+}
+
+// ProduceKeyedMsgToKafka 按 key 发送消息，相同 key 的消息写入同一 partition，保证消费顺序
+func (kafkaProducerClient *KafkaProducerClient) ProduceKeyedMsgToKafka(ctx context.Context, topic string, key string, msgVal string) error {
 	msg := &sarama.ProducerMessage{
 		Topic: topic,
 		Value: sarama.StringEncoder(msgVal),
-		// key暂时不指定，走轮询策略，防止数据倾斜，如果有特定需求，未来新增按key区分partition
-		// Key:   sarama.StringEncoder(msgVal),
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
 	}
 
 	partition, offset, err := kafkaProducerClient.Producer.SendMessage(msg)
@@ -118,8 +131,12 @@ type ConsumerGroupHandlerImpl struct {
 	BatchTime   time.Duration // 5s
 	BatchSize   int           // 100
 	SessionTime time.Duration // 300s
-	ProcMsgList func([]*sarama.ConsumerMessage) error
-	Log         *logger.ContextLogger
+	// 返回 BatchError 表示部分消息失败，其他错误表示整批失败；失败的消息逐条重试，仍失败时写入死信 topic
+	ProcMsgList  func([]*sarama.ConsumerMessage) error
+	MaxRetries   int                  // 单条消息最大重试次数，默认 consts.KafkaMaximumRetryCount
+	RetryBackoff time.Duration        // 单条消息重试的初始退避时间，默认 consts.KafkaDefaultRetryBackoff 毫秒
+	DeadLetter   *KafkaProducerClient // 死信生产者，未配置时失败的批次不提交 offset，重新加入消费组后重放
+	Log          *logger.ContextLogger
 }
 
 // setup在sarama中，初始化只有一个协程执行一次，不像ConsumeClaim有partition数量的协程多次执行
@@ -165,7 +182,13 @@ func (handler *ConsumerGroupHandlerImpl) ConsumeClaim(session sarama.ConsumerGro
 		err := handler.ProcMsgList(msgList)
 		if err != nil {
 			handler.Log.Error(ctx, "procMsg error:%+v", err)
-			return err
+			if err = handler.handleFailure(ctx, msgList, err); err != nil {
+				prometheus.KafkaConsumeCounter.WithLabelValues(handler.Group, claim.Topic(), "failed").Add(float64(len(msgList)))
+				return err
+			}
+		}
+		if len(msgList) > 0 {
+			prometheus.KafkaConsumeCounter.WithLabelValues(handler.Group, claim.Topic(), "processed").Add(float64(len(msgList)))
 		}
 
 		for _, m := range msgList {
@@ -187,6 +210,8 @@ func (handler *ConsumerGroupHandlerImpl) ConsumeClaim(session sarama.ConsumerGro
 			}
 
 			msgList = append(msgList, msg)
+			prometheus.KafkaConsumerLag.WithLabelValues(handler.Group, msg.Topic, strconv.Itoa(int(msg.Partition))).
+				Set(float64(claim.HighWaterMarkOffset() - msg.Offset - 1))
 			if len(msgList) >= handler.BatchSize {
 				err := procMsg()
 				if err != nil {
@@ -235,7 +260,7 @@ func ConsumeKafkaMsgInSession(ctx context.Context, kafkaConf *conf.Kafka, handle
 	}
 	defer func() { _ = consumerGroup.Close() }()
 
-	handler.Log.Info(ctx, "consumer group:"+handler.Group+" consume topic:"+strings.Join(handler.Topics, ",")+" start!")
+	handler.Log.Info(ctx, "consumer group:%s consume topic:%s start!", handler.Group, strings.Join(handler.Topics, ","))
 	consumerTopics := handler.Topics
 	if err := consumerGroup.Consume(ctx, consumerTopics, handler); err != nil {
 		handler.Log.Error(ctx, "consume group error:%+v", err)
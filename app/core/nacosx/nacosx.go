@@ -41,6 +41,32 @@ type NacosClientX struct {
 	nacosConf      NacosConf
 }
 
+// NewConfigClient 创建配置客户端，用于读取和监听业务配置
+func NewConfigClient(nacos *conf.Nacos) (config_client.IConfigClient, error) {
+	serverConfigs := []constant.ServerConfig{
+		{
+			IpAddr:      nacos.Host,
+			Port:        uint64(nacos.Port),
+			ContextPath: "/nacos",
+			Scheme:      "http",
+		},
+	}
+	clientConfig := constant.ClientConfig{
+		Username:            nacos.Username,
+		Password:            nacos.Password,
+		TimeoutMs:           5000,
+		NotLoadCacheAtStart: true,
+		LogDir:              "nacos/log",
+		CacheDir:            "nacos/cache",
+		LogLevel:            "warn",
+	}
+
+	return clients.CreateConfigClient(map[string]interface{}{
+		"serverConfigs": serverConfigs,
+		"clientConfig":  clientConfig,
+	})
+}
+
 func NewNacosClientX(nacosConf NacosConf) (*NacosClientX, error) {
 
 	serverConfigs := make([]constant.ServerConfig, 0)
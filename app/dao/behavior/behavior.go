@@ -28,6 +28,8 @@ type BehaviorDAO interface {
 	GetCommunicationSession(ctx context.Context, sessionID string) (*dto.CommunicationSessionDTO, error)
 	// 保存会话记录
 	SaveCommunication(ctx context.Context, sessions []*dto.CommunicationSessionDTO, messages []*dto.CommunicationMessageDTO) error
+	// 保存单条会话消息，相同消息 id 重复保存时覆盖内容
+	SaveCommunicationMessage(ctx context.Context, message *dto.CommunicationMessageDTO) error
 	// 关闭会话，更新表
 	CloseCommunicationSession(ctx context.Context, sessionID string) error
 	// 查询指定会话的全部消息
@@ -36,10 +38,10 @@ type BehaviorDAO interface {
 	GetCommunicationSessionMessagesByIDs(ctx context.Context, sessionID string, messageIDs []string) ([]*dto.CommunicationMessageDTO, error)
 	// 查询指定课堂的全部消息
 	GetClassroomMessages(ctx context.Context, classroomID string, pageInfo *consts.DBPageInfo) ([]*dto.CommunicationMessageDTO, error)
-	// 获取班级学生最新行为
-	GetClassLatestBehaviors(ctx context.Context, ClassroomID uint64) ([]*dto.StudentLatestBehaviorDTO, error)
-	// 获取班级学生所有行为（用于汇总统计）
-	GetClassAllBehaviors(ctx context.Context, ClassroomID uint64) ([]*dto.StudentLatestBehaviorDTO, error)
+	// 获取班级学生最新行为，startTime、endTime 为零值时不限制行为时间
+	GetClassLatestBehaviors(ctx context.Context, ClassroomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error)
+	// 获取班级学生所有行为（用于汇总统计），startTime、endTime 为零值时不限制行为时间
+	GetClassAllBehaviors(ctx context.Context, ClassroomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error)
 	// 获取学生课堂详情
 	GetStudentClassroomDetail(ctx context.Context, studentID, classroomID uint64) (*dto.StudentClassroomDetailDTO, error)
 	// 获取课堂行为分类
@@ -50,12 +52,27 @@ type BehaviorDAO interface {
 	CountStudentTaskPraiseAndAttention(ctx context.Context, taskID, assignID uint64, studentIDs []uint64) ([]dao.CHGroupCountResult, error)
 	// 获取学生特定类型的行为数据
 	GetStudentBehaviorsByType(ctx context.Context, studentID, classroomID uint64, behaviorType string) ([]*StudentBehavior, error)
+	// GetTaskQuestionSessions 获取任务布置中学生针对题目发起的提问会话
+	GetTaskQuestionSessions(ctx context.Context, taskID, assignID int64) ([]*dto.CommunicationSessionDTO, error)
+	// GetSessionsStudentMessages 获取多个会话中学生发送的消息，按发送时间排序
+	GetSessionsStudentMessages(ctx context.Context, sessionIDs []string) ([]*dto.CommunicationMessageDTO, error)
+	// GetSessionsMessages 获取多个会话的全部消息，按发送时间排序
+	GetSessionsMessages(ctx context.Context, sessionIDs []string) ([]*dto.CommunicationMessageDTO, error)
+	// 查询会话全部消息的 id 和发送时间，按发送时间排序
+	GetCommunicationSessionMessageTimeline(ctx context.Context, sessionID string) ([]*dto.CommunicationMessageDTO, error)
+	// 保存用户在会话中的已读位置
+	SaveCommunicationReadCursor(ctx context.Context, cursor *dto.CommunicationReadCursorDTO) error
+	// 查询用户在会话中的已读位置，没有时返回 nil
+	GetCommunicationReadCursor(ctx context.Context, sessionID string, userID uint64, userType string) (*dto.CommunicationReadCursorDTO, error)
+	// 分页查询用户参与的会话，包括最后一条消息、未读数和参与者，按最后活跃时间倒序
+	GetCommunicationInbox(ctx context.Context, schoolID, userID uint64, userType string, pageInfo *consts.DBPageInfo) (*dto.CommunicationInboxDTO, error)
 }
 
 // BehaviorDAOImpl 行为数据访问对象实现
 type BehaviorDAOImpl struct {
 	communicationSessionDao *CommunicationSessionDao
 	communicationMessageDao *CommunicationMessageDao
+	readCursorDao           *CommunicationReadCursorDao
 	studentBehaviorDao      *StudentBehaviorDao
 	teacherBehaviorDao      *TeacherBehaviorDao
 	logger                  *clogger.ContextLogger
@@ -66,6 +83,7 @@ func NewBehaviorDAO(chClients map[string]*dao.ClickHouseRWClient, logger *clogge
 	return &BehaviorDAOImpl{
 		communicationSessionDao: newCommunicationSessionDao(chClients[consts.ChDBTeacher], logger),
 		communicationMessageDao: newCommunicationMessageDao(chClients[consts.ChDBTeacher], logger),
+		readCursorDao:           newCommunicationReadCursorDao(chClients[consts.ChDBTeacher], logger),
 		teacherBehaviorDao:      newTeacherBehaviorDao(chClients[consts.ChDBTeacher], logger),
 		studentBehaviorDao:      newStudentBehaviorDao(chClients[consts.ChDBStudent], logger),
 		logger:                  logger,
@@ -477,9 +495,9 @@ func (d *BehaviorDAOImpl) GetClassroomMessages(ctx context.Context, classroomID
 }
 
 // GetClassLatestBehaviors 获取班级学生最新行为
-func (d *BehaviorDAOImpl) GetClassLatestBehaviors(ctx context.Context, ClassroomID uint64) ([]*dto.StudentLatestBehaviorDTO, error) {
+func (d *BehaviorDAOImpl) GetClassLatestBehaviors(ctx context.Context, ClassroomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error) {
 	// 调用底层DAO获取数据
-	behaviors, err := d.studentBehaviorDao.GetClassLatestBehaviors(ctx, ClassroomID)
+	behaviors, err := d.studentBehaviorDao.GetClassLatestBehaviors(ctx, ClassroomID, startTime, endTime)
 	if err != nil {
 		d.logger.Error(ctx, "获取班级%d学生最新行为失败: %v", ClassroomID, err)
 		return nil, err
@@ -595,9 +613,9 @@ func (d *BehaviorDAOImpl) GetStudentsBehaviors(ctx context.Context, studentIDs [
 }
 
 // GetClassAllBehaviors 获取班级学生所有行为（用于汇总统计）
-func (d *BehaviorDAOImpl) GetClassAllBehaviors(ctx context.Context, ClassroomID uint64) ([]*dto.StudentLatestBehaviorDTO, error) {
+func (d *BehaviorDAOImpl) GetClassAllBehaviors(ctx context.Context, ClassroomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error) {
 	// 调用底层DAO获取数据
-	behaviors, err := d.studentBehaviorDao.GetClassAllBehaviors(ctx, ClassroomID)
+	behaviors, err := d.studentBehaviorDao.GetClassAllBehaviors(ctx, ClassroomID, startTime, endTime)
 	if err != nil {
 		d.logger.Error(ctx, "获取班级%d学生所有行为失败: %v", ClassroomID, err)
 		return nil, err
@@ -643,3 +661,250 @@ func (d *BehaviorDAOImpl) CountStudentTaskPraiseAndAttention(ctx context.Context
 func (d *BehaviorDAOImpl) GetStudentBehaviorsByType(ctx context.Context, studentID, classroomID uint64, behaviorType string) ([]*StudentBehavior, error) {
 	return d.studentBehaviorDao.GetStudentBehaviorsByType(ctx, studentID, classroomID, behaviorType)
 }
+
+// GetTaskQuestionSessions 获取任务布置中学生针对题目发起的全部提问会话，按页查询直到取完
+func (d *BehaviorDAOImpl) GetTaskQuestionSessions(ctx context.Context, taskID, assignID int64) ([]*dto.CommunicationSessionDTO, error) {
+	targetPrefix := consts.TaskAssignTargetIDPrefix(taskID, assignID)
+	records := make([]*CommunicationSession, 0)
+	for offset := 0; ; offset += consts.TaskQuestionSessionPageSize {
+		page, err := d.communicationSessionDao.GetStudentQuestionSessionsByTargetPrefix(ctx,
+			targetPrefix, consts.TaskQuestionSessionPageSize, offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "query task question sessions failed")
+		}
+		records = append(records, page...)
+		if len(page) < consts.TaskQuestionSessionPageSize {
+			break
+		}
+	}
+
+	sessions := make([]*dto.CommunicationSessionDTO, 0, len(records))
+	for _, session := range records {
+		sessions = append(sessions, &dto.CommunicationSessionDTO{
+			SessionID:   session.SessionID,
+			UserID:      session.UserID,
+			UserType:    session.UserType,
+			SchoolID:    session.SchoolID,
+			CourseID:    utils.PtrValue(session.CourseID),
+			ClassroomID: utils.PtrValue(session.ClassroomID),
+			SessionType: session.SessionType,
+			TargetID:    session.TargetID,
+			Closed:      session.Closed,
+			StartTime:   session.StartTime,
+			EndTime:     session.EndTime,
+		})
+	}
+	return sessions, nil
+}
+
+// GetSessionsStudentMessages 获取多个会话中学生发送的消息，按发送时间排序
+func (d *BehaviorDAOImpl) GetSessionsStudentMessages(ctx context.Context, sessionIDs []string) ([]*dto.CommunicationMessageDTO, error) {
+	records, err := d.communicationMessageDao.GetSessionsMessagesByUserType(ctx, sessionIDs, string(consts.CommunicationUserTypeStudent))
+	if err != nil {
+		return nil, errors.Wrap(err, "query sessions student messages failed")
+	}
+
+	messages := make([]*dto.CommunicationMessageDTO, 0, len(records))
+	for _, msg := range records {
+		messages = append(messages, &dto.CommunicationMessageDTO{
+			MessageID:      msg.MessageID,
+			SessionID:      msg.SessionID,
+			UserID:         msg.UserID,
+			UserType:       msg.UserType,
+			MessageContent: msg.MessageContent,
+			MessageType:    msg.MessageType,
+			AnswerTo:       msg.AnswerTo,
+			CreatedAt:      msg.CreatedAt,
+		})
+	}
+	return messages, nil
+}
+
+// GetSessionsMessages 获取多个会话的全部消息，按发送时间排序
+func (d *BehaviorDAOImpl) GetSessionsMessages(ctx context.Context, sessionIDs []string) ([]*dto.CommunicationMessageDTO, error) {
+	records, err := d.communicationMessageDao.GetSessionsMessages(ctx, sessionIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query sessions messages failed")
+	}
+
+	messages := make([]*dto.CommunicationMessageDTO, 0, len(records))
+	for _, msg := range records {
+		messages = append(messages, &dto.CommunicationMessageDTO{
+			MessageID:      msg.MessageID,
+			SessionID:      msg.SessionID,
+			UserID:         msg.UserID,
+			UserType:       msg.UserType,
+			MessageContent: msg.MessageContent,
+			MessageType:    msg.MessageType,
+			AnswerTo:       msg.AnswerTo,
+			CreatedAt:      msg.CreatedAt,
+		})
+	}
+	return messages, nil
+}
+
+// GetCommunicationSessionMessageTimeline 查询会话全部消息的 id 和发送时间，按发送时间排序
+func (d *BehaviorDAOImpl) GetCommunicationSessionMessageTimeline(ctx context.Context, sessionID string) ([]*dto.CommunicationMessageDTO, error) {
+	records, err := d.communicationMessageDao.GetSessionMessageTimeline(ctx, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query communication session message timeline failed")
+	}
+
+	messages := make([]*dto.CommunicationMessageDTO, 0, len(records))
+	for _, msg := range records {
+		messages = append(messages, &dto.CommunicationMessageDTO{
+			MessageID: msg.MessageID,
+			SessionID: sessionID,
+			CreatedAt: msg.CreatedAt,
+		})
+	}
+	return messages, nil
+}
+
+// SaveCommunicationReadCursor 保存用户在会话中的已读位置
+func (d *BehaviorDAOImpl) SaveCommunicationReadCursor(ctx context.Context, cursor *dto.CommunicationReadCursorDTO) error {
+	err := d.readCursorDao.SaveReadCursor(ctx, &CommunicationReadCursor{
+		SessionID:         cursor.SessionID,
+		UserID:            cursor.UserID,
+		UserType:          cursor.UserType,
+		LastReadMessageID: cursor.LastReadMessageID,
+		LastReadTime:      cursor.LastReadTime,
+		UpdateTime:        time.Now(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "save communication read cursor failed")
+	}
+	return nil
+}
+
+// GetCommunicationReadCursor 查询用户在会话中的已读位置，没有时返回 nil
+func (d *BehaviorDAOImpl) GetCommunicationReadCursor(ctx context.Context, sessionID string, userID uint64, userType string) (*dto.CommunicationReadCursorDTO, error) {
+	record, err := d.readCursorDao.GetReadCursor(ctx, sessionID, userID, userType)
+	if err != nil {
+		return nil, errors.Wrap(err, "query communication read cursor failed")
+	}
+	if record == nil {
+		return nil, nil
+	}
+	return &dto.CommunicationReadCursorDTO{
+		SessionID:         record.SessionID,
+		UserID:            record.UserID,
+		UserType:          record.UserType,
+		LastReadMessageID: record.LastReadMessageID,
+		LastReadTime:      record.LastReadTime,
+	}, nil
+}
+
+// GetCommunicationInbox 分页查询用户参与的会话，包括最后一条消息、未读数和参与者，按最后活跃时间倒序
+func (d *BehaviorDAOImpl) GetCommunicationInbox(ctx context.Context, schoolID, userID uint64, userType string, pageInfo *consts.DBPageInfo) (*dto.CommunicationInboxDTO, error) {
+	summary, err := d.readCursorDao.GetInboxSummary(ctx, schoolID, userID, userType)
+	if err != nil {
+		return nil, errors.Wrap(err, "count communication inbox failed")
+	}
+	inbox := &dto.CommunicationInboxDTO{
+		Sessions:    []*dto.CommunicationInboxSessionDTO{},
+		Total:       int64(summary.SessionCount),
+		UnreadCount: int64(summary.UnreadCount),
+	}
+	if summary.SessionCount == 0 {
+		return inbox, nil
+	}
+
+	records, err := d.readCursorDao.GetInboxSessions(ctx, schoolID, userID, userType, pageInfo)
+	if err != nil {
+		return nil, errors.Wrap(err, "query communication inbox failed")
+	}
+	if len(records) == 0 {
+		return inbox, nil
+	}
+
+	sessionIDs := make([]string, 0, len(records))
+	lastMessageIDs := make([]string, 0, len(records))
+	for _, record := range records {
+		sessionIDs = append(sessionIDs, record.SessionID)
+		lastMessageIDs = append(lastMessageIDs, record.LastMessageID)
+	}
+
+	sessions, err := d.communicationSessionDao.GetSessionsByIDs(ctx, sessionIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query inbox sessions failed")
+	}
+	// 关闭会话会更新记录，合并前可能同时存在新旧两条，以关闭的记录为准
+	sessionMap := make(map[string]*CommunicationSession, len(sessions))
+	for _, session := range sessions {
+		if existing, ok := sessionMap[session.SessionID]; !ok || !existing.Closed {
+			sessionMap[session.SessionID] = session
+		}
+	}
+
+	lastMessages, err := d.communicationMessageDao.GetMessagesByIDs(ctx, lastMessageIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query inbox last messages failed")
+	}
+	lastMessageMap := make(map[string]*CommunicationMessage, len(lastMessages))
+	for _, msg := range lastMessages {
+		if msg != nil {
+			lastMessageMap[msg.MessageID] = msg
+		}
+	}
+
+	senders, err := d.communicationMessageDao.GetSessionsSenders(ctx, sessionIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query inbox participants failed")
+	}
+	sendersMap := make(map[string][]*CommunicationMessage, len(sessionIDs))
+	for _, sender := range senders {
+		sendersMap[sender.SessionID] = append(sendersMap[sender.SessionID], sender)
+	}
+
+	for _, record := range records {
+		item := &dto.CommunicationInboxSessionDTO{
+			MessageCount: int64(record.MessageCount),
+			UnreadCount:  int64(record.UnreadCount),
+			Participants: []dto.CommunicationParticipantDTO{},
+			ActiveTime:   record.LastMessageTime,
+		}
+		seen := make(map[dto.CommunicationParticipantDTO]bool)
+		addParticipant := func(userID uint64, userType string) {
+			participant := dto.CommunicationParticipantDTO{UserID: userID, UserType: userType}
+			if !seen[participant] {
+				seen[participant] = true
+				item.Participants = append(item.Participants, participant)
+			}
+		}
+
+		if session, ok := sessionMap[record.SessionID]; ok {
+			item.Session = &dto.CommunicationSessionDTO{
+				SessionID:   session.SessionID,
+				UserID:      session.UserID,
+				UserType:    session.UserType,
+				SchoolID:    session.SchoolID,
+				CourseID:    utils.PtrValue(session.CourseID),
+				ClassroomID: utils.PtrValue(session.ClassroomID),
+				SessionType: session.SessionType,
+				TargetID:    session.TargetID,
+				Closed:      session.Closed,
+				StartTime:   session.StartTime,
+				EndTime:     session.EndTime,
+			}
+			addParticipant(session.UserID, session.UserType)
+		}
+		for _, sender := range sendersMap[record.SessionID] {
+			addParticipant(sender.UserID, sender.UserType)
+		}
+		if msg, ok := lastMessageMap[record.LastMessageID]; ok {
+			item.LastMessage = &dto.CommunicationMessageDTO{
+				MessageID:      msg.MessageID,
+				SessionID:      msg.SessionID,
+				UserID:         msg.UserID,
+				UserType:       msg.UserType,
+				MessageContent: msg.MessageContent,
+				MessageType:    msg.MessageType,
+				AnswerTo:       msg.AnswerTo,
+				CreatedAt:      msg.CreatedAt,
+			}
+		}
+		inbox.Sessions = append(inbox.Sessions, item)
+	}
+	return inbox, nil
+}
@@ -0,0 +1,288 @@
+package behavior
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils/idtools"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ClassroomReportDAO 课后课堂报告数据访问接口
+type ClassroomReportDAO interface {
+	// 保存课堂报告和学生学情统计，重复保存同一报告时覆盖
+	SaveClassroomReport(ctx context.Context, report *dto.ClassroomReportDTO, stats []*dto.ClassroomLearningStatsDTO) error
+	// 分页查询班级的课堂报告，按上课时间倒序
+	ListClassReports(ctx context.Context, schoolID, classID uint64, pageInfo *consts.DBPageInfo) ([]*dto.ClassroomReportDTO, int64, error)
+	// 查询课堂报告，不存在时返回 nil
+	GetClassroomReport(ctx context.Context, schoolID uint64, reportID string) (*dto.ClassroomReportDTO, error)
+	// 查询课堂报告最近一次生成的学生学情统计，按学习分倒序
+	GetClassroomLearningStats(ctx context.Context, reportID string, reportTime time.Time) ([]*dto.ClassroomLearningStatsDTO, error)
+	// 统计上课期间教师对学生的表扬和关注次数
+	CountClassroomTeacherActions(ctx context.Context, classroomID uint64, startTime, endTime time.Time) ([]*dto.ClassroomTeacherActionDTO, error)
+	// 统计上课期间发起的会话和消息数
+	CountClassroomCommunications(ctx context.Context, classroomID uint64, startTime, endTime time.Time) (*dto.ClassroomCommunicationStatsDTO, error)
+}
+
+// ClassroomReport 课堂报告表结构
+type ClassroomReport struct {
+	ID            string    `ch:"id"` // uuid
+	SchoolID      uint64    `ch:"school_id"`
+	ClassID       uint64    `ch:"class_id"`
+	ClassroomID   uint64    `ch:"classroom_id"`
+	TeacherID     uint64    `ch:"teacher_id"`
+	CourseID      uint64    `ch:"course_id"`
+	StartTime     time.Time `ch:"start_time"`
+	EndTime       time.Time `ch:"end_time"`
+	ReportContent string    `ch:"report_content"`
+	CreateTime    time.Time `ch:"create_time"`
+	UpdateTime    time.Time `ch:"update_time"`
+}
+
+func (m *ClassroomReport) TableName() string {
+	return "tbl_classroom_report"
+}
+
+// 报告 ID 在生成前已确定，这里只做兜底
+func (m *ClassroomReport) GenerateID(ctx context.Context) string {
+	if m.ID == "" {
+		m.ID = idtools.GetUUID()
+	}
+	return m.ID
+}
+
+// ClassroomLearningStats 课堂学情统计表结构
+type ClassroomLearningStats struct {
+	ID            string    `ch:"id"` // uuid
+	ReportID      string    `ch:"report_id"`
+	SchoolID      uint64    `ch:"school_id"`
+	ClassID       uint64    `ch:"class_id"`
+	CourseID      uint64    `ch:"course_id"`
+	ClassroomID   uint64    `ch:"classroom_id"`
+	StudentID     uint64    `ch:"student_id"`
+	LearningScore int64     `ch:"learning_score"`
+	Summary       string    `ch:"summary"`
+	ReportTime    time.Time `ch:"report_time"`
+}
+
+func (m *ClassroomLearningStats) TableName() string {
+	return "tbl_classroom_learning_stats"
+}
+
+// 由报告 ID 和学生 ID 生成，重新生成报告时覆盖旧记录
+func (m *ClassroomLearningStats) GenerateID(ctx context.Context) string {
+	if m.ID == "" {
+		m.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s#%d", m.ReportID, m.StudentID))).String()
+	}
+	return m.ID
+}
+
+// ClassroomReportDAOImpl 课后课堂报告数据访问对象实现
+type ClassroomReportDAOImpl struct {
+	db     *dao.ClickHouseRWClient
+	logger *clogger.ContextLogger
+}
+
+// NewClassroomReportDAO 创建课后课堂报告数据访问对象
+func NewClassroomReportDAO(chClients map[string]*dao.ClickHouseRWClient, logger *clogger.ContextLogger) ClassroomReportDAO {
+	return &ClassroomReportDAOImpl{
+		db:     chClients[consts.ChDBTeacher],
+		logger: logger,
+	}
+}
+
+// 先写学情统计再写报告，报告存在即说明统计已写入
+// 学情统计的 report_time 和报告的 update_time 相同，重新生成时不在本次结果中的学生旧记录按生成时间过滤掉
+func (d *ClassroomReportDAOImpl) SaveClassroomReport(ctx context.Context, report *dto.ClassroomReportDTO, stats []*dto.ClassroomLearningStatsDTO) error {
+	now := time.Now()
+	if len(stats) > 0 {
+		models := make([]*ClassroomLearningStats, 0, len(stats))
+		for _, stat := range stats {
+			models = append(models, &ClassroomLearningStats{
+				ReportID:      report.ReportID,
+				SchoolID:      report.SchoolID,
+				ClassID:       report.ClassID,
+				CourseID:      report.CourseID,
+				ClassroomID:   report.ClassroomID,
+				StudentID:     stat.StudentID,
+				LearningScore: stat.LearningScore,
+				Summary:       stat.Summary,
+				ReportTime:    now,
+			})
+		}
+		if _, err := d.db.Model(&ClassroomLearningStats{}).BatchInsert(ctx, models); err != nil {
+			return errors.Wrap(err, "save classroom learning stats failed")
+		}
+	}
+
+	model := &ClassroomReport{
+		ID:            report.ReportID,
+		SchoolID:      report.SchoolID,
+		ClassID:       report.ClassID,
+		ClassroomID:   report.ClassroomID,
+		TeacherID:     report.TeacherID,
+		CourseID:      report.CourseID,
+		StartTime:     report.StartTime,
+		EndTime:       report.EndTime,
+		ReportContent: report.Content,
+		CreateTime:    now,
+		UpdateTime:    now,
+	}
+	if _, err := d.db.Model(&ClassroomReport{}).BatchInsert(ctx, []*ClassroomReport{model}); err != nil {
+		return errors.Wrap(err, "save classroom report failed")
+	}
+	return nil
+}
+
+// 报告可能被重新生成，查询时使用 FINAL 去重
+func (d *ClassroomReportDAOImpl) ListClassReports(ctx context.Context, schoolID, classID uint64, pageInfo *consts.DBPageInfo) ([]*dto.ClassroomReportDTO, int64, error) {
+	table := (&ClassroomReport{}).TableName()
+	var total uint64
+	query := "SELECT count() FROM " + table + " FINAL WHERE school_id = ? AND class_id = ?"
+	if err := d.db.Read(ctx, &total, query, schoolID, classID); err != nil {
+		return nil, 0, errors.Wrap(err, "count classroom reports failed")
+	}
+	if total == 0 {
+		return []*dto.ClassroomReportDTO{}, 0, nil
+	}
+
+	pageInfo = consts.DefaultDBPageInfo(pageInfo)
+	records := make([]*ClassroomReport, 0)
+	query = fmt.Sprintf("SELECT * FROM %s FINAL WHERE school_id = ? AND class_id = ? ORDER BY start_time DESC LIMIT %d OFFSET %d",
+		table, pageInfo.Limit, (pageInfo.Page-1)*pageInfo.Limit)
+	if err := d.db.Read(ctx, &records, query, schoolID, classID); err != nil {
+		return nil, 0, errors.Wrap(err, "find classroom reports failed")
+	}
+
+	reports := make([]*dto.ClassroomReportDTO, 0, len(records))
+	for _, record := range records {
+		reports = append(reports, record.toDTO())
+	}
+	return reports, int64(total), nil
+}
+
+func (d *ClassroomReportDAOImpl) GetClassroomReport(ctx context.Context, schoolID uint64, reportID string) (*dto.ClassroomReportDTO, error) {
+	records := make([]*ClassroomReport, 0)
+	query := "SELECT * FROM " + (&ClassroomReport{}).TableName() + " FINAL WHERE school_id = ? AND id = ? LIMIT 1"
+	if err := d.db.Read(ctx, &records, query, schoolID, reportID); err != nil {
+		return nil, errors.Wrap(err, "find classroom report failed")
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0].toDTO(), nil
+}
+
+// 只返回和报告同一次生成的记录，之前生成的报告中有、本次没有的学生不再返回
+func (d *ClassroomReportDAOImpl) GetClassroomLearningStats(ctx context.Context, reportID string, reportTime time.Time) ([]*dto.ClassroomLearningStatsDTO, error) {
+	records := make([]*ClassroomLearningStats, 0)
+	query := "SELECT * FROM " + (&ClassroomLearningStats{}).TableName() + " FINAL WHERE report_id = ? AND report_time = ? ORDER BY learning_score DESC, student_id"
+	if err := d.db.Read(ctx, &records, query, reportID, reportTime); err != nil {
+		return nil, errors.Wrap(err, "find classroom learning stats failed")
+	}
+
+	stats := make([]*dto.ClassroomLearningStatsDTO, 0, len(records))
+	for _, record := range records {
+		stats = append(stats, &dto.ClassroomLearningStatsDTO{
+			StudentID:     record.StudentID,
+			LearningScore: record.LearningScore,
+			Summary:       record.Summary,
+		})
+	}
+	return stats, nil
+}
+
+// 表扬和关注每个学生记录一条教师行为，学生 ID 在 context 中
+func (d *ClassroomReportDAOImpl) CountClassroomTeacherActions(ctx context.Context, classroomID uint64, startTime, endTime time.Time) ([]*dto.ClassroomTeacherActionDTO, error) {
+	var records []*struct {
+		StudentID    uint64 `ch:"student_id"`
+		BehaviorType string `ch:"behavior_type"`
+		Cnt          uint64 `ch:"cnt"`
+	}
+	query := `
+		SELECT
+			JSONExtractUInt(context, 'studentId') AS student_id,
+			behavior_type,
+			count() AS cnt
+		FROM ` + (&TeacherBehavior{}).TableName() + `
+		WHERE classroom_id = ? AND behavior_type IN (?) AND create_time BETWEEN ? AND ?
+		GROUP BY student_id, behavior_type
+	`
+	behaviorTypes := []string{string(consts.BehaviorTypePraise), string(consts.BehaviorTypeAttention)}
+	if err := d.db.Read(ctx, &records, query, classroomID, behaviorTypes, startTime, endTime); err != nil {
+		return nil, errors.Wrap(err, "count classroom teacher actions failed")
+	}
+
+	actions := make([]*dto.ClassroomTeacherActionDTO, 0, len(records))
+	for _, record := range records {
+		actions = append(actions, &dto.ClassroomTeacherActionDTO{
+			StudentID:    record.StudentID,
+			BehaviorType: consts.BehaviorType(record.BehaviorType),
+			Count:        int64(record.Cnt),
+		})
+	}
+	return actions, nil
+}
+
+// 消息表没有课堂 ID，通过上课期间发起的会话关联
+func (d *ClassroomReportDAOImpl) CountClassroomCommunications(ctx context.Context, classroomID uint64, startTime, endTime time.Time) (*dto.ClassroomCommunicationStatsDTO, error) {
+	sessionTable := (&CommunicationSession{}).TableName()
+	sessionWhere := " WHERE classroom_id = ? AND start_time BETWEEN ? AND ?"
+
+	var sessionCount uint64
+	query := "SELECT count(DISTINCT session_id) FROM " + sessionTable + sessionWhere
+	if err := d.db.Read(ctx, &sessionCount, query, classroomID, startTime, endTime); err != nil {
+		return nil, errors.Wrap(err, "count classroom sessions failed")
+	}
+
+	stats := &dto.ClassroomCommunicationStatsDTO{SessionCount: int64(sessionCount)}
+	if sessionCount == 0 {
+		return stats, nil
+	}
+
+	var records []*struct {
+		UserType string `ch:"user_type"`
+		Cnt      uint64 `ch:"cnt"`
+	}
+	// 消息审核后会写入新版本，使用 FINAL 去重后再计数
+	query = "SELECT toString(user_type) AS user_type, count() AS cnt FROM " + (&CommunicationMessage{}).TableName() +
+		" FINAL WHERE session_id IN (SELECT session_id FROM " + sessionTable + sessionWhere + ") GROUP BY user_type"
+	if err := d.db.Read(ctx, &records, query, classroomID, startTime, endTime); err != nil {
+		return nil, errors.Wrap(err, "count classroom messages failed")
+	}
+
+	for _, record := range records {
+		switch consts.CommunicationUserType(record.UserType) {
+		case consts.CommunicationUserTypeStudent:
+			stats.StudentMessageCount = int64(record.Cnt)
+		case consts.CommunicationUserTypeTeacher:
+			stats.TeacherMessageCount = int64(record.Cnt)
+		case consts.CommunicationUserTypeAI:
+			stats.AIMessageCount = int64(record.Cnt)
+		}
+	}
+	return stats, nil
+}
+
+func (m *ClassroomReport) toDTO() *dto.ClassroomReportDTO {
+	return &dto.ClassroomReportDTO{
+		ReportID:    m.ID,
+		SchoolID:    m.SchoolID,
+		ClassID:     m.ClassID,
+		ClassroomID: m.ClassroomID,
+		TeacherID:   m.TeacherID,
+		CourseID:    m.CourseID,
+		StartTime:   m.StartTime,
+		EndTime:     m.EndTime,
+		Content:     m.ReportContent,
+		CreateTime:  m.CreateTime,
+		UpdateTime:  m.UpdateTime,
+	}
+}
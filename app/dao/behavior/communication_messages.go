@@ -2,6 +2,7 @@ package behavior
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"gil_teacher/app/consts"
@@ -69,10 +70,17 @@ func (m *CommunicationMessageDao) GetSessionParticipants(ctx context.Context, se
 	return records, nil
 }
 
-// 获取指定会话的全部消息
+// 获取指定会话的全部消息，消息审核后会覆盖内容，查询时使用 FINAL 去重
 func (m *CommunicationMessageDao) GetSessionMessages(ctx context.Context, sessionID string, pageInfo *consts.DBPageInfo) ([]*CommunicationMessage, error) {
+	pageInfo = consts.DefaultDBPageInfo(pageInfo)
+	query := "SELECT * FROM " + (&CommunicationMessage{}).TableName() + " FINAL WHERE session_id = ?"
+	if pageInfo.SortBy != "" {
+		query += fmt.Sprintf(" ORDER BY %s %s", pageInfo.SortBy, pageInfo.SortType)
+	}
+	query += fmt.Sprintf(" LIMIT %d OFFSET %d", pageInfo.Limit, (pageInfo.Page-1)*pageInfo.Limit)
+
 	records := make([]*CommunicationMessage, 0)
-	err := m.DB(ctx).FindAll(ctx, &records, map[string]any{"session_id": sessionID}, pageInfo)
+	err := m.DB(ctx).Read(ctx, &records, query, sessionID)
 	if err != nil {
 		return nil, errors.Wrap(err, "query session messages failed")
 	}
@@ -89,15 +97,15 @@ func (m *CommunicationMessageDao) GetClassroomMessages(ctx context.Context, clas
 	return records, nil
 }
 
-// 按消息 id 批量查询数据
+// 按消息 id 批量查询数据，使用 FINAL 取审核后的内容
 func (m *CommunicationMessageDao) GetMessagesByIDs(ctx context.Context, messageIDs []string) ([]*CommunicationMessage, error) {
 	if len(messageIDs) == 0 {
 		return nil, nil
 	}
 
-	// 预分配一定数量的结果对象
-	messages := make([]*CommunicationMessage, len(messageIDs))
-	err := m.DB(ctx).FindAll(ctx, &messages, map[string]any{"message_id": messageIDs}, nil)
+	messages := make([]*CommunicationMessage, 0, len(messageIDs))
+	query := "SELECT * FROM " + (&CommunicationMessage{}).TableName() + " FINAL WHERE message_id IN (?)"
+	err := m.DB(ctx).Read(ctx, &messages, query, messageIDs)
 	if err != nil {
 		return nil, errors.Wrap(err, "query messages by ids failed")
 	}
@@ -117,3 +125,63 @@ func (m *CommunicationMessageDao) CheckMessageIDsExist(ctx context.Context, mess
 	}
 	return len(existRecords) == len(messageIDs), nil
 }
+
+// 查询多个会话中指定用户类型的消息，按创建时间排序，使用 FINAL 取审核后的内容
+func (m *CommunicationMessageDao) GetSessionsMessagesByUserType(ctx context.Context, sessionIDs []string, userType string) ([]*CommunicationMessage, error) {
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+
+	records := make([]*CommunicationMessage, 0)
+	query := "SELECT * FROM " + (&CommunicationMessage{}).TableName() +
+		" FINAL WHERE session_id IN (?) AND user_type = ? ORDER BY created_at"
+	err := m.DB(ctx).Read(ctx, &records, query, sessionIDs, userType)
+	if err != nil {
+		return nil, errors.Wrap(err, "query sessions messages failed")
+	}
+	return records, nil
+}
+
+// 查询会话全部消息的 id 和发送时间，按发送时间排序，用于重建会话消息缓存
+func (m *CommunicationMessageDao) GetSessionMessageTimeline(ctx context.Context, sessionID string) ([]*CommunicationMessage, error) {
+	records := make([]*CommunicationMessage, 0)
+	query := "SELECT DISTINCT message_id, created_at FROM " + (&CommunicationMessage{}).TableName() +
+		" WHERE session_id = ? ORDER BY created_at"
+	err := m.DB(ctx).Read(ctx, &records, query, sessionID)
+	if err != nil {
+		return nil, errors.Wrap(err, "query session message timeline failed")
+	}
+	return records, nil
+}
+
+// 查询多个会话中发过消息的用户，去重
+func (m *CommunicationMessageDao) GetSessionsSenders(ctx context.Context, sessionIDs []string) ([]*CommunicationMessage, error) {
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+
+	records := make([]*CommunicationMessage, 0)
+	query := "SELECT DISTINCT session_id, user_id, user_type FROM " + (&CommunicationMessage{}).TableName() +
+		" WHERE session_id IN (?) ORDER BY session_id, user_type, user_id"
+	err := m.DB(ctx).Read(ctx, &records, query, sessionIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query sessions senders failed")
+	}
+	return records, nil
+}
+
+// 查询多个会话的全部消息，按创建时间排序
+func (m *CommunicationMessageDao) GetSessionsMessages(ctx context.Context, sessionIDs []string) ([]*CommunicationMessage, error) {
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+
+	records := make([]*CommunicationMessage, 0)
+	query := "SELECT * FROM " + (&CommunicationMessage{}).TableName() +
+		" FINAL WHERE session_id IN (?) ORDER BY created_at, message_id"
+	err := m.DB(ctx).Read(ctx, &records, query, sessionIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "query sessions messages failed")
+	}
+	return records, nil
+}
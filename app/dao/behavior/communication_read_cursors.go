@@ -0,0 +1,129 @@
+package behavior
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type CommunicationReadCursorDao struct {
+	db     *dao.ClickHouseRWClient
+	logger *clogger.ContextLogger
+}
+
+func newCommunicationReadCursorDao(db *dao.ClickHouseRWClient, logger *clogger.ContextLogger) *CommunicationReadCursorDao {
+	return &CommunicationReadCursorDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// CommunicationReadCursor 用户在会话中的已读位置，每次标记已读写入一条，按 update_time 保留最新记录
+type CommunicationReadCursor struct {
+	ID                string    `ch:"id"`                   // 由会话ID和用户生成
+	SessionID         string    `ch:"session_id"`           // 会话ID
+	UserID            uint64    `ch:"user_id"`              // 用户ID
+	UserType          string    `ch:"user_type"`            // 用户类型
+	LastReadMessageID string    `ch:"last_read_message_id"` // 最后已读消息ID
+	LastReadTime      time.Time `ch:"last_read_time"`       // 最后已读消息的发送时间
+	UpdateTime        time.Time `ch:"update_time"`          // 更新时间
+}
+
+func (m *CommunicationReadCursor) TableName() string {
+	return "tbl_communication_read_cursors"
+}
+
+// 同一用户在同一会话只有一个已读位置
+func (m *CommunicationReadCursor) GenerateID(ctx context.Context) string {
+	if m.ID == "" {
+		m.ID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("%s#%s#%d", m.SessionID, m.UserType, m.UserID))).String()
+	}
+	return m.ID
+}
+
+// CommunicationInboxSession 收件箱中单个会话的消息统计
+type CommunicationInboxSession struct {
+	SessionID       string    `ch:"session_id"`
+	MessageCount    uint64    `ch:"message_count"`
+	UnreadCount     uint64    `ch:"unread_count"`
+	LastMessageID   string    `ch:"last_message_id"`
+	LastMessageTime time.Time `ch:"last_message_time"`
+}
+
+// CommunicationInboxSummary 收件箱会话总数和未读总数
+type CommunicationInboxSummary struct {
+	SessionCount uint64 `ch:"session_count"`
+	UnreadCount  uint64 `ch:"unread_count"`
+}
+
+func (m *CommunicationReadCursorDao) DB(ctx context.Context) *dao.ClickHouseRWClient {
+	return m.db.Model(&CommunicationReadCursor{})
+}
+
+// 保存已读位置
+func (m *CommunicationReadCursorDao) SaveReadCursor(ctx context.Context, cursor *CommunicationReadCursor) error {
+	_, err := m.DB(ctx).BatchInsert(ctx, []*CommunicationReadCursor{cursor})
+	return err
+}
+
+// 查询用户在会话中的已读位置，没有时返回 nil
+func (m *CommunicationReadCursorDao) GetReadCursor(ctx context.Context, sessionID string, userID uint64, userType string) (*CommunicationReadCursor, error) {
+	records := make([]*CommunicationReadCursor, 0)
+	query := "SELECT * FROM " + (&CommunicationReadCursor{}).TableName() +
+		" WHERE session_id = ? AND user_id = ? AND user_type = ? ORDER BY last_read_time DESC, update_time DESC LIMIT 1"
+	if err := m.DB(ctx).Read(ctx, &records, query, sessionID, userID, userType); err != nil {
+		return nil, errors.Wrap(err, "query read cursor failed")
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0], nil
+}
+
+// 用户参与的会话：本人发起或在会话中发过消息，未读数按已读位置之后的消息统计。
+// 没有消息的会话不出现在收件箱中
+func (m *CommunicationReadCursorDao) inboxQuery(schoolID, userID uint64, userType string) (string, []any) {
+	query := "SELECT m.session_id AS session_id, count() AS message_count," +
+		" countIf(m.created_at > c.last_read_time) AS unread_count," +
+		" argMax(m.message_id, m.created_at) AS last_message_id, max(m.created_at) AS last_message_time" +
+		" FROM " + (&CommunicationMessage{}).TableName() + " AS m FINAL" +
+		" LEFT JOIN (SELECT session_id, max(last_read_time) AS last_read_time FROM " + (&CommunicationReadCursor{}).TableName() +
+		" WHERE user_id = ? AND user_type = ? GROUP BY session_id) AS c ON m.session_id = c.session_id" +
+		" WHERE m.session_id IN (SELECT session_id FROM " + (&CommunicationSession{}).TableName() +
+		" WHERE school_id = ? AND ((user_id = ? AND user_type = ?) OR session_id IN (SELECT session_id FROM " + (&CommunicationMessage{}).TableName() +
+		" WHERE user_id = ? AND user_type = ?)))" +
+		" GROUP BY m.session_id"
+	return query, []any{userID, userType, schoolID, userID, userType, userID, userType}
+}
+
+// 统计用户收件箱的会话数和未读消息总数
+func (m *CommunicationReadCursorDao) GetInboxSummary(ctx context.Context, schoolID, userID uint64, userType string) (*CommunicationInboxSummary, error) {
+	inbox, args := m.inboxQuery(schoolID, userID, userType)
+	summary := &CommunicationInboxSummary{}
+	query := "SELECT count() AS session_count, sum(unread_count) AS unread_count FROM (" + inbox + ")"
+	if err := m.DB(ctx).Read(ctx, summary, query, args...); err != nil {
+		return nil, errors.Wrap(err, "count inbox sessions failed")
+	}
+	return summary, nil
+}
+
+// 分页查询用户收件箱的会话，按最后一条消息时间倒序
+func (m *CommunicationReadCursorDao) GetInboxSessions(ctx context.Context, schoolID, userID uint64, userType string, pageInfo *consts.DBPageInfo) ([]*CommunicationInboxSession, error) {
+	pageInfo = consts.DefaultDBPageInfo(pageInfo)
+	inbox, args := m.inboxQuery(schoolID, userID, userType)
+	query := fmt.Sprintf("%s ORDER BY last_message_time DESC, session_id LIMIT %d OFFSET %d",
+		inbox, pageInfo.Limit, (pageInfo.Page-1)*pageInfo.Limit)
+
+	records := make([]*CommunicationInboxSession, 0)
+	if err := m.DB(ctx).Read(ctx, &records, query, args...); err != nil {
+		return nil, errors.Wrap(err, "query inbox sessions failed")
+	}
+	return records, nil
+}
@@ -105,3 +105,16 @@ func (m *CommunicationSessionDao) CheckSessionIDsExist(ctx context.Context, sess
 func (m *CommunicationSessionDao) UpdateCommunicationSession(ctx context.Context, update map[string]any, where map[string]any) error {
 	return m.DB(ctx).Update(ctx, update, where)
 }
+
+// 分页查询学生发起的 target_id 以指定前缀开头的提问会话，按开始时间和会话ID排序保证分页稳定
+func (m *CommunicationSessionDao) GetStudentQuestionSessionsByTargetPrefix(ctx context.Context, targetPrefix string, limit, offset int) ([]*CommunicationSession, error) {
+	dests := make([]*CommunicationSession, 0)
+	query := "SELECT * FROM " + (&CommunicationSession{}).TableName() +
+		" WHERE session_type = ? AND user_type = ? AND startsWith(target_id, ?) ORDER BY start_time, session_id LIMIT ? OFFSET ?"
+	err := m.DB(ctx).Read(ctx, &dests, query,
+		string(consts.CommunicationSessionTypeQuestion), string(consts.CommunicationUserTypeStudent), targetPrefix, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "query question sessions failed")
+	}
+	return dests, nil
+}
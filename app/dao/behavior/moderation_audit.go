@@ -0,0 +1,193 @@
+package behavior
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils/idtools"
+
+	"github.com/pkg/errors"
+)
+
+// ModerationAuditDAO 内容审核记录数据访问接口，内容当前的审核状态以最新一条记录为准
+type ModerationAuditDAO interface {
+	// 保存审核记录，相同审核记录ID重复保存时覆盖
+	SaveAudit(ctx context.Context, audit *dto.ModerationAuditDTO) error
+	// 查询内容最新的审核记录，没有时返回 nil
+	GetLatestAudit(ctx context.Context, schoolID uint64, contentType, contentID string) (*dto.ModerationAuditDTO, error)
+	// 查询班级内容的全部审核记录，按审核时间正序
+	ListAudits(ctx context.Context, schoolID uint64, classIDs []uint64, contentType, contentID string) ([]*dto.ModerationAuditDTO, error)
+	// 分页查询班级中最新审核结论为指定处理方式的内容，按审核时间正序，contentType 为空时不过滤
+	ListLatestAuditsByAction(ctx context.Context, schoolID uint64, classIDs []uint64, contentType, action string, pageInfo *consts.DBPageInfo) ([]*dto.ModerationAuditDTO, int64, error)
+}
+
+// ModerationAudit 内容审核记录表结构
+type ModerationAudit struct {
+	ID            string    `ch:"id"` // uuid
+	ContentType   string    `ch:"content_type"`
+	ContentID     string    `ch:"content_id"`
+	ParentID      string    `ch:"parent_id"`
+	SchoolID      uint64    `ch:"school_id"`
+	ClassID       uint64    `ch:"class_id"`
+	UserID        uint64    `ch:"user_id"`
+	UserType      string    `ch:"user_type"`
+	Content       string    `ch:"content"`
+	MaskedContent string    `ch:"masked_content"`
+	Action        string    `ch:"action"`
+	Category      string    `ch:"category"`
+	Severity      string    `ch:"severity"`
+	Reason        string    `ch:"reason"`
+	Source        string    `ch:"source"`
+	PromptVersion string    `ch:"prompt_version"`
+	ReviewerID    uint64    `ch:"reviewer_id"`
+	CreatedAt     time.Time `ch:"created_at"`
+}
+
+func (m *ModerationAudit) TableName() string {
+	return "tbl_moderation_audits"
+}
+
+// 自动审核的记录 ID 在审核时确定，重复消费时覆盖
+func (m *ModerationAudit) GenerateID(ctx context.Context) string {
+	if m.ID == "" {
+		m.ID = idtools.GetUUID()
+	}
+	return m.ID
+}
+
+func (m *ModerationAudit) toDTO() *dto.ModerationAuditDTO {
+	return &dto.ModerationAuditDTO{
+		AuditID:       m.ID,
+		ContentType:   m.ContentType,
+		ContentID:     m.ContentID,
+		ParentID:      m.ParentID,
+		SchoolID:      m.SchoolID,
+		ClassID:       m.ClassID,
+		UserID:        m.UserID,
+		UserType:      m.UserType,
+		Content:       m.Content,
+		MaskedContent: m.MaskedContent,
+		Action:        m.Action,
+		Category:      m.Category,
+		Severity:      m.Severity,
+		Reason:        m.Reason,
+		Source:        m.Source,
+		PromptVersion: m.PromptVersion,
+		ReviewerID:    m.ReviewerID,
+		CreatedAt:     m.CreatedAt,
+	}
+}
+
+// ModerationAuditDAOImpl 内容审核记录数据访问对象实现
+type ModerationAuditDAOImpl struct {
+	db     *dao.ClickHouseRWClient
+	logger *clogger.ContextLogger
+}
+
+// NewModerationAuditDAO 创建内容审核记录数据访问对象
+func NewModerationAuditDAO(chClients map[string]*dao.ClickHouseRWClient, logger *clogger.ContextLogger) ModerationAuditDAO {
+	return &ModerationAuditDAOImpl{
+		db:     chClients[consts.ChDBTeacher],
+		logger: logger,
+	}
+}
+
+func (d *ModerationAuditDAOImpl) SaveAudit(ctx context.Context, audit *dto.ModerationAuditDTO) error {
+	model := &ModerationAudit{
+		ID:            audit.AuditID,
+		ContentType:   audit.ContentType,
+		ContentID:     audit.ContentID,
+		ParentID:      audit.ParentID,
+		SchoolID:      audit.SchoolID,
+		ClassID:       audit.ClassID,
+		UserID:        audit.UserID,
+		UserType:      audit.UserType,
+		Content:       audit.Content,
+		MaskedContent: audit.MaskedContent,
+		Action:        audit.Action,
+		Category:      audit.Category,
+		Severity:      audit.Severity,
+		Reason:        audit.Reason,
+		Source:        audit.Source,
+		PromptVersion: audit.PromptVersion,
+		ReviewerID:    audit.ReviewerID,
+		CreatedAt:     audit.CreatedAt,
+	}
+	if _, err := d.db.Model(&ModerationAudit{}).BatchInsert(ctx, []*ModerationAudit{model}); err != nil {
+		return errors.Wrap(err, "save moderation audit failed")
+	}
+	audit.AuditID = model.ID
+	return nil
+}
+
+func (d *ModerationAuditDAOImpl) GetLatestAudit(ctx context.Context, schoolID uint64, contentType, contentID string) (*dto.ModerationAuditDTO, error) {
+	records := make([]*ModerationAudit, 0)
+	query := "SELECT * FROM " + (&ModerationAudit{}).TableName() +
+		" FINAL WHERE school_id = ? AND content_type = ? AND content_id = ? ORDER BY created_at DESC LIMIT 1"
+	if err := d.db.Read(ctx, &records, query, schoolID, contentType, contentID); err != nil {
+		return nil, errors.Wrap(err, "find latest moderation audit failed")
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[0].toDTO(), nil
+}
+
+func (d *ModerationAuditDAOImpl) ListAudits(ctx context.Context, schoolID uint64, classIDs []uint64, contentType, contentID string) ([]*dto.ModerationAuditDTO, error) {
+	if len(classIDs) == 0 {
+		return []*dto.ModerationAuditDTO{}, nil
+	}
+	records := make([]*ModerationAudit, 0)
+	query := "SELECT * FROM " + (&ModerationAudit{}).TableName() +
+		" FINAL WHERE school_id = ? AND class_id IN (?) AND content_type = ? AND content_id = ? ORDER BY created_at"
+	if err := d.db.Read(ctx, &records, query, schoolID, classIDs, contentType, contentID); err != nil {
+		return nil, errors.Wrap(err, "find moderation audits failed")
+	}
+
+	audits := make([]*dto.ModerationAuditDTO, 0, len(records))
+	for _, record := range records {
+		audits = append(audits, record.toDTO())
+	}
+	return audits, nil
+}
+
+// 每个内容取最新一条记录，再按处理方式过滤
+func (d *ModerationAuditDAOImpl) ListLatestAuditsByAction(ctx context.Context, schoolID uint64, classIDs []uint64, contentType, action string, pageInfo *consts.DBPageInfo) ([]*dto.ModerationAuditDTO, int64, error) {
+	if len(classIDs) == 0 {
+		return []*dto.ModerationAuditDTO{}, 0, nil
+	}
+	where, args := "school_id = ? AND class_id IN (?)", []any{schoolID, classIDs}
+	if contentType != "" {
+		where += " AND content_type = ?"
+		args = append(args, contentType)
+	}
+	latest := "SELECT * FROM (SELECT * FROM " + (&ModerationAudit{}).TableName() + " FINAL WHERE " + where +
+		" ORDER BY created_at DESC LIMIT 1 BY content_type, content_id) WHERE action = ?"
+	args = append(args, action)
+
+	var total uint64
+	if err := d.db.Read(ctx, &total, "SELECT count() FROM ("+latest+")", args...); err != nil {
+		return nil, 0, errors.Wrap(err, "count latest moderation audits failed")
+	}
+	if total == 0 {
+		return []*dto.ModerationAuditDTO{}, 0, nil
+	}
+
+	pageInfo = consts.DefaultDBPageInfo(pageInfo)
+	records := make([]*ModerationAudit, 0)
+	query := fmt.Sprintf("%s ORDER BY created_at, id LIMIT %d OFFSET %d", latest, pageInfo.Limit, (pageInfo.Page-1)*pageInfo.Limit)
+	if err := d.db.Read(ctx, &records, query, args...); err != nil {
+		return nil, 0, errors.Wrap(err, "find latest moderation audits failed")
+	}
+
+	audits := make([]*dto.ModerationAuditDTO, 0, len(records))
+	for _, record := range records {
+		audits = append(audits, record.toDTO())
+	}
+	return audits, int64(total), nil
+}
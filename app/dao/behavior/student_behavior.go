@@ -101,8 +101,19 @@ func (m *StudentBehaviorDao) SaveStudentBehaviors(ctx context.Context, behaviors
 	return nil
 }
 
-// GetClassLatestBehaviors 获取班级学生最新行为
-func (m *StudentBehaviorDao) GetClassLatestBehaviors(ctx context.Context, classRoomID uint64) ([]*dto.StudentLatestBehaviorDTO, error) {
+// classBehaviorWhere 班级学生行为的查询条件，startTime、endTime 为零值时不限制行为时间
+func classBehaviorWhere(classRoomID uint64, startTime, endTime time.Time) (string, []any) {
+	where := "classroom_id = ? AND behavior_type != 'class_comment'"
+	args := []any{classRoomID}
+	if !startTime.IsZero() && !endTime.IsZero() {
+		where += " AND create_time BETWEEN ? AND ?"
+		args = append(args, startTime, endTime)
+	}
+	return where, args
+}
+
+// GetClassLatestBehaviors 获取班级学生最新行为，startTime、endTime 为零值时不限制行为时间
+func (m *StudentBehaviorDao) GetClassLatestBehaviors(ctx context.Context, classRoomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error) {
 	// 定义查询结果接收结构
 	var records []*struct {
 		StudentID      uint64    `ch:"student_id"`
@@ -114,6 +125,7 @@ func (m *StudentBehaviorDao) GetClassLatestBehaviors(ctx context.Context, classR
 		CorrectAnswers uint64    `ch:"correct_answers"`
 	}
 
+	where, args := classBehaviorWhere(classRoomID, startTime, endTime)
 	// 使用 WITH 子句优化查询
 	query := `
 		WITH 
@@ -131,7 +143,7 @@ func (m *StudentBehaviorDao) GetClassLatestBehaviors(ctx context.Context, classR
 					count(*) OVER (PARTITION BY student_id),
 					0) as total_questions
 			FROM tbl_student_behavior_logs
-			WHERE classroom_id = ? AND behavior_type != 'class_comment'
+			WHERE ` + where + `
 			ORDER BY student_id, create_time DESC
 		)
 		SELECT 
@@ -149,7 +161,7 @@ func (m *StudentBehaviorDao) GetClassLatestBehaviors(ctx context.Context, classR
 	`
 
 	// 执行查询
-	if err := m.db.Read(ctx, &records, query, classRoomID); err != nil {
+	if err := m.db.Read(ctx, &records, query, args...); err != nil {
 		m.logger.Error(ctx, "查询班级学生最新行为失败: %v", err)
 		return nil, err
 	}
@@ -555,8 +567,8 @@ func (d *StudentBehaviorDao) GetStudentsBehaviors(ctx context.Context, studentID
 	return results, nil
 }
 
-// GetClassAllBehaviors 获取班级学生所有行为（用于汇总统计）
-func (m *StudentBehaviorDao) GetClassAllBehaviors(ctx context.Context, classRoomID uint64) ([]*dto.StudentLatestBehaviorDTO, error) {
+// GetClassAllBehaviors 获取班级学生所有行为（用于汇总统计），startTime、endTime 为零值时不限制行为时间
+func (m *StudentBehaviorDao) GetClassAllBehaviors(ctx context.Context, classRoomID uint64, startTime, endTime time.Time) ([]*dto.StudentLatestBehaviorDTO, error) {
 	// 定义查询结果接收结构
 	var records []*struct {
 		StudentID      uint64    `ch:"student_id"`
@@ -570,6 +582,7 @@ func (m *StudentBehaviorDao) GetClassAllBehaviors(ctx context.Context, classRoom
 
 	// 使用 WITH 子句优化查询，但不按学生ID分组取最新记录
 	// 而是获取所有记录用于汇总统计
+	where, args := classBehaviorWhere(classRoomID, startTime, endTime)
 	query := `
 		SELECT 
 			student_id,
@@ -582,12 +595,12 @@ func (m *StudentBehaviorDao) GetClassAllBehaviors(ctx context.Context, classRoom
 				0) as correct_answers,
 			if(behavior_type = 'Answer', 1, 0) as total_questions
 		FROM tbl_student_behavior_logs
-		WHERE classroom_id = ? AND behavior_type != 'class_comment'
+		WHERE ` + where + `
 		ORDER BY student_id DESC
 	`
 
 	// 执行查询
-	if err := m.db.Read(ctx, &records, query, classRoomID); err != nil {
+	if err := m.db.Read(ctx, &records, query, args...); err != nil {
 		m.logger.Error(ctx, "查询班级学生所有行为失败: %v", err)
 		return nil, err
 	}
@@ -0,0 +1,188 @@
+package behavior
+
+import (
+	"context"
+	"time"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/model/dto"
+
+	"github.com/pkg/errors"
+)
+
+// StudentProfileDAO 学生跨课堂行为画像数据访问接口，按时间范围汇总学生在班级中的行为
+type StudentProfileDAO interface {
+	// 查询学生在时间范围内上过的课的课后学情统计，按上课时间正序
+	ListStudentLessonStats(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentLessonStatsDTO, error)
+	// 按天、按类型统计教师对学生的表扬和关注次数，包含作业点赞和提醒
+	CountStudentTeacherActions(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentTeacherActionCountDTO, error)
+	// 按天、按行为类型统计学生行为
+	CountStudentBehaviors(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentBehaviorDayCountDTO, error)
+}
+
+// StudentProfileDAOImpl 学生跨课堂行为画像数据访问对象实现
+type StudentProfileDAOImpl struct {
+	teacherDB *dao.ClickHouseRWClient
+	studentDB *dao.ClickHouseRWClient
+	logger    *clogger.ContextLogger
+}
+
+// NewStudentProfileDAO 创建学生跨课堂行为画像数据访问对象
+func NewStudentProfileDAO(chClients map[string]*dao.ClickHouseRWClient, logger *clogger.ContextLogger) StudentProfileDAO {
+	return &StudentProfileDAOImpl{
+		teacherDB: chClients[consts.ChDBTeacher],
+		studentDB: chClients[consts.ChDBStudent],
+		logger:    logger,
+	}
+}
+
+// 学情统计没有上课时间，关联课堂报告按上课时间过滤，报告可能被重新生成，两张表都使用 FINAL 去重
+func (d *StudentProfileDAOImpl) ListStudentLessonStats(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentLessonStatsDTO, error) {
+	var records []*struct {
+		ReportID      string    `ch:"report_id"`
+		ClassroomID   uint64    `ch:"classroom_id"`
+		CourseID      uint64    `ch:"course_id"`
+		StartTime     time.Time `ch:"start_time"`
+		LearningScore int64     `ch:"learning_score"`
+		Summary       string    `ch:"summary"`
+	}
+	sql := `
+		SELECT
+			toString(s.report_id) AS report_id,
+			s.classroom_id AS classroom_id,
+			s.course_id AS course_id,
+			r.start_time AS start_time,
+			s.learning_score AS learning_score,
+			s.summary AS summary
+		FROM (
+			SELECT report_id, classroom_id, course_id, learning_score, summary
+			FROM ` + (&ClassroomLearningStats{}).TableName() + ` FINAL
+			WHERE school_id = ? AND class_id = ? AND student_id = ?
+		) AS s
+		INNER JOIN (
+			SELECT id, start_time
+			FROM ` + (&ClassroomReport{}).TableName() + ` FINAL
+			WHERE school_id = ? AND class_id = ? AND start_time >= ? AND start_time < ?
+		) AS r ON s.report_id = r.id
+		ORDER BY start_time
+	`
+	err := d.teacherDB.Read(ctx, &records, sql,
+		query.SchoolID, query.ClassID, query.StudentID,
+		query.SchoolID, query.ClassID, query.StartTime, query.EndTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "list student lesson stats failed")
+	}
+
+	stats := make([]*dto.StudentLessonStatsDTO, 0, len(records))
+	for _, record := range records {
+		stats = append(stats, &dto.StudentLessonStatsDTO{
+			ReportID:      record.ReportID,
+			ClassroomID:   record.ClassroomID,
+			CourseID:      record.CourseID,
+			StartTime:     record.StartTime,
+			LearningScore: record.LearningScore,
+			Summary:       record.Summary,
+		})
+	}
+	return stats, nil
+}
+
+// 课堂表扬和关注的学生 ID 在 context 中，作业点赞和提醒的学生 ID 在 student_id 列
+func (d *StudentProfileDAOImpl) CountStudentTeacherActions(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentTeacherActionCountDTO, error) {
+	var records []*struct {
+		Date         string `ch:"date"`
+		BehaviorType string `ch:"behavior_type"`
+		ActionType   string `ch:"action_type"`
+		Cnt          uint64 `ch:"cnt"`
+	}
+	sql := `
+		SELECT
+			toString(toDate(create_time, 'Asia/Shanghai')) AS date,
+			behavior_type,
+			multiIf(
+				behavior_type = ?, JSONExtractString(context, 'praiseType'),
+				behavior_type = ?, JSONExtractString(context, 'attentionType'),
+				''
+			) AS action_type,
+			count() AS cnt
+		FROM ` + (&TeacherBehavior{}).TableName() + `
+		WHERE school_id = ? AND class_id = ? AND behavior_type IN (?)
+			AND (student_id = ? OR JSONExtractUInt(context, 'studentId') = ?)
+			AND create_time >= ? AND create_time < ?
+		GROUP BY date, behavior_type, action_type
+		ORDER BY date
+	`
+	behaviorTypes := []string{
+		string(consts.BehaviorTypePraise),
+		string(consts.BehaviorTypeAttention),
+		string(consts.BehaviorTypeTaskPraise),
+		string(consts.BehaviorTypeTaskAttention),
+	}
+	err := d.teacherDB.Read(ctx, &records, sql,
+		string(consts.BehaviorTypePraise), string(consts.BehaviorTypeAttention),
+		query.SchoolID, query.ClassID, behaviorTypes,
+		query.StudentID, query.StudentID, query.StartTime, query.EndTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "count student teacher actions failed")
+	}
+
+	actions := make([]*dto.StudentTeacherActionCountDTO, 0, len(records))
+	for _, record := range records {
+		actions = append(actions, &dto.StudentTeacherActionCountDTO{
+			Date:         record.Date,
+			BehaviorType: consts.BehaviorType(record.BehaviorType),
+			ActionType:   record.ActionType,
+			Count:        int64(record.Cnt),
+		})
+	}
+	return actions, nil
+}
+
+// 历史数据中行为类型存在大小写混用，统一转为小写后分组
+func (d *StudentProfileDAOImpl) CountStudentBehaviors(ctx context.Context, query *dto.StudentProfileQueryDTO) ([]*dto.StudentBehaviorDayCountDTO, error) {
+	var records []*struct {
+		Date            string `ch:"date"`
+		BehaviorType    string `ch:"behavior_type"`
+		Cnt             uint64 `ch:"cnt"`
+		CorrectCnt      uint64 `ch:"correct_cnt"`
+		PageSwitchCnt   uint64 `ch:"page_switch_cnt"`
+		OtherContentCnt uint64 `ch:"other_content_cnt"`
+		PauseCnt        uint64 `ch:"pause_cnt"`
+	}
+	sql := `
+		SELECT
+			toString(toDate(create_time, 'Asia/Shanghai')) AS date,
+			lower(behavior_type) AS behavior_type,
+			count() AS cnt,
+			countIf(JSONExtractInt(context, 'isCorrect') = 1) AS correct_cnt,
+			sum(JSONExtractUInt(context, 'page_switch_count')) AS page_switch_cnt,
+			sum(JSONExtractUInt(context, 'other_content_count')) AS other_content_cnt,
+			sum(JSONExtractUInt(context, 'pause_count')) AS pause_cnt
+		FROM ` + (&StudentBehavior{}).TableName() + `
+		WHERE school_id = ? AND class_id = ? AND student_id = ?
+			AND create_time >= ? AND create_time < ?
+		GROUP BY date, behavior_type
+		ORDER BY date
+	`
+	err := d.studentDB.Read(ctx, &records, sql,
+		query.SchoolID, query.ClassID, query.StudentID, query.StartTime, query.EndTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "count student behaviors failed")
+	}
+
+	counts := make([]*dto.StudentBehaviorDayCountDTO, 0, len(records))
+	for _, record := range records {
+		counts = append(counts, &dto.StudentBehaviorDayCountDTO{
+			Date:              record.Date,
+			BehaviorType:      consts.BehaviorType(record.BehaviorType),
+			Count:             int64(record.Cnt),
+			CorrectCount:      int64(record.CorrectCnt),
+			PageSwitchCount:   int64(record.PageSwitchCnt),
+			OtherContentCount: int64(record.OtherContentCnt),
+			PauseCount:        int64(record.PauseCnt),
+		})
+	}
+	return counts, nil
+}
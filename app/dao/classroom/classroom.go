@@ -0,0 +1,147 @@
+package dao_classroom
+
+import (
+	"context"
+	"errors"
+
+	clogger "gil_teacher/app/core/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClassroomDAO 课堂数据访问接口
+type ClassroomDAO interface {
+	// GetOrCreate 按课堂ID和计划上课时间创建一节课，已存在时返回已有记录
+	GetOrCreate(ctx context.Context, classroom *Classroom) (*Classroom, error)
+	// GetLatest 查询教师最近一次开课的课堂记录，不存在时返回 nil
+	GetLatest(ctx context.Context, classroomID int64, teacherID int64) (*Classroom, error)
+	// Transition 从指定状态变更课堂，状态已被修改时返回 false
+	Transition(ctx context.Context, id int64, fromStatus int64, updates map[string]any) (bool, error)
+	// ExistsForTeacher 课堂是否由教师在学校中开过课
+	ExistsForTeacher(ctx context.Context, classroomID int64, teacherID int64, schoolID int64) (bool, error)
+	// GetClassIDs 查询课堂所属的班级，返回课堂ID => 班级ID，没有开课记录的课堂不返回
+	GetClassIDs(ctx context.Context, classroomIDs []int64) (map[int64]int64, error)
+}
+
+type classroomDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewClassroomDAO(db *gorm.DB, logger *clogger.ContextLogger) ClassroomDAO {
+	return &classroomDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Classroom 一节课的上课记录，同一课堂ID每周复用，按计划上课时间区分每节课
+type Classroom struct {
+	ID                 int64 `gorm:"column:id;type:bigserial;primaryKey" json:"id"`                              // 自增主键ID
+	ClassroomID        int64 `gorm:"column:classroom_id;type:bigint;not null" json:"classroomId"`                // 课堂ID，由课表ID或临时课表ID生成
+	TeacherID          int64 `gorm:"column:teacher_id;type:bigint;not null" json:"teacherId"`                    // 授课教师ID
+	SchoolID           int64 `gorm:"column:school_id;type:bigint;not null" json:"schoolId"`                      // 学校ID
+	ClassID            int64 `gorm:"column:class_id;type:bigint;not null" json:"classId"`                        // 班级ID
+	CourseID           int64 `gorm:"column:course_id;type:bigint;not null" json:"courseId"`                      // 课程ID
+	ScheduleID         int64 `gorm:"column:schedule_id;type:bigint;default:0" json:"scheduleId"`                 // 课表ID，临时课为0
+	TmpScheduleID      int64 `gorm:"column:tmp_schedule_id;type:bigint;default:0" json:"tmpScheduleId"`          // 临时课表ID，非临时课为0
+	Status             int64 `gorm:"column:status;type:bigint;not null" json:"status"`                           // 课堂状态
+	ScheduledStartTime int64 `gorm:"column:scheduled_start_time;type:bigint;not null" json:"scheduledStartTime"` // 课表计划上课时间
+	ScheduledEndTime   int64 `gorm:"column:scheduled_end_time;type:bigint;not null" json:"scheduledEndTime"`     // 课表计划下课时间
+	ActualStartTime    int64 `gorm:"column:actual_start_time;type:bigint;default:0" json:"actualStartTime"`      // 实际开始上课时间
+	ActualEndTime      int64 `gorm:"column:actual_end_time;type:bigint;default:0" json:"actualEndTime"`          // 实际结束上课时间
+	PausedAt           int64 `gorm:"column:paused_at;type:bigint;default:0" json:"pausedAt"`                     // 最近一次暂停时间，未暂停为0
+	PausedDuration     int64 `gorm:"column:paused_duration;type:bigint;default:0" json:"pausedDuration"`         // 累计暂停时长(秒)
+	CreateTime         int64 `gorm:"column:create_time;type:bigint;autoCreateTime" json:"createTime"`            // 创建时间
+	UpdateTime         int64 `gorm:"column:update_time;type:bigint;autoUpdateTime" json:"updateTime"`            // 更新时间
+}
+
+// TableName 指定表名
+func (Classroom) TableName() string {
+	return "tbl_classroom"
+}
+
+func (d *classroomDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&Classroom{})
+}
+
+// 多个端同时开课时只插入一条，之后统一按唯一键读取
+func (d *classroomDao) GetOrCreate(ctx context.Context, classroom *Classroom) (*Classroom, error) {
+	if classroom == nil {
+		return nil, errors.New("entity is nil")
+	}
+	err := d.DB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "classroom_id"}, {Name: "scheduled_start_time"}},
+		DoNothing: true,
+	}).Create(classroom).Error
+	if err != nil {
+		d.logger.Error(ctx, "[GetOrCreate] 创建课堂失败, classroom: %+v, err: %v", classroom, err)
+		return nil, err
+	}
+
+	var result Classroom
+	err = d.DB(ctx).Where("classroom_id = ? AND scheduled_start_time = ?", classroom.ClassroomID, classroom.ScheduledStartTime).
+		Take(&result).Error
+	if err != nil {
+		d.logger.Error(ctx, "[GetOrCreate] 查询课堂失败, classroomID: %d, err: %v", classroom.ClassroomID, err)
+		return nil, err
+	}
+	return &result, nil
+}
+
+// 查询教师最近一次开课的课堂记录
+func (d *classroomDao) GetLatest(ctx context.Context, classroomID int64, teacherID int64) (*Classroom, error) {
+	var result Classroom
+	err := d.DB(ctx).Where("classroom_id = ? AND teacher_id = ?", classroomID, teacherID).
+		Order("scheduled_start_time DESC").Take(&result).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		d.logger.Error(ctx, "[GetLatest] 查询课堂失败, classroomID: %d, teacherID: %d, err: %v", classroomID, teacherID, err)
+		return nil, err
+	}
+	return &result, nil
+}
+
+// 从指定状态变更课堂，并发操作同一节课时只有一个生效
+func (d *classroomDao) Transition(ctx context.Context, id int64, fromStatus int64, updates map[string]any) (bool, error) {
+	result := d.DB(ctx).Where("id = ? AND status = ?", id, fromStatus).Updates(updates)
+	if result.Error != nil {
+		d.logger.Error(ctx, "[Transition] 变更课堂状态失败, id: %d, fromStatus: %d, err: %v", id, fromStatus, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// 课堂是否由教师在学校中开过课
+func (d *classroomDao) ExistsForTeacher(ctx context.Context, classroomID int64, teacherID int64, schoolID int64) (bool, error) {
+	var count int64
+	err := d.DB(ctx).Where("classroom_id = ? AND teacher_id = ? AND school_id = ?", classroomID, teacherID, schoolID).
+		Limit(1).Count(&count).Error
+	if err != nil {
+		d.logger.Error(ctx, "[ExistsForTeacher] 查询课堂失败, classroomID: %d, teacherID: %d, err: %v", classroomID, teacherID, err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// 同一课堂ID由课表生成，每节课的班级相同，取任意一条开课记录即可
+func (d *classroomDao) GetClassIDs(ctx context.Context, classroomIDs []int64) (map[int64]int64, error) {
+	result := make(map[int64]int64, len(classroomIDs))
+	if len(classroomIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []*Classroom
+	err := d.DB(ctx).Distinct("classroom_id", "class_id").Where("classroom_id IN ?", classroomIDs).Find(&rows).Error
+	if err != nil {
+		d.logger.Error(ctx, "[GetClassIDs] 查询课堂班级失败, classroomIDs: %v, err: %v", classroomIDs, err)
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ClassroomID] = row.ClassID
+	}
+	return result, nil
+}
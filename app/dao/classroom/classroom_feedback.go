@@ -0,0 +1,219 @@
+package dao_classroom
+
+import (
+	"context"
+	"errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+
+	"gorm.io/gorm"
+)
+
+// ClassroomFeedbackDAO 课堂反馈数据访问接口
+type ClassroomFeedbackDAO interface {
+	// Create 创建课堂反馈
+	Create(ctx context.Context, feedback *ClassroomFeedback) error
+	// List 按条件分页查询课堂反馈，按创建时间倒序
+	List(ctx context.Context, query *ClassroomFeedbackQuery, page, pageSize int64) ([]*ClassroomFeedback, int64, error)
+	// CountByDay 按条件统计每天各类型、状态、备注标识的反馈数
+	CountByDay(ctx context.Context, query *ClassroomFeedbackQuery) ([]*ClassroomFeedbackCount, error)
+	// GetByIDs 查询学校中的课堂反馈
+	GetByIDs(ctx context.Context, schoolID int64, ids []int64) ([]*ClassroomFeedback, error)
+	// MarkHandled 将待处理的反馈标记为已处理，返回实际标记的数量
+	MarkHandled(ctx context.Context, schoolID int64, ids []int64, handlerID int64, handleTime int64) (int64, error)
+	// UpdateModeration 更新反馈的审核状态，content 不为空时同时替换反馈内容
+	UpdateModeration(ctx context.Context, schoolID, id int64, status int64, content string) error
+	// ListModerationPending 查询在 createdBefore 之前提交且仍待审核的反馈，按ID正序
+	ListModerationPending(ctx context.Context, createdBefore int64, limit int) ([]*ClassroomFeedback, error)
+}
+
+type classroomFeedbackDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewClassroomFeedbackDAO(db *gorm.DB, logger *clogger.ContextLogger) ClassroomFeedbackDAO {
+	return &classroomFeedbackDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ClassroomFeedback 学生或教师提交的课堂反馈
+type ClassroomFeedback struct {
+	ID               int64  `gorm:"column:id;type:bigserial;primaryKey" json:"feedbackId"`                  // 自增主键ID，即反馈ID
+	UserID           int64  `gorm:"column:user_id;type:bigint;not null" json:"userId"`                      // 反馈用户ID，学生ID或教师ID
+	SchoolID         int64  `gorm:"column:school_id;type:bigint;not null" json:"-"`                         // 学校ID
+	ClassID          int64  `gorm:"column:class_id;type:bigint" json:"classId"`                             // 班级ID
+	ClassroomID      int64  `gorm:"column:classroom_id;type:bigint;default:0" json:"classroomId"`           // 课堂ID，未关联课堂为0
+	Content          string `gorm:"column:content;type:text" json:"content"`                                // 反馈内容
+	Remark           int64  `gorm:"column:remark;type:bigint" json:"remark"`                                // 反馈备注标识，客户端定义的反馈选项
+	CreateType       int64  `gorm:"column:create_type;type:bigint;not null" json:"createType"`              // 反馈创建类型
+	Status           int64  `gorm:"column:status;type:bigint;default:0" json:"status"`                      // 处理状态
+	HandlerID        int64  `gorm:"column:handler_id;type:bigint;default:0" json:"handlerId"`               // 处理反馈的教师ID
+	HandleTime       int64  `gorm:"column:handle_time;type:bigint;default:0" json:"handleTime"`             // 反馈处理时间
+	ModerationStatus int64  `gorm:"column:moderation_status;type:bigint;default:0" json:"moderationStatus"` // 内容审核状态，待教师审核和不予展示的反馈不出现在查询和统计中
+	CreateTime       int64  `gorm:"column:create_time;type:bigint;autoCreateTime" json:"createTime"`        // 创建时间
+	UpdateTime       int64  `gorm:"column:update_time;type:bigint;autoUpdateTime" json:"updateTime"`        // 更新时间
+}
+
+// TableName 指定表名
+func (ClassroomFeedback) TableName() string {
+	return "tbl_classroom_feedback"
+}
+
+// ClassroomFeedbackQuery 课堂反馈查询条件，为 0 或 nil 的条件不过滤
+type ClassroomFeedbackQuery struct {
+	SchoolID    int64
+	ClassID     int64
+	ClassroomID int64
+	CreateType  int64
+	Status      *int64
+	StartTime   int64 // 创建时间起点，包含
+	EndTime     int64 // 创建时间终点，不包含
+}
+
+// ClassroomFeedbackCount 按天分组的课堂反馈数
+type ClassroomFeedbackCount struct {
+	Date       string `gorm:"column:date"`        // 日期，北京时间 YYYY-MM-DD
+	CreateType int64  `gorm:"column:create_type"` // 反馈创建类型
+	Status     int64  `gorm:"column:status"`      // 处理状态
+	Remark     int64  `gorm:"column:remark"`      // 反馈备注标识
+	Count      int64  `gorm:"column:count"`       // 反馈数
+}
+
+func (d *classroomFeedbackDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&ClassroomFeedback{})
+}
+
+func (d *classroomFeedbackDao) where(ctx context.Context, query *ClassroomFeedbackQuery) *gorm.DB {
+	db := d.DB(ctx).Where("school_id = ? AND moderation_status NOT IN ?", query.SchoolID,
+		[]int64{consts.ModerationStatusHeld, consts.ModerationStatusRejected})
+	if query.ClassID > 0 {
+		db = db.Where("class_id = ?", query.ClassID)
+	}
+	if query.ClassroomID > 0 {
+		db = db.Where("classroom_id = ?", query.ClassroomID)
+	}
+	if query.CreateType > 0 {
+		db = db.Where("create_type = ?", query.CreateType)
+	}
+	if query.Status != nil {
+		db = db.Where("status = ?", *query.Status)
+	}
+	if query.StartTime > 0 {
+		db = db.Where("create_time >= ?", query.StartTime)
+	}
+	if query.EndTime > 0 {
+		db = db.Where("create_time < ?", query.EndTime)
+	}
+	return db
+}
+
+// 创建课堂反馈
+func (d *classroomFeedbackDao) Create(ctx context.Context, feedback *ClassroomFeedback) error {
+	if feedback == nil {
+		return errors.New("entity is nil")
+	}
+	if err := d.DB(ctx).Create(feedback).Error; err != nil {
+		d.logger.Error(ctx, "[Create] 创建课堂反馈失败, feedback: %+v, err: %v", feedback, err)
+		return err
+	}
+	return nil
+}
+
+// 按条件分页查询课堂反馈
+func (d *classroomFeedbackDao) List(ctx context.Context, query *ClassroomFeedbackQuery, page, pageSize int64) ([]*ClassroomFeedback, int64, error) {
+	var total int64
+	if err := d.where(ctx, query).Count(&total).Error; err != nil {
+		d.logger.Error(ctx, "[List] 统计课堂反馈失败, query: %+v, err: %v", query, err)
+		return nil, 0, err
+	}
+
+	feedbacks := make([]*ClassroomFeedback, 0)
+	if total == 0 {
+		return feedbacks, 0, nil
+	}
+	err := d.where(ctx, query).Order("create_time DESC, id DESC").
+		Offset(int((page - 1) * pageSize)).Limit(int(pageSize)).Find(&feedbacks).Error
+	if err != nil {
+		d.logger.Error(ctx, "[List] 查询课堂反馈失败, query: %+v, err: %v", query, err)
+		return nil, 0, err
+	}
+	return feedbacks, total, nil
+}
+
+// 按北京时间的日期分组统计课堂反馈数
+func (d *classroomFeedbackDao) CountByDay(ctx context.Context, query *ClassroomFeedbackQuery) ([]*ClassroomFeedbackCount, error) {
+	counts := make([]*ClassroomFeedbackCount, 0)
+	err := d.where(ctx, query).
+		Select("TO_CHAR(TO_TIMESTAMP(create_time) AT TIME ZONE 'Asia/Shanghai', 'YYYY-MM-DD') AS date, " +
+			"create_type, status, COALESCE(remark, 0) AS remark, COUNT(*) AS count").
+		Group("date, create_type, status, COALESCE(remark, 0)").
+		Order("date").
+		Scan(&counts).Error
+	if err != nil {
+		d.logger.Error(ctx, "[CountByDay] 统计课堂反馈失败, query: %+v, err: %v", query, err)
+		return nil, err
+	}
+	return counts, nil
+}
+
+// 查询学校中的课堂反馈
+func (d *classroomFeedbackDao) GetByIDs(ctx context.Context, schoolID int64, ids []int64) ([]*ClassroomFeedback, error) {
+	feedbacks := make([]*ClassroomFeedback, 0)
+	if len(ids) == 0 {
+		return feedbacks, nil
+	}
+	if err := d.DB(ctx).Where("school_id = ? AND id IN ?", schoolID, ids).Find(&feedbacks).Error; err != nil {
+		d.logger.Error(ctx, "[GetByIDs] 查询课堂反馈失败, ids: %v, err: %v", ids, err)
+		return nil, err
+	}
+	return feedbacks, nil
+}
+
+// 将待处理的反馈标记为已处理，已处理的反馈保留第一次处理的教师和时间
+func (d *classroomFeedbackDao) MarkHandled(ctx context.Context, schoolID int64, ids []int64, handlerID int64, handleTime int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := d.DB(ctx).
+		Where("school_id = ? AND id IN ? AND status = ?", schoolID, ids, consts.ClassroomFeedbackStatusPending).
+		Updates(map[string]any{
+			"status":      consts.ClassroomFeedbackStatusHandled,
+			"handler_id":  handlerID,
+			"handle_time": handleTime,
+		})
+	if result.Error != nil {
+		d.logger.Error(ctx, "[MarkHandled] 标记课堂反馈已处理失败, ids: %v, err: %v", ids, result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// 更新审核状态，屏蔽违规片段时同时替换内容
+func (d *classroomFeedbackDao) UpdateModeration(ctx context.Context, schoolID, id int64, status int64, content string) error {
+	updates := map[string]any{"moderation_status": status}
+	if content != "" {
+		updates["content"] = content
+	}
+	if err := d.DB(ctx).Where("school_id = ? AND id = ?", schoolID, id).Updates(updates).Error; err != nil {
+		d.logger.Error(ctx, "[UpdateModeration] 更新课堂反馈审核状态失败, id: %d, status: %d, err: %v", id, status, err)
+		return err
+	}
+	return nil
+}
+
+// 查询仍待审核的反馈，用于补偿投递失败或审核失败的反馈
+func (d *classroomFeedbackDao) ListModerationPending(ctx context.Context, createdBefore int64, limit int) ([]*ClassroomFeedback, error) {
+	feedbacks := make([]*ClassroomFeedback, 0)
+	err := d.DB(ctx).
+		Where("moderation_status = ? AND create_time < ?", consts.ModerationStatusPending, createdBefore).
+		Order("id").Limit(limit).Find(&feedbacks).Error
+	if err != nil {
+		d.logger.Error(ctx, "[ListModerationPending] 查询待审核课堂反馈失败, err: %v", err)
+		return nil, err
+	}
+	return feedbacks, nil
+}
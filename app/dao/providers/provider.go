@@ -3,6 +3,7 @@ package providers
 import (
 	"gil_teacher/app/dao"
 	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_classroom "gil_teacher/app/dao/classroom"
 	"gil_teacher/app/dao/live_room/impl"
 	"gil_teacher/app/dao/resource_favorite"
 	dao_task "gil_teacher/app/dao/task"
@@ -22,16 +23,21 @@ func ResourceFavoriteDAOProvider(db *gorm.DB) *resource_favorite.ResourceFavorit
 }
 
 var RepoProviderSet = wire.NewSet(
-	dao.NewActivityDB,           // 提供ActivityDB实例
-	dao.NewDBTestClient,         // 提供DBTestClient实例
-	dao.NewPostgreSQLClient,     // 提供PostgreSQLClient实例
-	dao.NewClickHouseRWClient,   // 提供ClickHouse读写实例
-	impl.NewLiveRoomDao,         // 提供LiveRoom DAO
-	ProvidePostgreSQLDB,         // 提供GORM DB实例
-	dao.NewApiRedisClient,       // 提供API Redis实例
-	dao_task.TaskDAOProvider,    // 提供任务数据DAO
-	ResourceFavoriteDAOProvider, // 提供资源收藏DAO
-	ResourceDAOProvider,         // 提供资源DAO
-	FileRecordDAOProvider,       // 提供文件记录DAO
-	behaviorDao.NewBehaviorDAO,  // 提供行为DAO
+	dao.NewActivityDB,                     // 提供ActivityDB实例
+	dao.NewDBTestClient,                   // 提供DBTestClient实例
+	dao.NewPostgreSQLClient,               // 提供PostgreSQLClient实例
+	dao.NewClickHouseRWClient,             // 提供ClickHouse读写实例
+	impl.NewLiveRoomDao,                   // 提供LiveRoom DAO
+	ProvidePostgreSQLDB,                   // 提供GORM DB实例
+	dao.NewApiRedisClient,                 // 提供API Redis实例
+	dao_task.TaskDAOProvider,              // 提供任务数据DAO
+	ResourceFavoriteDAOProvider,           // 提供资源收藏DAO
+	ResourceDAOProvider,                   // 提供资源DAO
+	FileRecordDAOProvider,                 // 提供文件记录DAO
+	behaviorDao.NewBehaviorDAO,            // 提供行为DAO
+	behaviorDao.NewClassroomReportDAO,     // 提供课后课堂报告DAO
+	behaviorDao.NewStudentProfileDAO,      // 提供学生行为画像DAO
+	behaviorDao.NewModerationAuditDAO,     // 提供内容审核记录DAO
+	dao_classroom.NewClassroomDAO,         // 提供课堂DAO
+	dao_classroom.NewClassroomFeedbackDAO, // 提供课堂反馈DAO
 )
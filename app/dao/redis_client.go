@@ -300,6 +300,39 @@ func (c *ApiRdbClient) KeyExists(ctx context.Context, key string) (bool, error)
 	return result.Val() > 0, nil
 }
 
+// SetNX key 不存在时写入数据，用于分布式锁和幂等标记
+// key: redis中的key
+// data: 任意数据，包括基本类型和结构体
+// ttlSec: 过期时间，秒
+// 返回值: (是否写入成功, 错误信息)
+func (c *ApiRdbClient) SetNX(ctx context.Context, key string, data any, ttlSec int64) (bool, error) {
+	if err := c.checkParams(key, data, nil); err != nil {
+		return false, err
+	}
+
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		return false, fmt.Errorf("数据序列化失败: %w", err)
+	}
+
+	result := (*redis.Client)(c).SetNX(ctx, c.realKey(key), bytes, c.expire(ttlSec))
+	if err := c.handleRedisError(result.Err(), "写入redis"); err != nil {
+		return false, err
+	}
+
+	return result.Val(), nil
+}
+
+// Del 删除 key
+func (c *ApiRdbClient) Del(ctx context.Context, key string) error {
+	if err := c.checkParams(key, nil, nil); err != nil {
+		return err
+	}
+
+	result := (*redis.Client)(c).Del(ctx, c.realKey(key))
+	return c.handleRedisError(result.Err(), "删除redis数据")
+}
+
 // ZAdd 向有序集合添加一个成员
 // key: redis中的key
 // score: 成员的分数
@@ -447,6 +480,23 @@ func (c *ApiRdbClient) Keys(ctx context.Context, pattern string, dest *[]string)
 	return true, nil
 }
 
+// Scan 按游标增量遍历符合给定模式的键，不会像 Keys 一样阻塞 redis
+// cursor: 游标，首次传 0
+// pattern: 匹配模式
+// count: 每批建议返回的数量
+// 返回值: (本批次的键, 下一次的游标，为 0 时遍历结束, 错误信息)
+func (c *ApiRdbClient) Scan(ctx context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	if err := c.checkParams(pattern, nil, nil); err != nil {
+		return nil, 0, err
+	}
+
+	keys, next, err := (*redis.Client)(c).Scan(ctx, cursor, c.realKey(pattern), count).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("遍历键失败: %w", err)
+	}
+	return keys, next, nil
+}
+
 // SMembers 返回集合中的所有成员
 // key: redis中的key
 // dest: 目标切片指针，用于存储结果
@@ -537,7 +587,7 @@ func (c *ApiRdbClient) ZRem(ctx context.Context, key string, member string) (boo
 // max: 最大分数
 // 返回值: (key是否存在, 错误信息)
 func (c *ApiRdbClient) ZRemRangeByScore(ctx context.Context, key string, min, max string) (bool, error) {
-	if err := c.checkParams(key, min, max); err != nil {
+	if err := c.checkParams(key, min, nil); err != nil {
 		return false, err
 	}
 
@@ -636,6 +686,26 @@ func (c *ApiRdbClient) SCard(ctx context.Context, key string, dest *int64) (bool
 	return true, nil
 }
 
+// Publish 向频道发布消息，频道名称与 key 使用相同的公共前缀
+// channel: 频道名称
+// message: 消息内容
+// 返回值: 错误信息
+func (c *ApiRdbClient) Publish(ctx context.Context, channel string, message string) error {
+	if err := c.checkParams(channel, message, nil); err != nil {
+		return err
+	}
+
+	result := (*redis.Client)(c).Publish(ctx, c.realKey(channel), message)
+	return c.handleRedisError(result.Err(), "发布消息")
+}
+
+// Subscribe 订阅频道，调用方负责关闭返回的订阅
+// channel: 频道名称
+// 返回值: 订阅
+func (c *ApiRdbClient) Subscribe(ctx context.Context, channel string) *redis.PubSub {
+	return (*redis.Client)(c).Subscribe(ctx, c.realKey(channel))
+}
+
 /****************************************
 				类型转换
 ****************************************/
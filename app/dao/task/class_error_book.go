@@ -0,0 +1,194 @@
+package dao_task
+
+import (
+	"context"
+	"errors"
+
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/core/postgresqlx"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type classErrorBookDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewClassErrorBookDao(db *gorm.DB, logger *clogger.ContextLogger) ClassErrorBookDAO {
+	return &classErrorBookDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ClassErrorBookItem 班级共性错题本中的一道题，同一班级学科下同一题目只有一条记录
+// 每次布置中题目的答题人数和正确率达到班级报告设置的共性错题阈值时记录一次出现
+type ClassErrorBookItem struct {
+	ID              int64                  `gorm:"column:id;type:bigserial;primaryKey" json:"itemId"`                   // 自增主键ID
+	SchoolID        int64                  `gorm:"column:school_id;type:bigint;not null" json:"-"`                      // 学校ID
+	ClassID         int64                  `gorm:"column:class_id;type:bigint;not null" json:"classId"`                 // 班级ID
+	Subject         int64                  `gorm:"column:subject;type:bigint;not null" json:"subject"`                  // 学科
+	QuestionID      string                 `gorm:"column:question_id;type:varchar(64);not null" json:"questionId"`      // 题目ID
+	ResourceID      string                 `gorm:"column:resource_id;type:varchar(16);not null" json:"resourceId"`      // 最近一次出现时题目所属资源ID
+	ResourceType    int64                  `gorm:"column:resource_type;type:bigint;not null" json:"resourceType"`       // 最近一次出现时题目所属资源类型
+	FirstTaskID     int64                  `gorm:"column:first_task_id;type:bigint;not null" json:"firstTaskId"`        // 第一次出现的任务ID
+	FirstAssignID   int64                  `gorm:"column:first_assign_id;type:bigint;not null" json:"firstAssignId"`    // 第一次出现的布置ID
+	FirstSeenTime   int64                  `gorm:"column:first_seen_time;type:bigint;not null" json:"firstSeenTime"`    // 第一次出现的布置开始时间
+	LastTaskID      int64                  `gorm:"column:last_task_id;type:bigint;not null" json:"lastTaskId"`          // 最近一次出现的任务ID
+	LastAssignID    int64                  `gorm:"column:last_assign_id;type:bigint;not null" json:"lastAssignId"`      // 最近一次出现的布置ID
+	LastSeenTime    int64                  `gorm:"column:last_seen_time;type:bigint;not null" json:"lastSeenTime"`      // 最近一次出现的布置开始时间
+	AssignIDs       postgresqlx.Int64Array `gorm:"column:assign_ids;type:bigint[]" json:"-"`                            // 出现过的布置ID，重复汇总同一布置不重复计数
+	OccurrenceCount int64                  `gorm:"column:occurrence_count;type:bigint;not null" json:"occurrenceCount"` // 出现次数，即出现过的布置数
+	AnswerCount     int64                  `gorm:"column:answer_count;type:bigint;not null" json:"answerCount"`         // 最近一次出现时的答题人数
+	IncorrectCount  int64                  `gorm:"column:incorrect_count;type:bigint;not null" json:"incorrectCount"`   // 最近一次出现时的答错人数
+	CorrectRate     float64                `gorm:"column:correct_rate;type:numeric(5,4);not null" json:"correctRate"`   // 最近一次出现时的正确率
+	ExplainTime     int64                  `gorm:"column:explain_time;type:bigint;default:0" json:"explainTime"`        // 标记已讲解的时间，0 为未讲解
+	ExplainerID     int64                  `gorm:"column:explainer_id;type:bigint;default:0" json:"explainerId"`        // 标记已讲解的教师ID
+	CreateTime      int64                  `gorm:"column:create_time;type:bigint;autoCreateTime" json:"createTime"`     // 创建时间
+	UpdateTime      int64                  `gorm:"column:update_time;type:bigint;autoUpdateTime" json:"updateTime"`     // 更新时间
+}
+
+// TableName 指定表名
+func (ClassErrorBookItem) TableName() string {
+	return "tbl_class_error_book"
+}
+
+func (d *classErrorBookDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&ClassErrorBookItem{})
+}
+
+// 记录题目在一次布置中的出现，按 school_id、class_id、subject、question_id 合并
+// 同一布置重复写入时只更新该布置的作答数据，不增加出现次数；first/last 按布置开始时间比较，乱序写入结果一致
+func (d *classErrorBookDao) BatchUpsert(ctx context.Context, items []*ClassErrorBookItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	const (
+		table  = "tbl_class_error_book"
+		seen   = table + ".assign_ids @> ARRAY[excluded.last_assign_id]"
+		later  = "excluded.last_seen_time >= " + table + ".last_seen_time"
+		newIDs = "CASE WHEN " + seen + " THEN " + table + ".assign_ids ELSE array_append(" + table + ".assign_ids, excluded.last_assign_id) END"
+	)
+	lastOf := func(column string) clause.Expr {
+		return gorm.Expr("CASE WHEN " + later + " THEN excluded." + column + " ELSE " + table + "." + column + " END")
+	}
+	err := d.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "school_id"}, {Name: "class_id"}, {Name: "subject"}, {Name: "question_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"first_task_id":    gorm.Expr("CASE WHEN excluded.first_seen_time < " + table + ".first_seen_time THEN excluded.first_task_id ELSE " + table + ".first_task_id END"),
+			"first_assign_id":  gorm.Expr("CASE WHEN excluded.first_seen_time < " + table + ".first_seen_time THEN excluded.first_assign_id ELSE " + table + ".first_assign_id END"),
+			"first_seen_time":  gorm.Expr("LEAST(excluded.first_seen_time, " + table + ".first_seen_time)"),
+			"last_task_id":     lastOf("last_task_id"),
+			"last_assign_id":   lastOf("last_assign_id"),
+			"resource_id":      lastOf("resource_id"),
+			"resource_type":    lastOf("resource_type"),
+			"answer_count":     lastOf("answer_count"),
+			"incorrect_count":  lastOf("incorrect_count"),
+			"correct_rate":     lastOf("correct_rate"),
+			"last_seen_time":   gorm.Expr("GREATEST(excluded.last_seen_time, " + table + ".last_seen_time)"),
+			"assign_ids":       gorm.Expr(newIDs),
+			"occurrence_count": gorm.Expr("cardinality(" + newIDs + ")"),
+			"update_time":      gorm.Expr("excluded.update_time"),
+		}),
+	}).CreateInBatches(items, 200).Error
+	if err != nil {
+		d.logger.Error(ctx, "[BatchUpsert] 写入班级共性错题失败, count: %d, err: %v", len(items), err)
+		return err
+	}
+	return nil
+}
+
+// 分页查询班级学科的共性错题，按最近出现时间倒序
+// explained 为 nil 时不限制，true 只查询已讲解，false 只查询未讲解
+func (d *classErrorBookDao) List(ctx context.Context, schoolID, classID, subject int64, explained *bool, page, pageSize int64) ([]*ClassErrorBookItem, int64, error) {
+	db := d.DB(ctx).Where("school_id = ? AND class_id = ? AND subject = ?", schoolID, classID, subject)
+	if explained != nil {
+		if *explained {
+			db = db.Where("explain_time > 0")
+		} else {
+			db = db.Where("explain_time = 0")
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		d.logger.Error(ctx, "[List] 统计班级共性错题失败, classID: %d, subject: %d, err: %v", classID, subject, err)
+		return nil, 0, err
+	}
+	items := make([]*ClassErrorBookItem, 0)
+	if total == 0 {
+		return items, 0, nil
+	}
+	if pageSize > 0 {
+		db = db.Offset(int((page - 1) * pageSize)).Limit(int(pageSize))
+	}
+	if err := db.Order("last_seen_time DESC, id DESC").Find(&items).Error; err != nil {
+		d.logger.Error(ctx, "[List] 查询班级共性错题失败, classID: %d, subject: %d, err: %v", classID, subject, err)
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// 查询学校内指定ID的共性错题
+func (d *classErrorBookDao) GetByIDs(ctx context.Context, schoolID int64, ids []int64) ([]*ClassErrorBookItem, error) {
+	items := make([]*ClassErrorBookItem, 0)
+	if len(ids) == 0 {
+		return items, nil
+	}
+	if err := d.DB(ctx).Where("school_id = ? AND id IN ?", schoolID, ids).Order("id").Find(&items).Error; err != nil {
+		d.logger.Error(ctx, "[GetByIDs] 查询班级共性错题失败, ids: %v, err: %v", ids, err)
+		return nil, err
+	}
+	return items, nil
+}
+
+// 撤销题目在一次布置中的出现，只在该布置出现过的题目直接删除
+// 重新汇总后不再达到阈值的题目调用，first/last 字段保留原值
+func (d *classErrorBookDao) RemoveAssign(ctx context.Context, schoolID, classID, subject, assignID int64, questionIDs []string) error {
+	if len(questionIDs) == 0 {
+		return nil
+	}
+
+	const remaining = "array_remove(assign_ids, ?)"
+	err := d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		db := func() *gorm.DB {
+			return tx.Model(&ClassErrorBookItem{}).
+				Where("school_id = ? AND class_id = ? AND subject = ? AND question_id IN ?", schoolID, classID, subject, questionIDs).
+				Where("assign_ids @> ARRAY[?]::bigint[]", assignID)
+		}
+		if err := db().Where("cardinality("+remaining+") = 0", assignID).Delete(&ClassErrorBookItem{}).Error; err != nil {
+			return err
+		}
+		return db().Updates(map[string]any{
+			"assign_ids":       gorm.Expr(remaining, assignID),
+			"occurrence_count": gorm.Expr("cardinality("+remaining+")", assignID),
+		}).Error
+	})
+	if err != nil {
+		d.logger.Error(ctx, "[RemoveAssign] 撤销班级共性错题失败, classID: %d, assignID: %d, err: %v", classID, assignID, err)
+		return err
+	}
+	return nil
+}
+
+// 标记或取消标记班级内的共性错题已讲解，返回更新的记录数
+func (d *classErrorBookDao) MarkExplained(ctx context.Context, schoolID, classID int64, ids []int64, teacherID int64, explainTime int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids is empty")
+	}
+	if explainTime == 0 {
+		teacherID = 0
+	}
+	result := d.DB(ctx).Where("school_id = ? AND class_id = ? AND id IN ?", schoolID, classID, ids).Updates(map[string]any{
+		"explain_time": explainTime,
+		"explainer_id": teacherID,
+	})
+	if result.Error != nil {
+		d.logger.Error(ctx, "[MarkExplained] 标记共性错题失败, ids: %v, err: %v", ids, result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -20,6 +20,10 @@ var TaskDAOProvider = wire.NewSet(
 	NewTaskStudentsReportDao,
 	NewTaskReportSettingDao,
 	NewTaskStudentDao,
+	NewTaskExportJobDao,
+	NewTaskTierDao,
+	NewTaskRecurrenceDao,
+	NewClassErrorBookDao,
 )
 
 // TaskDAO 任务数据访问接口
@@ -44,6 +48,12 @@ type TaskDAO interface {
 	GetLatestTask(ctx context.Context, teacherID, schoolID, subjectID int64) ([]*Task, error)
 	// GetStudentTaskList 获取学生的任务列表
 	GetStudentTaskList(ctx context.Context, req *dto.StudentTaskListQuery) ([]*TaskAndTaskAssign, int64, error)
+	// UpdateTaskStatus 按当前状态更新任务状态，状态不匹配时返回 false
+	UpdateTaskStatus(ctx context.Context, taskID int64, creatorID int64, fromStatus []int64, updates map[string]any) (bool, error)
+	// PublishDueTasks 发布到达发布时间的定时任务
+	PublishDueTasks(ctx context.Context, now int64) (int64, error)
+	// RefreshPublishTime 更新布置所属的定时任务的发布时间为最早的布置开始时间
+	RefreshPublishTime(ctx context.Context, assignIDs []int64) error
 }
 
 // TaskResourceDAO 任务资源数据访问接口
@@ -91,6 +101,16 @@ type TaskStudentDAO interface {
 	// GetAssignStudents 获取指定布置ID列表的学生ID列表
 	//  map[assignID][studentID]int64
 	GetAssignStudents(ctx context.Context, assignIDs []int64) (map[int64][]int64, error)
+
+	// GetStudentTiers 获取任务布置下学生的分层序号，studentIDs 为空时返回布置下全部学生
+	//  map[studentID]tierNo
+	GetStudentTiers(ctx context.Context, taskID int64, assignID int64, studentIDs []int64) (map[int64]int64, error)
+}
+
+// TaskTierDAO 分层作业的分层数据访问接口
+type TaskTierDAO interface {
+	// GetByTaskID 获取任务的分层列表，按分层序号排序
+	GetByTaskID(ctx context.Context, taskID int64) ([]*TaskTier, error)
 }
 
 // TeacherTempSelectionDAO 教师临时选择数据访问接口
@@ -111,6 +131,8 @@ type TaskReportDAO interface {
 	FindByTaskIDAndAssignIDs(ctx context.Context, taskID int64, assignIDs []int64) ([]*TaskReport, error)
 	// FindByAssignIDs 获取指定布置ID列表的统计数据
 	FindByTaskAssignIDs(ctx context.Context, taskAssignIdsMap map[int64][]int64) (map[int64][]*TaskReport, error)
+	// Upsert 写入或覆盖任务布置的统计数据
+	Upsert(ctx context.Context, report *TaskReport) error
 }
 
 type TaskStudentDetailsDao interface {
@@ -134,6 +156,18 @@ type TaskStudentDetailsDao interface {
 
 	// 计算指定布置任务每个题的答题时间和答题人数，用于计算每个题目的平均用时
 	GetTaskAnswerTime(ctx context.Context, taskID int64, assignID int64, resourceQuestionIDs []string) (map[string]int64, map[string]int64, error)
+
+	// 批量写入作答详情，同一题目以最后一次作答为准
+	BatchUpsert(ctx context.Context, details []*TaskStudentDetails) error
+
+	// 删除学生在指定资源下作答时间早于 answerTimeBefore 的作答详情，用于整体覆盖学生的作答
+	DeleteStudentResourceDetails(ctx context.Context, taskID, assignID int64, resourceKey string, studentIDs []int64, answerTimeBefore int64) error
+
+	// 按学生、资源汇总指定任务布置的作答数据
+	GetStudentResourceAnswerStats(ctx context.Context, taskID, assignID int64, studentIDs []int64) ([]*StudentResourceAnswerStat, error)
+
+	// 查询学生在时间范围内答错的题目，用于生成错题作业
+	FindStudentWrongQuestions(ctx context.Context, query *dto.WrongQuestionQuery) ([]*StudentWrongQuestion, error)
 }
 
 type TaskStudentsReportDao interface {
@@ -142,6 +176,12 @@ type TaskStudentsReportDao interface {
 
 	// 指定任务指定布置指定学生 id 的统计数据
 	FindByTaskIDAndStudentID(ctx context.Context, taskID int64, assignID int64, studentID int64) (*TaskStudentsReport, error)
+
+	// 批量写入学生统计数据
+	BatchUpsert(ctx context.Context, reports []*TaskStudentsReport) error
+
+	// 查询学生在指定学科下最近一次有作答的作业报告正确率
+	FindStudentsLatestAccuracy(ctx context.Context, subject int64, studentIDs []int64) (map[int64]float64, error)
 }
 
 type TaskReportSettingDao interface {
@@ -152,3 +192,53 @@ type TaskReportSettingDao interface {
 	// 查询指定学校班级指定科目的设置
 	GetSettingByClassIDAndSubjectID(ctx context.Context, schoolID, classID, subjectID int64) (*TaskReportSetting, error)
 }
+
+// TaskExportJobDAO 作业报告导出任务数据访问接口
+type TaskExportJobDAO interface {
+	// 创建导出任务
+	Create(ctx context.Context, job *TaskExportJob) error
+	// 查询导出任务，不存在返回 nil
+	GetByID(ctx context.Context, jobID int64) (*TaskExportJob, error)
+	// 按当前状态更新导出任务，状态不匹配时返回 false
+	UpdateStatus(ctx context.Context, jobID int64, fromStatus int64, updates map[string]any) (bool, error)
+	// 查询指定状态且在 updatedBefore 之前更新的导出任务
+	FindByStatus(ctx context.Context, status int64, updatedBefore int64, limit int) ([]*TaskExportJob, error)
+	// 续期执行中任务的租约，任务不在执行中或租约已被重置、重新抢占时返回 false
+	RenewLease(ctx context.Context, jobID int64, fromLeaseUntil, leaseUntil int64) (bool, error)
+	// 查询租约在 now 之前过期的执行中任务
+	FindLeaseExpired(ctx context.Context, now int64, limit int) ([]*TaskExportJob, error)
+	// 将租约已过期的执行中任务重置为等待执行，租约已续期时返回 false
+	ResetLeaseExpired(ctx context.Context, jobID int64, now int64) (bool, error)
+}
+
+// TaskRecurrenceDAO 任务周期布置规则数据访问接口
+type TaskRecurrenceDAO interface {
+	// 创建周期布置规则
+	Create(ctx context.Context, recurrence *TaskRecurrence) error
+	// 查询任务的周期布置规则
+	GetByTaskID(ctx context.Context, taskID int64) ([]*TaskRecurrence, error)
+	// 统计任务生效中的周期布置规则数
+	CountActiveByTaskID(ctx context.Context, taskID int64) (int64, error)
+	// 停止教师创建的生效中的规则，规则不存在或不是生效中时返回 false
+	Stop(ctx context.Context, recurrenceID int64, creatorID int64) (bool, error)
+	// 停止任务的全部生效中的规则
+	StopByTaskIDs(ctx context.Context, taskIDs []int64) error
+	// 查询下一次布置时间已到的生效中规则，跳过 excludeIDs 中的规则
+	FindDue(ctx context.Context, now int64, excludeIDs []int64, limit int) ([]*TaskRecurrence, error)
+	// 按下一次布置时间推进规则，已被其他实例推进时返回 false
+	Advance(ctx context.Context, tx *gorm.DB, recurrenceID int64, fromNextRunTime int64, updates map[string]any) (bool, error)
+}
+
+// ClassErrorBookDAO 班级共性错题本数据访问接口
+type ClassErrorBookDAO interface {
+	// 批量记录题目在布置中的出现，同一布置重复写入不增加出现次数
+	BatchUpsert(ctx context.Context, items []*ClassErrorBookItem) error
+	// 分页查询班级学科的共性错题，pageSize 为 0 时不分页
+	List(ctx context.Context, schoolID, classID, subject int64, explained *bool, page, pageSize int64) ([]*ClassErrorBookItem, int64, error)
+	// 查询学校内指定ID的共性错题
+	GetByIDs(ctx context.Context, schoolID int64, ids []int64) ([]*ClassErrorBookItem, error)
+	// 撤销题目在布置中的出现，没有其他出现的题目直接删除
+	RemoveAssign(ctx context.Context, schoolID, classID, subject, assignID int64, questionIDs []string) error
+	// 标记班级内的共性错题已讲解，explainTime 为 0 时取消标记，返回更新的记录数
+	MarkExplained(ctx context.Context, schoolID, classID int64, ids []int64, teacherID int64, explainTime int64) (int64, error)
+}
@@ -21,6 +21,8 @@ type Task struct {
 	TaskName       string `gorm:"column:task_name;type:varchar(32)" json:"taskName"`                                                      // 任务名称
 	TeacherComment string `gorm:"column:teacher_comment;type:varchar(256);default:''" json:"teacherComment"`                              // 老师留言
 	TaskExtraInfo  string `gorm:"column:task_extra_info;type:text" json:"taskExtraInfo"`                                                  // 任务额外信息
+	Status         int64  `gorm:"column:status;type:bigint;default:3" json:"status"`                                                      // 任务状态，1 草稿，2 定时发布，3 已发布，4 已归档
+	PublishTime    int64  `gorm:"column:publish_time;type:bigint;default:0" json:"publishTime"`                                           // 定时发布时间，为最早的布置开始时间
 	Deleted        int64  `gorm:"column:deleted;type:bigint" json:"-"`                                                                    // 任务是否删除标识
 	CreatorID      int64  `gorm:"column:creator_id;type:bigint" json:"-"`                                                                 // 任务创建者ID
 	UpdaterID      int64  `gorm:"column:updater_id;type:bigint" json:"-"`                                                                 // 任务更新者ID
@@ -121,6 +123,36 @@ func (d *taskDAO) GetTaskByIDAndCreatorID(ctx context.Context, taskID int64, cre
 	return task, nil
 }
 
+// UpdateTaskStatus 按当前状态更新任务状态，状态不匹配时返回 false
+func (d *taskDAO) UpdateTaskStatus(ctx context.Context, taskID int64, creatorID int64, fromStatus []int64, updates map[string]interface{}) (bool, error) {
+	result := d.DB(ctx).Model(&Task{}).
+		Where("task_id = ? AND creator_id = ? AND deleted = 0 AND status IN ?", taskID, creatorID, fromStatus).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// PublishDueTasks 发布到达发布时间的定时任务，返回发布的任务数
+func (d *taskDAO) PublishDueTasks(ctx context.Context, now int64) (int64, error) {
+	result := d.DB(ctx).Model(&Task{}).
+		Where("status = ? AND publish_time <= ? AND deleted = 0", consts.TASK_STATUS_SCHEDULED, now).
+		Update("status", consts.TASK_STATUS_PUBLISHED)
+	return result.RowsAffected, result.Error
+}
+
+// RefreshPublishTime 布置修改或删除后，将布置所属的定时任务的发布时间更新为最早的布置开始时间
+func (d *taskDAO) RefreshPublishTime(ctx context.Context, assignIDs []int64) error {
+	if len(assignIDs) == 0 {
+		return nil
+	}
+	return d.DB(ctx).Exec(`UPDATE tbl_task SET publish_time = COALESCE((
+		SELECT MIN(start_time) FROM tbl_task_assign WHERE tbl_task_assign.task_id = tbl_task.task_id AND tbl_task_assign.deleted = 0
+	), publish_time) WHERE task_id IN (SELECT task_id FROM tbl_task_assign WHERE assign_id IN ?) AND status = ?`,
+		assignIDs, consts.TASK_STATUS_SCHEDULED).Error
+}
+
 // GetTasksByIDs 通过id 查询任务列表
 func (d *taskDAO) GetTasksByIDs(ctx context.Context, taskIDs []int64) ([]*Task, error) {
 	var tasks []*Task
@@ -148,7 +180,7 @@ func (d *taskDAO) GetLatestTask(ctx context.Context, teacherID, schoolID, subjec
 	// 使用子查询获取每种任务类型的最新任务
 	subQuery := d.DB(ctx).Model(&Task{}).
 		Select("task_type, MAX(create_time) as max_create_time").
-		Where("creator_id = ? AND school_id = ? AND deleted = 0", teacherID, schoolID)
+		Where("creator_id = ? AND school_id = ? AND deleted = 0 AND status IN ?", teacherID, schoolID, consts.TaskVisibleStatus)
 
 	if subjectID > 0 {
 		subQuery = subQuery.Where("subject = ?", subjectID)
@@ -158,7 +190,7 @@ func (d *taskDAO) GetLatestTask(ctx context.Context, teacherID, schoolID, subjec
 
 	mainQuery := d.DB(ctx).Model(&Task{}).
 		Joins("INNER JOIN (?) as latest ON tbl_task.task_type = latest.task_type AND tbl_task.create_time = latest.max_create_time", subQuery).
-		Where("tbl_task.creator_id = ? AND tbl_task.school_id = ? AND tbl_task.deleted = 0", teacherID, schoolID)
+		Where("tbl_task.creator_id = ? AND tbl_task.school_id = ? AND tbl_task.deleted = 0 AND tbl_task.status IN ?", teacherID, schoolID, consts.TaskVisibleStatus)
 
 	if subjectID > 0 {
 		mainQuery = mainQuery.Where("tbl_task.subject = ?", subjectID)
@@ -174,6 +206,7 @@ type TaskAndTaskAssign struct {
 	Task
 	StartTime int64 `gorm:"column:start_time" json:"startTime"` // 任务开始时间
 	Deadline  int64 `gorm:"column:deadline" json:"deadline"`    // 任务截止时间
+	TierNo    int64 `gorm:"column:tier_no" json:"tierNo"`       // 学生所在分层，分层作业使用
 }
 
 // GetStudentTaskList 获取指定学生的任务列表
@@ -184,9 +217,10 @@ func (d *taskDAO) GetStudentTaskList(ctx context.Context, req *dto.StudentTaskLi
 		Joins("INNER JOIN tbl_task_assign ON tbl_task.task_id = tbl_task_assign.task_id").
 		Joins("INNER JOIN tbl_task_student ON tbl_task_assign.assign_id = tbl_task_student.assign_id").
 		Where("tbl_task.deleted = 0 AND tbl_task_assign.deleted = 0").
+		Where("tbl_task.status IN ?", consts.TaskVisibleStatus).
 		Where("tbl_task_student.student_id = ?", req.StudentID).
 		Where("tbl_task.subject = ?", req.Subject).
-		Select(`tbl_task.*, tbl_task_assign.start_time as "start_time", tbl_task_assign.deadline as "deadline"`)
+		Select(`tbl_task.*, tbl_task_assign.start_time as "start_time", tbl_task_assign.deadline as "deadline", tbl_task_student.tier_no as "tier_no"`)
 
 	// tbl_task 可选参数
 	if req.TaskType != 0 {
@@ -48,7 +48,7 @@ func (d *taskAssignDAO) GetTeacherTasks(ctx context.Context, reqs *dto.TaskAssig
 	// 构建基础查询
 	query := d.DB(ctx).
 		Joins("INNER JOIN tbl_task s ON tbl_task_assign.task_id = s.task_id").
-		Where("s.creator_id = ? AND s.school_id = ? AND s.deleted = 0", reqs.TeacherID, reqs.SchoolID)
+		Where("s.creator_id = ? AND s.school_id = ? AND s.deleted = 0 AND s.status IN ?", reqs.TeacherID, reqs.SchoolID, consts.TaskVisibleStatus)
 
 	// 群组或班级条件
 	switch consts.GroupType(reqs.GroupType) {
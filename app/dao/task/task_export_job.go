@@ -0,0 +1,180 @@
+package dao_task
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+
+	"gorm.io/gorm"
+)
+
+type taskExportJobDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewTaskExportJobDao(db *gorm.DB, logger *clogger.ContextLogger) TaskExportJobDAO {
+	return &taskExportJobDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// TaskExportJob 作业报告异步导出任务
+type TaskExportJob struct {
+	ID         int64            `gorm:"column:id;type:bigserial;primaryKey"`
+	SchoolID   int64            `gorm:"column:school_id;type:bigint;not null"`
+	TeacherID  int64            `gorm:"column:teacher_id;type:bigint;not null"`
+	Format     string           `gorm:"column:format;type:varchar(8);not null"` // 导出格式 csv/xlsx
+	Params     *ExportJobParams `gorm:"column:params;type:jsonb"`               // 导出参数
+	Status     int64            `gorm:"column:status;type:bigint;not null"`     // 任务状态
+	FileName   string           `gorm:"column:file_name;type:varchar(255)"`     // 导出文件名
+	ObjectKey  string           `gorm:"column:object_key;type:varchar(255)"`    // OSS 对象路径
+	ErrorMsg   string           `gorm:"column:error_msg;type:varchar(512)"`     // 失败原因
+	FinishTime int64            `gorm:"column:finish_time;type:bigint"`         // 完成时间
+	LeaseUntil int64            `gorm:"column:lease_until;type:bigint"`         // 执行中任务的租约到期时间，执行协程定时续期
+	CreateTime int64            `gorm:"column:create_time;type:bigint"`
+	UpdateTime int64            `gorm:"column:update_time;type:bigint"`
+}
+
+// 单个导出对象，对应一个任务布置（班级/小组）
+type ExportJobItem struct {
+	TaskID       int64  `json:"taskId"`
+	AssignID     int64  `json:"assignId"`
+	ResourceID   string `json:"resourceId,omitempty"`
+	ResourceType int64  `json:"resourceType,omitempty"`
+}
+
+// 导出任务参数，多个导出对象时打包为 zip
+type ExportJobParams struct {
+	Items    []*ExportJobItem `json:"items"`
+	Fields   []string         `json:"fields"`
+	SortBy   string           `json:"sortBy,omitempty"`
+	SortType consts.SortType  `json:"sortType,omitempty"`
+}
+
+// Value 实现 driver.Valuer 接口
+func (p ExportJobParams) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+// Scan 实现 sql.Scanner 接口
+func (p *ExportJobParams) Scan(value any) error {
+	if value == nil {
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for ExportJobParams: %T", value)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, p)
+}
+
+// GormDataType 实现 gorm 的数据类型接口
+func (ExportJobParams) GormDataType() string {
+	return "jsonb"
+}
+
+func (m *TaskExportJob) TableName() string {
+	return "tbl_task_export_job"
+}
+
+func (d *taskExportJobDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&TaskExportJob{})
+}
+
+// 创建导出任务
+func (d *taskExportJobDao) Create(ctx context.Context, job *TaskExportJob) error {
+	if job == nil {
+		return errors.New("entity is nil")
+	}
+	if err := d.DB(ctx).Create(job).Error; err != nil {
+		d.logger.Error(ctx, "[Create] 创建导出任务失败, job: %+v, err: %v", job, err)
+		return err
+	}
+	return nil
+}
+
+// 查询导出任务，不存在返回 nil
+func (d *taskExportJobDao) GetByID(ctx context.Context, jobID int64) (*TaskExportJob, error) {
+	var job TaskExportJob
+	err := d.DB(ctx).Where("id = ?", jobID).First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		d.logger.Error(ctx, "[GetByID] 查询导出任务失败, jobID: %d, err: %v", jobID, err)
+		return nil, err
+	}
+	return &job, nil
+}
+
+// 按当前状态更新导出任务，状态不匹配时返回 false，用于多实例抢占任务
+func (d *taskExportJobDao) UpdateStatus(ctx context.Context, jobID int64, fromStatus int64, updates map[string]any) (bool, error) {
+	result := d.DB(ctx).Where("id = ? AND status = ?", jobID, fromStatus).Updates(updates)
+	if result.Error != nil {
+		d.logger.Error(ctx, "[UpdateStatus] 更新导出任务失败, jobID: %d, fromStatus: %d, err: %v", jobID, fromStatus, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// 查询指定状态且在 updatedBefore 之前更新的导出任务，用于恢复未执行或执行中断的任务
+func (d *taskExportJobDao) FindByStatus(ctx context.Context, status int64, updatedBefore int64, limit int) ([]*TaskExportJob, error) {
+	jobs := make([]*TaskExportJob, 0)
+	err := d.DB(ctx).Where("status = ? AND update_time <= ?", status, updatedBefore).
+		Order("id").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		d.logger.Error(ctx, "[FindByStatus] 查询导出任务失败, status: %d, err: %v", status, err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// 续期执行中任务的租约，任务不在执行中或租约已被重置、重新抢占时返回 false
+// 按上次设置的租约时间更新，任务被其他实例重新抢占后租约时间不同，原实例不能继续续期
+func (d *taskExportJobDao) RenewLease(ctx context.Context, jobID int64, fromLeaseUntil, leaseUntil int64) (bool, error) {
+	result := d.DB(ctx).Where("id = ? AND status = ? AND lease_until = ?", jobID, consts.EXPORT_JOB_STATUS_RUNNING, fromLeaseUntil).Update("lease_until", leaseUntil)
+	if result.Error != nil {
+		d.logger.Error(ctx, "[RenewLease] 续期导出任务租约失败, jobID: %d, err: %v", jobID, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// 查询租约在 now 之前过期的执行中任务
+func (d *taskExportJobDao) FindLeaseExpired(ctx context.Context, now int64, limit int) ([]*TaskExportJob, error) {
+	jobs := make([]*TaskExportJob, 0)
+	err := d.DB(ctx).Where("status = ? AND lease_until < ?", consts.EXPORT_JOB_STATUS_RUNNING, now).
+		Order("id").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		d.logger.Error(ctx, "[FindLeaseExpired] 查询租约过期的导出任务失败, err: %v", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// 将租约已过期的执行中任务重置为等待执行，条件中再次检查租约，避免重置刚续期的任务
+func (d *taskExportJobDao) ResetLeaseExpired(ctx context.Context, jobID int64, now int64) (bool, error) {
+	result := d.DB(ctx).Where("id = ? AND status = ? AND lease_until < ?", jobID, consts.EXPORT_JOB_STATUS_RUNNING, now).
+		Updates(map[string]any{"status": consts.EXPORT_JOB_STATUS_PENDING, "lease_until": 0})
+	if result.Error != nil {
+		d.logger.Error(ctx, "[ResetLeaseExpired] 重置导出任务失败, jobID: %d, err: %v", jobID, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
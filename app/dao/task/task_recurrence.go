@@ -0,0 +1,144 @@
+package dao_task
+
+import (
+	"context"
+	"errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/core/postgresqlx"
+
+	"gorm.io/gorm"
+)
+
+type taskRecurrenceDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewTaskRecurrenceDao(db *gorm.DB, logger *clogger.ContextLogger) TaskRecurrenceDAO {
+	return &taskRecurrenceDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// TaskRecurrence 任务周期布置规则，按规则在每个布置日为群组新增一次任务布置
+type TaskRecurrence struct {
+	ID           int64                  `gorm:"column:id;type:bigserial;primaryKey" json:"recurrenceId"`         // 自增主键ID，即规则ID
+	TaskID       int64                  `gorm:"column:task_id;type:bigint;not null" json:"taskId"`               // 任务ID
+	SchoolID     int64                  `gorm:"column:school_id;type:bigint;not null" json:"schoolId"`           // 学校ID
+	CreatorID    int64                  `gorm:"column:creator_id;type:bigint;not null" json:"-"`                 // 创建规则的教师ID
+	GroupType    int64                  `gorm:"column:group_type;type:bigint;not null" json:"groupType"`         // 群组类型
+	GroupID      int64                  `gorm:"column:group_id;type:bigint;not null" json:"groupId"`             // 群组ID
+	StudentIDs   postgresqlx.Int64Array `gorm:"column:student_ids;type:bigint[]" json:"studentIds"`              // 创建规则时群组的学生，每次布置给这些学生
+	Weekdays     postgresqlx.Int64Array `gorm:"column:weekdays;type:bigint[]" json:"weekdays"`                   // 布置日，1-7 对应周一到周日
+	StartOffset  int64                  `gorm:"column:start_offset;type:bigint;not null" json:"startOffset"`     // 每次布置的开始时间，当天零点后的秒数
+	Duration     int64                  `gorm:"column:duration;type:bigint;not null" json:"duration"`            // 每次布置从开始到截止的秒数
+	StartDate    int64                  `gorm:"column:start_date;type:bigint;not null" json:"startDate"`         // 规则开始日期，当天零点
+	EndDate      int64                  `gorm:"column:end_date;type:bigint;not null" json:"endDate"`             // 规则结束日期，当天零点，当天仍会布置
+	NextRunTime  int64                  `gorm:"column:next_run_time;type:bigint;not null" json:"nextRunTime"`    // 下一次布置的开始时间
+	LastAssignID int64                  `gorm:"column:last_assign_id;type:bigint;default:0" json:"lastAssignId"` // 最近一次生成的布置ID
+	Status       int64                  `gorm:"column:status;type:bigint;not null" json:"status"`                // 规则状态
+	CreateTime   int64                  `gorm:"column:create_time;type:bigint;autoCreateTime" json:"createTime"` // 创建时间
+	UpdateTime   int64                  `gorm:"column:update_time;type:bigint;autoUpdateTime" json:"updateTime"` // 更新时间
+}
+
+// TableName 指定表名
+func (TaskRecurrence) TableName() string {
+	return "tbl_task_recurrence"
+}
+
+func (d *taskRecurrenceDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.WithContext(ctx).Model(&TaskRecurrence{})
+}
+
+// 创建周期布置规则
+func (d *taskRecurrenceDao) Create(ctx context.Context, recurrence *TaskRecurrence) error {
+	if recurrence == nil {
+		return errors.New("entity is nil")
+	}
+	if err := d.DB(ctx).Create(recurrence).Error; err != nil {
+		d.logger.Error(ctx, "[Create] 创建周期布置规则失败, recurrence: %+v, err: %v", recurrence, err)
+		return err
+	}
+	return nil
+}
+
+// 查询任务的周期布置规则，按创建顺序排序
+func (d *taskRecurrenceDao) GetByTaskID(ctx context.Context, taskID int64) ([]*TaskRecurrence, error) {
+	recurrences := make([]*TaskRecurrence, 0)
+	if err := d.DB(ctx).Where("task_id = ?", taskID).Order("id").Find(&recurrences).Error; err != nil {
+		d.logger.Error(ctx, "[GetByTaskID] 查询周期布置规则失败, taskID: %d, err: %v", taskID, err)
+		return nil, err
+	}
+	return recurrences, nil
+}
+
+// 统计任务生效中的周期布置规则数
+func (d *taskRecurrenceDao) CountActiveByTaskID(ctx context.Context, taskID int64) (int64, error) {
+	var count int64
+	err := d.DB(ctx).Where("task_id = ? AND status = ?", taskID, consts.TASK_RECURRENCE_STATUS_ACTIVE).Count(&count).Error
+	if err != nil {
+		d.logger.Error(ctx, "[CountActiveByTaskID] 统计周期布置规则失败, taskID: %d, err: %v", taskID, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// 停止教师创建的生效中的规则，规则不存在或不是生效中时返回 false
+func (d *taskRecurrenceDao) Stop(ctx context.Context, recurrenceID int64, creatorID int64) (bool, error) {
+	result := d.DB(ctx).
+		Where("id = ? AND creator_id = ? AND status = ?", recurrenceID, creatorID, consts.TASK_RECURRENCE_STATUS_ACTIVE).
+		Update("status", consts.TASK_RECURRENCE_STATUS_STOPPED)
+	if result.Error != nil {
+		d.logger.Error(ctx, "[Stop] 停止周期布置规则失败, recurrenceID: %d, err: %v", recurrenceID, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// 停止任务的全部生效中的规则，任务删除或归档时使用
+func (d *taskRecurrenceDao) StopByTaskIDs(ctx context.Context, taskIDs []int64) error {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+	err := d.DB(ctx).Where("task_id IN ? AND status = ?", taskIDs, consts.TASK_RECURRENCE_STATUS_ACTIVE).
+		Update("status", consts.TASK_RECURRENCE_STATUS_STOPPED).Error
+	if err != nil {
+		d.logger.Error(ctx, "[StopByTaskIDs] 停止周期布置规则失败, taskIDs: %v, err: %v", taskIDs, err)
+		return err
+	}
+	return nil
+}
+
+// 查询下一次布置时间已到的生效中规则
+func (d *taskRecurrenceDao) FindDue(ctx context.Context, now int64, excludeIDs []int64, limit int) ([]*TaskRecurrence, error) {
+	recurrences := make([]*TaskRecurrence, 0)
+	query := d.DB(ctx).Where("status = ? AND next_run_time <= ?", consts.TASK_RECURRENCE_STATUS_ACTIVE, now)
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
+	}
+	err := query.Order("next_run_time, id").Limit(limit).Find(&recurrences).Error
+	if err != nil {
+		d.logger.Error(ctx, "[FindDue] 查询到期的周期布置规则失败, err: %v", err)
+		return nil, err
+	}
+	return recurrences, nil
+}
+
+// 按下一次布置时间推进规则，下一次布置时间已被其他实例修改时返回 false
+// tx 为空时使用默认连接，与生成布置放在同一事务中时传入事务
+func (d *taskRecurrenceDao) Advance(ctx context.Context, tx *gorm.DB, recurrenceID int64, fromNextRunTime int64, updates map[string]any) (bool, error) {
+	if tx == nil {
+		tx = d.db
+	}
+	result := tx.WithContext(ctx).Model(&TaskRecurrence{}).
+		Where("id = ? AND status = ? AND next_run_time = ?", recurrenceID, consts.TASK_RECURRENCE_STATUS_ACTIVE, fromNextRunTime).
+		Updates(updates)
+	if result.Error != nil {
+		d.logger.Error(ctx, "[Advance] 推进周期布置规则失败, recurrenceID: %d, err: %v", recurrenceID, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
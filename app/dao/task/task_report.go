@@ -11,6 +11,7 @@ import (
 	"gil_teacher/app/core/logger"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type taskReportDAO struct {
@@ -225,6 +226,24 @@ func (d *taskReportDAO) Insert(ctx context.Context, report TaskReport) error {
 	return nil
 }
 
+// 写入或覆盖任务布置的统计数据
+func (d *taskReportDAO) Upsert(ctx context.Context, report *TaskReport) error {
+	if report == nil {
+		return nil
+	}
+
+	err := d.DB(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}, {Name: "assign_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"report_detail", "resource_report_detail", "update_time"}),
+	}).Create(report).Error
+	if err != nil {
+		d.log.Error(ctx, "[Upsert]任务统计数据写入失败, taskID: %d, assignID: %d, err: %v", report.TaskID, report.AssignID, err)
+		return err
+	}
+
+	return nil
+}
+
 // 更新单个任务布置的统计数据
 func (d *taskReportDAO) Update(ctx context.Context, taskID int64, assignID int64, detail *TaskCompleteStat) error {
 	if detail == nil {
@@ -3,6 +3,9 @@ package dao_task
 import (
 	"context"
 	"errors"
+	"slices"
+
+	"gil_teacher/app/consts"
 	"gil_teacher/app/core/postgresqlx"
 
 	"gorm.io/gorm"
@@ -16,6 +19,8 @@ type TaskResource struct {
 	ResourceSubIDs postgresqlx.StringArray `gorm:"column:resource_sub_ids;type:text[]" json:"resourceSubIds"`      // 子资源ID列表
 	ResourceType   int64                   `gorm:"column:resource_type;type:bigint;not null" json:"resourceType"`  // 资源类型
 	ResourceExtra  string                  `gorm:"column:resource_extra;type:text" json:"resourceExtra"`           // 资源额外信息，供前端记录额外信息，后端不解析处理
+	TierNo         int64                   `gorm:"column:tier_no;type:bigint;default:0" json:"tierNo"`             // 分层序号，分层作业使用，0 为全部分层共用
+	StudentIDs     postgresqlx.Int64Array  `gorm:"column:student_ids;type:bigint[]" json:"studentIds,omitempty"`   // 资源所属学生，错题作业使用，为空时布置的全部学生可见
 }
 
 // TableName 指定表名
@@ -23,6 +28,14 @@ func (TaskResource) TableName() string {
 	return "tbl_task_resource"
 }
 
+// VisibleTo 资源对指定分层的学生是否可见，共用资源（分层 0）且未指定学生的资源全部学生可见
+func (r *TaskResource) VisibleTo(tierNo int64, studentID int64) bool {
+	if r.TierNo != consts.TaskTierNoCommon && r.TierNo != tierNo {
+		return false
+	}
+	return len(r.StudentIDs) == 0 || slices.Contains(r.StudentIDs, studentID)
+}
+
 // ----------------------------------------------
 // ----------------------------------------------
 // taskResourceDAO 任务资源数据访问实现
@@ -26,6 +26,7 @@ type TaskStudent struct {
 	AssignID  int64 `gorm:"column:assign_id;type:bigint;not null"`  // 分配ID，关联任务分配表
 	TaskID    int64 `gorm:"column:task_id;type:bigint;not null"`    // 任务ID，关联任务表
 	StudentID int64 `gorm:"column:student_id;type:bigint;not null"` // 学生ID，关联学生表
+	TierNo    int64 `gorm:"column:tier_no;type:bigint;default:0"`   // 分层序号，分层作业使用，非分层作业为 0
 }
 
 // TableName 指定表名
@@ -83,3 +84,32 @@ func (t *taskStudentDao) GetAssignStudents(ctx context.Context, assignIDs []int6
 
 	return studentMap, nil
 }
+
+// GetStudentTiers 获取任务布置下学生的分层序号，studentIDs 为空时返回布置下全部学生
+//
+//	map[studentID]tierNo
+func (t *taskStudentDao) GetStudentTiers(ctx context.Context, taskID int64, assignID int64, studentIDs []int64) (map[int64]int64, error) {
+	if taskID == 0 {
+		return nil, errors.New("taskID is zero")
+	}
+
+	query := t.DB(ctx).Where("task_id = ?", taskID)
+	if assignID > 0 {
+		query = query.Where("assign_id = ?", assignID)
+	}
+	if len(studentIDs) > 0 {
+		query = query.Where("student_id IN (?)", studentIDs)
+	}
+
+	students := make([]*TaskStudent, 0)
+	if err := query.Find(&students).Error; err != nil {
+		t.logger.Error(ctx, "GetStudentTiers error:%v", err)
+		return nil, err
+	}
+
+	tiers := make(map[int64]int64, len(students))
+	for _, student := range students {
+		tiers[student.StudentID] = student.TierNo
+	}
+	return tiers, nil
+}
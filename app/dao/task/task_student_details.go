@@ -12,6 +12,7 @@ import (
 	"gil_teacher/app/utils"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type taskStudentDetailsDao struct {
@@ -37,6 +38,7 @@ type TaskStudentDetails struct {
 	AnswerContent string `gorm:"column:answer_content"`
 	Correctness   bool   `gorm:"column:correctness"`
 	CostTime      int64  `gorm:"column:cost_time"`
+	AnswerTime    int64  `gorm:"column:answer_time"` // 作答事件时间
 	CreateTime    int64  `gorm:"column:create_time"`
 	UpdateTime    int64  `gorm:"column:update_time"`
 }
@@ -47,6 +49,23 @@ type QuestionAnswer struct {
 	Accuracy       float64 `gorm:"column:accuracy"`        // 正确率
 }
 
+// 学生在某个资源下的作答汇总
+type StudentResourceAnswerStat struct {
+	StudentID      int64  `gorm:"column:student_id"`
+	ResourceKey    string `gorm:"column:resource_key"`
+	AnswerCount    int64  `gorm:"column:answer_count"`    // 答题数
+	IncorrectCount int64  `gorm:"column:incorrect_count"` // 答错数
+	CostTime       int64  `gorm:"column:cost_time"`       // 总用时
+}
+
+// 学生的一道历史错题
+type StudentWrongQuestion struct {
+	StudentID     int64  `gorm:"column:student_id"`
+	QuestionID    string `gorm:"column:question_id"`
+	WrongCount    int64  `gorm:"column:wrong_count"`     // 答错次数，不同任务中答错分别计数
+	LastWrongTime int64  `gorm:"column:last_wrong_time"` // 最近一次答错的作答时间
+}
+
 func (m *TaskStudentDetails) TableName() string {
 	return "tbl_task_student_details"
 }
@@ -63,9 +82,122 @@ func (d *taskStudentDetailsDao) Insert(ctx context.Context, details *TaskStudent
 
 	details.CreateTime = time.Now().Unix()
 	details.UpdateTime = time.Now().Unix()
+	if details.AnswerTime == 0 {
+		details.AnswerTime = details.CreateTime
+	}
 	return d.DB(ctx).Create(details).Error
 }
 
+// 批量写入作答详情，按唯一键 task_id#assign_id#student_id#resource_key#question_id 覆盖
+// answer_time 记录作答事件时间，只有更晚的作答才会覆盖已有记录，保证消息重放、乱序时结果一致
+// 不能用 update_time 判断，update_timestamp 触发器会在每次更新时把它改成当前时间
+func (d *taskStudentDetailsDao) BatchUpsert(ctx context.Context, details []*TaskStudentDetails) error {
+	if len(details) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	for _, detail := range details {
+		if detail.CreateTime == 0 {
+			detail.CreateTime = now
+		}
+		if detail.UpdateTime == 0 {
+			detail.UpdateTime = now
+		}
+		if detail.AnswerTime == 0 {
+			detail.AnswerTime = now
+		}
+	}
+
+	err := d.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{
+			{Name: "task_id"}, {Name: "assign_id"}, {Name: "student_id"}, {Name: "resource_key"}, {Name: "question_id"},
+		},
+		DoUpdates: clause.AssignmentColumns([]string{"answer_content", "correctness", "cost_time", "answer_time", "update_time"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "tbl_task_student_details.answer_time <= excluded.answer_time"},
+		}},
+	}).CreateInBatches(details, 500).Error
+	if err != nil {
+		d.logger.Error(ctx, "[BatchUpsert] 写入作答详情失败, count: %d, err: %v", len(details), err)
+		return err
+	}
+	return nil
+}
+
+// 删除学生在指定资源下作答时间早于 answerTimeBefore 的作答详情
+// 先写入新的作答再删除旧的作答，覆盖过程中不会出现作答数据为空的情况
+func (d *taskStudentDetailsDao) DeleteStudentResourceDetails(ctx context.Context, taskID, assignID int64, resourceKey string, studentIDs []int64, answerTimeBefore int64) error {
+	if taskID == 0 || assignID == 0 || resourceKey == "" {
+		return errors.New("taskID, assignID, resourceKey is required")
+	}
+	if len(studentIDs) == 0 {
+		return nil
+	}
+
+	err := d.DB(ctx).
+		Where("task_id = ? AND assign_id = ? AND resource_key = ?", taskID, assignID, resourceKey).
+		Where("student_id IN (?) AND answer_time < ?", studentIDs, answerTimeBefore).
+		Delete(&TaskStudentDetails{}).Error
+	if err != nil {
+		d.logger.Error(ctx, "[DeleteStudentResourceDetails] 删除作答详情失败, taskID: %d, assignID: %d, resourceKey: %s, err: %v", taskID, assignID, resourceKey, err)
+		return err
+	}
+	return nil
+}
+
+// 按学生、资源汇总指定任务布置的作答数据，studentIDs 为空时汇总全部学生
+func (d *taskStudentDetailsDao) GetStudentResourceAnswerStats(ctx context.Context, taskID, assignID int64, studentIDs []int64) ([]*StudentResourceAnswerStat, error) {
+	if taskID == 0 || assignID == 0 {
+		return nil, errors.New("taskID, assignID is required")
+	}
+
+	db := d.DB(ctx).
+		Select("student_id, resource_key, COUNT(*) as answer_count, SUM(CASE WHEN correctness = false THEN 1 ELSE 0 END) as incorrect_count, COALESCE(SUM(cost_time), 0) as cost_time").
+		Where("task_id = ? AND assign_id = ?", taskID, assignID)
+	if len(studentIDs) > 0 {
+		db = db.Where("student_id IN ?", studentIDs)
+	}
+
+	stats := make([]*StudentResourceAnswerStat, 0)
+	if err := db.Group("student_id, resource_key").Scan(&stats).Error; err != nil {
+		d.logger.Error(ctx, "[GetStudentResourceAnswerStats] 查询失败, taskID: %d, assignID: %d, err: %v", taskID, assignID, err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// 查询学生在时间范围内答错的题目，同一学生同一题目去重
+// 时间范围按作答事件时间 answer_time 筛选，update_time 会被触发器改写，不能表示作答时间
+// 答题卡的题目ID只是题号，不同任务的同一题号不是同一道题，不参与错题统计
+// 按学生分组，组内按答错次数、最近答错时间倒序
+func (d *taskStudentDetailsDao) FindStudentWrongQuestions(ctx context.Context, query *dto.WrongQuestionQuery) ([]*StudentWrongQuestion, error) {
+	if query == nil || len(query.StudentIDs) == 0 {
+		return nil, errors.New("studentIDs is required")
+	}
+
+	db := d.DB(ctx).
+		Select("tbl_task_student_details.student_id, tbl_task_student_details.question_id, COUNT(*) as wrong_count, MAX(tbl_task_student_details.answer_time) as last_wrong_time").
+		Joins("JOIN tbl_task ON tbl_task.task_id = tbl_task_student_details.task_id AND tbl_task.deleted = 0").
+		Where("tbl_task.subject = ?", query.Subject).
+		Where("tbl_task_student_details.correctness = false").
+		Where("tbl_task_student_details.resource_key <> ?", utils.JoinList([]any{consts.AnswerCardResourceID, consts.RESOURCE_TYPE_ANSWER_CARD}, consts.CombineKey)).
+		Where("tbl_task_student_details.student_id IN ?", query.StudentIDs).
+		Where("tbl_task_student_details.answer_time >= ? AND tbl_task_student_details.answer_time < ?", query.AnswerTimeFrom, query.AnswerTimeTo)
+	if len(query.SourceTaskIDs) > 0 {
+		db = db.Where("tbl_task_student_details.task_id IN ?", query.SourceTaskIDs)
+	}
+
+	questions := make([]*StudentWrongQuestion, 0)
+	if err := db.Group("tbl_task_student_details.student_id, tbl_task_student_details.question_id").
+		Order("tbl_task_student_details.student_id, wrong_count DESC, last_wrong_time DESC").
+		Scan(&questions).Error; err != nil {
+		d.logger.Error(ctx, "[FindStudentWrongQuestions] 查询失败, subject: %d, studentCount: %d, err: %v", query.Subject, len(query.StudentIDs), err)
+		return nil, err
+	}
+	return questions, nil
+}
+
 // 更新任务完成详情
 func (d *taskStudentDetailsDao) Update(ctx context.Context, id int64, details *TaskStudentDetails) error {
 	if details == nil {
@@ -0,0 +1,36 @@
+package dao_task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/model/dto"
+)
+
+func TestFindStudentWrongQuestionsExcludesAnswerCard(t *testing.T) {
+	// DryRun 只生成 SQL，不连接数据库
+	db, err := gorm.Open(postgres.New(postgres.Config{DriverName: "pgx", DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	require.NoError(t, err)
+
+	var sql string
+	require.NoError(t, db.Callback().Row().After("gorm:row").Register("test:capture_sql", func(tx *gorm.DB) {
+		sql = tx.Dialector.Explain(tx.Statement.SQL.String(), tx.Statement.Vars...)
+	}))
+
+	dao := NewTaskStudentDetailsDao(db, clogger.NewContextLogger(log.DefaultLogger))
+	// DryRun 模式下 Scan 返回 unsupported 错误，只检查生成的 SQL
+	_, _ = dao.FindStudentWrongQuestions(context.Background(), &dto.WrongQuestionQuery{
+		Subject:    2,
+		StudentIDs: []int64{1001},
+	})
+	// 答题卡的题号不能和题库题目合并为同一道错题
+	assert.Contains(t, sql, "tbl_task_student_details.resource_key <> 'answer_card#105'")
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"gil_teacher/app/consts"
 	clogger "gil_teacher/app/core/logger"
@@ -44,9 +45,9 @@ type TaskStudentsReport struct {
 	AssignID             int64             `gorm:"assign_id"`
 	StudentID            int64             `gorm:"student_id"`
 	ResourceDetailReport                   // 任务完成报告
-	ResourceReport       ResourceReportMap `gorm:"resource_report"` // 分资源统计的报告 map[resource_id#resource_type]TaskCompleteReport
-	CreateTime           int64             `gorm:"create_time"`     // 首次统计时间
-	UpdateTime           int64             `gorm:"update_time"`     // 最后更新时间
+	ResourceReport       ResourceReportMap `gorm:"column:task_report"` // 分资源统计的报告 map[resource_id#resource_type]TaskCompleteReport
+	CreateTime           int64             `gorm:"create_time"`        // 首次统计时间
+	UpdateTime           int64             `gorm:"update_time"`        // 最后更新时间
 }
 
 // Value 实现 driver.Valuer 接口，用于将 TaskReportMap 转换为数据库值
@@ -100,6 +101,25 @@ func (d *taskStudentsReportDao) Insert(ctx context.Context, report *TaskStudents
 	return d.DB(ctx).Create(report).Error
 }
 
+// 批量写入学生统计数据，task_id#assign_id#student_id 已存在则整体覆盖
+func (d *taskStudentsReportDao) BatchUpsert(ctx context.Context, reports []*TaskStudentsReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	err := d.DB(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "task_id"}, {Name: "assign_id"}, {Name: "student_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"study_score", "completed_progress", "accuracy_rate", "answer_count", "incorrect_count", "cost_time", "task_report", "update_time",
+		}),
+	}).CreateInBatches(reports, 200).Error
+	if err != nil {
+		d.logger.Error(ctx, "[BatchUpsert] 写入学生统计数据失败, count: %d, err: %v", len(reports), err)
+		return err
+	}
+	return nil
+}
+
 // 查询指定任务指定学生的统计数据
 func (d *taskStudentsReportDao) FindByTaskIDAndStudentID(ctx context.Context, taskID, assignID, studentID int64) (*TaskStudentsReport, error) {
 	var report TaskStudentsReport
@@ -173,3 +193,35 @@ func (d *taskStudentsReportDao) FindByTaskIDAndAssignIDs(ctx context.Context, ta
 	}
 	return reports, nil
 }
+
+// 查询学生在指定学科下最近一次有作答的作业报告正确率，用于分层作业自动分层
+//
+//	map[studentID]accuracyRate，没有作答记录的学生不返回
+func (d *taskStudentsReportDao) FindStudentsLatestAccuracy(ctx context.Context, subject int64, studentIDs []int64) (map[int64]float64, error) {
+	result := make(map[int64]float64, len(studentIDs))
+	if len(studentIDs) == 0 {
+		return result, nil
+	}
+
+	type latestAccuracy struct {
+		StudentID    int64   `gorm:"column:student_id"`
+		AccuracyRate float64 `gorm:"column:accuracy_rate"`
+	}
+	rows := make([]*latestAccuracy, 0, len(studentIDs))
+	err := d.DB(ctx).
+		Select("DISTINCT ON (tbl_task_students_report.student_id) tbl_task_students_report.student_id, tbl_task_students_report.accuracy_rate").
+		Joins("INNER JOIN tbl_task ON tbl_task.task_id = tbl_task_students_report.task_id").
+		Where("tbl_task.deleted = 0 AND tbl_task.subject = ? AND tbl_task.task_type = ?", subject, consts.TASK_TYPE_HOMEWORK).
+		Where("tbl_task_students_report.student_id IN (?) AND tbl_task_students_report.answer_count > 0", studentIDs).
+		Order("tbl_task_students_report.student_id, tbl_task_students_report.update_time DESC").
+		Scan(&rows).Error
+	if err != nil {
+		d.logger.Error(ctx, "[FindStudentsLatestAccuracy] 查询失败, subject: %d, studentIDs: %v, err: %v", subject, studentIDs, err)
+		return nil, err
+	}
+
+	for _, row := range rows {
+		result[row.StudentID] = row.AccuracyRate
+	}
+	return result, nil
+}
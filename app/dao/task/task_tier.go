@@ -0,0 +1,51 @@
+package dao_task
+
+import (
+	"context"
+
+	clogger "gil_teacher/app/core/logger"
+
+	"gorm.io/gorm"
+)
+
+// TaskTier 分层作业的分层定义表
+type TaskTier struct {
+	ID          int64   `gorm:"column:id;type:bigserial;primaryKey" json:"-"`                    // 自增主键ID
+	TaskID      int64   `gorm:"column:task_id;type:bigint;not null" json:"taskId"`               // 任务ID
+	TierNo      int64   `gorm:"column:tier_no;type:bigint;not null" json:"tierNo"`               // 分层序号，从 1 开始
+	TierName    string  `gorm:"column:tier_name;type:varchar(32);not null" json:"tierName"`      // 分层名称
+	MinAccuracy float64 `gorm:"column:min_accuracy;type:numeric(5,4)" json:"minAccuracy"`        // 自动分层时进入该层的最低正确率
+	CreateTime  int64   `gorm:"column:create_time;type:bigint;autoCreateTime" json:"createTime"` // 创建时间
+}
+
+// TableName 指定表名
+func (TaskTier) TableName() string {
+	return "tbl_task_tier"
+}
+
+type taskTierDao struct {
+	db     *gorm.DB
+	logger *clogger.ContextLogger
+}
+
+func NewTaskTierDao(db *gorm.DB, logger *clogger.ContextLogger) TaskTierDAO {
+	return &taskTierDao{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (d *taskTierDao) DB(ctx context.Context) *gorm.DB {
+	return d.db.Model(&TaskTier{}).WithContext(ctx)
+}
+
+// GetByTaskID 获取任务的分层列表，按分层序号排序，非分层作业返回空列表
+func (d *taskTierDao) GetByTaskID(ctx context.Context, taskID int64) ([]*TaskTier, error) {
+	tiers := make([]*TaskTier, 0)
+	err := d.DB(ctx).Where("task_id = ?", taskID).Order("tier_no ASC").Find(&tiers).Error
+	if err != nil {
+		d.logger.Error(ctx, "[GetByTaskID] 查询任务分层失败, taskID: %d, err: %v", taskID, err)
+		return nil, err
+	}
+	return tiers, nil
+}
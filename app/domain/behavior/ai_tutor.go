@@ -0,0 +1,500 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/domain/push"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/model/itl"
+	"gil_teacher/app/service/gil_internal/question_service"
+	"gil_teacher/app/third_party/volc_ai"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrAITutorTask 任务不存在或不属于当前学校
+	ErrAITutorTask = errors.New("任务不存在")
+	// ErrAITutorAnswer 要修正的消息不是 AI 回答
+	ErrAITutorAnswer = errors.New("AI 回答不存在")
+	// ErrAITutorForbidden 布置对象不是班级，且当前教师不是任务创建者
+	ErrAITutorForbidden = errors.New("无权查看该任务布置的 AI 答疑")
+)
+
+// 题目详情来源，由题库服务提供
+type tutorQuestionSource interface {
+	GetQuestionDetail(ctx context.Context, questionID string) (*itl.Question, error)
+}
+
+// 回答投递，和其他会话消息一样写入沟通 topic，由行为消费组入库
+type tutorMessageSender interface {
+	SendCommunicationMessage(ctx context.Context, message *dto.CommunicationMessageDTO) error
+}
+
+// AITutor AI 答疑，回答学生针对作业题目的提问，无法回答或学生要求时转交布置任务的教师
+type AITutor struct {
+	behaviorDAO   behaviorDao.BehaviorDAO
+	taskDAO       dao_task.TaskDAO
+	taskAssignDAO dao_task.TaskAssignDAO
+	questions     tutorQuestionSource
+	model         volc_ai.ChatModel
+	pusher        *push.PushPublisher
+	sender        tutorMessageSender
+	logger        *clogger.ContextLogger
+}
+
+func NewAITutor(
+	behaviorDAO behaviorDao.BehaviorDAO,
+	taskDAO dao_task.TaskDAO,
+	taskAssignDAO dao_task.TaskAssignDAO,
+	questionClient *question_service.Client,
+	volcAI *volc_ai.Client,
+	pusher *push.PushPublisher,
+	logger *clogger.ContextLogger,
+) *AITutor {
+	return &AITutor{
+		behaviorDAO:   behaviorDAO,
+		taskDAO:       taskDAO,
+		taskAssignDAO: taskAssignDAO,
+		questions:     questionClient,
+		model:         volcAI,
+		pusher:        pusher,
+		logger:        logger,
+	}
+}
+
+// 模型返回的回答
+type tutorAnswer struct {
+	Answer      string  `json:"answer"`
+	Confidence  float64 `json:"confidence"`
+	NeedTeacher bool    `json:"needTeacher"`
+}
+
+// 提问会话关联的作业和题目，不是作业题目提问时为空
+type tutorQuestionContext struct {
+	taskID     int64
+	assignID   int64
+	questionID string
+	task       *dao_task.Task
+	question   *itl.Question
+}
+
+// Consume 独立消费组订阅沟通 topic，回答学生提问，回答通过 producer 写回沟通 topic
+func (t *AITutor) Consume(ctx context.Context, kafkaConf *conf.Kafka, producer *kafka.KafkaProducerClient) {
+	if producer == nil {
+		t.logger.Error(ctx, "AI 答疑没有可用的 Kafka 生产者，不启动")
+		return
+	}
+	t.sender = NewBehaviorProducer(nil, producer, t.logger)
+
+	t.logger.Info(ctx, "AI 答疑 Kafka 配置信息: broker=%s, group=%s, topics=%v",
+		kafkaConf.Brokers,
+		consts.KafkaGroupAITutor,
+		consts.KafkaTopicAITutor)
+
+	consumerGroupHandlerImpl := &kafka.ConsumerGroupHandlerImpl{
+		Group:        consts.KafkaGroupAITutor,
+		Topics:       consts.KafkaTopicAITutor,
+		BatchSize:    kafkaConf.Consumer.BatchSize,
+		BatchTime:    kafkaConf.Consumer.BatchTime * time.Second,
+		SessionTime:  kafkaConf.Consumer.SessionTime * time.Second,
+		ProcMsgList:  t.HandleMessage,
+		MaxRetries:   kafkaConf.Consumer.MaxRetries,
+		RetryBackoff: kafkaConf.Consumer.RetryBackoff * time.Millisecond,
+		DeadLetter:   producer,
+		Log:          t.logger,
+	}
+	for ctx.Err() == nil {
+		kafka.ConsumeKafkaMsgInSession(ctx, kafkaConf, consumerGroupHandlerImpl)
+		time.Sleep(time.Second)
+	}
+}
+
+// HandleMessage 回答一批会话消息中的学生提问
+// 无法解析的消息由行为消费组写入死信 topic，这里只记录日志；查询或投递失败时返回可重试的错误
+func (t *AITutor) HandleMessage(msgs []*sarama.ConsumerMessage) error {
+	ctx := context.Background()
+	events, decodeFailures := decodeBehaviorEvents(msgs)
+	for _, failure := range decodeFailures {
+		t.logger.Warn(ctx, "[AITutor] 跳过无法解析的消息: %v", failure)
+	}
+
+	var failures kafka.BatchError
+	for _, event := range events {
+		if event.msgType != consts.MessageTypeCommunication {
+			continue
+		}
+		var message dto.CommunicationMessageDTO
+		if err := json.Unmarshal(event.content, &message); err != nil {
+			t.logger.Warn(ctx, "[AITutor] 跳过无法解析的会话消息, eventID:%s, error:%v", event.eventID, err)
+			continue
+		}
+		if message.UserType != string(consts.CommunicationUserTypeStudent) {
+			continue
+		}
+		if err := t.Respond(ctx, &message); err != nil {
+			t.logger.Error(ctx, "[AITutor] 回答学生提问失败, sessionID:%s, messageID:%s, error:%v", message.SessionID, message.MessageID, err)
+			failures = append(failures, &kafka.MessageError{Msg: event.msg, Err: err, Retryable: true})
+		}
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// Respond 回答学生在提问会话中发送的消息
+// 教师已回复或已转交教师的会话不再回答；模型失败、置信度低或学生要求时转交教师
+func (t *AITutor) Respond(ctx context.Context, message *dto.CommunicationMessageDTO) error {
+	ctx, cancel := context.WithTimeout(ctx, consts.AITutorTimeout)
+	defer cancel()
+
+	session, err := t.behaviorDAO.GetCommunicationSession(ctx, message.SessionID)
+	if err != nil {
+		return errors.Wrap(err, "查询会话失败")
+	}
+	if session.SessionType != string(consts.CommunicationSessionTypeQuestion) || session.Closed {
+		return nil
+	}
+
+	history, err := t.behaviorDAO.GetSessionsMessages(ctx, []string{session.SessionID})
+	if err != nil {
+		return errors.Wrap(err, "查询会话消息失败")
+	}
+	replyID := aiTutorReplyID(message.MessageID)
+	for _, m := range history {
+		if m.MessageID == replyID || m.UserType == string(consts.CommunicationUserTypeTeacher) ||
+			m.MessageType == consts.CommunicationMessageTypeAIEscalated {
+			return nil
+		}
+	}
+
+	reply := &dto.CommunicationMessageDTO{
+		MessageID: replyID,
+		SessionID: session.SessionID,
+		UserID:    consts.AITutorUserID,
+		UserType:  string(consts.CommunicationUserTypeAI),
+		AnswerTo:  message.MessageID,
+	}
+	questionContext := t.questionContext(ctx, session)
+	if studentWantsTeacher(message.MessageContent) {
+		return t.escalate(ctx, session, questionContext, message, reply, "学生要求教师解答")
+	}
+
+	answer, err := t.answer(ctx, questionContext, history, message)
+	if err != nil {
+		t.logger.Warn(ctx, "[AITutor] 模型回答失败, sessionID:%s, error:%v", session.SessionID, err)
+		return t.escalate(ctx, session, questionContext, message, reply, "AI 无法回答")
+	}
+	if answer.NeedTeacher || answer.Confidence < consts.AITutorMinConfidence {
+		return t.escalate(ctx, session, questionContext, message, reply, fmt.Sprintf("AI 置信度 %.2f", answer.Confidence))
+	}
+
+	reply.MessageType = consts.CommunicationMessageTypeAIAnswer
+	reply.MessageContent = answer.Answer
+	reply.CreatedAt = time.Now()
+	return t.sender.SendCommunicationMessage(ctx, reply)
+}
+
+// 查询提问关联的作业和题目，查询失败时不带题目信息回答
+func (t *AITutor) questionContext(ctx context.Context, session *dto.CommunicationSessionDTO) *tutorQuestionContext {
+	questionContext := &tutorQuestionContext{}
+	if session.TargetID == nil {
+		return questionContext
+	}
+	taskID, assignID, questionID, ok := consts.ParseTaskQuestionTarget(*session.TargetID)
+	if !ok {
+		return questionContext
+	}
+	questionContext.taskID, questionContext.assignID, questionContext.questionID = taskID, assignID, questionID
+
+	tasks, err := t.taskDAO.GetTasksByIDs(ctx, []int64{taskID})
+	if err != nil {
+		t.logger.Warn(ctx, "[AITutor] 查询任务失败, taskID:%d, error:%v", taskID, err)
+	} else if len(tasks) > 0 {
+		questionContext.task = tasks[0]
+	}
+	question, err := t.questions.GetQuestionDetail(ctx, questionID)
+	if err != nil {
+		t.logger.Warn(ctx, "[AITutor] 查询题目失败, questionID:%s, error:%v", questionID, err)
+	} else {
+		questionContext.question = question
+	}
+	return questionContext
+}
+
+// 请求模型回答，最近的会话消息作为上下文
+func (t *AITutor) answer(ctx context.Context, questionContext *tutorQuestionContext, history []*dto.CommunicationMessageDTO,
+	message *dto.CommunicationMessageDTO) (*tutorAnswer, error) {
+	t.logger.Info(ctx, "[AITutor] 回答学生提问, sessionID:%s, Prompt 版本: %s", message.SessionID, consts.AITutorPromptV1.Version)
+
+	messages := []volc_ai.ChatMessage{{Role: volc_ai.ChatRoleSystem, Content: tutorSystemPrompt(questionContext)}}
+	if len(history) > consts.AITutorHistoryLimit {
+		history = history[len(history)-consts.AITutorHistoryLimit:]
+	}
+	for _, m := range history {
+		if m.MessageID == message.MessageID {
+			continue
+		}
+		switch m.UserType {
+		case string(consts.CommunicationUserTypeStudent):
+			messages = append(messages, volc_ai.ChatMessage{Role: volc_ai.ChatRoleUser, Content: m.MessageContent})
+		case string(consts.CommunicationUserTypeAI):
+			messages = append(messages, volc_ai.ChatMessage{Role: volc_ai.ChatRoleAssistant, Content: m.MessageContent})
+		}
+	}
+	messages = append(messages, volc_ai.ChatMessage{Role: volc_ai.ChatRoleUser, Content: message.MessageContent})
+
+	content, err := t.model.Chat(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	return parseTutorAnswer(content)
+}
+
+// 转交教师：回复学生已转交，并推送给布置任务的教师
+func (t *AITutor) escalate(ctx context.Context, session *dto.CommunicationSessionDTO, questionContext *tutorQuestionContext,
+	message, reply *dto.CommunicationMessageDTO, reason string) error {
+	reply.MessageType = consts.CommunicationMessageTypeAIEscalated
+	reply.MessageContent = consts.AITutorEscalatedReply
+	reply.CreatedAt = time.Now()
+	if err := t.sender.SendCommunicationMessage(ctx, reply); err != nil {
+		return err
+	}
+
+	t.logger.Info(ctx, "[AITutor] 转交教师, sessionID:%s, reason:%s", session.SessionID, reason)
+	if questionContext.task == nil || t.pusher == nil {
+		return nil
+	}
+	data, err := json.Marshal(map[string]any{
+		"sessionId":  session.SessionID,
+		"taskId":     questionContext.taskID,
+		"assignId":   questionContext.assignID,
+		"questionId": questionContext.questionID,
+		"studentId":  message.UserID,
+		"reason":     reason,
+	})
+	if err != nil {
+		data = []byte("{}")
+	}
+	// 推送失败不影响转交，教师可以在审核列表中看到
+	_ = t.pusher.Publish(ctx, &dto.PushMessage{
+		UserType:    consts.CommunicationUserTypeTeacher,
+		UserID:      questionContext.task.CreatorID,
+		ClassroomID: int64(session.ClassroomID),
+		Type:        consts.PushMessageTypeEscalated,
+		Content:     message.MessageContent,
+		Data:        data,
+	})
+	return nil
+}
+
+// TaskClassIDs 返回教师查看任务布置的 AI 答疑需要具备权限的班级，由调用方检查班级权限
+//
+//	任务创建者不需要班级权限，返回空；布置对象不是班级时只有任务创建者可以查看
+func (t *AITutor) TaskClassIDs(ctx context.Context, schoolID, teacherID, taskID, assignID int64) ([]int64, error) {
+	tasks, err := t.taskDAO.GetTasksByIDs(ctx, []int64{taskID})
+	if err != nil {
+		t.logger.Error(ctx, "[TaskClassIDs] GetTasksByIDs failed, taskID:%d, error:%v", taskID, err)
+		return nil, err
+	}
+	if len(tasks) == 0 || tasks[0].Deleted != 0 || tasks[0].SchoolID != schoolID {
+		return nil, ErrAITutorTask
+	}
+	if tasks[0].CreatorID == teacherID {
+		return nil, nil
+	}
+
+	assigns, err := t.taskAssignDAO.GetTaskAssigns(ctx, taskID, []int64{assignID})
+	if err != nil {
+		t.logger.Error(ctx, "[TaskClassIDs] GetTaskAssigns failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	if len(assigns) == 0 || assigns[0].SchoolID != schoolID {
+		return nil, ErrAITutorTask
+	}
+	if assigns[0].GroupType != consts.TASK_GROUP_TYPE_CLASS {
+		return nil, ErrAITutorForbidden
+	}
+	return []int64{assigns[0].GroupID}, nil
+}
+
+// AnswerClassIDs 返回教师修正会话中的 AI 回答需要具备权限的班级，规则同 TaskClassIDs
+func (t *AITutor) AnswerClassIDs(ctx context.Context, schoolID, teacherID int64, sessionID string) ([]int64, error) {
+	session, err := t.behaviorDAO.GetCommunicationSession(ctx, sessionID)
+	if err != nil {
+		t.logger.Error(ctx, "[AnswerClassIDs] 查询会话失败, sessionID:%s, error:%v", sessionID, err)
+		return nil, err
+	}
+	if session == nil || session.SchoolID != uint64(schoolID) || session.TargetID == nil {
+		return nil, ErrAITutorAnswer
+	}
+	taskID, assignID, _, ok := consts.ParseTaskQuestionTarget(*session.TargetID)
+	if !ok {
+		return nil, ErrAITutorAnswer
+	}
+	return t.TaskClassIDs(ctx, schoolID, teacherID, taskID, assignID)
+}
+
+// ListTaskAnswers 查询任务布置下 AI 的全部回答和转交记录，供教师审核，调用前需通过 TaskClassIDs 校验权限
+func (t *AITutor) ListTaskAnswers(ctx context.Context, taskID, assignID int64) ([]*dto.AITutorAnswerDTO, error) {
+	sessions, err := t.behaviorDAO.GetTaskQuestionSessions(ctx, taskID, assignID)
+	if err != nil {
+		t.logger.Error(ctx, "[ListTaskAnswers] GetTaskQuestionSessions failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return []*dto.AITutorAnswerDTO{}, nil
+	}
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.SessionID)
+	}
+	messages, err := t.behaviorDAO.GetSessionsMessages(ctx, sessionIDs)
+	if err != nil {
+		t.logger.Error(ctx, "[ListTaskAnswers] GetSessionsMessages failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	return buildAITutorAnswers(sessions, messages), nil
+}
+
+// OverrideAnswer 校验教师要修正的 AI 回答，返回待投递的教师修正消息，调用前需通过 AnswerClassIDs 校验权限
+func (t *AITutor) OverrideAnswer(ctx context.Context, schoolID, teacherID int64, req *api.OverrideAIAnswerRequest) (*dto.CommunicationMessageDTO, error) {
+	messages, err := t.behaviorDAO.GetCommunicationSessionMessagesByIDs(ctx, req.SessionID, []string{req.MessageID})
+	if err != nil {
+		t.logger.Error(ctx, "[OverrideAnswer] 查询 AI 回答失败, messageID:%s, error:%v", req.MessageID, err)
+		return nil, err
+	}
+	if len(messages) == 0 || messages[0] == nil || messages[0].SessionID != req.SessionID ||
+		messages[0].UserType != string(consts.CommunicationUserTypeAI) {
+		return nil, ErrAITutorAnswer
+	}
+
+	session, err := t.behaviorDAO.GetCommunicationSession(ctx, req.SessionID)
+	if err != nil {
+		t.logger.Error(ctx, "[OverrideAnswer] 查询会话失败, sessionID:%s, error:%v", req.SessionID, err)
+		return nil, err
+	}
+	if session.SchoolID != uint64(schoolID) {
+		return nil, ErrAITutorAnswer
+	}
+
+	return &dto.CommunicationMessageDTO{
+		MessageID:      uuid.NewString(),
+		SessionID:      req.SessionID,
+		UserID:         uint64(teacherID),
+		UserType:       string(consts.CommunicationUserTypeTeacher),
+		MessageContent: req.Content,
+		MessageType:    consts.CommunicationMessageTypeAIOverride,
+		AnswerTo:       req.MessageID,
+		CreatedAt:      time.Now(),
+	}, nil
+}
+
+// 每条 AI 消息对应一条审核记录，关联被回答的学生消息和教师最近一次修正
+func buildAITutorAnswers(sessions []*dto.CommunicationSessionDTO, messages []*dto.CommunicationMessageDTO) []*dto.AITutorAnswerDTO {
+	questionIDs := make(map[string]string, len(sessions))
+	for _, session := range sessions {
+		if session.TargetID != nil {
+			questionIDs[session.SessionID], _ = consts.ParseTaskQuestionTargetID(*session.TargetID)
+		}
+	}
+	messageMap := make(map[string]*dto.CommunicationMessageDTO, len(messages))
+	overrides := make(map[string]*dto.CommunicationMessageDTO)
+	for _, message := range messages {
+		messageMap[message.MessageID] = message
+		if message.MessageType == consts.CommunicationMessageTypeAIOverride {
+			overrides[message.AnswerTo] = message
+		}
+	}
+
+	answers := make([]*dto.AITutorAnswerDTO, 0)
+	for _, message := range messages {
+		if message.UserType != string(consts.CommunicationUserTypeAI) {
+			continue
+		}
+		answer := &dto.AITutorAnswerDTO{
+			SessionID:  message.SessionID,
+			QuestionID: questionIDs[message.SessionID],
+			Question:   messageMap[message.AnswerTo],
+			Answer:     message,
+			Escalated:  message.MessageType == consts.CommunicationMessageTypeAIEscalated,
+			Override:   overrides[message.MessageID],
+		}
+		if answer.Question != nil {
+			answer.StudentID = answer.Question.UserID
+		}
+		answers = append(answers, answer)
+	}
+	return answers
+}
+
+// AI 回答的消息 ID 由学生消息 ID 生成，重复消费时写入同一条消息
+func aiTutorReplyID(messageID string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte("ai_tutor#"+messageID)).String()
+}
+
+func studentWantsTeacher(content string) bool {
+	for _, keyword := range consts.AITutorEscalationKeywords {
+		if strings.Contains(content, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 系统提示带上作业名称、题干和选项、参考答案和解析
+func tutorSystemPrompt(questionContext *tutorQuestionContext) string {
+	taskName, stem, answer, explanation := "", "", "", ""
+	if questionContext.task != nil {
+		taskName = questionContext.task.TaskName
+	}
+	if question := questionContext.question; question != nil && question.QuestionContentEntity != nil {
+		explanation = question.QuestionExplanation
+		if format := question.QuestionContentFormat; format != nil {
+			options := make([]string, 0, len(format.QuestionOptionList))
+			for _, option := range format.QuestionOptionList {
+				options = append(options, option.OptionKey+". "+option.OptionVal)
+			}
+			stem = strings.TrimSpace(format.QuestionStem + "\n" + strings.Join(options, "\n"))
+		}
+		if question.QuestionAnswer != nil {
+			options := make([]string, 0, len(question.QuestionAnswer.AnswerOptionList))
+			for _, option := range question.QuestionAnswer.AnswerOptionList {
+				options = append(options, strings.TrimSpace(option.OptionKey+" "+option.OptionVal))
+			}
+			answer = strings.Join(options, "；")
+		}
+	}
+	return fmt.Sprintf(consts.AITutorPromptV1.SystemPrompt, taskName, stem, answer, explanation)
+}
+
+// 解析模型返回的回答，回答为空时转交教师
+func parseTutorAnswer(content string) (*tutorAnswer, error) {
+	object, err := extractJSONObject(content)
+	if err != nil {
+		return nil, err
+	}
+	var answer tutorAnswer
+	if err := json.Unmarshal([]byte(object), &answer); err != nil {
+		return nil, errors.Wrapf(err, "模型返回格式不正确: %s", content)
+	}
+	answer.Answer = strings.TrimSpace(answer.Answer)
+	if answer.Answer == "" {
+		answer.NeedTeacher = true
+	}
+	return &answer, nil
+}
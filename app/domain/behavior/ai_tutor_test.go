@@ -0,0 +1,225 @@
+package behavior
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/model/itl"
+	"gil_teacher/app/third_party/volc_ai"
+)
+
+type stubTutorDAO struct {
+	behaviorDao.BehaviorDAO
+	session  *dto.CommunicationSessionDTO
+	messages []*dto.CommunicationMessageDTO
+}
+
+func (d *stubTutorDAO) GetCommunicationSession(ctx context.Context, sessionID string) (*dto.CommunicationSessionDTO, error) {
+	return d.session, nil
+}
+
+func (d *stubTutorDAO) GetSessionsMessages(ctx context.Context, sessionIDs []string) ([]*dto.CommunicationMessageDTO, error) {
+	return d.messages, nil
+}
+
+type stubTutorTaskDAO struct {
+	dao_task.TaskDAO
+}
+
+func (d *stubTutorTaskDAO) GetTasksByIDs(ctx context.Context, taskIDs []int64) ([]*dao_task.Task, error) {
+	return []*dao_task.Task{{TaskID: taskIDs[0], TaskName: "第一单元练习", CreatorID: 7, SchoolID: 1}}, nil
+}
+
+type stubTutorAssignDAO struct {
+	dao_task.TaskAssignDAO
+	assign *dao_task.TaskAssign
+}
+
+func (d *stubTutorAssignDAO) GetTaskAssigns(ctx context.Context, taskID int64, assignIds []int64) ([]*dao_task.TaskAssign, error) {
+	return []*dao_task.TaskAssign{d.assign}, nil
+}
+
+type stubTutorQuestions struct{}
+
+func (stubTutorQuestions) GetQuestionDetail(ctx context.Context, questionID string) (*itl.Question, error) {
+	return &itl.Question{QuestionId: questionID}, nil
+}
+
+type stubChatModel struct {
+	reply    string
+	err      error
+	messages []volc_ai.ChatMessage
+}
+
+func (m *stubChatModel) Chat(ctx context.Context, messages []volc_ai.ChatMessage) (string, error) {
+	m.messages = messages
+	return m.reply, m.err
+}
+
+type stubTutorSender struct {
+	sent []*dto.CommunicationMessageDTO
+}
+
+func (s *stubTutorSender) SendCommunicationMessage(ctx context.Context, message *dto.CommunicationMessageDTO) error {
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func newTestAITutor(model *stubChatModel, messages ...*dto.CommunicationMessageDTO) (*AITutor, *stubTutorSender) {
+	targetID := consts.TaskQuestionTargetID(11, 12, "q1")
+	sender := &stubTutorSender{}
+	return &AITutor{
+		behaviorDAO: &stubTutorDAO{
+			session: &dto.CommunicationSessionDTO{
+				SessionID:   "s1",
+				SessionType: string(consts.CommunicationSessionTypeQuestion),
+				TargetID:    &targetID,
+			},
+			messages: messages,
+		},
+		taskDAO:   &stubTutorTaskDAO{},
+		questions: stubTutorQuestions{},
+		model:     model,
+		sender:    sender,
+		logger:    clogger.NewContextLogger(log.DefaultLogger),
+	}, sender
+}
+
+func studentQuestion(content string) *dto.CommunicationMessageDTO {
+	return &dto.CommunicationMessageDTO{
+		MessageID:      "m1",
+		SessionID:      "s1",
+		UserID:         100,
+		UserType:       string(consts.CommunicationUserTypeStudent),
+		MessageContent: content,
+		CreatedAt:      time.Unix(1700000000, 0),
+	}
+}
+
+func TestAITutorRespond(t *testing.T) {
+	model := &stubChatModel{reply: `好的。{"answer":"先想想三角形内角和是多少","confidence":0.9,"needTeacher":false}`}
+	tutor, sender := newTestAITutor(model)
+
+	require.NoError(t, tutor.Respond(context.Background(), studentQuestion("这道题怎么做")))
+	require.Len(t, sender.sent, 1)
+	reply := sender.sent[0]
+	assert.Equal(t, consts.CommunicationMessageTypeAIAnswer, reply.MessageType)
+	assert.Equal(t, "先想想三角形内角和是多少", reply.MessageContent)
+	assert.Equal(t, "m1", reply.AnswerTo)
+	assert.Equal(t, aiTutorReplyID("m1"), reply.MessageID)
+	assert.Equal(t, string(consts.CommunicationUserTypeAI), reply.UserType)
+
+	// 系统提示带上作业名称，当前提问作为最后一条消息
+	require.Len(t, model.messages, 2)
+	assert.Contains(t, model.messages[0].Content, "第一单元练习")
+	assert.Equal(t, volc_ai.ChatMessage{Role: volc_ai.ChatRoleUser, Content: "这道题怎么做"}, model.messages[1])
+}
+
+func TestAITutorEscalate(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   *stubChatModel
+		content string
+	}{
+		{"置信度低", &stubChatModel{reply: `{"answer":"可能是 B","confidence":0.3}`}, "这道题怎么做"},
+		{"模型要求教师", &stubChatModel{reply: `{"answer":"请问老师","confidence":0.9,"needTeacher":true}`}, "这道题怎么做"},
+		{"模型失败", &stubChatModel{err: errors.New("timeout")}, "这道题怎么做"},
+		{"格式不正确", &stubChatModel{reply: "我不知道"}, "这道题怎么做"},
+		{"学生要求", &stubChatModel{}, "我想找老师问问"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tutor, sender := newTestAITutor(tt.model)
+			require.NoError(t, tutor.Respond(context.Background(), studentQuestion(tt.content)))
+			require.Len(t, sender.sent, 1)
+			assert.Equal(t, consts.CommunicationMessageTypeAIEscalated, sender.sent[0].MessageType)
+			assert.Equal(t, consts.AITutorEscalatedReply, sender.sent[0].MessageContent)
+			assert.Equal(t, "m1", sender.sent[0].AnswerTo)
+		})
+	}
+}
+
+func TestAITutorSkip(t *testing.T) {
+	question := studentQuestion("这道题怎么做")
+	tests := []struct {
+		name    string
+		history *dto.CommunicationMessageDTO
+	}{
+		{"已回答", &dto.CommunicationMessageDTO{MessageID: aiTutorReplyID("m1"), UserType: string(consts.CommunicationUserTypeAI)}},
+		{"教师已回复", &dto.CommunicationMessageDTO{MessageID: "m0", UserType: string(consts.CommunicationUserTypeTeacher)}},
+		{"已转交", &dto.CommunicationMessageDTO{MessageID: "m0", UserType: string(consts.CommunicationUserTypeAI), MessageType: consts.CommunicationMessageTypeAIEscalated}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &stubChatModel{reply: `{"answer":"ok","confidence":1}`}
+			tutor, sender := newTestAITutor(model, tt.history, question)
+			require.NoError(t, tutor.Respond(context.Background(), question))
+			assert.Empty(t, sender.sent)
+			assert.Nil(t, model.messages)
+		})
+	}
+}
+
+func TestBuildAITutorAnswers(t *testing.T) {
+	targetID := consts.TaskQuestionTargetID(11, 12, "q1")
+	sessions := []*dto.CommunicationSessionDTO{{SessionID: "s1", TargetID: &targetID}}
+	question := studentQuestion("这道题怎么做")
+	answer := &dto.CommunicationMessageDTO{MessageID: "a1", SessionID: "s1", UserType: string(consts.CommunicationUserTypeAI),
+		MessageType: consts.CommunicationMessageTypeAIAnswer, AnswerTo: "m1"}
+	override := &dto.CommunicationMessageDTO{MessageID: "o1", SessionID: "s1", UserType: string(consts.CommunicationUserTypeTeacher),
+		MessageType: consts.CommunicationMessageTypeAIOverride, AnswerTo: "a1"}
+
+	answers := buildAITutorAnswers(sessions, []*dto.CommunicationMessageDTO{question, answer, override})
+	require.Len(t, answers, 1)
+	assert.Equal(t, "q1", answers[0].QuestionID)
+	assert.Equal(t, uint64(100), answers[0].StudentID)
+	assert.Equal(t, question, answers[0].Question)
+	assert.Equal(t, override, answers[0].Override)
+	assert.False(t, answers[0].Escalated)
+}
+
+func TestAITutorClassIDs(t *testing.T) {
+	ctx := context.Background()
+	tutor, _ := newTestAITutor(&stubChatModel{})
+	assignDAO := &stubTutorAssignDAO{assign: &dao_task.TaskAssign{AssignID: 12, TaskID: 11, SchoolID: 1, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1001}}
+	tutor.taskAssignDAO = assignDAO
+
+	// 任务创建者不需要班级权限
+	classIDs, err := tutor.TaskClassIDs(ctx, 1, 7, 11, 12)
+	require.NoError(t, err)
+	assert.Empty(t, classIDs)
+
+	// 其他教师需要布置班级的权限
+	classIDs, err = tutor.TaskClassIDs(ctx, 1, 8, 11, 12)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1001}, classIDs)
+
+	// 其他学校的任务
+	_, err = tutor.TaskClassIDs(ctx, 2, 7, 11, 12)
+	assert.ErrorIs(t, err, ErrAITutorTask)
+
+	// 布置给学生的任务只有创建者可以查看
+	assignDAO.assign.GroupType = consts.TASK_GROUP_TYPE_STUDENT
+	_, err = tutor.TaskClassIDs(ctx, 1, 8, 11, 12)
+	assert.ErrorIs(t, err, ErrAITutorForbidden)
+
+	// 修正回答时从会话解析任务布置
+	assignDAO.assign.GroupType = consts.TASK_GROUP_TYPE_CLASS
+	tutor.behaviorDAO.(*stubTutorDAO).session.SchoolID = 1
+	classIDs, err = tutor.AnswerClassIDs(ctx, 1, 8, "s1")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1001}, classIDs)
+	_, err = tutor.AnswerClassIDs(ctx, 2, 8, "s1")
+	assert.ErrorIs(t, err, ErrAITutorAnswer)
+}
@@ -0,0 +1,398 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/service/schedule"
+	"gil_teacher/app/utils"
+)
+
+// ClassroomReportHandler 课后课堂报告，定时扫描缓存的教师课程表，为已下课的课堂生成报告快照
+type ClassroomReportHandler struct {
+	behaviorHandler *BehaviorHandler
+	reportDAO       behaviorDao.ClassroomReportDAO
+	redisClient     *dao.ApiRdbClient
+	logger          *clogger.ContextLogger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// endedClassroom 课程表中已下课的一节课
+type endedClassroom struct {
+	SchoolID int64
+	*schedule.Lesson
+}
+
+func NewClassroomReportHandler(
+	behaviorHandler *BehaviorHandler,
+	reportDAO behaviorDao.ClassroomReportDAO,
+	redisClient *dao.ApiRdbClient,
+	logger *clogger.ContextLogger,
+) (*ClassroomReportHandler, func()) {
+	h := &ClassroomReportHandler{
+		behaviorHandler: behaviorHandler,
+		reportDAO:       reportDAO,
+		redisClient:     redisClient,
+		logger:          logger,
+		stop:            make(chan struct{}),
+	}
+
+	h.wg.Add(1)
+	go h.scanner()
+
+	cleanup := func() {
+		close(h.stop)
+		h.wg.Wait()
+	}
+	return h, cleanup
+}
+
+// ListClassReports 分页查询班级历史课堂报告
+func (h *ClassroomReportHandler) ListClassReports(ctx context.Context, schoolID int64, req *api.ClassroomReportListRequest) (*api.ClassroomReportListResponse, error) {
+	pageInfo := &consts.DBPageInfo{Page: req.Page, Limit: req.PageSize}
+	reports, total, err := h.reportDAO.ListClassReports(ctx, uint64(schoolID), uint64(req.ClassID), pageInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*api.ClassroomReport, 0, len(reports))
+	for _, report := range reports {
+		item, err := toAPIClassroomReport(report)
+		if err != nil {
+			h.logger.Warn(ctx, "[ListClassReports] 解析课堂报告失败, reportID:%s, error:%v", report.ReportID, err)
+			continue
+		}
+		list = append(list, item)
+	}
+	return &api.ClassroomReportListResponse{
+		List: list,
+		PageInfo: &consts.ApiPageResponse{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+			Total:    total,
+		},
+	}, nil
+}
+
+// GetClassroomReport 查询课堂报告详情，包含学生学情，报告不存在时返回 nil
+func (h *ClassroomReportHandler) GetClassroomReport(ctx context.Context, schoolID int64, reportID string) (*api.ClassroomReport, error) {
+	report, err := h.reportDAO.GetClassroomReport(ctx, uint64(schoolID), reportID)
+	if err != nil || report == nil {
+		return nil, err
+	}
+	result, err := toAPIClassroomReport(report)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := h.reportDAO.GetClassroomLearningStats(ctx, reportID, report.UpdateTime)
+	if err != nil {
+		return nil, err
+	}
+	result.Students = make([]*api.ClassroomReportStudent, 0, len(stats))
+	for _, stat := range stats {
+		var student api.ClassroomReportStudent
+		if err := json.Unmarshal([]byte(stat.Summary), &student); err != nil {
+			h.logger.Warn(ctx, "[GetClassroomReport] 解析学生学情失败, reportID:%s, studentID:%d, error:%v", reportID, stat.StudentID, err)
+			continue
+		}
+		result.Students = append(result.Students, &student)
+	}
+	return result, nil
+}
+
+func (h *ClassroomReportHandler) scanner() {
+	defer h.wg.Done()
+	ticker := time.NewTicker(consts.ClassroomReportScanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.scan()
+		}
+	}
+}
+
+// 使用 SCAN 分批遍历所有教师的课程表缓存，为已下课的课堂生成报告
+func (h *ClassroomReportHandler) scan() {
+	ctx := context.Background()
+	now := time.Now()
+	var cursor uint64
+	for {
+		keys, next, err := h.redisClient.Scan(ctx, cursor, consts.TeacherScheduleAllPattern, consts.ClassroomReportScanCount)
+		if err != nil {
+			h.logger.Error(ctx, "[scan] 遍历课程表缓存失败, error:%v", err)
+			return
+		}
+		for _, key := range keys {
+			select {
+			case <-h.stop:
+				return
+			default:
+			}
+			h.scanTeacherSchedule(ctx, key, now)
+		}
+		if next == 0 {
+			return
+		}
+		cursor = next
+	}
+}
+
+// 为一位教师课程表中已下课的课堂生成报告
+func (h *ClassroomReportHandler) scanTeacherSchedule(ctx context.Context, key string, now time.Time) {
+	schoolID, teacherID, ok := parseTeacherScheduleKey(key)
+	if !ok {
+		return
+	}
+	var dataStr string
+	exists, err := h.redisClient.Get(ctx, consts.GetTeacherScheduleKey(schoolID, teacherID), &dataStr)
+	if err != nil || !exists {
+		return
+	}
+	var scheduleResp api.ScheduleResponse
+	if err := json.Unmarshal([]byte(dataStr), &scheduleResp); err != nil {
+		h.logger.Warn(ctx, "[scan] 解析课程表失败, schoolID:%d, teacherID:%d, error:%v", schoolID, teacherID, err)
+		return
+	}
+
+	for _, classroom := range endedClassrooms(schoolID, &scheduleResp, now) {
+		h.generateOnce(classroom)
+	}
+}
+
+// 通过 redis 标记保证每节课只生成一次，生成失败时清除标记，下次扫描重试
+func (h *ClassroomReportHandler) generateOnce(classroom *endedClassroom) {
+	ctx, cancel := context.WithTimeout(context.Background(), consts.ClassroomReportTimeout)
+	defer cancel()
+
+	key := consts.GetClassroomReportKey(classroom.ClassroomID, classroom.StartTime)
+	reportID := classroomReportID(classroom.ClassroomID, classroom.StartTime)
+	ok, err := h.redisClient.SetNX(ctx, key, reportID, consts.ClassroomReportExpire)
+	if err != nil || !ok {
+		return
+	}
+
+	if err := h.Generate(ctx, classroom); err != nil {
+		h.logger.Error(ctx, "[generateOnce] 生成课堂报告失败, classroomID:%d, startTime:%v, error:%v",
+			classroom.ClassroomID, classroom.StartTime, err)
+		if err := h.redisClient.Del(ctx, key); err != nil {
+			h.logger.Warn(ctx, "[generateOnce] 清除课堂报告标记失败, key:%s, error:%v", key, err)
+		}
+		return
+	}
+	h.logger.Info(ctx, "[generateOnce] 生成课堂报告成功, classroomID:%d, reportID:%s", classroom.ClassroomID, reportID)
+}
+
+// Generate 快照课堂的学情汇总、表扬关注、学习分和沟通数据，保存为课堂报告
+func (h *ClassroomReportHandler) Generate(ctx context.Context, classroom *endedClassroom) error {
+	classroomID := uint64(classroom.ClassroomID)
+	summary, err := h.behaviorHandler.GetClassroomBehaviorSummary(ctx, classroom.SchoolID, classroomID, classroom.StartTime, classroom.EndTime)
+	if err != nil {
+		return err
+	}
+	scores, err := h.behaviorHandler.GetClassroomLearningScores(ctx, classroom.SchoolID, classroomID, classroom.StartTime, classroom.EndTime)
+	if err != nil {
+		return err
+	}
+	actions, err := h.reportDAO.CountClassroomTeacherActions(ctx, classroomID, classroom.StartTime, classroom.EndTime)
+	if err != nil {
+		return err
+	}
+	communication, err := h.reportDAO.CountClassroomCommunications(ctx, classroomID, classroom.StartTime, classroom.EndTime)
+	if err != nil {
+		return err
+	}
+
+	content, students := buildClassroomReport(classroom, summary, scores, actions, communication)
+	contentBytes, err := json.Marshal(content)
+	if err != nil {
+		return errors.Wrap(err, "marshal classroom report failed")
+	}
+	stats := make([]*dto.ClassroomLearningStatsDTO, 0, len(students))
+	for _, student := range students {
+		summaryBytes, err := json.Marshal(student)
+		if err != nil {
+			return errors.Wrap(err, "marshal classroom learning stats failed")
+		}
+		stats = append(stats, &dto.ClassroomLearningStatsDTO{
+			StudentID:     student.StudentID,
+			LearningScore: student.LearningScore,
+			Summary:       string(summaryBytes),
+		})
+	}
+
+	report := &dto.ClassroomReportDTO{
+		ReportID:    classroomReportID(classroom.ClassroomID, classroom.StartTime),
+		SchoolID:    uint64(classroom.SchoolID),
+		ClassID:     uint64(classroom.Schedule.ClassID),
+		ClassroomID: classroomID,
+		TeacherID:   uint64(classroom.Schedule.ClassScheduleTeacherID),
+		CourseID:    uint64(classroom.Schedule.ClassScheduleCourseID),
+		StartTime:   classroom.StartTime,
+		EndTime:     classroom.EndTime,
+		Content:     string(contentBytes),
+	}
+	return h.reportDAO.SaveClassroomReport(ctx, report, stats)
+}
+
+// 汇总课堂报告内容和每个学生的学情，学生取行为汇总和学习分的并集
+func buildClassroomReport(
+	classroom *endedClassroom,
+	summary *api.ClassroomBehaviorSummaryResponse,
+	scores []*dto.StudentLearningScoreDTO,
+	actions []*dto.ClassroomTeacherActionDTO,
+	communication *dto.ClassroomCommunicationStatsDTO,
+) (*api.ClassroomReportContent, []*api.ClassroomReportStudent) {
+	content := &api.ClassroomReportContent{
+		ClassName:     classroom.Schedule.ClassName,
+		CourseName:    classroom.Schedule.ClassScheduleCourse,
+		TeacherName:   classroom.Schedule.TeacherName,
+		TotalStudents: summary.TotalStudents,
+		AvgAccuracy:   summary.AvgAccuracy,
+		AvgProgress:   summary.AvgProgress,
+		TotalDuration: summary.TotalDuration,
+		PraiseList:    summary.PraiseList,
+		AttentionList: summary.AttentionList,
+	}
+	if communication != nil {
+		content.Communication = *communication
+	}
+
+	students := make(map[uint64]*api.ClassroomReportStudent)
+	getStudent := func(studentID uint64) *api.ClassroomReportStudent {
+		student, ok := students[studentID]
+		if !ok {
+			student = &api.ClassroomReportStudent{StudentID: studentID, BehaviorTags: []api.BehaviorTag{}}
+			students[studentID] = student
+		}
+		return student
+	}
+
+	for _, category := range summary.AllStudents {
+		student := getStudent(category.StudentID)
+		student.StudentName = category.StudentName
+		student.AvatarURL = category.AvatarUrl
+		student.AccuracyRate = category.AccuracyRate
+		student.LearningProgress = category.LearningProgress
+		if category.BehaviorTags != nil {
+			student.BehaviorTags = category.BehaviorTags
+		}
+	}
+
+	var totalScore int64
+	for _, score := range scores {
+		student := getStudent(score.StudentID)
+		if student.StudentName == "" {
+			student.StudentName = score.StudentName
+			student.AvatarURL = score.AvatarURL
+		}
+		student.LearningScore = score.LearningScore
+		student.LearningTime = score.LearningTime
+		student.CorrectCount = score.CorrectCount
+		student.TotalCount = score.TotalCount
+		student.ScoreItems = score.ScoreItems
+		totalScore += score.LearningScore
+	}
+	content.AvgLearningScore = utils.F64Div(float64(totalScore), float64(len(scores)), 2)
+
+	for _, action := range actions {
+		switch action.BehaviorType {
+		case consts.BehaviorTypePraise:
+			content.PraiseCount += action.Count
+		case consts.BehaviorTypeAttention:
+			content.AttentionCount += action.Count
+		default:
+			continue
+		}
+		// 学生 ID 缺失的旧记录只计入课堂总数
+		if action.StudentID == 0 {
+			continue
+		}
+		student := getStudent(action.StudentID)
+		if action.BehaviorType == consts.BehaviorTypePraise {
+			student.PraiseCount += action.Count
+		} else {
+			student.AttentionCount += action.Count
+		}
+	}
+
+	result := make([]*api.ClassroomReportStudent, 0, len(students))
+	for _, student := range students {
+		result = append(result, student)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].LearningScore != result[j].LearningScore {
+			return result[i].LearningScore > result[j].LearningScore
+		}
+		return result[i].StudentID < result[j].StudentID
+	})
+	return content, result
+}
+
+// 从课程表中找出已下课且超过延迟时间、仍在回溯时间内的课堂
+// 课程表按星期几分组时通过 Dates 映射为实际日期
+func endedClassrooms(schoolID int64, scheduleResp *api.ScheduleResponse, now time.Time) []*endedClassroom {
+	classrooms := make([]*endedClassroom, 0)
+	for _, lesson := range schedule.Lessons(scheduleResp) {
+		if now.Before(lesson.EndTime.Add(consts.ClassroomReportDelay)) || now.Sub(lesson.EndTime) > consts.ClassroomReportLookback {
+			continue
+		}
+		classrooms = append(classrooms, &endedClassroom{SchoolID: schoolID, Lesson: lesson})
+	}
+	return classrooms
+}
+
+// 同一课堂每周重复上课，报告 ID 由课堂 ID 和上课时间生成，重复生成时覆盖
+func classroomReportID(classroomID int64, startTime time.Time) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprintf("classroom_report#%d#%d", classroomID, startTime.Unix()))).String()
+}
+
+// scan 命令返回带公共前缀的完整 key，解析出学校ID和教师ID
+func parseTeacherScheduleKey(key string) (int64, int64, bool) {
+	index := strings.LastIndex(key, strings.TrimSuffix(consts.TeacherScheduleAllPattern, "*"))
+	if index < 0 {
+		return 0, 0, false
+	}
+	var schoolID, teacherID int64
+	if _, err := fmt.Sscanf(key[index:], consts.TeacherScheduleKeyFormat, &schoolID, &teacherID); err != nil {
+		return 0, 0, false
+	}
+	return schoolID, teacherID, true
+}
+
+func toAPIClassroomReport(report *dto.ClassroomReportDTO) (*api.ClassroomReport, error) {
+	var content api.ClassroomReportContent
+	if err := json.Unmarshal([]byte(report.Content), &content); err != nil {
+		return nil, errors.Wrap(err, "unmarshal classroom report failed")
+	}
+	return &api.ClassroomReport{
+		ReportID:               report.ReportID,
+		ClassID:                report.ClassID,
+		ClassroomID:            report.ClassroomID,
+		TeacherID:              report.TeacherID,
+		CourseID:               report.CourseID,
+		StartTime:              report.StartTime.Unix(),
+		EndTime:                report.EndTime.Unix(),
+		CreateTime:             report.CreateTime.Unix(),
+		ClassroomReportContent: &content,
+	}, nil
+}
@@ -0,0 +1,151 @@
+package behavior
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/service/schedule"
+)
+
+func TestEndedClassrooms(t *testing.T) {
+	scheduleResp := &api.ScheduleResponse{
+		Schedule: map[string][]api.Schedule{
+			"1": {
+				{ScheduleID: 11, ScheduleTplPeriodStartTime: "08:00:00", ScheduleTplPeriodEndTime: "08:45:00"},
+				{ScheduleID: 12, ScheduleTplPeriodStartTime: "09:00:00", ScheduleTplPeriodEndTime: "09:45:00"},
+				{ScheduleID: 13, ScheduleTplPeriodStartTime: "10:00:00", ScheduleTplPeriodEndTime: "10:45:00"},
+			},
+			"2": {
+				{IsTmp: 1, TmpScheduleID: 7, ScheduleTplPeriodStartTime: "08:15:00", ScheduleTplPeriodEndTime: "08:58:00"},
+			},
+			"2026-10-05": {
+				{ScheduleID: 14, ScheduleTplPeriodStartTime: "08:00:00", ScheduleTplPeriodEndTime: "08:45:00"},
+			},
+			// 没有日期映射的星期跳过
+			"3": {
+				{ScheduleID: 15, ScheduleTplPeriodStartTime: "08:00:00", ScheduleTplPeriodEndTime: "08:45:00"},
+			},
+		},
+		Dates: map[string]string{"1": "2026-10-12", "2": "2026-10-13"},
+	}
+	now := time.Date(2026, 10, 13, 9, 0, 0, 0, consts.LocationShanghai)
+
+	// 周一 08:45 下课的课和 10-05 的课超过回溯时间，周二 08:58 下课的临时课还不到延迟时间
+	classrooms := endedClassrooms(1, scheduleResp, now)
+	ids := make([]int64, 0, len(classrooms))
+	for _, classroom := range classrooms {
+		ids = append(ids, classroom.ClassroomID)
+	}
+	assert.Equal(t, []int64{12, 13}, ids)
+	assert.Equal(t, time.Date(2026, 10, 12, 9, 0, 0, 0, consts.LocationShanghai).Unix(), classrooms[0].StartTime.Unix())
+
+	// 下课超过延迟时间后生成临时课堂报告
+	classrooms = endedClassrooms(1, scheduleResp, now.Add(consts.ClassroomReportDelay))
+	assert.Equal(t, consts.GenerateTempClassroomID(7), classrooms[len(classrooms)-1].ClassroomID)
+}
+
+func TestParseTeacherScheduleKey(t *testing.T) {
+	schoolID, teacherID, ok := parseTeacherScheduleKey("gil_teacher:test:teacher_course:3:42")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), schoolID)
+	assert.Equal(t, int64(42), teacherID)
+
+	_, _, ok = parseTeacherScheduleKey("gil_teacher:test:teacher_info:3:42")
+	assert.False(t, ok)
+}
+
+func TestClassroomReportID(t *testing.T) {
+	start := time.Date(2026, 10, 12, 9, 0, 0, 0, consts.LocationShanghai)
+	// 同一节课重复生成报告 ID 不变，同一课堂不同周的课报告 ID 不同
+	assert.Equal(t, classroomReportID(12, start), classroomReportID(12, start))
+	assert.NotEqual(t, classroomReportID(12, start), classroomReportID(12, start.AddDate(0, 0, 7)))
+}
+
+func TestBuildClassroomReport(t *testing.T) {
+	classroom := &endedClassroom{Lesson: &schedule.Lesson{
+		ClassroomID: 12,
+		Schedule:    api.Schedule{ClassName: "一班", ClassScheduleCourse: "数学", TeacherName: "王老师"},
+	}}
+	summary := &api.ClassroomBehaviorSummaryResponse{
+		TotalStudents: 2,
+		AvgAccuracy:   75,
+		AllStudents: []api.StudentBehaviorCategory{
+			{StudentID: 1, StudentName: "张三", AccuracyRate: 100, BehaviorTags: []api.BehaviorTag{{Type: "correct_streak", Count: 3}}},
+			{StudentID: 2, StudentName: "李四", AccuracyRate: 50},
+		},
+	}
+	scores := []*dto.StudentLearningScoreDTO{
+		{StudentID: 1, StudentName: "张三", LearningScore: 90, CorrectCount: 4, TotalCount: 4},
+		{StudentID: 2, StudentName: "李四", LearningScore: 60, CorrectCount: 1, TotalCount: 2},
+		{StudentID: 3, StudentName: "王五", LearningScore: 75},
+	}
+	actions := []*dto.ClassroomTeacherActionDTO{
+		{StudentID: 1, BehaviorType: consts.BehaviorTypePraise, Count: 2},
+		{StudentID: 2, BehaviorType: consts.BehaviorTypeAttention, Count: 1},
+		{StudentID: 0, BehaviorType: consts.BehaviorTypeAttention, Count: 1},
+	}
+	communication := &dto.ClassroomCommunicationStatsDTO{SessionCount: 1, StudentMessageCount: 2}
+
+	content, students := buildClassroomReport(classroom, summary, scores, actions, communication)
+	assert.Equal(t, "一班", content.ClassName)
+	assert.Equal(t, "数学", content.CourseName)
+	assert.Equal(t, int64(2), content.TotalStudents)
+	assert.Equal(t, float64(75), content.AvgLearningScore)
+	assert.Equal(t, int64(2), content.PraiseCount)
+	assert.Equal(t, int64(2), content.AttentionCount)
+	assert.Equal(t, int64(2), content.Communication.StudentMessageCount)
+
+	// 学生按学习分倒序，只有学习分的学生也包含在内
+	assert.Len(t, students, 3)
+	assert.Equal(t, []uint64{1, 3, 2}, []uint64{students[0].StudentID, students[1].StudentID, students[2].StudentID})
+	assert.Equal(t, int64(2), students[0].PraiseCount)
+	assert.Equal(t, float64(100), students[0].AccuracyRate)
+	assert.Len(t, students[0].BehaviorTags, 1)
+	assert.Equal(t, "王五", students[1].StudentName)
+	assert.NotNil(t, students[1].BehaviorTags)
+	assert.Equal(t, int64(1), students[2].AttentionCount)
+}
+
+// 内存中的课堂报告表，学情统计按生成时间过滤，和 SQL 实现保持一致
+type stubClassroomReportDAO struct {
+	behaviorDao.ClassroomReportDAO
+	report *dto.ClassroomReportDTO
+	stats  map[time.Time][]*dto.ClassroomLearningStatsDTO
+}
+
+func (s *stubClassroomReportDAO) GetClassroomReport(ctx context.Context, schoolID uint64, reportID string) (*dto.ClassroomReportDTO, error) {
+	return s.report, nil
+}
+
+func (s *stubClassroomReportDAO) GetClassroomLearningStats(ctx context.Context, reportID string, reportTime time.Time) ([]*dto.ClassroomLearningStatsDTO, error) {
+	return s.stats[reportTime], nil
+}
+
+func TestGetClassroomReportRegenerated(t *testing.T) {
+	first := time.Date(2026, 10, 12, 9, 50, 0, 0, consts.LocationShanghai)
+	second := first.Add(time.Hour)
+	reportDAO := &stubClassroomReportDAO{
+		report: &dto.ClassroomReportDTO{ReportID: "r1", Content: "{}", CreateTime: second, UpdateTime: second},
+		stats: map[time.Time][]*dto.ClassroomLearningStatsDTO{
+			first:  {{StudentID: 1, Summary: `{"studentId":1}`}, {StudentID: 2, Summary: `{"studentId":2}`}},
+			second: {{StudentID: 1, Summary: `{"studentId":1}`}},
+		},
+	}
+	h := &ClassroomReportHandler{reportDAO: reportDAO, logger: clogger.NewContextLogger(log.DefaultLogger)}
+
+	// 重新生成后不在本次结果中的学生不再返回
+	report, err := h.GetClassroomReport(context.Background(), 1, "r1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, report) && assert.Len(t, report.Students, 1) {
+		assert.Equal(t, uint64(1), report.Students[0].StudentID)
+	}
+}
@@ -3,7 +3,8 @@ package behavior
 import (
 	"context"
 	"encoding/json"
-	"sync"
+	"fmt"
+	"sort"
 	"time"
 
 	"gil_teacher/app/conf"
@@ -18,18 +19,22 @@ import (
 
 // 用户行为数据消费处理
 type BehaviorConsumer struct {
-	kafkaConf *conf.Kafka
-	logger    *clogger.ContextLogger
+	kafkaConf  *conf.Kafka
+	deadLetter *kafka.KafkaProducerClient
+	logger     *clogger.ContextLogger
 }
 
-func NewBehaviorConsumer(kafkaConf *conf.Kafka, logger *clogger.ContextLogger) *BehaviorConsumer {
+// deadLetter 为空时处理失败的批次不提交 offset，重新加入消费组后重放
+func NewBehaviorConsumer(kafkaConf *conf.Kafka, deadLetter *kafka.KafkaProducerClient, logger *clogger.ContextLogger) *BehaviorConsumer {
 	return &BehaviorConsumer{
-		kafkaConf: kafkaConf,
-		logger:    logger,
+		kafkaConf:  kafkaConf,
+		deadLetter: deadLetter,
+		logger:     logger,
 	}
 }
 
 // 从 topic 批量消费消息，并调用 handler 处理
+// 消费会话到期或批次处理失败后重新加入消费组，从上次提交的 offset 继续消费，直到 ctx 结束
 func (c *BehaviorConsumer) Consume(ctx context.Context, handler *BehaviorHandler) {
 	// 添加日志确认实际使用的配置
 	c.logger.Info(ctx, "Kafka 配置信息: broker=%s, group=%s, topics=%v",
@@ -38,19 +43,96 @@ func (c *BehaviorConsumer) Consume(ctx context.Context, handler *BehaviorHandler
 		consts.KafkaTopicBehaviors)
 
 	consumerGroupHandlerImpl := &kafka.ConsumerGroupHandlerImpl{
-		Group:       consts.KafkaGroupBehavior,
-		Topics:      consts.KafkaTopicBehaviors,
-		BatchSize:   c.kafkaConf.Consumer.BatchSize,
-		BatchTime:   c.kafkaConf.Consumer.BatchTime * time.Second,
-		SessionTime: c.kafkaConf.Consumer.SessionTime * time.Second,
-		ProcMsgList: handler.HandleMessage,
-		Log:         c.logger,
-	}
-	kafka.ConsumeKafkaMsgInSession(ctx, c.kafkaConf, consumerGroupHandlerImpl)
+		Group:        consts.KafkaGroupBehavior,
+		Topics:       consts.KafkaTopicBehaviors,
+		BatchSize:    c.kafkaConf.Consumer.BatchSize,
+		BatchTime:    c.kafkaConf.Consumer.BatchTime * time.Second,
+		SessionTime:  c.kafkaConf.Consumer.SessionTime * time.Second,
+		ProcMsgList:  handler.HandleMessage,
+		MaxRetries:   c.kafkaConf.Consumer.MaxRetries,
+		RetryBackoff: c.kafkaConf.Consumer.RetryBackoff * time.Millisecond,
+		DeadLetter:   c.deadLetter,
+		Log:          c.logger,
+	}
+	for ctx.Err() == nil {
+		kafka.ConsumeKafkaMsgInSession(ctx, c.kafkaConf, consumerGroupHandlerImpl)
+		time.Sleep(time.Second)
+	}
+}
+
+// behaviorEvent 解码后的行为消息，保留原始消息用于排序、日志和失败上报
+type behaviorEvent struct {
+	eventID  string
+	msgType  consts.MessageType
+	content  json.RawMessage
+	msg      *sarama.ConsumerMessage
+	offset   int64 // 消息在原 partition 中的 offset，重放的死信取原 offset
+	replayed bool  // 是否为重放的死信
+}
+
+// 消息内容有问题，重试也不会成功，直接写入死信 topic
+func (e *behaviorEvent) reject(err error) *kafka.MessageError {
+	return &kafka.MessageError{Msg: e.msg, Err: err}
+}
+
+// decodeBehaviorEvents 按 topic、partition、offset 排序解码消息，并去掉批次内重复的事件
+// 旧版本生产端没有 EventID，使用消息的原始位置作为事件 ID，重放同一条消息时仍能去重
+func decodeBehaviorEvents(msgs []*sarama.ConsumerMessage) ([]*behaviorEvent, kafka.BatchError) {
+	sorted := make([]*sarama.ConsumerMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg != nil {
+			sorted = append(sorted, msg)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Topic != sorted[j].Topic {
+			return sorted[i].Topic < sorted[j].Topic
+		}
+		if sorted[i].Partition != sorted[j].Partition {
+			return sorted[i].Partition < sorted[j].Partition
+		}
+		return sorted[i].Offset < sorted[j].Offset
+	})
+
+	var failures kafka.BatchError
+	events := make([]*behaviorEvent, 0, len(sorted))
+	seen := make(map[string]struct{}, len(sorted))
+	for _, msg := range sorted {
+		behaviorMessage, err := DecodeBehaviorMessage(msg.Value)
+		if err != nil {
+			failures = append(failures, &kafka.MessageError{Msg: msg, Err: errors.Wrap(err, "解析消息失败")})
+			continue
+		}
+
+		offset, replayed := kafka.ReplayedOffset(msg)
+		if !replayed {
+			offset = msg.Offset
+		}
+		eventID := behaviorMessage.EventID
+		if eventID == "" {
+			eventID = fmt.Sprintf("%s%s%d%s%d", msg.Topic, consts.CombineKey, msg.Partition, consts.CombineKey, offset)
+		}
+		if _, ok := seen[eventID]; ok {
+			continue
+		}
+		seen[eventID] = struct{}{}
+
+		events = append(events, &behaviorEvent{
+			eventID:  eventID,
+			msgType:  behaviorMessage.Type,
+			content:  behaviorMessage.Content,
+			msg:      msg,
+			offset:   offset,
+			replayed: replayed,
+		})
+	}
+	return events, failures
 }
 
 // HandleMessage 实现 kafka.MessageHandler 接口
-// 不能退出，作为长连接消费
+// 同一批次来自同一 partition，按 offset 顺序分表批量入库，已入库的事件按 EventID 跳过，
+// 重放的死信比同一 key 已入库的消息更旧时跳过
+// 无法解析或校验不通过的消息、入库失败的消息通过 kafka.BatchError 返回，由消费框架重试或写入死信 topic
 func (h *BehaviorHandler) HandleMessage(msgs []*sarama.ConsumerMessage) error {
 	// 有消息才处理
 	if len(msgs) == 0 {
@@ -58,144 +140,257 @@ func (h *BehaviorHandler) HandleMessage(msgs []*sarama.ConsumerMessage) error {
 	}
 
 	ctx := context.Background()
-	// 1. 添加处理开始日志
 	startTime := time.Now()
 	h.logger.Debug(ctx, "开始处理消息批次，数量: %d", len(msgs))
 
-	// 目前 3 种消息都在一个队列中，等后续拆分
-	// 2. 对消息进行分类预处理
-	messagesByType := make(map[string][]json.RawMessage)
-	for _, msg := range msgs {
-		behaviorMessage, err := DecodeBehaviorMessage(msg.Value)
+	events, failures := decodeBehaviorEvents(msgs)
+	events = h.filterProcessedEvents(ctx, events)
+	events = h.filterStaleReplayedEvents(ctx, events)
+
+	// 按消息类型分表，保持 offset 顺序
+	eventsByType := make(map[consts.MessageType][]*behaviorEvent)
+	for _, event := range events {
+		switch event.msgType {
+		case consts.MessageTypeTeacherBehavior, consts.MessageTypeStudentBehavior, consts.MessageTypeCommunication:
+			eventsByType[event.msgType] = append(eventsByType[event.msgType], event)
+		default:
+			failures = append(failures, event.reject(errors.Errorf("未知消息类型: %s", event.msgType)))
+		}
+	}
+
+	processors := []struct {
+		msgType consts.MessageType
+		name    string
+		process func(context.Context, []*behaviorEvent) ([]*behaviorEvent, kafka.BatchError, error)
+	}{
+		{consts.MessageTypeTeacherBehavior, "教师行为", h.processTeacherBehaviors},
+		{consts.MessageTypeStudentBehavior, "学生行为", h.processStudentBehaviors},
+		{consts.MessageTypeCommunication, "沟通记录", h.processCommunications},
+	}
+	applied := make([]*behaviorEvent, 0, len(events))
+	for _, processor := range processors {
+		typeEvents := eventsByType[processor.msgType]
+		if len(typeEvents) == 0 {
+			continue
+		}
+		accepted, rejected, err := processor.process(ctx, typeEvents)
+		failures = append(failures, rejected...)
 		if err != nil {
-			h.logger.Error(ctx, "解析消息失败, error:%v, msg:%+v", err, msg)
-			continue
-		}
-
-		switch behaviorMessage.Type {
-		case consts.MessageTypeTeacherBehavior:
-			messagesByType[consts.KafkaTopicTeacherBehavior] = append(messagesByType[consts.KafkaTopicTeacherBehavior], behaviorMessage.Content)
-		//case consts.MessageTypeStudentBehavior:
-		//	messagesByType[consts.KafkaTopicStudentBehavior] = append(messagesByType[consts.KafkaTopicStudentBehavior], behaviorMessage.Content)
-		case consts.MessageTypeCommunication:
-			messagesByType[consts.KafkaTopicCommunication] = append(messagesByType[consts.KafkaTopicCommunication], behaviorMessage.Content)
-		}
-	}
-
-	// 3. 并发处理不同类型的消息
-	errChan := make(chan error, 3)
-	var wg sync.WaitGroup
-	for topic, messages := range messagesByType {
-		wg.Add(1)
-		go func(topic string, msgs []json.RawMessage) {
-			defer wg.Done()
-			var err error
-			switch topic {
-			case consts.KafkaTopicTeacherBehavior:
-				err = h.processTeacherBehaviors(ctx, msgs)
-				if err != nil {
-					h.logger.Error(ctx, "处理教师行为失败, error:%v, topic:%s, messages:%+v", err, topic, msgs)
-					errChan <- errors.Wrap(err, "处理教师行为失败")
-				}
-			//case consts.KafkaTopicStudentBehavior:
-			//	err = h.processStudentBehaviors(ctx, msgs)
-			//	if err != nil {
-			//		h.logger.Error(ctx, "处理学生行为失败, error:%v, topic:%s, messages:%+v", err, topic, msgs)
-			//		errChan <- errors.Wrap(err, "处理学生行为失败")
-			//	}
-			case consts.KafkaTopicCommunication:
-				err = h.processCommunications(ctx, msgs)
-				if err != nil {
-					h.logger.Error(ctx, "处理沟通记录失败, error:%v, topic:%s, messages:%+v", err, topic, msgs)
-					errChan <- errors.Wrap(err, "处理沟通记录失败")
-				}
-			default:
-				h.logger.Error(ctx, "未知消息类型, topic:%s, messages:%+v", topic, messages)
+			h.logger.Error(ctx, "处理%s失败, error:%v, 数量:%d", processor.name, err, len(accepted))
+			for _, event := range accepted {
+				failures = append(failures, &kafka.MessageError{
+					Msg:       event.msg,
+					Err:       errors.Wrapf(err, "处理%s失败", processor.name),
+					Retryable: true,
+				})
 			}
-		}(topic, messages)
+			continue
+		}
+		h.markEventsProcessed(ctx, accepted)
+		applied = append(applied, accepted...)
 	}
+	h.markAppliedOffsets(ctx, applied)
 
-	// 等待所有处理完成
-	wg.Wait()
-	close(errChan)
+	h.logger.Debug(ctx, "消息批次处理完成，耗时: %v, 入库数: %d, 失败数: %d",
+		time.Since(startTime), len(applied), len(failures))
+	if len(failures) > 0 {
+		for _, failure := range failures {
+			h.logger.Error(ctx, "[HandleMessage] 消息处理失败, retryable:%t, %v", failure.Retryable, failure)
+		}
+		return failures
+	}
+	return nil
+}
 
-	// 收集错误
-	var errs []error
-	for err := range errChan {
-		errs = append(errs, err)
+// filterProcessedEvents 过滤已入库的事件，Redis 不可用时不过滤，由下游容忍少量重复
+func (h *BehaviorHandler) filterProcessedEvents(ctx context.Context, events []*behaviorEvent) []*behaviorEvent {
+	if h.redisClient == nil {
+		return events
 	}
 
-	// 有消费才打印日志
-	if len(msgs) > 0 {
-		h.logger.Debug(ctx, "消息批次处理完成，耗时: %v, 成功数: %d, 错误数: %d",
-			time.Since(startTime), len(msgs)-len(errs), len(errs))
+	pending := make([]*behaviorEvent, 0, len(events))
+	for _, event := range events {
+		exists, err := h.redisClient.KeyExists(ctx, consts.GetBehaviorEventKey(event.eventID))
+		if err != nil {
+			h.logger.Warn(ctx, "[filterProcessedEvents] 查询事件去重标记失败, eventID:%s, error:%v", event.eventID, err)
+		}
+		if exists {
+			h.logger.Debug(ctx, "[filterProcessedEvents] 跳过已入库事件, eventID:%s, partition:%d, offset:%d",
+				event.eventID, event.msg.Partition, event.msg.Offset)
+			continue
+		}
+		pending = append(pending, event)
+	}
+	return pending
+}
+
+// markEventsProcessed 入库成功后记录事件去重标记
+func (h *BehaviorHandler) markEventsProcessed(ctx context.Context, events []*behaviorEvent) {
+	if h.redisClient == nil {
+		return
+	}
+
+	for _, event := range events {
+		if err := h.redisClient.Set(ctx, consts.GetBehaviorEventKey(event.eventID), 1, consts.BehaviorEventExpire); err != nil {
+			h.logger.Warn(ctx, "[markEventsProcessed] 记录事件去重标记失败, eventID:%s, error:%v", event.eventID, err)
+		}
+	}
+}
+
+// filterStaleReplayedEvents 过滤比同一 key 已入库的消息更旧的重放死信
+// 重放的死信写在原 partition 末尾，同一 key 之后的消息可能已经入库，再入库会用旧数据覆盖新数据
+func (h *BehaviorHandler) filterStaleReplayedEvents(ctx context.Context, events []*behaviorEvent) []*behaviorEvent {
+	if h.redisClient == nil {
+		return events
+	}
+
+	pending := make([]*behaviorEvent, 0, len(events))
+	for _, event := range events {
+		if !event.replayed || len(event.msg.Key) == 0 {
+			pending = append(pending, event)
+			continue
+		}
+		var appliedOffset int64
+		exists, err := h.redisClient.Get(ctx, consts.GetBehaviorAppliedOffsetKey(event.msg.Topic, event.msg.Partition, string(event.msg.Key)), &appliedOffset)
+		if err != nil {
+			h.logger.Warn(ctx, "[filterStaleReplayedEvents] 查询已入库 offset 失败, eventID:%s, error:%v", event.eventID, err)
+		}
+		if exists && appliedOffset > event.offset {
+			h.logger.Info(ctx, "[filterStaleReplayedEvents] 跳过过期的重放消息, eventID:%s, partition:%d, offset:%d, appliedOffset:%d",
+				event.eventID, event.msg.Partition, event.offset, appliedOffset)
+			continue
+		}
+		pending = append(pending, event)
+	}
+	return pending
+}
+
+// markAppliedOffsets 入库成功后记录每个消息 key 的最新 offset，只前进不后退
+// 批次失败后逐条重试的消息可能比已记录的 offset 更旧
+func (h *BehaviorHandler) markAppliedOffsets(ctx context.Context, events []*behaviorEvent) {
+	if h.redisClient == nil {
+		return
+	}
+
+	for key, offset := range latestKeyOffsets(events) {
+		var appliedOffset int64
+		if exists, err := h.redisClient.Get(ctx, key, &appliedOffset); err == nil && exists && appliedOffset >= offset {
+			continue
+		}
+		if err := h.redisClient.Set(ctx, key, offset, consts.BehaviorEventExpire); err != nil {
+			h.logger.Warn(ctx, "[markAppliedOffsets] 记录已入库 offset 失败, key:%s, error:%v", key, err)
+		}
 	}
-	return nil
 }
 
-func (h *BehaviorHandler) processTeacherBehaviors(ctx context.Context, content []json.RawMessage) error {
-	behaviors := make([]*dto.TeacherBehaviorDTO, 0, len(content))
-	for _, c := range content {
+// latestKeyOffsets 批次中每个消息 key 的最大 offset，没有 key 的消息和重放的死信不记录
+func latestKeyOffsets(events []*behaviorEvent) map[string]int64 {
+	offsets := make(map[string]int64)
+	for _, event := range events {
+		if event.replayed || len(event.msg.Key) == 0 {
+			continue
+		}
+		key := consts.GetBehaviorAppliedOffsetKey(event.msg.Topic, event.msg.Partition, string(event.msg.Key))
+		if offset, ok := offsets[key]; !ok || event.offset > offset {
+			offsets[key] = event.offset
+		}
+	}
+	return offsets
+}
+
+// 以下 process 方法返回通过校验的事件、被拒绝的消息和入库错误，入库失败时通过校验的事件都需要重试
+
+func (h *BehaviorHandler) processTeacherBehaviors(ctx context.Context, events []*behaviorEvent) ([]*behaviorEvent, kafka.BatchError, error) {
+	var rejected kafka.BatchError
+	accepted := make([]*behaviorEvent, 0, len(events))
+	behaviors := make([]*dto.TeacherBehaviorDTO, 0, len(events))
+	for _, event := range events {
 		var behavior dto.TeacherBehaviorDTO
-		if err := json.Unmarshal(c, &behavior); err != nil {
-			return errors.Wrap(err, "解析教师行为数据失败")
+		if err := json.Unmarshal(event.content, &behavior); err != nil {
+			rejected = append(rejected, event.reject(errors.Wrap(err, "解析教师行为数据失败")))
+			continue
 		}
 		h.logger.Debug(ctx, "解析教师行为数据成功, behavior:%+v", behavior)
 
 		if err := h.validateTeacherBehavior(&behavior); err != nil {
-			h.logger.Error(ctx, "教师行为数据验证失败, error:%+v, behavior:%+v", err, behavior)
+			rejected = append(rejected, event.reject(errors.Wrap(err, "教师行为数据验证失败")))
 			continue
 		}
 
+		accepted = append(accepted, event)
 		behaviors = append(behaviors, &behavior)
 	}
 
-	return h.behaviorDAO.SaveTeacherBehavior(ctx, behaviors)
+	if len(behaviors) == 0 {
+		return accepted, rejected, nil
+	}
+	return accepted, rejected, h.behaviorDAO.SaveTeacherBehavior(ctx, behaviors)
 }
 
-func (h *BehaviorHandler) processStudentBehaviors(ctx context.Context, content []json.RawMessage) error {
-	behaviors := make([]*dto.StudentBehaviorDTO, 0, len(content))
-	for _, c := range content {
+func (h *BehaviorHandler) processStudentBehaviors(ctx context.Context, events []*behaviorEvent) ([]*behaviorEvent, kafka.BatchError, error) {
+	var rejected kafka.BatchError
+	accepted := make([]*behaviorEvent, 0, len(events))
+	behaviors := make([]*dto.StudentBehaviorDTO, 0, len(events))
+	for _, event := range events {
 		var behavior dto.StudentBehaviorDTO
-		if err := json.Unmarshal(c, &behavior); err != nil {
-			return errors.Wrap(err, "解析学生行为数据失败")
+		if err := json.Unmarshal(event.content, &behavior); err != nil {
+			rejected = append(rejected, event.reject(errors.Wrap(err, "解析学生行为数据失败")))
+			continue
 		}
 
 		if err := h.validateStudentBehavior(&behavior); err != nil {
-			h.logger.Error(ctx, "学生行为数据验证失败, error:%v, behavior:%+v", err, behavior)
+			rejected = append(rejected, event.reject(errors.Wrap(err, "学生行为数据验证失败")))
 			continue
 		}
+		accepted = append(accepted, event)
 		behaviors = append(behaviors, &behavior)
 	}
 
-	return h.behaviorDAO.SaveStudentBehavior(ctx, behaviors)
+	if len(behaviors) == 0 {
+		return accepted, rejected, nil
+	}
+	return accepted, rejected, h.behaviorDAO.SaveStudentBehavior(ctx, behaviors)
 }
 
-func (h *BehaviorHandler) processCommunications(ctx context.Context, content []json.RawMessage) error {
-	communications := make([]*dto.CommunicationMessageDTO, 0, len(content))
-	for _, c := range content {
+func (h *BehaviorHandler) processCommunications(ctx context.Context, events []*behaviorEvent) ([]*behaviorEvent, kafka.BatchError, error) {
+	var rejected kafka.BatchError
+	accepted := make([]*behaviorEvent, 0, len(events))
+	communications := make([]*dto.CommunicationMessageDTO, 0, len(events))
+	for _, event := range events {
 		var communication dto.CommunicationMessageDTO
-		if err := json.Unmarshal(c, &communication); err != nil {
-			return errors.Wrap(err, "解析沟通记录失败")
+		if err := json.Unmarshal(event.content, &communication); err != nil {
+			rejected = append(rejected, event.reject(errors.Wrap(err, "解析沟通记录失败")))
+			continue
 		}
 		if err := h.validateCommunication(&communication); err != nil {
-			h.logger.Error(ctx, "沟通记录数据验证失败, error:%v, communication:%+v", err, communication)
+			rejected = append(rejected, event.reject(errors.Wrap(err, "沟通记录数据验证失败")))
 			continue
 		}
 
 		// 查看会话是否关闭，关闭 5 min后不允许再提交
 		session, err := h.GetCommunicationSession(ctx, communication.SessionID)
 		if err != nil {
-			h.logger.Error(ctx, "查询会话失败, error:%v, communication:%+v", err, communication)
+			rejected = append(rejected, &kafka.MessageError{Msg: event.msg, Err: errors.Wrap(err, "查询会话失败"), Retryable: true})
 			continue
 		}
-		if session.Closed && time.Since(*session.EndTime) > 5*time.Minute {
-			h.logger.Error(ctx, "会话已关闭，不能再提交消息, communication:%+v", communication)
+		if session.Closed && session.EndTime != nil && time.Since(*session.EndTime) > 5*time.Minute {
+			rejected = append(rejected, event.reject(errors.New("会话已关闭，不能再提交消息")))
 			continue
 		}
 
+		accepted = append(accepted, event)
 		communications = append(communications, &communication)
 	}
 
-	return h.behaviorDAO.SaveCommunication(ctx, nil, communications)
+	if len(communications) == 0 {
+		return accepted, rejected, nil
+	}
+	if err := h.behaviorDAO.SaveCommunication(ctx, nil, communications); err != nil {
+		return accepted, rejected, err
+	}
+	// 缓存只影响未读统计，更新失败时等缓存过期后从消息表重建
+	if err := appendSessionMessages(ctx, h.redisClient, communications); err != nil {
+		h.logger.Warn(ctx, "追加会话消息缓存失败: %v", err)
+	}
+	return accepted, rejected, nil
 }
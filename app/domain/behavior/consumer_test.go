@@ -0,0 +1,126 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/model/dto"
+)
+
+type stubBehaviorDAO struct {
+	behaviorDao.BehaviorDAO
+	students []*dto.StudentBehaviorDTO
+	err      error
+}
+
+func (d *stubBehaviorDAO) SaveStudentBehavior(ctx context.Context, behaviors []*dto.StudentBehaviorDTO) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.students = append(d.students, behaviors...)
+	return nil
+}
+
+func behaviorConsumerMessage(t *testing.T, offset int64, eventID string, studentID uint64) *sarama.ConsumerMessage {
+	content, err := json.Marshal(&dto.StudentBehaviorDTO{
+		SchoolID:     1,
+		ClassID:      2,
+		StudentID:    studentID,
+		BehaviorType: consts.BehaviorTypeAnswer,
+		CreateTime:   time.Unix(offset, 0),
+	})
+	assert.NoError(t, err)
+	msg := newBehaviorMessage(consts.MessageTypeStudentBehavior, content)
+	msg.EventID = eventID
+	return &sarama.ConsumerMessage{
+		Topic:     consts.KafkaTopicStudentBehavior,
+		Partition: 3,
+		Offset:    offset,
+		Value:     msg.Encode(),
+	}
+}
+
+func TestDecodeBehaviorEvents(t *testing.T) {
+	msgs := []*sarama.ConsumerMessage{
+		behaviorConsumerMessage(t, 12, "b", 2),
+		behaviorConsumerMessage(t, 10, "a", 1),
+		behaviorConsumerMessage(t, 11, "b", 2),
+		behaviorConsumerMessage(t, 13, "", 3),
+		{Topic: consts.KafkaTopicStudentBehavior, Partition: 3, Offset: 14, Value: []byte("not json")},
+	}
+
+	events, errs := decodeBehaviorEvents(msgs)
+	assert.Len(t, errs, 1)
+
+	// 按 offset 排序，批次内重复的事件只保留最早的一条，没有 EventID 时使用消息位置
+	ids := make([]string, 0, len(events))
+	offsets := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.eventID)
+		offsets = append(offsets, event.msg.Offset)
+	}
+	assert.Equal(t, []string{"a", "b", "topic-student-behaviors#3#13"}, ids)
+	assert.Equal(t, []int64{10, 11, 13}, offsets)
+}
+
+func TestHandleStudentBehaviorMessage(t *testing.T) {
+	behaviorDAO := &stubBehaviorDAO{}
+	h := NewBehaviorHandler(behaviorDAO, nil, nil, nil, clogger.NewContextLogger(log.DefaultLogger))
+
+	msgs := []*sarama.ConsumerMessage{
+		behaviorConsumerMessage(t, 21, "b", 2),
+		behaviorConsumerMessage(t, 20, "a", 1),
+	}
+	assert.NoError(t, h.HandleMessage(msgs))
+	assert.Len(t, behaviorDAO.students, 2)
+	assert.Equal(t, uint64(1), behaviorDAO.students[0].StudentID)
+	assert.Equal(t, uint64(2), behaviorDAO.students[1].StudentID)
+
+	// 校验不通过的消息不可重试，入库失败的消息可以重试
+	behaviorDAO.err = errors.New("clickhouse unavailable")
+	invalid := behaviorConsumerMessage(t, 22, "c", 0)
+	err := h.HandleMessage(append(msgs, invalid))
+	var batchErr kafka.BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Len(t, batchErr, 3)
+	retryable := make(map[int64]bool, len(batchErr))
+	for _, failure := range batchErr {
+		retryable[failure.Msg.Offset] = failure.Retryable
+	}
+	assert.Equal(t, map[int64]bool{20: true, 21: true, 22: false}, retryable)
+}
+
+func TestReplayedBehaviorEvents(t *testing.T) {
+	replayed := behaviorConsumerMessage(t, 30, "", 1)
+	replayed.Key = []byte("s1")
+	replayed.Headers = []*sarama.RecordHeader{{Key: []byte(consts.KafkaHeaderReplayOffset), Value: []byte("12")}}
+	newer := behaviorConsumerMessage(t, 25, "n", 1)
+	newer.Key = []byte("s1")
+	other := behaviorConsumerMessage(t, 26, "o", 2)
+	other.Key = []byte("s2")
+	noKey := behaviorConsumerMessage(t, 27, "k", 3)
+
+	events, errs := decodeBehaviorEvents([]*sarama.ConsumerMessage{replayed, other, newer, noKey})
+	assert.Empty(t, errs)
+	// 重放消息按原 offset 生成事件 ID，与原消息去重
+	assert.Equal(t, "topic-student-behaviors#3#12", events[3].eventID)
+	assert.True(t, events[3].replayed)
+	assert.Equal(t, int64(12), events[3].offset)
+
+	// 只记录非重放、有 key 的消息，同一 key 取最大 offset
+	assert.Equal(t, map[string]int64{
+		consts.GetBehaviorAppliedOffsetKey(consts.KafkaTopicStudentBehavior, 3, "s1"): 25,
+		consts.GetBehaviorAppliedOffsetKey(consts.KafkaTopicStudentBehavior, 3, "s2"): 26,
+	}, latestKeyOffsets(events))
+}
@@ -0,0 +1,369 @@
+package behavior
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+// ErrInvalidBehaviorContext 行为类型未注册上下文结构，或上下文不符合注册的结构
+var ErrInvalidBehaviorContext = errors.New("行为上下文不符合结构定义")
+
+// behaviorContextKind 行为上下文的内容类型，决定上下文解码为哪种结构
+type behaviorContextKind int
+
+const (
+	behaviorContextActivity behaviorContextKind = iota + 1 // 学生学习活动，解码为 dto.StudentBehaviorContext
+	behaviorContextNotice                                  // 教师表扬、关注、评价等，解码为 dto.TeacherBehaviorContext
+	behaviorContextText                                    // 纯文本内容，放在 dto.TeacherBehaviorContext.Message 中
+)
+
+type behaviorContextSchemaKey struct {
+	behaviorType consts.BehaviorType
+	version      int64
+}
+
+// behaviorContextSchema 一种行为类型在一个结构版本下的上下文定义
+type behaviorContextSchema struct {
+	kind behaviorContextKind
+	// 拒绝结构中未定义的字段
+	strict bool
+	// 行为类型自己的校验，如必填字段，通用的取值范围校验对所有版本生效
+	validateActivity func(c *dto.StudentBehaviorContext) error
+	validateNotice   func(c *dto.TeacherBehaviorContext) error
+}
+
+// behaviorContextSchemas 按行为类型和结构版本注册的上下文结构，行为类型按小写匹配，兼容历史数据中首字母大写的类型
+var behaviorContextSchemas = make(map[behaviorContextSchemaKey]*behaviorContextSchema)
+
+func registerBehaviorContextSchema(behaviorType consts.BehaviorType, version int64, schema *behaviorContextSchema) {
+	key := behaviorContextSchemaKey{behaviorType: consts.BehaviorType(strings.ToLower(string(behaviorType))), version: version}
+	if _, ok := behaviorContextSchemas[key]; ok {
+		panic("duplicate behavior context schema: " + string(behaviorType))
+	}
+	behaviorContextSchemas[key] = schema
+}
+
+func init() {
+	// 学生学习活动，statistics、studytime、score 是历史数据中由服务端写入的统计类型，只有旧版结构
+	for _, behaviorType := range []consts.BehaviorType{
+		consts.BehaviorTypeBrowse, consts.BehaviorTypeAnswer, consts.BehaviorTypeQuestion,
+		consts.BehaviorTypeLearning, consts.BehaviorTypeInteract, consts.BehaviorTypeChat,
+	} {
+		registerBehaviorContextSchema(behaviorType, consts.BehaviorContextSchemaV1, &behaviorContextSchema{kind: behaviorContextActivity})
+		registerBehaviorContextSchema(behaviorType, consts.BehaviorContextSchemaV2, &behaviorContextSchema{
+			kind:             behaviorContextActivity,
+			strict:           true,
+			validateActivity: activityRequiredFields[behaviorType],
+		})
+	}
+	for _, behaviorType := range []consts.BehaviorType{"statistics", "studytime", "score"} {
+		registerBehaviorContextSchema(behaviorType, consts.BehaviorContextSchemaV1, &behaviorContextSchema{kind: behaviorContextActivity})
+	}
+
+	// 教师对学生的行为，评价和布置任务记录在学生行为表中，上下文结构相同
+	for _, behaviorType := range []consts.BehaviorType{
+		consts.BehaviorTypePraise, consts.BehaviorTypeAttention, consts.BehaviorTypeClassComment,
+		consts.BehaviorTypeAssignTask, consts.BehaviorTypeCommunication, consts.BehaviorTypeOfflineCommunication,
+	} {
+		registerBehaviorContextSchema(behaviorType, consts.BehaviorContextSchemaV1, &behaviorContextSchema{kind: behaviorContextNotice})
+		registerBehaviorContextSchema(behaviorType, consts.BehaviorContextSchemaV2, &behaviorContextSchema{
+			kind:           behaviorContextNotice,
+			strict:         true,
+			validateNotice: noticeRequiredFields[behaviorType],
+		})
+	}
+
+	// 作业点赞和提醒的内容是纯文本，没有结构版本
+	registerBehaviorContextSchema(consts.BehaviorTypeTaskPraise, consts.BehaviorContextSchemaV1, &behaviorContextSchema{kind: behaviorContextText})
+	registerBehaviorContextSchema(consts.BehaviorTypeTaskAttention, consts.BehaviorContextSchemaV1, &behaviorContextSchema{kind: behaviorContextText})
+}
+
+// activityRequiredFields 新版结构中学生学习活动的必填字段
+var activityRequiredFields = map[consts.BehaviorType]func(c *dto.StudentBehaviorContext) error{
+	consts.BehaviorTypeAnswer: func(c *dto.StudentBehaviorContext) error {
+		if c.QuestionID == "" {
+			return errors.New("答题行为缺少 questionId")
+		}
+		if c.IsCorrect == nil {
+			return errors.New("答题行为缺少 isCorrect")
+		}
+		return nil
+	},
+	consts.BehaviorTypeQuestion: func(c *dto.StudentBehaviorContext) error {
+		if c.QuestionContent == "" {
+			return errors.New("提问行为缺少 question_content")
+		}
+		return nil
+	},
+}
+
+// noticeRequiredFields 新版结构中教师行为的必填字段
+var noticeRequiredFields = map[consts.BehaviorType]func(c *dto.TeacherBehaviorContext) error{
+	consts.BehaviorTypePraise:       requireNoticeStudentMessage,
+	consts.BehaviorTypeAttention:    requireNoticeStudentMessage,
+	consts.BehaviorTypeClassComment: requireNoticeMessage,
+}
+
+func requireNoticeStudentMessage(c *dto.TeacherBehaviorContext) error {
+	if c.StudentID == 0 {
+		return errors.New("缺少 studentId")
+	}
+	return requireNoticeMessage(c)
+}
+
+func requireNoticeMessage(c *dto.TeacherBehaviorContext) error {
+	if c.Message == "" {
+		return errors.New("缺少 message")
+	}
+	return nil
+}
+
+// validateBehaviorContext 按行为类型注册的结构校验上下文，记录行为和消费入库前调用
+func validateBehaviorContext(behaviorType consts.BehaviorType, content string) error {
+	schema, version, err := lookupBehaviorContextSchema(behaviorType, content)
+	if err != nil {
+		return err
+	}
+	switch schema.kind {
+	case behaviorContextActivity:
+		_, err = decodeActivityContext(schema, version, content)
+	case behaviorContextNotice:
+		_, err = decodeNoticeContext(schema, version, content)
+	}
+	return err
+}
+
+// decodeStudentBehaviorContext 解码学生学习活动上下文，教师行为类型没有学习活动数据，返回空上下文
+func decodeStudentBehaviorContext(behaviorType consts.BehaviorType, content string) (*dto.StudentBehaviorContext, error) {
+	schema, version, err := lookupBehaviorContextSchema(behaviorType, content)
+	if err != nil {
+		return nil, err
+	}
+	if schema.kind != behaviorContextActivity {
+		return &dto.StudentBehaviorContext{SchemaVersion: version}, nil
+	}
+	return decodeActivityContext(schema, version, content)
+}
+
+// decodeTeacherBehaviorContext 解码教师行为上下文，纯文本内容放在 Message 中，学习活动类型返回空上下文
+func decodeTeacherBehaviorContext(behaviorType consts.BehaviorType, content string) (*dto.TeacherBehaviorContext, error) {
+	schema, version, err := lookupBehaviorContextSchema(behaviorType, content)
+	if err != nil {
+		return nil, err
+	}
+	switch schema.kind {
+	case behaviorContextNotice:
+		return decodeNoticeContext(schema, version, content)
+	case behaviorContextText:
+		return &dto.TeacherBehaviorContext{SchemaVersion: version, Message: content}, nil
+	}
+	return &dto.TeacherBehaviorContext{SchemaVersion: version}, nil
+}
+
+// lookupBehaviorContextSchema 读取上下文中的结构版本，未填写时为旧版，并查找行为类型在该版本下注册的结构
+func lookupBehaviorContextSchema(behaviorType consts.BehaviorType, content string) (*behaviorContextSchema, int64, error) {
+	behaviorType = consts.BehaviorType(strings.ToLower(string(behaviorType)))
+	version := consts.BehaviorContextSchemaV1
+	if text := behaviorContextSchemas[behaviorContextSchemaKey{behaviorType: behaviorType, version: version}]; text != nil && text.kind == behaviorContextText {
+		return text, version, nil
+	}
+
+	if strings.TrimSpace(content) != "" {
+		var header struct {
+			SchemaVersion *int64 `json:"schemaVersion"`
+		}
+		if err := json.Unmarshal([]byte(content), &header); err != nil {
+			return nil, 0, errors.Wrapf(ErrInvalidBehaviorContext, "上下文不是 JSON 对象: %v", err)
+		}
+		if header.SchemaVersion != nil {
+			version = *header.SchemaVersion
+		}
+	}
+
+	schema := behaviorContextSchemas[behaviorContextSchemaKey{behaviorType: behaviorType, version: version}]
+	if schema == nil {
+		return nil, 0, errors.Wrapf(ErrInvalidBehaviorContext, "行为类型 %s 没有版本 %d 的上下文结构", behaviorType, version)
+	}
+	return schema, version, nil
+}
+
+func decodeActivityContext(schema *behaviorContextSchema, version int64, content string) (*dto.StudentBehaviorContext, error) {
+	var c *dto.StudentBehaviorContext
+	if schema.strict {
+		c = &dto.StudentBehaviorContext{}
+		if err := decodeStrict(content, c); err != nil {
+			return nil, errors.Wrapf(ErrInvalidBehaviorContext, "解析学习活动上下文失败: %v", err)
+		}
+	} else {
+		var legacy legacyStudentBehaviorContext
+		if strings.TrimSpace(content) != "" {
+			if err := json.Unmarshal([]byte(content), &legacy); err != nil {
+				return nil, errors.Wrapf(ErrInvalidBehaviorContext, "解析学习活动上下文失败: %v", err)
+			}
+		}
+		c = legacy.normalize()
+	}
+	c.SchemaVersion = version
+
+	if err := validateActivityContext(c); err != nil {
+		return nil, errors.Wrap(ErrInvalidBehaviorContext, err.Error())
+	}
+	if schema.validateActivity != nil {
+		if err := schema.validateActivity(c); err != nil {
+			return nil, errors.Wrap(ErrInvalidBehaviorContext, err.Error())
+		}
+	}
+	return c, nil
+}
+
+func decodeNoticeContext(schema *behaviorContextSchema, version int64, content string) (*dto.TeacherBehaviorContext, error) {
+	c := &dto.TeacherBehaviorContext{}
+	if schema.strict {
+		if err := decodeStrict(content, c); err != nil {
+			return nil, errors.Wrapf(ErrInvalidBehaviorContext, "解析教师行为上下文失败: %v", err)
+		}
+	} else if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), c); err != nil {
+			return nil, errors.Wrapf(ErrInvalidBehaviorContext, "解析教师行为上下文失败: %v", err)
+		}
+		// 旧版表扬上下文只有 behaviorType 表示表扬类型
+		if c.PraiseType == "" {
+			c.PraiseType = c.BehaviorType
+		}
+	}
+	c.SchemaVersion = version
+
+	if c.ReminderCount < 0 || c.PushTime < 0 {
+		return nil, errors.Wrap(ErrInvalidBehaviorContext, "次数和时间不能为负数")
+	}
+	if schema.validateNotice != nil {
+		if err := schema.validateNotice(c); err != nil {
+			return nil, errors.Wrap(ErrInvalidBehaviorContext, err.Error())
+		}
+	}
+	return c, nil
+}
+
+func decodeStrict(content string, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// validateActivityContext 所有版本通用的取值范围校验
+func validateActivityContext(c *dto.StudentBehaviorContext) error {
+	for _, count := range []int64{
+		c.StayDuration, c.EarlyLearnCount, c.QuestionCount, c.CorrectStreak,
+		c.PageSwitchCount, c.OtherContentCount, c.PauseCount, c.WrongAnswers,
+	} {
+		if count < 0 {
+			return errors.New("时长和次数不能为负数")
+		}
+	}
+	if c.LearningProgress < 0 || c.LearningProgress > 100 {
+		return errors.New("学习进度必须在 0-100 之间")
+	}
+	if c.IsCorrect != nil && *c.IsCorrect != 0 && *c.IsCorrect != 1 {
+		return errors.New("isCorrect 只能是 0 或 1")
+	}
+	if info := c.QuestionsInfo; info != nil {
+		if info.TotalQuestions < 0 || info.CorrectAnswers < 0 || info.WrongAnswers < 0 {
+			return errors.New("答题数不能为负数")
+		}
+		if info.CorrectAnswers+info.WrongAnswers > info.TotalQuestions {
+			return errors.New("答对和答错题数之和不能超过总题数")
+		}
+	}
+	return nil
+}
+
+// legacyStudentBehaviorContext 旧版学习活动上下文，历史客户端对同一字段混用下划线和驼峰命名，
+// 答题汇总也曾写在 questions 或顶层字段中，入库字段名优先，没有时使用别名
+type legacyStudentBehaviorContext struct {
+	dto.StudentBehaviorContext
+
+	LearningTypeAlias      string                    `json:"learningType"`
+	VideoStatusAlias       string                    `json:"videoStatus"`
+	StayDurationAlias      *float64                  `json:"stayDuration"`
+	EarlyLearnCountAlias   *int64                    `json:"earlyLearnCount"`
+	QuestionCountAlias     *int64                    `json:"questionCount"`
+	CorrectStreakAlias     *int64                    `json:"correctStreak"`
+	PageSwitchCountAlias   *int64                    `json:"pageSwitchCount"`
+	OtherContentCountAlias *int64                    `json:"otherContentCount"`
+	PauseCountAlias        *int64                    `json:"pauseCount"`
+	Questions              *dto.QuestionsInfoContext `json:"questions"`
+	TotalQuestions         *int64                    `json:"total_questions"`
+	TotalQuestionsAlias    *int64                    `json:"totalQuestions"`
+	CorrectAnswers         *int64                    `json:"correct_answers"`
+	CorrectAnswersAlias    *int64                    `json:"correctAnswers"`
+}
+
+func (l *legacyStudentBehaviorContext) normalize() *dto.StudentBehaviorContext {
+	c := l.StudentBehaviorContext
+	if c.LearningType == "" {
+		c.LearningType = l.LearningTypeAlias
+	}
+	if c.VideoStatus == "" {
+		c.VideoStatus = l.VideoStatusAlias
+	}
+	if c.StayDuration == 0 && l.StayDurationAlias != nil {
+		c.StayDuration = int64(*l.StayDurationAlias)
+	}
+	for _, alias := range []struct {
+		field *int64
+		value *int64
+	}{
+		{&c.EarlyLearnCount, l.EarlyLearnCountAlias},
+		{&c.QuestionCount, l.QuestionCountAlias},
+		{&c.CorrectStreak, l.CorrectStreakAlias},
+		{&c.PageSwitchCount, l.PageSwitchCountAlias},
+		{&c.OtherContentCount, l.OtherContentCountAlias},
+		{&c.PauseCount, l.PauseCountAlias},
+	} {
+		if *alias.field == 0 && alias.value != nil {
+			*alias.field = *alias.value
+		}
+	}
+
+	if c.QuestionsInfo == nil {
+		c.QuestionsInfo = l.Questions
+	}
+	total := firstInt64(l.TotalQuestions, l.TotalQuestionsAlias)
+	correct := firstInt64(l.CorrectAnswers, l.CorrectAnswersAlias)
+	if c.QuestionsInfo == nil && (total != nil || correct != nil) {
+		c.QuestionsInfo = &dto.QuestionsInfoContext{WrongAnswers: c.WrongAnswers}
+		if total != nil {
+			c.QuestionsInfo.TotalQuestions = *total
+		}
+		if correct != nil {
+			c.QuestionsInfo.CorrectAnswers = *correct
+		}
+	}
+	return &c
+}
+
+func firstInt64(values ...*int64) *int64 {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// studentBehaviorContext 解码学生学习活动上下文，上下文不符合结构时记录日志并按空上下文处理，避免一条脏数据影响整个班级的统计
+func (h *BehaviorHandler) studentBehaviorContext(ctx context.Context, behaviorType consts.BehaviorType, content string) *dto.StudentBehaviorContext {
+	c, err := decodeStudentBehaviorContext(behaviorType, content)
+	if err != nil {
+		h.logger.Warn(ctx, "解析学生行为上下文失败, behaviorType:%s, error:%v", behaviorType, err)
+		return &dto.StudentBehaviorContext{}
+	}
+	return c
+}
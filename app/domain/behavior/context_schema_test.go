@@ -0,0 +1,92 @@
+package behavior
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+)
+
+func TestDecodeStudentBehaviorContextV1(t *testing.T) {
+	// 旧版结构兼容驼峰别名和历史上的答题汇总字段，入库字段名优先
+	c, err := decodeStudentBehaviorContext(consts.BehaviorTypeLearning,
+		`{"student_name":"张三","learningType":"video","stayDuration":120.5,"correctStreak":3,"pause_count":2,"pauseCount":5,"extra":"x"}`)
+	require.NoError(t, err)
+	assert.Equal(t, consts.BehaviorContextSchemaV1, c.SchemaVersion)
+	assert.Equal(t, "张三", c.StudentName)
+	assert.Equal(t, "video", c.LearningType)
+	assert.Equal(t, int64(120), c.StayDuration)
+	assert.Equal(t, int64(3), c.CorrectStreak)
+	assert.Equal(t, int64(2), c.PauseCount)
+
+	c, err = decodeStudentBehaviorContext(consts.BehaviorTypeAnswer, `{"questions":{"total_questions":5,"correct_answers":4}}`)
+	require.NoError(t, err)
+	require.NotNil(t, c.QuestionsInfo)
+	assert.Equal(t, int64(4), c.QuestionsInfo.CorrectAnswers)
+
+	c, err = decodeStudentBehaviorContext(consts.BehaviorTypeAnswer, `{"totalQuestions":5,"correct_answers":5}`)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), c.QuestionsInfo.TotalQuestions)
+	assert.Equal(t, int64(5), c.QuestionsInfo.CorrectAnswers)
+
+	// 历史数据中首字母大写的类型和空上下文
+	c, err = decodeStudentBehaviorContext("Statistics", "")
+	require.NoError(t, err)
+	assert.Zero(t, c.ViolationCount())
+
+	// 教师行为类型没有学习活动数据
+	c, err = decodeStudentBehaviorContext(consts.BehaviorTypeClassComment, `{"message":"表现很好","pushTime":1700000000}`)
+	require.NoError(t, err)
+	assert.Zero(t, c.CorrectStreak)
+}
+
+func TestDecodeStudentBehaviorContextInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		behaviorType consts.BehaviorType
+		content      string
+	}{
+		"not json":         {consts.BehaviorTypeBrowse, `page_switch_count=3`},
+		"wrong type":       {consts.BehaviorTypeBrowse, `{"page_switch_count":"3"}`},
+		"negative count":   {consts.BehaviorTypeBrowse, `{"pause_count":-1}`},
+		"progress":         {consts.BehaviorTypeLearning, `{"learning_progress":120}`},
+		"answers":          {consts.BehaviorTypeAnswer, `{"questions_info":{"total_questions":2,"correct_answers":2,"wrong_answers":1}}`},
+		"is correct":       {consts.BehaviorTypeAnswer, `{"isCorrect":2}`},
+		"unregistered":     {"shake", `{}`},
+		"unknown version":  {consts.BehaviorTypeBrowse, `{"schemaVersion":9}`},
+		"v2 unknown field": {consts.BehaviorTypeBrowse, `{"schemaVersion":2,"pageSwitchCount":1}`},
+		"v2 required":      {consts.BehaviorTypeAnswer, `{"schemaVersion":2,"questionId":"q1"}`},
+		"legacy v2":        {"score", `{"schemaVersion":2}`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := validateBehaviorContext(tc.behaviorType, tc.content)
+			assert.True(t, errors.Is(err, ErrInvalidBehaviorContext), "error: %v", err)
+		})
+	}
+
+	c, err := decodeStudentBehaviorContext(consts.BehaviorTypeAnswer, `{"schemaVersion":2,"questionId":"q1","isCorrect":1,"page_switch_count":1}`)
+	require.NoError(t, err)
+	assert.Equal(t, consts.BehaviorContextSchemaV2, c.SchemaVersion)
+	assert.Equal(t, int64(1), *c.IsCorrect)
+	assert.Equal(t, int64(1), c.ViolationCount())
+}
+
+func TestDecodeTeacherBehaviorContext(t *testing.T) {
+	c, err := decodeTeacherBehaviorContext(consts.BehaviorTypePraise, `{"studentId":9,"message":"真棒","behaviorType":"question"}`)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), c.StudentID)
+	assert.Equal(t, "question", c.PraiseType)
+
+	// 作业点赞和提醒是纯文本，不按 JSON 解析
+	c, err = decodeTeacherBehaviorContext(consts.BehaviorTypeTaskAttention, "请按时完成作业")
+	require.NoError(t, err)
+	assert.Equal(t, "请按时完成作业", c.Message)
+
+	_, err = decodeTeacherBehaviorContext(consts.BehaviorTypeAttention, `{"schemaVersion":2,"message":"请专注"}`)
+	assert.True(t, errors.Is(err, ErrInvalidBehaviorContext))
+
+	_, err = decodeTeacherBehaviorContext(consts.BehaviorTypeAttention, `{"studentId":"9"}`)
+	assert.True(t, errors.Is(err, ErrInvalidBehaviorContext))
+}
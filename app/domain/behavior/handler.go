@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"slices"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +15,7 @@ import (
 	clogger "gil_teacher/app/core/logger"
 	"gil_teacher/app/dao"
 	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/domain/push"
 	"gil_teacher/app/model/api"
 	"gil_teacher/app/model/dto"
 	"gil_teacher/app/utils"
@@ -25,17 +25,23 @@ import (
 type BehaviorHandler struct {
 	behaviorDAO behaviorDao.BehaviorDAO
 	redisClient *dao.ApiRdbClient
+	pusher      *push.PushPublisher
+	rules       *BehaviorRuleStore
 	logger      *clogger.ContextLogger
 }
 
 func NewBehaviorHandler(
 	behaviorDAO behaviorDao.BehaviorDAO,
 	redisClient *dao.ApiRdbClient,
+	pusher *push.PushPublisher,
+	rules *BehaviorRuleStore,
 	logger *clogger.ContextLogger,
 ) *BehaviorHandler {
 	return &BehaviorHandler{
 		behaviorDAO: behaviorDAO,
 		redisClient: redisClient,
+		pusher:      pusher,
+		rules:       rules,
 		logger:      logger,
 	}
 }
@@ -61,6 +67,9 @@ func (h *BehaviorHandler) validateTeacherBehavior(behavior *dto.TeacherBehaviorD
 	if behavior.CreateTime.IsZero() {
 		return errors.New("创建时间不能为0")
 	}
+	if err := validateBehaviorContext(behavior.BehaviorType, behavior.Context); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -81,6 +90,9 @@ func (h *BehaviorHandler) validateStudentBehavior(behavior *dto.StudentBehaviorD
 	if behavior.CreateTime.IsZero() {
 		return errors.New("创建时间不能为0")
 	}
+	if err := validateBehaviorContext(behavior.BehaviorType, behavior.Context); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -225,7 +237,7 @@ func setTargetID(targetID string) *string {
 
 // GetClassLatestBehaviors 获取班级学生最新行为
 func (h *BehaviorHandler) GetClassLatestBehaviors(ctx context.Context, ClassroomID uint64) ([]*dto.StudentLatestBehaviorDTO, error) {
-	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, ClassroomID)
+	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, ClassroomID, time.Time{}, time.Time{})
 	if err != nil {
 		h.logger.Error(ctx, "获取班级学生最新行为失败: %v", err)
 		return nil, errors.Wrap(err, "获取班级学生最新行为失败")
@@ -243,14 +255,12 @@ func (h *BehaviorHandler) GetClassLatestBehaviors(ctx context.Context, Classroom
 		if behavior != nil {
 			// 从Context中提取学生姓名和头像URL，如果为空则尝试填充
 			if behavior.StudentName == "" || behavior.AvatarURL == "" {
-				var contextMap map[string]interface{}
-				if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-					if behavior.StudentName == "" {
-						behavior.StudentName = utils.GetMapStringKey(contextMap, "student_name")
-					}
-					if behavior.AvatarURL == "" {
-						behavior.AvatarURL = utils.GetMapStringKey(contextMap, "avatar_url")
-					}
+				behaviorContext := h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context)
+				if behavior.StudentName == "" {
+					behavior.StudentName = behaviorContext.StudentName
+				}
+				if behavior.AvatarURL == "" {
+					behavior.AvatarURL = behaviorContext.AvatarURL
 				}
 			}
 			nonNilBehaviors = append(nonNilBehaviors, behavior)
@@ -335,20 +345,18 @@ func (h *BehaviorHandler) GetStudentClassroomDetail(ctx context.Context, req *ap
 
 			// 从最近的评价记录中提取评价内容
 			if latestBehavior != nil {
-				var contextMap map[string]interface{}
-				if err := json.Unmarshal([]byte(latestBehavior.Context), &contextMap); err == nil {
+				evaluateContext, err := decodeTeacherBehaviorContext(consts.BehaviorType(latestBehavior.BehaviorType), latestBehavior.Context)
+				if err != nil {
+					h.logger.Warn(ctx, "解析课堂评价上下文失败, studentID:%d, error:%v", req.StudentID, err)
+				} else {
 					// 尝试获取message字段作为评价内容
-					if message, ok := contextMap["message"].(string); ok {
-						response.EvaluateContent = message
+					if evaluateContext.Message != "" {
+						response.EvaluateContent = evaluateContext.Message
 					} else {
 						// 如果没有message字段，则将整个context作为评价内容
 						response.EvaluateContent = latestBehavior.Context
 					}
-
-					// 提取推送时间
-					if pushTime, ok := contextMap["pushTime"].(float64); ok {
-						response.PushTime = int64(pushTime)
-					}
+					response.PushTime = evaluateContext.PushTime
 				}
 			}
 		}
@@ -374,15 +382,7 @@ func (h *BehaviorHandler) GetStudentClassroomDetail(ctx context.Context, req *ap
 				}
 
 				// 从Context中提取违规行为数据
-				var contextMap map[string]interface{}
-				if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-					// 统计违规行为次数
-					pageSwitchCount := int64(utils.GetMapIntKey(contextMap, "page_switch_count", 0))
-					otherContentCount := int64(utils.GetMapIntKey(contextMap, "other_content_count", 0))
-					pauseCount := int64(utils.GetMapIntKey(contextMap, "pause_count", 0))
-
-					violationCount += pageSwitchCount + otherContentCount + pauseCount
-				}
+				violationCount += h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context).ViolationCount()
 			}
 			response.ViolationCount = violationCount
 		}
@@ -409,9 +409,9 @@ func (h *BehaviorHandler) GetStudentClassroomDetail(ctx context.Context, req *ap
 }
 
 // GetClassBehaviorCategory 获取课堂行为分类
-func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroomID uint64) ([]dto.StudentBehaviorCategoryDTO, error) {
+func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, schoolID int64, classroomID uint64) ([]dto.StudentBehaviorCategoryDTO, error) {
 	// 获取课堂学生行为数据
-	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, classroomID)
+	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, classroomID, time.Time{}, time.Time{})
 	if err != nil {
 		h.logger.Error(ctx, "获取课堂学生行为数据失败: %v", err)
 		return nil, errors.Wrap(err, "获取课堂学生行为数据失败")
@@ -423,8 +423,16 @@ func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroo
 		return []dto.StudentBehaviorCategoryDTO{}, nil
 	}
 
+	return h.classBehaviorCategories(ctx, classroomID, behaviors, func(behavior *dto.StudentLatestBehaviorDTO) *dto.BehaviorRuleSet {
+		return h.behaviorRuleSet(schoolID, behavior)
+	})
+}
+
+// classBehaviorCategories 按学生汇总课堂行为，使用学生最新行为适用的规则集生成行为标签
+func (h *BehaviorHandler) classBehaviorCategories(ctx context.Context, classroomID uint64, behaviors []*dto.StudentLatestBehaviorDTO,
+	ruleSetOf func(behavior *dto.StudentLatestBehaviorDTO) *dto.BehaviorRuleSet) ([]dto.StudentBehaviorCategoryDTO, error) {
 	// 新增：获取班级所有行为用于聚合不同类型
-	allBehaviors, err := h.behaviorDAO.GetClassAllBehaviors(ctx, classroomID)
+	allBehaviors, err := h.behaviorDAO.GetClassAllBehaviors(ctx, classroomID, time.Time{}, time.Time{})
 	if err != nil {
 		h.logger.Error(ctx, "获取课堂学生所有行为失败: %v", err)
 		// 不要返回错误，继续使用原有的behaviors
@@ -442,6 +450,7 @@ func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroo
 	// 初始化结果
 	// 按学生ID聚合行为
 	studentBehaviorsMap := make(map[int64]*dto.StudentBehaviorCategoryDTO)
+	latestBehaviors := make(map[int64]*dto.StudentLatestBehaviorDTO)
 
 	// 首先处理最新行为，获取基本信息
 	for _, behavior := range behaviors {
@@ -466,12 +475,10 @@ func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroo
 		}
 
 		// 从Context中提取学生姓名和头像URL
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			student.StudentName = utils.GetMapStringKey(contextMap, "student_name")
-			student.AvatarUrl = utils.GetMapStringKey(contextMap, "avatar_url")
-			student.LearningProgress = utils.GetMapFloat64Key(contextMap, "learning_progress")
-		}
+		behaviorContext := h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context)
+		student.StudentName = behaviorContext.StudentName
+		student.AvatarUrl = behaviorContext.AvatarURL
+		student.LearningProgress = behaviorContext.LearningProgress
 
 		// 检查是否已处理
 		if handleTime, exists := handledMap[studentID]; exists && handleTime > 0 {
@@ -506,6 +513,7 @@ func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroo
 		student.PraiseCount = praiseCount
 
 		studentBehaviorsMap[studentID] = &student
+		latestBehaviors[studentID] = behavior
 	}
 
 	// 然后处理所有行为，累加统计值
@@ -521,202 +529,73 @@ func (h *BehaviorHandler) GetClassBehaviorCategory(ctx context.Context, classroo
 				continue // 如果不在最新行为中，跳过
 			}
 
-			// 从Context中提取各项行为计数
-			var contextMap map[string]interface{}
-			if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-				// 提取提前学习次数、提问次数和连对次数（表扬类型）
-				earlyLearnCount := utils.GetMapInt64Key(contextMap, "early_learn_count")
-				if earlyLearnCount > 0 {
-					student.EarlyLearnCount += earlyLearnCount
-				}
-
-				questionCount := utils.GetMapInt64Key(contextMap, "question_count")
-				if questionCount > 0 {
-					student.QuestionCount += questionCount
-				}
-
-				correctStreak := utils.GetMapInt64Key(contextMap, "correct_streak")
-				if correctStreak > student.CorrectStreak {
-					student.CorrectStreak = correctStreak
-				}
-
-				// 提取频繁切换页面次数、学习其他内容次数和停顿操作次数（关注类型）
-				pageSwitchCount := utils.GetMapInt64Key(contextMap, "page_switch_count")
-				if pageSwitchCount > 0 {
-					student.PageSwitchCount += pageSwitchCount
-				}
-
-				otherContentCount := utils.GetMapInt64Key(contextMap, "other_content_count")
-				if otherContentCount > 0 {
-					student.OtherContentCount += otherContentCount
-				}
-
-				pauseCount := utils.GetMapInt64Key(contextMap, "pause_count")
-				if pauseCount > 0 {
-					student.PauseCount += pauseCount
-				}
+			// 从Context中提取各项行为计数，上下文已校验过次数不为负数
+			behaviorContext := h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context)
+			// 提前学习次数、提问次数和连对次数（表扬类型）
+			student.EarlyLearnCount += behaviorContext.EarlyLearnCount
+			student.QuestionCount += behaviorContext.QuestionCount
+			if behaviorContext.CorrectStreak > student.CorrectStreak {
+				student.CorrectStreak = behaviorContext.CorrectStreak
 			}
+			// 频繁切换页面次数、学习其他内容次数和停顿操作次数（关注类型）
+			student.PageSwitchCount += behaviorContext.PageSwitchCount
+			student.OtherContentCount += behaviorContext.OtherContentCount
+			student.PauseCount += behaviorContext.PauseCount
 		}
 	}
 
 	// 转换回切片
 	categories := make([]dto.StudentBehaviorCategoryDTO, 0, len(studentBehaviorsMap))
-	for _, student := range studentBehaviorsMap {
+	for studentID, student := range studentBehaviorsMap {
 		// 生成行为标签
-		generateBehaviorTags(student)
+		generateBehaviorTags(ruleSetOf(latestBehaviors[studentID]), student)
 		categories = append(categories, *student)
 	}
 
 	return categories, nil
 }
 
-// generateBehaviorTags 为学生生成行为标签
-func generateBehaviorTags(student *dto.StudentBehaviorCategoryDTO) {
-	student.BehaviorTags = []dto.BehaviorTag{}
-
-	// 课堂表现很棒的标签
-	if student.EarlyLearnCount > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "earlyLearn",
-			Count: student.EarlyLearnCount,
-			Text:  fmt.Sprintf("提前学%d次", student.EarlyLearnCount),
-		})
-	}
-
-	if student.QuestionCount > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "question",
-			Count: student.QuestionCount,
-			Text:  fmt.Sprintf("提问%d次", student.QuestionCount),
-		})
-	}
-
-	if student.CorrectStreak > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "correctStreak",
-			Count: student.CorrectStreak,
-			Text:  fmt.Sprintf("连对%d次", student.CorrectStreak),
-		})
-	}
-
-	// 需要关注的行为标签
-	if student.PageSwitchCount > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "pageSwitch",
-			Count: student.PageSwitchCount,
-			Text:  fmt.Sprintf("频繁切换页面 %d次", student.PageSwitchCount),
-		})
-	}
-
-	if student.OtherContentCount > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "otherContent",
-			Count: student.OtherContentCount,
-			Text:  fmt.Sprintf("学习其他内容 %d次", student.OtherContentCount),
-		})
-	}
+// generateBehaviorTags 按规则集为学生生成行为标签
+func generateBehaviorTags(ruleSet *dto.BehaviorRuleSet, student *dto.StudentBehaviorCategoryDTO) {
+	student.BehaviorTags = behaviorTags(ruleSet, student)
+}
 
-	if student.PauseCount > 0 {
-		student.BehaviorTags = append(student.BehaviorTags, dto.BehaviorTag{
-			Type:  "pause",
-			Count: student.PauseCount,
-			Text:  fmt.Sprintf("停顿操作 %d次", student.PauseCount),
-		})
+// behaviorRuleSet 学生行为适用的规则集，学科取自学生当前行为
+func (h *BehaviorHandler) behaviorRuleSet(schoolID int64, behavior *dto.StudentLatestBehaviorDTO) *dto.BehaviorRuleSet {
+	subject := ""
+	if behavior != nil {
+		subject, _ = newBehaviorRuleFacts(behavior)[consts.BehaviorRuleFieldSubject].(string)
 	}
+	return h.rules.Get(schoolID, subject)
 }
 
-// isPraiseWorthy 判断学生行为是否值得表扬
-func (h *BehaviorHandler) isPraiseWorthy(behavior *dto.StudentLatestBehaviorDTO) bool {
+// isPraiseWorthy 判断学生行为是否值得表扬，命中规则集中任一表扬条件即可
+func (h *BehaviorHandler) isPraiseWorthy(ruleSet *dto.BehaviorRuleSet, behavior *dto.StudentLatestBehaviorDTO) bool {
 	if behavior == nil {
 		return false
 	}
 
-	// 获取行为上下文
-	var contextMap dto.BehaviorContext
-	if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err != nil {
-		h.logger.Error(nil, "解析行为上下文失败", "error", err)
+	hits := newBehaviorRuleFacts(behavior).hits(ruleSet.PraiseChecks)
+	if len(hits) == 0 {
 		return false
 	}
-
-	// 获取相关指标
-	correctStreak := contextMap.CorrectStreak
-	earlyLearnCount := contextMap.EarlyLearnCount
-	questionCount := contextMap.QuestionCount
-	correctAnswers := contextMap.CorrectAnswers
-	totalQuestions := contextMap.TotalQuestions
-	learningType := contextMap.LearningType
-
-	// 1. 连续答对类型检查
-	if correctStreak >= consts.PraiseCheckCorrectStreak {
-		h.logger.Debug(context.Background(), "表扬检查：连续答对达标", "连续答对次数", correctStreak)
-		return true
-	}
-
-	// 2. 提前学习类型检查
-	if earlyLearnCount >= consts.PraiseCheckEarlyLearnCountRequired || learningType == string(consts.LearningTypeSelfStudy) {
-		h.logger.Debug(context.Background(), "表扬检查：提前学习达标", "提前学习次数", earlyLearnCount)
-		return true
-	}
-
-	// 3. 提问类型检查
-	if questionCount >= consts.PraiseCheckQuestionCountRequired {
-		h.logger.Debug(context.Background(), "表扬检查：提问达标", "提问次数", questionCount)
-		return true
-	}
-
-	// 4. 答题正确率检查
-	if correctAnswers >= consts.PraiseCheckMinCorrectAnswers &&
-		(totalQuestions == 0 || float64(correctAnswers)/float64(totalQuestions) >= consts.PraiseCheckCorrectRateThreshold) {
-		h.logger.Debug(context.Background(), "表扬检查：答题正确率达标",
-			"正确答题数", correctAnswers,
-			"总题数", totalQuestions)
-		return true
-	}
-
-	// 5. 学习时长检查
-	stayDuration := float64(behavior.StayDuration)
-	videoStatus := behavior.VideoStatus
-
-	if stayDuration >= float64(consts.PraiseCheckMinStayDurationSeconds) && videoStatus != "pause" {
-		h.logger.Debug(context.Background(), "表扬检查：学习时长达标",
-			"学习时长(秒)", stayDuration,
-			"视频状态", videoStatus)
-		return true
-	}
-
-	return false
+	h.logger.Debug(context.Background(), "表扬检查：学生 %d 命中规则 %s, 规则集版本: %d", behavior.StudentID, hits[0].Name, ruleSet.Version)
+	return true
 }
 
-// needsAttention 判断学生是否需要关注
-func (h *BehaviorHandler) needsAttention(behavior *dto.StudentLatestBehaviorDTO) bool {
+// needsAttention 判断学生是否需要关注，命中规则集中任一关注条件即需要关注
+func (h *BehaviorHandler) needsAttention(ruleSet *dto.BehaviorRuleSet, behavior *dto.StudentLatestBehaviorDTO) bool {
 	// 增加空指针检查
 	if behavior == nil {
 		return false
 	}
 
-	// 答题正确率低于50%
-	if behavior.BehaviorType == consts.BehaviorTypeAnswer {
-		if behavior.AccuracyRate < 50 && behavior.TotalQuestions >= 3 {
-			return true
-		}
-	}
-
-	// 视频暂停时间过长
-	if behavior.BehaviorType == consts.BehaviorTypeLearning && behavior.VideoStatus == "pause" {
-		// 从Context中直接获取暂停时长
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if stayDuration, ok := contextMap["stay_duration"].(float64); ok {
-				if stayDuration >= consts.AttentionRuleVideoPauseTimeSeconds { // 使用常量替代硬编码的300秒
-					return true
-				}
-			}
-		} else if behavior.StayDuration >= consts.AttentionRuleVideoPauseTimeSeconds { // 使用常量替代硬编码的300秒
-			return true
-		}
+	hits := newBehaviorRuleFacts(behavior).hits(ruleSet.AttentionChecks)
+	if len(hits) == 0 {
+		return false
 	}
-
-	return false
+	h.logger.Debug(context.Background(), "关注检查：学生 %d 命中规则 %s, 规则集版本: %d", behavior.StudentID, hits[0].Name, ruleSet.Version)
+	return true
 }
 
 // generatePraiseDesc 生成表扬描述
@@ -728,14 +607,8 @@ func (h *BehaviorHandler) generatePraiseDesc(behavior *dto.StudentLatestBehavior
 	if behavior.BehaviorType == consts.BehaviorTypeAnswer {
 		// 从Context中提取更详细的答题信息
 		var correctAnswers int64 = behavior.CorrectAnswers
-
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if questionsInfo, ok := contextMap["questions_info"].(map[string]interface{}); ok {
-				if correct, ok := questionsInfo["correct_answers"].(float64); ok {
-					correctAnswers = int64(correct)
-				}
-			}
+		if questionsInfo := h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context).QuestionsInfo; questionsInfo != nil {
+			correctAnswers = questionsInfo.CorrectAnswers
 		}
 
 		// 连对题目描述
@@ -746,12 +619,8 @@ func (h *BehaviorHandler) generatePraiseDesc(behavior *dto.StudentLatestBehavior
 	if behavior.BehaviorType == consts.BehaviorTypeLearning {
 		// 从Context中提取更详细的学习信息
 		var stayDuration int64 = behavior.StayDuration
-
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if duration, ok := contextMap["stay_duration"].(float64); ok {
-				stayDuration = int64(duration)
-			}
+		if duration := h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context).StayDuration; duration > 0 {
+			stayDuration = duration
 		}
 
 		minutes := stayDuration / 60
@@ -764,11 +633,8 @@ func (h *BehaviorHandler) generatePraiseDesc(behavior *dto.StudentLatestBehavior
 
 	if behavior.BehaviorType == consts.BehaviorTypeQuestion {
 		// 从Context中提取提问内容
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if _, ok := contextMap["question_content"].(string); ok {
-				return consts.BehaviorDescActiveQuestioningSimple
-			}
+		if h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context).QuestionContent != "" {
+			return consts.BehaviorDescActiveQuestioningSimple
 		}
 
 		return "积极提问"
@@ -790,20 +656,13 @@ func (h *BehaviorHandler) generateAttentionDesc(behavior *dto.StudentLatestBehav
 		var totalQuestions int64 = behavior.TotalQuestions
 		var correctAnswers int64 = behavior.CorrectAnswers
 
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			// 从Context中更新这些信息（如果有的话）
-			if questionsInfo, ok := contextMap["questions"].(map[string]interface{}); ok {
-				if correct, ok := questionsInfo["correct_answers"].(float64); ok {
-					correctAnswers = int64(correct)
-				}
-				if total, ok := questionsInfo["total_questions"].(float64); ok {
-					totalQuestions = int64(total)
-				}
+		// 从Context中更新这些信息（如果有的话）
+		if questionsInfo := h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context).QuestionsInfo; questionsInfo != nil {
+			correctAnswers = questionsInfo.CorrectAnswers
+			totalQuestions = questionsInfo.TotalQuestions
 
-				// 重新计算正确率
-				accuracyRate = utils.F64Percent(float64(correctAnswers), float64(totalQuestions), 2)
-			}
+			// 重新计算正确率
+			accuracyRate = utils.F64Percent(float64(correctAnswers), float64(totalQuestions), 2)
 		}
 
 		if totalQuestions > 0 {
@@ -817,12 +676,8 @@ func (h *BehaviorHandler) generateAttentionDesc(behavior *dto.StudentLatestBehav
 	if behavior.BehaviorType == consts.BehaviorTypeLearning && behavior.VideoStatus == "pause" {
 		// 从Context中提取更详细的学习信息
 		var stayDuration int64 = behavior.StayDuration
-
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if duration, ok := contextMap["stay_duration"].(float64); ok {
-				stayDuration = int64(duration)
-			}
+		if duration := h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context).StayDuration; duration > 0 {
+			stayDuration = duration
 		}
 
 		minutes := stayDuration / 60
@@ -835,29 +690,27 @@ func (h *BehaviorHandler) generateAttentionDesc(behavior *dto.StudentLatestBehav
 	}
 
 	// 检查其他问题
-	var contextMap map[string]interface{}
-	if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-		var problems []string
+	behaviorContext := h.studentBehaviorContext(context.Background(), behavior.BehaviorType, behavior.Context)
+	var problems []string
 
-		// 检查频繁切换页面
-		if pageSwitchCount := utils.GetMapValueI64(contextMap, "page_switch_count", 0); pageSwitchCount > 0 {
-			problems = append(problems, consts.FormatMessage(consts.ProblemTplFrequentPageSwitch, pageSwitchCount))
-		}
+	// 检查频繁切换页面
+	if behaviorContext.PageSwitchCount > 0 {
+		problems = append(problems, consts.FormatMessage(consts.ProblemTplFrequentPageSwitch, behaviorContext.PageSwitchCount))
+	}
 
-		// 检查学习其它内容
-		if otherContentCount := utils.GetMapValueI64(contextMap, "other_content_count", 0); otherContentCount > 0 {
-			problems = append(problems, consts.FormatMessage(consts.ProblemTplOtherContent, otherContentCount))
-		}
+	// 检查学习其它内容
+	if behaviorContext.OtherContentCount > 0 {
+		problems = append(problems, consts.FormatMessage(consts.ProblemTplOtherContent, behaviorContext.OtherContentCount))
+	}
 
-		// 检查停顿操作
-		if pauseCount := utils.GetMapValueI64(contextMap, "pause_count", 0); pauseCount > 0 {
-			problems = append(problems, consts.FormatMessage(consts.ProblemTplPauseOperation, pauseCount))
-		}
+	// 检查停顿操作
+	if behaviorContext.PauseCount > 0 {
+		problems = append(problems, consts.FormatMessage(consts.ProblemTplPauseOperation, behaviorContext.PauseCount))
+	}
 
-		// 生成提醒消息
-		if len(problems) > 0 {
-			return strings.Join(problems, "，")
-		}
+	// 生成提醒消息
+	if len(problems) > 0 {
+		return strings.Join(problems, "，")
 	}
 
 	return consts.BehaviorDescNeedAttention
@@ -923,16 +776,8 @@ func (h *BehaviorHandler) PraiseStudents(ctx context.Context, req *api.PraiseStu
 
 		// 从行为数据中获取学生信息
 		if behavior, ok := studentBehaviors[studentID]; ok && behavior != nil {
-			// 尝试从Context中提取学生姓名
-			var contextMap map[string]interface{}
-			if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-				if name, ok := contextMap["student_name"].(string); ok {
-					studentName = name
-				}
-			} else {
-				// 记录错误但不中断流程
-				h.logger.Error(ctx, "解析学生%d行为上下文失败: %v, context=%s", studentID, err, behavior.Context)
-			}
+			// 尝试从Context中提取学生姓名，解析失败时记录日志但不中断流程
+			studentName = h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context).StudentName
 		}
 
 		// 获取该学生剩余可用的表扬类型和已表扬的类型数量
@@ -988,9 +833,10 @@ func (h *BehaviorHandler) PraiseStudents(ctx context.Context, req *api.PraiseStu
 
 		// 检查该学生是否满足表扬条件
 		behavior, hasBehavior := studentBehaviors[studentID]
+		ruleSet := h.behaviorRuleSet(req.SchoolID, behavior)
 		isWorthy := false
 		if hasBehavior && behavior != nil {
-			isWorthy = h.isPraiseWorthy(behavior)
+			isWorthy = h.isPraiseWorthy(ruleSet, behavior)
 			h.logger.Debug(ctx, "学生 %d 表扬条件检查结果: %v", studentID, isWorthy)
 		}
 
@@ -1023,7 +869,7 @@ func (h *BehaviorHandler) PraiseStudents(ctx context.Context, req *api.PraiseStu
 
 		// 自动选择一个表扬类型
 		// 首先检查学生行为，尝试选择最适合的类型
-		selectedType := h.selectBestPraiseType(studentID, studentBehaviors, availableTypes)
+		selectedType := h.selectBestPraiseType(ruleSet, studentID, studentBehaviors, availableTypes)
 		// 为每个学生单独记录选择的类型
 		selectedTypes[studentID] = selectedType
 
@@ -1119,8 +965,8 @@ func (h *BehaviorHandler) PraiseStudents(ctx context.Context, req *api.PraiseStu
 	return response, nil
 }
 
-// selectBestPraiseType 根据学生行为自动选择最佳表扬类型
-func (h *BehaviorHandler) selectBestPraiseType(studentID uint64, behaviors map[uint64]*dto.StudentLatestBehaviorDTO, availableTypes []string) string {
+// selectBestPraiseType 按规则集为学生的可用表扬类型评分，选择得分最高的类型
+func (h *BehaviorHandler) selectBestPraiseType(ruleSet *dto.BehaviorRuleSet, studentID uint64, behaviors map[uint64]*dto.StudentLatestBehaviorDTO, availableTypes []string) string {
 	// 如果没有可用类型，返回默认值
 	if len(availableTypes) == 0 {
 		h.logger.Debug(context.Background(), "学生 %d 没有可用表扬类型，返回默认类型", studentID)
@@ -1134,130 +980,14 @@ func (h *BehaviorHandler) selectBestPraiseType(studentID uint64, behaviors map[u
 		return availableTypes[0]
 	}
 
-	// 解析学生行为上下文
-	var contextMap map[string]interface{}
-	if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err != nil {
-		h.logger.Debug(context.Background(), "学生 %d 行为上下文解析失败: %v，选择第一个可用类型: %s",
-			studentID, err, availableTypes[0])
-		return availableTypes[0]
-	}
-
-	h.logger.Debug(context.Background(), "学生 %d 行为数据: 类型=%s, 上下文=%+v, 可用表扬类型=%v",
-		studentID, behavior.BehaviorType, contextMap, availableTypes)
-
-	// 各类型的匹配分数
-	typeScores := make(map[string]int)
-	for _, t := range availableTypes {
-		typeScores[t] = consts.PraiseTypeInitialBaseScore // 初始化基础得分为5，避免零分状态
-	}
-
-	// 从上下文中提取所有可能的指标
-	correctStreak := int(utils.GetMapFloat64Key(contextMap, "correct_streak"))
-	earlyLearnCount := int(utils.GetMapFloat64Key(contextMap, "early_learn_count"))
-	questionCount := int(utils.GetMapFloat64Key(contextMap, "question_count"))
-	totalQuestions := int(utils.GetMapFloat64Key(contextMap, "total_questions"))
-	correctAnswers := int(utils.GetMapFloat64Key(contextMap, "correct_answers"))
-	stayDuration := int(behavior.StayDuration)
-	learningType := utils.GetMapStringKey(contextMap, "learning_type")
-
-	// 1. 评估连续答对类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypeCorrectStreak)) {
-		score := typeScores[string(consts.BehaviorTagTypeCorrectStreak)]
-
-		// 连对越多分数越高
-		if correctStreak > 0 {
-			score += correctStreak * consts.PraiseCorrectStreakScoreWeight // 提高连对的权重
-		}
-
-		// 答题正确率高也加分
-		if totalQuestions > 0 && correctAnswers > 0 {
-			accuracyRate := float64(correctAnswers) / float64(totalQuestions) * 100
-			if accuracyRate >= consts.PraiseAccuracyRateThresholdForBonus {
-				score += consts.PraiseAccuracyRateBonusScore // 正确率高于80%加10分
-			}
-		}
-
-		// 行为类型是答题也加分
-		if behavior.BehaviorType == consts.BehaviorTypeAnswer {
-			score += consts.PraiseAnswerTypeBonusScore
-		}
-
-		typeScores[string(consts.BehaviorTagTypeCorrectStreak)] = score
-	}
-
-	// 2. 评估主动提问类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypeQuestion)) {
-		score := typeScores[string(consts.BehaviorTagTypeQuestion)]
-
-		// 提问次数加高分
-		if questionCount > 0 {
-			score += questionCount * consts.PraiseQuestionCountScoreWeight // 提高提问的权重
-		}
-
-		// 行为类型是提问也加高分
-		if behavior.BehaviorType == consts.BehaviorTypeQuestion {
-			score += consts.PraiseQuestionTypeBonusScore
-		}
-
-		typeScores[string(consts.BehaviorTagTypeQuestion)] = score
-	}
-
-	// 3. 评估提前学习类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypeEarlyLearn)) {
-		score := typeScores[string(consts.BehaviorTagTypeEarlyLearn)]
-
-		// 提前学习次数加高分
-		if earlyLearnCount > 0 {
-			score += earlyLearnCount * consts.PraiseEarlyLearnCountScoreWeight
-		}
-
-		// 学习时间较长加分
-		if stayDuration > consts.PraiseStayDurationThresholdMinutesForBonus*60 { // 超过15分钟
-			score += int(stayDuration/60/consts.PraiseStayDurationBonusIntervalMinutes)*consts.PraiseStayDurationBonusScorePerInterval + consts.PraiseLongStayDurationBaseBonusScore // 每5分钟加1分，基础加10分
-		}
-
-		// 学习类型是自学加高分
-		if learningType == consts.LearningTypeSelfStudy {
-			score += consts.PraiseSelfStudyLearningTypeBonusScore
-		}
-
-		// 行为类型是学习也加分
-		if behavior.BehaviorType == consts.BehaviorTypeLearning {
-			score += consts.PraiseLearningBehaviorTypeBonusScore
-		}
-
-		typeScores[string(consts.BehaviorTagTypeEarlyLearn)] = score
-	}
-
-	// 打印每种类型的得分
+	typeScores := newBehaviorRuleFacts(behavior).scoreTypes(ruleSet.PraiseTypes, availableTypes)
 	for t, score := range typeScores {
 		h.logger.Debug(context.Background(), "学生 %d 表扬类型评分: 类型=%s, 得分=%d", studentID, t, score)
 	}
 
-	// 找出得分最高的类型
-	var bestType string
-	bestScore := -1
-
-	// 随机打乱顺序，避免相同分数时总是选择同一个类型
-	keys := make([]string, 0, len(typeScores))
-	for t := range typeScores {
-		keys = append(keys, t)
-	}
-	for _, t := range keys {
-		score := typeScores[t]
-		if score > bestScore {
-			bestScore = score
-			bestType = t
-		}
-	}
-
-	// 如果没有找到明显最佳类型，返回第一个可用类型
-	if bestScore <= consts.PraiseBestTypeMinScoreThreshold { // 只有初始得分
-		h.logger.Debug(context.Background(), "学生 %d 没有找到明显最佳类型，选择第一个可用类型: %s", studentID, availableTypes[0])
-		return availableTypes[0]
-	}
-
-	h.logger.Debug(context.Background(), "学生 %d 选择最佳表扬类型: %s, 得分=%d", studentID, bestType, bestScore)
+	// 没有找到明显最佳类型时返回第一个可用类型
+	bestType, bestScore := bestRuleType(typeScores, availableTypes, ruleSet.PraiseMinScore)
+	h.logger.Debug(context.Background(), "学生 %d 选择最佳表扬类型: %s, 得分=%d, 规则集版本=%d", studentID, bestType, bestScore, ruleSet.Version)
 	return bestType
 }
 
@@ -1280,6 +1010,10 @@ func (h *BehaviorHandler) sendPraiseNotifications(ctx context.Context, req *api.
 	results map[uint64]*api.StudentHandleResult, behaviors map[uint64]*dto.StudentLatestBehaviorDTO,
 	selectedTypes map[uint64]string) {
 
+	// 推送给学生端和教师的其他屏幕
+	pushMessages := make([]*dto.PushMessage, 0, len(results)+1)
+	praisedIDs := make([]uint64, 0, len(results))
+
 	// 构建消息队列请求
 	for studentID, result := range results {
 		if !result.Success {
@@ -1297,13 +1031,14 @@ func (h *BehaviorHandler) sendPraiseNotifications(ctx context.Context, req *api.
 		behavior := behaviors[studentID]
 		if behavior != nil {
 			// 解析行为上下文
-			var contextMap map[string]interface{}
-			if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
+			if behaviorContext, err := decodeStudentBehaviorContext(behavior.BehaviorType, behavior.Context); err != nil {
+				h.logger.Warn(ctx, "解析学生%d行为上下文失败: %v", studentID, err)
+			} else {
 				// 根据表扬类型生成特定消息
 				switch selectedType {
 				case string(consts.BehaviorTagTypeCorrectStreak):
 					// 连续答对表扬
-					correctStreak := int(utils.GetMapFloat64Key(contextMap, "correct_streak"))
+					correctStreak := behaviorContext.CorrectStreak
 					if correctStreak >= consts.PraiseCheckCorrectStreak { // 使用配置项
 						praiseMsg = fmt.Sprintf("太棒了！你已经连续答对 %d 题了！", correctStreak)
 					} else {
@@ -1312,7 +1047,7 @@ func (h *BehaviorHandler) sendPraiseNotifications(ctx context.Context, req *api.
 
 				case string(consts.BehaviorTagTypeEarlyLearn):
 					// 提前学习表扬
-					earlyLearnCount := int(utils.GetMapFloat64Key(contextMap, "early_learn_count"))
+					earlyLearnCount := behaviorContext.EarlyLearnCount
 					if earlyLearnCount > 0 {
 						praiseMsg = fmt.Sprintf(consts.BehaviorDescPraiseEarlyLearnSpecific, earlyLearnCount)
 					} else {
@@ -1321,7 +1056,7 @@ func (h *BehaviorHandler) sendPraiseNotifications(ctx context.Context, req *api.
 
 				case string(consts.BehaviorTagTypeQuestion):
 					// 提问表扬
-					questionCount := int(utils.GetMapFloat64Key(contextMap, "question_count"))
+					questionCount := behaviorContext.QuestionCount
 					if questionCount > 0 {
 						praiseMsg = consts.BehaviorDescPraiseQuestionSpecific
 					} else {
@@ -1382,7 +1117,15 @@ func (h *BehaviorHandler) sendPraiseNotifications(ctx context.Context, req *api.
 		} else {
 			h.logger.Debug(ctx, "成功保存学生 %d 的表扬行为记录", studentID)
 		}
+
+		pushMessages = append(pushMessages, studentPushMessage(studentID, int64(req.ClassroomID), consts.PushMessageTypePraise, praiseMsg, contextBytes))
+		praisedIDs = append(praisedIDs, studentID)
 	}
+
+	if len(praisedIDs) > 0 {
+		pushMessages = append(pushMessages, teacherHandledPushMessage(req.TeacherID, int64(req.ClassroomID), consts.BehaviorTypePraise, praisedIDs))
+	}
+	h.publishPushMessages(ctx, pushMessages)
 }
 
 // AttentionStudents 关注学生（单个或批量）
@@ -1420,19 +1163,17 @@ func (h *BehaviorHandler) AttentionStudents(ctx context.Context, req *api.Attent
 		behaviorDTO := &dto.StudentLatestBehaviorDTO{
 			StudentID:      int64(behavior.StudentID),
 			BehaviorType:   consts.BehaviorType(behavior.BehaviorType),
+			Subject:        behavior.Subject,
+			VideoStatus:    behavior.VideoStatus,
+			StayDuration:   behavior.StayDuration,
 			Context:        behavior.Context,
 			LastUpdateTime: time.Now().Unix(), // 使用当前时间代替CreateTime
 		}
 
 		// 解析上下文数据
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			// 只在成功解析JSON时提取字段
-			behaviorDTO.StudentName = utils.GetMapStringKey(contextMap, "student_name")
-			behaviorDTO.AvatarURL = utils.GetMapStringKey(contextMap, "avatar_url")
-		} else {
-			h.logger.Error(ctx, "解析行为上下文失败: %v, context: %s", err, behavior.Context)
-		}
+		behaviorContext := h.studentBehaviorContext(ctx, behaviorDTO.BehaviorType, behavior.Context)
+		behaviorDTO.StudentName = behaviorContext.StudentName
+		behaviorDTO.AvatarURL = behaviorContext.AvatarURL
 
 		studentBehaviors[uint64(behaviorDTO.StudentID)] = behaviorDTO
 	}
@@ -1473,7 +1214,7 @@ func (h *BehaviorHandler) AttentionStudents(ctx context.Context, req *api.Attent
 		}
 
 		// 选择最合适的关注类型（仅用于消息生成，不限制多次提醒）
-		selectedType := h.selectBestAttentionType(ctx, studentID, studentBehaviors, allTypes)
+		selectedType := h.selectBestAttentionType(ctx, h.behaviorRuleSet(req.SchoolID, behavior), studentID, studentBehaviors, allTypes)
 		selectedTypes[studentID] = selectedType
 
 		// 获取提醒次数
@@ -1484,9 +1225,9 @@ func (h *BehaviorHandler) AttentionStudents(ctx context.Context, req *api.Attent
 		if ok && behavior != nil {
 			// 从类型选择生成对应的提醒消息
 			attentionDesc := h.generateAttentionDescForType(behavior, selectedType)
-			result.Message = consts.FormatMessage(consts.MsgTplKeepFocusedWithProblems, attentionDesc, strconv.FormatInt(reminderCount, 10))
+			result.Message = consts.FormatMessage(consts.MsgTplKeepFocusedWithProblems, attentionDesc, reminderCount)
 		} else {
-			result.Message = consts.FormatMessage(consts.MsgTplKeepFocused, strconv.FormatInt(reminderCount, 10))
+			result.Message = consts.FormatMessage(consts.MsgTplKeepFocused, reminderCount)
 		}
 
 		// 设置处理结果
@@ -1507,8 +1248,8 @@ func (h *BehaviorHandler) AttentionStudents(ctx context.Context, req *api.Attent
 	return response, nil
 }
 
-// selectBestAttentionType 根据学生行为自动选择最佳关注类型
-func (h *BehaviorHandler) selectBestAttentionType(ctx context.Context, studentID uint64, behaviors map[uint64]*dto.StudentLatestBehaviorDTO, availableTypes []string) string {
+// selectBestAttentionType 按规则集为学生的关注类型评分，选择得分最高的类型
+func (h *BehaviorHandler) selectBestAttentionType(ctx context.Context, ruleSet *dto.BehaviorRuleSet, studentID uint64, behaviors map[uint64]*dto.StudentLatestBehaviorDTO, availableTypes []string) string {
 	// 如果没有可用类型，返回默认值
 	if len(availableTypes) == 0 {
 		h.logger.Debug(ctx, "学生 %d 没有可用关注类型，返回默认类型", studentID)
@@ -1522,100 +1263,14 @@ func (h *BehaviorHandler) selectBestAttentionType(ctx context.Context, studentID
 		return availableTypes[0]
 	}
 
-	// 解析学生行为上下文
-	var contextMap map[string]interface{}
-	if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err != nil {
-		h.logger.Debug(ctx, "学生 %d 行为上下文解析失败: %v，选择第一个可用类型: %s",
-			studentID, err, availableTypes[0])
-		return availableTypes[0]
-	}
-
-	h.logger.Debug(ctx, "学生 %d 行为数据: 类型=%s, 上下文=%+v, 可用关注类型=%v",
-		studentID, behavior.BehaviorType, contextMap, availableTypes)
-
-	// 各类型的匹配分数
-	typeScores := make(map[string]int)
-	for _, t := range availableTypes {
-		typeScores[t] = 0 // 初始化得分
-	}
-
-	// 1. 评估频繁切换页面类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypePageSwitch)) {
-		score := 0
-
-		// 如果有页面切换记录，加分
-		if pageSwitchCount, ok := contextMap["page_switch_count"].(float64); ok && pageSwitchCount > 0 {
-			score += consts.AttentionRulePageSwitchBaseScore
-			// 切换次数越多分数越高
-			score += int(pageSwitchCount) * consts.AttentionRulePageSwitchScorePerCount
-		}
-
-		typeScores[string(consts.BehaviorTagTypePageSwitch)] = score
-	}
-
-	// 2. 评估学习其他内容类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypeOtherContent)) {
-		score := 0
-
-		// 如果有学习其他内容记录，加分
-		if otherContentCount, ok := contextMap["other_content_count"].(float64); ok && otherContentCount > 0 {
-			score += consts.AttentionRuleOtherContentBaseScore
-			// 次数越多分数越高
-			score += int(otherContentCount) * consts.AttentionRuleOtherContentScorePerCount
-		}
-
-		typeScores[string(consts.BehaviorTagTypeOtherContent)] = score
-	}
-
-	// 3. 评估停顿操作类型
-	if slices.Contains(availableTypes, string(consts.BehaviorTagTypePause)) {
-		score := 0
-
-		// 如果是学习行为且视频处于暂停状态，加高分
-		if behavior.BehaviorType == consts.BehaviorTypeLearning && behavior.VideoStatus == "pause" {
-			score += consts.AttentionRuleVideoPauseLearningScore
-		}
-
-		// 如果有暂停记录，加分
-		if pauseCount, ok := contextMap["pause_count"].(float64); ok && pauseCount > 0 {
-			score += consts.AttentionRulePauseCountBaseScore
-			// 暂停次数越多分数越高
-			score += int(pauseCount) * consts.AttentionRulePauseCountScorePerCount
-		}
-
-		// 如果有停留时间记录且较长，加分
-		if stayDuration, ok := contextMap["stay_duration"].(float64); ok && stayDuration > consts.AttentionRuleVideoPauseTimeSeconds {
-			score += consts.AttentionRuleBaseScoreForLongPause
-			// 停留时间越长分数越高
-			score += int(stayDuration/60) * consts.AttentionRuleScorePerMinutePaused
-		}
-
-		typeScores[string(consts.BehaviorTagTypePause)] = score
-	}
-
-	// 打印每种类型的得分
+	typeScores := newBehaviorRuleFacts(behavior).scoreTypes(ruleSet.AttentionTypes, availableTypes)
 	for t, score := range typeScores {
 		h.logger.Debug(ctx, "学生 %d 关注类型评分: 类型=%s, 得分=%d", studentID, t, score)
 	}
 
-	// 找出得分最高的类型
-	var bestType string
-	bestScore := -1
-
-	for t, score := range typeScores {
-		if score > bestScore {
-			bestScore = score
-			bestType = t
-		}
-	}
-
-	// 如果没有找到明显最佳类型，返回第一个可用类型
-	if bestScore <= 0 {
-		h.logger.Debug(ctx, "学生 %d 没有找到明显最佳关注类型，选择第一个可用类型: %s", studentID, availableTypes[0])
-		return availableTypes[0]
-	}
-
-	h.logger.Debug(ctx, "学生 %d 选择最佳关注类型: %s, 得分=%d", studentID, bestType, bestScore)
+	// 没有找到明显最佳类型时返回第一个可用类型
+	bestType, bestScore := bestRuleType(typeScores, availableTypes, ruleSet.AttentionMinScore)
+	h.logger.Debug(ctx, "学生 %d 选择最佳关注类型: %s, 得分=%d, 规则集版本=%d", studentID, bestType, bestScore, ruleSet.Version)
 	return bestType
 }
 
@@ -1625,36 +1280,33 @@ func (h *BehaviorHandler) generateAttentionDescForType(behavior *dto.StudentLate
 		return consts.BehaviorDescNeedAttention
 	}
 
-	var contextMap map[string]interface{}
-	if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err != nil {
+	behaviorContext, err := decodeStudentBehaviorContext(behavior.BehaviorType, behavior.Context)
+	if err != nil {
 		return consts.BehaviorDescNeedAttention
 	}
 
 	switch attentionType {
 	case string(consts.BehaviorTagTypePageSwitch):
-		if pageSwitchCount, ok := contextMap["page_switch_count"].(float64); ok && pageSwitchCount > 0 {
-			return fmt.Sprintf("频繁切换页面 %d 次，请保持专注", int(pageSwitchCount))
+		if behaviorContext.PageSwitchCount > 0 {
+			return fmt.Sprintf("频繁切换页面 %d 次，请保持专注", behaviorContext.PageSwitchCount)
 		}
 		return consts.AttentionDescFrequentSwitchFocus
 
 	case string(consts.BehaviorTagTypeOtherContent):
-		if otherContentCount, ok := contextMap["other_content_count"].(float64); ok && otherContentCount > 0 {
-			return fmt.Sprintf("浏览其他内容 %d 次，请回到学习页面", int(otherContentCount))
+		if behaviorContext.OtherContentCount > 0 {
+			return fmt.Sprintf("浏览其他内容 %d 次，请回到学习页面", behaviorContext.OtherContentCount)
 		}
 		return consts.AttentionDescIrrelevantContentFocus
 
 	case string(consts.BehaviorTagTypePause):
 		if behavior.BehaviorType == consts.BehaviorTypeLearning && behavior.VideoStatus == "pause" {
-			if stayDuration, ok := contextMap["stay_duration"].(float64); ok && stayDuration > 0 {
-				minutes := int(stayDuration) / 60
-				if minutes > 0 {
-					return fmt.Sprintf("视频已暂停 %d 分钟，需要帮助吗？", minutes)
-				}
+			if minutes := behaviorContext.StayDuration / 60; minutes > 0 {
+				return fmt.Sprintf("视频已暂停 %d 分钟，需要帮助吗？", minutes)
 			}
 			return consts.AttentionDescVideoPausedNeedHelp
 		}
-		if pauseCount, ok := contextMap["pause_count"].(float64); ok && pauseCount > 0 {
-			return fmt.Sprintf("多次暂停操作 %d 次，请专注学习", int(pauseCount))
+		if behaviorContext.PauseCount > 0 {
+			return fmt.Sprintf("多次暂停操作 %d 次，请专注学习", behaviorContext.PauseCount)
 		}
 		return consts.AttentionDescFrequentPausesFocus
 	}
@@ -1681,6 +1333,10 @@ func (h *BehaviorHandler) sendAttentionNotifications(ctx context.Context, req *a
 	results map[uint64]*api.StudentHandleResult, behaviors map[uint64]*dto.StudentLatestBehaviorDTO,
 	selectedTypes map[uint64]string) {
 
+	// 推送给学生端和教师的其他屏幕
+	pushMessages := make([]*dto.PushMessage, 0, len(results)+1)
+	attentionIDs := make([]uint64, 0, len(results))
+
 	// 构建消息队列请求
 	for studentID, result := range results {
 		if !result.Success {
@@ -1726,18 +1382,27 @@ func (h *BehaviorHandler) sendAttentionNotifications(ctx context.Context, req *a
 		if err != nil {
 			h.logger.Error(ctx, "保存关注行为失败: %v", err)
 		}
+
+		pushMessages = append(pushMessages, studentPushMessage(studentID, int64(req.ClassroomID), consts.PushMessageTypeAttention, result.Message, contextBytes))
+		attentionIDs = append(attentionIDs, studentID)
 	}
+
+	if len(attentionIDs) > 0 {
+		pushMessages = append(pushMessages, teacherHandledPushMessage(req.TeacherID, int64(req.ClassroomID), consts.BehaviorTypeAttention, attentionIDs))
+	}
+	h.publishPushMessages(ctx, pushMessages)
 }
 
-// GetClassroomLearningScores 获取单节课程的学习分列表
-func (h *BehaviorHandler) GetClassroomLearningScores(ctx context.Context, classroomID uint64) ([]*dto.StudentLearningScoreDTO, error) {
+// GetClassroomLearningScores 获取单节课程的学习分列表，学习分按学校和学生当前学科的学习分模型计算
+// startTime、endTime 为零值时统计课堂的全部行为，生成课后报告时只统计本节课上课期间的行为
+func (h *BehaviorHandler) GetClassroomLearningScores(ctx context.Context, schoolID int64, classroomID uint64, startTime, endTime time.Time) ([]*dto.StudentLearningScoreDTO, error) {
 	// 参数校验
 	if classroomID == 0 {
 		return nil, errors.New("课堂ID不能为0")
 	}
 
 	// 获取学生行为数据
-	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, classroomID)
+	behaviors, err := h.behaviorDAO.GetClassLatestBehaviors(ctx, classroomID, startTime, endTime)
 	if err != nil {
 		h.logger.Error(ctx, "获取课堂学习分列表失败: %v", err)
 		return nil, errors.Wrap(err, "获取课堂学习分列表失败")
@@ -1760,23 +1425,13 @@ func (h *BehaviorHandler) GetClassroomLearningScores(ctx context.Context, classr
 			continue
 		}
 
-		// 计算学习分数：学习时间占30%，答题正确率占70%
-		timeScore := utils.F64Min(float64(behavior.StayDuration)/60, 30)
-		accuracyScore := utils.F64Percent(float64(behavior.CorrectAnswers), float64(behavior.TotalQuestions), 2) * 70
-		totalScore := int64(timeScore + accuracyScore)
+		// 计算学习分数及明细
+		totalScore, scoreItems := newBehaviorRuleFacts(behavior).learningScore(h.behaviorRuleSet(schoolID, behavior).LearningScore)
 
 		// 获取头像URL和学生姓名
-		studentName := ""
-		avatarURL := ""
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-			if name, ok := contextMap["student_name"].(string); ok {
-				studentName = name
-			}
-			if avatar, ok := contextMap["avatar_url"].(string); ok {
-				avatarURL = avatar
-			}
-		}
+		behaviorContext := h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context)
+		studentName := behaviorContext.StudentName
+		avatarURL := behaviorContext.AvatarURL
 
 		results = append(results, &dto.StudentLearningScoreDTO{
 			StudentID:     uint64(behavior.StudentID),
@@ -1786,6 +1441,7 @@ func (h *BehaviorHandler) GetClassroomLearningScores(ctx context.Context, classr
 			LearningTime:  uint64(behavior.StayDuration),
 			CorrectCount:  uint64(behavior.CorrectAnswers),
 			TotalCount:    uint64(behavior.TotalQuestions),
+			ScoreItems:    scoreItems,
 		})
 	}
 
@@ -1798,15 +1454,15 @@ func (h *BehaviorHandler) GetClassroomLearningScores(ctx context.Context, classr
 	return results, nil
 }
 
-// GetClassroomBehaviorSummary 获取课后行为汇总统计
-func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, classroomID uint64) (*api.ClassroomBehaviorSummaryResponse, error) {
+// GetClassroomBehaviorSummary 获取课后行为汇总统计，startTime、endTime 为零值时统计课堂的全部行为
+func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, schoolID int64, classroomID uint64, startTime, endTime time.Time) (*api.ClassroomBehaviorSummaryResponse, error) {
 	// 参数校验
 	if classroomID == 0 {
 		return nil, errors.New("课堂ID不能为0")
 	}
 
 	// 获取课堂学生行为数据
-	behaviors, err := h.behaviorDAO.GetClassAllBehaviors(ctx, classroomID)
+	behaviors, err := h.behaviorDAO.GetClassAllBehaviors(ctx, classroomID, startTime, endTime)
 	if err != nil {
 		h.logger.Error(ctx, "获取课堂学生行为数据失败: %v", err)
 		return nil, errors.Wrap(err, "获取课堂学生行为数据失败")
@@ -1871,12 +1527,10 @@ func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, class
 		studentCategory.LastUpdateTime = latestBehavior.LastUpdateTime
 
 		// 从Context中提取学生信息
-		var contextMap map[string]interface{}
-		if err := json.Unmarshal([]byte(latestBehavior.Context), &contextMap); err == nil {
-			studentCategory.StudentName = utils.GetMapStringKey(contextMap, "student_name")
-			studentCategory.AvatarUrl = utils.GetMapStringKey(contextMap, "avatar_url")
-			studentCategory.LearningProgress = utils.GetMapFloat64Key(contextMap, "learning_progress")
-		}
+		latestContext := h.studentBehaviorContext(ctx, latestBehavior.BehaviorType, latestBehavior.Context)
+		studentCategory.StudentName = latestContext.StudentName
+		studentCategory.AvatarUrl = latestContext.AvatarURL
+		studentCategory.LearningProgress = latestContext.LearningProgress
 
 		// 统计各类行为次数
 		var totalQuestions int64
@@ -1900,20 +1554,16 @@ func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, class
 			stayDuration += behavior.StayDuration
 
 			// 提取特殊行为计数
-			var contextMap map[string]interface{}
-			if err := json.Unmarshal([]byte(behavior.Context), &contextMap); err == nil {
-				// 累加行为计数
-				studentCategory.EarlyLearnCount += int64(utils.GetMapIntKey(contextMap, "early_learn_count", 0))
-				studentCategory.QuestionCount += int64(utils.GetMapIntKey(contextMap, "question_count", 0))
-				studentCategory.PageSwitchCount += int64(utils.GetMapIntKey(contextMap, "page_switch_count", 0))
-				studentCategory.OtherContentCount += int64(utils.GetMapIntKey(contextMap, "other_content_count", 0))
-				studentCategory.PauseCount += int64(utils.GetMapIntKey(contextMap, "pause_count", 0))
-
-				// 对于连对次数，取最大值
-				correctStreak := int64(utils.GetMapIntKey(contextMap, "correct_streak", 0))
-				if correctStreak > studentCategory.CorrectStreak {
-					studentCategory.CorrectStreak = correctStreak
-				}
+			behaviorContext := h.studentBehaviorContext(ctx, behavior.BehaviorType, behavior.Context)
+			studentCategory.EarlyLearnCount += behaviorContext.EarlyLearnCount
+			studentCategory.QuestionCount += behaviorContext.QuestionCount
+			studentCategory.PageSwitchCount += behaviorContext.PageSwitchCount
+			studentCategory.OtherContentCount += behaviorContext.OtherContentCount
+			studentCategory.PauseCount += behaviorContext.PauseCount
+
+			// 对于连对次数，取最大值
+			if behaviorContext.CorrectStreak > studentCategory.CorrectStreak {
+				studentCategory.CorrectStreak = behaviorContext.CorrectStreak
 			}
 		}
 
@@ -1930,7 +1580,7 @@ func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, class
 		}
 
 		// 生成行为标签
-		generateBehaviorTags(&studentCategory)
+		generateBehaviorTags(h.behaviorRuleSet(schoolID, latestBehavior), &studentCategory)
 
 		// 添加到列表
 		studentCategories = append(studentCategories, studentCategory)
@@ -1958,10 +1608,10 @@ func (h *BehaviorHandler) GetClassroomBehaviorSummary(ctx context.Context, class
 		var attentionTags []dto.BehaviorTag
 
 		for _, tag := range student.BehaviorTags {
-			switch tag.Type {
-			case string(consts.BehaviorTagTypeEarlyLearn), string(consts.BehaviorTagTypeQuestion), string(consts.BehaviorTagTypeCorrectStreak):
+			switch {
+			case slices.Contains(praiseTagTypes, tag.Type):
 				praiseTags = append(praiseTags, tag)
-			case string(consts.BehaviorTagTypePageSwitch), string(consts.BehaviorTagTypeOtherContent), string(consts.BehaviorTagTypePause):
+			case slices.Contains(attentionTagTypes, tag.Type):
 				attentionTags = append(attentionTags, tag)
 			}
 		}
@@ -2021,5 +1671,19 @@ func convertToAPIBehaviorCategory(student dto.StudentBehaviorCategoryDTO) api.St
 		PageSwitchCount:   student.PageSwitchCount,
 		OtherContentCount: student.OtherContentCount,
 		PauseCount:        student.PauseCount,
+		BehaviorTags:      convertToAPIBehaviorTags(student.BehaviorTags),
+	}
+}
+
+// convertToAPIBehaviorTags 将行为标签转换为API响应格式
+func convertToAPIBehaviorTags(tags []dto.BehaviorTag) []api.BehaviorTag {
+	result := make([]api.BehaviorTag, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, api.BehaviorTag{
+			Type:  tag.Type,
+			Count: tag.Count,
+			Text:  tag.Text,
+		})
 	}
+	return result
 }
@@ -0,0 +1,95 @@
+package behavior
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+)
+
+// LearningScoreModel 获取学校学科的学习分模型，规则集没有配置学习分模型时使用内置默认模型
+func (s *BehaviorRuleStore) LearningScoreModel(schoolID int64, subject string) *dto.LearningScoreModel {
+	if model := s.Get(schoolID, subject).LearningScore; model != nil {
+		return model
+	}
+	return defaultLearningScoreModel
+}
+
+// CalcLearningScore 按学习分模型计算学习分及明细，model 为空时使用内置默认模型
+func CalcLearningScore(model *dto.LearningScoreModel, input *dto.LearningScoreInput) (int64, []dto.LearningScoreItem) {
+	facts := behaviorRuleFacts{
+		consts.BehaviorRuleFieldStayDuration:      float64(input.StayDuration),
+		consts.BehaviorRuleFieldCorrectStreak:     float64(input.CorrectStreak),
+		consts.BehaviorRuleFieldEarlyLearnCount:   float64(input.EarlyLearnCount),
+		consts.BehaviorRuleFieldQuestionCount:     float64(input.QuestionCount),
+		consts.BehaviorRuleFieldCorrectAnswers:    float64(input.CorrectAnswers),
+		consts.BehaviorRuleFieldTotalQuestions:    float64(input.TotalQuestions),
+		consts.BehaviorRuleFieldAccuracyRate:      utils.F64Percent(float64(input.CorrectAnswers), float64(input.TotalQuestions), 4),
+		consts.BehaviorRuleFieldPageSwitchCount:   float64(input.PageSwitchCount),
+		consts.BehaviorRuleFieldOtherContentCount: float64(input.OtherContentCount),
+		consts.BehaviorRuleFieldPauseCount:        float64(input.PauseCount),
+	}
+	return facts.learningScore(model)
+}
+
+// learningScore 按学习分模型计算学习分及明细
+func (f behaviorRuleFacts) learningScore(model *dto.LearningScoreModel) (int64, []dto.LearningScoreItem) {
+	if model == nil {
+		model = defaultLearningScoreModel
+	}
+
+	total := 0.0
+	items := make([]dto.LearningScoreItem, 0, len(model.Components))
+	for _, component := range model.Components {
+		value, _ := ruleNumber(f[component.Field])
+		unit := component.Unit
+		if unit <= 0 {
+			unit = 1
+		}
+		units := value / unit
+		if component.Cap > 0 {
+			units = math.Min(units, component.Cap)
+		}
+		score := units * component.Weight
+		total += score
+		items = append(items, dto.LearningScoreItem{
+			Name:  component.Name,
+			Field: component.Field,
+			Value: value,
+			Score: utils.F64Div(score, 1, 2),
+		})
+	}
+
+	total = math.Max(total, 0)
+	if model.MaxScore > 0 {
+		total = math.Min(total, model.MaxScore)
+	}
+	return int64(math.Round(total)), items
+}
+
+func validateLearningScoreModel(model *dto.LearningScoreModel) error {
+	if model.MaxScore < 0 {
+		return errors.Errorf("学习分上限无效: %v", model.MaxScore)
+	}
+	if len(model.Components) == 0 {
+		return errors.New("学习分计分项不能为空")
+	}
+	for _, component := range model.Components {
+		if component.Name == "" {
+			return errors.New("学习分计分项名称不能为空")
+		}
+		if !behaviorRuleFields[component.Field] {
+			return errors.Errorf("学习分计分项 %s 的字段无效: %s", component.Name, component.Field)
+		}
+		if component.Unit < 0 || component.Cap < 0 {
+			return errors.Errorf("学习分计分项 %s 的计分单位或上限无效", component.Name)
+		}
+		if math.IsNaN(component.Weight) || math.IsInf(component.Weight, 0) {
+			return errors.Errorf("学习分计分项 %s 的得分无效", component.Name)
+		}
+	}
+	return nil
+}
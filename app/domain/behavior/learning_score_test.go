@@ -0,0 +1,60 @@
+package behavior
+
+import (
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/model/dto"
+)
+
+func TestCalcLearningScore(t *testing.T) {
+	// 默认模型：学习时长最多计 30 分钟，正确率占 70 分
+	score, items := CalcLearningScore(nil, &dto.LearningScoreInput{StayDuration: 45 * 60, TotalQuestions: 4, CorrectAnswers: 3})
+	assert.Equal(t, int64(83), score)
+	assert.Equal(t, []dto.LearningScoreItem{
+		{Name: "学习时长", Field: consts.BehaviorRuleFieldStayDuration, Value: 2700, Score: 30},
+		{Name: "正确率", Field: consts.BehaviorRuleFieldAccuracyRate, Value: 75, Score: 52.5},
+	}, items)
+
+	model := &dto.LearningScoreModel{
+		MaxScore: 50,
+		Components: []dto.LearningScoreComponent{
+			{Name: "答对", Field: consts.BehaviorRuleFieldCorrectAnswers, Weight: 10},
+			{Name: "提问", Field: consts.BehaviorRuleFieldQuestionCount, Cap: 2, Weight: 5},
+			{Name: "切换页面", Field: consts.BehaviorRuleFieldPageSwitchCount, Weight: -3},
+		},
+	}
+	score, items = CalcLearningScore(model, &dto.LearningScoreInput{TotalQuestions: 3, CorrectAnswers: 2, QuestionCount: 5, PageSwitchCount: 4})
+	assert.Equal(t, int64(18), score) // 20 + 10 - 12
+	assert.Equal(t, float64(10), items[1].Score)
+	assert.Equal(t, float64(-12), items[2].Score)
+
+	// 扣分后不低于 0，不超过上限
+	score, _ = CalcLearningScore(model, &dto.LearningScoreInput{PageSwitchCount: 10})
+	assert.Equal(t, int64(0), score)
+	score, _ = CalcLearningScore(model, &dto.LearningScoreInput{CorrectAnswers: 9})
+	assert.Equal(t, int64(50), score)
+}
+
+func TestBehaviorRuleStoreLearningScoreModel(t *testing.T) {
+	store := &BehaviorRuleStore{
+		logger:   clogger.NewContextLogger(log.DefaultLogger),
+		ruleSets: make(map[string]*dto.BehaviorRuleSet),
+	}
+	require.NoError(t, store.Load(`{"ruleSets":[
+		{"schoolId":1,"subject":"数学","version":1,"learningScore":{"components":[{"name":"答对","field":"correct_answers","weight":2}]}},
+		{"schoolId":1,"version":1}]}`))
+
+	assert.Equal(t, "答对", store.LearningScoreModel(1, "数学").Components[0].Name)
+	assert.Same(t, defaultLearningScoreModel, store.LearningScoreModel(1, "语文"))
+	assert.Same(t, defaultLearningScoreModel, store.LearningScoreModel(2, "数学"))
+
+	// 未知字段、空计分项不生效
+	assert.Error(t, store.Load(`{"ruleSets":[{"schoolId":1,"subject":"数学","version":2,"learningScore":{"components":[{"name":"x","field":"subject","weight":1}]}}]}`))
+	assert.Error(t, store.Load(`{"ruleSets":[{"schoolId":1,"subject":"数学","version":2,"learningScore":{"components":[]}}]}`))
+}
@@ -5,16 +5,29 @@ import (
 	"time"
 
 	"gil_teacher/app/consts"
+	"gil_teacher/app/utils/idtools"
 )
 
 // BehaviorMessageQueue 行为消息
+// EventID 由生产端生成，消费端据此去重，保证重复投递和重放时只入库一次
 type BehaviorMessageQueue struct {
+	EventID   string             `json:"eventId,omitempty"`
 	Type      consts.MessageType `json:"type"`
 	Content   json.RawMessage    `json:"content"`
 	Timestamp time.Time          `json:"timestamp"`
 	Version   string             `json:"version"`
 }
 
+func newBehaviorMessage(msgType consts.MessageType, content json.RawMessage) *BehaviorMessageQueue {
+	return &BehaviorMessageQueue{
+		EventID:   idtools.GetUUID(),
+		Type:      msgType,
+		Content:   content,
+		Timestamp: time.Now(),
+		Version:   "1.0",
+	}
+}
+
 // 实现 kafka.Message 接口
 // 获取BehaviorMessageQueue类型的函数
 func (m *BehaviorMessageQueue) GetType() string {
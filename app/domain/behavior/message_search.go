@@ -0,0 +1,448 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/third_party/elasticsearch"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+)
+
+// ErrMessageSearchUnavailable 没有配置 Elasticsearch，无法检索
+var ErrMessageSearchUnavailable = errors.New("消息检索不可用")
+
+// 检索索引，由 Elasticsearch 提供
+type messageSearchIndex interface {
+	EnsureIndex(ctx context.Context, index string, body string) error
+	BulkIndex(ctx context.Context, index string, docs []*elasticsearch.BulkDocument) ([]*elasticsearch.BulkItemError, error)
+	Delete(ctx context.Context, index string, id string) error
+	Search(ctx context.Context, index string, query map[string]any) (*elasticsearch.SearchResult, error)
+}
+
+// MessageSearcher 会话消息全文检索，消费沟通 topic 写入索引，教师按任职班级检索
+type MessageSearcher struct {
+	behaviorDAO   behaviorDao.BehaviorDAO
+	taskAssignDAO dao_task.TaskAssignDAO
+	classroomDAO  dao_classroom.ClassroomDAO
+	index         messageSearchIndex
+	indexReady    atomic.Bool
+	logger        *clogger.ContextLogger
+}
+
+func NewMessageSearcher(
+	behaviorDAO behaviorDao.BehaviorDAO,
+	taskAssignDAO dao_task.TaskAssignDAO,
+	classroomDAO dao_classroom.ClassroomDAO,
+	esClient *elasticsearch.Client,
+	logger *clogger.ContextLogger,
+) *MessageSearcher {
+	searcher := &MessageSearcher{
+		behaviorDAO:   behaviorDAO,
+		taskAssignDAO: taskAssignDAO,
+		classroomDAO:  classroomDAO,
+		logger:        logger,
+	}
+	if esClient.Enabled() {
+		searcher.index = esClient
+	}
+	return searcher
+}
+
+// Consume 独立消费组订阅沟通 topic，将会话消息写入检索索引
+func (s *MessageSearcher) Consume(ctx context.Context, kafkaConf *conf.Kafka, deadLetter *kafka.KafkaProducerClient) {
+	if s.index == nil {
+		s.logger.Warn(ctx, "没有配置 Elasticsearch，会话消息检索索引不启动")
+		return
+	}
+
+	s.logger.Info(ctx, "会话消息检索 Kafka 配置信息: broker=%s, group=%s, topics=%v",
+		kafkaConf.Brokers,
+		consts.KafkaGroupMessageSearch,
+		consts.KafkaTopicMessageSearch)
+
+	consumerGroupHandlerImpl := &kafka.ConsumerGroupHandlerImpl{
+		Group:        consts.KafkaGroupMessageSearch,
+		Topics:       consts.KafkaTopicMessageSearch,
+		BatchSize:    kafkaConf.Consumer.BatchSize,
+		BatchTime:    kafkaConf.Consumer.BatchTime * time.Second,
+		SessionTime:  kafkaConf.Consumer.SessionTime * time.Second,
+		ProcMsgList:  s.HandleMessage,
+		MaxRetries:   kafkaConf.Consumer.MaxRetries,
+		RetryBackoff: kafkaConf.Consumer.RetryBackoff * time.Millisecond,
+		DeadLetter:   deadLetter,
+		Log:          s.logger,
+	}
+	for ctx.Err() == nil {
+		kafka.ConsumeKafkaMsgInSession(ctx, kafkaConf, consumerGroupHandlerImpl)
+		time.Sleep(time.Second)
+	}
+}
+
+// HandleMessage 将一批会话消息写入检索索引
+// 无法解析的消息由行为消费组写入死信 topic，这里只记录日志；索引不可用时整批重试，单条消息失败时按失败原因决定是否重试
+func (s *MessageSearcher) HandleMessage(msgs []*sarama.ConsumerMessage) error {
+	ctx := context.Background()
+	events, decodeFailures := decodeBehaviorEvents(msgs)
+	for _, failure := range decodeFailures {
+		s.logger.Warn(ctx, "[MessageSearcher] 跳过无法解析的消息: %v", failure)
+	}
+
+	var messages []*dto.CommunicationMessageDTO
+	sources := make(map[string]*sarama.ConsumerMessage)
+	for _, event := range events {
+		if event.msgType != consts.MessageTypeCommunication {
+			continue
+		}
+		var message dto.CommunicationMessageDTO
+		if err := json.Unmarshal(event.content, &message); err != nil {
+			s.logger.Warn(ctx, "[MessageSearcher] 跳过无法解析的会话消息, eventID:%s, error:%v", event.eventID, err)
+			continue
+		}
+		if message.MessageID == "" || message.MessageContent == "" {
+			continue
+		}
+		messages = append(messages, &message)
+		sources[message.MessageID] = event.msg
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	failed, err := s.IndexMessages(ctx, messages)
+	if err != nil {
+		return err
+	}
+	var failures kafka.BatchError
+	for _, message := range messages {
+		err, ok := failed[message.MessageID]
+		if !ok {
+			continue
+		}
+		retryable := true
+		var itemErr *elasticsearch.BulkItemError
+		if errors.As(err, &itemErr) {
+			retryable = itemErr.Retryable()
+		}
+		s.logger.Error(ctx, "[MessageSearcher] 写入检索索引失败, retryable:%v, messageID:%s, error:%v", retryable, message.MessageID, err)
+		failures = append(failures, &kafka.MessageError{Msg: sources[message.MessageID], Err: err, Retryable: retryable})
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// IndexMessages 补充会话所属的学校、班级、课堂和作业题目后写入检索索引，相同消息ID重复写入时覆盖
+// 返回写入失败的消息ID => 失败原因；索引不可用或查询班级失败时返回 error
+func (s *MessageSearcher) IndexMessages(ctx context.Context, messages []*dto.CommunicationMessageDTO) (map[string]error, error) {
+	if s.index == nil {
+		return nil, ErrMessageSearchUnavailable
+	}
+	if err := s.ensureIndex(ctx); err != nil {
+		return nil, err
+	}
+
+	failed := make(map[string]error)
+	docs, err := s.buildDocuments(ctx, messages, failed)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return failed, nil
+	}
+
+	bulk := make([]*elasticsearch.BulkDocument, 0, len(docs))
+	for _, doc := range docs {
+		bulk = append(bulk, &elasticsearch.BulkDocument{ID: doc.MessageID, Body: doc})
+	}
+	itemErrs, err := s.index.BulkIndex(ctx, consts.MessageSearchIndex, bulk)
+	if err != nil {
+		return nil, errors.Wrap(err, "写入检索索引失败")
+	}
+	for _, itemErr := range itemErrs {
+		failed[itemErr.ID] = itemErr
+	}
+	return failed, nil
+}
+
+// SyncModeratedMessage 审核更新会话消息后同步检索索引，屏蔽违规片段和通过时按当前内容重新写入，
+// 暂停展示和不予展示时删除文档；没有配置 Elasticsearch 时不处理
+func (s *MessageSearcher) SyncModeratedMessage(ctx context.Context, message *dto.CommunicationMessageDTO, action consts.ModerationAction) error {
+	if s == nil || s.index == nil {
+		return nil
+	}
+
+	switch action {
+	case consts.ModerationActionReview, consts.ModerationActionReject:
+		if err := s.index.Delete(ctx, consts.MessageSearchIndex, message.MessageID); err != nil {
+			return errors.Wrap(err, "删除检索文档失败")
+		}
+		return nil
+	}
+	failed, err := s.IndexMessages(ctx, []*dto.CommunicationMessageDTO{message})
+	if err != nil {
+		return err
+	}
+	return failed[message.MessageID]
+}
+
+// 索引在第一次写入前创建，创建失败时下一批重试
+func (s *MessageSearcher) ensureIndex(ctx context.Context) error {
+	if s.indexReady.Load() {
+		return nil
+	}
+	if err := s.index.EnsureIndex(ctx, consts.MessageSearchIndex, consts.MessageSearchIndexBody); err != nil {
+		return errors.Wrap(err, "创建检索索引失败")
+	}
+	s.indexReady.Store(true)
+	return nil
+}
+
+// 按会话补充检索字段，同一批消息中的会话、课堂和任务布置只查询一次
+// 课堂中的会话按开课记录确定班级，作业题目提问按任务布置的班级确定，其他会话的班级为 0
+// 消息已经过审核时按 ClickHouse 中的当前内容写入，暂停展示和不予展示的消息不写入
+func (s *MessageSearcher) buildDocuments(ctx context.Context, messages []*dto.CommunicationMessageDTO, failed map[string]error) ([]*dto.MessageSearchDocumentDTO, error) {
+	sessionMessages := make(map[string][]string)
+	for _, message := range messages {
+		sessionMessages[message.SessionID] = append(sessionMessages[message.SessionID], message.MessageID)
+	}
+	contents, err := s.messageContents(ctx, sessionMessages)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询消息当前内容失败")
+	}
+
+	sessions := make(map[string]*dto.CommunicationSessionDTO)
+	docs := make([]*dto.MessageSearchDocumentDTO, 0, len(messages))
+	var classroomIDs []int64
+	assignIDs := make(map[int64][]int64)
+	for _, message := range messages {
+		content := message.MessageContent
+		if current, ok := contents[message.MessageID]; ok {
+			content = current
+		}
+		if content == consts.ModerationHeldContent || content == consts.ModerationRejectedContent {
+			continue
+		}
+
+		session, ok := sessions[message.SessionID]
+		if !ok {
+			var err error
+			session, err = s.behaviorDAO.GetCommunicationSession(ctx, message.SessionID)
+			if err != nil {
+				failed[message.MessageID] = errors.Wrap(err, "查询会话失败")
+				continue
+			}
+			sessions[message.SessionID] = session
+			if session.ClassroomID > 0 {
+				classroomIDs = append(classroomIDs, int64(session.ClassroomID))
+			}
+		}
+
+		doc := &dto.MessageSearchDocumentDTO{
+			MessageID:   message.MessageID,
+			SessionID:   message.SessionID,
+			SessionType: session.SessionType,
+			SchoolID:    session.SchoolID,
+			ClassID:     session.ClassID,
+			ClassroomID: session.ClassroomID,
+			CourseID:    session.CourseID,
+			UserID:      message.UserID,
+			UserType:    message.UserType,
+			MessageType: message.MessageType,
+			AnswerTo:    message.AnswerTo,
+			Content:     content,
+			CreatedAt:   message.CreatedAt,
+		}
+		if session.TargetID != nil {
+			if taskID, assignID, questionID, ok := consts.ParseTaskQuestionTarget(*session.TargetID); ok {
+				doc.TaskID, doc.AssignID, doc.QuestionID = taskID, assignID, questionID
+				if !slices.Contains(assignIDs[taskID], assignID) {
+					assignIDs[taskID] = append(assignIDs[taskID], assignID)
+				}
+			}
+		}
+		docs = append(docs, doc)
+	}
+
+	classroomClasses, err := s.classroomDAO.GetClassIDs(ctx, classroomIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课堂班级失败")
+	}
+	assignClasses := make(map[int64]int64)
+	for taskID, ids := range assignIDs {
+		assigns, err := s.taskAssignDAO.GetTaskAssigns(ctx, taskID, ids)
+		if err != nil {
+			return nil, errors.Wrap(err, "查询任务布置失败")
+		}
+		for _, assign := range assigns {
+			if assign.GroupType == consts.TASK_GROUP_TYPE_CLASS {
+				assignClasses[assign.AssignID] = assign.GroupID
+			}
+		}
+	}
+
+	for _, doc := range docs {
+		if doc.ClassID > 0 {
+			continue
+		}
+		if classID := classroomClasses[int64(doc.ClassroomID)]; classID > 0 {
+			doc.ClassID = uint64(classID)
+		} else if classID := assignClasses[doc.AssignID]; classID > 0 {
+			doc.ClassID = uint64(classID)
+		}
+	}
+	return docs, nil
+}
+
+// Search 在教师有权限的班级中检索会话消息，classIDs 为空时没有结果
+// 消息内容以 ClickHouse 为准，审核后内容发生变化的消息返回当前内容，不返回高亮
+func (s *MessageSearcher) Search(ctx context.Context, schoolID int64, classIDs []int64, req *api.MessageSearchRequest) (*api.MessageSearchResponse, error) {
+	if s.index == nil {
+		return nil, ErrMessageSearchUnavailable
+	}
+	resp := &api.MessageSearchResponse{
+		List:     []*dto.MessageSearchHitDTO{},
+		PageInfo: &consts.ApiPageResponse{Page: req.Page, PageSize: req.PageSize},
+	}
+	if len(classIDs) == 0 {
+		return resp, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, consts.MessageSearchTimeout*time.Second)
+	defer cancel()
+	result, err := s.index.Search(ctx, consts.MessageSearchIndex, buildMessageSearchQuery(schoolID, classIDs, req))
+	if err != nil {
+		s.logger.Error(ctx, "[Search] 检索会话消息失败, schoolID:%d, keyword:%s, error:%v", schoolID, req.Keyword, err)
+		return nil, err
+	}
+
+	for _, hit := range result.Hits {
+		var doc dto.MessageSearchDocumentDTO
+		if err := json.Unmarshal(hit.Source, &doc); err != nil {
+			s.logger.Warn(ctx, "[Search] 跳过无法解析的检索结果, id:%s, error:%v", hit.ID, err)
+			continue
+		}
+		resp.List = append(resp.List, &dto.MessageSearchHitDTO{
+			MessageSearchDocumentDTO: &doc,
+			Highlights:               hit.Highlight["content"],
+		})
+	}
+	if err := s.refreshContents(ctx, resp.List); err != nil {
+		s.logger.Error(ctx, "[Search] 查询消息当前内容失败, schoolID:%d, error:%v", schoolID, err)
+		return nil, err
+	}
+	resp.PageInfo.Total = result.Total
+	return resp, nil
+}
+
+// 审核时会同步检索索引，同步前检索到的结果仍按 ClickHouse 中的当前内容返回
+func (s *MessageSearcher) refreshContents(ctx context.Context, hits []*dto.MessageSearchHitDTO) error {
+	sessionMessages := make(map[string][]string)
+	for _, hit := range hits {
+		sessionMessages[hit.SessionID] = append(sessionMessages[hit.SessionID], hit.MessageID)
+	}
+	contents, err := s.messageContents(ctx, sessionMessages)
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		if content, ok := contents[hit.MessageID]; ok && content != hit.Content {
+			hit.Content = content
+			hit.Highlights = nil
+		}
+	}
+	return nil
+}
+
+// 查询消息在 ClickHouse 中的当前内容，sessionID => 消息ID 列表，还没有入库的消息不返回
+func (s *MessageSearcher) messageContents(ctx context.Context, sessionMessages map[string][]string) (map[string]string, error) {
+	contents := make(map[string]string)
+	for sessionID, messageIDs := range sessionMessages {
+		messages, err := s.behaviorDAO.GetCommunicationSessionMessagesByIDs(ctx, sessionID, messageIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			contents[message.MessageID] = message.MessageContent
+		}
+	}
+	return contents, nil
+}
+
+// 关键词全部命中，按相关度和发送时间倒序；学校和班级为必选过滤条件
+func buildMessageSearchQuery(schoolID int64, classIDs []int64, req *api.MessageSearchRequest) map[string]any {
+	filters := []any{
+		map[string]any{"term": map[string]any{"schoolId": schoolID}},
+		map[string]any{"terms": map[string]any{"classId": classIDs}},
+	}
+	terms := []struct {
+		field string
+		value any
+		set   bool
+	}{
+		{"classroomId", req.ClassroomID, req.ClassroomID > 0},
+		{"taskId", req.TaskID, req.TaskID > 0},
+		{"assignId", req.AssignID, req.AssignID > 0},
+		{"questionId", req.QuestionID, req.QuestionID != ""},
+		{"sessionType", req.SessionType, req.SessionType != ""},
+		{"userId", req.UserID, req.UserID > 0},
+		{"userType", req.UserType, req.UserType != ""},
+	}
+	for _, term := range terms {
+		if term.set {
+			filters = append(filters, map[string]any{"term": map[string]any{term.field: term.value}})
+		}
+	}
+	if req.StartTime > 0 || req.EndTime > 0 {
+		createdAt := map[string]any{"format": "epoch_second"}
+		if req.StartTime > 0 {
+			createdAt["gte"] = req.StartTime
+		}
+		if req.EndTime > 0 {
+			createdAt["lte"] = req.EndTime
+		}
+		filters = append(filters, map[string]any{"range": map[string]any{"createdAt": createdAt}})
+	}
+
+	return map[string]any{
+		"query": map[string]any{
+			"bool": map[string]any{
+				"must": []any{
+					map[string]any{"match": map[string]any{"content": map[string]any{"query": req.Keyword, "operator": "and"}}},
+				},
+				"filter": filters,
+			},
+		},
+		"sort": []any{
+			map[string]any{"_score": "desc"},
+			map[string]any{"createdAt": "desc"},
+		},
+		"highlight": map[string]any{
+			"pre_tags":  []string{consts.MessageSearchHighlightPreTag},
+			"post_tags": []string{consts.MessageSearchHighlightPostTag},
+			"fields": map[string]any{
+				"content": map[string]any{
+					"fragment_size":       consts.MessageSearchHighlightFragment,
+					"number_of_fragments": consts.MessageSearchHighlightCount,
+				},
+			},
+		},
+		"from": (req.Page - 1) * req.PageSize,
+		"size": req.PageSize,
+	}
+}
@@ -0,0 +1,252 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/third_party/elasticsearch"
+)
+
+type stubSearchDAO struct {
+	behaviorDao.BehaviorDAO
+	sessions map[string]*dto.CommunicationSessionDTO
+	messages []*dto.CommunicationMessageDTO
+}
+
+func (d *stubSearchDAO) GetCommunicationSession(ctx context.Context, sessionID string) (*dto.CommunicationSessionDTO, error) {
+	if session, ok := d.sessions[sessionID]; ok {
+		return session, nil
+	}
+	return nil, errors.New("communication session not found")
+}
+
+func (d *stubSearchDAO) GetCommunicationSessionMessagesByIDs(ctx context.Context, sessionID string, messageIDs []string) ([]*dto.CommunicationMessageDTO, error) {
+	return d.messages, nil
+}
+
+type stubSearchAssignDAO struct {
+	dao_task.TaskAssignDAO
+}
+
+func (d *stubSearchAssignDAO) GetTaskAssigns(ctx context.Context, taskID int64, assignIds []int64) ([]*dao_task.TaskAssign, error) {
+	return []*dao_task.TaskAssign{{AssignID: 12, TaskID: taskID, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 302}}, nil
+}
+
+type stubSearchClassroomDAO struct {
+	dao_classroom.ClassroomDAO
+}
+
+func (d *stubSearchClassroomDAO) GetClassIDs(ctx context.Context, classroomIDs []int64) (map[int64]int64, error) {
+	return map[int64]int64{501: 301}, nil
+}
+
+type stubSearchIndex struct {
+	ensured  int
+	docs     []*elasticsearch.BulkDocument
+	deleted  []string
+	failures []*elasticsearch.BulkItemError
+	result   *elasticsearch.SearchResult
+	query    map[string]any
+}
+
+func (i *stubSearchIndex) EnsureIndex(ctx context.Context, index string, body string) error {
+	i.ensured++
+	return nil
+}
+
+func (i *stubSearchIndex) BulkIndex(ctx context.Context, index string, docs []*elasticsearch.BulkDocument) ([]*elasticsearch.BulkItemError, error) {
+	i.docs = append(i.docs, docs...)
+	return i.failures, nil
+}
+
+func (i *stubSearchIndex) Delete(ctx context.Context, index string, id string) error {
+	i.deleted = append(i.deleted, id)
+	return nil
+}
+
+func (i *stubSearchIndex) Search(ctx context.Context, index string, query map[string]any) (*elasticsearch.SearchResult, error) {
+	i.query = query
+	return i.result, nil
+}
+
+func newTestMessageSearcher(behaviorDAO *stubSearchDAO, index *stubSearchIndex) *MessageSearcher {
+	return &MessageSearcher{
+		behaviorDAO:   behaviorDAO,
+		taskAssignDAO: &stubSearchAssignDAO{},
+		classroomDAO:  &stubSearchClassroomDAO{},
+		index:         index,
+		logger:        clogger.NewContextLogger(log.DefaultLogger),
+	}
+}
+
+func communicationConsumerMessage(t *testing.T, offset int64, message *dto.CommunicationMessageDTO) *sarama.ConsumerMessage {
+	content, err := json.Marshal(message)
+	require.NoError(t, err)
+	return &sarama.ConsumerMessage{
+		Topic:     consts.KafkaTopicCommunication,
+		Partition: 0,
+		Offset:    offset,
+		Value:     newBehaviorMessage(consts.MessageTypeCommunication, content).Encode(),
+	}
+}
+
+func TestMessageSearcherHandleMessage(t *testing.T) {
+	targetID := consts.TaskQuestionTargetID(11, 12, "q1")
+	behaviorDAO := &stubSearchDAO{sessions: map[string]*dto.CommunicationSessionDTO{
+		"classroom": {SessionID: "classroom", SchoolID: 1, ClassroomID: 501, SessionType: string(consts.CommunicationSessionTypeChat)},
+		"question":  {SessionID: "question", SchoolID: 1, TargetID: &targetID, SessionType: string(consts.CommunicationSessionTypeQuestion)},
+	}}
+	index := &stubSearchIndex{failures: []*elasticsearch.BulkItemError{{ID: "m2", Status: http.StatusBadRequest, Reason: "mapper_parsing_exception"}}}
+	searcher := newTestMessageSearcher(behaviorDAO, index)
+
+	createdAt := time.Unix(1700000000, 0)
+	msgs := []*sarama.ConsumerMessage{
+		communicationConsumerMessage(t, 1, &dto.CommunicationMessageDTO{MessageID: "m1", SessionID: "classroom", UserID: 100, UserType: "student", MessageContent: "老师这里没听懂", CreatedAt: createdAt}),
+		communicationConsumerMessage(t, 2, &dto.CommunicationMessageDTO{MessageID: "m2", SessionID: "question", UserID: 100, UserType: "student", MessageContent: "第三题怎么做", CreatedAt: createdAt}),
+		communicationConsumerMessage(t, 3, &dto.CommunicationMessageDTO{MessageID: "m3", SessionID: "missing", UserID: 100, UserType: "student", MessageContent: "在吗", CreatedAt: createdAt}),
+		communicationConsumerMessage(t, 4, &dto.CommunicationMessageDTO{MessageID: "m4", SessionID: "classroom", UserID: 100, UserType: "student", CreatedAt: createdAt}),
+	}
+
+	err := searcher.HandleMessage(msgs)
+	var batchErr kafka.BatchError
+	require.ErrorAs(t, err, &batchErr)
+	require.Len(t, batchErr, 2)
+	assert.Equal(t, int64(2), batchErr[0].Msg.Offset)
+	assert.False(t, batchErr[0].Retryable)
+	assert.Equal(t, int64(3), batchErr[1].Msg.Offset)
+	assert.True(t, batchErr[1].Retryable)
+
+	// 空消息不写入，课堂会话按开课记录补充班级，作业题目提问按任务布置补充班级
+	require.Len(t, index.docs, 2)
+	classroomDoc := index.docs[0].Body.(*dto.MessageSearchDocumentDTO)
+	assert.Equal(t, "m1", index.docs[0].ID)
+	assert.Equal(t, uint64(301), classroomDoc.ClassID)
+	questionDoc := index.docs[1].Body.(*dto.MessageSearchDocumentDTO)
+	assert.Equal(t, uint64(302), questionDoc.ClassID)
+	assert.Equal(t, int64(11), questionDoc.TaskID)
+	assert.Equal(t, "q1", questionDoc.QuestionID)
+
+	// 索引只在第一次写入前创建
+	require.NoError(t, searcher.HandleMessage(msgs[:1]))
+	assert.Equal(t, 1, index.ensured)
+}
+
+func TestMessageSearcherIndexModeratedContent(t *testing.T) {
+	behaviorDAO := &stubSearchDAO{
+		sessions: map[string]*dto.CommunicationSessionDTO{"s1": {SessionID: "s1", SchoolID: 1, ClassID: 301}},
+		messages: []*dto.CommunicationMessageDTO{
+			{MessageID: "m1", SessionID: "s1", MessageContent: "作业不会加**"},
+			{MessageID: "m2", SessionID: "s1", MessageContent: consts.ModerationRejectedContent},
+		},
+	}
+	index := &stubSearchIndex{}
+	searcher := newTestMessageSearcher(behaviorDAO, index)
+
+	// 消费到消息时已经审核过，按当前内容写入，不予展示的消息不写入
+	failed, err := searcher.IndexMessages(context.Background(), []*dto.CommunicationMessageDTO{
+		{MessageID: "m1", SessionID: "s1", MessageContent: "作业不会加微信"},
+		{MessageID: "m2", SessionID: "s1", MessageContent: "违规内容"},
+		{MessageID: "m3", SessionID: "s1", MessageContent: "还没有入库"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, failed)
+	require.Len(t, index.docs, 2)
+	assert.Equal(t, "作业不会加**", index.docs[0].Body.(*dto.MessageSearchDocumentDTO).Content)
+	assert.Equal(t, "还没有入库", index.docs[1].Body.(*dto.MessageSearchDocumentDTO).Content)
+
+	// 暂停展示和不予展示时删除文档，屏蔽违规片段时重新写入
+	require.NoError(t, searcher.SyncModeratedMessage(context.Background(), behaviorDAO.messages[1], consts.ModerationActionReject))
+	assert.Equal(t, []string{"m2"}, index.deleted)
+	require.NoError(t, searcher.SyncModeratedMessage(context.Background(), behaviorDAO.messages[0], consts.ModerationActionMask))
+	require.Len(t, index.docs, 3)
+	assert.Equal(t, "作业不会加**", index.docs[2].Body.(*dto.MessageSearchDocumentDTO).Content)
+
+	// 没有配置 Elasticsearch 时不处理
+	searcher.index = nil
+	assert.NoError(t, searcher.SyncModeratedMessage(context.Background(), behaviorDAO.messages[1], consts.ModerationActionReject))
+}
+
+func TestBuildMessageSearchQuery(t *testing.T) {
+	req := &api.MessageSearchRequest{Keyword: "三角形", QuestionID: "q1", SessionType: "question", StartTime: 1700000000, Page: 3, PageSize: 20}
+	query := buildMessageSearchQuery(1, []int64{301, 302}, req)
+
+	body, err := json.Marshal(query)
+	require.NoError(t, err)
+	var decoded struct {
+		Query struct {
+			Bool struct {
+				Must   []map[string]map[string]map[string]any `json:"must"`
+				Filter []map[string]map[string]any            `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		From int64 `json:"from"`
+		Size int64 `json:"size"`
+	}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+
+	assert.Equal(t, "三角形", decoded.Query.Bool.Must[0]["match"]["content"]["query"])
+	filters := decoded.Query.Bool.Filter
+	require.Len(t, filters, 5)
+	assert.Equal(t, float64(1), filters[0]["term"]["schoolId"])
+	assert.Equal(t, []any{float64(301), float64(302)}, filters[1]["terms"]["classId"])
+	assert.Equal(t, "q1", filters[2]["term"]["questionId"])
+	assert.Equal(t, "question", filters[3]["term"]["sessionType"])
+	assert.Equal(t, map[string]any{"format": "epoch_second", "gte": float64(1700000000)}, filters[4]["range"]["createdAt"])
+	assert.Equal(t, int64(40), decoded.From)
+	assert.Equal(t, int64(20), decoded.Size)
+}
+
+func TestMessageSearcherSearch(t *testing.T) {
+	source := func(id, content string) json.RawMessage {
+		body, _ := json.Marshal(&dto.MessageSearchDocumentDTO{MessageID: id, SessionID: "s1", Content: content})
+		return body
+	}
+	index := &stubSearchIndex{result: &elasticsearch.SearchResult{Total: 2, Hits: []*elasticsearch.SearchHit{
+		{ID: "m1", Source: source("m1", "三角形内角和"), Highlight: map[string][]string{"content": {"<em>三角形</em>内角和"}}},
+		{ID: "m2", Source: source("m2", "加微信问三角形"), Highlight: map[string][]string{"content": {"加微信问<em>三角形</em>"}}},
+	}}}
+	behaviorDAO := &stubSearchDAO{messages: []*dto.CommunicationMessageDTO{
+		{MessageID: "m1", MessageContent: "三角形内角和"},
+		{MessageID: "m2", MessageContent: consts.ModerationHeldContent},
+	}}
+	searcher := newTestMessageSearcher(behaviorDAO, index)
+	req := &api.MessageSearchRequest{Keyword: "三角形", Page: 1, PageSize: 20}
+
+	resp, err := searcher.Search(context.Background(), 1, []int64{301}, req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), resp.PageInfo.Total)
+	require.Len(t, resp.List, 2)
+	assert.Equal(t, []string{"<em>三角形</em>内角和"}, resp.List[0].Highlights)
+	// 审核后内容发生变化，按当前内容返回
+	assert.Equal(t, consts.ModerationHeldContent, resp.List[1].Content)
+	assert.Nil(t, resp.List[1].Highlights)
+
+	// 没有任职班级时不检索
+	index.query = nil
+	resp, err = searcher.Search(context.Background(), 1, nil, req)
+	require.NoError(t, err)
+	assert.Empty(t, resp.List)
+	assert.Nil(t, index.query)
+
+	// 没有配置 Elasticsearch
+	searcher.index = nil
+	_, err = searcher.Search(context.Background(), 1, []int64{301}, req)
+	assert.ErrorIs(t, err, ErrMessageSearchUnavailable)
+}
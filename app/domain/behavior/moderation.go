@@ -0,0 +1,549 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/third_party/volc_ai"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrModerationNotHeld 内容不存在或不在待教师审核状态
+	ErrModerationNotHeld = errors.New("内容不在待审核状态")
+	// ErrModerationNoClass 内容无法确定所属班级，教师无法按班级权限审核
+	ErrModerationNoClass = errors.New("内容没有所属班级")
+
+	// 消息还没有入库，等待行为消费组写入后重试
+	errModerationContentNotFound = errors.New("待审核内容不存在")
+)
+
+// ContentModerator 内容审核，异步审核会话消息和课堂反馈，按结论屏蔽违规片段、暂停展示等待教师审核或不予展示，
+// 每次结论都记录审核记录；模型不可用时使用本地词典
+type ContentModerator struct {
+	behaviorDAO   behaviorDao.BehaviorDAO
+	auditDAO      behaviorDao.ModerationAuditDAO
+	feedbackDAO   dao_classroom.ClassroomFeedbackDAO
+	classroomDAO  dao_classroom.ClassroomDAO
+	taskAssignDAO dao_task.TaskAssignDAO
+	model         volc_ai.ChatModel
+	searcher      *MessageSearcher
+	logger        *clogger.ContextLogger
+}
+
+func NewContentModerator(
+	behaviorDAO behaviorDao.BehaviorDAO,
+	auditDAO behaviorDao.ModerationAuditDAO,
+	feedbackDAO dao_classroom.ClassroomFeedbackDAO,
+	classroomDAO dao_classroom.ClassroomDAO,
+	taskAssignDAO dao_task.TaskAssignDAO,
+	volcAI *volc_ai.Client,
+	searcher *MessageSearcher,
+	logger *clogger.ContextLogger,
+) *ContentModerator {
+	return &ContentModerator{
+		behaviorDAO:   behaviorDAO,
+		auditDAO:      auditDAO,
+		feedbackDAO:   feedbackDAO,
+		classroomDAO:  classroomDAO,
+		taskAssignDAO: taskAssignDAO,
+		model:         volcAI,
+		searcher:      searcher,
+		logger:        logger,
+	}
+}
+
+// 模型返回的审核结论
+type moderationReply struct {
+	Category string   `json:"category"`
+	Severity string   `json:"severity"`
+	Reason   string   `json:"reason"`
+	Keywords []string `json:"keywords"`
+}
+
+// Consume 独立消费组订阅沟通 topic 和待审核内容 topic
+func (m *ContentModerator) Consume(ctx context.Context, kafkaConf *conf.Kafka, deadLetter *kafka.KafkaProducerClient) {
+	m.logger.Info(ctx, "内容审核 Kafka 配置信息: broker=%s, group=%s, topics=%v",
+		kafkaConf.Brokers,
+		consts.KafkaGroupModeration,
+		consts.KafkaTopicModerations)
+
+	consumerGroupHandlerImpl := &kafka.ConsumerGroupHandlerImpl{
+		Group:        consts.KafkaGroupModeration,
+		Topics:       consts.KafkaTopicModerations,
+		BatchSize:    kafkaConf.Consumer.BatchSize,
+		BatchTime:    kafkaConf.Consumer.BatchTime * time.Second,
+		SessionTime:  kafkaConf.Consumer.SessionTime * time.Second,
+		ProcMsgList:  m.HandleMessage,
+		MaxRetries:   kafkaConf.Consumer.MaxRetries,
+		RetryBackoff: kafkaConf.Consumer.RetryBackoff * time.Millisecond,
+		DeadLetter:   deadLetter,
+		Log:          m.logger,
+	}
+	for ctx.Err() == nil {
+		kafka.ConsumeKafkaMsgInSession(ctx, kafkaConf, consumerGroupHandlerImpl)
+		time.Sleep(time.Second)
+	}
+}
+
+// HandleMessage 审核一批会话消息和待审核内容
+// 沟通 topic 中无法解析的消息由行为消费组写入死信 topic，这里只处理待审核内容 topic 的解析失败
+func (m *ContentModerator) HandleMessage(msgs []*sarama.ConsumerMessage) error {
+	ctx := context.Background()
+	events, decodeFailures := decodeBehaviorEvents(msgs)
+
+	var failures kafka.BatchError
+	for _, failure := range decodeFailures {
+		if failure.Msg.Topic == consts.KafkaTopicModeration {
+			failures = append(failures, failure)
+			continue
+		}
+		m.logger.Warn(ctx, "[ContentModerator] 跳过无法解析的消息: %v", failure)
+	}
+
+	for _, event := range events {
+		req, err := m.moderationRequest(ctx, event)
+		if err != nil {
+			m.logger.Error(ctx, "[ContentModerator] 解析待审核内容失败, eventID:%s, error:%v", event.eventID, err)
+			failures = append(failures, &kafka.MessageError{Msg: event.msg, Err: err, Retryable: true})
+			continue
+		}
+		if req == nil {
+			continue
+		}
+		if err := m.Moderate(ctx, req); err != nil {
+			m.logger.Error(ctx, "[ContentModerator] 审核失败, contentType:%s, contentID:%s, error:%v", req.ContentType, req.ContentID, err)
+			failures = append(failures, &kafka.MessageError{Msg: event.msg, Err: err, Retryable: true})
+		}
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// 将消息转换为待审核内容，不需要审核的消息返回 nil
+func (m *ContentModerator) moderationRequest(ctx context.Context, event *behaviorEvent) (*dto.ModerationRequestDTO, error) {
+	switch event.msgType {
+	case consts.MessageTypeModeration:
+		var req dto.ModerationRequestDTO
+		if err := json.Unmarshal(event.content, &req); err != nil {
+			return nil, errors.Wrap(err, "解析待审核内容失败")
+		}
+		return &req, nil
+	case consts.MessageTypeCommunication:
+		var message dto.CommunicationMessageDTO
+		if err := json.Unmarshal(event.content, &message); err != nil {
+			return nil, errors.Wrap(err, "解析会话消息失败")
+		}
+		// AI 的回答由系统生成，不审核
+		if message.UserType == string(consts.CommunicationUserTypeAI) || strings.TrimSpace(message.MessageContent) == "" {
+			return nil, nil
+		}
+		session, err := m.behaviorDAO.GetCommunicationSession(ctx, message.SessionID)
+		if err != nil {
+			return nil, errors.Wrap(err, "查询会话失败")
+		}
+		classID, err := m.sessionClassID(ctx, session)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.ModerationRequestDTO{
+			ContentType: string(consts.ModerationContentTypeMessage),
+			ContentID:   message.MessageID,
+			ParentID:    message.SessionID,
+			SchoolID:    session.SchoolID,
+			ClassID:     classID,
+			UserID:      message.UserID,
+			UserType:    message.UserType,
+			Content:     message.MessageContent,
+			CreatedAt:   message.CreatedAt,
+		}, nil
+	}
+	return nil, nil
+}
+
+// 会话所属的班级，会话没有记录班级时按课堂或作业布置确定，都无法确定时返回 0
+func (m *ContentModerator) sessionClassID(ctx context.Context, session *dto.CommunicationSessionDTO) (uint64, error) {
+	if session.ClassID > 0 {
+		return session.ClassID, nil
+	}
+	if session.ClassroomID > 0 {
+		classIDs, err := m.classroomDAO.GetClassIDs(ctx, []int64{int64(session.ClassroomID)})
+		if err != nil {
+			return 0, errors.Wrap(err, "查询课堂班级失败")
+		}
+		if classID := classIDs[int64(session.ClassroomID)]; classID > 0 {
+			return uint64(classID), nil
+		}
+	}
+	if session.TargetID != nil {
+		if taskID, assignID, _, ok := consts.ParseTaskQuestionTarget(*session.TargetID); ok {
+			assigns, err := m.taskAssignDAO.GetTaskAssigns(ctx, taskID, []int64{assignID})
+			if err != nil {
+				return 0, errors.Wrap(err, "查询任务布置失败")
+			}
+			if len(assigns) > 0 && assigns[0].GroupType == consts.TASK_GROUP_TYPE_CLASS {
+				return uint64(assigns[0].GroupID), nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+// Moderate 审核内容并按结论处理，已有审核记录的内容不重复审核
+func (m *ContentModerator) Moderate(ctx context.Context, req *dto.ModerationRequestDTO) error {
+	latest, err := m.auditDAO.GetLatestAudit(ctx, req.SchoolID, req.ContentType, req.ContentID)
+	if err != nil {
+		return errors.Wrap(err, "查询审核记录失败")
+	}
+	if latest != nil {
+		return nil
+	}
+
+	verdict := m.Check(ctx, req.Content)
+	// 通过的会话消息内容不变，不需要更新；课堂反馈需要记录审核状态
+	if verdict.Action != string(consts.ModerationActionPass) || req.ContentType == string(consts.ModerationContentTypeFeedback) {
+		if err := m.apply(ctx, req, consts.ModerationAction(verdict.Action), verdict.MaskedContent); err != nil {
+			return err
+		}
+	}
+
+	audit := newModerationAudit(req, verdict)
+	// 自动审核的记录 ID 由内容生成，写入审核记录失败重试时覆盖
+	audit.AuditID = uuid.NewSHA1(uuid.NameSpaceOID, []byte(req.ContentType+consts.CombineKey+req.ContentID)).String()
+	if err := m.auditDAO.SaveAudit(ctx, audit); err != nil {
+		return errors.Wrap(err, "保存审核记录失败")
+	}
+	if verdict.Action != string(consts.ModerationActionPass) {
+		m.logger.Info(ctx, "[ContentModerator] 内容审核未通过, contentType:%s, contentID:%s, action:%s, category:%s, source:%s",
+			req.ContentType, req.ContentID, verdict.Action, verdict.Category, verdict.Source)
+	}
+	return nil
+}
+
+// FeedbackModerationRequest 课堂反馈的待审核内容
+func FeedbackModerationRequest(feedback *dao_classroom.ClassroomFeedback) *dto.ModerationRequestDTO {
+	userType := consts.CommunicationUserTypeStudent
+	if feedback.CreateType == consts.ClassroomFeedbackCreateTypeTeacher {
+		userType = consts.CommunicationUserTypeTeacher
+	}
+	return &dto.ModerationRequestDTO{
+		ContentType: string(consts.ModerationContentTypeFeedback),
+		ContentID:   strconv.FormatInt(feedback.ID, 10),
+		ParentID:    strconv.FormatInt(feedback.ClassID, 10),
+		SchoolID:    uint64(feedback.SchoolID),
+		ClassID:     uint64(feedback.ClassID),
+		UserID:      uint64(feedback.UserID),
+		UserType:    string(userType),
+		Content:     feedback.Content,
+		CreatedAt:   time.Unix(feedback.CreateTime, 0),
+	}
+}
+
+// SweepPendingFeedback 定时补偿审核仍待审核的课堂反馈，投递审核请求失败或审核消息进入死信时反馈会一直停留在待审核状态
+func (m *ContentModerator) SweepPendingFeedback(ctx context.Context) {
+	ticker := time.NewTicker(consts.ModerationSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sweepPendingFeedback(ctx)
+		}
+	}
+}
+
+func (m *ContentModerator) sweepPendingFeedback(ctx context.Context) {
+	createdBefore := time.Now().Add(-consts.ModerationSweepDelay).Unix()
+	feedbacks, err := m.feedbackDAO.ListModerationPending(ctx, createdBefore, consts.ModerationSweepBatchSize)
+	if err != nil {
+		return
+	}
+	for _, feedback := range feedbacks {
+		if err := m.Moderate(ctx, FeedbackModerationRequest(feedback)); err != nil {
+			m.logger.Error(ctx, "[ContentModerator] 补偿审核课堂反馈失败, feedbackID:%d, error:%v", feedback.ID, err)
+		}
+	}
+}
+
+// Check 审核内容，模型不可用或返回格式不正确时使用本地词典
+func (m *ContentModerator) Check(ctx context.Context, content string) *dto.ModerationVerdictDTO {
+	verdict, err := m.modelVerdict(ctx, content)
+	if err != nil {
+		m.logger.Warn(ctx, "[ContentModerator] 模型审核失败，使用本地词典, error:%v", err)
+		verdict = dictionaryVerdict(content)
+	}
+
+	action := moderationAction(consts.ModerationSeverity(verdict.Severity))
+	if action == consts.ModerationActionMask {
+		verdict.MaskedContent = maskContent(content, verdict.Keywords)
+		// 没有可以屏蔽的片段时无法只屏蔽违规部分，交给教师审核
+		if verdict.MaskedContent == content {
+			verdict.MaskedContent = ""
+			action = consts.ModerationActionReview
+		}
+	}
+	verdict.Action = string(action)
+	return verdict
+}
+
+func (m *ContentModerator) modelVerdict(ctx context.Context, content string) (*dto.ModerationVerdictDTO, error) {
+	if m.model == nil {
+		return nil, errors.New("没有可用的审核模型")
+	}
+	ctx, cancel := context.WithTimeout(ctx, consts.ModerationTimeout)
+	defer cancel()
+
+	reply, err := m.model.Chat(ctx, []volc_ai.ChatMessage{
+		{Role: volc_ai.ChatRoleSystem, Content: consts.ModerationPromptV1.SystemPrompt},
+		{Role: volc_ai.ChatRoleUser, Content: content},
+	})
+	if err != nil {
+		return nil, err
+	}
+	verdict, err := parseModerationVerdict(reply, content)
+	if err != nil {
+		return nil, err
+	}
+	verdict.PromptVersion = consts.ModerationPromptV1.Version
+	return verdict, nil
+}
+
+// ListHeld 分页查询教师班级中等待教师审核的内容，按审核时间正序
+func (m *ContentModerator) ListHeld(ctx context.Context, schoolID int64, classIDs []int64, contentType string, page, pageSize int64) ([]*dto.ModerationAuditDTO, int64, error) {
+	audits, total, err := m.auditDAO.ListLatestAuditsByAction(ctx, uint64(schoolID), toUint64s(classIDs), contentType,
+		string(consts.ModerationActionReview), &consts.DBPageInfo{Page: page, Limit: pageSize})
+	if err != nil {
+		m.logger.Error(ctx, "[ListHeld] ListLatestAuditsByAction failed, schoolID:%d, error:%v", schoolID, err)
+		return nil, 0, err
+	}
+	return audits, total, nil
+}
+
+// ListAudits 查询教师班级中内容的全部审核记录
+func (m *ContentModerator) ListAudits(ctx context.Context, schoolID int64, classIDs []int64, contentType, contentID string) ([]*dto.ModerationAuditDTO, error) {
+	audits, err := m.auditDAO.ListAudits(ctx, uint64(schoolID), toUint64s(classIDs), contentType, contentID)
+	if err != nil {
+		m.logger.Error(ctx, "[ListAudits] ListAudits failed, contentType:%s, contentID:%s, error:%v", contentType, contentID, err)
+		return nil, err
+	}
+	return audits, nil
+}
+
+// HeldClassID 查询等待教师审核的内容所属的班级，由调用方检查班级权限
+func (m *ContentModerator) HeldClassID(ctx context.Context, schoolID int64, contentType, contentID string) (int64, error) {
+	latest, err := m.auditDAO.GetLatestAudit(ctx, uint64(schoolID), contentType, contentID)
+	if err != nil {
+		m.logger.Error(ctx, "[HeldClassID] GetLatestAudit failed, contentType:%s, contentID:%s, error:%v", contentType, contentID, err)
+		return 0, err
+	}
+	if latest == nil || latest.Action != string(consts.ModerationActionReview) {
+		return 0, ErrModerationNotHeld
+	}
+	if latest.ClassID == 0 {
+		return 0, ErrModerationNoClass
+	}
+	return int64(latest.ClassID), nil
+}
+
+// Review 教师审核暂停展示的内容，通过时恢复原始内容，不通过时不予展示，调用前需通过 HeldClassID 检查班级权限
+func (m *ContentModerator) Review(ctx context.Context, schoolID, teacherID int64, req *api.ModerationReviewRequest) (*dto.ModerationAuditDTO, error) {
+	latest, err := m.auditDAO.GetLatestAudit(ctx, uint64(schoolID), req.ContentType, req.ContentID)
+	if err != nil {
+		m.logger.Error(ctx, "[Review] GetLatestAudit failed, contentType:%s, contentID:%s, error:%v", req.ContentType, req.ContentID, err)
+		return nil, err
+	}
+	if latest == nil || latest.Action != string(consts.ModerationActionReview) {
+		return nil, ErrModerationNotHeld
+	}
+
+	action := consts.ModerationActionReject
+	if req.Approve {
+		action = consts.ModerationActionPass
+	}
+	content := &dto.ModerationRequestDTO{
+		ContentType: latest.ContentType,
+		ContentID:   latest.ContentID,
+		ParentID:    latest.ParentID,
+		SchoolID:    latest.SchoolID,
+		ClassID:     latest.ClassID,
+		UserID:      latest.UserID,
+		UserType:    latest.UserType,
+		Content:     latest.Content,
+	}
+	if err := m.apply(ctx, content, action, ""); err != nil {
+		m.logger.Error(ctx, "[Review] apply failed, contentType:%s, contentID:%s, error:%v", req.ContentType, req.ContentID, err)
+		return nil, err
+	}
+
+	audit := newModerationAudit(content, &dto.ModerationVerdictDTO{
+		Action:   string(action),
+		Category: latest.Category,
+		Severity: latest.Severity,
+		Reason:   req.Reason,
+		Source:   consts.ModerationSourceTeacher,
+	})
+	audit.ReviewerID = uint64(teacherID)
+	if err := m.auditDAO.SaveAudit(ctx, audit); err != nil {
+		m.logger.Error(ctx, "[Review] SaveAudit failed, contentType:%s, contentID:%s, error:%v", req.ContentType, req.ContentID, err)
+		return nil, err
+	}
+	return audit, nil
+}
+
+// 按处理方式更新内容：会话消息替换展示内容，通过时恢复原始内容，并同步检索索引；
+// 课堂反馈更新审核状态，暂停展示和不予展示的反馈不出现在查询中，只有屏蔽违规片段时替换内容
+func (m *ContentModerator) apply(ctx context.Context, req *dto.ModerationRequestDTO, action consts.ModerationAction, maskedContent string) error {
+	switch consts.ModerationContentType(req.ContentType) {
+	case consts.ModerationContentTypeMessage:
+		content := req.Content
+		switch action {
+		case consts.ModerationActionMask:
+			content = maskedContent
+		case consts.ModerationActionReview:
+			content = consts.ModerationHeldContent
+		case consts.ModerationActionReject:
+			content = consts.ModerationRejectedContent
+		}
+
+		messages, err := m.behaviorDAO.GetCommunicationSessionMessagesByIDs(ctx, req.ParentID, []string{req.ContentID})
+		if err != nil {
+			return errors.Wrap(err, "查询会话消息失败")
+		}
+		if len(messages) == 0 || messages[0] == nil {
+			return errModerationContentNotFound
+		}
+		message := messages[0]
+		if message.MessageContent != content {
+			message.MessageContent = content
+			if err := m.behaviorDAO.SaveCommunicationMessage(ctx, message); err != nil {
+				return errors.Wrap(err, "更新会话消息失败")
+			}
+		}
+		// 检索索引保留原文时，教师仍能按违规词检索到屏蔽或不予展示的消息；内容未变化时也同步，覆盖上次同步失败的情况
+		return errors.Wrap(m.searcher.SyncModeratedMessage(ctx, message, action), "更新检索索引失败")
+	case consts.ModerationContentTypeFeedback:
+		feedbackID, err := strconv.ParseInt(req.ContentID, 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "反馈ID不正确: %s", req.ContentID)
+		}
+		content := ""
+		if action == consts.ModerationActionMask {
+			content = maskedContent
+		}
+		return errors.Wrap(m.feedbackDAO.UpdateModeration(ctx, int64(req.SchoolID), feedbackID, consts.ModerationActionStatus[action], content),
+			"更新课堂反馈审核状态失败")
+	}
+	return errors.Errorf("不支持的审核内容类型: %s", req.ContentType)
+}
+
+func newModerationAudit(req *dto.ModerationRequestDTO, verdict *dto.ModerationVerdictDTO) *dto.ModerationAuditDTO {
+	return &dto.ModerationAuditDTO{
+		ContentType:   req.ContentType,
+		ContentID:     req.ContentID,
+		ParentID:      req.ParentID,
+		SchoolID:      req.SchoolID,
+		ClassID:       req.ClassID,
+		UserID:        req.UserID,
+		UserType:      req.UserType,
+		Content:       req.Content,
+		MaskedContent: verdict.MaskedContent,
+		Action:        verdict.Action,
+		Category:      verdict.Category,
+		Severity:      verdict.Severity,
+		Reason:        verdict.Reason,
+		Source:        verdict.Source,
+		PromptVersion: verdict.PromptVersion,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// 按违规程度确定处理方式：轻微的屏蔽违规片段，疑似违规的等待教师审核，明确违规的不予展示
+func moderationAction(severity consts.ModerationSeverity) consts.ModerationAction {
+	switch severity {
+	case consts.ModerationSeverityHigh:
+		return consts.ModerationActionReject
+	case consts.ModerationSeverityMedium:
+		return consts.ModerationActionReview
+	case consts.ModerationSeverityLow:
+		return consts.ModerationActionMask
+	}
+	return consts.ModerationActionPass
+}
+
+// 解析模型返回的审核结论，程度不在约定范围内时视为格式不正确；只保留原文中出现的屏蔽片段
+func parseModerationVerdict(reply, content string) (*dto.ModerationVerdictDTO, error) {
+	object, err := extractJSONObject(reply)
+	if err != nil {
+		return nil, err
+	}
+	var parsed moderationReply
+	if err := json.Unmarshal([]byte(object), &parsed); err != nil {
+		return nil, errors.Wrapf(err, "模型返回格式不正确: %s", reply)
+	}
+	severity := consts.ModerationSeverity(strings.ToLower(strings.TrimSpace(parsed.Severity)))
+	if _, ok := consts.ModerationSeverityLevels[severity]; !ok {
+		return nil, errors.Errorf("模型返回的违规程度不正确: %s", parsed.Severity)
+	}
+
+	verdict := &dto.ModerationVerdictDTO{
+		Category: strings.TrimSpace(parsed.Category),
+		Severity: string(severity),
+		Reason:   truncateRunes(strings.TrimSpace(parsed.Reason), consts.ModerationReasonMaxLen),
+		Source:   consts.ModerationSourceModel,
+	}
+	if verdict.Category == "" {
+		verdict.Category = consts.ModerationCategoryOther
+		if severity == consts.ModerationSeverityNone {
+			verdict.Category = consts.ModerationCategoryNormal
+		}
+	}
+	for _, keyword := range parsed.Keywords {
+		if keyword != "" && strings.Contains(content, keyword) {
+			verdict.Keywords = append(verdict.Keywords, keyword)
+		}
+	}
+	return verdict, nil
+}
+
+// 模型可能在 JSON 前后输出多余内容，只取第一个 { 到最后一个 } 之间的部分
+func extractJSONObject(reply string) (string, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return "", errors.Errorf("模型返回格式不正确: %s", reply)
+	}
+	return reply[start : end+1], nil
+}
+
+func truncateRunes(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
+
+func toUint64s(ids []int64) []uint64 {
+	result := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, uint64(id))
+	}
+	return result
+}
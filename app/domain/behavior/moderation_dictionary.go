@@ -0,0 +1,81 @@
+package behavior
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+// 编译后的本地审核词典规则
+type moderationRule struct {
+	category string
+	severity consts.ModerationSeverity
+	patterns []*regexp.Regexp
+}
+
+var moderationRules = compileModerationRules(consts.ModerationDictionary)
+
+// 关键词按字面匹配且不区分大小写，和正则一起编译
+func compileModerationRules(dictionary []consts.ModerationRule) []*moderationRule {
+	rules := make([]*moderationRule, 0, len(dictionary))
+	for _, rule := range dictionary {
+		compiled := &moderationRule{category: rule.Category, severity: rule.Severity}
+		for _, keyword := range rule.Keywords {
+			compiled.patterns = append(compiled.patterns, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(keyword)))
+		}
+		for _, pattern := range rule.Patterns {
+			compiled.patterns = append(compiled.patterns, regexp.MustCompile(pattern))
+		}
+		rules = append(rules, compiled)
+	}
+	return rules
+}
+
+// 使用本地词典审核，类别和程度取命中规则中程度最高的一条，命中的片段全部屏蔽
+func dictionaryVerdict(content string) *dto.ModerationVerdictDTO {
+	verdict := &dto.ModerationVerdictDTO{
+		Category: consts.ModerationCategoryNormal,
+		Severity: string(consts.ModerationSeverityNone),
+		Source:   consts.ModerationSourceDictionary,
+	}
+	for _, rule := range moderationRules {
+		matched := false
+		for _, pattern := range rule.patterns {
+			for _, keyword := range pattern.FindAllString(content, -1) {
+				matched = true
+				if !slices.Contains(verdict.Keywords, keyword) {
+					verdict.Keywords = append(verdict.Keywords, keyword)
+				}
+			}
+		}
+		if matched && severityAbove(rule.severity, consts.ModerationSeverity(verdict.Severity)) {
+			verdict.Category = rule.category
+			verdict.Severity = string(rule.severity)
+		}
+	}
+	if len(verdict.Keywords) > 0 {
+		verdict.Reason = "命中本地审核词典: " + strings.Join(verdict.Keywords, "、")
+	}
+	return verdict
+}
+
+func severityAbove(a, b consts.ModerationSeverity) bool {
+	return consts.ModerationSeverityLevels[a] > consts.ModerationSeverityLevels[b]
+}
+
+// 将违规片段替换为等长的屏蔽字符，长片段优先替换，避免短片段先替换后长片段匹配不到
+func maskContent(content string, keywords []string) string {
+	keywords = slices.Clone(keywords)
+	slices.SortFunc(keywords, func(a, b string) int { return len(b) - len(a) })
+	for _, keyword := range keywords {
+		if keyword == "" {
+			continue
+		}
+		mask := strings.Repeat(string(consts.ModerationMaskRune), len([]rune(keyword)))
+		content = regexp.MustCompile(`(?i)`+regexp.QuoteMeta(keyword)).ReplaceAllLiteralString(content, mask)
+	}
+	return content
+}
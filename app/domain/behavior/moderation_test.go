@@ -0,0 +1,329 @@
+package behavior
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+)
+
+type stubModerationDAO struct {
+	behaviorDao.BehaviorDAO
+	messages map[string]*dto.CommunicationMessageDTO
+}
+
+func (d *stubModerationDAO) GetCommunicationSessionMessagesByIDs(ctx context.Context, sessionID string, messageIDs []string) ([]*dto.CommunicationMessageDTO, error) {
+	var messages []*dto.CommunicationMessageDTO
+	for _, id := range messageIDs {
+		if message, ok := d.messages[id]; ok {
+			copied := *message
+			messages = append(messages, &copied)
+		}
+	}
+	return messages, nil
+}
+
+func (d *stubModerationDAO) GetCommunicationSession(ctx context.Context, sessionID string) (*dto.CommunicationSessionDTO, error) {
+	return &dto.CommunicationSessionDTO{SessionID: sessionID, SchoolID: 1, ClassID: 1001}, nil
+}
+
+func (d *stubModerationDAO) SaveCommunicationMessage(ctx context.Context, message *dto.CommunicationMessageDTO) error {
+	d.messages[message.MessageID] = message
+	return nil
+}
+
+type stubAuditDAO struct {
+	behaviorDao.ModerationAuditDAO
+	audits []*dto.ModerationAuditDTO
+}
+
+func (d *stubAuditDAO) SaveAudit(ctx context.Context, audit *dto.ModerationAuditDTO) error {
+	d.audits = append(d.audits, audit)
+	return nil
+}
+
+func (d *stubAuditDAO) GetLatestAudit(ctx context.Context, schoolID uint64, contentType, contentID string) (*dto.ModerationAuditDTO, error) {
+	for i := len(d.audits) - 1; i >= 0; i-- {
+		if d.audits[i].ContentType == contentType && d.audits[i].ContentID == contentID {
+			return d.audits[i], nil
+		}
+	}
+	return nil, nil
+}
+
+type stubModerationFeedbackDAO struct {
+	dao_classroom.ClassroomFeedbackDAO
+	status  int64
+	content string
+	pending []*dao_classroom.ClassroomFeedback
+}
+
+func (d *stubModerationFeedbackDAO) ListModerationPending(ctx context.Context, createdBefore int64, limit int) ([]*dao_classroom.ClassroomFeedback, error) {
+	var feedbacks []*dao_classroom.ClassroomFeedback
+	for _, feedback := range d.pending {
+		if feedback.CreateTime < createdBefore {
+			feedbacks = append(feedbacks, feedback)
+		}
+	}
+	return feedbacks, nil
+}
+
+func (d *stubModerationFeedbackDAO) UpdateModeration(ctx context.Context, schoolID, id int64, status int64, content string) error {
+	d.status, d.content = status, content
+	return nil
+}
+
+type stubModerationClassroomDAO struct {
+	dao_classroom.ClassroomDAO
+}
+
+func (d *stubModerationClassroomDAO) GetClassIDs(ctx context.Context, classroomIDs []int64) (map[int64]int64, error) {
+	return map[int64]int64{501: 1002}, nil
+}
+
+type stubModerationAssignDAO struct {
+	dao_task.TaskAssignDAO
+}
+
+func (d *stubModerationAssignDAO) GetTaskAssigns(ctx context.Context, taskID int64, assignIds []int64) ([]*dao_task.TaskAssign, error) {
+	return []*dao_task.TaskAssign{{AssignID: assignIds[0], TaskID: taskID, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1003}}, nil
+}
+
+func newTestModerator(model *stubChatModel) (*ContentModerator, *stubModerationDAO, *stubAuditDAO, *stubModerationFeedbackDAO) {
+	behaviorDAO := &stubModerationDAO{messages: map[string]*dto.CommunicationMessageDTO{
+		"m1": {MessageID: "m1", SessionID: "s1", MessageContent: "老师好"},
+	}}
+	auditDAO := &stubAuditDAO{}
+	feedbackDAO := &stubModerationFeedbackDAO{}
+	moderator := &ContentModerator{
+		behaviorDAO:   behaviorDAO,
+		auditDAO:      auditDAO,
+		feedbackDAO:   feedbackDAO,
+		classroomDAO:  &stubModerationClassroomDAO{},
+		taskAssignDAO: &stubModerationAssignDAO{},
+		logger:        clogger.NewContextLogger(log.DefaultLogger),
+	}
+	// 不传模型时模拟模型不可用
+	if model != nil {
+		moderator.model = model
+	}
+	return moderator, behaviorDAO, auditDAO, feedbackDAO
+}
+
+func messageModerationRequest(content string) *dto.ModerationRequestDTO {
+	return &dto.ModerationRequestDTO{
+		ContentType: string(consts.ModerationContentTypeMessage),
+		ContentID:   "m1",
+		ParentID:    "s1",
+		SchoolID:    1,
+		ClassID:     1001,
+		UserID:      100,
+		UserType:    string(consts.CommunicationUserTypeStudent),
+		Content:     content,
+		CreatedAt:   time.Unix(1700000000, 0),
+	}
+}
+
+func TestDictionaryVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		category string
+		action   consts.ModerationAction
+		masked   string
+	}{
+		{"正常", "这道题我会做了", consts.ModerationCategoryNormal, consts.ModerationActionPass, ""},
+		{"手机号", "我的电话是13812345678", consts.ModerationCategoryPrivacy, consts.ModerationActionMask, "我的电话是***********"},
+		{"辱骂不区分大小写", "你是傻X吧", consts.ModerationCategoryAbuse, consts.ModerationActionMask, "你是**吧"},
+		{"广告", "作业不会加微信", consts.ModerationCategoryAd, consts.ModerationActionReview, ""},
+		{"取程度最高的类别", "一起去赌博吧，加微信", consts.ModerationCategoryIllegal, consts.ModerationActionReject, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moderator, _, _, _ := newTestModerator(nil)
+			verdict := moderator.Check(context.Background(), tt.content)
+			assert.Equal(t, tt.category, verdict.Category)
+			assert.Equal(t, string(tt.action), verdict.Action)
+			assert.Equal(t, tt.masked, verdict.MaskedContent)
+			assert.Equal(t, consts.ModerationSourceDictionary, verdict.Source)
+		})
+	}
+}
+
+func TestParseModerationVerdict(t *testing.T) {
+	verdict, err := parseModerationVerdict(`结论：{"category":"abuse","severity":"low","reason":"辱骂同学","keywords":["笨蛋","不存在"]}`, "你这个笨蛋")
+	require.NoError(t, err)
+	assert.Equal(t, consts.ModerationCategoryAbuse, verdict.Category)
+	assert.Equal(t, string(consts.ModerationSeverityLow), verdict.Severity)
+	assert.Equal(t, []string{"笨蛋"}, verdict.Keywords)
+	assert.Equal(t, consts.ModerationSourceModel, verdict.Source)
+
+	_, err = parseModerationVerdict(`{"category":"abuse","severity":"serious"}`, "你这个笨蛋")
+	assert.Error(t, err)
+	_, err = parseModerationVerdict("无法判断", "你这个笨蛋")
+	assert.Error(t, err)
+}
+
+func TestModerationCheckModel(t *testing.T) {
+	model := &stubChatModel{reply: `{"category":"abuse","severity":"low","reason":"辱骂同学","keywords":["笨蛋"]}`}
+	moderator, _, _, _ := newTestModerator(model)
+
+	verdict := moderator.Check(context.Background(), "你这个笨蛋")
+	assert.Equal(t, string(consts.ModerationActionMask), verdict.Action)
+	assert.Equal(t, "你这个**", verdict.MaskedContent)
+	assert.Equal(t, consts.ModerationPromptV1.Version, verdict.PromptVersion)
+
+	// 模型失败时使用本地词典
+	moderator.model = &stubChatModel{err: errors.New("timeout")}
+	verdict = moderator.Check(context.Background(), "你这个笨蛋")
+	assert.Equal(t, string(consts.ModerationActionPass), verdict.Action)
+	assert.Equal(t, consts.ModerationSourceDictionary, verdict.Source)
+}
+
+func TestModerateMessage(t *testing.T) {
+	moderator, behaviorDAO, auditDAO, _ := newTestModerator(nil)
+	req := messageModerationRequest("作业不会加微信")
+
+	require.NoError(t, moderator.Moderate(context.Background(), req))
+	assert.Equal(t, consts.ModerationHeldContent, behaviorDAO.messages["m1"].MessageContent)
+	require.Len(t, auditDAO.audits, 1)
+	assert.Equal(t, string(consts.ModerationActionReview), auditDAO.audits[0].Action)
+	assert.Equal(t, "作业不会加微信", auditDAO.audits[0].Content)
+
+	// 重复消费不重复审核
+	require.NoError(t, moderator.Moderate(context.Background(), req))
+	assert.Len(t, auditDAO.audits, 1)
+}
+
+func TestModerateFeedback(t *testing.T) {
+	moderator, _, auditDAO, feedbackDAO := newTestModerator(nil)
+	req := &dto.ModerationRequestDTO{
+		ContentType: string(consts.ModerationContentTypeFeedback),
+		ContentID:   "42",
+		SchoolID:    1,
+		Content:     "这节课很有意思",
+	}
+
+	require.NoError(t, moderator.Moderate(context.Background(), req))
+	assert.Equal(t, consts.ModerationStatusPassed, feedbackDAO.status)
+	assert.Empty(t, feedbackDAO.content)
+	require.Len(t, auditDAO.audits, 1)
+}
+
+func TestSweepPendingFeedback(t *testing.T) {
+	moderator, _, auditDAO, feedbackDAO := newTestModerator(nil)
+	now := time.Now().Unix()
+	feedbackDAO.pending = []*dao_classroom.ClassroomFeedback{
+		{ID: 42, SchoolID: 1, ClassID: 1001, UserID: 7, Content: "这节课很有意思", CreateType: consts.ClassroomFeedbackCreateTypeTeacher, CreateTime: now - 3600},
+		{ID: 43, SchoolID: 1, ClassID: 1001, UserID: 8, Content: "刚提交的反馈", CreateTime: now},
+	}
+
+	// 刚提交的反馈等待正常投递的审核
+	moderator.sweepPendingFeedback(context.Background())
+	require.Len(t, auditDAO.audits, 1)
+	audit := auditDAO.audits[0]
+	assert.Equal(t, "42", audit.ContentID)
+	assert.Equal(t, string(consts.CommunicationUserTypeTeacher), audit.UserType)
+	assert.Equal(t, consts.ModerationStatusPassed, feedbackDAO.status)
+
+	// 已有审核记录的反馈不重复审核
+	moderator.sweepPendingFeedback(context.Background())
+	assert.Len(t, auditDAO.audits, 1)
+}
+
+func TestModerationReview(t *testing.T) {
+	moderator, behaviorDAO, auditDAO, _ := newTestModerator(nil)
+	review := &api.ModerationReviewRequest{
+		ContentType: string(consts.ModerationContentTypeMessage),
+		ContentID:   "m1",
+		Approve:     true,
+	}
+
+	_, err := moderator.Review(context.Background(), 1, 7, review)
+	assert.ErrorIs(t, err, ErrModerationNotHeld)
+	_, err = moderator.HeldClassID(context.Background(), 1, review.ContentType, review.ContentID)
+	assert.ErrorIs(t, err, ErrModerationNotHeld)
+
+	require.NoError(t, moderator.Moderate(context.Background(), messageModerationRequest("作业不会加微信")))
+	classID, err := moderator.HeldClassID(context.Background(), 1, review.ContentType, review.ContentID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1001), classID)
+
+	audit, err := moderator.Review(context.Background(), 1, 7, review)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), audit.ClassID)
+	assert.Equal(t, string(consts.ModerationActionPass), audit.Action)
+	assert.Equal(t, consts.ModerationSourceTeacher, audit.Source)
+	assert.Equal(t, uint64(7), audit.ReviewerID)
+	assert.Equal(t, "作业不会加微信", behaviorDAO.messages["m1"].MessageContent)
+	assert.Len(t, auditDAO.audits, 2)
+
+	// 审核完成后不能再次审核
+	_, err = moderator.Review(context.Background(), 1, 7, review)
+	assert.ErrorIs(t, err, ErrModerationNotHeld)
+}
+
+func TestModerationSyncsSearchIndex(t *testing.T) {
+	moderator, behaviorDAO, _, _ := newTestModerator(nil)
+	index := &stubSearchIndex{}
+	moderator.searcher = &MessageSearcher{
+		behaviorDAO:   behaviorDAO,
+		taskAssignDAO: &stubSearchAssignDAO{},
+		classroomDAO:  &stubSearchClassroomDAO{},
+		index:         index,
+		logger:        clogger.NewContextLogger(log.DefaultLogger),
+	}
+
+	// 暂停展示时删除检索文档，教师审核通过后按原始内容重新写入
+	require.NoError(t, moderator.Moderate(context.Background(), messageModerationRequest("作业不会加微信")))
+	assert.Equal(t, []string{"m1"}, index.deleted)
+	assert.Empty(t, index.docs)
+
+	_, err := moderator.Review(context.Background(), 1, 7, &api.ModerationReviewRequest{
+		ContentType: string(consts.ModerationContentTypeMessage),
+		ContentID:   "m1",
+		Approve:     true,
+	})
+	require.NoError(t, err)
+	require.Len(t, index.docs, 1)
+	assert.Equal(t, "作业不会加微信", index.docs[0].Body.(*dto.MessageSearchDocumentDTO).Content)
+}
+
+func TestModerationSessionClassID(t *testing.T) {
+	moderator, _, _, _ := newTestModerator(nil)
+	ctx := context.Background()
+	targetID := consts.TaskQuestionTargetID(11, 12, "q1")
+
+	// 会话记录了班级时直接使用，否则按课堂、作业布置确定
+	classID, err := moderator.sessionClassID(ctx, &dto.CommunicationSessionDTO{ClassID: 1001, ClassroomID: 501})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), classID)
+	classID, err = moderator.sessionClassID(ctx, &dto.CommunicationSessionDTO{ClassroomID: 501})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1002), classID)
+	classID, err = moderator.sessionClassID(ctx, &dto.CommunicationSessionDTO{TargetID: &targetID})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1003), classID)
+	classID, err = moderator.sessionClassID(ctx, &dto.CommunicationSessionDTO{})
+	require.NoError(t, err)
+	assert.Zero(t, classID)
+
+	// 无法确定班级的内容不能按班级权限审核
+	req := messageModerationRequest("作业不会加微信")
+	req.ClassID = 0
+	require.NoError(t, moderator.Moderate(ctx, req))
+	_, err = moderator.HeldClassID(ctx, 1, req.ContentType, req.ContentID)
+	assert.ErrorIs(t, err, ErrModerationNoClass)
+}
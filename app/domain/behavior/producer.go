@@ -3,6 +3,7 @@ package behavior
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 	"time"
 
 	"gil_teacher/app/consts"
@@ -37,6 +38,10 @@ func NewBehaviorProducer(
 
 // RecordTeacherBehavior 记录教师行为
 func (h *BehaviorProducer) RecordTeacherBehavior(ctx context.Context, req *api.TeacherBehaviorRequest) error {
+	if err := validateBehaviorContext(consts.BehaviorType(req.BehaviorType), req.Context); err != nil {
+		return err
+	}
+
 	// 关联会话的，需要检查会话是否存在
 	if req.CommunicationSessionID != "" {
 		session, err := h.handler.behaviorDAO.GetCommunicationSession(ctx, req.CommunicationSessionID)
@@ -63,6 +68,10 @@ func (h *BehaviorProducer) RecordTeacherBehavior(ctx context.Context, req *api.T
 
 // RecordStudentBehavior 记录学生行为
 func (h *BehaviorProducer) RecordStudentBehavior(ctx context.Context, req *api.StudentBehaviorRequest) error {
+	if err := validateBehaviorContext(consts.BehaviorType(req.BehaviorType), req.Context); err != nil {
+		return err
+	}
+
 	// 关联会话的，需要检查会话是否存在
 	if req.CommunicationSessionID != "" {
 		session, err := h.handler.behaviorDAO.GetCommunicationSession(ctx, req.CommunicationSessionID)
@@ -122,15 +131,12 @@ func (s *BehaviorProducer) SendTeacherBehavior(ctx context.Context, behavior *dt
 		return errors.Wrap(err, "序列化教师行为失败")
 	}
 
-	msg := &BehaviorMessageQueue{
-		Type:      consts.MessageTypeTeacherBehavior,
-		Content:   content,
-		Timestamp: time.Now(),
-		Version:   "1.0",
-	}
+	msg := newBehaviorMessage(consts.MessageTypeTeacherBehavior, content)
 
 	s.logger.Info(ctx, "发送教师行为, behavior:%+v", behavior)
-	return s.kafkaClient.ProduceMsgToKafka(ctx, consts.KafkaTopicTeacherBehavior, string(msg.Encode()))
+	// 按教师分区，保证同一教师的行为按发生顺序入库
+	key := strconv.FormatUint(behavior.TeacherID, 10)
+	return s.kafkaClient.ProduceKeyedMsgToKafka(ctx, consts.KafkaTopicTeacherBehavior, key, string(msg.Encode()))
 }
 
 // SendStudentBehavior 发送学生行为
@@ -141,14 +147,11 @@ func (s *BehaviorProducer) SendStudentBehavior(ctx context.Context, behavior *dt
 		return errors.Wrap(err, "序列化学生行为失败")
 	}
 
-	msg := &BehaviorMessageQueue{
-		Type:      consts.MessageTypeStudentBehavior,
-		Content:   content,
-		Timestamp: time.Now(),
-		Version:   "1.0",
-	}
+	msg := newBehaviorMessage(consts.MessageTypeStudentBehavior, content)
 
-	return s.kafkaClient.ProduceMsgToKafka(ctx, consts.KafkaTopicTeacherBehavior, string(msg.Encode()))
+	// 按学生分区，保证学生最新行为不会因乱序或重放被旧行为覆盖
+	key := strconv.FormatUint(behavior.StudentID, 10)
+	return s.kafkaClient.ProduceKeyedMsgToKafka(ctx, consts.KafkaTopicStudentBehavior, key, string(msg.Encode()))
 }
 
 // SendCommunicationMessage 发送沟通会话
@@ -159,12 +162,27 @@ func (s *BehaviorProducer) SendCommunicationMessage(ctx context.Context, message
 		return errors.Wrap(err, "序列化沟通会话失败")
 	}
 
-	msg := &BehaviorMessageQueue{
-		Type:      consts.MessageTypeCommunication,
-		Content:   content,
-		Timestamp: time.Now(),
-		Version:   "1.0",
+	msg := newBehaviorMessage(consts.MessageTypeCommunication, content)
+
+	// 按会话分区，保证同一会话的消息按发送顺序入库
+	return s.kafkaClient.ProduceKeyedMsgToKafka(ctx, consts.KafkaTopicCommunication, message.SessionID, string(msg.Encode()))
+}
+
+// SendModerationRequest 发送待审核内容，由内容审核消费组异步审核
+func (s *BehaviorProducer) SendModerationRequest(ctx context.Context, req *dto.ModerationRequestDTO) error {
+	content, err := json.Marshal(req)
+	if err != nil {
+		s.logger.Error(ctx, "序列化待审核内容失败, error:%v, req:%+v", err, req)
+		return errors.Wrap(err, "序列化待审核内容失败")
 	}
 
-	return s.kafkaClient.ProduceMsgToKafka(ctx, consts.KafkaTopicTeacherBehavior, string(msg.Encode()))
+	msg := newBehaviorMessage(consts.MessageTypeModeration, content)
+
+	// 按内容分区，保证同一内容的审核请求按顺序处理
+	return s.kafkaClient.ProduceKeyedMsgToKafka(ctx, consts.KafkaTopicModeration, req.ContentType+consts.CombineKey+req.ContentID, string(msg.Encode()))
+}
+
+// PushStudentReportHandled 推送教师对学生作业报告的点赞、提醒，推送失败不影响行为投递
+func (s *BehaviorProducer) PushStudentReportHandled(ctx context.Context, teacherID int64, req *api.StudentReportHandleRequest) {
+	s.handler.pushStudentReportHandled(ctx, teacherID, req)
 }
@@ -0,0 +1,77 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+)
+
+// 发送给学生的推送消息
+func studentPushMessage(studentID uint64, classroomID int64, messageType consts.PushMessageType, content string, data []byte) *dto.PushMessage {
+	return &dto.PushMessage{
+		UserType:    consts.CommunicationUserTypeStudent,
+		UserID:      int64(studentID),
+		ClassroomID: classroomID,
+		Type:        messageType,
+		Content:     content,
+		Data:        data,
+	}
+}
+
+// 教师处理学生后同步到教师其他屏幕的推送消息
+func teacherHandledPushMessage(teacherID int64, classroomID int64, behaviorType consts.BehaviorType, studentIDs []uint64) *dto.PushMessage {
+	data, err := json.Marshal(map[string]any{
+		"behaviorType": behaviorType,
+		"studentIds":   studentIDs,
+	})
+	if err != nil {
+		data = []byte("{}")
+	}
+	return &dto.PushMessage{
+		UserType:    consts.CommunicationUserTypeTeacher,
+		UserID:      teacherID,
+		ClassroomID: classroomID,
+		Type:        consts.PushMessageTypeHandled,
+		Data:        data,
+	}
+}
+
+// 推送失败不影响行为记录，失败原因已由 PushPublisher 记录日志
+func (h *BehaviorHandler) publishPushMessages(ctx context.Context, messages []*dto.PushMessage) {
+	if len(messages) == 0 || h.pusher == nil {
+		return
+	}
+	_ = h.pusher.Publish(ctx, messages...)
+}
+
+// 推送教师对学生作业报告的点赞、提醒
+func (h *BehaviorHandler) pushStudentReportHandled(ctx context.Context, teacherID int64, req *api.StudentReportHandleRequest) {
+	messageType := consts.PushMessageTypeReminder
+	content := req.Content
+	if req.BehaviorType == consts.BehaviorTypeTaskPraise {
+		messageType = consts.PushMessageTypePraise
+		if content == "" {
+			content = consts.BehaviorDescDefaultPraiseSimple
+		}
+	}
+	data, err := json.Marshal(map[string]any{
+		"taskId":       req.TaskID,
+		"assignId":     req.AssignID,
+		"behaviorType": req.BehaviorType,
+	})
+	if err != nil {
+		data = []byte("{}")
+	}
+
+	messages := make([]*dto.PushMessage, 0, len(req.StudentIDs)+1)
+	studentIDs := make([]uint64, 0, len(req.StudentIDs))
+	for _, studentID := range req.StudentIDs {
+		messages = append(messages, studentPushMessage(uint64(studentID), 0, messageType, content, data))
+		studentIDs = append(studentIDs, uint64(studentID))
+	}
+	messages = append(messages, teacherHandledPushMessage(teacherID, 0, req.BehaviorType, studentIDs))
+	h.publishPushMessages(ctx, messages)
+}
@@ -43,4 +43,6 @@ type SessionMessageRepository interface {
 	GetUnreadMessageCount(ctx context.Context, userID int64, sessionID string) (int64, error)
 	// 获取用户指定会话的未读消息列表
 	GetUnreadMessageList(ctx context.Context, userID int64, sessionID string) ([]*dto.CommunicationMessageDTO, error)
+	// 获取用户参与的全部会话和未读消息总数
+	GetInbox(ctx context.Context, schoolID, userID int64, page, size int64) (*dto.CommunicationInboxDTO, error)
 }
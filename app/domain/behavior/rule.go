@@ -0,0 +1,365 @@
+package behavior
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+// 学生最新行为可用于规则的字段，值为是否数值字段
+var behaviorRuleFields = map[string]bool{
+	consts.BehaviorRuleFieldBehaviorType:      false,
+	consts.BehaviorRuleFieldSubject:           false,
+	consts.BehaviorRuleFieldLearningType:      false,
+	consts.BehaviorRuleFieldVideoStatus:       false,
+	consts.BehaviorRuleFieldStayDuration:      true,
+	consts.BehaviorRuleFieldCorrectStreak:     true,
+	consts.BehaviorRuleFieldEarlyLearnCount:   true,
+	consts.BehaviorRuleFieldQuestionCount:     true,
+	consts.BehaviorRuleFieldCorrectAnswers:    true,
+	consts.BehaviorRuleFieldTotalQuestions:    true,
+	consts.BehaviorRuleFieldAccuracyRate:      true,
+	consts.BehaviorRuleFieldPageSwitchCount:   true,
+	consts.BehaviorRuleFieldOtherContentCount: true,
+	consts.BehaviorRuleFieldPauseCount:        true,
+}
+
+// 课堂行为分类汇总后可用于标签的计数字段
+var behaviorTagFields = []string{
+	consts.BehaviorRuleFieldCorrectStreak,
+	consts.BehaviorRuleFieldEarlyLearnCount,
+	consts.BehaviorRuleFieldQuestionCount,
+	consts.BehaviorRuleFieldCorrectAnswers,
+	consts.BehaviorRuleFieldTotalQuestions,
+	consts.BehaviorRuleFieldPageSwitchCount,
+	consts.BehaviorRuleFieldOtherContentCount,
+	consts.BehaviorRuleFieldPauseCount,
+}
+
+var (
+	praiseTagTypes = []string{
+		string(consts.BehaviorTagTypeCorrectStreak),
+		string(consts.BehaviorTagTypeEarlyLearn),
+		string(consts.BehaviorTagTypeQuestion),
+	}
+	attentionTagTypes = []string{
+		string(consts.BehaviorTagTypePageSwitch),
+		string(consts.BehaviorTagTypeOtherContent),
+		string(consts.BehaviorTagTypePause),
+	}
+)
+
+var behaviorRuleTemplateField = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// behaviorRuleFacts 规则求值使用的字段值，数值字段统一为 float64
+type behaviorRuleFacts map[string]any
+
+// newBehaviorRuleFacts 从学生最新行为及其上下文提取字段值
+// 上下文按行为类型注册的结构解码，不符合结构时按空上下文处理；上下文中没有时使用行为记录上的值
+func newBehaviorRuleFacts(behavior *dto.StudentLatestBehaviorDTO) behaviorRuleFacts {
+	behaviorContext, err := decodeStudentBehaviorContext(behavior.BehaviorType, behavior.Context)
+	if err != nil {
+		behaviorContext = &dto.StudentBehaviorContext{}
+	}
+
+	facts := behaviorRuleFacts{
+		consts.BehaviorRuleFieldBehaviorType:      string(behavior.BehaviorType),
+		consts.BehaviorRuleFieldSubject:           cmp.Or(behaviorContext.Subject, behavior.Subject),
+		consts.BehaviorRuleFieldLearningType:      cmp.Or(behaviorContext.LearningType, behavior.LearningType),
+		consts.BehaviorRuleFieldVideoStatus:       cmp.Or(behaviorContext.VideoStatus, behavior.VideoStatus),
+		consts.BehaviorRuleFieldStayDuration:      float64(cmp.Or(behaviorContext.StayDuration, behavior.StayDuration)),
+		consts.BehaviorRuleFieldCorrectStreak:     float64(behaviorContext.CorrectStreak),
+		consts.BehaviorRuleFieldEarlyLearnCount:   float64(behaviorContext.EarlyLearnCount),
+		consts.BehaviorRuleFieldQuestionCount:     float64(behaviorContext.QuestionCount),
+		consts.BehaviorRuleFieldPageSwitchCount:   float64(behaviorContext.PageSwitchCount),
+		consts.BehaviorRuleFieldOtherContentCount: float64(behaviorContext.OtherContentCount),
+		consts.BehaviorRuleFieldPauseCount:        float64(behaviorContext.PauseCount),
+	}
+
+	correctAnswers := float64(behavior.CorrectAnswers)
+	totalQuestions := float64(behavior.TotalQuestions)
+	if questionsInfo := behaviorContext.QuestionsInfo; questionsInfo != nil {
+		correctAnswers = float64(questionsInfo.CorrectAnswers)
+		totalQuestions = float64(questionsInfo.TotalQuestions)
+	}
+	accuracyRate := behavior.AccuracyRate
+	if totalQuestions > 0 {
+		accuracyRate = correctAnswers / totalQuestions * 100
+	}
+	facts[consts.BehaviorRuleFieldCorrectAnswers] = correctAnswers
+	facts[consts.BehaviorRuleFieldTotalQuestions] = totalQuestions
+	facts[consts.BehaviorRuleFieldAccuracyRate] = accuracyRate
+	return facts
+}
+
+// newBehaviorTagFacts 从课堂行为分类汇总提取标签计数字段
+func newBehaviorTagFacts(student *dto.StudentBehaviorCategoryDTO) behaviorRuleFacts {
+	return behaviorRuleFacts{
+		consts.BehaviorRuleFieldCorrectStreak:     float64(student.CorrectStreak),
+		consts.BehaviorRuleFieldEarlyLearnCount:   float64(student.EarlyLearnCount),
+		consts.BehaviorRuleFieldQuestionCount:     float64(student.QuestionCount),
+		consts.BehaviorRuleFieldCorrectAnswers:    float64(student.CorrectAnswers),
+		consts.BehaviorRuleFieldTotalQuestions:    float64(student.TotalQuestions),
+		consts.BehaviorRuleFieldPageSwitchCount:   float64(student.PageSwitchCount),
+		consts.BehaviorRuleFieldOtherContentCount: float64(student.OtherContentCount),
+		consts.BehaviorRuleFieldPauseCount:        float64(student.PauseCount),
+	}
+}
+
+// hits 返回命中的规则
+func (f behaviorRuleFacts) hits(rules []dto.BehaviorRule) []dto.BehaviorRuleHit {
+	hits := make([]dto.BehaviorRuleHit, 0)
+	for _, rule := range rules {
+		if hit, ok := f.evaluate(rule); ok {
+			hits = append(hits, hit)
+		}
+	}
+	return hits
+}
+
+// evaluate 全部条件满足时命中，返回规则得分和命中说明
+func (f behaviorRuleFacts) evaluate(rule dto.BehaviorRule) (dto.BehaviorRuleHit, bool) {
+	for _, condition := range rule.Conditions {
+		if !f.match(condition) {
+			return dto.BehaviorRuleHit{}, false
+		}
+	}
+
+	score := rule.Weight
+	if rule.Field != "" && rule.UnitWeight != 0 {
+		unit := rule.Unit
+		if unit <= 0 {
+			unit = 1
+		}
+		value, _ := ruleNumber(f[rule.Field])
+		score += int64(math.Floor(value/unit)) * rule.UnitWeight
+	}
+	return dto.BehaviorRuleHit{
+		Name:    rule.Name,
+		Score:   score,
+		Message: f.render(rule.Template),
+	}, true
+}
+
+func (f behaviorRuleFacts) match(condition dto.BehaviorRuleCondition) bool {
+	value, ok := f[condition.Field]
+	if !ok {
+		return false
+	}
+
+	switch condition.Op {
+	case consts.BehaviorRuleOpEq:
+		return ruleValueEqual(value, condition.Value)
+	case consts.BehaviorRuleOpNe:
+		return !ruleValueEqual(value, condition.Value)
+	case consts.BehaviorRuleOpIn:
+		values, _ := condition.Value.([]any)
+		return slices.ContainsFunc(values, func(v any) bool {
+			return ruleValueEqual(value, v)
+		})
+	}
+
+	left, ok := ruleNumber(value)
+	if !ok {
+		return false
+	}
+	right, ok := ruleNumber(condition.Value)
+	if !ok {
+		return false
+	}
+	switch condition.Op {
+	case consts.BehaviorRuleOpGt:
+		return left > right
+	case consts.BehaviorRuleOpGte:
+		return left >= right
+	case consts.BehaviorRuleOpLt:
+		return left < right
+	case consts.BehaviorRuleOpLte:
+		return left <= right
+	}
+	return false
+}
+
+// render 将模板中的 {字段名} 替换为字段值，未知字段保留原样
+func (f behaviorRuleFacts) render(template string) string {
+	if template == "" {
+		return ""
+	}
+	return behaviorRuleTemplateField.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := f[placeholder[1:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		if number, ok := ruleNumber(value); ok {
+			if number == math.Trunc(number) {
+				return strconv.FormatInt(int64(number), 10)
+			}
+			return strconv.FormatFloat(number, 'f', 2, 64)
+		}
+		return fmt.Sprint(value)
+	})
+}
+
+// scoreTypes 计算各可用类型的得分，规则集中没有配置的类型得分为 0
+func (f behaviorRuleFacts) scoreTypes(typeRules []dto.BehaviorTypeRule, availableTypes []string) map[string]int64 {
+	scores := make(map[string]int64, len(availableTypes))
+	for _, t := range availableTypes {
+		scores[t] = 0
+	}
+	for _, typeRule := range typeRules {
+		if _, ok := scores[typeRule.Type]; !ok {
+			continue
+		}
+		score := typeRule.BaseScore
+		for _, hit := range f.hits(typeRule.Rules) {
+			score += hit.Score
+		}
+		scores[typeRule.Type] = score
+	}
+	return scores
+}
+
+// bestRuleType 按可用类型顺序选出得分最高的类型，最高分不超过 minScore 时返回第一个可用类型
+func bestRuleType(scores map[string]int64, availableTypes []string, minScore int64) (string, int64) {
+	bestType := availableTypes[0]
+	bestScore := int64(math.MinInt64)
+	for _, t := range availableTypes {
+		if scores[t] > bestScore {
+			bestType, bestScore = t, scores[t]
+		}
+	}
+	if bestScore <= minScore {
+		return availableTypes[0], bestScore
+	}
+	return bestType, bestScore
+}
+
+// behaviorTags 按规则集生成行为标签
+func behaviorTags(ruleSet *dto.BehaviorRuleSet, student *dto.StudentBehaviorCategoryDTO) []dto.BehaviorTag {
+	facts := newBehaviorTagFacts(student)
+	tags := make([]dto.BehaviorTag, 0)
+	for _, tagRule := range ruleSet.Tags {
+		count, _ := ruleNumber(facts[tagRule.Field])
+		if count <= 0 || int64(count) < max(tagRule.MinCount, 1) {
+			continue
+		}
+		tags = append(tags, dto.BehaviorTag{
+			Type:  tagRule.Type,
+			Count: int64(count),
+			Text:  facts.render(tagRule.Template),
+		})
+	}
+	return tags
+}
+
+func ruleNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func ruleValueEqual(left, right any) bool {
+	leftNumber, leftOK := ruleNumber(left)
+	rightNumber, rightOK := ruleNumber(right)
+	if leftOK && rightOK {
+		return leftNumber == rightNumber
+	}
+	return fmt.Sprint(left) == fmt.Sprint(right)
+}
+
+// validateBehaviorRuleSet 校验规则集，字段、运算符和标签类型必须是已知的
+func validateBehaviorRuleSet(ruleSet *dto.BehaviorRuleSet) error {
+	if ruleSet == nil {
+		return errors.New("规则集不能为空")
+	}
+	if ruleSet.SchoolID < 0 || ruleSet.Version <= 0 {
+		return errors.Errorf("规则集学校ID或版本号无效, schoolId:%d, version:%d", ruleSet.SchoolID, ruleSet.Version)
+	}
+
+	rules := slices.Concat(ruleSet.PraiseChecks, ruleSet.AttentionChecks)
+	for _, typeRule := range ruleSet.PraiseTypes {
+		if !slices.Contains(praiseTagTypes, typeRule.Type) {
+			return errors.Errorf("未知的表扬类型: %s", typeRule.Type)
+		}
+		rules = append(rules, typeRule.Rules...)
+	}
+	for _, typeRule := range ruleSet.AttentionTypes {
+		if !slices.Contains(attentionTagTypes, typeRule.Type) {
+			return errors.Errorf("未知的关注类型: %s", typeRule.Type)
+		}
+		rules = append(rules, typeRule.Rules...)
+	}
+	for _, rule := range rules {
+		if err := validateBehaviorRule(rule); err != nil {
+			return errors.Wrapf(err, "规则 %s 无效", rule.Name)
+		}
+	}
+
+	for _, tagRule := range ruleSet.Tags {
+		if !slices.Contains(praiseTagTypes, tagRule.Type) && !slices.Contains(attentionTagTypes, tagRule.Type) {
+			return errors.Errorf("未知的标签类型: %s", tagRule.Type)
+		}
+		if !slices.Contains(behaviorTagFields, tagRule.Field) {
+			return errors.Errorf("标签 %s 的计数字段无效: %s", tagRule.Type, tagRule.Field)
+		}
+	}
+
+	if ruleSet.LearningScore != nil {
+		if err := validateLearningScoreModel(ruleSet.LearningScore); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBehaviorRule(rule dto.BehaviorRule) error {
+	if rule.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if rule.Field != "" && !behaviorRuleFields[rule.Field] {
+		return errors.Errorf("计分字段无效: %s", rule.Field)
+	}
+	if rule.Unit < 0 {
+		return errors.Errorf("计分单位无效: %v", rule.Unit)
+	}
+	for _, condition := range rule.Conditions {
+		numeric, ok := behaviorRuleFields[condition.Field]
+		if !ok {
+			return errors.Errorf("未知的字段: %s", condition.Field)
+		}
+		switch condition.Op {
+		case consts.BehaviorRuleOpEq, consts.BehaviorRuleOpNe:
+		case consts.BehaviorRuleOpIn:
+			if _, ok := condition.Value.([]any); !ok {
+				return errors.Errorf("字段 %s 的 in 运算需要数组", condition.Field)
+			}
+		case consts.BehaviorRuleOpGt, consts.BehaviorRuleOpGte, consts.BehaviorRuleOpLt, consts.BehaviorRuleOpLte:
+			if _, ok := ruleNumber(condition.Value); !ok || !numeric {
+				return errors.Errorf("字段 %s 的 %s 运算需要数值", condition.Field, condition.Op)
+			}
+		default:
+			return errors.Errorf("未知的运算符: %s", condition.Op)
+		}
+	}
+	return nil
+}
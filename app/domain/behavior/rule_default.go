@@ -0,0 +1,234 @@
+package behavior
+
+import (
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+// defaultBehaviorRuleSet 内置默认规则集，没有配置学校或学科规则集时使用
+var defaultBehaviorRuleSet = &dto.BehaviorRuleSet{
+	PraiseChecks: []dto.BehaviorRule{
+		{
+			Name:       "连续答对",
+			Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldCorrectStreak, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckCorrectStreak}},
+			Template:   "连续答对{correct_streak}题",
+		},
+		{
+			Name:       "提前学习",
+			Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldEarlyLearnCount, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckEarlyLearnCountRequired}},
+			Template:   "提前学习{early_learn_count}次",
+		},
+		{
+			Name:       "课堂自学",
+			Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldLearningType, Op: consts.BehaviorRuleOpEq, Value: consts.LearningTypeSelfStudy}},
+			Template:   "课堂自学",
+		},
+		{
+			Name:       "主动提问",
+			Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldQuestionCount, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckQuestionCountRequired}},
+			Template:   "主动提问{question_count}次",
+		},
+		{
+			Name: "答题正确率高",
+			Conditions: []dto.BehaviorRuleCondition{
+				{Field: consts.BehaviorRuleFieldCorrectAnswers, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckMinCorrectAnswers},
+				{Field: consts.BehaviorRuleFieldAccuracyRate, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckCorrectRateThreshold * 100},
+			},
+			Template: "答对{correct_answers}题，正确率{accuracy_rate}%",
+		},
+		{
+			Name: "答对题数",
+			Conditions: []dto.BehaviorRuleCondition{
+				{Field: consts.BehaviorRuleFieldCorrectAnswers, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckMinCorrectAnswers},
+				{Field: consts.BehaviorRuleFieldTotalQuestions, Op: consts.BehaviorRuleOpEq, Value: 0},
+			},
+			Template: "答对{correct_answers}题",
+		},
+		{
+			Name: "学习时长",
+			Conditions: []dto.BehaviorRuleCondition{
+				{Field: consts.BehaviorRuleFieldStayDuration, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseCheckMinStayDurationSeconds},
+				{Field: consts.BehaviorRuleFieldVideoStatus, Op: consts.BehaviorRuleOpNe, Value: "pause"},
+			},
+			Template: "专注学习{stay_duration}秒",
+		},
+	},
+	AttentionChecks: []dto.BehaviorRule{
+		{
+			Name: "答题正确率低",
+			Conditions: []dto.BehaviorRuleCondition{
+				{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeAnswer)},
+				{Field: consts.BehaviorRuleFieldAccuracyRate, Op: consts.BehaviorRuleOpLt, Value: consts.AttentionRuleLowAccuracyRate},
+				{Field: consts.BehaviorRuleFieldTotalQuestions, Op: consts.BehaviorRuleOpGte, Value: consts.AttentionRuleLowAccuracyMinQuestions},
+			},
+			Template: "答题正确率{accuracy_rate}%",
+		},
+		{
+			Name: "视频暂停过久",
+			Conditions: []dto.BehaviorRuleCondition{
+				{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeLearning)},
+				{Field: consts.BehaviorRuleFieldVideoStatus, Op: consts.BehaviorRuleOpEq, Value: "pause"},
+				{Field: consts.BehaviorRuleFieldStayDuration, Op: consts.BehaviorRuleOpGte, Value: consts.AttentionRuleVideoPauseTimeSeconds},
+			},
+			Template: "视频已暂停{stay_duration}秒",
+		},
+	},
+	PraiseTypes: []dto.BehaviorTypeRule{
+		{
+			Type:      string(consts.BehaviorTagTypeCorrectStreak),
+			BaseScore: consts.PraiseTypeInitialBaseScore,
+			Rules: []dto.BehaviorRule{
+				{
+					Name:       "连对次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldCorrectStreak, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Field:      consts.BehaviorRuleFieldCorrectStreak,
+					UnitWeight: consts.PraiseCorrectStreakScoreWeight,
+				},
+				{
+					Name: "正确率高",
+					Conditions: []dto.BehaviorRuleCondition{
+						{Field: consts.BehaviorRuleFieldTotalQuestions, Op: consts.BehaviorRuleOpGt, Value: 0},
+						{Field: consts.BehaviorRuleFieldCorrectAnswers, Op: consts.BehaviorRuleOpGt, Value: 0},
+						{Field: consts.BehaviorRuleFieldAccuracyRate, Op: consts.BehaviorRuleOpGte, Value: consts.PraiseAccuracyRateThresholdForBonus},
+					},
+					Weight: consts.PraiseAccuracyRateBonusScore,
+				},
+				{
+					Name:       "答题行为",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeAnswer)}},
+					Weight:     consts.PraiseAnswerTypeBonusScore,
+				},
+			},
+		},
+		{
+			Type:      string(consts.BehaviorTagTypeQuestion),
+			BaseScore: consts.PraiseTypeInitialBaseScore,
+			Rules: []dto.BehaviorRule{
+				{
+					Name:       "提问次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldQuestionCount, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Field:      consts.BehaviorRuleFieldQuestionCount,
+					UnitWeight: consts.PraiseQuestionCountScoreWeight,
+				},
+				{
+					Name:       "提问行为",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeQuestion)}},
+					Weight:     consts.PraiseQuestionTypeBonusScore,
+				},
+			},
+		},
+		{
+			Type:      string(consts.BehaviorTagTypeEarlyLearn),
+			BaseScore: consts.PraiseTypeInitialBaseScore,
+			Rules: []dto.BehaviorRule{
+				{
+					Name:       "提前学习次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldEarlyLearnCount, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Field:      consts.BehaviorRuleFieldEarlyLearnCount,
+					UnitWeight: consts.PraiseEarlyLearnCountScoreWeight,
+				},
+				{
+					Name:       "学习时长较长",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldStayDuration, Op: consts.BehaviorRuleOpGt, Value: consts.PraiseStayDurationThresholdMinutesForBonus * 60}},
+					Weight:     consts.PraiseLongStayDurationBaseBonusScore,
+					Field:      consts.BehaviorRuleFieldStayDuration,
+					Unit:       consts.PraiseStayDurationBonusIntervalMinutes * 60,
+					UnitWeight: consts.PraiseStayDurationBonusScorePerInterval,
+				},
+				{
+					Name:       "课堂自学",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldLearningType, Op: consts.BehaviorRuleOpEq, Value: consts.LearningTypeSelfStudy}},
+					Weight:     consts.PraiseSelfStudyLearningTypeBonusScore,
+				},
+				{
+					Name:       "学习行为",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeLearning)}},
+					Weight:     consts.PraiseLearningBehaviorTypeBonusScore,
+				},
+			},
+		},
+	},
+	PraiseMinScore: consts.PraiseBestTypeMinScoreThreshold,
+	AttentionTypes: []dto.BehaviorTypeRule{
+		{
+			Type: string(consts.BehaviorTagTypePageSwitch),
+			Rules: []dto.BehaviorRule{
+				{
+					Name:       "切换页面次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldPageSwitchCount, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Weight:     consts.AttentionRulePageSwitchBaseScore,
+					Field:      consts.BehaviorRuleFieldPageSwitchCount,
+					UnitWeight: consts.AttentionRulePageSwitchScorePerCount,
+				},
+			},
+		},
+		{
+			Type: string(consts.BehaviorTagTypeOtherContent),
+			Rules: []dto.BehaviorRule{
+				{
+					Name:       "学习其他内容次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldOtherContentCount, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Weight:     consts.AttentionRuleOtherContentBaseScore,
+					Field:      consts.BehaviorRuleFieldOtherContentCount,
+					UnitWeight: consts.AttentionRuleOtherContentScorePerCount,
+				},
+			},
+		},
+		{
+			Type: string(consts.BehaviorTagTypePause),
+			Rules: []dto.BehaviorRule{
+				{
+					Name: "学习时视频暂停",
+					Conditions: []dto.BehaviorRuleCondition{
+						{Field: consts.BehaviorRuleFieldBehaviorType, Op: consts.BehaviorRuleOpEq, Value: string(consts.BehaviorTypeLearning)},
+						{Field: consts.BehaviorRuleFieldVideoStatus, Op: consts.BehaviorRuleOpEq, Value: "pause"},
+					},
+					Weight: consts.AttentionRuleVideoPauseLearningScore,
+				},
+				{
+					Name:       "暂停次数",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldPauseCount, Op: consts.BehaviorRuleOpGt, Value: 0}},
+					Weight:     consts.AttentionRulePauseCountBaseScore,
+					Field:      consts.BehaviorRuleFieldPauseCount,
+					UnitWeight: consts.AttentionRulePauseCountScorePerCount,
+				},
+				{
+					Name:       "长时间停留",
+					Conditions: []dto.BehaviorRuleCondition{{Field: consts.BehaviorRuleFieldStayDuration, Op: consts.BehaviorRuleOpGt, Value: consts.AttentionRuleVideoPauseTimeSeconds}},
+					Weight:     consts.AttentionRuleBaseScoreForLongPause,
+					Field:      consts.BehaviorRuleFieldStayDuration,
+					Unit:       60,
+					UnitWeight: consts.AttentionRuleScorePerMinutePaused,
+				},
+			},
+		},
+	},
+	Tags: []dto.BehaviorTagRule{
+		{Type: string(consts.BehaviorTagTypeEarlyLearn), Field: consts.BehaviorRuleFieldEarlyLearnCount, Template: consts.BehaviorTagTextEarlyLearn},
+		{Type: string(consts.BehaviorTagTypeQuestion), Field: consts.BehaviorRuleFieldQuestionCount, Template: consts.BehaviorTagTextQuestion},
+		{Type: string(consts.BehaviorTagTypeCorrectStreak), Field: consts.BehaviorRuleFieldCorrectStreak, Template: consts.BehaviorTagTextCorrectStreak},
+		{Type: string(consts.BehaviorTagTypePageSwitch), Field: consts.BehaviorRuleFieldPageSwitchCount, Template: consts.BehaviorTagTextPageSwitch},
+		{Type: string(consts.BehaviorTagTypeOtherContent), Field: consts.BehaviorRuleFieldOtherContentCount, Template: consts.BehaviorTagTextOtherContent},
+		{Type: string(consts.BehaviorTagTypePause), Field: consts.BehaviorRuleFieldPauseCount, Template: consts.BehaviorTagTextPause},
+	},
+	LearningScore: defaultLearningScoreModel,
+}
+
+// defaultLearningScoreModel 内置默认学习分模型，学习时长占 30 分，正确率占 70 分
+var defaultLearningScoreModel = &dto.LearningScoreModel{
+	MaxScore: consts.LearningScoreDefaultMaxScore,
+	Components: []dto.LearningScoreComponent{
+		{
+			Name:   "学习时长",
+			Field:  consts.BehaviorRuleFieldStayDuration,
+			Unit:   consts.LearningScoreDefaultStayUnit,
+			Cap:    consts.LearningScoreDefaultStayCap,
+			Weight: consts.LearningScoreDefaultStayWeight,
+		},
+		{
+			Name:   "正确率",
+			Field:  consts.BehaviorRuleFieldAccuracyRate,
+			Weight: consts.LearningScoreDefaultAccuracyWeight,
+		},
+	},
+}
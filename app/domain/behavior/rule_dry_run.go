@@ -0,0 +1,86 @@
+package behavior
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+)
+
+// 试运行的规则集校验不通过
+var ErrInvalidBehaviorRuleSet = errors.New("行为规则集无效")
+
+// DryRunBehaviorRules 使用规则集评估课堂学生当前行为，只返回评估结果，不记录表扬和关注
+// 指定待发布的规则集时所有学生都使用该规则集，否则按学校和学科使用已生效的规则集
+func (h *BehaviorHandler) DryRunBehaviorRules(ctx context.Context, req *api.BehaviorRuleDryRunRequest) (*api.BehaviorRuleDryRunResponse, error) {
+	if req.RuleSet != nil {
+		if err := validateBehaviorRuleSet(req.RuleSet); err != nil {
+			return nil, errors.Wrap(ErrInvalidBehaviorRuleSet, err.Error())
+		}
+	}
+	ruleSetOf := func(behavior *dto.StudentLatestBehaviorDTO) *dto.BehaviorRuleSet {
+		switch {
+		case req.RuleSet != nil:
+			return req.RuleSet
+		case req.Subject != "":
+			return h.rules.Get(req.SchoolID, req.Subject)
+		default:
+			return h.behaviorRuleSet(req.SchoolID, behavior)
+		}
+	}
+
+	behaviors, err := h.GetClassLatestBehaviors(ctx, req.ClassroomID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := h.classBehaviorCategories(ctx, req.ClassroomID, behaviors, ruleSetOf)
+	if err != nil {
+		return nil, err
+	}
+	studentTags := make(map[uint64][]dto.BehaviorTag, len(categories))
+	for _, category := range categories {
+		studentTags[category.StudentID] = category.BehaviorTags
+	}
+
+	students := make([]api.BehaviorRuleDryRunStudent, 0, len(behaviors))
+	for _, behavior := range behaviors {
+		ruleSet := ruleSetOf(behavior)
+		facts := newBehaviorRuleFacts(behavior)
+
+		praiseHits := facts.hits(ruleSet.PraiseChecks)
+		praiseScores := facts.scoreTypes(ruleSet.PraiseTypes, praiseTagTypes)
+		praiseType, _ := bestRuleType(praiseScores, praiseTagTypes, ruleSet.PraiseMinScore)
+		attentionHits := facts.hits(ruleSet.AttentionChecks)
+		attentionScores := facts.scoreTypes(ruleSet.AttentionTypes, attentionTagTypes)
+		attentionType, _ := bestRuleType(attentionScores, attentionTagTypes, ruleSet.AttentionMinScore)
+
+		students = append(students, api.BehaviorRuleDryRunStudent{
+			StudentID:       behavior.StudentID,
+			StudentName:     behavior.StudentName,
+			BehaviorType:    string(behavior.BehaviorType),
+			RuleSetSchoolID: ruleSet.SchoolID,
+			RuleSetSubject:  ruleSet.Subject,
+			RuleSetVersion:  ruleSet.Version,
+			PraiseWorthy:    len(praiseHits) > 0,
+			PraiseHits:      praiseHits,
+			PraiseScores:    praiseScores,
+			PraiseType:      praiseType,
+			NeedsAttention:  len(attentionHits) > 0,
+			AttentionHits:   attentionHits,
+			AttentionScores: attentionScores,
+			AttentionType:   attentionType,
+			BehaviorTags:    convertToAPIBehaviorTags(studentTags[uint64(behavior.StudentID)]),
+		})
+	}
+	sort.Slice(students, func(i, j int) bool {
+		return students[i].StudentID < students[j].StudentID
+	})
+
+	return &api.BehaviorRuleDryRunResponse{
+		ClassroomID: req.ClassroomID,
+		Students:    students,
+	}, nil
+}
@@ -0,0 +1,161 @@
+package behavior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/core/nacosx"
+	"gil_teacher/app/model/dto"
+)
+
+// BehaviorRuleStore 按学校和学科管理行为规则集
+// 规则集从 Nacos 加载并监听变更，未配置 Nacos 或没有匹配的规则集时使用内置默认规则集
+type BehaviorRuleStore struct {
+	client config_client.IConfigClient
+	logger *clogger.ContextLogger
+
+	mu       sync.RWMutex
+	ruleSets map[string]*dto.BehaviorRuleSet
+}
+
+func NewBehaviorRuleStore(data *conf.Data, logger *clogger.ContextLogger) (*BehaviorRuleStore, func()) {
+	s := &BehaviorRuleStore{
+		logger:   logger,
+		ruleSets: make(map[string]*dto.BehaviorRuleSet),
+	}
+	if data == nil || data.Nacos == nil || data.Nacos.Host == "" {
+		logger.Info(context.Background(), "[NewBehaviorRuleStore] 未配置 Nacos，使用默认行为规则集")
+		return s, func() {}
+	}
+
+	client, err := nacosx.NewConfigClient(data.Nacos)
+	if err != nil {
+		logger.Error(context.Background(), "[NewBehaviorRuleStore] 创建 Nacos 配置客户端失败，使用默认行为规则集, error:%v", err)
+		return s, func() {}
+	}
+	s.client = client
+	s.watch()
+
+	cleanup := func() {
+		if err := s.client.CancelListenConfig(s.configParam()); err != nil {
+			s.logger.Warn(context.Background(), "[BehaviorRuleStore] 取消监听行为规则集失败, error:%v", err)
+		}
+	}
+	return s, cleanup
+}
+
+func (s *BehaviorRuleStore) configParam() vo.ConfigParam {
+	return vo.ConfigParam{
+		DataId: consts.BehaviorRuleNacosDataID,
+		Group:  consts.BehaviorRuleNacosGroup,
+	}
+}
+
+// 加载当前配置并监听变更，加载失败时保留已生效的规则集
+func (s *BehaviorRuleStore) watch() {
+	ctx := context.Background()
+
+	content, err := s.client.GetConfig(s.configParam())
+	if err != nil {
+		s.logger.Warn(ctx, "[BehaviorRuleStore] 获取行为规则集失败, error:%v", err)
+	} else if err := s.Load(content); err != nil {
+		s.logger.Error(ctx, "[BehaviorRuleStore] 加载行为规则集失败, error:%v", err)
+	}
+
+	param := s.configParam()
+	param.Type = vo.JSON
+	param.OnChange = func(namespace, group, dataId, data string) {
+		if err := s.Load(data); err != nil {
+			s.logger.Error(ctx, "[BehaviorRuleStore] 行为规则集变更加载失败，保留当前规则集, error:%v", err)
+		}
+	}
+	if err := s.client.ListenConfig(param); err != nil {
+		s.logger.Error(ctx, "[BehaviorRuleStore] 监听行为规则集失败, error:%v", err)
+	}
+}
+
+// Load 加载规则集配置，整份配置校验通过后才生效
+// 同一学校学科的版本号只能递增，版本号不变时内容也不能变化；配置中不再包含的规则集会被移除
+func (s *BehaviorRuleStore) Load(content string) error {
+	document := &dto.BehaviorRuleDocument{}
+	if strings.TrimSpace(content) != "" {
+		if err := json.Unmarshal([]byte(content), document); err != nil {
+			return errors.Wrap(err, "解析行为规则集失败")
+		}
+	}
+
+	ruleSets := make(map[string]*dto.BehaviorRuleSet, len(document.RuleSets))
+	for _, ruleSet := range document.RuleSets {
+		if err := validateBehaviorRuleSet(ruleSet); err != nil {
+			return err
+		}
+		key := behaviorRuleSetKey(ruleSet.SchoolID, ruleSet.Subject)
+		if _, ok := ruleSets[key]; ok {
+			return errors.Errorf("规则集重复, schoolId:%d, subject:%s", ruleSet.SchoolID, ruleSet.Subject)
+		}
+		ruleSets[key] = ruleSet
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, ruleSet := range ruleSets {
+		current, ok := s.ruleSets[key]
+		if !ok {
+			continue
+		}
+		if ruleSet.Version < current.Version {
+			return errors.Errorf("规则集版本号不能回退, schoolId:%d, subject:%s, version:%d, current:%d",
+				ruleSet.SchoolID, ruleSet.Subject, ruleSet.Version, current.Version)
+		}
+		if ruleSet.Version == current.Version && !reflect.DeepEqual(ruleSet, current) {
+			return errors.Errorf("规则集内容变更但版本号未变更, schoolId:%d, subject:%s, version:%d",
+				ruleSet.SchoolID, ruleSet.Subject, ruleSet.Version)
+		}
+	}
+	for key, ruleSet := range ruleSets {
+		if current, ok := s.ruleSets[key]; !ok || current.Version != ruleSet.Version {
+			s.logger.Info(context.Background(), "[BehaviorRuleStore] 行为规则集生效, schoolId:%d, subject:%s, version:%d",
+				ruleSet.SchoolID, ruleSet.Subject, ruleSet.Version)
+		}
+	}
+	s.ruleSets = ruleSets
+	return nil
+}
+
+// Get 获取学校学科的规则集，依次查找学校+学科、学校、学科、全局规则集，都没有时使用内置默认规则集
+func (s *BehaviorRuleStore) Get(schoolID int64, subject string) *dto.BehaviorRuleSet {
+	if s == nil {
+		return defaultBehaviorRuleSet
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range []string{
+		behaviorRuleSetKey(schoolID, subject),
+		behaviorRuleSetKey(schoolID, ""),
+		behaviorRuleSetKey(0, subject),
+		behaviorRuleSetKey(0, ""),
+	} {
+		if ruleSet, ok := s.ruleSets[key]; ok {
+			return ruleSet
+		}
+	}
+	return defaultBehaviorRuleSet
+}
+
+func behaviorRuleSetKey(schoolID int64, subject string) string {
+	return fmt.Sprintf("%d%s%s", schoolID, consts.CombineKey, subject)
+}
@@ -0,0 +1,86 @@
+package behavior
+
+import (
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/model/dto"
+)
+
+func TestDefaultBehaviorRuleSet(t *testing.T) {
+	ruleSet := *defaultBehaviorRuleSet
+	ruleSet.Version = 1
+	assert.NoError(t, validateBehaviorRuleSet(&ruleSet))
+
+	// 连对 4 题且全部答对：基础 5 + 连对 4*5 + 正确率 10 + 答题 10
+	answer := newBehaviorRuleFacts(&dto.StudentLatestBehaviorDTO{
+		BehaviorType: consts.BehaviorTypeAnswer,
+		Context:      `{"correct_streak":4,"total_questions":5,"correct_answers":5}`,
+	})
+	assert.NotEmpty(t, answer.hits(ruleSet.PraiseChecks))
+	assert.Empty(t, answer.hits(ruleSet.AttentionChecks))
+	scores := answer.scoreTypes(ruleSet.PraiseTypes, praiseTagTypes)
+	assert.Equal(t, int64(45), scores[string(consts.BehaviorTagTypeCorrectStreak)])
+	assert.Equal(t, int64(5), scores[string(consts.BehaviorTagTypeQuestion)])
+	bestType, _ := bestRuleType(scores, praiseTagTypes, ruleSet.PraiseMinScore)
+	assert.Equal(t, string(consts.BehaviorTagTypeCorrectStreak), bestType)
+
+	// 学习时视频暂停 10 分钟：暂停 15 + 长时间停留 5 + 10 分钟 * 1
+	paused := newBehaviorRuleFacts(&dto.StudentLatestBehaviorDTO{
+		BehaviorType: consts.BehaviorTypeLearning,
+		VideoStatus:  "pause",
+		Context:      `{"stay_duration":600}`,
+	})
+	assert.Empty(t, paused.hits(ruleSet.PraiseChecks))
+	assert.Equal(t, "视频已暂停600秒", paused.hits(ruleSet.AttentionChecks)[0].Message)
+	scores = paused.scoreTypes(ruleSet.AttentionTypes, attentionTagTypes)
+	assert.Equal(t, int64(30), scores[string(consts.BehaviorTagTypePause)])
+
+	// 没有明显最佳类型时选择第一个可用类型
+	idle := newBehaviorRuleFacts(&dto.StudentLatestBehaviorDTO{})
+	scores = idle.scoreTypes(ruleSet.PraiseTypes, []string{string(consts.BehaviorTagTypeQuestion), string(consts.BehaviorTagTypeEarlyLearn)})
+	bestType, _ = bestRuleType(scores, []string{string(consts.BehaviorTagTypeQuestion), string(consts.BehaviorTagTypeEarlyLearn)}, ruleSet.PraiseMinScore)
+	assert.Equal(t, string(consts.BehaviorTagTypeQuestion), bestType)
+
+	tags := behaviorTags(&ruleSet, &dto.StudentBehaviorCategoryDTO{CorrectStreak: 4, PauseCount: 2})
+	assert.Equal(t, []dto.BehaviorTag{
+		{Type: string(consts.BehaviorTagTypeCorrectStreak), Count: 4, Text: "连续答对 4题"},
+		{Type: string(consts.BehaviorTagTypePause), Count: 2, Text: "长时间暂停 2次"},
+	}, tags)
+}
+
+func TestBehaviorRuleStoreLoad(t *testing.T) {
+	store := &BehaviorRuleStore{
+		logger:   clogger.NewContextLogger(log.DefaultLogger),
+		ruleSets: make(map[string]*dto.BehaviorRuleSet),
+	}
+	document := `{"ruleSets":[{"schoolId":1,"subject":"数学","version":2,
+		"praiseChecks":[{"name":"连对两题","conditions":[{"field":"correct_streak","op":"gte","value":2}]}],
+		"tags":[{"type":"question","field":"question_count","minCount":2,"template":"提问{question_count}次"}]}]}`
+	assert.NoError(t, store.Load(document))
+
+	ruleSet := store.Get(1, "数学")
+	assert.Equal(t, int64(2), ruleSet.Version)
+	assert.Same(t, defaultBehaviorRuleSet, store.Get(1, "语文"))
+	assert.Same(t, defaultBehaviorRuleSet, store.Get(2, "数学"))
+
+	facts := newBehaviorRuleFacts(&dto.StudentLatestBehaviorDTO{BehaviorType: consts.BehaviorTypeAnswer, Context: `{"correctStreak":2}`})
+	assert.Len(t, facts.hits(ruleSet.PraiseChecks), 1)
+	assert.Empty(t, behaviorTags(ruleSet, &dto.StudentBehaviorCategoryDTO{QuestionCount: 1}))
+
+	// 版本号回退、同版本内容变更和未知字段都不生效，保留当前规则集
+	assert.Error(t, store.Load(`{"ruleSets":[{"schoolId":1,"subject":"数学","version":1}]}`))
+	assert.Error(t, store.Load(`{"ruleSets":[{"schoolId":1,"subject":"数学","version":2}]}`))
+	assert.Error(t, store.Load(`{"ruleSets":[{"schoolId":1,"version":3,"praiseChecks":[{"name":"x","conditions":[{"field":"unknown","op":"eq","value":1}]}]}]}`))
+	assert.Same(t, ruleSet, store.Get(1, "数学"))
+
+	// 学校规则集对所有学科生效，配置中移除后恢复默认规则集
+	assert.NoError(t, store.Load(`{"ruleSets":[{"schoolId":1,"version":1}]}`))
+	assert.Equal(t, int64(1), store.Get(1, "语文").Version)
+	assert.NoError(t, store.Load(""))
+	assert.Same(t, defaultBehaviorRuleSet, store.Get(1, "数学"))
+}
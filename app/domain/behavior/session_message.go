@@ -3,6 +3,7 @@ package behavior
 import (
 	"context"
 	"math"
+	"time"
 
 	"gil_teacher/app/consts"
 	clogger "gil_teacher/app/core/logger"
@@ -10,9 +11,16 @@ import (
 	behaviorDao "gil_teacher/app/dao/behavior"
 	"gil_teacher/app/model/dto"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/pkg/errors"
 )
 
+// ErrSessionMessageNotFound 标记已读的消息不在会话中
+var ErrSessionMessageNotFound = errors.New("会话消息不存在")
+
+// 教师端只有教师查看会话，已读位置按教师记录
+const sessionReaderType = string(consts.CommunicationUserTypeTeacher)
+
 // 会话消息，包括创建会话、关闭会话、发送消息、接收消息、关闭会话、标记已读、获取未读消息数量、获取未读消息列表
 type SessionMessageHandler struct {
 	behaviorDAO behaviorDao.BehaviorDAO
@@ -51,6 +59,11 @@ func (h *SessionMessageHandler) GetAllMessageIDs(ctx context.Context, userID int
 		return nil, err
 	}
 
+	if err := h.loadSessionMessages(ctx, sessionID); err != nil {
+		h.logger.Error(ctx, "GetAllMessageIDs, load session messages error: %v", err)
+		return nil, err
+	}
+
 	var messageIDs []string
 	key := consts.GetSessionMessageKey(sessionID)
 	exists, err := h.redisClient.ZRange(ctx, key, 0, -1, &messageIDs)
@@ -64,13 +77,50 @@ func (h *SessionMessageHandler) GetAllMessageIDs(ctx context.Context, userID int
 	return messageIDs, nil
 }
 
-// 标记用户消息已读
+// 标记用户消息已读，已读位置同时写入缓存和持久化存储，只向后移动
 func (h *SessionMessageHandler) MarkMessageAsRead(ctx context.Context, userID int64, sessionID string, messageID string) error {
 	_, err := h.checkSessionPermission(ctx, userID, sessionID)
 	if err != nil {
 		h.logger.Error(ctx, "MarkMessageAsRead, check session permission error: %v", err)
 		return err
 	}
+	if err := h.loadSessionMessages(ctx, sessionID); err != nil {
+		h.logger.Error(ctx, "MarkMessageAsRead, load session messages error: %v", err)
+		return err
+	}
+
+	messageTimestamp := 0.0
+	exists, err := h.redisClient.ZScore(ctx, consts.GetSessionMessageKey(sessionID), messageID, &messageTimestamp)
+	if err != nil {
+		h.logger.Error(ctx, "MarkMessageAsRead, redis zscore error: %v", err)
+		return err
+	}
+	if !exists {
+		return ErrSessionMessageNotFound
+	}
+
+	cursor, err := h.userSessionReadCursor(ctx, userID, sessionID)
+	if err != nil {
+		h.logger.Error(ctx, "MarkMessageAsRead, get read cursor error: %v", err)
+		return err
+	}
+	readTime := time.Unix(int64(messageTimestamp), 0)
+	if !readCursorAdvanced(cursor, readTime) {
+		return nil
+	}
+
+	err = h.behaviorDAO.SaveCommunicationReadCursor(ctx, &dto.CommunicationReadCursorDTO{
+		SessionID:         sessionID,
+		UserID:            uint64(userID),
+		UserType:          sessionReaderType,
+		LastReadMessageID: messageID,
+		LastReadTime:      readTime,
+	})
+	if err != nil {
+		h.logger.Error(ctx, "MarkMessageAsRead, save read cursor error: %v", err)
+		return err
+	}
+
 	key := consts.GetSessionUserLastReadMessageKey(sessionID, userID)
 	err = h.redisClient.Set(ctx, key, messageID, consts.UserLastReadMessageExpire)
 	if err != nil {
@@ -80,35 +130,62 @@ func (h *SessionMessageHandler) MarkMessageAsRead(ctx context.Context, userID in
 	return nil
 }
 
-// 获取用户最后已读消息时间戳
-func (h *SessionMessageHandler) userSessionLastMessageTimestamp(ctx context.Context, userID int64, sessionID string) (float64, error) {
-	_, err := h.checkSessionPermission(ctx, userID, sessionID)
-	if err != nil {
-		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] check session permission error: %v", err)
-		return 0, err
-	}
-
+// 获取用户在会话中的已读位置，缓存过期时从持久化存储恢复，没有时返回 nil
+func (h *SessionMessageHandler) userSessionReadCursor(ctx context.Context, userID int64, sessionID string) (*dto.CommunicationReadCursorDTO, error) {
 	lastMessageID := ""
 	lastReadMessageKey := consts.GetSessionUserLastReadMessageKey(sessionID, userID) // 用户最后已读消息 key
 	exists, err := h.redisClient.Get(ctx, lastReadMessageKey, &lastMessageID)
 	if err != nil {
-		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] redis get error: %v", err)
-		return 0, err
+		return nil, errors.Wrap(err, "redis get last read message failed")
+	}
+	if exists {
+		lastReadMessageTimestamp := 0.0
+		found, err := h.redisClient.ZScore(ctx, consts.GetSessionMessageKey(sessionID), lastMessageID, &lastReadMessageTimestamp)
+		if err != nil {
+			return nil, errors.Wrap(err, "redis zscore last read message failed")
+		}
+		if found {
+			return &dto.CommunicationReadCursorDTO{
+				SessionID:         sessionID,
+				UserID:            uint64(userID),
+				UserType:          sessionReaderType,
+				LastReadMessageID: lastMessageID,
+				LastReadTime:      time.Unix(int64(lastReadMessageTimestamp), 0),
+			}, nil
+		}
 	}
 
-	if !exists {
-		return 0, nil
+	cursor, err := h.behaviorDAO.GetCommunicationReadCursor(ctx, sessionID, uint64(userID), sessionReaderType)
+	if err != nil {
+		return nil, err
 	}
+	if cursor == nil {
+		return nil, nil
+	}
+	if err := h.redisClient.Set(ctx, lastReadMessageKey, cursor.LastReadMessageID, consts.UserLastReadMessageExpire); err != nil {
+		h.logger.Warn(ctx, "userSessionReadCursor, redis set error: %v", err)
+	}
+	return cursor, nil
+}
 
-	messageSessionKey := consts.GetSessionMessageKey(sessionID)
-	lastReadMessageTimestamp := 0.0
-	_, err = h.redisClient.ZScore(ctx, messageSessionKey, lastMessageID, &lastReadMessageTimestamp)
+// 获取用户未读消息的起始时间戳，已读消息之后的消息都是未读
+func (h *SessionMessageHandler) userSessionLastMessageTimestamp(ctx context.Context, userID int64, sessionID string) (float64, error) {
+	_, err := h.checkSessionPermission(ctx, userID, sessionID)
 	if err != nil {
-		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] redis zscore error: %v", err)
+		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] check session permission error: %v", err)
+		return 0, err
+	}
+	if err := h.loadSessionMessages(ctx, sessionID); err != nil {
+		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] load session messages error: %v", err)
 		return 0, err
 	}
 
-	return lastReadMessageTimestamp, nil
+	cursor, err := h.userSessionReadCursor(ctx, userID, sessionID)
+	if err != nil {
+		h.logger.Error(ctx, "[GetUserLastReadMessageTimestamp] get read cursor error: %v", err)
+		return 0, err
+	}
+	return unreadMessageMinScore(cursor), nil
 }
 
 // 获取用户指定会话的未读消息数量
@@ -171,6 +248,86 @@ func (h *SessionMessageHandler) GetUnreadMessageList(ctx context.Context, userID
 	return messageList, nil
 }
 
+// 获取用户的收件箱，列出参与的全部会话和未读消息总数
+func (h *SessionMessageHandler) GetInbox(ctx context.Context, schoolID, userID int64, page, size int64) (*dto.CommunicationInboxDTO, error) {
+	inbox, err := h.behaviorDAO.GetCommunicationInbox(ctx, uint64(schoolID), uint64(userID), sessionReaderType,
+		&consts.DBPageInfo{Page: page, Limit: size})
+	if err != nil {
+		h.logger.Error(ctx, "[GetInbox] get communication inbox error: %v", err)
+		return nil, err
+	}
+	return inbox, nil
+}
+
+// 会话消息缓存不存在时从消息表重建
+func (h *SessionMessageHandler) loadSessionMessages(ctx context.Context, sessionID string) error {
+	key := consts.GetSessionMessageKey(sessionID)
+	exists, err := h.redisClient.KeyExists(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "redis check session messages failed")
+	}
+	if exists {
+		return nil
+	}
+
+	messages, err := h.behaviorDAO.GetCommunicationSessionMessageTimeline(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	if err := h.redisClient.ZAddBatch(ctx, key, sessionMessageMembers(messages), consts.SessionMessageExpire); err != nil {
+		return errors.Wrap(err, "redis rebuild session messages failed")
+	}
+	return nil
+}
+
+// 新消息入库后追加到已有的会话消息缓存，缓存不存在时等读取时整体重建，避免只缓存部分消息
+func appendSessionMessages(ctx context.Context, redisClient *dao.ApiRdbClient, messages []*dto.CommunicationMessageDTO) error {
+	sessionMessages := make(map[string][]*dto.CommunicationMessageDTO)
+	for _, message := range messages {
+		sessionMessages[message.SessionID] = append(sessionMessages[message.SessionID], message)
+	}
+
+	for sessionID, messages := range sessionMessages {
+		key := consts.GetSessionMessageKey(sessionID)
+		exists, err := redisClient.KeyExists(ctx, key)
+		if err != nil {
+			return errors.Wrap(err, "redis check session messages failed")
+		}
+		if !exists {
+			continue
+		}
+		if err := redisClient.ZAddBatch(ctx, key, sessionMessageMembers(messages), consts.SessionMessageExpire); err != nil {
+			return errors.Wrap(err, "redis append session messages failed")
+		}
+	}
+	return nil
+}
+
+// 会话消息缓存成员，score 为消息发送时间的秒数
+func sessionMessageMembers(messages []*dto.CommunicationMessageDTO) []*redis.Z {
+	members := make([]*redis.Z, 0, len(messages))
+	for _, message := range messages {
+		members = append(members, &redis.Z{Score: float64(message.CreatedAt.Unix()), Member: message.MessageID})
+	}
+	return members
+}
+
+// 消息时间只精确到秒，已读消息所在的这一秒内的消息都视为已读
+func unreadMessageMinScore(cursor *dto.CommunicationReadCursorDTO) float64 {
+	if cursor == nil {
+		return 0
+	}
+	return float64(cursor.LastReadTime.Unix() + 1)
+}
+
+// 已读位置只向后移动，先读到的新消息不会被之后标记的旧消息覆盖
+func readCursorAdvanced(cursor *dto.CommunicationReadCursorDTO, readTime time.Time) bool {
+	return cursor == nil || readTime.After(cursor.LastReadTime)
+}
+
 // 检查用户对会话的权限
 // 1. 会话是否存在
 // 2. 用户和会话创建者在同一班级：教师不做限制，因为可能会代课，学生需要限制
@@ -0,0 +1,68 @@
+package behavior
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/model/dto"
+)
+
+type stubInboxDAO struct {
+	behaviorDao.BehaviorDAO
+	schoolID, userID uint64
+	userType         string
+	pageInfo         *consts.DBPageInfo
+}
+
+func (d *stubInboxDAO) GetCommunicationInbox(ctx context.Context, schoolID, userID uint64, userType string, pageInfo *consts.DBPageInfo) (*dto.CommunicationInboxDTO, error) {
+	d.schoolID, d.userID, d.userType, d.pageInfo = schoolID, userID, userType, pageInfo
+	return &dto.CommunicationInboxDTO{Total: 1, UnreadCount: 3}, nil
+}
+
+func TestSessionMessageMembers(t *testing.T) {
+	createdAt := time.Unix(1700000000, 0)
+	members := sessionMessageMembers([]*dto.CommunicationMessageDTO{
+		{MessageID: "m1", SessionID: "s1", CreatedAt: createdAt},
+		{MessageID: "m2", SessionID: "s1", CreatedAt: createdAt.Add(1500 * time.Millisecond)},
+	})
+	require.Len(t, members, 2)
+	assert.Equal(t, "m1", members[0].Member)
+	assert.Equal(t, float64(1700000000), members[0].Score)
+	assert.Equal(t, float64(1700000001), members[1].Score)
+}
+
+func TestReadCursor(t *testing.T) {
+	readTime := time.Unix(1700000000, 0)
+	cursor := &dto.CommunicationReadCursorDTO{SessionID: "s1", LastReadMessageID: "m1", LastReadTime: readTime}
+
+	// 没有已读位置时全部消息都是未读，已读消息所在的这一秒视为已读
+	assert.Equal(t, float64(0), unreadMessageMinScore(nil))
+	assert.Equal(t, float64(1700000001), unreadMessageMinScore(cursor))
+
+	// 已读位置只向后移动
+	assert.True(t, readCursorAdvanced(nil, readTime))
+	assert.True(t, readCursorAdvanced(cursor, readTime.Add(time.Second)))
+	assert.False(t, readCursorAdvanced(cursor, readTime))
+	assert.False(t, readCursorAdvanced(cursor, readTime.Add(-time.Minute)))
+}
+
+func TestGetInbox(t *testing.T) {
+	behaviorDAO := &stubInboxDAO{}
+	h := NewSessionMessageHandler(behaviorDAO, nil, clogger.NewContextLogger(log.DefaultLogger))
+
+	inbox, err := h.GetInbox(context.Background(), 1, 9, 2, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), inbox.UnreadCount)
+	assert.Equal(t, uint64(1), behaviorDAO.schoolID)
+	assert.Equal(t, uint64(9), behaviorDAO.userID)
+	assert.Equal(t, string(consts.CommunicationUserTypeTeacher), behaviorDAO.userType)
+	assert.Equal(t, &consts.DBPageInfo{Page: 2, Limit: 20}, behaviorDAO.pageInfo)
+}
@@ -0,0 +1,181 @@
+package behavior
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	behaviorDao "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+)
+
+// StudentProfileHandler 学生跨课堂行为画像，汇总学生在一段时间内各节课的学情和行为，供班主任查看一周或一学期的趋势
+type StudentProfileHandler struct {
+	profileDAO behaviorDao.StudentProfileDAO
+	logger     *clogger.ContextLogger
+}
+
+func NewStudentProfileHandler(
+	profileDAO behaviorDao.StudentProfileDAO,
+	logger *clogger.ContextLogger,
+) *StudentProfileHandler {
+	return &StudentProfileHandler{
+		profileDAO: profileDAO,
+		logger:     logger,
+	}
+}
+
+// GetStudentProfile 查询学生在时间范围内的行为画像，请求需要先通过 Validate 转换时间范围
+func (h *StudentProfileHandler) GetStudentProfile(ctx context.Context, schoolID int64, req *api.StudentProfileRequest) (*api.StudentProfileResponse, error) {
+	query := &dto.StudentProfileQueryDTO{
+		SchoolID:  uint64(schoolID),
+		ClassID:   uint64(req.ClassID),
+		StudentID: uint64(req.StudentID),
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+	}
+
+	lessons, err := h.profileDAO.ListStudentLessonStats(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询学生课后学情失败")
+	}
+	actions, err := h.profileDAO.CountStudentTeacherActions(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "统计学生被表扬和关注次数失败")
+	}
+	behaviors, err := h.profileDAO.CountStudentBehaviors(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "统计学生行为失败")
+	}
+
+	profile := buildStudentProfile(req, lessons, actions, behaviors)
+	for i, lesson := range lessons {
+		var student api.ClassroomReportStudent
+		if err := json.Unmarshal([]byte(lesson.Summary), &student); err != nil {
+			h.logger.Warn(ctx, "[GetStudentProfile] 解析学生学情失败, reportID:%s, studentID:%d, error:%v", lesson.ReportID, req.StudentID, err)
+			continue
+		}
+		profile.Lessons[i].LearningTime = student.LearningTime
+		profile.Lessons[i].CorrectCount = student.CorrectCount
+		profile.Lessons[i].TotalCount = student.TotalCount
+		profile.Lessons[i].AccuracyRate = student.AccuracyRate
+	}
+	return profile, nil
+}
+
+// 汇总学生行为画像，时间范围内没有数据的日期补 0；课堂学情的详细内容由调用方从 summary 中补充
+func buildStudentProfile(
+	req *api.StudentProfileRequest,
+	lessons []*dto.StudentLessonStatsDTO,
+	actions []*dto.StudentTeacherActionCountDTO,
+	behaviors []*dto.StudentBehaviorDayCountDTO,
+) *api.StudentProfileResponse {
+	profile := &api.StudentProfileResponse{
+		StudentID:  req.StudentID,
+		ClassID:    req.ClassID,
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		Days:       make([]*api.StudentProfileDay, 0),
+		Lessons:    make([]*api.StudentProfileLesson, 0, len(lessons)),
+		Praises:    make([]*api.StudentProfileActionStat, 0),
+		Attentions: make([]*api.StudentProfileActionStat, 0),
+	}
+
+	days := make(map[string]*api.StudentProfileDay)
+	for day := req.StartTime.In(consts.LocationShanghai); day.Before(req.EndTime); day = day.AddDate(0, 0, 1) {
+		dayStat := &api.StudentProfileDay{Date: day.Format(consts.TimeFormatDate)}
+		days[dayStat.Date] = dayStat
+		profile.Days = append(profile.Days, dayStat)
+	}
+	// 查询结果的日期都在时间范围内，找不到时只计入汇总
+	stats := func(date string) []*api.StudentProfileStat {
+		if dayStat := days[date]; dayStat != nil {
+			return []*api.StudentProfileStat{&profile.Total, &dayStat.StudentProfileStat}
+		}
+		return []*api.StudentProfileStat{&profile.Total}
+	}
+
+	scores := make(map[*api.StudentProfileStat]int64)
+	for _, lesson := range lessons {
+		profile.Lessons = append(profile.Lessons, &api.StudentProfileLesson{
+			ReportID:      lesson.ReportID,
+			ClassroomID:   lesson.ClassroomID,
+			CourseID:      lesson.CourseID,
+			StartTime:     lesson.StartTime.Unix(),
+			LearningScore: lesson.LearningScore,
+		})
+		for _, stat := range stats(lesson.StartTime.In(consts.LocationShanghai).Format(consts.TimeFormatDate)) {
+			stat.LessonCount++
+			scores[stat] += lesson.LearningScore
+		}
+	}
+
+	praises := make(map[string]*api.StudentProfileActionStat)
+	attentions := make(map[string]*api.StudentProfileActionStat)
+	for _, action := range actions {
+		actionType := action.ActionType
+		switch action.BehaviorType {
+		case consts.BehaviorTypeTaskPraise, consts.BehaviorTypeTaskAttention:
+			actionType = consts.StudentProfileTaskActionType
+		}
+
+		switch action.BehaviorType {
+		case consts.BehaviorTypePraise, consts.BehaviorTypeTaskPraise:
+			for _, stat := range stats(action.Date) {
+				stat.PraiseCount += action.Count
+			}
+			if praises[actionType] == nil {
+				praises[actionType] = &api.StudentProfileActionStat{ActionType: actionType}
+				profile.Praises = append(profile.Praises, praises[actionType])
+			}
+			praises[actionType].Count += action.Count
+		case consts.BehaviorTypeAttention, consts.BehaviorTypeTaskAttention:
+			for _, stat := range stats(action.Date) {
+				stat.AttentionCount += action.Count
+			}
+			if attentions[actionType] == nil {
+				attentions[actionType] = &api.StudentProfileActionStat{ActionType: actionType}
+				profile.Attentions = append(profile.Attentions, attentions[actionType])
+			}
+			attentions[actionType].Count += action.Count
+		}
+	}
+
+	for _, behavior := range behaviors {
+		for _, stat := range stats(behavior.Date) {
+			switch behavior.BehaviorType {
+			case consts.BehaviorTypeAnswer:
+				stat.AnswerCount += behavior.Count
+				stat.CorrectCount += behavior.CorrectCount
+			case consts.BehaviorTypeQuestion:
+				stat.QuestionCount += behavior.Count
+			}
+			stat.PageSwitchCount += behavior.PageSwitchCount
+			stat.OtherContentCount += behavior.OtherContentCount
+			stat.PauseCount += behavior.PauseCount
+		}
+	}
+
+	finish := func(stat *api.StudentProfileStat) {
+		stat.AvgLearningScore = utils.F64Div(float64(scores[stat]), float64(stat.LessonCount), 2)
+		stat.AccuracyRate = utils.F64Percent(float64(stat.CorrectCount), float64(stat.AnswerCount), 4)
+	}
+	finish(&profile.Total)
+	for _, dayStat := range profile.Days {
+		finish(&dayStat.StudentProfileStat)
+	}
+
+	sortActionStats := func(a, b *api.StudentProfileActionStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.ActionType, b.ActionType))
+	}
+	slices.SortFunc(profile.Praises, sortActionStats)
+	slices.SortFunc(profile.Attentions, sortActionStats)
+	return profile
+}
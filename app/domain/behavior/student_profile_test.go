@@ -0,0 +1,96 @@
+package behavior
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+)
+
+func TestBuildStudentProfile(t *testing.T) {
+	req := &api.StudentProfileRequest{StudentID: 9, ClassID: 3, StartDate: "2026-10-12", EndDate: "2026-10-14"}
+	require.NoError(t, req.Validate())
+
+	lessons := []*dto.StudentLessonStatsDTO{
+		{ReportID: "r1", ClassroomID: 11, StartTime: time.Date(2026, 10, 12, 8, 0, 0, 0, consts.LocationShanghai), LearningScore: 80},
+		{ReportID: "r2", ClassroomID: 12, StartTime: time.Date(2026, 10, 12, 9, 0, 0, 0, consts.LocationShanghai), LearningScore: 90},
+		// 北京时间 10-14 凌晨的课按北京时间日期统计
+		{ReportID: "r3", ClassroomID: 13, StartTime: time.Date(2026, 10, 13, 17, 0, 0, 0, time.UTC), LearningScore: 71},
+	}
+	actions := []*dto.StudentTeacherActionCountDTO{
+		{Date: "2026-10-12", BehaviorType: consts.BehaviorTypePraise, ActionType: "question", Count: 1},
+		{Date: "2026-10-13", BehaviorType: consts.BehaviorTypePraise, ActionType: "correctStreak", Count: 2},
+		{Date: "2026-10-13", BehaviorType: consts.BehaviorTypeTaskPraise, Count: 1},
+		{Date: "2026-10-14", BehaviorType: consts.BehaviorTypeAttention, ActionType: "pageSwitch", Count: 1},
+		{Date: "2026-10-14", BehaviorType: consts.BehaviorTypeTaskAttention, Count: 2},
+	}
+	behaviors := []*dto.StudentBehaviorDayCountDTO{
+		{Date: "2026-10-12", BehaviorType: consts.BehaviorTypeAnswer, Count: 4, CorrectCount: 3},
+		{Date: "2026-10-12", BehaviorType: consts.BehaviorTypeQuestion, Count: 2},
+		{Date: "2026-10-14", BehaviorType: consts.BehaviorTypeAnswer, Count: 2, CorrectCount: 0},
+		{Date: "2026-10-14", BehaviorType: consts.BehaviorTypeBrowse, Count: 5, PageSwitchCount: 3, OtherContentCount: 1, PauseCount: 2},
+	}
+
+	profile := buildStudentProfile(req, lessons, actions, behaviors)
+
+	// 汇总
+	assert.Equal(t, int64(3), profile.Total.LessonCount)
+	assert.Equal(t, 80.33, profile.Total.AvgLearningScore)
+	assert.Equal(t, int64(6), profile.Total.AnswerCount)
+	assert.Equal(t, int64(3), profile.Total.CorrectCount)
+	assert.Equal(t, float64(50), profile.Total.AccuracyRate)
+	assert.Equal(t, int64(2), profile.Total.QuestionCount)
+	assert.Equal(t, int64(4), profile.Total.PraiseCount)
+	assert.Equal(t, int64(3), profile.Total.AttentionCount)
+	assert.Equal(t, int64(3), profile.Total.PageSwitchCount)
+	assert.Equal(t, int64(1), profile.Total.OtherContentCount)
+	assert.Equal(t, int64(2), profile.Total.PauseCount)
+
+	// 按天趋势，没有数据的日期补 0
+	require.Len(t, profile.Days, 3)
+	assert.Equal(t, []string{"2026-10-12", "2026-10-13", "2026-10-14"},
+		[]string{profile.Days[0].Date, profile.Days[1].Date, profile.Days[2].Date})
+	assert.Equal(t, int64(2), profile.Days[0].LessonCount)
+	assert.Equal(t, float64(85), profile.Days[0].AvgLearningScore)
+	assert.Equal(t, float64(75), profile.Days[0].AccuracyRate)
+	assert.Equal(t, int64(0), profile.Days[1].LessonCount)
+	assert.Equal(t, float64(0), profile.Days[1].AccuracyRate)
+	assert.Equal(t, int64(3), profile.Days[1].PraiseCount)
+	assert.Equal(t, int64(1), profile.Days[2].LessonCount)
+	assert.Equal(t, float64(71), profile.Days[2].AvgLearningScore)
+	assert.Equal(t, float64(0), profile.Days[2].AccuracyRate)
+	assert.Equal(t, int64(3), profile.Days[2].PageSwitchCount)
+
+	// 按类型统计，次数相同时按类型排序，作业点赞和提醒归到 task
+	assert.Equal(t, []*api.StudentProfileActionStat{
+		{ActionType: "correctStreak", Count: 2},
+		{ActionType: "question", Count: 1},
+		{ActionType: consts.StudentProfileTaskActionType, Count: 1},
+	}, profile.Praises)
+	assert.Equal(t, []*api.StudentProfileActionStat{
+		{ActionType: consts.StudentProfileTaskActionType, Count: 2},
+		{ActionType: "pageSwitch", Count: 1},
+	}, profile.Attentions)
+
+	require.Len(t, profile.Lessons, 3)
+	assert.Equal(t, "r3", profile.Lessons[2].ReportID)
+	assert.Equal(t, int64(71), profile.Lessons[2].LearningScore)
+}
+
+func TestStudentProfileRequestValidate(t *testing.T) {
+	req := &api.StudentProfileRequest{StudentID: 9, ClassID: 3, EndDate: "2026-10-14"}
+	require.NoError(t, req.Validate())
+	assert.Equal(t, "2026-10-08", req.StartDate)
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, consts.LocationShanghai).Unix(), req.EndTime.Unix())
+
+	req = &api.StudentProfileRequest{StudentID: 9, ClassID: 3, StartDate: "2026-01-01", EndDate: "2026-10-14"}
+	assert.Error(t, req.Validate())
+
+	req = &api.StudentProfileRequest{ClassID: 3}
+	assert.Error(t, req.Validate())
+}
@@ -0,0 +1,254 @@
+package classroom
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/service/schedule"
+	"gil_teacher/app/utils/idtools"
+)
+
+// 课堂不在教师当前的课程表中，或教师没有开过该课堂
+var ErrClassroomNotFound = errors.New("课堂不存在")
+
+// 课堂当前状态不支持该操作，如未开始的课堂不能暂停、已结束的课堂不能再开始
+var ErrClassroomStatus = errors.New("当前课堂状态不支持该操作")
+
+// ClassroomHandler 课堂生命周期，教师开始、暂停、结束上课时维护 tbl_classroom 中的上课记录，并发送课堂生命周期事件
+type ClassroomHandler struct {
+	classroomDAO    dao_classroom.ClassroomDAO
+	scheduleService *schedule.ScheduleCacheService
+	kafkaClient     *kafka.KafkaProducerClient
+	redisClient     *dao.ApiRdbClient
+	logger          *clogger.ContextLogger
+	checkBehavior   bool
+}
+
+func NewClassroomHandler(
+	classroomDAO dao_classroom.ClassroomDAO,
+	scheduleService *schedule.ScheduleCacheService,
+	kafkaClient *kafka.KafkaProducerClient,
+	redisClient *dao.ApiRdbClient,
+	logger *clogger.ContextLogger,
+	cfg *conf.Config,
+) *ClassroomHandler {
+	return &ClassroomHandler{
+		classroomDAO:    classroomDAO,
+		scheduleService: scheduleService,
+		kafkaClient:     kafkaClient,
+		redisClient:     redisClient,
+		logger:          logger,
+		checkBehavior:   cfg.Classroom != nil && cfg.Classroom.CheckBehavior,
+	}
+}
+
+// Start 开始上课，暂停中的课堂继续上课，重复开始时返回当前课堂
+// 按教师缓存的课程表匹配当前这节课，记录计划上课时间和实际上课时间
+func (h *ClassroomHandler) Start(ctx context.Context, schoolID, teacherID, classroomID int64) (*dao_classroom.Classroom, error) {
+	now := time.Now()
+	lesson, err := h.scheduleService.FindLesson(ctx, schoolID, teacherID, classroomID, now)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课程表失败")
+	}
+
+	var classroom *dao_classroom.Classroom
+	if lesson != nil {
+		classroom, err = h.classroomDAO.GetOrCreate(ctx, &dao_classroom.Classroom{
+			ClassroomID:        classroomID,
+			TeacherID:          teacherID,
+			SchoolID:           schoolID,
+			ClassID:            lesson.Schedule.ClassID,
+			CourseID:           lesson.Schedule.ClassScheduleCourseID,
+			ScheduleID:         lesson.Schedule.ScheduleID,
+			TmpScheduleID:      lesson.Schedule.TmpScheduleID,
+			Status:             consts.ClassroomStatusNotStarted,
+			ScheduledStartTime: lesson.StartTime.Unix(),
+			ScheduledEndTime:   lesson.EndTime.Unix(),
+		})
+	} else {
+		// 拖堂时课程表中的课已经下课，暂停中的课堂仍可以继续上课
+		classroom, err = h.classroomDAO.GetLatest(ctx, classroomID, teacherID)
+		if classroom != nil && classroom.Status != consts.ClassroomStatusPaused && classroom.Status != consts.ClassroomStatusInProgress {
+			classroom = nil
+		}
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课堂失败")
+	}
+	if classroom == nil || classroom.TeacherID != teacherID || classroom.SchoolID != schoolID {
+		return nil, ErrClassroomNotFound
+	}
+	return h.change(ctx, classroom, consts.ClassroomStatusInProgress, now)
+}
+
+// Pause 暂停上课，重复暂停时返回当前课堂
+func (h *ClassroomHandler) Pause(ctx context.Context, schoolID, teacherID, classroomID int64) (*dao_classroom.Classroom, error) {
+	classroom, err := h.getLatest(ctx, schoolID, teacherID, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	return h.change(ctx, classroom, consts.ClassroomStatusPaused, time.Now())
+}
+
+// End 结束上课，暂停中的课堂结束时累计暂停时长，重复结束时返回当前课堂
+func (h *ClassroomHandler) End(ctx context.Context, schoolID, teacherID, classroomID int64) (*dao_classroom.Classroom, error) {
+	classroom, err := h.getLatest(ctx, schoolID, teacherID, classroomID)
+	if err != nil {
+		return nil, err
+	}
+	return h.change(ctx, classroom, consts.ClassroomStatusEnded, time.Now())
+}
+
+// CheckTeacherClassroom 检查课堂是否由教师开过课，校验通过的结果缓存在 redis 中
+func (h *ClassroomHandler) CheckTeacherClassroom(ctx context.Context, schoolID, teacherID, classroomID int64) (bool, error) {
+	key := consts.GetClassroomOwnerKey(classroomID, teacherID)
+	if exists, err := h.redisClient.KeyExists(ctx, key); err == nil && exists {
+		return true, nil
+	}
+
+	exists, err := h.classroomDAO.ExistsForTeacher(ctx, classroomID, teacherID, schoolID)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		if err := h.redisClient.Set(ctx, key, 1, consts.ClassroomOwnerExpire); err != nil {
+			h.logger.Warn(ctx, "[CheckTeacherClassroom] 缓存课堂归属失败, classroomID:%d, teacherID:%d, error:%v", classroomID, teacherID, err)
+		}
+	}
+	return exists, nil
+}
+
+// CheckBehaviorClassroom 行为接口校验课堂归属，未开启校验时直接通过
+func (h *ClassroomHandler) CheckBehaviorClassroom(ctx context.Context, schoolID, teacherID, classroomID int64) (bool, error) {
+	if !h.checkBehavior {
+		return true, nil
+	}
+	return h.CheckTeacherClassroom(ctx, schoolID, teacherID, classroomID)
+}
+
+// GetClassID 查询课堂所属的班级，没有开课记录的课堂返回 0
+func (h *ClassroomHandler) GetClassID(ctx context.Context, classroomID int64) (int64, error) {
+	classIDs, err := h.classroomDAO.GetClassIDs(ctx, []int64{classroomID})
+	if err != nil {
+		return 0, errors.Wrap(err, "查询课堂班级失败")
+	}
+	return classIDs[classroomID], nil
+}
+
+func (h *ClassroomHandler) getLatest(ctx context.Context, schoolID, teacherID, classroomID int64) (*dao_classroom.Classroom, error) {
+	classroom, err := h.classroomDAO.GetLatest(ctx, classroomID, teacherID)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课堂失败")
+	}
+	if classroom == nil || classroom.SchoolID != schoolID {
+		return nil, ErrClassroomNotFound
+	}
+	return classroom, nil
+}
+
+// 按目标状态变更课堂，状态已被其他请求修改时以最新状态重新判断一次
+func (h *ClassroomHandler) change(ctx context.Context, classroom *dao_classroom.Classroom, status int64, now time.Time) (*dao_classroom.Classroom, error) {
+	next, event, err := nextClassroom(classroom, status, now.Unix())
+	if err != nil || event == "" {
+		return next, err
+	}
+
+	ok, err := h.classroomDAO.Transition(ctx, classroom.ID, classroom.Status, map[string]any{
+		"status":            next.Status,
+		"actual_start_time": next.ActualStartTime,
+		"actual_end_time":   next.ActualEndTime,
+		"paused_at":         next.PausedAt,
+		"paused_duration":   next.PausedDuration,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "变更课堂状态失败")
+	}
+	if !ok {
+		latest, err := h.classroomDAO.GetLatest(ctx, classroom.ClassroomID, classroom.TeacherID)
+		if err != nil {
+			return nil, errors.Wrap(err, "查询课堂失败")
+		}
+		if latest == nil || latest.ID != classroom.ID || latest.Status != status {
+			return nil, ErrClassroomStatus
+		}
+		return latest, nil
+	}
+
+	h.sendLifecycleEvent(ctx, next, event, now)
+	return next, nil
+}
+
+// 生命周期事件只用于下游统计，发送失败不影响上课
+func (h *ClassroomHandler) sendLifecycleEvent(ctx context.Context, classroom *dao_classroom.Classroom, event consts.ClassroomLifecycleEvent, now time.Time) {
+	content, err := json.Marshal(&dto.ClassroomLifecycleEventDTO{
+		EventID:            idtools.GetUUID(),
+		Event:              event,
+		ClassroomID:        classroom.ClassroomID,
+		SchoolID:           classroom.SchoolID,
+		ClassID:            classroom.ClassID,
+		CourseID:           classroom.CourseID,
+		TeacherID:          classroom.TeacherID,
+		Status:             classroom.Status,
+		ScheduledStartTime: classroom.ScheduledStartTime,
+		ScheduledEndTime:   classroom.ScheduledEndTime,
+		ActualStartTime:    classroom.ActualStartTime,
+		ActualEndTime:      classroom.ActualEndTime,
+		PausedDuration:     classroom.PausedDuration,
+		EventTime:          now.Unix(),
+	})
+	if err != nil {
+		h.logger.Error(ctx, "[sendLifecycleEvent] 序列化课堂事件失败, classroomID:%d, error:%v", classroom.ClassroomID, err)
+		return
+	}
+
+	// 按课堂分区，保证同一课堂的事件按发生顺序消费
+	key := strconv.FormatInt(classroom.ClassroomID, 10)
+	if err := h.kafkaClient.ProduceKeyedMsgToKafka(ctx, consts.KafkaTopicClassroomLifecycle, key, string(content)); err != nil {
+		h.logger.Error(ctx, "[sendLifecycleEvent] 发送课堂事件失败, classroomID:%d, event:%s, error:%v", classroom.ClassroomID, event, err)
+	}
+}
+
+// 计算课堂变更到目标状态后的记录和对应的生命周期事件，已处于目标状态时事件为空
+func nextClassroom(classroom *dao_classroom.Classroom, status int64, now int64) (*dao_classroom.Classroom, consts.ClassroomLifecycleEvent, error) {
+	if classroom.Status == status {
+		return classroom, "", nil
+	}
+
+	next := *classroom
+	next.Status = status
+	var event consts.ClassroomLifecycleEvent
+	switch {
+	case status == consts.ClassroomStatusInProgress && classroom.Status == consts.ClassroomStatusNotStarted:
+		event = consts.ClassroomLifecycleStart
+		next.ActualStartTime = now
+	case status == consts.ClassroomStatusInProgress && classroom.Status == consts.ClassroomStatusPaused:
+		event = consts.ClassroomLifecycleResume
+		next.PausedDuration += max(now-classroom.PausedAt, 0)
+		next.PausedAt = 0
+	case status == consts.ClassroomStatusPaused && classroom.Status == consts.ClassroomStatusInProgress:
+		event = consts.ClassroomLifecyclePause
+		next.PausedAt = now
+	case status == consts.ClassroomStatusEnded && classroom.Status == consts.ClassroomStatusInProgress:
+		event = consts.ClassroomLifecycleEnd
+		next.ActualEndTime = now
+	case status == consts.ClassroomStatusEnded && classroom.Status == consts.ClassroomStatusPaused:
+		event = consts.ClassroomLifecycleEnd
+		next.ActualEndTime = now
+		next.PausedDuration += max(now-classroom.PausedAt, 0)
+		next.PausedAt = 0
+	default:
+		return nil, "", ErrClassroomStatus
+	}
+	return &next, event, nil
+}
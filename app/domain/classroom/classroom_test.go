@@ -0,0 +1,56 @@
+package classroom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	dao_classroom "gil_teacher/app/dao/classroom"
+)
+
+func TestNextClassroom(t *testing.T) {
+	classroom := &dao_classroom.Classroom{ID: 1, ClassroomID: 12, Status: consts.ClassroomStatusNotStarted}
+
+	// 开始上课记录实际上课时间
+	started, event, err := nextClassroom(classroom, consts.ClassroomStatusInProgress, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.ClassroomLifecycleStart, event)
+	assert.Equal(t, int64(1000), started.ActualStartTime)
+	assert.Equal(t, consts.ClassroomStatusNotStarted, classroom.Status)
+
+	// 重复开始不产生事件
+	same, event, err := nextClassroom(started, consts.ClassroomStatusInProgress, 1100)
+	assert.NoError(t, err)
+	assert.Empty(t, event)
+	assert.Equal(t, started, same)
+
+	// 暂停后继续上课累计暂停时长
+	paused, event, err := nextClassroom(started, consts.ClassroomStatusPaused, 1200)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.ClassroomLifecyclePause, event)
+	assert.Equal(t, int64(1200), paused.PausedAt)
+
+	resumed, event, err := nextClassroom(paused, consts.ClassroomStatusInProgress, 1500)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.ClassroomLifecycleResume, event)
+	assert.Equal(t, int64(300), resumed.PausedDuration)
+	assert.Zero(t, resumed.PausedAt)
+	assert.Equal(t, int64(1000), resumed.ActualStartTime)
+
+	// 暂停中结束上课同样累计暂停时长
+	paused, _, _ = nextClassroom(resumed, consts.ClassroomStatusPaused, 2000)
+	ended, event, err := nextClassroom(paused, consts.ClassroomStatusEnded, 2100)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.ClassroomLifecycleEnd, event)
+	assert.Equal(t, int64(2100), ended.ActualEndTime)
+	assert.Equal(t, int64(400), ended.PausedDuration)
+
+	// 已结束的课堂不能再开始，未开始的课堂不能暂停或结束
+	_, _, err = nextClassroom(ended, consts.ClassroomStatusInProgress, 2200)
+	assert.ErrorIs(t, err, ErrClassroomStatus)
+	_, _, err = nextClassroom(classroom, consts.ClassroomStatusPaused, 2200)
+	assert.ErrorIs(t, err, ErrClassroomStatus)
+	_, _, err = nextClassroom(classroom, consts.ClassroomStatusEnded, 2200)
+	assert.ErrorIs(t, err, ErrClassroomStatus)
+}
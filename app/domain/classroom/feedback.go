@@ -0,0 +1,179 @@
+package classroom
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	"gil_teacher/app/model/api"
+)
+
+// 反馈不存在或不属于当前学校
+var ErrClassroomFeedbackNotFound = errors.New("课堂反馈不存在")
+
+// ClassroomFeedbackHandler 课堂反馈，学生和教师提交反馈，教师按班级查看、汇总和处理反馈
+type ClassroomFeedbackHandler struct {
+	feedbackDAO dao_classroom.ClassroomFeedbackDAO
+	logger      *clogger.ContextLogger
+}
+
+func NewClassroomFeedbackHandler(
+	feedbackDAO dao_classroom.ClassroomFeedbackDAO,
+	logger *clogger.ContextLogger,
+) *ClassroomFeedbackHandler {
+	return &ClassroomFeedbackHandler{
+		feedbackDAO: feedbackDAO,
+		logger:      logger,
+	}
+}
+
+// Submit 提交课堂反馈，内容审核在提交后异步进行，审核完成前反馈正常展示
+func (h *ClassroomFeedbackHandler) Submit(ctx context.Context, userID, schoolID, createType int64, req *api.SubmitClassroomFeedbackRequest) (*dao_classroom.ClassroomFeedback, error) {
+	feedback := &dao_classroom.ClassroomFeedback{
+		UserID:      userID,
+		SchoolID:    schoolID,
+		ClassID:     req.ClassID,
+		ClassroomID: req.ClassroomID,
+		Content:     req.Content,
+		Remark:      req.Remark,
+		CreateType:  createType,
+		Status:      consts.ClassroomFeedbackStatusPending,
+	}
+	if err := h.feedbackDAO.Create(ctx, feedback); err != nil {
+		return nil, errors.Wrap(err, "创建课堂反馈失败")
+	}
+	return feedback, nil
+}
+
+// List 按条件分页查询班级的课堂反馈
+func (h *ClassroomFeedbackHandler) List(ctx context.Context, schoolID int64, req *api.ClassroomFeedbackListRequest) (*api.ClassroomFeedbackListResponse, error) {
+	feedbacks, total, err := h.feedbackDAO.List(ctx, feedbackQuery(schoolID, &req.ClassroomFeedbackFilter), req.Page, req.PageSize)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课堂反馈失败")
+	}
+	return &api.ClassroomFeedbackListResponse{
+		List:     feedbacks,
+		PageInfo: &consts.ApiPageResponse{Page: req.Page, PageSize: req.PageSize, Total: total},
+	}, nil
+}
+
+// Stats 汇总班级在时间范围内的课堂反馈
+func (h *ClassroomFeedbackHandler) Stats(ctx context.Context, schoolID int64, filter *api.ClassroomFeedbackFilter) (*api.ClassroomFeedbackStatsResponse, error) {
+	counts, err := h.feedbackDAO.CountByDay(ctx, feedbackQuery(schoolID, filter))
+	if err != nil {
+		return nil, errors.Wrap(err, "统计课堂反馈失败")
+	}
+	return buildFeedbackStats(filter, counts), nil
+}
+
+// GetFeedbackClassIDs 查询反馈所属的班级，用于校验教师权限，有反馈不存在时返回 ErrClassroomFeedbackNotFound
+func (h *ClassroomFeedbackHandler) GetFeedbackClassIDs(ctx context.Context, schoolID int64, ids []int64) ([]int64, error) {
+	feedbacks, err := h.feedbackDAO.GetByIDs(ctx, schoolID, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询课堂反馈失败")
+	}
+
+	found := make([]int64, 0, len(feedbacks))
+	classIDs := make([]int64, 0, len(feedbacks))
+	for _, feedback := range feedbacks {
+		found = append(found, feedback.ID)
+		if !slices.Contains(classIDs, feedback.ClassID) {
+			classIDs = append(classIDs, feedback.ClassID)
+		}
+	}
+	for _, id := range ids {
+		if !slices.Contains(found, id) {
+			return nil, ErrClassroomFeedbackNotFound
+		}
+	}
+	return classIDs, nil
+}
+
+// Handle 将反馈标记为已处理，已处理的反馈不重复标记，返回本次标记的数量
+func (h *ClassroomFeedbackHandler) Handle(ctx context.Context, schoolID, teacherID int64, ids []int64) (int64, error) {
+	count, err := h.feedbackDAO.MarkHandled(ctx, schoolID, ids, teacherID, time.Now().Unix())
+	if err != nil {
+		return 0, errors.Wrap(err, "标记课堂反馈已处理失败")
+	}
+	return count, nil
+}
+
+func feedbackQuery(schoolID int64, filter *api.ClassroomFeedbackFilter) *dao_classroom.ClassroomFeedbackQuery {
+	return &dao_classroom.ClassroomFeedbackQuery{
+		SchoolID:    schoolID,
+		ClassID:     filter.ClassID,
+		ClassroomID: filter.ClassroomID,
+		CreateType:  filter.CreateType,
+		Status:      filter.Status,
+		StartTime:   filter.StartTime,
+		EndTime:     filter.EndTime,
+	}
+}
+
+// 汇总按天分组的反馈数，时间范围内没有反馈的日期补 0
+func buildFeedbackStats(filter *api.ClassroomFeedbackFilter, counts []*dao_classroom.ClassroomFeedbackCount) *api.ClassroomFeedbackStatsResponse {
+	stats := &api.ClassroomFeedbackStatsResponse{
+		ClassID:   filter.ClassID,
+		StartDate: filter.StartDate,
+		EndDate:   filter.EndDate,
+		Remarks:   make([]*api.ClassroomFeedbackRemarkStat, 0),
+		Days:      make([]*api.ClassroomFeedbackDayStat, 0),
+	}
+
+	days := make(map[string]*api.ClassroomFeedbackDayStat)
+	for day := time.Unix(filter.StartTime, 0).In(consts.LocationShanghai); day.Unix() < filter.EndTime; day = day.AddDate(0, 0, 1) {
+		dayStat := &api.ClassroomFeedbackDayStat{Date: day.Format(consts.TimeFormatDate)}
+		days[dayStat.Date] = dayStat
+		stats.Days = append(stats.Days, dayStat)
+	}
+
+	remarks := make(map[int64]*api.ClassroomFeedbackRemarkStat)
+	for _, count := range counts {
+		stats.Total += count.Count
+		dayStat := days[count.Date]
+		if dayStat != nil {
+			dayStat.Total += count.Count
+		}
+
+		switch count.CreateType {
+		case consts.ClassroomFeedbackCreateTypeStudent:
+			stats.StudentCount += count.Count
+			if dayStat != nil {
+				dayStat.StudentCount += count.Count
+			}
+		case consts.ClassroomFeedbackCreateTypeTeacher:
+			stats.TeacherCount += count.Count
+			if dayStat != nil {
+				dayStat.TeacherCount += count.Count
+			}
+		}
+
+		if count.Status == consts.ClassroomFeedbackStatusHandled {
+			stats.HandledCount += count.Count
+		} else {
+			stats.PendingCount += count.Count
+			if dayStat != nil {
+				dayStat.PendingCount += count.Count
+			}
+		}
+
+		if count.Remark > 0 {
+			if remarks[count.Remark] == nil {
+				remarks[count.Remark] = &api.ClassroomFeedbackRemarkStat{Remark: count.Remark}
+				stats.Remarks = append(stats.Remarks, remarks[count.Remark])
+			}
+			remarks[count.Remark].Count += count.Count
+		}
+	}
+
+	slices.SortFunc(stats.Remarks, func(a, b *api.ClassroomFeedbackRemarkStat) int {
+		return cmp.Compare(a.Remark, b.Remark)
+	})
+	return stats
+}
@@ -0,0 +1,52 @@
+package classroom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	dao_classroom "gil_teacher/app/dao/classroom"
+	"gil_teacher/app/model/api"
+)
+
+func TestBuildFeedbackStats(t *testing.T) {
+	filter := &api.ClassroomFeedbackFilter{ClassID: 3, StartDate: "2026-10-12", EndDate: "2026-10-14"}
+	assert.NoError(t, filter.Validate())
+
+	counts := []*dao_classroom.ClassroomFeedbackCount{
+		{Date: "2026-10-12", CreateType: consts.ClassroomFeedbackCreateTypeStudent, Status: consts.ClassroomFeedbackStatusPending, Remark: 2, Count: 3},
+		{Date: "2026-10-12", CreateType: consts.ClassroomFeedbackCreateTypeTeacher, Status: consts.ClassroomFeedbackStatusHandled, Count: 1},
+		{Date: "2026-10-14", CreateType: consts.ClassroomFeedbackCreateTypeStudent, Status: consts.ClassroomFeedbackStatusHandled, Remark: 1, Count: 2},
+		{Date: "2026-10-14", CreateType: consts.ClassroomFeedbackCreateTypeStudent, Status: consts.ClassroomFeedbackStatusPending, Remark: 2, Count: 1},
+	}
+	stats := buildFeedbackStats(filter, counts)
+
+	assert.Equal(t, int64(7), stats.Total)
+	assert.Equal(t, int64(6), stats.StudentCount)
+	assert.Equal(t, int64(1), stats.TeacherCount)
+	assert.Equal(t, int64(4), stats.PendingCount)
+	assert.Equal(t, int64(3), stats.HandledCount)
+
+	// 未选择备注标识的反馈不计入备注统计，按标识排序
+	assert.Equal(t, []*api.ClassroomFeedbackRemarkStat{{Remark: 1, Count: 2}, {Remark: 2, Count: 4}}, stats.Remarks)
+
+	// 没有反馈的日期补 0
+	assert.Len(t, stats.Days, 3)
+	assert.Equal(t, "2026-10-13", stats.Days[1].Date)
+	assert.Zero(t, stats.Days[1].Total)
+	assert.Equal(t, api.ClassroomFeedbackDayStat{Date: "2026-10-14", Total: 3, StudentCount: 3, PendingCount: 1}, *stats.Days[2])
+}
+
+func TestClassroomFeedbackFilterValidate(t *testing.T) {
+	filter := &api.ClassroomFeedbackFilter{ClassID: 3, EndDate: "2026-10-14"}
+	assert.NoError(t, filter.Validate())
+	// 默认查询结束日期前 7 天，结束时间为结束日期次日零点
+	assert.Equal(t, "2026-10-08", filter.StartDate)
+	assert.Equal(t, int64(7*24*3600), filter.EndTime-filter.StartTime)
+
+	assert.Error(t, (&api.ClassroomFeedbackFilter{ClassID: 3, StartDate: "2026-10-15", EndDate: "2026-10-14"}).Validate())
+	assert.Error(t, (&api.ClassroomFeedbackFilter{ClassID: 3, StartDate: "2026-01-01", EndDate: "2026-10-14"}).Validate())
+	assert.Error(t, (&api.ClassroomFeedbackFilter{ClassID: 3, CreateType: 3}).Validate())
+	assert.Error(t, (&api.ClassroomFeedbackFilter{StartDate: "2026-10-12"}).Validate())
+}
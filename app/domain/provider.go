@@ -2,6 +2,8 @@ package domain
 
 import (
 	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/domain/classroom"
+	"gil_teacher/app/domain/push"
 	"gil_teacher/app/domain/task"
 
 	"github.com/google/wire"
@@ -9,7 +11,23 @@ import (
 
 var DomainProviderSet = wire.NewSet(
 	behavior.NewBehaviorHandler,
+	behavior.NewBehaviorRuleStore,
 	behavior.NewBehaviorProducer,
 	behavior.NewSessionMessageHandler,
+	behavior.NewClassroomReportHandler,
+	behavior.NewStudentProfileHandler,
+	behavior.NewAITutor,
+	behavior.NewContentModerator,
+	behavior.NewMessageSearcher,
+	classroom.NewClassroomHandler,
+	classroom.NewClassroomFeedbackHandler,
+	push.NewPushPublisher,
+	push.NewPushGateway,
 	task.NewTaskReportHandler,
+	task.NewTaskReportAggregator,
+	task.NewClassErrorBookCollector,
+	task.NewClassErrorBookHandler,
+	task.NewAnswerCardHandler,
+	task.NewTaskExportJobHandler,
+	task.NewTaskScheduleHandler,
 )
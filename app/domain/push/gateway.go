@@ -0,0 +1,179 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/model/dto"
+)
+
+// PushConn 一个推送连接，按用户注册，指定课堂时同时接收课堂广播
+type PushConn struct {
+	userKey     string
+	classroomID int64
+	messages    chan *dto.PushMessage
+}
+
+// Messages 待发送给客户端的消息
+func (c *PushConn) Messages() <-chan *dto.PushMessage {
+	return c.messages
+}
+
+// 指定课堂的连接不接收其他课堂的消息
+func (c *PushConn) accepts(message *dto.PushMessage) bool {
+	return c.classroomID == 0 || message.ClassroomID == 0 || c.classroomID == message.ClassroomID
+}
+
+// PushGateway 实时推送网关，维护本实例的推送连接
+// 订阅 Redis pub/sub 频道，每个实例都会收到全部消息，只投递给本实例上的连接
+type PushGateway struct {
+	publisher   *PushPublisher
+	redisClient *dao.ApiRdbClient
+	logger      *clogger.ContextLogger
+
+	mu         sync.RWMutex
+	users      map[string]map[*PushConn]struct{}
+	classrooms map[int64]map[*PushConn]struct{}
+
+	pubsub *redis.PubSub
+	wg     sync.WaitGroup
+}
+
+func NewPushGateway(
+	publisher *PushPublisher,
+	redisClient *dao.ApiRdbClient,
+	logger *clogger.ContextLogger,
+) (*PushGateway, func()) {
+	g := &PushGateway{
+		publisher:   publisher,
+		redisClient: redisClient,
+		logger:      logger,
+		users:       make(map[string]map[*PushConn]struct{}),
+		classrooms:  make(map[int64]map[*PushConn]struct{}),
+		pubsub:      redisClient.Subscribe(context.Background(), consts.PushChannel),
+	}
+
+	g.wg.Add(1)
+	go g.receive()
+
+	cleanup := func() {
+		g.pubsub.Close()
+		g.wg.Wait()
+	}
+	return g, cleanup
+}
+
+// 接收频道消息，订阅断开后由客户端自动重连，关闭订阅后退出
+func (g *PushGateway) receive() {
+	defer g.wg.Done()
+
+	ctx := context.Background()
+	for msg := range g.pubsub.Channel() {
+		message := &dto.PushMessage{}
+		if err := json.Unmarshal([]byte(msg.Payload), message); err != nil {
+			g.logger.Warn(ctx, "[receive] 解析推送消息失败, payload:%s, error:%v", msg.Payload, err)
+			continue
+		}
+		g.dispatch(ctx, message)
+	}
+}
+
+// 投递给本实例上的连接，连接的待发送消息已满时丢弃，由客户端重连后从离线消息补发
+func (g *PushGateway) dispatch(ctx context.Context, message *dto.PushMessage) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	var conns map[*PushConn]struct{}
+	if message.UserID > 0 {
+		conns = g.users[userKey(message.UserType, message.UserID)]
+	} else {
+		conns = g.classrooms[message.ClassroomID]
+	}
+	for conn := range conns {
+		if !conn.accepts(message) {
+			continue
+		}
+		select {
+		case conn.messages <- message:
+		default:
+			g.logger.Warn(ctx, "[dispatch] 推送连接繁忙，丢弃消息, user:%s, seq:%d, type:%s", conn.userKey, message.Seq, message.Type)
+		}
+	}
+}
+
+// Connect 注册推送连接，返回连接和序号大于 lastSeq 的未确认消息
+// 先注册再查询离线消息，两者之间发布的消息可能重复，由调用方按序号去重
+func (g *PushGateway) Connect(ctx context.Context, userType consts.CommunicationUserType, userID, classroomID, lastSeq int64) (*PushConn, []*dto.PushMessage, error) {
+	conn := &PushConn{
+		userKey:     userKey(userType, userID),
+		classroomID: classroomID,
+		messages:    make(chan *dto.PushMessage, consts.PushConnBufferSize),
+	}
+
+	g.mu.Lock()
+	addConn(g.users, conn.userKey, conn)
+	if classroomID > 0 {
+		addConn(g.classrooms, classroomID, conn)
+	}
+	g.mu.Unlock()
+
+	messages, err := g.publisher.Pending(ctx, userType, userID, lastSeq)
+	if err != nil {
+		g.Disconnect(conn)
+		return nil, nil, err
+	}
+	pending := make([]*dto.PushMessage, 0, len(messages))
+	for _, message := range messages {
+		if conn.accepts(message) {
+			pending = append(pending, message)
+		}
+	}
+	return conn, pending, nil
+}
+
+// Disconnect 注销推送连接
+func (g *PushGateway) Disconnect(conn *PushConn) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	removeConn(g.users, conn.userKey, conn)
+	if conn.classroomID > 0 {
+		removeConn(g.classrooms, conn.classroomID, conn)
+	}
+}
+
+// Ack 确认用户序号不大于 seq 的全部消息
+func (g *PushGateway) Ack(ctx context.Context, userType consts.CommunicationUserType, userID, seq int64) error {
+	return g.publisher.Ack(ctx, userType, userID, seq)
+}
+
+func userKey(userType consts.CommunicationUserType, userID int64) string {
+	return fmt.Sprintf("%s%s%d", userType, consts.CombineKey, userID)
+}
+
+func addConn[K comparable](index map[K]map[*PushConn]struct{}, key K, conn *PushConn) {
+	conns, ok := index[key]
+	if !ok {
+		conns = make(map[*PushConn]struct{})
+		index[key] = conns
+	}
+	conns[conn] = struct{}{}
+}
+
+func removeConn[K comparable](index map[K]map[*PushConn]struct{}, key K, conn *PushConn) {
+	conns, ok := index[key]
+	if !ok {
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(index, key)
+	}
+}
@@ -0,0 +1,63 @@
+package push
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+func TestPushGatewayDispatch(t *testing.T) {
+	g := &PushGateway{
+		users:      make(map[string]map[*PushConn]struct{}),
+		classrooms: make(map[int64]map[*PushConn]struct{}),
+	}
+	newConn := func(userType consts.CommunicationUserType, userID, classroomID int64) *PushConn {
+		conn := &PushConn{
+			userKey:     userKey(userType, userID),
+			classroomID: classroomID,
+			messages:    make(chan *dto.PushMessage, 1),
+		}
+		addConn(g.users, conn.userKey, conn)
+		if classroomID > 0 {
+			addConn(g.classrooms, classroomID, conn)
+		}
+		return conn
+	}
+	student := newConn(consts.CommunicationUserTypeStudent, 1, 100)
+	otherClassroom := newConn(consts.CommunicationUserTypeStudent, 1, 200)
+	anyClassroom := newConn(consts.CommunicationUserTypeStudent, 1, 0)
+	teacher := newConn(consts.CommunicationUserTypeTeacher, 1, 100)
+
+	// 用户消息只投递给该用户，指定课堂的连接不接收其他课堂的消息
+	g.dispatch(context.Background(), &dto.PushMessage{
+		Seq:         1,
+		UserType:    consts.CommunicationUserTypeStudent,
+		UserID:      1,
+		ClassroomID: 100,
+		Type:        consts.PushMessageTypePraise,
+	})
+	assert.Len(t, student.messages, 1)
+	assert.Len(t, otherClassroom.messages, 0)
+	assert.Len(t, anyClassroom.messages, 1)
+	assert.Len(t, teacher.messages, 0)
+	<-student.messages
+	<-anyClassroom.messages
+
+	// 课堂广播投递给订阅了该课堂的全部连接
+	g.dispatch(context.Background(), &dto.PushMessage{ClassroomID: 100, Type: consts.PushMessageTypeAttention})
+	assert.Len(t, student.messages, 1)
+	assert.Len(t, teacher.messages, 1)
+	assert.Len(t, otherClassroom.messages, 0)
+	assert.Len(t, anyClassroom.messages, 0)
+
+	removeConn(g.users, student.userKey, student)
+	removeConn(g.classrooms, student.classroomID, student)
+	removeConn(g.users, otherClassroom.userKey, otherClassroom)
+	removeConn(g.users, anyClassroom.userKey, anyClassroom)
+	assert.NotContains(t, g.users, student.userKey)
+	assert.Len(t, g.classrooms[100], 1)
+}
@@ -0,0 +1,118 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/model/dto"
+)
+
+// PushPublisher 实时推送消息的发布
+// 发送给用户的消息先按用户分配序号写入离线消息，再通过 Redis pub/sub 发布，由各实例的 PushGateway 投递给本地连接
+// 离线消息在客户端确认后删除，未确认的消息在重连时补发
+type PushPublisher struct {
+	redisClient *dao.ApiRdbClient
+	logger      *clogger.ContextLogger
+}
+
+func NewPushPublisher(
+	redisClient *dao.ApiRdbClient,
+	logger *clogger.ContextLogger,
+) *PushPublisher {
+	return &PushPublisher{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Publish 发布推送消息，单条消息失败不影响其他消息，返回第一个错误
+func (p *PushPublisher) Publish(ctx context.Context, messages ...*dto.PushMessage) error {
+	var firstErr error
+	for _, message := range messages {
+		if err := p.publish(ctx, message); err != nil {
+			p.logger.Error(ctx, "[Publish] 发布推送消息失败, userType:%s, userID:%d, classroomID:%d, type:%s, error:%v",
+				message.UserType, message.UserID, message.ClassroomID, message.Type, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (p *PushPublisher) publish(ctx context.Context, message *dto.PushMessage) error {
+	if message.UserID == 0 && message.ClassroomID == 0 {
+		return errors.New("推送消息没有接收者")
+	}
+	if message.CreateTime == 0 {
+		message.CreateTime = time.Now().UnixMilli()
+	}
+
+	var outboxKey string
+	if message.UserID > 0 {
+		seq, err := p.redisClient.Incr(ctx, consts.GetPushSeqKey(message.UserType, message.UserID), consts.PushSeqExpire)
+		if err != nil {
+			return errors.Wrap(err, "分配消息序号失败")
+		}
+		message.Seq = seq
+		outboxKey = consts.GetPushOutboxKey(message.UserType, message.UserID)
+	}
+
+	bytes, err := json.Marshal(message)
+	if err != nil {
+		return errors.Wrap(err, "序列化推送消息失败")
+	}
+	if outboxKey != "" {
+		if err := p.redisClient.ZAdd(ctx, outboxKey, float64(message.Seq), string(bytes), consts.PushOutboxExpire); err != nil {
+			return errors.Wrap(err, "写入离线消息失败")
+		}
+		// 超出上限的旧消息不再补发
+		if message.Seq > consts.PushOutboxMaxSize {
+			maxSeq := strconv.FormatInt(message.Seq-consts.PushOutboxMaxSize, 10)
+			if _, err := p.redisClient.ZRemRangeByScore(ctx, outboxKey, "-inf", maxSeq); err != nil {
+				p.logger.Warn(ctx, "[publish] 清理离线消息失败, key:%s, error:%v", outboxKey, err)
+			}
+		}
+	}
+
+	// 已写入离线消息时，发布失败的消息在客户端重连后补发
+	if err := p.redisClient.Publish(ctx, consts.PushChannel, string(bytes)); err != nil {
+		return errors.Wrap(err, "发布推送消息失败")
+	}
+	return nil
+}
+
+// Pending 查询用户序号大于 afterSeq 的未确认消息，按序号升序
+func (p *PushPublisher) Pending(ctx context.Context, userType consts.CommunicationUserType, userID int64, afterSeq int64) ([]*dto.PushMessage, error) {
+	members := make([]string, 0)
+	if _, err := p.redisClient.ZRangeByScore(ctx, consts.GetPushOutboxKey(userType, userID), float64(afterSeq+1), math.MaxFloat64, &members); err != nil {
+		return nil, errors.Wrap(err, "查询离线消息失败")
+	}
+
+	messages := make([]*dto.PushMessage, 0, len(members))
+	for _, member := range members {
+		message := &dto.PushMessage{}
+		if err := json.Unmarshal([]byte(member), message); err != nil {
+			p.logger.Warn(ctx, "[Pending] 解析离线消息失败, userType:%s, userID:%d, error:%v", userType, userID, err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// Ack 确认用户序号不大于 seq 的全部消息，确认后不再补发
+func (p *PushPublisher) Ack(ctx context.Context, userType consts.CommunicationUserType, userID int64, seq int64) error {
+	if _, err := p.redisClient.ZRemRangeByScore(ctx, consts.GetPushOutboxKey(userType, userID), "-inf", strconv.FormatInt(seq, 10)); err != nil {
+		return errors.Wrap(err, "确认离线消息失败")
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/model/itl"
+	"gil_teacher/app/service/task_service"
+	"gil_teacher/app/utils"
+	"gil_teacher/app/utils/xlsx"
+)
+
+// 任务不是答题卡任务、不是当前教师创建，或布置不属于该任务
+var ErrAnswerCardTask = errors.New("任务不是答题卡任务或无权限导入")
+
+// 导入文件无法解析
+var ErrAnswerCardFile = errors.New("导入文件格式错误")
+
+// AnswerCardHandler 答题卡任务线下作答导入
+// 按任务保存的答案判分后转换为作答事件，与学生端作答一样写入作答详情并汇总任务报告
+type AnswerCardHandler struct {
+	taskService    *task_service.TaskService
+	taskStudentDAO dao_task.TaskStudentDAO
+	aggregator     *TaskReportAggregator
+	log            *logger.ContextLogger
+}
+
+func NewAnswerCardHandler(
+	taskService *task_service.TaskService,
+	taskStudentDAO dao_task.TaskStudentDAO,
+	aggregator *TaskReportAggregator,
+	log *logger.ContextLogger,
+) *AnswerCardHandler {
+	return &AnswerCardHandler{
+		taskService:    taskService,
+		taskStudentDAO: taskStudentDAO,
+		aggregator:     aggregator,
+		log:            log,
+	}
+}
+
+// ParseFile 解析导入文件，支持 csv、xlsx
+// 每行第一列为学生ID，之后按题号顺序为作答，首行为表头时跳过，返回每个学生作答所在的文件行号
+func (h *AnswerCardHandler) ParseFile(fileName string, content []byte) ([]*dto.AnswerCardStudentAnswer, []int64, error) {
+	var rows [][]string
+	var err error
+	switch strings.ToLower(strings.TrimPrefix(path.Ext(fileName), ".")) {
+	case consts.ExportFormatCSV:
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))))
+		reader.FieldsPerRecord = -1
+		rows, err = reader.ReadAll()
+	case consts.ExportFormatXLSX:
+		rows, err = xlsx.ReadFirstSheet(bytes.NewReader(content), int64(len(content)))
+	default:
+		return nil, nil, ErrAnswerCardFile
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(ErrAnswerCardFile, err.Error())
+	}
+
+	answers := make([]*dto.AnswerCardStudentAnswer, 0, len(rows))
+	rowNos := make([]int64, 0, len(rows))
+	for i, row := range rows {
+		if len(row) == 0 || strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		studentID, err := strconv.ParseInt(strings.TrimSpace(row[0]), 10, 64)
+		if err != nil {
+			// 表头行
+			if i == 0 {
+				continue
+			}
+			studentID = 0
+		}
+		answers = append(answers, &dto.AnswerCardStudentAnswer{
+			StudentID: studentID,
+			Answers:   row[1:],
+		})
+		rowNos = append(rowNos, int64(i+1))
+	}
+	if len(answers) == 0 || len(answers) > consts.AnswerCardImportMaxRows {
+		return nil, nil, ErrAnswerCardFile
+	}
+	return answers, rowNos, nil
+}
+
+// Import 按答题卡答案判分并写入作答数据，rowNos 为每个学生作答对应的行号，为空时按下标加 1
+// 存在错误的行不导入并在结果中返回，重复导入时以最后一次导入的学生作答整体覆盖，新导入中未作答的题目删除
+func (h *AnswerCardHandler) Import(ctx context.Context, teacherID, taskID, assignID int64, answers []*dto.AnswerCardStudentAnswer, rowNos []int64) (*api.AnswerCardImportResult, error) {
+	task, err := h.taskService.GetTaskByIDAndCreatorID(ctx, taskID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !consts.IsAnswerCardTask(task.TaskSubType) {
+		return nil, ErrAnswerCardTask
+	}
+	var answerCard dto.AnswerCard
+	if err := json.Unmarshal([]byte(task.TaskExtraInfo), &answerCard); err != nil || len(answerCard.Questions) == 0 {
+		return nil, errors.Errorf("答题卡答案解析失败, taskID:%d", taskID)
+	}
+
+	students, err := h.taskStudentDAO.GetStudentTiers(ctx, taskID, assignID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(students) == 0 {
+		return nil, ErrAnswerCardTask
+	}
+
+	result := &api.AnswerCardImportResult{Errors: make([]*api.AnswerCardImportError, 0)}
+	events := make([]*dto.TaskAnswerEventDTO, 0)
+	imported := make(map[int64]int64) // 学生ID -> 行号，同一学生只能出现一次
+	answerTime := time.Now().Unix()
+	for i, answer := range answers {
+		rowNo := int64(i + 1)
+		if i < len(rowNos) {
+			rowNo = rowNos[i]
+		}
+		rowError := func(format string, args ...any) {
+			result.Errors = append(result.Errors, &api.AnswerCardImportError{
+				Row:       rowNo,
+				StudentID: answer.StudentID,
+				Reason:    fmt.Sprintf(format, args...),
+			})
+		}
+
+		if _, ok := students[answer.StudentID]; !ok {
+			rowError("学生不在该布置中")
+			continue
+		}
+		if row, ok := imported[answer.StudentID]; ok {
+			rowError("学生重复，与第 %d 行重复", row)
+			continue
+		}
+		studentEvents, err := scoreAnswerCard(&answerCard, answer.Answers)
+		if err != nil {
+			rowError("%s", err.Error())
+			continue
+		}
+		imported[answer.StudentID] = rowNo
+
+		for _, event := range studentEvents {
+			event.TaskID = taskID
+			event.AssignID = assignID
+			event.StudentID = answer.StudentID
+			event.AnswerTime = answerTime
+			events = append(events, event)
+			if event.Correctness {
+				result.CorrectNum++
+			}
+		}
+	}
+	result.StudentNum = int64(len(imported))
+	result.AnswerNum = int64(len(events))
+
+	if len(imported) > 0 {
+		studentIDs := make([]int64, 0, len(imported))
+		for studentID := range imported {
+			studentIDs = append(studentIDs, studentID)
+		}
+		resourceKey := utils.JoinList([]any{consts.AnswerCardResourceID, consts.RESOURCE_TYPE_ANSWER_CARD}, consts.CombineKey)
+		if err := h.aggregator.ReplaceStudentAnswers(ctx, taskID, assignID, resourceKey, studentIDs, answerTime, events); err != nil {
+			h.log.Error(ctx, "[Import] 答题卡作答汇总失败, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+			return nil, err
+		}
+	}
+	h.log.Info(ctx, "[Import] 答题卡作答导入完成, taskID:%d, assignID:%d, studentNum:%d, answerNum:%d, errorNum:%d",
+		taskID, assignID, result.StudentNum, result.AnswerNum, len(result.Errors))
+	return result, nil
+}
+
+// 答题卡题目对应的题目类型
+var answerCardQuestionTypes = map[int64]consts.QuestionType{
+	consts.ANSWER_CARD_QUESTION_SINGLE:   consts.QUESTION_TYPE_SINGLE_CHOICE,
+	consts.ANSWER_CARD_QUESTION_MULTIPLE: consts.QUESTION_TYPE_MULTIPLE_CHOICE,
+	consts.ANSWER_CARD_QUESTION_JUDGE:    consts.QUESTION_TYPE_JUDGE,
+}
+
+// 答题卡的题目不在题库中，按任务保存的答案生成题目，题目ID和题号一致
+func answerCardQuestions(task *dao_task.Task) ([]*itl.Question, error) {
+	var answerCard dto.AnswerCard
+	if err := json.Unmarshal([]byte(task.TaskExtraInfo), &answerCard); err != nil || len(answerCard.Questions) == 0 {
+		return nil, errors.Errorf("答题卡答案解析失败, taskID:%d", task.TaskID)
+	}
+
+	questions := make([]*itl.Question, 0, len(answerCard.Questions))
+	for i, question := range answerCard.Questions {
+		options := make([]*itl.QuestionOption, 0, len(question.CorrectOptions))
+		for _, option := range question.CorrectOptions {
+			options = append(options, &itl.QuestionOption{OptionKey: option})
+		}
+		questions = append(questions, &itl.Question{
+			QuestionId:         strconv.Itoa(i + 1),
+			QuestionInfoEntity: &itl.QuestionInfoEntity{QuestionType: int64(answerCardQuestionTypes[question.QuestionType])},
+			QuestionContentEntity: &itl.QuestionContentEntity{
+				QuestionContentFormat: &itl.QuestionContentFormat{QuestionOrder: int64(i + 1)},
+				QuestionAnswer:        &itl.QuestionAnswerFormat{AnswerOptionList: options},
+			},
+		})
+	}
+	return questions, nil
+}
+
+// 按答题卡答案为一个学生的作答判分，未作答的题目不生成作答事件
+func scoreAnswerCard(answerCard *dto.AnswerCard, answers []string) ([]*dto.TaskAnswerEventDTO, error) {
+	// 表格末尾可能有空列
+	for len(answers) > len(answerCard.Questions) && strings.TrimSpace(answers[len(answers)-1]) == "" {
+		answers = answers[:len(answers)-1]
+	}
+	if len(answers) > len(answerCard.Questions) {
+		return nil, errors.Errorf("作答数 %d 超过题目数 %d", len(answers), len(answerCard.Questions))
+	}
+
+	events := make([]*dto.TaskAnswerEventDTO, 0, len(answers))
+	for i, raw := range answers {
+		question := answerCard.Questions[i]
+		answer, ok := normalizeAnswer(question.QuestionType, raw)
+		if !ok {
+			return nil, errors.Errorf("第 %d 题作答 %q 无法识别", i+1, raw)
+		}
+		if answer == "" {
+			continue
+		}
+		correct, _ := normalizeAnswer(question.QuestionType, strings.Join(question.CorrectOptions, ""))
+		events = append(events, &dto.TaskAnswerEventDTO{
+			ResourceID:    consts.AnswerCardResourceID,
+			ResourceType:  consts.RESOURCE_TYPE_ANSWER_CARD,
+			QuestionID:    strconv.Itoa(i + 1),
+			AnswerContent: answer,
+			Correctness:   answer == correct,
+			QuestionCount: int64(len(answerCard.Questions)),
+		})
+	}
+	return events, nil
+}
+
+// 判断题作答的常见写法
+var judgeAnswers = map[string]string{
+	"T": "T", "TRUE": "T", "Y": "T", "√": "T", "对": "T", "正确": "T",
+	"F": "F", "FALSE": "F", "N": "F", "X": "F", "×": "F", "错": "F", "错误": "F",
+}
+
+// 统一作答格式，选择题去除分隔符后按字母排序，判断题转换为 T 或 F，空作答返回空字符串
+func normalizeAnswer(questionType int64, raw string) (string, bool) {
+	answer := strings.ToUpper(strings.TrimSpace(raw))
+	if answer == "" {
+		return "", true
+	}
+
+	if questionType == consts.ANSWER_CARD_QUESTION_JUDGE {
+		judge, ok := judgeAnswers[answer]
+		return judge, ok
+	}
+
+	options := make([]string, 0, len(answer))
+	exists := make(map[rune]struct{}, len(answer))
+	for _, r := range answer {
+		switch {
+		case strings.ContainsRune(" ,，、;；", r):
+			continue
+		case r < 'A' || r > 'Z':
+			return "", false
+		}
+		if _, ok := exists[r]; ok {
+			continue
+		}
+		exists[r] = struct{}{}
+		options = append(options, string(r))
+	}
+	sort.Strings(options)
+	return strings.Join(options, ""), true
+}
@@ -0,0 +1,78 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/model/itl"
+)
+
+func TestScoreAnswerCard(t *testing.T) {
+	answerCard := &dto.AnswerCard{Questions: []*dto.AnswerCardQuestion{
+		{QuestionType: consts.ANSWER_CARD_QUESTION_SINGLE, CorrectOptions: []string{"B"}},
+		{QuestionType: consts.ANSWER_CARD_QUESTION_MULTIPLE, CorrectOptions: []string{"C", "A"}},
+		{QuestionType: consts.ANSWER_CARD_QUESTION_JUDGE, CorrectOptions: []string{"F"}},
+		{QuestionType: consts.ANSWER_CARD_QUESTION_SINGLE, CorrectOptions: []string{"D"}},
+	}}
+
+	events, err := scoreAnswerCard(answerCard, []string{"b", "C,A", "×", "", ""})
+	assert.NoError(t, err)
+	assert.Len(t, events, 3) // 第 4 题未作答
+	for _, event := range events {
+		assert.True(t, event.Correctness, event.QuestionID)
+		assert.Equal(t, int64(4), event.QuestionCount)
+	}
+	assert.Equal(t, "AC", events[1].AnswerContent)
+
+	events, err = scoreAnswerCard(answerCard, []string{"A", "A"})
+	assert.NoError(t, err)
+	assert.False(t, events[0].Correctness)
+	assert.False(t, events[1].Correctness) // 多选少选
+
+	_, err = scoreAnswerCard(answerCard, []string{"A", "A", "是"})
+	assert.Error(t, err)
+	_, err = scoreAnswerCard(answerCard, []string{"A", "A", "T", "D", "A"})
+	assert.Error(t, err)
+}
+
+func TestAnswerCardQuestions(t *testing.T) {
+	task := &dao_task.Task{
+		TaskID:        1,
+		TaskType:      consts.TASK_TYPE_HOMEWORK,
+		TaskSubType:   consts.TASK_TYPE_HOMEWORK_ANSWER,
+		TaskExtraInfo: `{"questions":[{"questionType":1,"correctOptions":["B"]},{"questionType":3,"correctOptions":["T"]},{"questionType":2,"correctOptions":["A","C"]}]}`,
+	}
+	questions, err := answerCardQuestions(task)
+	assert.NoError(t, err)
+	if assert.Len(t, questions, 3) {
+		assert.Equal(t, "2", questions[1].QuestionId)
+		assert.Equal(t, int64(consts.QUESTION_TYPE_JUDGE), questions[1].QuestionType)
+		assert.Equal(t, "T", questions[1].QuestionAnswer.AnswerOptionList[0].OptionKey)
+	}
+
+	// 按题型筛选后合并到题目数据中，题目总数不受筛选影响
+	h := &TaskReportHandler{}
+	questionMap := make(map[string]map[string]*itl.Question)
+	questionIDs, total, err := h.mergeAnswerCardQuestions(task, consts.AnswerCardResourceID,
+		&dto.TaskReportCommonQuery{QuestionType: int64(consts.QUESTION_TYPE_MULTIPLE_CHOICE)}, questionMap, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []string{"3"}, questionIDs)
+	assert.Contains(t, questionMap, "answer_card#105")
+
+	// 答题卡题目按题号排序
+	questionAnswers := make([]*api.QuestionAnswer, 0, len(questions))
+	for i := len(questions) - 1; i >= 0; i-- {
+		questionAnswers = append(questionAnswers, &api.QuestionAnswer{Question: questions[i]})
+	}
+	_, sortedIDs := h.sortQuestions(&taskData{task: task}, questionAnswers, nil)
+	assert.Equal(t, []string{"1", "2", "3"}, sortedIDs)
+
+	_, err = answerCardQuestions(&dao_task.Task{TaskID: 2, TaskExtraInfo: "{}"})
+	assert.Error(t, err)
+}
@@ -32,6 +32,10 @@ type taskData struct {
 	taskResources    map[string]*dao_task.TaskResource // resource_key -> TaskResource 任务资源列表
 	resourceQuestion *resourceQuestion                 // 资源题目数据
 	assignDataMap    map[int64]*assignData             // assignID -> assignData 布置对象数据
+
+	resourcesLoaded   bool // 已从 db 获取任务资源，按学生筛选后 taskResources 可能为空
+	resourcesFiltered bool // 已按学生分层或所属学生筛选过任务资源
+	questionsLoaded   bool // 已从题库获取资源题目数据，getResources 只记录题目ID
 }
 
 type resourceQuestion struct {
@@ -105,8 +109,12 @@ func (h *TaskReportHandler) getTasksData(ctx context.Context, taskIds []int64, t
 //  1. 排序后的题目列表
 //  2. 排序后的题目ID列表
 func (h *TaskReportHandler) sortQuestions(taskData *taskData, questionAnswers []*api.QuestionAnswer, query *dto.TaskReportCommonQuery) ([]*api.QuestionAnswer, []string) {
-	switch taskData.task.TaskType {
-	case consts.TASK_TYPE_COURSE: // 课程任务，按题库中的顺序返回，注意questions的数据不一定连续，需要重新从 1 开始编号
+	switch {
+	case consts.IsAnswerCardTask(taskData.task.TaskSubType): // 答题卡任务，按答题卡题号返回
+		for _, qa := range questionAnswers {
+			qa.QuestionIndex = qa.Question.QuestionContentFormat.QuestionOrder
+		}
+	case taskData.task.TaskType == consts.TASK_TYPE_COURSE: // 课程任务，按题库中的顺序返回，注意questions的数据不一定连续，需要重新从 1 开始编号
 		sort.Slice(questionAnswers, func(i, j int) bool {
 			return questionAnswers[i].Question.QuestionContentFormat.QuestionOrder <
 				questionAnswers[j].Question.QuestionContentFormat.QuestionOrder
@@ -114,7 +122,7 @@ func (h *TaskReportHandler) sortQuestions(taskData *taskData, questionAnswers []
 		for i, qa := range questionAnswers {
 			qa.QuestionIndex = int64(i + 1)
 		}
-	case consts.TASK_TYPE_HOMEWORK: // 作业任务，按题目添加顺序返回
+	case taskData.task.TaskType == consts.TASK_TYPE_HOMEWORK: // 作业任务，按题目添加顺序返回
 		taskResources := make([]*dao_task.TaskResource, 0)
 		for _, resource := range taskData.taskResources {
 			taskResources = append(taskResources, resource)
@@ -228,6 +236,32 @@ func (h *TaskReportHandler) getQuestions(
 	return filteredQuestionMap, filteredQuestionIDs, nil
 }
 
+// 筛选答题卡资源的题目，合并到按资源分类的题目数据中. 返回：
+//  1. 合并后的题目ID列表
+//  2. 答题卡题目总数
+//  3. 错误信息
+func (h *TaskReportHandler) mergeAnswerCardQuestions(
+	task *dao_task.Task,
+	resourceID string,
+	query *dto.TaskReportCommonQuery,
+	questionMap map[string]map[string]*itl.Question,
+	questionIDs []string,
+) ([]string, int64, error) {
+	questions, err := answerCardQuestions(task)
+	if err != nil {
+		return nil, 0, err
+	}
+	resourceKey := utils.JoinList([]any{resourceID, consts.RESOURCE_TYPE_ANSWER_CARD}, consts.CombineKey)
+	for _, q := range h.filterQuestions(questions, query) {
+		if _, ok := questionMap[resourceKey]; !ok {
+			questionMap[resourceKey] = make(map[string]*itl.Question)
+		}
+		questionMap[resourceKey][q.QuestionId] = q
+		questionIDs = append(questionIDs, q.QuestionId)
+	}
+	return questionIDs, int64(len(questions)), nil
+}
+
 // 筛选符合要求的题目. 返回：
 //  1. 按资源ID和资源类型分类的题目数据
 //  2. 题目ID列表
@@ -295,7 +329,7 @@ func (h *singleTask) getResources(ctx context.Context, taskId int64) error {
 	}
 
 	// 已经获取过任务资源数据
-	if len(h.taskResources) > 0 {
+	if h.resourcesLoaded {
 		return nil
 	}
 
@@ -305,6 +339,7 @@ func (h *singleTask) getResources(ctx context.Context, taskId int64) error {
 		return err
 	}
 	h.taskResources = taskResourceMap
+	h.resourcesLoaded = true
 	for _, resource := range taskResourceMap {
 		// 资源类型是题目
 		switch resource.ResourceType {
@@ -323,6 +358,31 @@ func (h *singleTask) getResources(ctx context.Context, taskId int64) error {
 	return nil
 }
 
+// 只保留学生可见的资源，分层作业为共用资源和学生所在分层的资源，错题作业为学生自己的资源
+// 需要在 getResourceQuestions 之前调用
+func (h *singleTask) keepStudentResources(tierNo, studentID int64) {
+	h.resourcesFiltered = true
+	resourceIDs := make(map[string]struct{}, len(h.taskResources))
+	for resourceKey, resource := range h.taskResources {
+		if !resource.VisibleTo(tierNo, studentID) {
+			delete(h.taskResources, resourceKey)
+			continue
+		}
+		resourceIDs[resource.ResourceID] = struct{}{}
+	}
+
+	// getResources 中按资源顺序记录的题目ID，同样只保留分层内的资源
+	if h.resourceQuestion != nil {
+		questionIDs := make([]string, 0, len(h.resourceQuestion.questionIDs))
+		for _, questionID := range h.resourceQuestion.questionIDs {
+			if _, ok := resourceIDs[questionID]; ok {
+				questionIDs = append(questionIDs, questionID)
+			}
+		}
+		h.resourceQuestion.questionIDs = questionIDs
+	}
+}
+
 // 检查有无任务资源题目数据，没有则从题库获取
 func (h *singleTask) getResourceQuestions(ctx context.Context, taskId int64, query *dto.TaskReportCommonQuery) error {
 	if h.task == nil {
@@ -330,40 +390,56 @@ func (h *singleTask) getResourceQuestions(ctx context.Context, taskId int64, que
 	}
 
 	// 未获取过任务资源数据，则先获取任务资源数据
-	if len(h.taskResources) == 0 {
-		if err := h.getResources(ctx, taskId); err != nil {
-			h.log.Error(ctx, "[getResourceQuestions] getTaskResource failed, taskID:%d, err:%v", taskId, err)
-			return err
-		}
+	if err := h.getResources(ctx, taskId); err != nil {
+		h.log.Error(ctx, "[getResourceQuestions] getTaskResource failed, taskID:%d, err:%v", taskId, err)
+		return err
 	}
 
 	// 已经获取过任务资源题目数据
-	if h.resourceQuestion != nil {
+	if h.questionsLoaded {
 		return nil
 	}
 
 	if len(h.taskResources) == 0 {
-		return errors.New("task has no resources")
+		if !h.resourcesFiltered {
+			return errors.New("task has no resources")
+		}
+		// 学生所在分层或学生自己没有资源
+		h.resourceQuestion = &resourceQuestion{
+			questionMap: make(map[string]map[string]*itl.Question),
+			questionIDs: make([]string, 0),
+		}
+		h.questionsLoaded = true
+		return nil
 	}
 
 	questionIDs := make([]string, 0)
 	practiceIDs := make([]int64, 0)
+	answerCardID := ""
 	for _, resource := range h.taskResources {
 		switch resource.ResourceType {
 		case consts.RESOURCE_TYPE_QUESTION:
 			questionIDs = append(questionIDs, resource.ResourceID)
 		case consts.RESOURCE_TYPE_PRACTICE:
 			practiceIDs = append(practiceIDs, utils.Atoi64(resource.ResourceID))
+		case consts.RESOURCE_TYPE_ANSWER_CARD:
+			answerCardID = resource.ResourceID
 		default:
 			h.log.Error(ctx, "[getResourceQuestions] resource type not supported: %d", resource.ResourceType)
 		}
 	}
 
-	// 从内容平台查询全部题目信息
-	resourceQuestions, resourcePractices, totalQuestionCount, err := h.questionAPI.GetResources(ctx, questionIDs, practiceIDs)
-	if err != nil {
-		h.log.Error(ctx, "[getResourceQuestions] GetResources error:%v", err)
-		return err
+	// 从内容平台查询全部题目信息，答题卡任务没有题库题目
+	var resourceQuestions map[string][]*itl.Question
+	var resourcePractices map[int64][]*itl.Question
+	var totalQuestionCount int64
+	if len(questionIDs) > 0 || len(practiceIDs) > 0 {
+		var err error
+		resourceQuestions, resourcePractices, totalQuestionCount, err = h.questionAPI.GetResources(ctx, questionIDs, practiceIDs)
+		if err != nil {
+			h.log.Error(ctx, "[getResourceQuestions] GetResources error:%v", err)
+			return err
+		}
 	}
 
 	// 筛选符合要求的题目
@@ -372,12 +448,22 @@ func (h *singleTask) getResourceQuestions(ctx context.Context, taskId int64, que
 		h.log.Error(ctx, "[getTaskResources] getQuestions error:%v", err)
 		return err
 	}
+	if answerCardID != "" {
+		var answerCardCount int64
+		filteredQuestionIDs, answerCardCount, err = h.mergeAnswerCardQuestions(h.task, answerCardID, query, filteredQuestionMap, filteredQuestionIDs)
+		if err != nil {
+			h.log.Error(ctx, "[getResourceQuestions] answerCardQuestions error:%v", err)
+			return err
+		}
+		totalQuestionCount += answerCardCount
+	}
 
 	h.resourceQuestion = &resourceQuestion{
 		questionMap:      filteredQuestionMap,
 		questionIDs:      filteredQuestionIDs,
 		totalQuestionNum: totalQuestionCount,
 	}
+	h.questionsLoaded = true
 	return nil
 }
 
@@ -776,6 +862,7 @@ func (h *multiTasks) getResource(ctx context.Context, taskIds []int64) error {
 	}
 	for taskID, resourceMap := range taskResourceMap {
 		h.taskDataMap[taskID].taskResources = resourceMap
+		h.taskDataMap[taskID].resourcesLoaded = true
 	}
 	for taskID := range taskResourceMap {
 		if h.taskDataMap[taskID].resourceQuestion == nil {
@@ -832,22 +919,31 @@ func (h *multiTasks) getResourceQuestions(ctx context.Context, tarTaskIds []int6
 		practiceIDs := make([]int64, 0)
 		for _, taskId := range noResourceQuestionTaskIds {
 			taskData := h.taskDataMap[taskId]
+			answerCardID := ""
 			for _, resource := range taskData.taskResources {
 				switch resource.ResourceType {
 				case consts.RESOURCE_TYPE_QUESTION:
 					questionIDs = append(questionIDs, resource.ResourceID)
 				case consts.RESOURCE_TYPE_PRACTICE:
 					practiceIDs = append(practiceIDs, utils.Atoi64(resource.ResourceID))
+				case consts.RESOURCE_TYPE_ANSWER_CARD:
+					answerCardID = resource.ResourceID
 				default:
 					h.log.Error(ctx, "[getResourceQuestions] resource type not supported: %d", resource.ResourceType)
 				}
 			}
 
-			// 从内容平台查询全部题目信息
-			resourceQuestions, resourcePractices, totalQuestionCount, err := h.questionAPI.GetResources(ctx, questionIDs, practiceIDs)
-			if err != nil {
-				h.log.Error(ctx, "[getResourceQuestions] GetResources error:%v", err)
-				return err
+			// 从内容平台查询全部题目信息，答题卡任务没有题库题目
+			var resourceQuestions map[string][]*itl.Question
+			var resourcePractices map[int64][]*itl.Question
+			var totalQuestionCount int64
+			if len(questionIDs) > 0 || len(practiceIDs) > 0 {
+				var err error
+				resourceQuestions, resourcePractices, totalQuestionCount, err = h.questionAPI.GetResources(ctx, questionIDs, practiceIDs)
+				if err != nil {
+					h.log.Error(ctx, "[getResourceQuestions] GetResources error:%v", err)
+					return err
+				}
 			}
 
 			// 筛选符合要求的题目
@@ -856,12 +952,22 @@ func (h *multiTasks) getResourceQuestions(ctx context.Context, tarTaskIds []int6
 				h.log.Error(ctx, "[getTaskResources] getQuestions error:%v", err)
 				return err
 			}
+			if answerCardID != "" {
+				var answerCardCount int64
+				filteredQuestionIDs, answerCardCount, err = h.mergeAnswerCardQuestions(taskData.task, answerCardID, query, filteredQuestionMap, filteredQuestionIDs)
+				if err != nil {
+					h.log.Error(ctx, "[getResourceQuestions] answerCardQuestions error:%v", err)
+					return err
+				}
+				totalQuestionCount += answerCardCount
+			}
 
 			h.taskDataMap[taskId].resourceQuestion = &resourceQuestion{
 				questionMap:      filteredQuestionMap,
 				questionIDs:      filteredQuestionIDs,
 				totalQuestionNum: totalQuestionCount,
 			}
+			h.taskDataMap[taskId].questionsLoaded = true
 		}
 	}
 	return nil
@@ -0,0 +1,40 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+)
+
+func TestKeepStudentResourcesEmpty(t *testing.T) {
+	h := &singleTask{
+		TaskReportHandler: &TaskReportHandler{log: clogger.NewContextLogger(log.DefaultLogger)},
+		taskData: &taskData{
+			task: &dao_task.Task{TaskID: 1, TaskType: consts.TASK_TYPE_HOMEWORK, TaskSubType: consts.TASK_TYPE_HOMEWORK_LAYERED},
+			taskResources: map[string]*dao_task.TaskResource{
+				"q1#103": {ResourceID: "q1", ResourceType: consts.RESOURCE_TYPE_QUESTION, TierNo: 1},
+				"q2#103": {ResourceID: "q2", ResourceType: consts.RESOURCE_TYPE_QUESTION, TierNo: 2},
+			},
+			resourceQuestion: &resourceQuestion{questionIDs: []string{"q1", "q2"}},
+			resourcesLoaded:  true,
+		},
+	}
+
+	// 学生所在分层没有资源时返回空题目列表，不重新加载全部资源
+	h.keepStudentResources(3, 100)
+	assert.Empty(t, h.taskResources)
+	assert.NoError(t, h.getResourceQuestions(context.Background(), 1, nil))
+	assert.Empty(t, h.resourceQuestion.questionIDs)
+	assert.NotNil(t, h.resourceQuestion.questionMap)
+	assert.Equal(t, int64(0), h.resourceQuestion.totalQuestionNum)
+
+	// 未经筛选的任务没有资源仍然返回错误
+	h.taskData = &taskData{task: h.task, taskResources: map[string]*dao_task.TaskResource{}, resourcesLoaded: true}
+	assert.Error(t, h.getResourceQuestions(context.Background(), 1, nil))
+}
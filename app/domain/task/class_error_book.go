@@ -0,0 +1,405 @@
+package task
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/core/postgresqlx"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/service/gil_internal/question_service"
+	"gil_teacher/app/service/task_service"
+	"gil_teacher/app/utils"
+)
+
+// 共性错题不存在、不属于当前学校，或与请求的学科不一致
+var ErrInvalidErrorBookItem = errors.New("共性错题不存在")
+
+// ClassErrorBookCollector 布置报告汇总后，按班级报告设置的共性错题阈值收集班级共性错题
+type ClassErrorBookCollector struct {
+	taskDAO          dao_task.TaskDAO
+	taskAssignDAO    dao_task.TaskAssignDAO
+	reportSettingDAO dao_task.TaskReportSettingDao
+	errorBookDAO     dao_task.ClassErrorBookDAO
+	logger           *clogger.ContextLogger
+}
+
+func NewClassErrorBookCollector(
+	taskDAO dao_task.TaskDAO,
+	taskAssignDAO dao_task.TaskAssignDAO,
+	reportSettingDAO dao_task.TaskReportSettingDao,
+	errorBookDAO dao_task.ClassErrorBookDAO,
+	logger *clogger.ContextLogger,
+) *ClassErrorBookCollector {
+	return &ClassErrorBookCollector{
+		taskDAO:          taskDAO,
+		taskAssignDAO:    taskAssignDAO,
+		reportSettingDAO: reportSettingDAO,
+		errorBookDAO:     errorBookDAO,
+		logger:           logger,
+	}
+}
+
+// Collect 记录布置中达到共性错题阈值的题目，只收集布置给班级的任务
+// 重新汇总后不再达到阈值的题目撤销该布置的出现
+// 答题卡的题目ID只是题号，不同任务的同一题号不是同一道题，不参与收集
+//
+//	questions map[questionKey][答题人数, 答错人数]，questionKey 为 resourceID#resourceType#questionID
+func (c *ClassErrorBookCollector) Collect(ctx context.Context, taskID, assignID int64, questions map[string][2]int64) error {
+	if len(questions) == 0 {
+		return nil
+	}
+	assigns, err := c.taskAssignDAO.GetTaskAssignInfo(ctx, taskID, assignID)
+	if err != nil {
+		return errors.Wrap(err, "查询任务布置失败")
+	}
+	if len(assigns) == 0 || assigns[0].GroupType != consts.TASK_GROUP_TYPE_CLASS {
+		return nil
+	}
+	assign := assigns[0]
+
+	tasks, err := c.taskDAO.GetTasksByIDs(ctx, []int64{taskID})
+	if err != nil {
+		return errors.Wrap(err, "查询任务失败")
+	}
+	if len(tasks) == 0 || tasks[0].Deleted != 0 {
+		return nil
+	}
+	task := tasks[0]
+
+	setting, err := c.reportSettingDAO.GetSettingByClassIDAndSubjectID(ctx, assign.SchoolID, assign.GroupID, task.Subject)
+	if err != nil {
+		return errors.Wrap(err, "查询班级报告设置失败")
+	}
+	answerNum, correctRate := classErrorBookThresholds(setting)
+
+	now := time.Now().Unix()
+	items := make([]*dao_task.ClassErrorBookItem, 0)
+	resolved := make([]string, 0)
+	for questionKey, counts := range questions {
+		resourceID, resourceType, questionID, ok := splitQuestionKey(questionKey)
+		if !ok || resourceType == consts.RESOURCE_TYPE_ANSWER_CARD {
+			continue
+		}
+		rate := utils.F64Div(float64(counts[0]-counts[1]), float64(counts[0]), 4)
+		if counts[0] < answerNum || rate >= correctRate {
+			resolved = append(resolved, questionID)
+			continue
+		}
+		items = append(items, &dao_task.ClassErrorBookItem{
+			SchoolID:        assign.SchoolID,
+			ClassID:         assign.GroupID,
+			Subject:         task.Subject,
+			QuestionID:      questionID,
+			ResourceID:      resourceID,
+			ResourceType:    resourceType,
+			FirstTaskID:     taskID,
+			FirstAssignID:   assignID,
+			FirstSeenTime:   assign.StartTime,
+			LastTaskID:      taskID,
+			LastAssignID:    assignID,
+			LastSeenTime:    assign.StartTime,
+			AssignIDs:       postgresqlx.Int64Array{assignID},
+			OccurrenceCount: 1,
+			AnswerCount:     counts[0],
+			IncorrectCount:  counts[1],
+			CorrectRate:     rate,
+			CreateTime:      now,
+			UpdateTime:      now,
+		})
+	}
+	// 固定写入顺序，避免并发写入同一批题目时死锁
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].QuestionID < items[j].QuestionID
+	})
+	sort.Strings(resolved)
+	if err := c.errorBookDAO.BatchUpsert(ctx, items); err != nil {
+		return errors.Wrap(err, "写入班级共性错题失败")
+	}
+	if err := c.errorBookDAO.RemoveAssign(ctx, assign.SchoolID, assign.GroupID, task.Subject, assignID, resolved); err != nil {
+		return errors.Wrap(err, "撤销班级共性错题失败")
+	}
+	return nil
+}
+
+// 班级共性错题的答题人数和正确率阈值，未设置时使用默认值
+// 报告设置中的正确率可能按百分比保存，大于 1 时换算为小数
+func classErrorBookThresholds(setting *dao_task.TaskReportSetting) (int64, float64) {
+	answerNum := int64(consts.ClassErrorBookDefaultAnswerNum)
+	correctRate := consts.ClassErrorBookDefaultCorrectRate
+	if setting == nil || setting.Setting == nil {
+		return answerNum, correctRate
+	}
+	common := setting.Setting.CommonIncorrectQuestion
+	if common.AnswerNum > 0 {
+		answerNum = common.AnswerNum
+	}
+	if common.CorrectRate > 1 {
+		correctRate = common.CorrectRate / 100
+	} else if common.CorrectRate > 0 {
+		correctRate = common.CorrectRate
+	}
+	return answerNum, correctRate
+}
+
+// 拆分 resourceID#resourceType#questionID
+func splitQuestionKey(questionKey string) (string, int64, string, bool) {
+	parts := strings.SplitN(questionKey, consts.CombineKey, 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", 0, "", false
+	}
+	resourceType := utils.Atoi64(parts[1])
+	if resourceType == 0 {
+		return "", 0, "", false
+	}
+	return parts[0], resourceType, parts[2], true
+}
+
+// ClassErrorBookHandler 班级共性错题本的浏览、讲解标记和推送到新任务
+type ClassErrorBookHandler struct {
+	errorBookDAO dao_task.ClassErrorBookDAO
+	taskDAO      dao_task.TaskDAO
+	taskService  *task_service.TaskService
+	questionAPI  *question_service.Client
+	log          *clogger.ContextLogger
+}
+
+func NewClassErrorBookHandler(
+	errorBookDAO dao_task.ClassErrorBookDAO,
+	taskDAO dao_task.TaskDAO,
+	taskService *task_service.TaskService,
+	questionAPI *question_service.Client,
+	log *clogger.ContextLogger,
+) *ClassErrorBookHandler {
+	return &ClassErrorBookHandler{
+		errorBookDAO: errorBookDAO,
+		taskDAO:      taskDAO,
+		taskService:  taskService,
+		questionAPI:  questionAPI,
+		log:          log,
+	}
+}
+
+// GetErrorBook 按知识点浏览班级学科的共性错题
+// 题目的知识点由所属巩固练习确定，需要先加载全部错题再按知识点筛选和分页
+func (h *ClassErrorBookHandler) GetErrorBook(ctx context.Context, schoolID int64, query *api.ClassErrorBookQuery) (*api.ClassErrorBookResponse, error) {
+	var explained *bool
+	switch query.Explained {
+	case consts.ClassErrorBookFilterUnexplained:
+		explained = new(bool)
+	case consts.ClassErrorBookFilterExplained:
+		explained = new(bool)
+		*explained = true
+	}
+	items, total, err := h.errorBookDAO.List(ctx, schoolID, query.ClassID, query.Subject, explained, 1, consts.ClassErrorBookMaxItems)
+	if err != nil {
+		return nil, err
+	}
+	if total > consts.ClassErrorBookMaxItems {
+		h.log.Warn(ctx, "[GetErrorBook] 共性错题超过加载上限, classID:%d, subject:%d, total:%d", query.ClassID, query.Subject, total)
+	}
+
+	practiceIDs := make([]int64, 0)
+	seenPractices := make(map[string]struct{})
+	for _, item := range items {
+		if item.ResourceType != consts.RESOURCE_TYPE_PRACTICE {
+			continue
+		}
+		if _, ok := seenPractices[item.ResourceID]; !ok {
+			seenPractices[item.ResourceID] = struct{}{}
+			practiceIDs = append(practiceIDs, utils.Atoi64(item.ResourceID))
+		}
+	}
+	practiceNodes, err := getPracticeBizTreeNodes(ctx, h.questionAPI, practiceIDs)
+	if err != nil {
+		h.log.Error(ctx, "[GetErrorBook] getPracticeBizTreeNodes failed, classID:%d, error:%v", query.ClassID, err)
+		return nil, err
+	}
+
+	knowledgeMap := make(map[int64]*api.ErrorBookKnowledge)
+	knowledges := make([]*api.ErrorBookKnowledge, 0)
+	nodeIDs := make([]int64, 0)
+	filtered := make([]*api.ErrorBookItem, 0, len(items))
+	for _, item := range items {
+		bookItem := &api.ErrorBookItem{
+			ClassErrorBookItem:     item,
+			Explained:              item.ExplainTime > 0,
+			RecurredAfterExplained: item.ExplainTime > 0 && item.LastSeenTime > item.ExplainTime,
+		}
+		if item.ResourceType == consts.RESOURCE_TYPE_PRACTICE {
+			bookItem.BizTreeNodeID = practiceNodes[item.ResourceID]
+		}
+
+		knowledge, ok := knowledgeMap[bookItem.BizTreeNodeID]
+		if !ok {
+			knowledge = &api.ErrorBookKnowledge{BizTreeNodeID: bookItem.BizTreeNodeID}
+			knowledgeMap[bookItem.BizTreeNodeID] = knowledge
+			knowledges = append(knowledges, knowledge)
+			if bookItem.BizTreeNodeID != 0 {
+				nodeIDs = append(nodeIDs, bookItem.BizTreeNodeID)
+			}
+		}
+		knowledge.ItemCount++
+		if !bookItem.Explained {
+			knowledge.UnexplainedCount++
+		}
+
+		if query.BizTreeNodeID == nil || *query.BizTreeNodeID == bookItem.BizTreeNodeID {
+			filtered = append(filtered, bookItem)
+		}
+	}
+
+	// 知识点名称查询失败时不影响错题浏览
+	if len(nodeIDs) > 0 {
+		phase, err := h.getErrorBookPhase(ctx, items[0])
+		if err != nil {
+			h.log.Warn(ctx, "[GetErrorBook] getErrorBookPhase failed, classID:%d, error:%v", query.ClassID, err)
+		}
+		nodeNames, err := getBizTreeNodeNames(ctx, h.questionAPI, phase, query.Subject, nodeIDs)
+		if err != nil {
+			h.log.Warn(ctx, "[GetErrorBook] getBizTreeNodeNames failed, classID:%d, error:%v", query.ClassID, err)
+		}
+		for _, knowledge := range knowledges {
+			knowledge.BizTreeNodeName = nodeNames[knowledge.BizTreeNodeID]
+		}
+	}
+	sort.SliceStable(knowledges, func(i, j int) bool {
+		return knowledges[i].ItemCount > knowledges[j].ItemCount
+	})
+
+	start := min((query.Page-1)*query.PageSize, int64(len(filtered)))
+	end := min(start+query.PageSize, int64(len(filtered)))
+	return &api.ClassErrorBookResponse{
+		Knowledges: knowledges,
+		Items:      filtered[start:end],
+		PageInfo: &consts.ApiPageResponse{
+			Page:     query.Page,
+			PageSize: query.PageSize,
+			Total:    int64(len(filtered)),
+		},
+	}, nil
+}
+
+// 共性错题所在的学段，取最近一次出现的任务的学段
+func (h *ClassErrorBookHandler) getErrorBookPhase(ctx context.Context, item *dao_task.ClassErrorBookItem) (int64, error) {
+	tasks, err := h.taskDAO.GetTasksByIDs(ctx, []int64{item.LastTaskID})
+	if err != nil {
+		return 0, err
+	}
+	if len(tasks) == 0 {
+		return 0, errors.Errorf("任务不存在, taskID:%d", item.LastTaskID)
+	}
+	return tasks[0].Phase, nil
+}
+
+// MarkExplained 标记或取消标记共性错题已讲解，items 由 GetItemsByIDs 加载，调用方需先检查班级权限
+func (h *ClassErrorBookHandler) MarkExplained(ctx context.Context, schoolID, teacherID int64, items []*dao_task.ClassErrorBookItem, explained bool) (*api.ErrorBookExplainResponse, error) {
+	var explainTime int64
+	if explained {
+		explainTime = time.Now().Unix()
+	}
+
+	classIDs := make([]int64, 0)
+	classItems := make(map[int64][]int64)
+	for _, item := range items {
+		if _, ok := classItems[item.ClassID]; !ok {
+			classIDs = append(classIDs, item.ClassID)
+		}
+		classItems[item.ClassID] = append(classItems[item.ClassID], item.ID)
+	}
+
+	var count int64
+	for _, classID := range classIDs {
+		n, err := h.errorBookDAO.MarkExplained(ctx, schoolID, classID, classItems[classID], teacherID, explainTime)
+		if err != nil {
+			return nil, err
+		}
+		count += n
+	}
+	return &api.ErrorBookExplainResponse{UpdatedCount: count}, nil
+}
+
+// GetItems 获取学校内指定学科的共性错题，按 itemIDs 的顺序返回，任一错题不存在时返回 ErrInvalidErrorBookItem
+func (h *ClassErrorBookHandler) GetItems(ctx context.Context, schoolID, subject int64, itemIDs []int64) ([]*dao_task.ClassErrorBookItem, error) {
+	items, err := h.GetItemsByIDs(ctx, schoolID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Subject != subject {
+			return nil, ErrInvalidErrorBookItem
+		}
+	}
+	return items, nil
+}
+
+// GetItemsByIDs 获取学校内的共性错题，按 itemIDs 的顺序返回，任一错题不存在时返回 ErrInvalidErrorBookItem
+func (h *ClassErrorBookHandler) GetItemsByIDs(ctx context.Context, schoolID int64, itemIDs []int64) ([]*dao_task.ClassErrorBookItem, error) {
+	items, err := h.errorBookDAO.GetByIDs(ctx, schoolID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	itemMap := make(map[int64]*dao_task.ClassErrorBookItem, len(items))
+	for _, item := range items {
+		itemMap[item.ID] = item
+	}
+	result := make([]*dao_task.ClassErrorBookItem, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		item, ok := itemMap[itemID]
+		if !ok {
+			return nil, ErrInvalidErrorBookItem
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// PushToTask 将共性错题作为单题资源创建新的作业任务，不同班级的相同题目只布置一次
+// 答题卡的题目不是题库中的题目，包含答题卡题目时返回 ErrInvalidErrorBookItem
+func (h *ClassErrorBookHandler) PushToTask(ctx context.Context, items []*dao_task.ClassErrorBookItem, req *api.PushErrorBookTaskRequest) (*api.PushErrorBookTaskResponse, error) {
+	resources := make([]api.TaskResource, 0, len(items))
+	resourceKeys := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		if item.ResourceType == consts.RESOURCE_TYPE_ANSWER_CARD {
+			return nil, ErrInvalidErrorBookItem
+		}
+		resource := api.TaskResource{
+			ResourceID:   item.QuestionID,
+			ResourceType: consts.RESOURCE_TYPE_QUESTION,
+		}
+		// 按任务资源的完整 key 去重，与 tbl_task_resource 的唯一索引一致
+		resourceKey := resource.ResourceID + consts.CombineKey + strconv.FormatInt(resource.ResourceType, 10)
+		if _, ok := resourceKeys[resourceKey]; ok {
+			continue
+		}
+		resourceKeys[resourceKey] = struct{}{}
+		resources = append(resources, resource)
+	}
+
+	err := h.taskService.CreateTask(ctx, &api.CreateTaskRequestBody{
+		SchoolID:       req.SchoolID,
+		Phase:          req.Phase,
+		Subject:        req.Subject,
+		TaskType:       consts.TASK_TYPE_HOMEWORK,
+		TaskName:       req.TaskName,
+		TeacherComment: req.TeacherComment,
+		CreatorID:      req.CreatorID,
+		UpdaterID:      req.CreatorID,
+		Resources:      resources,
+		StudentGroups:  req.StudentGroups,
+		Status:         req.Status,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	h.log.Info(ctx, "[PushToTask] 共性错题推送到新任务成功, teacherID:%d, questionNum:%d", req.CreatorID, len(resources))
+	return &api.PushErrorBookTaskResponse{QuestionNum: int64(len(resources))}, nil
+}
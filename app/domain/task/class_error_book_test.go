@@ -0,0 +1,176 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+)
+
+type stubErrorBookTaskDAO struct {
+	dao_task.TaskDAO
+	task *dao_task.Task
+}
+
+func (s *stubErrorBookTaskDAO) GetTasksByIDs(ctx context.Context, taskIDs []int64) ([]*dao_task.Task, error) {
+	return []*dao_task.Task{s.task}, nil
+}
+
+type stubErrorBookAssignDAO struct {
+	dao_task.TaskAssignDAO
+	assign *dao_task.TaskAssign
+}
+
+func (s *stubErrorBookAssignDAO) GetTaskAssignInfo(ctx context.Context, taskID int64, assignID int64) ([]*dao_task.TaskAssign, error) {
+	return []*dao_task.TaskAssign{s.assign}, nil
+}
+
+type stubErrorBookSettingDAO struct {
+	dao_task.TaskReportSettingDao
+}
+
+func (s *stubErrorBookSettingDAO) GetSettingByClassIDAndSubjectID(ctx context.Context, schoolID, classID, subjectID int64) (*dao_task.TaskReportSetting, error) {
+	return nil, nil
+}
+
+// 记录写入的共性错题
+type stubErrorBookDAO struct {
+	dao_task.ClassErrorBookDAO
+	upserted  []*dao_task.ClassErrorBookItem
+	removed   []string
+	explained map[int64][]int64
+}
+
+func (s *stubErrorBookDAO) BatchUpsert(ctx context.Context, items []*dao_task.ClassErrorBookItem) error {
+	s.upserted = append(s.upserted, items...)
+	return nil
+}
+
+func (s *stubErrorBookDAO) RemoveAssign(ctx context.Context, schoolID, classID, subject, assignID int64, questionIDs []string) error {
+	s.removed = append(s.removed, questionIDs...)
+	return nil
+}
+
+func (s *stubErrorBookDAO) MarkExplained(ctx context.Context, schoolID, classID int64, ids []int64, teacherID int64, explainTime int64) (int64, error) {
+	s.explained[classID] = append(s.explained[classID], ids...)
+	return int64(len(ids)), nil
+}
+
+func TestClassErrorBookThresholds(t *testing.T) {
+	answerNum, correctRate := classErrorBookThresholds(nil)
+	assert.Equal(t, int64(consts.ClassErrorBookDefaultAnswerNum), answerNum)
+	assert.Equal(t, consts.ClassErrorBookDefaultCorrectRate, correctRate)
+
+	setting := &dao_task.TaskReportSetting{Setting: &dao_task.Setting{}}
+	setting.Setting.CommonIncorrectQuestion.AnswerNum = 10
+	setting.Setting.CommonIncorrectQuestion.CorrectRate = 0.5
+	answerNum, correctRate = classErrorBookThresholds(setting)
+	assert.Equal(t, int64(10), answerNum)
+	assert.Equal(t, 0.5, correctRate)
+
+	// 按百分比保存的正确率
+	setting.Setting.CommonIncorrectQuestion.CorrectRate = 70
+	_, correctRate = classErrorBookThresholds(setting)
+	assert.Equal(t, 0.7, correctRate)
+}
+
+func TestSplitQuestionKey(t *testing.T) {
+	resourceID, resourceType, questionID, ok := splitQuestionKey("1001#2#q-1")
+	assert.True(t, ok)
+	assert.Equal(t, "1001", resourceID)
+	assert.Equal(t, int64(2), resourceType)
+	assert.Equal(t, "q-1", questionID)
+
+	_, _, _, ok = splitQuestionKey("1001#0#q-1")
+	assert.False(t, ok)
+	_, _, _, ok = splitQuestionKey("1001#2")
+	assert.False(t, ok)
+}
+
+func TestClassErrorBookCollect(t *testing.T) {
+	errorBookDAO := &stubErrorBookDAO{}
+	assign := &dao_task.TaskAssign{AssignID: 11, TaskID: 1, SchoolID: 10, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1001}
+	collector := NewClassErrorBookCollector(
+		&stubErrorBookTaskDAO{task: &dao_task.Task{TaskID: 1, Subject: 2}},
+		&stubErrorBookAssignDAO{assign: assign},
+		&stubErrorBookSettingDAO{},
+		errorBookDAO,
+		clogger.NewContextLogger(log.DefaultLogger),
+	)
+
+	answerNum := int64(consts.ClassErrorBookDefaultAnswerNum)
+	err := collector.Collect(context.Background(), 1, 11, map[string][2]int64{
+		"1001#2#q-wrong":    {answerNum, answerNum},
+		"1001#2#q-right":    {answerNum, 0},
+		"1001#2#q-too-few":  {answerNum - 1, answerNum - 1},
+		"1001#2#q-improved": {answerNum, 0},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, errorBookDAO.upserted, 1) {
+		item := errorBookDAO.upserted[0]
+		assert.Equal(t, "q-wrong", item.QuestionID)
+		assert.Equal(t, int64(1001), item.ClassID)
+		assert.Equal(t, int64(2), item.Subject)
+	}
+	// 不再达到阈值的题目撤销本次布置的出现
+	assert.Equal(t, []string{"q-improved", "q-right", "q-too-few"}, errorBookDAO.removed)
+
+	// 答题卡的题号不是题库题目ID，不收集也不撤销
+	errorBookDAO.upserted, errorBookDAO.removed = nil, nil
+	err = collector.Collect(context.Background(), 1, 11, map[string][2]int64{
+		"answer_card#105#1": {answerNum, answerNum},
+		"answer_card#105#2": {answerNum, 0},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, errorBookDAO.upserted)
+	assert.Empty(t, errorBookDAO.removed)
+
+	// 全部题目都不再是共性错题时也要撤销
+	errorBookDAO.upserted, errorBookDAO.removed = nil, nil
+	err = collector.Collect(context.Background(), 1, 11, map[string][2]int64{"1001#2#q-wrong": {answerNum, 0}})
+	assert.NoError(t, err)
+	assert.Empty(t, errorBookDAO.upserted)
+	assert.Equal(t, []string{"q-wrong"}, errorBookDAO.removed)
+
+	// 布置给学生的任务不收集
+	errorBookDAO.removed = nil
+	assign.GroupType = consts.TASK_GROUP_TYPE_STUDENT
+	err = collector.Collect(context.Background(), 1, 11, map[string][2]int64{"1001#2#q-wrong": {answerNum, answerNum}})
+	assert.NoError(t, err)
+	assert.Empty(t, errorBookDAO.upserted)
+	assert.Empty(t, errorBookDAO.removed)
+}
+
+func TestClassErrorBookMarkExplained(t *testing.T) {
+	errorBookDAO := &stubErrorBookDAO{explained: make(map[int64][]int64)}
+	handler := NewClassErrorBookHandler(errorBookDAO, nil, nil, nil, clogger.NewContextLogger(log.DefaultLogger))
+
+	items := []*dao_task.ClassErrorBookItem{
+		{ID: 1, ClassID: 1001},
+		{ID: 2, ClassID: 1002},
+		{ID: 3, ClassID: 1001},
+	}
+	result, err := handler.MarkExplained(context.Background(), 10, 100, items, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.UpdatedCount)
+	// 按班级更新，更新条件包含班级
+	assert.Equal(t, map[int64][]int64{1001: {1, 3}, 1002: {2}}, errorBookDAO.explained)
+}
+
+func TestClassErrorBookPushRejectsAnswerCard(t *testing.T) {
+	handler := NewClassErrorBookHandler(&stubErrorBookDAO{}, nil, nil, nil, clogger.NewContextLogger(log.DefaultLogger))
+
+	// 答题卡的题号不是题库中的题目，不能作为单题资源布置
+	items := []*dao_task.ClassErrorBookItem{
+		{ID: 1, QuestionID: "q-1", ResourceID: "1001", ResourceType: consts.RESOURCE_TYPE_QUESTION},
+		{ID: 2, QuestionID: "1", ResourceID: consts.AnswerCardResourceID, ResourceType: consts.RESOURCE_TYPE_ANSWER_CARD},
+	}
+	_, err := handler.PushToTask(context.Background(), items, &api.PushErrorBookTaskRequest{})
+	assert.ErrorIs(t, err, ErrInvalidErrorBookItem)
+}
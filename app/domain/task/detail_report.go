@@ -41,6 +41,19 @@ func (h *TaskReportHandler) StudentTaskReport(ctx context.Context, studentID int
 		return nil, err
 	}
 
+	// 分层作业只统计学生所在分层的资源，错题作业只统计学生自己的资源
+	switch taskHandler.task.TaskSubType {
+	case consts.TASK_TYPE_HOMEWORK_LAYERED:
+		tierNo, err := h.taskAssignService.GetStudentTier(ctx, taskID, query.AssignID, studentID)
+		if err != nil {
+			h.log.Error(ctx, "[StudentTaskReport] GetStudentTier failed, taskID:%d, studentID:%d, err:%v", taskID, studentID, err)
+			return nil, err
+		}
+		taskHandler.keepStudentResources(tierNo, studentID)
+	case consts.TASK_TYPE_HOMEWORK_WRONG:
+		taskHandler.keepStudentResources(consts.TaskTierNoCommon, studentID)
+	}
+
 	if err := taskHandler.getResourceQuestions(ctx, taskID, &query.TaskReportCommonQuery); err != nil {
 		h.log.Error(ctx, "[StudentTaskReport] getResourceQuestions failed, taskID:%d, err:%v", taskID, err)
 		return nil, err
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+)
+
+// ExportTaskReportFile 按导出格式生成作业报告文件，返回带后缀的文件名和文件内容
+func (h *TaskReportHandler) ExportTaskReportFile(ctx context.Context, query *dto.ExportTaskReportQuery, format string) (string, []byte, error) {
+	switch format {
+	case consts.ExportFormatXLSX:
+		reportName, workbook, err := h.ExportTaskReportWorkbook(ctx, query)
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := workbook.Bytes()
+		if err != nil {
+			h.log.Error(ctx, "[ExportTaskReportFile] Write XLSX error:%v", err)
+			return "", nil, err
+		}
+		return reportName + "." + consts.ExportFormatXLSX, content, nil
+	case consts.ExportFormatCSV:
+		reportName, report, err := h.ExportTaskReport(ctx, query)
+		if err != nil {
+			return "", nil, err
+		}
+		content, err := encodeCSV(report)
+		if err != nil {
+			h.log.Error(ctx, "[ExportTaskReportFile] Write CSV error:%v", err)
+			return "", nil, err
+		}
+		return reportName + "." + consts.ExportFormatCSV, content, nil
+	default:
+		return "", nil, fmt.Errorf("不支持的导出格式: %s", format)
+	}
+}
+
+// 生成带 UTF-8 BOM 的 csv 内容，便于 Excel 直接打开
+func encodeCSV(report *dto.ExportTaskReportResult) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(report.Meta); err != nil {
+		return nil, err
+	}
+	if err := writer.WriteAll(report.Data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,472 @@
+package task
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils/oss"
+)
+
+// 导出任务不存在或不属于当前教师
+var ErrExportJobNotFound = errors.New("导出任务不存在")
+
+// 导出任务尚未成功完成
+var ErrExportJobNotReady = errors.New("导出任务未完成")
+
+// 导出的任务布置不存在或不属于当前学校
+var ErrExportItemInvalid = errors.New("导出的任务布置不存在")
+
+// 导出的布置对象不是班级，且当前教师不是任务创建者
+var ErrExportItemForbidden = errors.New("无权导出该任务布置")
+
+// TaskExportJobHandler 作业报告异步导出
+// 提交后写入导出任务表并投递到本地队列，由后台协程生成文件上传 OSS
+// 队列满或实例重启导致未执行的任务，由定时扫描重新投递
+// 执行中的任务持有租约并定时续期，租约过期说明执行实例已中断，由定时扫描重置为等待执行
+type TaskExportJobHandler struct {
+	reportHandler *TaskReportHandler
+	exportJobDAO  dao_task.TaskExportJobDAO
+	taskDAO       dao_task.TaskDAO
+	taskAssignDAO dao_task.TaskAssignDAO
+	ossClient     *oss.OSSClient
+	log           *logger.ContextLogger
+
+	queue chan int64
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func NewTaskExportJobHandler(
+	reportHandler *TaskReportHandler,
+	exportJobDAO dao_task.TaskExportJobDAO,
+	taskDAO dao_task.TaskDAO,
+	taskAssignDAO dao_task.TaskAssignDAO,
+	ossClient *oss.OSSClient,
+	log *logger.ContextLogger,
+) (*TaskExportJobHandler, func()) {
+	h := &TaskExportJobHandler{
+		reportHandler: reportHandler,
+		exportJobDAO:  exportJobDAO,
+		taskDAO:       taskDAO,
+		taskAssignDAO: taskAssignDAO,
+		ossClient:     ossClient,
+		log:           log,
+		queue:         make(chan int64, consts.ExportJobQueueSize),
+		stop:          make(chan struct{}),
+	}
+
+	for i := 0; i < consts.ExportJobWorkerNum; i++ {
+		h.wg.Add(1)
+		go h.worker()
+	}
+	h.wg.Add(1)
+	go h.scanner()
+
+	cleanup := func() {
+		close(h.stop)
+		h.wg.Wait()
+	}
+	return h, cleanup
+}
+
+// ResolveItemClassIDs 校验导出的任务布置都属于当前学校，返回布置的班级ID，由调用方检查班级权限
+//
+//	布置对象不是班级时没有对应的班级，只有任务创建者可以导出
+func (h *TaskExportJobHandler) ResolveItemClassIDs(ctx context.Context, teacherID, schoolID int64, items []*dao_task.ExportJobItem) ([]int64, error) {
+	taskIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		if !slices.Contains(taskIDs, item.TaskID) {
+			taskIDs = append(taskIDs, item.TaskID)
+		}
+	}
+
+	tasks, err := h.taskDAO.GetTasksByIDs(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	taskMap := make(map[int64]*dao_task.Task, len(tasks))
+	for _, t := range tasks {
+		taskMap[t.TaskID] = t
+	}
+	assigns, err := h.taskAssignDAO.GetTaskAssignsByTaskIDs(ctx, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+	assignMap := make(map[int64]*dao_task.TaskAssign, len(assigns))
+	for _, assign := range assigns {
+		assignMap[assign.AssignID] = assign
+	}
+
+	classIDs := make([]int64, 0, len(items))
+	for _, item := range items {
+		t, ok := taskMap[item.TaskID]
+		if !ok || t.Deleted != 0 || t.SchoolID != schoolID {
+			return nil, errors.Wrapf(ErrExportItemInvalid, "taskID:%d", item.TaskID)
+		}
+		assign, ok := assignMap[item.AssignID]
+		if !ok || assign.TaskID != item.TaskID || assign.SchoolID != schoolID {
+			return nil, errors.Wrapf(ErrExportItemInvalid, "taskID:%d, assignID:%d", item.TaskID, item.AssignID)
+		}
+
+		if assign.GroupType != consts.TASK_GROUP_TYPE_CLASS {
+			if t.CreatorID != teacherID {
+				return nil, errors.Wrapf(ErrExportItemForbidden, "taskID:%d, assignID:%d", item.TaskID, item.AssignID)
+			}
+			continue
+		}
+		if !slices.Contains(classIDs, assign.GroupID) {
+			classIDs = append(classIDs, assign.GroupID)
+		}
+	}
+	return classIDs, nil
+}
+
+// Submit 提交导出任务，返回导出任务ID，调用前需通过 ResolveItemClassIDs 校验导出权限
+func (h *TaskExportJobHandler) Submit(ctx context.Context, teacherID, schoolID int64, req *api.ExportJobSubmitRequest) (*api.ExportJob, error) {
+	job := &dao_task.TaskExportJob{
+		SchoolID:  schoolID,
+		TeacherID: teacherID,
+		Format:    req.Format,
+		Params: &dao_task.ExportJobParams{
+			Items:    req.Items,
+			Fields:   req.Fields,
+			SortBy:   req.SortBy,
+			SortType: req.SortType,
+		},
+		Status:     consts.EXPORT_JOB_STATUS_PENDING,
+		CreateTime: time.Now().Unix(),
+		UpdateTime: time.Now().Unix(),
+	}
+	if err := h.exportJobDAO.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	h.enqueue(job.ID)
+	return toExportJob(job), nil
+}
+
+// GetJob 查询当前教师的导出任务
+func (h *TaskExportJobHandler) GetJob(ctx context.Context, teacherID, jobID int64) (*api.ExportJob, error) {
+	job, err := h.getTeacherJob(ctx, teacherID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return toExportJob(job), nil
+}
+
+// GetDownloadURL 获取导出文件的预签名下载地址，仅执行成功的任务可下载
+func (h *TaskExportJobHandler) GetDownloadURL(ctx context.Context, teacherID, jobID int64) (*api.ExportJobURL, error) {
+	job, err := h.getTeacherJob(ctx, teacherID, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != consts.EXPORT_JOB_STATUS_SUCCEEDED || job.ObjectKey == "" {
+		return nil, ErrExportJobNotReady
+	}
+
+	url, err := h.ossClient.GeneratePresignedURL(job.ObjectKey, "GET", consts.ExportJobURLExpire, nil)
+	if err != nil {
+		h.log.Error(ctx, "[GetDownloadURL] 生成下载地址失败, jobID:%d, objectKey:%s, error:%v", jobID, job.ObjectKey, err)
+		return nil, err
+	}
+	return &api.ExportJobURL{
+		JobID:      job.ID,
+		FileName:   job.FileName,
+		URL:        url,
+		ExpireTime: time.Now().Unix() + consts.ExportJobURLExpire,
+	}, nil
+}
+
+func (h *TaskExportJobHandler) getTeacherJob(ctx context.Context, teacherID, jobID int64) (*dao_task.TaskExportJob, error) {
+	job, err := h.exportJobDAO.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil || job.TeacherID != teacherID {
+		return nil, ErrExportJobNotFound
+	}
+	return job, nil
+}
+
+// 非阻塞投递，队列满时等待定时扫描补偿
+func (h *TaskExportJobHandler) enqueue(jobID int64) {
+	select {
+	case h.queue <- jobID:
+	default:
+		h.log.Warn(context.Background(), "[enqueue] 导出任务队列已满, jobID:%d", jobID)
+	}
+}
+
+func (h *TaskExportJobHandler) worker() {
+	defer h.wg.Done()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case jobID := <-h.queue:
+			h.run(jobID)
+		}
+	}
+}
+
+// 定时扫描等待执行的任务重新投递，租约过期的任务重置为等待执行
+func (h *TaskExportJobHandler) scanner() {
+	defer h.wg.Done()
+	ticker := time.NewTicker(consts.ExportJobScanInterval * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.scan()
+		}
+	}
+}
+
+func (h *TaskExportJobHandler) scan() {
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	// 租约过期说明执行实例已中断，仍在执行的任务会定时续期，不会被重置
+	expiredJobs, err := h.exportJobDAO.FindLeaseExpired(ctx, now, consts.ExportJobQueueSize)
+	if err != nil {
+		return
+	}
+	for _, job := range expiredJobs {
+		ok, err := h.exportJobDAO.ResetLeaseExpired(ctx, job.ID, now)
+		if err == nil && ok {
+			h.log.Warn(ctx, "[scan] 导出任务租约过期，重新执行, jobID:%d", job.ID)
+			h.enqueue(job.ID)
+		}
+	}
+
+	// 刚提交的任务已在队列中，只补偿提交超过一个扫描周期仍未执行的任务
+	pendingJobs, err := h.exportJobDAO.FindByStatus(ctx, consts.EXPORT_JOB_STATUS_PENDING, now-consts.ExportJobScanInterval, consts.ExportJobQueueSize)
+	if err != nil {
+		return
+	}
+	for _, job := range pendingJobs {
+		h.enqueue(job.ID)
+	}
+}
+
+// 执行导出任务，多实例部署时通过状态更新抢占任务
+func (h *TaskExportJobHandler) run(jobID int64) {
+	ctx := context.Background()
+	leaseUntil := time.Now().Unix() + consts.ExportJobLeaseDuration
+	ok, err := h.exportJobDAO.UpdateStatus(ctx, jobID, consts.EXPORT_JOB_STATUS_PENDING, map[string]any{
+		"status":      consts.EXPORT_JOB_STATUS_RUNNING,
+		"lease_until": leaseUntil,
+		"update_time": time.Now().Unix(),
+	})
+	if err != nil || !ok {
+		return
+	}
+
+	job, err := h.exportJobDAO.GetByID(ctx, jobID)
+	if err != nil || job == nil {
+		return
+	}
+
+	// 租约丢失时取消导出，任务已由其他实例重新执行，不再上传文件和更新状态
+	buildCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopHeartbeat := h.heartbeat(ctx, jobID, leaseUntil, cancel)
+	startTime := time.Now()
+	fileName, objectKey, err := h.build(buildCtx, job)
+	stopHeartbeat()
+	if buildCtx.Err() != nil {
+		h.log.Warn(ctx, "[run] 导出任务租约已丢失，放弃执行结果, jobID:%d", jobID)
+		return
+	}
+	updates := map[string]any{
+		"finish_time": time.Now().Unix(),
+		"update_time": time.Now().Unix(),
+	}
+	if err != nil {
+		h.log.Error(ctx, "[run] 导出任务执行失败, jobID:%d, error:%v", jobID, err)
+		updates["status"] = consts.EXPORT_JOB_STATUS_FAILED
+		updates["error_msg"] = truncateErrorMsg(err.Error(), 500)
+	} else {
+		h.log.Info(ctx, "[run] 导出任务执行成功, jobID:%d, objectKey:%s, 耗时:%v", jobID, objectKey, time.Since(startTime))
+		updates["status"] = consts.EXPORT_JOB_STATUS_SUCCEEDED
+		updates["file_name"] = fileName
+		updates["object_key"] = objectKey
+	}
+	_, _ = h.exportJobDAO.UpdateStatus(ctx, jobID, consts.EXPORT_JOB_STATUS_RUNNING, updates)
+}
+
+// 执行期间定时续期租约，租约丢失时调用 cancel，返回停止续期的函数
+func (h *TaskExportJobHandler) heartbeat(ctx context.Context, jobID, leaseUntil int64, cancel context.CancelFunc) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(consts.ExportJobHeartbeatInterval * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				var ok bool
+				leaseUntil, ok = h.renewLease(ctx, jobID, leaseUntil)
+				if !ok {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// 续期一次租约，返回新的租约时间，租约丢失时返回 false
+// 续期失败但租约尚未过期时保留原租约，等待下次续期
+func (h *TaskExportJobHandler) renewLease(ctx context.Context, jobID, leaseUntil int64) (int64, bool) {
+	now := time.Now().Unix()
+	nextLeaseUntil := now + consts.ExportJobLeaseDuration
+	ok, err := h.exportJobDAO.RenewLease(ctx, jobID, leaseUntil, nextLeaseUntil)
+	if err != nil {
+		if now >= leaseUntil {
+			h.log.Warn(ctx, "[heartbeat] 导出任务租约已过期, jobID:%d, error:%v", jobID, err)
+			return leaseUntil, false
+		}
+		return leaseUntil, true
+	}
+	if !ok {
+		h.log.Warn(ctx, "[heartbeat] 导出任务租约已丢失, jobID:%d", jobID)
+		return leaseUntil, false
+	}
+	return nextLeaseUntil, true
+}
+
+// 生成导出文件并上传 OSS，单个布置直接上传报告文件，多个布置打包为 zip
+func (h *TaskExportJobHandler) build(ctx context.Context, job *dao_task.TaskExportJob) (fileName, objectKey string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("导出任务 panic: %v", r)
+		}
+	}()
+
+	if job.Params == nil || len(job.Params.Items) == 0 {
+		return "", "", errors.New("导出参数为空")
+	}
+
+	var content []byte
+	if len(job.Params.Items) == 1 {
+		fileName, content, err = h.reportHandler.ExportTaskReportFile(ctx, h.query(job, job.Params.Items[0]), job.Format)
+		if err != nil {
+			return "", "", err
+		}
+	} else {
+		fileName = fmt.Sprintf("作业报告_%s.zip", time.Unix(job.CreateTime, 0).Format("20060102150405"))
+		content, err = h.buildZip(ctx, job)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return "", "", err
+	}
+	objectKey = path.Join(consts.ExportJobOSSPath, fmt.Sprintf("%d", job.ID), fileName)
+	if err = h.ossClient.PutObject(objectKey, bytes.NewReader(content)); err != nil {
+		return "", "", errors.Wrap(err, "上传导出文件失败")
+	}
+	return fileName, objectKey, nil
+}
+
+func (h *TaskExportJobHandler) buildZip(ctx context.Context, job *dao_task.TaskExportJob) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	names := make(map[string]int, len(job.Params.Items))
+	for _, item := range job.Params.Items {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		fileName, content, err := h.reportHandler.ExportTaskReportFile(ctx, h.query(job, item), job.Format)
+		if err != nil {
+			return nil, errors.Wrapf(err, "导出任务布置失败, taskID:%d, assignID:%d", item.TaskID, item.AssignID)
+		}
+
+		// 不同布置的报告名称可能相同，重名时追加序号
+		names[fileName]++
+		if n := names[fileName]; n > 1 {
+			ext := path.Ext(fileName)
+			fileName = fmt.Sprintf("%s(%d)%s", strings.TrimSuffix(fileName, ext), n, ext)
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     fileName,
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+			Flags:    0x800, // 文件名使用 UTF-8 编码
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (h *TaskExportJobHandler) query(job *dao_task.TaskExportJob, item *dao_task.ExportJobItem) *dto.ExportTaskReportQuery {
+	return &dto.ExportTaskReportQuery{
+		TaskID:       item.TaskID,
+		AssignID:     item.AssignID,
+		ResourceID:   item.ResourceID,
+		ResourceType: item.ResourceType,
+		SortBy:       job.Params.SortBy,
+		SortType:     job.Params.SortType,
+		Fields:       job.Params.Fields,
+	}
+}
+
+func toExportJob(job *dao_task.TaskExportJob) *api.ExportJob {
+	itemCount := int64(0)
+	if job.Params != nil {
+		itemCount = int64(len(job.Params.Items))
+	}
+	return &api.ExportJob{
+		JobID:      job.ID,
+		Status:     job.Status,
+		Format:     job.Format,
+		ItemCount:  itemCount,
+		FileName:   job.FileName,
+		ErrorMsg:   job.ErrorMsg,
+		CreateTime: job.CreateTime,
+		FinishTime: job.FinishTime,
+	}
+}
+
+func truncateErrorMsg(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,194 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+)
+
+type stubExportTaskDAO struct {
+	dao_task.TaskDAO
+	tasks []*dao_task.Task
+}
+
+func (s *stubExportTaskDAO) GetTasksByIDs(ctx context.Context, taskIDs []int64) ([]*dao_task.Task, error) {
+	return s.tasks, nil
+}
+
+type stubExportAssignDAO struct {
+	dao_task.TaskAssignDAO
+	assigns []*dao_task.TaskAssign
+}
+
+func (s *stubExportAssignDAO) GetTaskAssignsByTaskIDs(ctx context.Context, taskIDs []int64) ([]*dao_task.TaskAssign, error) {
+	return s.assigns, nil
+}
+
+// 内存中的导出任务表，条件和 SQL 实现保持一致
+type stubExportJobDAO struct {
+	dao_task.TaskExportJobDAO
+	jobs map[int64]*dao_task.TaskExportJob
+}
+
+func (s *stubExportJobDAO) Create(ctx context.Context, job *dao_task.TaskExportJob) error {
+	job.ID = int64(len(s.jobs) + 1)
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *stubExportJobDAO) FindByStatus(ctx context.Context, status int64, updatedBefore int64, limit int) ([]*dao_task.TaskExportJob, error) {
+	var jobs []*dao_task.TaskExportJob
+	for _, job := range s.jobs {
+		if job.Status == status && job.UpdateTime < updatedBefore {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *stubExportJobDAO) RenewLease(ctx context.Context, jobID int64, fromLeaseUntil, leaseUntil int64) (bool, error) {
+	job, ok := s.jobs[jobID]
+	if !ok || job.Status != consts.EXPORT_JOB_STATUS_RUNNING || job.LeaseUntil != fromLeaseUntil {
+		return false, nil
+	}
+	job.LeaseUntil = leaseUntil
+	return true, nil
+}
+
+func (s *stubExportJobDAO) FindLeaseExpired(ctx context.Context, now int64, limit int) ([]*dao_task.TaskExportJob, error) {
+	var jobs []*dao_task.TaskExportJob
+	for _, job := range s.jobs {
+		if job.Status == consts.EXPORT_JOB_STATUS_RUNNING && job.LeaseUntil < now {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func (s *stubExportJobDAO) ResetLeaseExpired(ctx context.Context, jobID int64, now int64) (bool, error) {
+	job, ok := s.jobs[jobID]
+	if !ok || job.Status != consts.EXPORT_JOB_STATUS_RUNNING || job.LeaseUntil >= now {
+		return false, nil
+	}
+	job.Status = consts.EXPORT_JOB_STATUS_PENDING
+	job.LeaseUntil = 0
+	return true, nil
+}
+
+func newTestExportJobHandler(tasks []*dao_task.Task, assigns []*dao_task.TaskAssign) (*TaskExportJobHandler, *stubExportJobDAO) {
+	jobDAO := &stubExportJobDAO{jobs: make(map[int64]*dao_task.TaskExportJob)}
+	return &TaskExportJobHandler{
+		exportJobDAO:  jobDAO,
+		taskDAO:       &stubExportTaskDAO{tasks: tasks},
+		taskAssignDAO: &stubExportAssignDAO{assigns: assigns},
+		log:           clogger.NewContextLogger(log.DefaultLogger),
+		queue:         make(chan int64, consts.ExportJobQueueSize),
+	}, jobDAO
+}
+
+func TestExportJobResolveItemClassIDs(t *testing.T) {
+	ctx := context.Background()
+	tasks := []*dao_task.Task{
+		{TaskID: 1, SchoolID: 10, CreatorID: 100},
+		{TaskID: 2, SchoolID: 20, CreatorID: 100},
+		{TaskID: 3, SchoolID: 10, CreatorID: 100, Deleted: 1},
+	}
+	assigns := []*dao_task.TaskAssign{
+		{AssignID: 11, TaskID: 1, SchoolID: 10, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1001},
+		{AssignID: 12, TaskID: 1, SchoolID: 10, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1002},
+		{AssignID: 13, TaskID: 1, SchoolID: 10, GroupType: consts.TASK_GROUP_TYPE_STUDENT},
+		{AssignID: 21, TaskID: 2, SchoolID: 20, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 2001},
+		{AssignID: 31, TaskID: 3, SchoolID: 10, GroupType: consts.TASK_GROUP_TYPE_CLASS, GroupID: 1001},
+	}
+	h, _ := newTestExportJobHandler(tasks, assigns)
+
+	classIDs, err := h.ResolveItemClassIDs(ctx, 100, 10, []*dao_task.ExportJobItem{
+		{TaskID: 1, AssignID: 11}, {TaskID: 1, AssignID: 12}, {TaskID: 1, AssignID: 11},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1001, 1002}, classIDs)
+
+	// 布置给学生的只有任务创建者可以导出
+	classIDs, err = h.ResolveItemClassIDs(ctx, 100, 10, []*dao_task.ExportJobItem{{TaskID: 1, AssignID: 13}})
+	assert.NoError(t, err)
+	assert.Empty(t, classIDs)
+	_, err = h.ResolveItemClassIDs(ctx, 101, 10, []*dao_task.ExportJobItem{{TaskID: 1, AssignID: 13}})
+	assert.ErrorIs(t, err, ErrExportItemForbidden)
+
+	// 其他学校的任务、已删除的任务、布置和任务不匹配、布置不存在
+	for _, item := range []*dao_task.ExportJobItem{
+		{TaskID: 2, AssignID: 21},
+		{TaskID: 3, AssignID: 31},
+		{TaskID: 1, AssignID: 21},
+		{TaskID: 1, AssignID: 99},
+		{TaskID: 9, AssignID: 11},
+	} {
+		_, err = h.ResolveItemClassIDs(ctx, 100, 10, []*dao_task.ExportJobItem{{TaskID: 1, AssignID: 11}, item})
+		assert.ErrorIs(t, err, ErrExportItemInvalid, "taskID:%d, assignID:%d", item.TaskID, item.AssignID)
+	}
+}
+
+func TestExportJobSubmit(t *testing.T) {
+	h, jobDAO := newTestExportJobHandler(nil, nil)
+	req := &api.ExportJobSubmitRequest{
+		Items:  []*dao_task.ExportJobItem{{TaskID: 1, AssignID: 11}},
+		Format: consts.ExportFormatCSV,
+	}
+
+	job, err := h.Submit(context.Background(), 100, 10, req)
+	assert.NoError(t, err)
+	saved := jobDAO.jobs[job.JobID]
+	if assert.NotNil(t, saved) {
+		assert.Equal(t, int64(100), saved.TeacherID)
+		assert.Equal(t, int64(10), saved.SchoolID)
+		assert.Equal(t, consts.EXPORT_JOB_STATUS_PENDING, saved.Status)
+		assert.Equal(t, req.Items, saved.Params.Items)
+	}
+	assert.Equal(t, job.JobID, <-h.queue)
+}
+
+func TestExportJobScanLeaseExpired(t *testing.T) {
+	h, jobDAO := newTestExportJobHandler(nil, nil)
+	now := time.Now().Unix()
+	jobDAO.jobs[1] = &dao_task.TaskExportJob{ID: 1, Status: consts.EXPORT_JOB_STATUS_RUNNING, LeaseUntil: now - 1, UpdateTime: now - 3600}
+	jobDAO.jobs[2] = &dao_task.TaskExportJob{ID: 2, Status: consts.EXPORT_JOB_STATUS_RUNNING, LeaseUntil: now + consts.ExportJobLeaseDuration, UpdateTime: now - 3600}
+
+	// 执行时间超过租约但仍在续期的任务不会被重置
+	h.scan()
+	assert.Equal(t, consts.EXPORT_JOB_STATUS_PENDING, jobDAO.jobs[1].Status)
+	assert.Equal(t, consts.EXPORT_JOB_STATUS_RUNNING, jobDAO.jobs[2].Status)
+	assert.Equal(t, int64(1), <-h.queue)
+
+	// 重置后的任务不再续期
+	ok, err := jobDAO.RenewLease(context.Background(), 1, now-1, now+consts.ExportJobLeaseDuration)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestExportJobRenewLease(t *testing.T) {
+	h, jobDAO := newTestExportJobHandler(nil, nil)
+	now := time.Now().Unix()
+	jobDAO.jobs[1] = &dao_task.TaskExportJob{ID: 1, Status: consts.EXPORT_JOB_STATUS_RUNNING, LeaseUntil: now + 10}
+
+	leaseUntil, ok := h.renewLease(context.Background(), 1, now+10)
+	assert.True(t, ok)
+	assert.Equal(t, jobDAO.jobs[1].LeaseUntil, leaseUntil)
+
+	// 租约过期后被其他实例重新抢占，原实例的租约丢失
+	jobDAO.jobs[1].LeaseUntil = leaseUntil + 1
+	_, ok = h.renewLease(context.Background(), 1, leaseUntil)
+	assert.False(t, ok)
+
+	// 任务被重置为等待执行
+	jobDAO.jobs[1].Status = consts.EXPORT_JOB_STATUS_PENDING
+	_, ok = h.renewLease(context.Background(), 1, jobDAO.jobs[1].LeaseUntil)
+	assert.False(t, ok)
+}
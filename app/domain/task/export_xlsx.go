@@ -0,0 +1,152 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+	"gil_teacher/app/utils/xlsx"
+)
+
+// xlsx 导出的 sheet 名称
+const (
+	sheetNameStudentSummary = "学生汇总"
+	sheetNameQuestions      = "题目统计"
+	sheetNameAnswerMatrix   = "作答明细"
+)
+
+// 作答明细中的作答结果
+const (
+	answerMarkCorrect   = "√"
+	answerMarkIncorrect = "×"
+)
+
+// ExportTaskReportWorkbook 导出指定班级的任务报告（xlsx）
+// 包含三个 sheet：学生汇总（按 query.Fields 输出）、题目统计（答题面板数据）、作答明细（学生 x 题目矩阵）
+func (h *TaskReportHandler) ExportTaskReportWorkbook(ctx context.Context, query *dto.ExportTaskReportQuery) (string, *xlsx.Workbook, error) {
+	exportData, err := h.getExportData(ctx, query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	panel, err := h.GetTaskAnswerPanel(ctx, &dto.TaskReportCommonQuery{
+		TaskID:       query.TaskID,
+		AssignID:     query.AssignID,
+		ResourceID:   query.ResourceID,
+		ResourceType: query.ResourceType,
+	})
+	if err != nil {
+		h.log.Error(ctx, "[ExportTaskReportWorkbook] GetTaskAnswerPanel failed, taskID:%d, assignID:%d, error:%v", query.TaskID, query.AssignID, err)
+		return "", nil, err
+	}
+	questions := panel.Panel
+	sort.Slice(questions, func(i, j int) bool {
+		return questions[i].QuestionIndex < questions[j].QuestionIndex
+	})
+
+	studentAnswers, err := h.taskReportService.GetTaskAssignAnswers(ctx, &dto.TaskAssignAnswersQuery{
+		TaskReportCommonQuery: dto.TaskReportCommonQuery{
+			TaskID:       query.TaskID,
+			AssignID:     query.AssignID,
+			ResourceID:   query.ResourceID,
+			ResourceType: query.ResourceType,
+		},
+	}, consts.AllDataPageInfo())
+	if err != nil {
+		h.log.Error(ctx, "[ExportTaskReportWorkbook] GetTaskAssignAnswers failed, taskID:%d, assignID:%d, error:%v", query.TaskID, query.AssignID, err)
+		return "", nil, err
+	}
+
+	workbook := xlsx.NewWorkbook()
+	h.fillStudentSummarySheet(workbook.AddSheet(sheetNameStudentSummary), exportData, query.Fields)
+	h.fillQuestionSheet(workbook.AddSheet(sheetNameQuestions), questions)
+
+	matrix := workbook.AddSheet(sheetNameAnswerMatrix)
+	header := []string{consts.ExportFieldsCN["studentName"]}
+	for _, question := range questions {
+		header = append(header, fmt.Sprintf("第%d题", question.QuestionIndex))
+	}
+	header = append(header, "答对数", "答错数")
+	matrix.SetHeader(header)
+	for _, studentReport := range exportData.studentReports {
+		answers := studentAnswers[studentReport.StudentID]
+		row := []xlsx.Cell{xlsx.Text(exportData.studentName(studentReport.StudentID))}
+		correct, incorrect := int64(0), int64(0)
+		for _, question := range questions {
+			resourceKey := utils.JoinList([]any{question.ResourceID, question.ResourceType}, consts.CombineKey)
+			questionKey := utils.JoinList([]any{resourceKey, question.QuestionID}, consts.CombineKey)
+			answer, ok := answers[questionKey]
+			switch {
+			case !ok:
+				row = append(row, xlsx.Empty())
+			case answer.Correctness:
+				correct++
+				row = append(row, xlsx.Text(answerMarkCorrect))
+			default:
+				incorrect++
+				row = append(row, xlsx.Text(answerMarkIncorrect))
+			}
+		}
+		row = append(row, xlsx.Int(correct), xlsx.Int(incorrect))
+		matrix.AddRow(row...)
+	}
+
+	return exportData.fileName, workbook, nil
+}
+
+// 学生汇总 sheet，字段顺序与 csv 导出一致，数值列写入数值类型
+func (h *TaskReportHandler) fillStudentSummarySheet(sheet *xlsx.Sheet, exportData *exportData, fields []string) {
+	header := make([]string, 0, len(fields)+1)
+	for _, field := range fields {
+		if field == "incorrectCount/answerCount" {
+			// 拆分为两列，便于在表格中计算
+			header = append(header, "错题数", "答题数")
+			continue
+		}
+		header = append(header, consts.ExportFieldsCN[field])
+	}
+	sheet.SetHeader(header)
+
+	for _, studentReport := range exportData.studentReports {
+		row := make([]xlsx.Cell, 0, len(header))
+		for _, field := range fields {
+			switch field {
+			case "studentName":
+				row = append(row, xlsx.Text(exportData.studentName(studentReport.StudentID)))
+			case "studyScore":
+				row = append(row, xlsx.Int(studentReport.StudyScore))
+			case "progress":
+				row = append(row, xlsx.Percent(studentReport.Progress))
+			case "accuracyRate":
+				row = append(row, xlsx.Percent(studentReport.AccuracyRate))
+			case "difficultyDegree":
+				row = append(row, xlsx.Float(studentReport.DifficultyDegree))
+			case "incorrectCount/answerCount":
+				row = append(row, xlsx.Int(studentReport.IncorrectNum), xlsx.Int(studentReport.AnswerNum))
+			case "answerTime":
+				row = append(row, xlsx.Int(studentReport.CostTime))
+			}
+		}
+		sheet.AddRow(row...)
+	}
+}
+
+// 题目统计 sheet，每个题目一行
+func (h *TaskReportHandler) fillQuestionSheet(sheet *xlsx.Sheet, questions []*api.QuestionPanel) {
+	sheet.SetHeader([]string{"题号", "资源类型", "资源ID", "题目ID", "作答人数", "答错人数", "正确率"})
+	for _, question := range questions {
+		sheet.AddRow(
+			xlsx.Int(question.QuestionIndex),
+			xlsx.Text(consts.GetResourceTypeName(question.ResourceType)),
+			xlsx.Text(question.ResourceID),
+			xlsx.Text(question.QuestionID),
+			xlsx.Int(question.AnswerCount),
+			xlsx.Int(question.IncorrectCount),
+			xlsx.Percent(question.CorrectRate),
+		)
+	}
+}
@@ -0,0 +1,427 @@
+package task
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/model/itl"
+	"gil_teacher/app/service/gil_internal/question_service"
+	"gil_teacher/app/utils"
+)
+
+// 任务不存在、不属于当前学校，或布置不属于该任务
+var ErrTaskQuestionReport = errors.New("任务或布置不存在")
+
+// 作业提问记录的统计数据
+type questionAskData struct {
+	questions  []*api.QuestionAskReport          // 有提问的题目，按提问次数倒序
+	knowledges []*api.KnowledgeAskStat           // 有提问的知识点，按提问次数倒序
+	nodeNames  map[int64]string                  // bizTreeNodeID -> 业务树叶子节点名称
+	askerIDs   map[int64]map[uint64]struct{}     // bizTreeNodeID -> 提问的学生
+	askCount   int64                             // 提问总次数
+	allAskers  map[uint64]struct{}               // 提问的全部学生
+	resources  map[string]*dao_task.TaskResource // questionID -> 题目所属资源
+}
+
+// GetTaskQuestions 获取任务布置的全部提问记录，按题目和知识点分组统计
+func (h *TaskReportHandler) GetTaskQuestions(ctx context.Context, schoolID int64, query *api.TaskQuestionsQuery) (*api.TaskQuestionsResponse, error) {
+	data, err := h.getQuestionAskData(ctx, schoolID, query.TaskID, query.AssignID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.TaskQuestionsResponse{
+		TaskID:     query.TaskID,
+		AssignID:   query.AssignID,
+		AskCount:   data.askCount,
+		AskerCount: int64(len(data.allAskers)),
+		Questions:  data.questions,
+		Knowledges: data.knowledges,
+	}, nil
+}
+
+// GetKnowledgeQuestions 获取任务布置中单个知识点的全部提问记录
+func (h *TaskReportHandler) GetKnowledgeQuestions(ctx context.Context, schoolID int64, query *api.TaskQuestionsQuery) (*api.KnowledgeQuestionsResponse, error) {
+	data, err := h.getQuestionAskData(ctx, schoolID, query.TaskID, query.AssignID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &api.KnowledgeQuestionsResponse{
+		TaskID:   query.TaskID,
+		AssignID: query.AssignID,
+		KnowledgeAskStat: &api.KnowledgeAskStat{
+			BizTreeNodeID:   query.BizTreeNodeID,
+			BizTreeNodeName: data.nodeNames[query.BizTreeNodeID],
+			QuestionIDs:     []string{},
+		},
+		Questions: []*api.QuestionAskReport{},
+	}
+	for _, knowledge := range data.knowledges {
+		if knowledge.BizTreeNodeID == query.BizTreeNodeID {
+			res.KnowledgeAskStat = knowledge
+			break
+		}
+	}
+	for _, question := range data.questions {
+		if question.BizTreeNodeID == query.BizTreeNodeID {
+			res.Questions = append(res.Questions, question)
+		}
+	}
+	return res, nil
+}
+
+// 查询任务布置的提问会话和学生消息，并按题目、学生、知识点汇总
+func (h *TaskReportHandler) getQuestionAskData(ctx context.Context, schoolID, taskID, assignID int64) (*questionAskData, error) {
+	task, err := h.taskService.GetTaskByID(ctx, taskID)
+	if err != nil || task.SchoolID != schoolID {
+		h.log.Warn(ctx, "[getQuestionAskData] task not found, taskID:%d, error:%v", taskID, err)
+		return nil, ErrTaskQuestionReport
+	}
+	assigns, err := h.taskAssignService.GetTaskAssignInfo(ctx, taskID, assignID)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionAskData] GetTaskAssignInfo failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	if len(assigns) == 0 {
+		return nil, ErrTaskQuestionReport
+	}
+
+	data := &questionAskData{
+		questions:  make([]*api.QuestionAskReport, 0),
+		knowledges: make([]*api.KnowledgeAskStat, 0),
+		nodeNames:  make(map[int64]string),
+		askerIDs:   make(map[int64]map[uint64]struct{}),
+		allAskers:  make(map[uint64]struct{}),
+	}
+
+	sessions, err := h.behaviorDAO.GetTaskQuestionSessions(ctx, taskID, assignID)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionAskData] GetTaskQuestionSessions failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return data, nil
+	}
+
+	// 题目所属的资源和知识点
+	questionNodes, err := h.getQuestionResources(ctx, task, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 学生发送的消息，第一条消息作为提问内容
+	sessionIDs := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		sessionIDs = append(sessionIDs, session.SessionID)
+	}
+	messages, err := h.behaviorDAO.GetSessionsStudentMessages(ctx, sessionIDs)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionAskData] GetSessionsStudentMessages failed, taskID:%d, assignID:%d, error:%v", taskID, assignID, err)
+		return nil, err
+	}
+	sessionMessages := make(map[string][]*dto.CommunicationMessageDTO)
+	for _, message := range messages {
+		sessionMessages[message.SessionID] = append(sessionMessages[message.SessionID], message)
+	}
+
+	// 按题目、学生汇总会话
+	questionMap := make(map[string]*api.QuestionAskReport)
+	askerMap := make(map[string]map[uint64]*api.QuestionAsker) // questionID -> studentID -> asker
+	for _, session := range sessions {
+		questionID, ok := consts.ParseTaskQuestionTargetID(utils.PtrValue(session.TargetID))
+		if !ok {
+			continue
+		}
+		question, ok := questionMap[questionID]
+		if !ok {
+			question = &api.QuestionAskReport{
+				QuestionID:    questionID,
+				BizTreeNodeID: questionNodes[questionID],
+				Askers:        make([]*api.QuestionAsker, 0),
+			}
+			if resource, ok := data.resources[questionID]; ok {
+				question.ResourceID = resource.ResourceID
+				question.ResourceType = resource.ResourceType
+			}
+			questionMap[questionID] = question
+			askerMap[questionID] = make(map[uint64]*api.QuestionAsker)
+			data.questions = append(data.questions, question)
+		}
+		asker, ok := askerMap[questionID][session.UserID]
+		if !ok {
+			asker = &api.QuestionAsker{
+				Student:  &api.Student{StudentID: int64(session.UserID)},
+				Sessions: make([]*api.QuestionAskSession, 0),
+			}
+			askerMap[questionID][session.UserID] = asker
+			question.Askers = append(question.Askers, asker)
+		}
+
+		askSession := &api.QuestionAskSession{
+			SessionID:    session.SessionID,
+			MessageCount: int64(len(sessionMessages[session.SessionID])),
+			Closed:       session.Closed,
+			StartTime:    session.StartTime.Unix(),
+		}
+		if askSession.MessageCount > 0 {
+			askSession.FirstMessage = sessionMessages[session.SessionID][0].MessageContent
+		}
+		asker.Sessions = append(asker.Sessions, askSession)
+		asker.AskCount++
+		question.AskCount++
+		data.askCount++
+		data.allAskers[session.UserID] = struct{}{}
+		if data.askerIDs[question.BizTreeNodeID] == nil {
+			data.askerIDs[question.BizTreeNodeID] = make(map[uint64]struct{})
+		}
+		data.askerIDs[question.BizTreeNodeID][session.UserID] = struct{}{}
+	}
+
+	if err := h.fillAskerInfo(ctx, schoolID, data); err != nil {
+		return nil, err
+	}
+	h.buildKnowledgeAskStats(ctx, task, data)
+	return data, nil
+}
+
+// 获取题目所属的资源和业务树叶子节点，巩固练习中的题目取题集所在的节点，
+// 单独布置的题目按题目关联的知识点匹配
+//
+//	map[questionID]bizTreeNodeID
+func (h *TaskReportHandler) getQuestionResources(ctx context.Context, task *dao_task.Task, data *questionAskData) (map[string]int64, error) {
+	taskResources, _, err := h.taskResourceService.GetTaskResourceByTaskID(ctx, task.TaskID)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionResources] GetTaskResourceByTaskID failed, taskID:%d, error:%v", task.TaskID, err)
+		return nil, err
+	}
+
+	data.resources = make(map[string]*dao_task.TaskResource)
+	practiceIDs := make([]int64, 0)
+	questionIDs := make([]string, 0)
+	for _, resource := range taskResources {
+		switch resource.ResourceType {
+		case consts.RESOURCE_TYPE_QUESTION:
+			data.resources[resource.ResourceID] = resource
+			questionIDs = append(questionIDs, resource.ResourceID)
+		case consts.RESOURCE_TYPE_PRACTICE:
+			practiceIDs = append(practiceIDs, utils.Atoi64(resource.ResourceID))
+			for _, questionID := range resource.ResourceSubIDs {
+				data.resources[questionID] = resource
+			}
+		}
+	}
+
+	practiceNodes, err := getPracticeBizTreeNodes(ctx, h.questionAPI, practiceIDs)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionResources] getPracticeBizTreeNodes failed, taskID:%d, error:%v", task.TaskID, err)
+		return nil, err
+	}
+	questionNodes, err := getQuestionBizTreeNodes(ctx, h.questionAPI, task.Phase, task.Subject, questionIDs)
+	if err != nil {
+		h.log.Error(ctx, "[getQuestionResources] getQuestionBizTreeNodes failed, taskID:%d, error:%v", task.TaskID, err)
+		return nil, err
+	}
+	for questionID, resource := range data.resources {
+		if resource.ResourceType == consts.RESOURCE_TYPE_PRACTICE {
+			questionNodes[questionID] = practiceNodes[resource.ResourceID]
+		}
+	}
+	return questionNodes, nil
+}
+
+// 查询单独布置的题目关联的知识点，在任务学段学科的业务树中匹配关联了该知识点的叶子节点，
+// 全部匹配后不再查询其余业务树，map[questionID]bizTreeNodeID
+func getQuestionBizTreeNodes(ctx context.Context, questionAPI *question_service.Client, phase, subject int64, questionIDs []string) (map[string]int64, error) {
+	questionNodes := make(map[string]int64, len(questionIDs))
+	if len(questionIDs) == 0 {
+		return questionNodes, nil
+	}
+	questions, err := questionAPI.GetQuestionListByID(ctx, questionIDs, false)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[string][]int64, len(questions))
+	for _, question := range questions {
+		if question != nil && question.QuestionInfoEntity != nil && len(question.BaseTreeNodeIds) > 0 {
+			pending[question.QuestionId] = question.BaseTreeNodeIds
+		}
+	}
+	if len(pending) == 0 {
+		return questionNodes, nil
+	}
+
+	bizTrees, err := questionAPI.GetBizTreeList(ctx, consts.QuestionBizTreeTypeAll, phase, subject)
+	if err != nil {
+		return nil, err
+	}
+	for _, bizTree := range bizTrees {
+		if len(pending) == 0 {
+			break
+		}
+		detail, err := questionAPI.GetBizTreeDetail(ctx, bizTree.BizTreeId)
+		if err != nil {
+			return nil, err
+		}
+		if detail != nil {
+			matchQuestionBizTreeNodes(detail.BizTreeDetail, pending, questionNodes)
+		}
+	}
+	return questionNodes, nil
+}
+
+// 遍历业务树，题目关联的任一知识点属于叶子节点时记录该节点，按遍历顺序取第一个匹配的叶子节点
+func matchQuestionBizTreeNodes(node *itl.BizTreeNodeEntity, pending map[string][]int64, questionNodes map[string]int64) {
+	if node == nil || len(pending) == 0 {
+		return
+	}
+	if len(node.BizTreeNodeChildren) == 0 {
+		for questionID, baseNodeIDs := range pending {
+			for _, baseNodeID := range baseNodeIDs {
+				if slices.Contains(node.BaseTreeNodeIDs, baseNodeID) {
+					questionNodes[questionID] = node.BizTreeNodeId
+					delete(pending, questionID)
+					break
+				}
+			}
+		}
+		return
+	}
+	for _, child := range node.BizTreeNodeChildren {
+		matchQuestionBizTreeNodes(child, pending, questionNodes)
+	}
+}
+
+// 查询巩固练习所属的业务树叶子节点，map[practiceID]bizTreeNodeID
+func getPracticeBizTreeNodes(ctx context.Context, questionAPI *question_service.Client, practiceIDs []int64) (map[string]int64, error) {
+	practiceNodes := make(map[string]int64, len(practiceIDs))
+	if len(practiceIDs) == 0 {
+		return practiceNodes, nil
+	}
+	practices, err := questionAPI.CheckQuestionSetExistByIDs(ctx, practiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, practice := range practices {
+		practiceNodes[utils.I64ToStr(practice.QuestionSetId)] = practice.BizTreeNodeId
+	}
+	return practiceNodes, nil
+}
+
+// 填充提问学生的姓名和头像，并按提问次数排序
+func (h *TaskReportHandler) fillAskerInfo(ctx context.Context, schoolID int64, data *questionAskData) error {
+	studentIDs := make([]int64, 0, len(data.allAskers))
+	for studentID := range data.allAskers {
+		studentIDs = append(studentIDs, int64(studentID))
+	}
+	studentInfoMap, err := h.ucenterService.GetStudentInfoByID(ctx, schoolID, studentIDs)
+	if err != nil {
+		h.log.Error(ctx, "[fillAskerInfo] GetStudentInfoByID failed, error:%v", err)
+		return err
+	}
+
+	for _, question := range data.questions {
+		for _, asker := range question.Askers {
+			if info, ok := studentInfoMap[asker.StudentID]; ok {
+				asker.StudentName = info.Student.Name
+				asker.Avatar = info.Student.Avatar
+			}
+		}
+		sort.SliceStable(question.Askers, func(i, j int) bool {
+			if question.Askers[i].AskCount != question.Askers[j].AskCount {
+				return question.Askers[i].AskCount > question.Askers[j].AskCount
+			}
+			return question.Askers[i].StudentID < question.Askers[j].StudentID
+		})
+	}
+	sort.SliceStable(data.questions, func(i, j int) bool {
+		return data.questions[i].AskCount > data.questions[j].AskCount
+	})
+	return nil
+}
+
+// 按业务树叶子节点汇总提问，节点名称查询失败时不影响提问记录
+func (h *TaskReportHandler) buildKnowledgeAskStats(ctx context.Context, task *dao_task.Task, data *questionAskData) {
+	knowledgeMap := make(map[int64]*api.KnowledgeAskStat)
+	nodeIDs := make([]int64, 0)
+	for _, question := range data.questions {
+		knowledge, ok := knowledgeMap[question.BizTreeNodeID]
+		if !ok {
+			knowledge = &api.KnowledgeAskStat{
+				BizTreeNodeID: question.BizTreeNodeID,
+				AskerCount:    int64(len(data.askerIDs[question.BizTreeNodeID])),
+				QuestionIDs:   make([]string, 0),
+			}
+			knowledgeMap[question.BizTreeNodeID] = knowledge
+			data.knowledges = append(data.knowledges, knowledge)
+			if question.BizTreeNodeID != 0 {
+				nodeIDs = append(nodeIDs, question.BizTreeNodeID)
+			}
+		}
+		knowledge.AskCount += question.AskCount
+		knowledge.QuestionIDs = append(knowledge.QuestionIDs, question.QuestionID)
+	}
+
+	nodeNames, err := getBizTreeNodeNames(ctx, h.questionAPI, task.Phase, task.Subject, nodeIDs)
+	if err != nil {
+		h.log.Warn(ctx, "[buildKnowledgeAskStats] getBizTreeNodeNames failed, taskID:%d, error:%v", task.TaskID, err)
+	}
+	data.nodeNames = nodeNames
+	for _, knowledge := range data.knowledges {
+		knowledge.BizTreeNodeName = nodeNames[knowledge.BizTreeNodeID]
+	}
+	sort.SliceStable(data.knowledges, func(i, j int) bool {
+		return data.knowledges[i].AskCount > data.knowledges[j].AskCount
+	})
+}
+
+// 在任务学段学科的业务树中查找节点名称，全部找到后不再查询其余业务树
+func getBizTreeNodeNames(ctx context.Context, questionAPI *question_service.Client, phase, subject int64, nodeIDs []int64) (map[int64]string, error) {
+	nodeNames := make(map[int64]string)
+	if len(nodeIDs) == 0 {
+		return nodeNames, nil
+	}
+
+	bizTrees, err := questionAPI.GetBizTreeList(ctx, consts.QuestionBizTreeTypeAll, phase, subject)
+	if err != nil {
+		return nodeNames, err
+	}
+	pending := make(map[int64]struct{}, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		pending[nodeID] = struct{}{}
+	}
+	for _, bizTree := range bizTrees {
+		if len(pending) == 0 {
+			break
+		}
+		detail, err := questionAPI.GetBizTreeDetail(ctx, bizTree.BizTreeId)
+		if err != nil {
+			return nodeNames, err
+		}
+		if detail != nil {
+			collectBizTreeNodeNames(detail.BizTreeDetail, pending, nodeNames)
+		}
+	}
+	return nodeNames, nil
+}
+
+// 遍历业务树，记录待查找节点的名称
+func collectBizTreeNodeNames(node *itl.BizTreeNodeEntity, pending map[int64]struct{}, nodeNames map[int64]string) {
+	if node == nil {
+		return
+	}
+	if _, ok := pending[node.BizTreeNodeId]; ok {
+		nodeNames[node.BizTreeNodeId] = node.BizTreeNodeName
+		delete(pending, node.BizTreeNodeId)
+	}
+	for _, child := range node.BizTreeNodeChildren {
+		collectBizTreeNodeNames(child, pending, nodeNames)
+	}
+}
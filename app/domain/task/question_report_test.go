@@ -0,0 +1,39 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/model/itl"
+)
+
+func TestMatchQuestionBizTreeNodes(t *testing.T) {
+	tree := &itl.BizTreeNodeEntity{
+		BizTreeNodeId: 1,
+		// 非叶子节点关联的知识点不参与匹配
+		BaseTreeNodeIDs: []int64{100, 200, 300},
+		BizTreeNodeChildren: []*itl.BizTreeNodeEntity{
+			{BizTreeNodeId: 11, BaseTreeNodeIDs: []int64{100}},
+			{BizTreeNodeId: 12, BizTreeNodeChildren: []*itl.BizTreeNodeEntity{
+				{BizTreeNodeId: 121, BaseTreeNodeIDs: []int64{200, 100}},
+			}},
+		},
+	}
+	pending := map[string][]int64{
+		"q1": {100},
+		"q2": {200},
+		"q3": {900, 100}, // 任一知识点匹配即可
+		"q4": {300},      // 只有非叶子节点关联
+	}
+	questionNodes := make(map[string]int64)
+
+	matchQuestionBizTreeNodes(tree, pending, questionNodes)
+	assert.Equal(t, map[string]int64{"q1": 11, "q2": 121, "q3": 11}, questionNodes)
+	assert.Equal(t, map[string][]int64{"q4": {300}}, pending)
+
+	// 已匹配的题目不会被后续业务树覆盖
+	matchQuestionBizTreeNodes(&itl.BizTreeNodeEntity{BizTreeNodeId: 2, BaseTreeNodeIDs: []int64{100, 300}}, pending, questionNodes)
+	assert.Equal(t, map[string]int64{"q1": 11, "q2": 121, "q3": 11, "q4": 2}, questionNodes)
+	assert.Empty(t, pending)
+}
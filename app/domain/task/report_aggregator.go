@@ -0,0 +1,511 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+)
+
+// TaskReportAggregator 消费学生作答事件，聚合生成任务报告
+//
+//	tbl_task_student_details 作答详情，按题目覆盖写入
+//	tbl_task_students_report 学生维度报告，由作答详情重新汇总
+//	tbl_task_report          布置维度报告，由学生报告重新汇总
+//
+// 每一层都由下一层的持久化数据重新计算，重复消费或重放消息结果一致。
+// 学习分按任务所属学校和学科的学习分模型计算，与课堂学习分一致。
+type TaskReportAggregator struct {
+	taskDAO           dao_task.TaskDAO
+	taskResourceDAO   dao_task.TaskResourceDAO
+	taskStudentDAO    dao_task.TaskStudentDAO
+	taskReportDAO     dao_task.TaskReportDAO
+	studentsReportDAO dao_task.TaskStudentsReportDao
+	studentDetailsDAO dao_task.TaskStudentDetailsDao
+	errorBook         *ClassErrorBookCollector
+	rules             *behavior.BehaviorRuleStore
+	logger            *clogger.ContextLogger
+}
+
+func NewTaskReportAggregator(
+	taskDAO dao_task.TaskDAO,
+	taskResourceDAO dao_task.TaskResourceDAO,
+	taskStudentDAO dao_task.TaskStudentDAO,
+	taskReportDAO dao_task.TaskReportDAO,
+	studentsReportDAO dao_task.TaskStudentsReportDao,
+	studentDetailsDAO dao_task.TaskStudentDetailsDao,
+	errorBook *ClassErrorBookCollector,
+	rules *behavior.BehaviorRuleStore,
+	logger *clogger.ContextLogger,
+) *TaskReportAggregator {
+	return &TaskReportAggregator{
+		taskDAO:           taskDAO,
+		taskResourceDAO:   taskResourceDAO,
+		taskStudentDAO:    taskStudentDAO,
+		taskReportDAO:     taskReportDAO,
+		studentsReportDAO: studentsReportDAO,
+		studentDetailsDAO: studentDetailsDAO,
+		errorBook:         errorBook,
+		rules:             rules,
+		logger:            logger,
+	}
+}
+
+// 任务布置
+type assignKey struct {
+	taskID   int64
+	assignID int64
+}
+
+// 一个布置下本批次涉及的学生及资源题目数
+type assignBatch struct {
+	studentIDs     map[int64]struct{}
+	questionCounts map[string]int64 // resource_key -> 事件携带的题目总数
+}
+
+func (a *TaskReportAggregator) validateAnswerEvent(event *dto.TaskAnswerEventDTO) error {
+	if event.TaskID == 0 || event.AssignID == 0 {
+		return errors.New("任务ID、布置ID不能为0")
+	}
+	if event.StudentID == 0 {
+		return errors.New("学生ID不能为0")
+	}
+	if event.ResourceID == "" || event.ResourceType == 0 {
+		return errors.New("资源ID、资源类型不能为空")
+	}
+	if event.QuestionID == "" {
+		return errors.New("题目ID不能为空")
+	}
+	return nil
+}
+
+// Aggregate 写入一批作答事件，并重新汇总受影响的学生报告和布置报告
+func (a *TaskReportAggregator) Aggregate(ctx context.Context, events []*dto.TaskAnswerEventDTO) error {
+	details, batches := a.collectAnswerDetails(ctx, events)
+	if len(details) == 0 {
+		return nil
+	}
+
+	if err := a.studentDetailsDAO.BatchUpsert(ctx, details); err != nil {
+		return errors.Wrap(err, "写入作答详情失败")
+	}
+
+	var errs []error
+	for key, batch := range batches {
+		if err := a.aggregateAssign(ctx, key, batch); err != nil {
+			a.logger.Error(ctx, "[Aggregate] 汇总任务报告失败, taskID:%d, assignID:%d, error:%v", key.taskID, key.assignID, err)
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Wrapf(errs[0], "汇总任务报告失败, 失败布置数:%d", len(errs))
+	}
+	return nil
+}
+
+// ReplaceStudentAnswers 用一批作答整体覆盖学生在指定资源下的作答，并重新汇总报告
+// 先按事件写入作答详情，再删除这些学生在该资源下 answerTime 之前的作答，本批次中没有的题目不再保留
+// 事件的作答时间不能早于 answerTime，学生在本批次中没有作答时清空该资源的作答
+func (a *TaskReportAggregator) ReplaceStudentAnswers(ctx context.Context, taskID, assignID int64, resourceKey string, studentIDs []int64, answerTime int64, events []*dto.TaskAnswerEventDTO) error {
+	details, batches := a.collectAnswerDetails(ctx, events)
+	if len(details) > 0 {
+		if err := a.studentDetailsDAO.BatchUpsert(ctx, details); err != nil {
+			return errors.Wrap(err, "写入作答详情失败")
+		}
+	}
+	if err := a.studentDetailsDAO.DeleteStudentResourceDetails(ctx, taskID, assignID, resourceKey, studentIDs, answerTime); err != nil {
+		return errors.Wrap(err, "删除作答详情失败")
+	}
+
+	key := assignKey{taskID: taskID, assignID: assignID}
+	batch, ok := batches[key]
+	if !ok {
+		batch = &assignBatch{
+			studentIDs:     make(map[int64]struct{}),
+			questionCounts: make(map[string]int64),
+		}
+	}
+	for _, studentID := range studentIDs {
+		batch.studentIDs[studentID] = struct{}{}
+	}
+	return a.aggregateAssign(ctx, key, batch)
+}
+
+// 将作答事件转换为作答详情，同一题目在一个批次内多次作答时只保留作答时间最晚的一次
+func (a *TaskReportAggregator) collectAnswerDetails(ctx context.Context, events []*dto.TaskAnswerEventDTO) ([]*dao_task.TaskStudentDetails, map[assignKey]*assignBatch) {
+	detailMap := make(map[string]*dao_task.TaskStudentDetails)
+	batches := make(map[assignKey]*assignBatch)
+	for _, event := range events {
+		if err := a.validateAnswerEvent(event); err != nil {
+			a.logger.Error(ctx, "[Aggregate] 作答事件校验失败, error:%v, event:%+v", err, event)
+			continue
+		}
+
+		answerTime := event.AnswerTime
+		if answerTime == 0 {
+			answerTime = time.Now().Unix()
+		}
+		resourceKey := utils.JoinList([]any{event.ResourceID, event.ResourceType}, consts.CombineKey)
+		uniqKey := utils.JoinList([]any{event.TaskID, event.AssignID, event.StudentID, resourceKey, event.QuestionID}, consts.CombineKey)
+		if exist, ok := detailMap[uniqKey]; ok && exist.AnswerTime > answerTime {
+			continue
+		}
+		detailMap[uniqKey] = &dao_task.TaskStudentDetails{
+			TaskID:        event.TaskID,
+			AssignID:      event.AssignID,
+			ResourceKey:   resourceKey,
+			QuestionID:    event.QuestionID,
+			StudentID:     event.StudentID,
+			AnswerContent: event.AnswerContent,
+			Correctness:   event.Correctness,
+			CostTime:      event.CostTime,
+			AnswerTime:    answerTime,
+		}
+
+		key := assignKey{taskID: event.TaskID, assignID: event.AssignID}
+		batch, ok := batches[key]
+		if !ok {
+			batch = &assignBatch{
+				studentIDs:     make(map[int64]struct{}),
+				questionCounts: make(map[string]int64),
+			}
+			batches[key] = batch
+		}
+		batch.studentIDs[event.StudentID] = struct{}{}
+		if event.QuestionCount > batch.questionCounts[resourceKey] {
+			batch.questionCounts[resourceKey] = event.QuestionCount
+		}
+	}
+
+	details := make([]*dao_task.TaskStudentDetails, 0, len(detailMap))
+	for _, detail := range detailMap {
+		details = append(details, detail)
+	}
+	return details, batches
+}
+
+// 重新汇总一个布置下的学生报告和布置报告
+func (a *TaskReportAggregator) aggregateAssign(ctx context.Context, key assignKey, batch *assignBatch) error {
+	questionTotals, err := a.getResourceQuestionTotals(ctx, key.taskID, batch.questionCounts)
+	if err != nil {
+		return err
+	}
+	scoreModel, err := a.getLearningScoreModel(ctx, key.taskID)
+	if err != nil {
+		return err
+	}
+
+	studentIDs := make([]int64, 0, len(batch.studentIDs))
+	for studentID := range batch.studentIDs {
+		studentIDs = append(studentIDs, studentID)
+	}
+	answerStats, err := a.studentDetailsDAO.GetStudentResourceAnswerStats(ctx, key.taskID, key.assignID, studentIDs)
+	if err != nil {
+		return errors.Wrap(err, "汇总学生作答数据失败")
+	}
+
+	statMap := make(map[int64][]*dao_task.StudentResourceAnswerStat)
+	for _, stat := range answerStats {
+		statMap[stat.StudentID] = append(statMap[stat.StudentID], stat)
+	}
+
+	// 分层作业的学生只统计共用资源和所在分层的资源，错题作业的学生只统计自己的资源
+	studentTiers, err := a.taskStudentDAO.GetStudentTiers(ctx, key.taskID, key.assignID, studentIDs)
+	if err != nil {
+		return errors.Wrap(err, "查询学生分层失败")
+	}
+
+	now := time.Now().Unix()
+	// 作答被全部删除的学生没有汇总数据，同样需要覆盖学生报告
+	studentReports := make([]*dao_task.TaskStudentsReport, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		stats := statMap[studentID]
+		visibleTotals, visibleStats := filterStudentResources(questionTotals, stats, studentTiers[studentID], studentID)
+		report := buildStudentReport(visibleStats, visibleTotals, scoreModel)
+		report.TaskID = key.taskID
+		report.AssignID = key.assignID
+		report.StudentID = studentID
+		report.CreateTime = now
+		report.UpdateTime = now
+		studentReports = append(studentReports, report)
+	}
+	if err := a.studentsReportDAO.BatchUpsert(ctx, studentReports); err != nil {
+		return errors.Wrap(err, "写入学生报告失败")
+	}
+
+	return a.aggregateAssignReport(ctx, key)
+}
+
+// 由布置下全部学生报告汇总布置报告
+func (a *TaskReportAggregator) aggregateAssignReport(ctx context.Context, key assignKey) error {
+	studentTiers, err := a.taskStudentDAO.GetStudentTiers(ctx, key.taskID, key.assignID, nil)
+	if err != nil {
+		return errors.Wrap(err, "查询布置学生失败")
+	}
+	resources, err := a.taskResourceDAO.GetByTaskID(ctx, key.taskID)
+	if err != nil {
+		return errors.Wrap(err, "查询任务资源失败")
+	}
+
+	reports, _, err := a.studentsReportDAO.FindTaskStudentsReports(ctx, key.taskID, key.assignID, nil, &consts.DBPageInfo{All: true})
+	if err != nil {
+		return errors.Wrap(err, "查询学生报告失败")
+	}
+
+	answerStat, err := a.studentDetailsDAO.GetTaskAnswerCountStat(ctx, key.taskID, key.assignID, nil)
+	if err != nil {
+		return errors.Wrap(err, "查询题目作答数据失败")
+	}
+
+	// 学生表可能晚于作答写入，以两者中较大的人数为准
+	studentNum := int64(max(len(studentTiers), len(reports)))
+
+	// 分层资源、指定学生资源的应作答人数为可见该资源的学生数
+	resourceStudentNums := make(map[string]int64, len(resources))
+	for _, resource := range resources {
+		if resource.TierNo == consts.TaskTierNoCommon && len(resource.StudentIDs) == 0 {
+			continue
+		}
+		resourceKey := utils.JoinList([]any{resource.ResourceID, resource.ResourceType}, consts.CombineKey)
+		for studentID, tierNo := range studentTiers {
+			if resource.VisibleTo(tierNo, studentID) {
+				resourceStudentNums[resourceKey]++
+			}
+		}
+	}
+
+	overall := make([]dao_task.ResourceDetailReport, 0, len(reports))
+	resourceDetails := make(map[string][]dao_task.ResourceDetailReport)
+	for _, report := range reports {
+		overall = append(overall, report.ResourceDetailReport)
+		for resourceKey, resourceReport := range report.ResourceReport {
+			resourceDetails[resourceKey] = append(resourceDetails[resourceKey], resourceReport)
+		}
+	}
+
+	// 题目维度的作答人数、答错人数，按资源分组
+	resourceQuestions := make(map[string]map[string][2]int64)
+	allQuestions := make(map[string][2]int64)
+	for questionKey, answerCount := range answerStat.ResourceAnswerCount {
+		counts := [2]int64{answerCount, answerStat.ResourceIncorrectCount[questionKey]}
+		allQuestions[questionKey] = counts
+		idx := strings.LastIndex(questionKey, consts.CombineKey)
+		if idx <= 0 {
+			continue
+		}
+		resourceKey := questionKey[:idx]
+		if _, ok := resourceQuestions[resourceKey]; !ok {
+			resourceQuestions[resourceKey] = make(map[string][2]int64)
+		}
+		resourceQuestions[resourceKey][questionKey] = counts
+	}
+
+	resourceStats := make(dao_task.ResourceReportJSON)
+	for resourceKey, details := range resourceDetails {
+		resourceStudentNum := studentNum
+		if num, ok := resourceStudentNums[resourceKey]; ok {
+			resourceStudentNum = num
+		}
+		resourceStats[resourceKey] = buildCompleteStat(details, resourceStudentNum, resourceQuestions[resourceKey])
+	}
+
+	now := time.Now().Unix()
+	taskReport := &dao_task.TaskReport{
+		TaskID:               key.taskID,
+		AssignID:             key.assignID,
+		ReportDetail:         dao_task.CompleteReportJSON(buildCompleteStat(overall, studentNum, allQuestions)),
+		ResourceReportDetail: resourceStats,
+		CreateTime:           now,
+		UpdateTime:           now,
+	}
+	if err := a.taskReportDAO.Upsert(ctx, taskReport); err != nil {
+		return errors.Wrap(err, "写入布置报告失败")
+	}
+
+	// 共性错题本不影响报告汇总，失败时只记录日志，下次汇总该布置时重新收集
+	if a.errorBook != nil {
+		if err := a.errorBook.Collect(ctx, key.taskID, key.assignID, allQuestions); err != nil {
+			a.logger.Warn(ctx, "[aggregateAssignReport] 收集班级共性错题失败, taskID:%d, assignID:%d, error:%v", key.taskID, key.assignID, err)
+		}
+	}
+	return nil
+}
+
+// 任务所属学校和学科的学习分模型，任务不存在时使用内置默认模型
+func (a *TaskReportAggregator) getLearningScoreModel(ctx context.Context, taskID int64) (*dto.LearningScoreModel, error) {
+	tasks, err := a.taskDAO.GetTasksByIDs(ctx, []int64{taskID})
+	if err != nil {
+		return nil, errors.Wrap(err, "查询任务失败")
+	}
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	return a.rules.LearningScoreModel(tasks[0].SchoolID, consts.SubjectNameMap[tasks[0].Subject]), nil
+}
+
+// 任务资源及其题目总数
+type resourceQuestionTotal struct {
+	resource *dao_task.TaskResource
+	total    int64
+}
+
+// 获取任务每个资源的题目总数，map[resource_key]*resourceQuestionTotal
+// 单题资源为 1，记录了子题目的资源为子题目数，其它资源使用作答事件携带的题目数
+func (a *TaskReportAggregator) getResourceQuestionTotals(ctx context.Context, taskID int64, eventCounts map[string]int64) (map[string]*resourceQuestionTotal, error) {
+	resources, err := a.taskResourceDAO.GetByTaskID(ctx, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "查询任务资源失败")
+	}
+
+	totals := make(map[string]*resourceQuestionTotal, len(resources))
+	for _, resource := range resources {
+		resourceKey := utils.JoinList([]any{resource.ResourceID, resource.ResourceType}, consts.CombineKey)
+		total := &resourceQuestionTotal{resource: resource}
+		switch {
+		case resource.ResourceType == consts.RESOURCE_TYPE_QUESTION:
+			total.total = 1
+		case len(resource.ResourceSubIDs) > 0:
+			total.total = int64(len(resource.ResourceSubIDs))
+		default:
+			total.total = eventCounts[resourceKey]
+		}
+		totals[resourceKey] = total
+	}
+	return totals, nil
+}
+
+// 获取学生可见资源的题目数和作答汇总
+// 分层作业只包括共用资源（分层 0）和学生所在分层的资源，错题作业只包括学生自己的资源
+// 学生作答了任务中其它学生的资源时不计入报告，任务中不存在的资源保留作答，按已作答题目数计算进度
+func filterStudentResources(questionTotals map[string]*resourceQuestionTotal, stats []*dao_task.StudentResourceAnswerStat, tierNo, studentID int64) (map[string]int64, []*dao_task.StudentResourceAnswerStat) {
+	totals := make(map[string]int64, len(questionTotals))
+	for resourceKey, total := range questionTotals {
+		if total.resource.VisibleTo(tierNo, studentID) {
+			totals[resourceKey] = total.total
+		}
+	}
+	visibleStats := make([]*dao_task.StudentResourceAnswerStat, 0, len(stats))
+	for _, stat := range stats {
+		if _, ok := questionTotals[stat.ResourceKey]; ok {
+			if _, visible := totals[stat.ResourceKey]; !visible {
+				continue
+			}
+		}
+		visibleStats = append(visibleStats, stat)
+	}
+	return totals, visibleStats
+}
+
+// 由学生各资源的作答汇总生成学生报告
+// questionTotals 中题目数未知（为 0）的资源，按已作答题目数计算进度
+func buildStudentReport(stats []*dao_task.StudentResourceAnswerStat, questionTotals map[string]int64, scoreModel *dto.LearningScoreModel) *dao_task.TaskStudentsReport {
+	report := &dao_task.TaskStudentsReport{
+		ResourceReport: make(dao_task.ResourceReportMap),
+	}
+
+	answered := make(map[string]int64)
+	for _, stat := range stats {
+		total := max(questionTotals[stat.ResourceKey], stat.AnswerCount)
+		report.ResourceReport[stat.ResourceKey] = dao_task.ResourceDetailReport{
+			StudyScore:        calcStudyScore(scoreModel, stat.AnswerCount, stat.IncorrectCount, stat.CostTime),
+			CompletedProgress: utils.F64Div(float64(stat.AnswerCount), float64(total), 4),
+			AccuracyRate:      utils.F64Div(float64(stat.AnswerCount-stat.IncorrectCount), float64(stat.AnswerCount), 4),
+			AnswerCount:       stat.AnswerCount,
+			IncorrectCount:    stat.IncorrectCount,
+			CostTime:          stat.CostTime,
+		}
+		answered[stat.ResourceKey] = stat.AnswerCount
+
+		report.AnswerCount += stat.AnswerCount
+		report.IncorrectCount += stat.IncorrectCount
+		report.CostTime += stat.CostTime
+	}
+
+	// 任务总题数包含学生尚未作答的资源
+	totalQuestions := int64(0)
+	for resourceKey, total := range questionTotals {
+		totalQuestions += max(total, answered[resourceKey])
+	}
+	for resourceKey, count := range answered {
+		if _, ok := questionTotals[resourceKey]; !ok {
+			totalQuestions += count
+		}
+	}
+
+	report.StudyScore = calcStudyScore(scoreModel, report.AnswerCount, report.IncorrectCount, report.CostTime)
+	report.CompletedProgress = utils.F64Div(float64(report.AnswerCount), float64(totalQuestions), 4)
+	report.AccuracyRate = utils.F64Div(float64(report.AnswerCount-report.IncorrectCount), float64(report.AnswerCount), 4)
+	return report
+}
+
+// 学习分：按学习分模型计算，作答用时计为学习时长
+func calcStudyScore(scoreModel *dto.LearningScoreModel, answerCount, incorrectCount, costTime int64) int64 {
+	score, _ := behavior.CalcLearningScore(scoreModel, &dto.LearningScoreInput{
+		StayDuration:   costTime,
+		TotalQuestions: answerCount,
+		CorrectAnswers: answerCount - incorrectCount,
+	})
+	return score
+}
+
+// 由学生报告和题目作答数据汇总布置（或布置下单个资源）的完成情况
+// questions: map[questionKey][作答人数, 答错人数]
+func buildCompleteStat(details []dao_task.ResourceDetailReport, studentNum int64, questions map[string][2]int64) dao_task.TaskCompleteStat {
+	var (
+		completedNum     int64
+		answeredNum      int64
+		attentionUserNum int64
+		answerCount      int64
+		incorrectCount   int64
+		costTime         int64
+		totalProgress    float64
+	)
+	for _, detail := range details {
+		totalProgress += detail.CompletedProgress
+		if detail.CompletedProgress >= 1 {
+			completedNum++
+		}
+		if detail.AnswerCount == 0 {
+			continue
+		}
+		answeredNum++
+		answerCount += detail.AnswerCount
+		incorrectCount += detail.IncorrectCount
+		costTime += detail.CostTime
+		if detail.AccuracyRate < consts.TaskReportAttentionAccuracy {
+			attentionUserNum++
+		}
+	}
+
+	attentionQuestionNum := int64(0)
+	for _, counts := range questions {
+		if counts[0] == 0 {
+			continue
+		}
+		if utils.F64Div(float64(counts[0]-counts[1]), float64(counts[0]), 4) < consts.TaskReportAttentionAccuracy {
+			attentionQuestionNum++
+		}
+	}
+
+	studentNum = max(studentNum, int64(len(details)))
+	averageCostTime := int64(0)
+	if answeredNum > 0 {
+		averageCostTime = costTime / answeredNum
+	}
+	return dao_task.TaskCompleteStat{
+		CompletedProgress:    utils.F64Div(float64(completedNum), float64(studentNum), 4),
+		AverageProgress:      utils.F64Div(totalProgress, float64(studentNum), 4),
+		AccuracyRate:         utils.F64Div(float64(answerCount-incorrectCount), float64(answerCount), 4),
+		NeedAttentionNum:     attentionQuestionNum,
+		NeedAttentionUserNum: attentionUserNum,
+		AverageCostTime:      averageCostTime,
+	}
+}
@@ -0,0 +1,130 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/core/postgresqlx"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/dto"
+)
+
+func TestBuildStudentReport(t *testing.T) {
+	questionTotals := map[string]int64{
+		"1001#102": 4, // 巩固练习 4 题
+		"2001#103": 1, // 单题
+	}
+	stats := []*dao_task.StudentResourceAnswerStat{
+		{StudentID: 1, ResourceKey: "1001#102", AnswerCount: 2, IncorrectCount: 1, CostTime: 60},
+	}
+
+	report := buildStudentReport(stats, questionTotals, nil)
+	assert.Equal(t, int64(2), report.AnswerCount)
+	assert.Equal(t, int64(1), report.IncorrectCount)
+	assert.Equal(t, 0.4, report.CompletedProgress) // 2 / (4 + 1)
+	assert.Equal(t, 0.5, report.AccuracyRate)
+	assert.Equal(t, 0.5, report.ResourceReport["1001#102"].CompletedProgress)
+	// 默认学习分模型：学习 1 分钟得 1 分，正确率 50% 得 35 分
+	assert.Equal(t, int64(36), report.StudyScore)
+}
+
+func TestBuildCompleteStat(t *testing.T) {
+	details := []dao_task.ResourceDetailReport{
+		{CompletedProgress: 1, AccuracyRate: 1, AnswerCount: 2, CostTime: 30},
+		{CompletedProgress: 0.5, AccuracyRate: 0, AnswerCount: 1, IncorrectCount: 1, CostTime: 10},
+	}
+	questions := map[string][2]int64{
+		"1001#102#q1": {2, 0},
+		"1001#102#q2": {2, 1},
+	}
+
+	stat := buildCompleteStat(details, 4, questions)
+	assert.Equal(t, 0.25, stat.CompletedProgress)
+	assert.Equal(t, 0.375, stat.AverageProgress)
+	assert.Equal(t, 0.6667, stat.AccuracyRate)
+	assert.Equal(t, int64(1), stat.NeedAttentionNum)
+	assert.Equal(t, int64(1), stat.NeedAttentionUserNum)
+	assert.Equal(t, int64(20), stat.AverageCostTime)
+}
+
+func TestFilterStudentResources(t *testing.T) {
+	questionTotals := map[string]*resourceQuestionTotal{
+		"2001#103": {resource: &dao_task.TaskResource{TierNo: 0}, total: 1},                                // 共用资源
+		"1001#102": {resource: &dao_task.TaskResource{TierNo: 1}, total: 4},                                // 第 1 层
+		"1002#102": {resource: &dao_task.TaskResource{TierNo: 2}, total: 10},                               // 第 2 层
+		"3001#103": {resource: &dao_task.TaskResource{StudentIDs: postgresqlx.Int64Array{2}}, total: 1},    // 学生 2 的错题
+		"3002#103": {resource: &dao_task.TaskResource{StudentIDs: postgresqlx.Int64Array{1, 2}}, total: 1}, // 学生 1、2 的错题
+	}
+	stats := []*dao_task.StudentResourceAnswerStat{
+		{StudentID: 1, ResourceKey: "2001#103", AnswerCount: 1},
+		{StudentID: 1, ResourceKey: "1001#102", AnswerCount: 2},
+		{StudentID: 1, ResourceKey: "1002#102", AnswerCount: 3},
+		{StudentID: 1, ResourceKey: "3001#103", AnswerCount: 1},
+		{StudentID: 1, ResourceKey: "9001#103", AnswerCount: 1}, // 任务中不存在的资源
+	}
+
+	totals, visibleStats := filterStudentResources(questionTotals, stats, 1, 1)
+	assert.Equal(t, map[string]int64{"2001#103": 1, "1001#102": 4, "3002#103": 1}, totals)
+	assert.Len(t, visibleStats, 3)
+
+	report := buildStudentReport(visibleStats, totals, nil)
+	assert.Equal(t, int64(4), report.AnswerCount)
+	assert.Equal(t, 0.5714, report.CompletedProgress) // 4 / (1 + 4 + 1 + 1)
+
+	// 非分层作业的学生只过滤其它学生的资源
+	totals, visibleStats = filterStudentResources(questionTotals, stats, 0, 1)
+	assert.Len(t, totals, 2)
+	assert.Len(t, visibleStats, 2)
+}
+
+// 内存中的作答详情表，覆盖条件和 BatchUpsert 的 SQL 保持一致
+type stubStudentDetailsDAO struct {
+	dao_task.TaskStudentDetailsDao
+	rows map[string]*dao_task.TaskStudentDetails
+}
+
+func (s *stubStudentDetailsDAO) BatchUpsert(ctx context.Context, details []*dao_task.TaskStudentDetails) error {
+	for _, detail := range details {
+		key := detail.ResourceKey + "#" + detail.QuestionID
+		if exist, ok := s.rows[key]; ok && exist.AnswerTime > detail.AnswerTime {
+			continue
+		}
+		s.rows[key] = detail
+	}
+	return nil
+}
+
+func TestCollectAnswerDetailsOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	a := &TaskReportAggregator{logger: clogger.NewContextLogger(log.DefaultLogger)}
+	event := func(answerTime int64, correctness bool) *dto.TaskAnswerEventDTO {
+		return &dto.TaskAnswerEventDTO{
+			TaskID: 1, AssignID: 11, StudentID: 100, ResourceID: "1001", ResourceType: 102,
+			QuestionID: "q1", Correctness: correctness, AnswerTime: answerTime,
+		}
+	}
+
+	// 同一批次内后作答的事件先到
+	details, batches := a.collectAnswerDetails(ctx, []*dto.TaskAnswerEventDTO{event(200, true), event(100, false)})
+	if assert.Len(t, details, 1) {
+		assert.Equal(t, int64(200), details[0].AnswerTime)
+		assert.True(t, details[0].Correctness)
+	}
+	assert.Len(t, batches, 1)
+
+	// 跨批次乱序：较早的作答晚到，不能覆盖已写入的结果
+	detailsDAO := &stubStudentDetailsDAO{rows: make(map[string]*dao_task.TaskStudentDetails)}
+	for _, events := range [][]*dto.TaskAnswerEventDTO{{event(200, true)}, {event(100, false)}} {
+		details, _ = a.collectAnswerDetails(ctx, events)
+		assert.NoError(t, detailsDAO.BatchUpsert(ctx, details))
+	}
+	row := detailsDAO.rows["1001#102#q1"]
+	if assert.NotNil(t, row) {
+		assert.Equal(t, int64(200), row.AnswerTime)
+		assert.True(t, row.Correctness)
+	}
+}
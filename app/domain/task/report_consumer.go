@@ -0,0 +1,75 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/kafka"
+	"gil_teacher/app/model/dto"
+)
+
+// Consume 从作答事件 topic 批量消费消息，聚合生成任务报告
+// 同一布置的事件需要以 assign_id 作为消息 key 写入，保证同一布置由同一分区顺序处理
+// 消费会话到期后重新加入消费组，直到 ctx 结束；deadLetter 为空时处理失败的批次不提交 offset
+func (a *TaskReportAggregator) Consume(ctx context.Context, kafkaConf *conf.Kafka, deadLetter *kafka.KafkaProducerClient) {
+	a.logger.Info(ctx, "任务报告聚合 Kafka 配置信息: broker=%s, group=%s, topics=%v",
+		kafkaConf.Brokers,
+		consts.KafkaGroupTaskReport,
+		consts.KafkaTopicTaskReports)
+
+	consumerGroupHandlerImpl := &kafka.ConsumerGroupHandlerImpl{
+		Group:        consts.KafkaGroupTaskReport,
+		Topics:       consts.KafkaTopicTaskReports,
+		BatchSize:    kafkaConf.Consumer.BatchSize,
+		BatchTime:    kafkaConf.Consumer.BatchTime * time.Second,
+		SessionTime:  kafkaConf.Consumer.SessionTime * time.Second,
+		ProcMsgList:  a.HandleMessage,
+		MaxRetries:   kafkaConf.Consumer.MaxRetries,
+		RetryBackoff: kafkaConf.Consumer.RetryBackoff * time.Millisecond,
+		DeadLetter:   deadLetter,
+		Log:          a.logger,
+	}
+	for ctx.Err() == nil {
+		kafka.ConsumeKafkaMsgInSession(ctx, kafkaConf, consumerGroupHandlerImpl)
+		time.Sleep(time.Second)
+	}
+}
+
+// HandleMessage 处理一批作答事件
+// 聚合失败时返回错误，由消费框架逐条重试，仍失败时写入死信 topic；无法解析的事件直接写入死信 topic
+func (a *TaskReportAggregator) HandleMessage(msgs []*sarama.ConsumerMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	startTime := time.Now()
+	var failures kafka.BatchError
+	events := make([]*dto.TaskAnswerEventDTO, 0, len(msgs))
+	for _, msg := range msgs {
+		var event dto.TaskAnswerEventDTO
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			a.logger.Error(ctx, "解析作答事件失败, error:%v, partition:%d, offset:%d", err, msg.Partition, msg.Offset)
+			failures = append(failures, &kafka.MessageError{Msg: msg, Err: errors.Wrap(err, "解析作答事件失败")})
+			continue
+		}
+		events = append(events, &event)
+	}
+
+	if err := a.Aggregate(ctx, events); err != nil {
+		a.logger.Error(ctx, "作答事件聚合失败, error:%v, 数量:%d", err, len(events))
+		return err
+	}
+
+	a.logger.Debug(ctx, "作答事件批次处理完成，耗时: %v, 数量: %d", time.Since(startTime), len(events))
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
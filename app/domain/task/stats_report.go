@@ -195,26 +195,81 @@ func (h *TaskReportHandler) GetTaskReportSummaryDetail(ctx context.Context, quer
 // 导出指定班级的任务报告（csv）
 // 文件名：任务名-班级名(-资源名).csv
 func (h *TaskReportHandler) ExportTaskReport(ctx context.Context, query *dto.ExportTaskReportQuery) (string, *dto.ExportTaskReportResult, error) {
+	exportData, err := h.getExportData(ctx, query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	result := &dto.ExportTaskReportResult{
+		Meta: consts.ExtractExportFields(query.Fields),
+		Data: make([][]string, 0, len(exportData.studentReports)),
+	}
+
+	// 解析每个学生的报告数据，需要对齐字段顺序
+	for _, studentReport := range exportData.studentReports {
+		row := make([]string, 0)
+		for _, field := range query.Fields {
+			switch field {
+			case "studentName":
+				row = append(row, exportData.studentName(studentReport.StudentID))
+			case "studyScore":
+				row = append(row, utils.I64ToStr(studentReport.StudyScore))
+			case "progress":
+				row = append(row, utils.F64ToString(studentReport.Progress, 2, "-"))
+			case "accuracyRate":
+				row = append(row, utils.F64ToString(studentReport.AccuracyRate, 2, "-"))
+			case "difficultyDegree":
+				row = append(row, utils.F64ToString(studentReport.DifficultyDegree, 1, "-"))
+			case "incorrectCount/answerCount":
+				row = append(row, fmt.Sprintf("'%d/%d", studentReport.IncorrectNum, studentReport.AnswerNum))
+			case "answerTime":
+				row = append(row, utils.I64ToStr(studentReport.CostTime))
+			}
+		}
+		result.Data = append(result.Data, row)
+	}
+	return exportData.fileName, result, nil
+}
+
+// 导出使用的报告数据
+type exportData struct {
+	*singleTask
+	fileName       string                     // 导出文件名，不含后缀
+	students       []*itl.StudentInfo         // 布置对象的学生列表
+	studentMap     map[int64]*itl.StudentInfo // studentID -> 学生信息
+	studentReports []*api.TaskStudentReport   // 学生维度报告，已按查询条件排序
+}
+
+func (d *exportData) studentName(studentID int64) string {
+	if student, ok := d.studentMap[studentID]; ok {
+		return student.Name
+	}
+	return ""
+}
+
+// 获取导出需要的任务、班级、学生报告数据
+// 文件名：任务名-班级名(-资源名/任务类型名)
+func (h *TaskReportHandler) getExportData(ctx context.Context, query *dto.ExportTaskReportQuery) (*exportData, error) {
 	taskID := query.TaskID
 	taskHandler, err := h.getTaskData(ctx, taskID)
 	if err != nil {
 		h.log.Error(ctx, "[ExportTaskReport] GetTaskData failed, taskID:%d, error:%v", taskID, err)
-		return "", nil, err
+		return nil, err
 	}
 	if err := taskHandler.getAssignData(ctx, taskID, query.AssignID); err != nil {
 		h.log.Error(ctx, "[ExportTaskReport] GetTaskAssignData failed, taskID:%d, assignID:%d, error:%v", taskID, query.AssignID, err)
-		return "", nil, err
+		return nil, err
 	}
 
 	if err := taskHandler.getAssignClassInfo(ctx, taskID, query.AssignID); err != nil {
 		h.log.Error(ctx, "[ExportTaskReport] GetTaskAssignClassInfo failed, taskID:%d, assignID:%d, error:%v", taskID, query.AssignID, err)
-		return "", nil, err
+		return nil, err
 	}
 
 	// 获取题目数据，计算题目难度
 	if err := taskHandler.getResourceQuestions(ctx, taskID, nil); err != nil {
 		h.log.Error(ctx, "[ExportTaskReport] GetTaskResourceQuestions failed, taskID:%d, assignID:%d, error:%v", taskID, query.AssignID, err)
-		return "", nil, err
+		return nil, err
 	}
 
 	students := taskHandler.assignDataMap[query.AssignID].classInfo.Students
@@ -231,12 +286,7 @@ func (h *TaskReportHandler) ExportTaskReport(ctx context.Context, query *dto.Exp
 	studentReports, _, err := taskHandler.getStudentReports(ctx, students, assignReportQuery, consts.AllDataPageInfo())
 	if err != nil {
 		h.log.Error(ctx, "[ExportTaskReport] GetTaskStudentReports failed, taskID:%d, assignID:%d, error:%v", taskID, query.AssignID, err)
-		return "", nil, err
-	}
-
-	result := &dto.ExportTaskReportResult{
-		Meta: consts.ExtractExportFields(query.Fields),
-		Data: make([][]string, 0, len(studentReports)),
+		return nil, err
 	}
 
 	studentMap := make(map[int64]*itl.StudentInfo)
@@ -244,29 +294,6 @@ func (h *TaskReportHandler) ExportTaskReport(ctx context.Context, query *dto.Exp
 		studentMap[student.ID] = student
 	}
 
-	// 解析每个学生的报告数据，需要对齐字段顺序
-	for _, studentReport := range studentReports {
-		row := make([]string, 0)
-		for _, field := range query.Fields {
-			switch field {
-			case "studentName":
-				row = append(row, studentMap[studentReport.StudentID].Name)
-			case "studyScore":
-				row = append(row, utils.I64ToStr(studentReport.StudyScore))
-			case "progress":
-				row = append(row, utils.F64ToString(studentReport.AccuracyRate, 2, "-"))
-			case "accuracyRate":
-				row = append(row, utils.F64ToString(studentReport.AccuracyRate, 2, "-"))
-			case "difficultyDegree":
-				row = append(row, utils.F64ToString(studentReport.AccuracyRate, 1, "-"))
-			case "incorrectCount/answerCount":
-				row = append(row, fmt.Sprintf("'%d/%d", studentReport.IncorrectNum, studentReport.AnswerNum))
-			case "answerTime":
-				row = append(row, utils.I64ToStr(studentReport.CostTime))
-			}
-		}
-		result.Data = append(result.Data, row)
-	}
 	exportFileName := fmt.Sprintf("%s-%s", taskHandler.task.TaskName, taskHandler.assignDataMap[query.AssignID].classInfo.ClassName)
 	if query.ResourceID != "" {
 		resourceName := consts.GetResourceTypeName(query.ResourceType)
@@ -279,7 +306,13 @@ func (h *TaskReportHandler) ExportTaskReport(ctx context.Context, query *dto.Exp
 			exportFileName = fmt.Sprintf("%s-%s", exportFileName, taskTypeName)
 		}
 	}
-	return exportFileName, result, nil
+	return &exportData{
+		singleTask:     taskHandler,
+		fileName:       exportFileName,
+		students:       students,
+		studentMap:     studentMap,
+		studentReports: studentReports,
+	}, nil
 }
 
 // 获取任务布置对象每个题目的答题正确率统计，即答题面板统计数据，统计每个题目的答题正确率
@@ -319,9 +352,10 @@ func (h *TaskReportHandler) GetTaskAnswerPanel(ctx context.Context, query *dto.T
 
 	questionPanels := make([]*api.QuestionPanel, 0)
 	questionIndex := int64(1)
+	isAnswerCard := consts.IsAnswerCardTask(taskHandler.task.TaskSubType)
 	for resourceKey, questions := range questionMap {
 		parts := strings.Split(resourceKey, consts.CombineKey) // resource_id#resource_type
-		for questionId := range questions {
+		for questionId, question := range questions {
 			questionKey := utils.JoinList([]any{resourceKey, questionId}, consts.CombineKey)
 			tmp := &api.QuestionPanel{
 				ResourceID:    parts[0],
@@ -329,6 +363,10 @@ func (h *TaskReportHandler) GetTaskAnswerPanel(ctx context.Context, query *dto.T
 				QuestionID:    questionId,
 				QuestionIndex: questionIndex,
 			}
+			// 答题卡题目按题号展示和导出
+			if isAnswerCard {
+				tmp.QuestionIndex = question.QuestionContentFormat.QuestionOrder
+			}
 			if answer, ok := taskHandler.assignDataMap[query.AssignID].questionAnswers[questionKey]; ok {
 				tmp.CorrectRate = answer.Accuracy
 				tmp.AnswerCount = answer.AnswerCount
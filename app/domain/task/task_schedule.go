@@ -0,0 +1,275 @@
+package task
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+)
+
+// 任务不存在、不是当前教师创建，或任务类型、状态不支持周期布置
+var ErrTaskRecurrence = errors.New("任务不支持周期布置")
+
+// 周期布置规则不存在或已停止
+var ErrTaskRecurrenceNotFound = errors.New("周期布置规则不存在")
+
+// TaskScheduleHandler 定时发布和周期布置
+// 由 script 中的定时任务调用 RunDue，发布到期的定时任务，并按周期布置规则生成新的任务布置
+type TaskScheduleHandler struct {
+	taskDAO       dao_task.TaskDAO
+	recurrenceDAO dao_task.TaskRecurrenceDAO
+	log           *logger.ContextLogger
+}
+
+func NewTaskScheduleHandler(
+	taskDAO dao_task.TaskDAO,
+	recurrenceDAO dao_task.TaskRecurrenceDAO,
+	log *logger.ContextLogger,
+) *TaskScheduleHandler {
+	return &TaskScheduleHandler{
+		taskDAO:       taskDAO,
+		recurrenceDAO: recurrenceDAO,
+		log:           log,
+	}
+}
+
+// TaskScheduleResult 一次定时任务的执行结果
+type TaskScheduleResult struct {
+	PublishedTaskNum int64 // 发布的定时任务数
+	AssignNum        int64 // 周期布置生成的布置数
+	SkippedNum       int64 // 错过截止时间未生成的布置数
+	EndedRuleNum     int64 // 结束或停止的规则数
+	FailedRuleNum    int64 // 处理失败的规则数，下次执行时重试
+}
+
+// CreateRecurrence 为已发布或定时发布的任务创建周期布置规则，第一次布置为当前时间之后的第一个布置日
+// 分层作业和错题作业的学生资源与布置时的学生绑定，不支持周期布置
+func (h *TaskScheduleHandler) CreateRecurrence(ctx context.Context, teacherID, schoolID int64, req *api.CreateTaskRecurrenceRequest) (*dao_task.TaskRecurrence, error) {
+	task, err := h.taskDAO.GetTaskByIDAndCreatorID(ctx, req.TaskID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil || !recurrenceEnabled(task) {
+		return nil, ErrTaskRecurrence
+	}
+	if task.TaskSubType == consts.TASK_TYPE_HOMEWORK_LAYERED || task.TaskSubType == consts.TASK_TYPE_HOMEWORK_WRONG {
+		return nil, ErrTaskRecurrence
+	}
+	count, err := h.recurrenceDAO.CountActiveByTaskID(ctx, task.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= consts.TaskRecurrenceMaxPerTask {
+		return nil, ErrTaskRecurrence
+	}
+	if len(req.StudentIDs) == 0 {
+		return nil, ErrTaskRecurrence
+	}
+
+	recurrence := &dao_task.TaskRecurrence{
+		TaskID:      task.TaskID,
+		SchoolID:    schoolID,
+		CreatorID:   teacherID,
+		GroupType:   req.GroupType,
+		GroupID:     req.GroupID,
+		StudentIDs:  req.StudentIDs,
+		Weekdays:    req.Weekdays,
+		StartOffset: req.StartOffset,
+		Duration:    req.Duration,
+		StartDate:   dayStart(req.StartDate),
+		EndDate:     dayStart(req.EndDate),
+		Status:      consts.TASK_RECURRENCE_STATUS_ACTIVE,
+	}
+	next, ok := nextOccurrence(recurrence, time.Now().Unix())
+	if !ok {
+		return nil, ErrTaskRecurrence
+	}
+	recurrence.NextRunTime = next
+	if err := h.recurrenceDAO.Create(ctx, recurrence); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// StopRecurrence 停止周期布置规则，已生成的布置不受影响
+func (h *TaskScheduleHandler) StopRecurrence(ctx context.Context, teacherID, recurrenceID int64) error {
+	ok, err := h.recurrenceDAO.Stop(ctx, recurrenceID, teacherID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTaskRecurrenceNotFound
+	}
+	return nil
+}
+
+// GetRecurrences 获取教师任务的全部周期布置规则
+func (h *TaskScheduleHandler) GetRecurrences(ctx context.Context, teacherID, taskID int64) ([]*dao_task.TaskRecurrence, error) {
+	task, err := h.taskDAO.GetTaskByIDAndCreatorID(ctx, taskID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskRecurrence
+	}
+	return h.recurrenceDAO.GetByTaskID(ctx, taskID)
+}
+
+// RunDue 发布到达发布时间的定时任务，并为下一次布置时间已到的周期布置规则生成布置
+// 多实例同时执行时通过规则的下一次布置时间做乐观锁，同一次布置只会生成一次
+func (h *TaskScheduleHandler) RunDue(ctx context.Context, now int64) (*TaskScheduleResult, error) {
+	result := &TaskScheduleResult{}
+	published, err := h.taskDAO.PublishDueTasks(ctx, now)
+	if err != nil {
+		h.log.Error(ctx, "[RunDue] 发布定时任务失败, err: %v", err)
+		return nil, err
+	}
+	result.PublishedTaskNum = published
+
+	// 处理失败的规则本次不再重试，查询时跳过，避免失败规则占满批次导致后面的规则无法处理
+	failedIDs := make([]int64, 0)
+	for {
+		recurrences, err := h.recurrenceDAO.FindDue(ctx, now, failedIDs, consts.TaskScheduleBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(recurrences) == 0 {
+			break
+		}
+		for _, recurrence := range recurrences {
+			if err := h.expand(ctx, recurrence, now, result); err != nil {
+				h.log.Error(ctx, "[RunDue] 周期布置失败, recurrenceID: %d, err: %v", recurrence.ID, err)
+				failedIDs = append(failedIDs, recurrence.ID)
+			}
+		}
+	}
+	result.FailedRuleNum = int64(len(failedIDs))
+
+	h.log.Info(ctx, "[RunDue] 定时任务执行完成, published: %d, assigns: %d, skipped: %d, ended: %d, failed: %d",
+		result.PublishedTaskNum, result.AssignNum, result.SkippedNum, result.EndedRuleNum, result.FailedRuleNum)
+	return result, nil
+}
+
+// 为规则生成下一次布置并推进规则，已错过截止时间的布置不再生成
+func (h *TaskScheduleHandler) expand(ctx context.Context, recurrence *dao_task.TaskRecurrence, now int64, result *TaskScheduleResult) error {
+	tasks, err := h.taskDAO.GetTasksByIDs(ctx, []int64{recurrence.TaskID})
+	if err != nil {
+		return err
+	}
+	// 任务已删除或归档时停止规则
+	if len(tasks) == 0 || !recurrenceEnabled(tasks[0]) {
+		ok, err := h.recurrenceDAO.Advance(ctx, nil, recurrence.ID, recurrence.NextRunTime, map[string]any{
+			"status": consts.TASK_RECURRENCE_STATUS_STOPPED,
+		})
+		if ok {
+			result.EndedRuleNum++
+		}
+		return err
+	}
+
+	occurrence := recurrence.NextRunTime
+	updates := make(map[string]any)
+	if next, ok := nextOccurrence(recurrence, occurrence+1); ok {
+		updates["next_run_time"] = next
+	} else {
+		updates["status"] = consts.TASK_RECURRENCE_STATUS_FINISHED
+	}
+
+	if occurrence+recurrence.Duration <= now {
+		ok, err := h.recurrenceDAO.Advance(ctx, nil, recurrence.ID, occurrence, updates)
+		if ok {
+			result.SkippedNum++
+			if _, ended := updates["status"]; ended {
+				result.EndedRuleNum++
+			}
+		}
+		return err
+	}
+
+	tx := h.taskDAO.GetDB().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+	assign := &dao_task.TaskAssign{
+		TaskID:    recurrence.TaskID,
+		SchoolID:  recurrence.SchoolID,
+		GroupType: recurrence.GroupType,
+		GroupID:   recurrence.GroupID,
+		StartTime: occurrence,
+		Deadline:  occurrence + recurrence.Duration,
+	}
+	if err := tx.Create(assign).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	students := make([]*dao_task.TaskStudent, 0, len(recurrence.StudentIDs))
+	for _, studentID := range recurrence.StudentIDs {
+		students = append(students, &dao_task.TaskStudent{
+			AssignID:  assign.AssignID,
+			TaskID:    recurrence.TaskID,
+			StudentID: studentID,
+		})
+	}
+	if len(students) > 0 {
+		if err := tx.Create(students).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	updates["last_assign_id"] = assign.AssignID
+	ok, err := h.recurrenceDAO.Advance(ctx, tx, recurrence.ID, occurrence, updates)
+	if err != nil || !ok {
+		// 已被其他实例处理
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	result.AssignNum++
+	if _, ended := updates["status"]; ended {
+		result.EndedRuleNum++
+	}
+	h.log.Info(ctx, "[expand] 周期布置生成布置, recurrenceID: %d, taskID: %d, assignID: %d, startTime: %d",
+		recurrence.ID, recurrence.TaskID, assign.AssignID, occurrence)
+	return nil
+}
+
+// 草稿的布置在发布前不生效，归档和删除的任务不再生成布置
+func recurrenceEnabled(task *dao_task.Task) bool {
+	return task.Deleted == 0 && (task.Status == consts.TASK_STATUS_PUBLISHED || task.Status == consts.TASK_STATUS_SCHEDULED)
+}
+
+// 按规则计算不早于 after 的下一次布置开始时间，超过结束日期时返回 false
+func nextOccurrence(recurrence *dao_task.TaskRecurrence, after int64) (int64, bool) {
+	from := max(after, recurrence.StartDate)
+	endDate := time.Unix(recurrence.EndDate, 0).In(consts.LocationShanghai)
+	for day := time.Unix(dayStart(from), 0).In(consts.LocationShanghai); !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		startTime := day.Unix() + recurrence.StartOffset
+		if startTime < from {
+			continue
+		}
+		weekday := int64(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		if slices.Contains(recurrence.Weekdays, weekday) {
+			return startTime, true
+		}
+	}
+	return 0, false
+}
+
+// 时间戳所在日期的零点
+func dayStart(ts int64) int64 {
+	t := time.Unix(ts, 0).In(consts.LocationShanghai)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, consts.LocationShanghai).Unix()
+}
@@ -0,0 +1,117 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	date := func(day, hour int) int64 {
+		return time.Date(2026, time.October, day, hour, 0, 0, 0, consts.LocationShanghai).Unix()
+	}
+	// 2026-10-12 为周一，每个工作日 8 点开始，规则到 10-19（周一）结束
+	recurrence := &dao_task.TaskRecurrence{
+		Weekdays:    []int64{1, 2, 3, 4, 5},
+		StartOffset: 8 * 3600,
+		StartDate:   dayStart(date(12, 12)),
+		EndDate:     dayStart(date(19, 12)),
+	}
+
+	next, ok := nextOccurrence(recurrence, date(11, 9)) // 规则开始前
+	assert.True(t, ok)
+	assert.Equal(t, date(12, 8), next)
+
+	next, ok = nextOccurrence(recurrence, date(12, 8)+1) // 当天已过开始时间
+	assert.True(t, ok)
+	assert.Equal(t, date(13, 8), next)
+
+	next, ok = nextOccurrence(recurrence, date(16, 9)) // 周五之后跳过周末
+	assert.True(t, ok)
+	assert.Equal(t, date(19, 8), next)
+
+	_, ok = nextOccurrence(recurrence, date(19, 9)) // 超过结束日期
+	assert.False(t, ok)
+
+	recurrence.Weekdays = []int64{7}
+	next, ok = nextOccurrence(recurrence, date(12, 0))
+	assert.True(t, ok)
+	assert.Equal(t, date(18, 8), next)
+}
+
+type stubScheduleTaskDAO struct {
+	dao_task.TaskDAO
+	failTaskID int64
+}
+
+func (s *stubScheduleTaskDAO) PublishDueTasks(ctx context.Context, now int64) (int64, error) {
+	return 0, nil
+}
+
+func (s *stubScheduleTaskDAO) GetTasksByIDs(ctx context.Context, taskIDs []int64) ([]*dao_task.Task, error) {
+	if slices.Contains(taskIDs, s.failTaskID) {
+		return nil, errors.New("query task failed")
+	}
+	return nil, nil
+}
+
+// 内存中的周期布置规则表，条件和 SQL 实现保持一致
+type stubRecurrenceDAO struct {
+	dao_task.TaskRecurrenceDAO
+	recurrences []*dao_task.TaskRecurrence
+}
+
+func (s *stubRecurrenceDAO) FindDue(ctx context.Context, now int64, excludeIDs []int64, limit int) ([]*dao_task.TaskRecurrence, error) {
+	recurrences := make([]*dao_task.TaskRecurrence, 0)
+	for _, recurrence := range s.recurrences {
+		if recurrence.Status == consts.TASK_RECURRENCE_STATUS_ACTIVE && recurrence.NextRunTime <= now &&
+			!slices.Contains(excludeIDs, recurrence.ID) && len(recurrences) < limit {
+			recurrences = append(recurrences, recurrence)
+		}
+	}
+	return recurrences, nil
+}
+
+func (s *stubRecurrenceDAO) Advance(ctx context.Context, tx *gorm.DB, recurrenceID int64, fromNextRunTime int64, updates map[string]any) (bool, error) {
+	for _, recurrence := range s.recurrences {
+		if recurrence.ID == recurrenceID && recurrence.NextRunTime == fromNextRunTime {
+			if status, ok := updates["status"]; ok {
+				recurrence.Status = status.(int64)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRunDueSkipsFailedRecurrences(t *testing.T) {
+	now := time.Now().Unix()
+	recurrenceDAO := &stubRecurrenceDAO{}
+	// 失败的规则排在前面且超过一批，后面的规则仍然会被处理
+	for i := 1; i <= consts.TaskScheduleBatchSize+1; i++ {
+		recurrenceDAO.recurrences = append(recurrenceDAO.recurrences, &dao_task.TaskRecurrence{
+			ID: int64(i), TaskID: 1, NextRunTime: now - 60, Status: consts.TASK_RECURRENCE_STATUS_ACTIVE,
+		})
+	}
+	recurrenceDAO.recurrences = append(recurrenceDAO.recurrences, &dao_task.TaskRecurrence{
+		ID: 1000, TaskID: 2, NextRunTime: now, Status: consts.TASK_RECURRENCE_STATUS_ACTIVE,
+	})
+	h := NewTaskScheduleHandler(&stubScheduleTaskDAO{failTaskID: 1}, recurrenceDAO, clogger.NewContextLogger(log.DefaultLogger))
+
+	result, err := h.RunDue(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(consts.TaskScheduleBatchSize+1), result.FailedRuleNum)
+	// 任务已删除的规则被停止
+	assert.Equal(t, int64(1), result.EndedRuleNum)
+	assert.Equal(t, consts.TASK_RECURRENCE_STATUS_STOPPED, recurrenceDAO.recurrences[consts.TaskScheduleBatchSize+1].Status)
+}
@@ -2,9 +2,11 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"gil_teacher/app/consts"
 	"gil_teacher/app/model/dto"
 	"slices"
+	"strings"
 )
 
 // TeacherBehaviorRequest 教师行为请求
@@ -490,3 +492,225 @@ type ClassroomBehaviorSummaryResponse struct {
 	AttentionList []StudentBehaviorCategory `json:"attentionList"` // 建议关注学生列表
 	AllStudents   []StudentBehaviorCategory `json:"allStudents"`   // 所有学生列表
 }
+
+// BehaviorRuleDryRunRequest 行为规则试运行请求
+// 使用规则集评估课堂学生当前行为，不记录表扬和关注
+type BehaviorRuleDryRunRequest struct {
+	ClassroomID uint64               `json:"classroomId" binding:"required"` // 课堂ID
+	Subject     string               `json:"subject"`                        // 学科，指定后使用该学科的规则集，否则按学生当前行为的学科
+	RuleSet     *dto.BehaviorRuleSet `json:"ruleSet"`                        // 待发布的规则集，指定后代替已生效的规则集
+	SchoolID    int64                `json:"-"`                              // 学校ID
+}
+
+// Validate 验证请求参数
+func (r *BehaviorRuleDryRunRequest) Validate() error {
+	if r.ClassroomID == 0 {
+		return errors.New("课堂ID不能为0")
+	}
+	return nil
+}
+
+// BehaviorRuleDryRunResponse 行为规则试运行响应
+type BehaviorRuleDryRunResponse struct {
+	ClassroomID uint64                      `json:"classroomId"` // 课堂ID
+	Students    []BehaviorRuleDryRunStudent `json:"students"`    // 学生评估结果
+}
+
+// BehaviorRuleDryRunStudent 学生的规则评估结果
+type BehaviorRuleDryRunStudent struct {
+	StudentID       int64                 `json:"studentId"`       // 学生ID
+	StudentName     string                `json:"studentName"`     // 学生姓名
+	BehaviorType    string                `json:"behaviorType"`    // 当前行为类型
+	RuleSetSchoolID int64                 `json:"ruleSetSchoolId"` // 使用的规则集学校ID
+	RuleSetSubject  string                `json:"ruleSetSubject"`  // 使用的规则集学科
+	RuleSetVersion  int64                 `json:"ruleSetVersion"`  // 使用的规则集版本，0 为内置默认规则集
+	PraiseWorthy    bool                  `json:"praiseWorthy"`    // 是否值得表扬
+	PraiseHits      []dto.BehaviorRuleHit `json:"praiseHits"`      // 命中的表扬条件
+	PraiseScores    map[string]int64      `json:"praiseScores"`    // 各表扬类型得分
+	PraiseType      string                `json:"praiseType"`      // 选择的表扬类型
+	NeedsAttention  bool                  `json:"needsAttention"`  // 是否需要关注
+	AttentionHits   []dto.BehaviorRuleHit `json:"attentionHits"`   // 命中的关注条件
+	AttentionScores map[string]int64      `json:"attentionScores"` // 各关注类型得分
+	AttentionType   string                `json:"attentionType"`   // 选择的关注类型
+	BehaviorTags    []BehaviorTag         `json:"behaviorTags"`    // 行为标签
+}
+
+// SessionInboxRequest 会话收件箱请求
+type SessionInboxRequest struct {
+	Page     int64 `form:"page"`     // 页码
+	PageSize int64 `form:"pageSize"` // 每页数量
+}
+
+// Validate 验证请求参数
+func (r *SessionInboxRequest) Validate() error {
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	return err
+}
+
+// SessionInboxResponse 会话收件箱响应
+type SessionInboxResponse struct {
+	List        []*dto.CommunicationInboxSessionDTO `json:"list"`        // 会话列表，按最后活跃时间倒序
+	UnreadCount int64                               `json:"unreadCount"` // 全部会话的未读消息总数
+	PageInfo    *consts.ApiPageResponse             `json:"pageInfo"`
+}
+
+// AIAnswerListRequest AI 答疑审核列表请求
+type AIAnswerListRequest struct {
+	TaskID   int64 `form:"taskId" binding:"required"`   // 任务ID
+	AssignID int64 `form:"assignId" binding:"required"` // 布置ID
+}
+
+// Validate 验证请求参数
+func (r *AIAnswerListRequest) Validate() error {
+	if r.TaskID <= 0 {
+		return errors.New("任务ID不能为空")
+	}
+	if r.AssignID <= 0 {
+		return errors.New("布置ID不能为空")
+	}
+	return nil
+}
+
+// AIAnswerListResponse AI 答疑审核列表响应
+type AIAnswerListResponse struct {
+	List []*dto.AITutorAnswerDTO `json:"list"` // AI 回答列表，按回答时间排序
+}
+
+// OverrideAIAnswerRequest 教师修正 AI 回答请求
+type OverrideAIAnswerRequest struct {
+	SessionID string `json:"sessionId" binding:"required"` // 会话ID
+	MessageID string `json:"messageId" binding:"required"` // 被修正的 AI 消息ID
+	Content   string `json:"content" binding:"required"`   // 修正后的回答
+}
+
+// Validate 验证请求参数
+func (r *OverrideAIAnswerRequest) Validate() error {
+	if r.SessionID == "" {
+		return errors.New("会话ID不能为空")
+	}
+	if r.MessageID == "" {
+		return errors.New("消息ID不能为空")
+	}
+	if strings.TrimSpace(r.Content) == "" {
+		return errors.New("修正内容不能为空")
+	}
+	return nil
+}
+
+// ModerationHeldListRequest 待审核内容列表请求
+type ModerationHeldListRequest struct {
+	ContentType string `form:"contentType"` // 内容类型，为空时查询全部类型
+	Page        int64  `form:"page"`        // 页码
+	PageSize    int64  `form:"pageSize"`    // 每页数量
+}
+
+// Validate 验证请求参数
+func (r *ModerationHeldListRequest) Validate() error {
+	if r.ContentType != "" && !validModerationContentType(r.ContentType) {
+		return errors.New("内容类型不正确")
+	}
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	return err
+}
+
+// ModerationHeldListResponse 待审核内容列表响应
+type ModerationHeldListResponse struct {
+	List     []*dto.ModerationAuditDTO `json:"list"` // 待审核内容，按进入审核的时间正序
+	PageInfo *consts.ApiPageResponse   `json:"pageInfo"`
+}
+
+// ModerationAuditListRequest 内容审核记录请求
+type ModerationAuditListRequest struct {
+	ContentType string `form:"contentType" binding:"required"` // 内容类型
+	ContentID   string `form:"contentId" binding:"required"`   // 内容ID
+}
+
+// Validate 验证请求参数
+func (r *ModerationAuditListRequest) Validate() error {
+	if !validModerationContentType(r.ContentType) {
+		return errors.New("内容类型不正确")
+	}
+	if r.ContentID == "" {
+		return errors.New("内容ID不能为空")
+	}
+	return nil
+}
+
+// ModerationAuditListResponse 内容审核记录响应
+type ModerationAuditListResponse struct {
+	List []*dto.ModerationAuditDTO `json:"list"` // 审核记录，按审核时间正序
+}
+
+// ModerationReviewRequest 教师审核请求
+type ModerationReviewRequest struct {
+	ContentType string `json:"contentType" binding:"required"` // 内容类型
+	ContentID   string `json:"contentId" binding:"required"`   // 内容ID
+	Approve     bool   `json:"approve"`                        // 是否通过，通过时恢复展示，否则不予展示
+	Reason      string `json:"reason"`                         // 审核说明
+}
+
+// Validate 验证请求参数
+func (r *ModerationReviewRequest) Validate() error {
+	if !validModerationContentType(r.ContentType) {
+		return errors.New("内容类型不正确")
+	}
+	if r.ContentID == "" {
+		return errors.New("内容ID不能为空")
+	}
+	if len([]rune(r.Reason)) > consts.ModerationReasonMaxLen {
+		return errors.New("审核说明过长")
+	}
+	return nil
+}
+
+func validModerationContentType(contentType string) bool {
+	return contentType == string(consts.ModerationContentTypeMessage) || contentType == string(consts.ModerationContentTypeFeedback)
+}
+
+// MessageSearchRequest 会话消息检索请求，筛选条件为 0 或空时不过滤
+type MessageSearchRequest struct {
+	Keyword     string `form:"keyword" binding:"required"` // 关键词
+	ClassID     int64  `form:"classId"`                    // 班级ID，为 0 时检索教师任职的全部班级
+	ClassroomID int64  `form:"classroomId"`                // 课堂ID
+	TaskID      int64  `form:"taskId"`                     // 任务ID
+	AssignID    int64  `form:"assignId"`                   // 布置ID
+	QuestionID  string `form:"questionId"`                 // 题目ID
+	SessionType string `form:"sessionType"`                // 会话类型，question 为学生针对题目的提问
+	UserID      int64  `form:"userId"`                     // 发送人ID
+	UserType    string `form:"userType"`                   // 发送人类型
+	StartTime   int64  `form:"startTime"`                  // 发送时间起点，秒级时间戳
+	EndTime     int64  `form:"endTime"`                    // 发送时间终点，秒级时间戳
+	Page        int64  `form:"page"`                       // 页码
+	PageSize    int64  `form:"pageSize"`                   // 每页数量
+}
+
+// Validate 验证请求参数
+func (r *MessageSearchRequest) Validate() error {
+	r.Keyword = strings.TrimSpace(r.Keyword)
+	if r.Keyword == "" {
+		return errors.New("关键词不能为空")
+	}
+	if r.StartTime > 0 && r.EndTime > 0 && r.StartTime > r.EndTime {
+		return errors.New("开始时间不能晚于结束时间")
+	}
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	if err != nil {
+		return err
+	}
+	if r.PageSize > consts.MessageSearchMaxPageSize {
+		r.PageSize = consts.MessageSearchMaxPageSize
+	}
+	if r.Page*r.PageSize > consts.MessageSearchMaxWindow {
+		return fmt.Errorf("最多查看前 %d 条结果", consts.MessageSearchMaxWindow)
+	}
+	return nil
+}
+
+// MessageSearchResponse 会话消息检索响应
+type MessageSearchResponse struct {
+	List     []*dto.MessageSearchHitDTO `json:"list"` // 检索结果，按相关度和发送时间倒序
+	PageInfo *consts.ApiPageResponse    `json:"pageInfo"`
+}
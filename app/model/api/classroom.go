@@ -0,0 +1,190 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gil_teacher/app/consts"
+	dao_classroom "gil_teacher/app/dao/classroom"
+)
+
+// ClassroomLifecycleRequest 开始、暂停、结束上课请求
+type ClassroomLifecycleRequest struct {
+	ClassroomID int64 `json:"classroomId" binding:"required"` // 课堂ID，即课程表中的 classroom_id
+}
+
+// Validate 验证请求参数
+func (r *ClassroomLifecycleRequest) Validate() error {
+	if r.ClassroomID <= 0 {
+		return errors.New("课堂ID不能为0")
+	}
+	return nil
+}
+
+// SubmitClassroomFeedbackRequest 教师提交课堂反馈请求
+type SubmitClassroomFeedbackRequest struct {
+	ClassID     int64  `json:"classId"`     // 班级ID
+	ClassroomID int64  `json:"classroomId"` // 课堂ID，可选
+	Content     string `json:"content"`     // 反馈内容
+	Remark      int64  `json:"remark"`      // 反馈备注标识，客户端定义的反馈选项，可选
+}
+
+// Validate 验证请求参数
+func (r *SubmitClassroomFeedbackRequest) Validate() error {
+	if r.ClassID <= 0 {
+		return errors.New("classId is required")
+	}
+	if r.ClassroomID < 0 || r.Remark < 0 {
+		return errors.New("classroomId or remark is invalid")
+	}
+	r.Content = strings.TrimSpace(r.Content)
+	if r.Content == "" {
+		return errors.New("content is required")
+	}
+	if utf8.RuneCountInString(r.Content) > consts.ClassroomFeedbackContentMaxLen {
+		return fmt.Errorf("反馈内容不能超过 %d 字", consts.ClassroomFeedbackContentMaxLen)
+	}
+	return nil
+}
+
+// StudentSubmitClassroomFeedbackRequest 学生端提交课堂反馈请求
+type StudentSubmitClassroomFeedbackRequest struct {
+	StudentID int64 `json:"studentId"` // 学生ID
+	SchoolID  int64 `json:"schoolId"`  // 学校ID
+	SubmitClassroomFeedbackRequest
+}
+
+// Validate 验证请求参数
+func (r *StudentSubmitClassroomFeedbackRequest) Validate() error {
+	if r.StudentID <= 0 || r.SchoolID <= 0 {
+		return errors.New("studentId and schoolId are required")
+	}
+	return r.SubmitClassroomFeedbackRequest.Validate()
+}
+
+// ClassroomFeedbackFilter 课堂反馈查询条件
+type ClassroomFeedbackFilter struct {
+	ClassID     int64  `form:"classId"`     // 班级ID
+	ClassroomID int64  `form:"classroomId"` // 课堂ID，可选
+	CreateType  int64  `form:"createType"`  // 反馈创建类型，1学生 2教师，不传查询全部
+	Status      *int64 `form:"status"`      // 处理状态，0待处理 1已处理，不传查询全部
+	StartDate   string `form:"startDate"`   // 开始日期 YYYY-MM-DD，默认结束日期前 6 天
+	EndDate     string `form:"endDate"`     // 结束日期 YYYY-MM-DD，包含当天，默认今天
+
+	StartTime int64 `form:"-"` // 开始日期零点
+	EndTime   int64 `form:"-"` // 结束日期次日零点
+}
+
+// Validate 验证查询条件，并将日期转换为时间范围
+func (r *ClassroomFeedbackFilter) Validate() error {
+	if r.ClassID <= 0 {
+		return errors.New("classId is required")
+	}
+	if r.CreateType != 0 && r.CreateType != consts.ClassroomFeedbackCreateTypeStudent && r.CreateType != consts.ClassroomFeedbackCreateTypeTeacher {
+		return errors.New("createType is invalid")
+	}
+	if r.Status != nil && *r.Status != consts.ClassroomFeedbackStatusPending && *r.Status != consts.ClassroomFeedbackStatusHandled {
+		return errors.New("status is invalid")
+	}
+
+	endDate := time.Now().In(consts.LocationShanghai)
+	if r.EndDate != "" {
+		var err error
+		if endDate, err = time.ParseInLocation(consts.TimeFormatDate, r.EndDate, consts.LocationShanghai); err != nil {
+			return errors.New("endDate is invalid")
+		}
+	}
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, consts.LocationShanghai)
+	startDate := endDate.AddDate(0, 0, -6)
+	if r.StartDate != "" {
+		var err error
+		if startDate, err = time.ParseInLocation(consts.TimeFormatDate, r.StartDate, consts.LocationShanghai); err != nil {
+			return errors.New("startDate is invalid")
+		}
+	}
+	if startDate.After(endDate) {
+		return errors.New("startDate must not be after endDate")
+	}
+	if startDate.AddDate(0, 0, consts.ClassroomFeedbackMaxDays).Before(endDate) {
+		return fmt.Errorf("时间跨度不能超过 %d 天", consts.ClassroomFeedbackMaxDays)
+	}
+
+	r.StartDate = startDate.Format(consts.TimeFormatDate)
+	r.EndDate = endDate.Format(consts.TimeFormatDate)
+	r.StartTime = startDate.Unix()
+	r.EndTime = endDate.AddDate(0, 0, 1).Unix()
+	return nil
+}
+
+// ClassroomFeedbackListRequest 课堂反馈列表请求
+type ClassroomFeedbackListRequest struct {
+	ClassroomFeedbackFilter
+	Page     int64 `form:"page"`     // 页码
+	PageSize int64 `form:"pageSize"` // 每页数量
+}
+
+// Validate 验证请求参数
+func (r *ClassroomFeedbackListRequest) Validate() error {
+	if err := r.ClassroomFeedbackFilter.Validate(); err != nil {
+		return err
+	}
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	return err
+}
+
+// ClassroomFeedbackListResponse 课堂反馈列表响应
+type ClassroomFeedbackListResponse struct {
+	List     []*dao_classroom.ClassroomFeedback `json:"list"` // 课堂反馈，按提交时间倒序
+	PageInfo *consts.ApiPageResponse            `json:"pageInfo"`
+}
+
+// ClassroomFeedbackStatsResponse 班级课堂反馈汇总
+type ClassroomFeedbackStatsResponse struct {
+	ClassID      int64                          `json:"classId"`      // 班级ID
+	StartDate    string                         `json:"startDate"`    // 开始日期
+	EndDate      string                         `json:"endDate"`      // 结束日期
+	Total        int64                          `json:"total"`        // 反馈总数
+	StudentCount int64                          `json:"studentCount"` // 学生反馈数
+	TeacherCount int64                          `json:"teacherCount"` // 教师反馈数
+	PendingCount int64                          `json:"pendingCount"` // 待处理反馈数
+	HandledCount int64                          `json:"handledCount"` // 已处理反馈数
+	Remarks      []*ClassroomFeedbackRemarkStat `json:"remarks"`      // 按备注标识统计，按标识排序，不含未选择标识的反馈
+	Days         []*ClassroomFeedbackDayStat    `json:"days"`         // 按天统计，包含时间范围内的每一天
+}
+
+// ClassroomFeedbackRemarkStat 按备注标识统计的反馈数
+type ClassroomFeedbackRemarkStat struct {
+	Remark int64 `json:"remark"` // 反馈备注标识
+	Count  int64 `json:"count"`  // 反馈数
+}
+
+// ClassroomFeedbackDayStat 每天的反馈数
+type ClassroomFeedbackDayStat struct {
+	Date         string `json:"date"`         // 日期 YYYY-MM-DD
+	Total        int64  `json:"total"`        // 反馈总数
+	StudentCount int64  `json:"studentCount"` // 学生反馈数
+	TeacherCount int64  `json:"teacherCount"` // 教师反馈数
+	PendingCount int64  `json:"pendingCount"` // 待处理反馈数
+}
+
+// HandleClassroomFeedbackRequest 标记课堂反馈已处理请求
+type HandleClassroomFeedbackRequest struct {
+	FeedbackIDs []int64 `json:"feedbackIds"` // 反馈ID列表
+}
+
+// Validate 验证请求参数
+func (r *HandleClassroomFeedbackRequest) Validate() error {
+	if len(r.FeedbackIDs) == 0 || len(r.FeedbackIDs) > consts.ClassroomFeedbackHandleMaxSize {
+		return fmt.Errorf("feedbackIds 数量必须在 1 到 %d 之间", consts.ClassroomFeedbackHandleMaxSize)
+	}
+	for _, id := range r.FeedbackIDs {
+		if id <= 0 {
+			return errors.New("feedbackIds is invalid")
+		}
+	}
+	return nil
+}
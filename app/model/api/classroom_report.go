@@ -0,0 +1,95 @@
+package api
+
+import (
+	"errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/dto"
+
+	"github.com/google/uuid"
+)
+
+// ClassroomReportListRequest 班级历史课堂报告列表请求
+type ClassroomReportListRequest struct {
+	ClassID  int64 `form:"classId"`  // 班级ID
+	Page     int64 `form:"page"`     // 页码
+	PageSize int64 `form:"pageSize"` // 每页数量
+}
+
+// Validate 验证请求参数
+func (r *ClassroomReportListRequest) Validate() error {
+	if r.ClassID <= 0 {
+		return errors.New("classId is required")
+	}
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	return err
+}
+
+// ClassroomReportDetailRequest 课堂报告详情请求
+type ClassroomReportDetailRequest struct {
+	ReportID string `form:"reportId"` // 报告ID
+}
+
+// Validate 验证请求参数
+func (r *ClassroomReportDetailRequest) Validate() error {
+	if _, err := uuid.Parse(r.ReportID); err != nil {
+		return errors.New("reportId is invalid")
+	}
+	return nil
+}
+
+// ClassroomReportListResponse 班级历史课堂报告列表响应
+type ClassroomReportListResponse struct {
+	List     []*ClassroomReport      `json:"list"` // 课堂报告，按上课时间倒序，不包含学生学情
+	PageInfo *consts.ApiPageResponse `json:"pageInfo"`
+}
+
+// ClassroomReport 课后课堂报告
+type ClassroomReport struct {
+	ReportID    string `json:"reportId"`    // 报告ID
+	ClassID     uint64 `json:"classId"`     // 班级ID
+	ClassroomID uint64 `json:"classroomId"` // 课堂ID
+	TeacherID   uint64 `json:"teacherId"`   // 教师ID
+	CourseID    uint64 `json:"courseId"`    // 课程ID
+	StartTime   int64  `json:"startTime"`   // 上课时间(UTC秒数)
+	EndTime     int64  `json:"endTime"`     // 下课时间(UTC秒数)
+	CreateTime  int64  `json:"createTime"`  // 报告生成时间(UTC秒数)
+	*ClassroomReportContent
+	Students []*ClassroomReportStudent `json:"students,omitempty"` // 学生学情，按学习分倒序，仅详情返回
+}
+
+// ClassroomReportContent 课堂报告内容，生成时快照课堂数据，以 JSON 存储
+type ClassroomReportContent struct {
+	ClassName        string                             `json:"className"`        // 班级名称
+	CourseName       string                             `json:"courseName"`       // 课程名称
+	TeacherName      string                             `json:"teacherName"`      // 教师姓名
+	TotalStudents    int64                              `json:"totalStudents"`    // 课堂总人数
+	AvgAccuracy      float64                            `json:"avgAccuracy"`      // 班级平均正确率(%)
+	AvgProgress      float64                            `json:"avgProgress"`      // 班级平均进度(%)
+	TotalDuration    int64                              `json:"totalDuration"`    // 班级总学习时长(秒)
+	AvgLearningScore float64                            `json:"avgLearningScore"` // 班级平均学习分
+	PraiseCount      int64                              `json:"praiseCount"`      // 课上教师表扬次数
+	AttentionCount   int64                              `json:"attentionCount"`   // 课上教师关注次数
+	Communication    dto.ClassroomCommunicationStatsDTO `json:"communication"`    // 课上沟通统计
+	PraiseList       []StudentBehaviorCategory          `json:"praiseList"`       // 值得表扬学生列表
+	AttentionList    []StudentBehaviorCategory          `json:"attentionList"`    // 建议关注学生列表
+}
+
+// ClassroomReportStudent 课堂报告中单个学生的学情
+type ClassroomReportStudent struct {
+	StudentID        uint64        `json:"studentId"`        // 学生ID
+	StudentName      string        `json:"studentName"`      // 学生姓名
+	AvatarURL        string        `json:"avatarUrl"`        // 头像URL
+	LearningScore    int64         `json:"learningScore"`    // 学习分
+	LearningTime     uint64        `json:"learningTime"`     // 学习时长(秒)
+	CorrectCount     uint64        `json:"correctCount"`     // 正确答题数
+	TotalCount       uint64        `json:"totalCount"`       // 总答题数
+	AccuracyRate     float64       `json:"accuracyRate"`     // 正确率(%)
+	LearningProgress float64       `json:"learningProgress"` // 学习进度(%)
+	PraiseCount      int64         `json:"praiseCount"`      // 被表扬次数
+	AttentionCount   int64         `json:"attentionCount"`   // 被关注次数
+	BehaviorTags     []BehaviorTag `json:"behaviorTags"`     // 行为标签
+
+	ScoreItems []dto.LearningScoreItem `json:"scoreItems,omitempty"` // 学习分明细，早期报告没有
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"errors"
+)
+
+// PushStreamQuery 建立推送连接的参数
+// 重连时 lastSeq 不传则使用 Last-Event-ID 请求头，补发序号更大的未确认消息
+type PushStreamQuery struct {
+	StudentID   int64 `form:"studentId"`   // 学生ID，仅学生端连接需要
+	ClassroomID int64 `form:"classroomId"` // 课堂ID，指定后同时接收课堂广播，并只接收该课堂的课堂消息
+	LastSeq     int64 `form:"lastSeq"`     // 客户端已收到的最大消息序号
+}
+
+func (r *PushStreamQuery) Validate() error {
+	if r.ClassroomID < 0 || r.LastSeq < 0 {
+		return errors.New("classroomId or lastSeq is invalid")
+	}
+	return nil
+}
+
+// PushAckRequest 确认推送消息，确认后不再补发序号不大于 seq 的消息
+type PushAckRequest struct {
+	StudentID int64 `json:"studentId"` // 学生ID，仅学生端确认需要
+	Seq       int64 `json:"seq"`       // 已处理的最大消息序号
+}
+
+func (r *PushAckRequest) Validate() error {
+	if r.Seq <= 0 {
+		return errors.New("seq is required")
+	}
+	return nil
+}
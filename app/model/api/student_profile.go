@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gil_teacher/app/consts"
+)
+
+// StudentProfileRequest 学生跨课堂行为画像请求
+type StudentProfileRequest struct {
+	StudentID int64  `form:"studentId"` // 学生ID
+	ClassID   int64  `form:"classId"`   // 班级ID
+	StartDate string `form:"startDate"` // 开始日期 YYYY-MM-DD，默认结束日期前 6 天
+	EndDate   string `form:"endDate"`   // 结束日期 YYYY-MM-DD，包含当天，默认今天
+
+	StartTime time.Time `form:"-"` // 开始日期零点
+	EndTime   time.Time `form:"-"` // 结束日期次日零点
+}
+
+// Validate 验证请求参数，并将日期转换为时间范围
+func (r *StudentProfileRequest) Validate() error {
+	if r.StudentID <= 0 {
+		return errors.New("studentId is required")
+	}
+	if r.ClassID <= 0 {
+		return errors.New("classId is required")
+	}
+
+	endDate := time.Now().In(consts.LocationShanghai)
+	if r.EndDate != "" {
+		var err error
+		if endDate, err = time.ParseInLocation(consts.TimeFormatDate, r.EndDate, consts.LocationShanghai); err != nil {
+			return errors.New("endDate is invalid")
+		}
+	}
+	endDate = time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, consts.LocationShanghai)
+	startDate := endDate.AddDate(0, 0, 1-consts.StudentProfileDefaultDays)
+	if r.StartDate != "" {
+		var err error
+		if startDate, err = time.ParseInLocation(consts.TimeFormatDate, r.StartDate, consts.LocationShanghai); err != nil {
+			return errors.New("startDate is invalid")
+		}
+	}
+	if startDate.After(endDate) {
+		return errors.New("startDate must not be after endDate")
+	}
+	if startDate.AddDate(0, 0, consts.StudentProfileMaxDays).Before(endDate) {
+		return fmt.Errorf("时间跨度不能超过 %d 天", consts.StudentProfileMaxDays)
+	}
+
+	r.StartDate = startDate.Format(consts.TimeFormatDate)
+	r.EndDate = endDate.Format(consts.TimeFormatDate)
+	r.StartTime = startDate
+	r.EndTime = endDate.AddDate(0, 0, 1)
+	return nil
+}
+
+// StudentProfileResponse 学生跨课堂行为画像响应
+type StudentProfileResponse struct {
+	StudentID  int64                       `json:"studentId"`  // 学生ID
+	ClassID    int64                       `json:"classId"`    // 班级ID
+	StartDate  string                      `json:"startDate"`  // 开始日期
+	EndDate    string                      `json:"endDate"`    // 结束日期
+	Total      StudentProfileStat          `json:"total"`      // 时间范围内的汇总
+	Days       []*StudentProfileDay        `json:"days"`       // 按天趋势，没有数据的日期补 0
+	Lessons    []*StudentProfileLesson     `json:"lessons"`    // 每节课的学习分和正确率，按上课时间正序
+	Praises    []*StudentProfileActionStat `json:"praises"`    // 按类型统计的被表扬次数，按次数倒序
+	Attentions []*StudentProfileActionStat `json:"attentions"` // 按类型统计的被关注次数，按次数倒序
+}
+
+// StudentProfileStat 学生行为画像统计项
+type StudentProfileStat struct {
+	LessonCount       int64   `json:"lessonCount"`       // 有课后学情的课数
+	AvgLearningScore  float64 `json:"avgLearningScore"`  // 平均学习分
+	AnswerCount       int64   `json:"answerCount"`       // 答题数
+	CorrectCount      int64   `json:"correctCount"`      // 答对数
+	AccuracyRate      float64 `json:"accuracyRate"`      // 正确率(%)
+	QuestionCount     int64   `json:"questionCount"`     // 提问次数
+	PraiseCount       int64   `json:"praiseCount"`       // 被表扬次数，包含作业点赞
+	AttentionCount    int64   `json:"attentionCount"`    // 被关注次数，包含作业提醒
+	PageSwitchCount   int64   `json:"pageSwitchCount"`   // 频繁切换页面次数
+	OtherContentCount int64   `json:"otherContentCount"` // 学习其他内容次数
+	PauseCount        int64   `json:"pauseCount"`        // 停顿操作次数
+}
+
+// StudentProfileDay 学生行为画像的单日统计
+type StudentProfileDay struct {
+	Date string `json:"date"` // 日期 YYYY-MM-DD
+	StudentProfileStat
+}
+
+// StudentProfileLesson 学生在一节课的学情
+type StudentProfileLesson struct {
+	ReportID      string  `json:"reportId"`      // 课堂报告ID
+	ClassroomID   uint64  `json:"classroomId"`   // 课堂ID
+	CourseID      uint64  `json:"courseId"`      // 课程ID
+	StartTime     int64   `json:"startTime"`     // 上课时间(UTC秒数)
+	LearningScore int64   `json:"learningScore"` // 学习分
+	LearningTime  uint64  `json:"learningTime"`  // 学习时长(秒)
+	CorrectCount  uint64  `json:"correctCount"`  // 正确答题数
+	TotalCount    uint64  `json:"totalCount"`    // 总答题数
+	AccuracyRate  float64 `json:"accuracyRate"`  // 正确率(%)
+}
+
+// StudentProfileActionStat 按类型统计的表扬/关注次数
+type StudentProfileActionStat struct {
+	ActionType string `json:"actionType"` // 表扬/关注类型，作业点赞和提醒为 task
+	Count      int64  `json:"count"`      // 次数
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"time"
+
 	"gil_teacher/app/consts"
 	"gil_teacher/app/controller/http_server/response"
 	dao_task "gil_teacher/app/dao/task"
@@ -23,9 +25,10 @@ type GetTaskTypeResponse struct {
 
 // TaskResource 任务关联资源
 type TaskResource struct {
-	ResourceID    string `json:"resourceId" binding:"required"`
-	ResourceType  int64  `json:"resourceType" binding:"required"`
-	ResourceExtra string `json:"resourceExtra"` // 资源额外信息
+	ResourceID    string  `json:"resourceId" binding:"required"`
+	ResourceType  int64   `json:"resourceType" binding:"required"`
+	ResourceExtra string  `json:"resourceExtra"` // 资源额外信息
+	StudentIDs    []int64 `json:"-"`             // 资源所属学生，错题作业由后端生成，为空时全部学生可见
 }
 
 // StudentGroups 任务关联学生群组
@@ -37,11 +40,20 @@ type StudentGroup struct {
 	Deadline   int64   `json:"deadline" binding:"required"`  // 截止时间
 }
 
+// TaskTier 分层作业的单个分层
+type TaskTier struct {
+	TierName    string         `json:"tierName" binding:"required"`  // 分层名称
+	MinAccuracy float64        `json:"minAccuracy,omitempty"`        // 自动分层时进入该层的最低正确率，0-1
+	Resources   []TaskResource `json:"resources" binding:"required"` // 该层的资源，与任务的 resources 一起组成该层学生的资源
+	StudentIDs  []int64        `json:"studentIds,omitempty"`         // 手动分层时该层的学生ID列表
+}
+
 // GetTaskDetailByIDResponse 根据ID查询任务详情响应
 type GetTaskDetailByIDResponse struct {
 	*dao_task.Task
-	Resources     []*dao_task.TaskResource `json:"resources"`     // 任务关联资源列表
-	StudentGroups []*dao_task.TaskAssign   `json:"studentGroups"` // 任务关联学生群组列表
+	Resources     []*dao_task.TaskResource `json:"resources"`       // 任务关联资源列表
+	StudentGroups []*dao_task.TaskAssign   `json:"studentGroups"`   // 任务关联学生群组列表
+	Tiers         []*dao_task.TaskTier     `json:"tiers,omitempty"` // 分层列表，分层作业时存在
 }
 
 // GetStudentTaskListRequest 查询学生任务列表请求
@@ -90,18 +102,126 @@ type GetStudentTaskListResponse struct {
 
 // CreateTaskRequestBody 创建任务请求体
 type CreateTaskRequestBody struct {
-	SchoolID       int64          `json:"-"`
-	Phase          int64          `json:"-"`
-	Subject        int64          `json:"subject" binding:"required"`
-	TaskType       int64          `json:"taskType" binding:"required"`
-	TaskName       string         `json:"taskName" binding:"required"`
-	TeacherComment string         `json:"teacherComment,omitempty"` // 老师留言
-	TaskExtraInfo  string         `json:"taskExtraInfo,omitempty"`  // 任务额外信息
-	BizTreeID      int64          `json:"bizTreeId,omitempty"`      // 业务树ID，创建课程任务时使用
-	CreatorID      int64          `json:"-"`
-	UpdaterID      int64          `json:"-"`
-	Resources      []TaskResource `json:"resources" binding:"required"`
-	StudentGroups  []StudentGroup `json:"studentGroups" binding:"required"`
+	SchoolID       int64           `json:"-"`
+	Phase          int64           `json:"-"`
+	Subject        int64           `json:"subject" binding:"required"`
+	TaskType       int64           `json:"taskType" binding:"required"`
+	TaskSubType    int64           `json:"taskSubType,omitempty"` // 任务子类型，分层作业为 22
+	TaskName       string          `json:"taskName" binding:"required"`
+	TeacherComment string          `json:"teacherComment,omitempty"` // 老师留言
+	TaskExtraInfo  string          `json:"taskExtraInfo,omitempty"`  // 任务额外信息
+	BizTreeID      int64           `json:"bizTreeId,omitempty"`      // 业务树ID，创建课程任务时使用
+	CreatorID      int64           `json:"-"`
+	UpdaterID      int64           `json:"-"`
+	Resources      []TaskResource  `json:"resources" binding:"required"`
+	StudentGroups  []StudentGroup  `json:"studentGroups" binding:"required"`
+	TierMode       int64           `json:"tierMode,omitempty"`   // 分层方式，1 手动分层，2 按最近一次作业正确率自动分层
+	Tiers          []TaskTier      `json:"tiers,omitempty"`      // 分层列表，分层作业时必传，分层序号按数组顺序从 1 开始
+	AnswerCard     *dto.AnswerCard `json:"answerCard,omitempty"` // 答题卡答案，答题卡任务时必传
+	Status         int64           `json:"status,omitempty"`     // 任务状态，不传时立即发布，1 保存为草稿，2 定时发布
+}
+
+// IsLayered 是否为分层作业
+func (c *CreateTaskRequestBody) IsLayered() bool {
+	return c.TaskType == consts.TASK_TYPE_HOMEWORK && c.TaskSubType == consts.TASK_TYPE_HOMEWORK_LAYERED
+}
+
+// IsAnswerCard 是否为答题卡任务
+func (c *CreateTaskRequestBody) IsAnswerCard() bool {
+	return consts.IsAnswerCardTask(c.TaskSubType)
+}
+
+// 校验答题卡答案，选择题的选项为大写字母，单选题只能有一个正确选项，判断题为 T 或 F
+func (c *CreateTaskRequestBody) validateAnswerCard() *response.Response {
+	if c.AnswerCard == nil || len(c.AnswerCard.Questions) == 0 || len(c.AnswerCard.Questions) > consts.AnswerCardMaxQuestionNum {
+		return &response.ERR_INVALID_ANSWER_CARD
+	}
+	// 答题卡任务只有系统生成的答题卡资源
+	if len(c.Resources) > 0 || len(c.Tiers) > 0 {
+		return &response.ERR_INVALID_ANSWER_CARD
+	}
+	for _, question := range c.AnswerCard.Questions {
+		if question == nil || len(question.CorrectOptions) == 0 {
+			return &response.ERR_INVALID_ANSWER_CARD
+		}
+		options := make(map[string]struct{}, len(question.CorrectOptions))
+		for _, option := range question.CorrectOptions {
+			if _, ok := options[option]; ok {
+				return &response.ERR_INVALID_ANSWER_CARD
+			}
+			options[option] = struct{}{}
+		}
+		switch question.QuestionType {
+		case consts.ANSWER_CARD_QUESTION_SINGLE, consts.ANSWER_CARD_QUESTION_MULTIPLE:
+			if question.QuestionType == consts.ANSWER_CARD_QUESTION_SINGLE && len(question.CorrectOptions) != 1 {
+				return &response.ERR_INVALID_ANSWER_CARD
+			}
+			for _, option := range question.CorrectOptions {
+				if len(option) != 1 || option[0] < 'A' || option[0] > 'Z' {
+					return &response.ERR_INVALID_ANSWER_CARD
+				}
+			}
+		case consts.ANSWER_CARD_QUESTION_JUDGE:
+			if len(question.CorrectOptions) != 1 || (question.CorrectOptions[0] != "T" && question.CorrectOptions[0] != "F") {
+				return &response.ERR_INVALID_ANSWER_CARD
+			}
+		default:
+			return &response.ERR_INVALID_ANSWER_CARD
+		}
+	}
+	return nil
+}
+
+// AllResources 任务全部资源，包括共用资源和各分层的资源
+func (c *CreateTaskRequestBody) AllResources() []TaskResource {
+	resources := make([]TaskResource, 0, len(c.Resources))
+	resources = append(resources, c.Resources...)
+	for _, tier := range c.Tiers {
+		resources = append(resources, tier.Resources...)
+	}
+	return resources
+}
+
+// 校验分层作业的分层设置，同一个资源只能出现在一个分层或共用资源中
+func (c *CreateTaskRequestBody) validateTiers() *response.Response {
+	if len(c.Tiers) < consts.TaskTierMinNum || len(c.Tiers) > consts.TaskTierMaxNum {
+		return &response.ERR_INVALID_TASK_TIER
+	}
+	if c.TierMode != consts.TASK_TIER_MODE_MANUAL && c.TierMode != consts.TASK_TIER_MODE_AUTO {
+		return &response.ERR_INVALID_TASK_TIER
+	}
+
+	resourceKeys := make(map[string]struct{})
+	for _, resource := range c.Resources {
+		resourceKeys[utils.JoinList([]any{resource.ResourceID, resource.ResourceType}, consts.CombineKey)] = struct{}{}
+	}
+	tierStudents := make(map[int64]struct{})
+	for _, tier := range c.Tiers {
+		if tier.TierName == "" || len(tier.Resources) == 0 {
+			return &response.ERR_INVALID_TASK_TIER
+		}
+		if tier.MinAccuracy < 0 || tier.MinAccuracy > 1 {
+			return &response.ERR_INVALID_TASK_TIER
+		}
+		for _, resource := range tier.Resources {
+			resourceKey := utils.JoinList([]any{resource.ResourceID, resource.ResourceType}, consts.CombineKey)
+			if _, ok := resourceKeys[resourceKey]; ok {
+				return &response.ERR_INVALID_TASK_TIER
+			}
+			resourceKeys[resourceKey] = struct{}{}
+		}
+		if c.TierMode != consts.TASK_TIER_MODE_MANUAL {
+			continue
+		}
+		// 手动分层时一个学生只能在一个分层
+		for _, studentID := range tier.StudentIDs {
+			if _, ok := tierStudents[studentID]; ok {
+				return &response.ERR_INVALID_TASK_TIER
+			}
+			tierStudents[studentID] = struct{}{}
+		}
+	}
+	return nil
 }
 
 func (c *CreateTaskRequestBody) Validate() *response.Response {
@@ -111,24 +231,65 @@ func (c *CreateTaskRequestBody) Validate() *response.Response {
 	if _, ok := consts.TaskTypeNameMap[c.TaskType]; !ok {
 		return &response.ERR_INVALID_TASK_TYPE
 	}
-	if len(c.Resources) == 0 {
+	if !consts.TaskSubTypeExists(c.TaskType, c.TaskSubType) {
+		return &response.ERR_INVALID_TASK_TYPE
+	}
+	if c.IsLayered() {
+		if err := c.validateTiers(); err != nil {
+			return err
+		}
+	} else if len(c.Tiers) > 0 {
+		return &response.ERR_INVALID_TASK_TIER
+	}
+	if c.IsAnswerCard() {
+		if err := c.validateAnswerCard(); err != nil {
+			return err
+		}
+	} else if c.AnswerCard != nil {
+		return &response.ERR_INVALID_ANSWER_CARD
+	} else if len(c.AllResources()) == 0 {
 		return &response.ERR_EMPTY_RESOURCE
 	}
-	for _, resource := range c.Resources {
-		if _, ok := consts.ResourceTypeNameMap[resource.ResourceType]; !ok {
+	for _, resource := range c.AllResources() {
+		if _, ok := consts.ResourceTypeNameMap[resource.ResourceType]; !ok || resource.ResourceType == consts.RESOURCE_TYPE_ANSWER_CARD {
 			return &response.ERR_INVALID_RESOURCE_TYPE
 		}
 	}
-	if len(c.StudentGroups) == 0 {
-		return &response.ERR_EMPTY_STUDENT
-	}
 	// 课程任务必须传递业务树ID
 	if c.TaskType == consts.TASK_TYPE_COURSE && c.BizTreeID <= 0 {
 		return &response.ERR_BIZ_TREE
 	}
+	if err := validateStudentGroups(c.StudentGroups); err != nil {
+		return err
+	}
+	return validatePublishStatus(c.Status, c.StudentGroups)
+}
+
+// 校验任务的发布状态，定时发布时全部布置的开始时间都需要晚于当前时间，发布时间为最早的开始时间
+func validatePublishStatus(status int64, studentGroups []StudentGroup) *response.Response {
+	switch status {
+	case 0, consts.TASK_STATUS_DRAFT, consts.TASK_STATUS_PUBLISHED:
+		return nil
+	case consts.TASK_STATUS_SCHEDULED:
+		now := time.Now().Unix()
+		for _, studentGroup := range studentGroups {
+			if studentGroup.StartTime <= now {
+				return &response.ERR_INVALID_TIME
+			}
+		}
+		return nil
+	}
+	return &response.ERR_INVALID_TASK_STATUS
+}
+
+// 校验任务布置的学生群组
+func validateStudentGroups(studentGroups []StudentGroup) *response.Response {
+	if len(studentGroups) == 0 {
+		return &response.ERR_EMPTY_STUDENT
+	}
 	// 当次任务不能重复派发到同一个班级
 	classIDs := make(map[int64]struct{})
-	for _, studentGroup := range c.StudentGroups {
+	for _, studentGroup := range studentGroups {
 		if !utils.IsValidUnixTimestamp(studentGroup.StartTime, studentGroup.Deadline) {
 			return &response.ERR_INVALID_TIME
 		}
@@ -152,6 +313,47 @@ func (c *CreateTaskRequestBody) Validate() *response.Response {
 	return nil
 }
 
+// CreateWrongQuestionTaskRequest 按学生历史错题生成错题作业请求体
+type CreateWrongQuestionTaskRequest struct {
+	Subject        int64          `json:"subject" binding:"required"`
+	TaskName       string         `json:"taskName" binding:"required"`
+	TeacherComment string         `json:"teacherComment,omitempty"` // 老师留言
+	AnswerTimeFrom int64          `json:"answerTimeFrom"`           // 错题作答时间 >=
+	AnswerTimeTo   int64          `json:"answerTimeTo"`             // 错题作答时间 <
+	SourceTaskIDs  []int64        `json:"sourceTaskIds,omitempty"`  // 来源任务ID列表，为空时不限制
+	MaxQuestionNum int64          `json:"maxQuestionNum,omitempty"` // 每个学生最多错题数，默认 20
+	StudentGroups  []StudentGroup `json:"studentGroups" binding:"required"`
+}
+
+func (r *CreateWrongQuestionTaskRequest) Validate() *response.Response {
+	if r.TaskName == "" {
+		return &response.ERR_EMPTY_TASK_NAME
+	}
+	if !utils.IsValidUnixTimestamp(r.AnswerTimeFrom, r.AnswerTimeTo) || r.AnswerTimeFrom >= r.AnswerTimeTo {
+		return &response.ERR_INVALID_TIME
+	}
+	if r.AnswerTimeTo-r.AnswerTimeFrom > consts.WrongQuestionMaxDays*24*3600 {
+		return &response.ERR_INVALID_WRONG_QUESTION
+	}
+	if len(r.SourceTaskIDs) > consts.WrongQuestionMaxSourceNum {
+		return &response.ERR_INVALID_WRONG_QUESTION
+	}
+	if r.MaxQuestionNum == 0 {
+		r.MaxQuestionNum = consts.WrongQuestionDefaultNum
+	}
+	if r.MaxQuestionNum < 0 || r.MaxQuestionNum > consts.WrongQuestionMaxNum {
+		return &response.ERR_INVALID_WRONG_QUESTION
+	}
+	return validateStudentGroups(r.StudentGroups)
+}
+
+// CreateWrongQuestionTaskResponse 错题作业生成结果
+type CreateWrongQuestionTaskResponse struct {
+	StudentNum        int64   `json:"studentNum"`        // 布置错题的学生数
+	QuestionNum       int64   `json:"questionNum"`       // 去重后的题目数
+	SkippedStudentIDs []int64 `json:"skippedStudentIds"` // 没有错题而未布置的学生
+}
+
 // DeleteTaskRequestBody 删除任务请求体
 type DeleteTaskRequestBody struct {
 	TaskIDs []int64 `json:"taskIds" binding:"required"`
@@ -199,6 +401,191 @@ func (d *DeleteTaskAssignRequestBody) Validate() *response.Response {
 	return nil
 }
 
+// CloneTaskRequestBody 复制任务并布置给其他班级请求体
+type CloneTaskRequestBody struct {
+	SchoolID       int64          `json:"-"`
+	Phase          int64          `json:"-"`
+	CreatorID      int64          `json:"-"`
+	TaskID         int64          `json:"taskId" binding:"required"` // 被复制的任务ID
+	TaskName       string         `json:"taskName,omitempty"`        // 新任务名称，不传时与原任务相同
+	TeacherComment string         `json:"teacherComment,omitempty"`  // 老师留言，不传时与原任务相同
+	CheckResource  bool           `json:"checkResource,omitempty"`   // 是否重新向内容平台校验资源是否存在
+	StudentGroups  []StudentGroup `json:"studentGroups" binding:"required"`
+	Status         int64          `json:"status,omitempty"` // 任务状态，不传时立即发布，1 保存为草稿，2 定时发布
+}
+
+func (c *CloneTaskRequestBody) Validate() *response.Response {
+	if c.TaskID <= 0 {
+		return &response.ERR_INVALID_TASK
+	}
+	if err := validateStudentGroups(c.StudentGroups); err != nil {
+		return err
+	}
+	return validatePublishStatus(c.Status, c.StudentGroups)
+}
+
+// CloneTaskResponse 复制任务响应
+type CloneTaskResponse struct {
+	TaskID int64 `json:"taskId"` // 新任务ID
+}
+
+// DraftTierResources 草稿分层作业单个分层的资源
+type DraftTierResources struct {
+	Resources []TaskResource `json:"resources" binding:"required"`
+}
+
+// UpdateDraftRequestBody 编辑草稿请求体，资源整体替换
+type UpdateDraftRequestBody struct {
+	TaskID         int64                `json:"taskId" binding:"required"`
+	TaskName       string               `json:"taskName,omitempty"`
+	TeacherComment string               `json:"teacherComment,omitempty"`
+	Resources      []TaskResource       `json:"resources"`               // 共用资源
+	TierResources  []DraftTierResources `json:"tierResources,omitempty"` // 分层作业每个分层的资源，按分层序号顺序，分层数不能修改
+}
+
+// AllResources 获取草稿的全部资源
+func (u *UpdateDraftRequestBody) AllResources() []TaskResource {
+	resources := make([]TaskResource, 0, len(u.Resources))
+	resources = append(resources, u.Resources...)
+	for _, tier := range u.TierResources {
+		resources = append(resources, tier.Resources...)
+	}
+	return resources
+}
+
+func (u *UpdateDraftRequestBody) Validate() *response.Response {
+	if u.TaskID <= 0 {
+		return &response.ERR_INVALID_TASK
+	}
+	if len(u.AllResources()) == 0 {
+		return &response.ERR_EMPTY_RESOURCE
+	}
+	// 同一个资源只能出现在一个分层或共用资源中
+	resourceKeys := make(map[string]struct{})
+	for _, resource := range u.AllResources() {
+		if _, ok := consts.ResourceTypeNameMap[resource.ResourceType]; !ok || resource.ResourceType == consts.RESOURCE_TYPE_ANSWER_CARD {
+			return &response.ERR_INVALID_RESOURCE_TYPE
+		}
+		resourceKey := utils.JoinList([]any{resource.ResourceID, resource.ResourceType}, consts.CombineKey)
+		if _, ok := resourceKeys[resourceKey]; ok {
+			return &response.ERR_INVALID_TASK_TIER
+		}
+		resourceKeys[resourceKey] = struct{}{}
+	}
+	for _, tier := range u.TierResources {
+		if len(tier.Resources) == 0 {
+			return &response.ERR_INVALID_TASK_TIER
+		}
+	}
+	return nil
+}
+
+// PublishTaskRequestBody 发布草稿请求体
+type PublishTaskRequestBody struct {
+	TaskID int64 `json:"taskId" binding:"required"`
+	Status int64 `json:"status,omitempty"` // 不传或 3 时立即发布，2 时在最早的布置开始时间自动发布
+}
+
+func (p *PublishTaskRequestBody) Validate() *response.Response {
+	if p.TaskID <= 0 {
+		return &response.ERR_INVALID_TASK
+	}
+	if p.Status != 0 && p.Status != consts.TASK_STATUS_PUBLISHED && p.Status != consts.TASK_STATUS_SCHEDULED {
+		return &response.ERR_INVALID_TASK_STATUS
+	}
+	return nil
+}
+
+// ArchiveTaskRequestBody 归档任务请求体
+type ArchiveTaskRequestBody struct {
+	TaskIDs []int64 `json:"taskIds" binding:"required"`
+}
+
+// GetUnpublishedTaskListRequest 查询未发布的任务列表请求
+type GetUnpublishedTaskListRequest struct {
+	Status   int64 `form:"status"` // 1 草稿，2 定时发布，不传时为草稿
+	Subject  int64 `form:"subject"`
+	Page     int64 `form:"page"`
+	PageSize int64 `form:"pageSize"`
+}
+
+func (g *GetUnpublishedTaskListRequest) Validate() *response.Response {
+	if g.Status == 0 {
+		g.Status = consts.TASK_STATUS_DRAFT
+	}
+	if g.Status != consts.TASK_STATUS_DRAFT && g.Status != consts.TASK_STATUS_SCHEDULED {
+		return &response.ERR_INVALID_TASK_STATUS
+	}
+	if g.Subject != 0 && !consts.SubjectExists(g.Subject) {
+		return &response.ERR_SUBJECT
+	}
+	pageInfo := &consts.APIReqeustPageInfo{Page: g.Page, PageSize: g.PageSize}
+	if err := pageInfo.Check(); err != nil {
+		return err
+	}
+	g.Page, g.PageSize = pageInfo.Page, pageInfo.PageSize
+	return nil
+}
+
+// GetUnpublishedTaskListResponse 未发布的任务列表响应
+type GetUnpublishedTaskListResponse struct {
+	List                    []*dao_task.Task `json:"list"` // 任务列表
+	*consts.ApiPageResponse                  // 分页信息
+}
+
+// CreateTaskRecurrenceRequest 创建周期布置规则请求体
+type CreateTaskRecurrenceRequest struct {
+	TaskID      int64   `json:"taskId" binding:"required"`
+	GroupType   int64   `json:"groupType" binding:"required"` // 群组类型，目前只支持班级
+	GroupID     int64   `json:"groupId" binding:"required"`   // 班级ID
+	StudentIDs  []int64 `json:"-"`                            // 班级学生，后端填充
+	Weekdays    []int64 `json:"weekdays" binding:"required"`  // 布置日，1-7 对应周一到周日
+	StartOffset int64   `json:"startOffset"`                  // 每次布置的开始时间，当天零点后的秒数
+	Duration    int64   `json:"duration" binding:"required"`  // 每次布置从开始到截止的秒数
+	StartDate   int64   `json:"startDate" binding:"required"` // 规则开始日期，取当天零点
+	EndDate     int64   `json:"endDate" binding:"required"`   // 规则结束日期，取当天零点，当天仍会布置
+}
+
+func (c *CreateTaskRecurrenceRequest) Validate() *response.Response {
+	if c.TaskID <= 0 {
+		return &response.ERR_INVALID_TASK
+	}
+	if c.GroupType != consts.TASK_GROUP_TYPE_CLASS || c.GroupID <= 0 {
+		return &response.ERR_EMPTY_STUDENT
+	}
+	if len(c.Weekdays) == 0 || len(c.Weekdays) > 7 {
+		return &response.ERR_INVALID_TASK_RECURRENCE
+	}
+	weekdays := make(map[int64]struct{}, len(c.Weekdays))
+	for _, weekday := range c.Weekdays {
+		if weekday < 1 || weekday > 7 {
+			return &response.ERR_INVALID_TASK_RECURRENCE
+		}
+		if _, ok := weekdays[weekday]; ok {
+			return &response.ERR_INVALID_TASK_RECURRENCE
+		}
+		weekdays[weekday] = struct{}{}
+	}
+	if c.StartOffset < 0 || c.StartOffset >= 24*3600 {
+		return &response.ERR_INVALID_TASK_RECURRENCE
+	}
+	if c.Duration <= 0 || c.Duration > consts.TaskRecurrenceMaxDuration {
+		return &response.ERR_INVALID_TASK_RECURRENCE
+	}
+	if !utils.IsValidUnixTimestamp(c.StartDate, c.EndDate) {
+		return &response.ERR_INVALID_TIME
+	}
+	if c.StartDate > c.EndDate || c.EndDate-c.StartDate > consts.TaskRecurrenceMaxDays*24*3600 {
+		return &response.ERR_INVALID_TASK_RECURRENCE
+	}
+	return nil
+}
+
+// StopTaskRecurrenceRequest 停止周期布置规则请求体
+type StopTaskRecurrenceRequest struct {
+	RecurrenceID int64 `json:"recurrenceId" binding:"required"`
+}
+
 // GetQuestionListRequest 查询题目列表请求
 type GetQuestionListRequest struct {
 	Phase             int64   `json:"-"`
@@ -256,3 +643,39 @@ type GetQuestionSetDetailResponse struct {
 	EstimatedTime     int64 `json:"estimatedTime"`     // 预估时间，单位：分钟，暂无
 	itl.QuestionSetStableInfo
 }
+
+// PushErrorBookTaskRequest 将班级共性错题推送到新任务请求体
+type PushErrorBookTaskRequest struct {
+	SchoolID       int64          `json:"-"`
+	Phase          int64          `json:"-"`
+	CreatorID      int64          `json:"-"`
+	Subject        int64          `json:"subject" binding:"required"`
+	ItemIDs        []int64        `json:"itemIds" binding:"required"` // 共性错题ID，按顺序作为任务的题目
+	TaskName       string         `json:"taskName" binding:"required"`
+	TeacherComment string         `json:"teacherComment,omitempty"` // 老师留言
+	StudentGroups  []StudentGroup `json:"studentGroups" binding:"required"`
+	Status         int64          `json:"status,omitempty"` // 任务状态，不传时立即发布，1 保存为草稿，2 定时发布
+}
+
+func (p *PushErrorBookTaskRequest) Validate() *response.Response {
+	if p.TaskName == "" {
+		return &response.ERR_EMPTY_TASK_NAME
+	}
+	if len(p.ItemIDs) == 0 || len(p.ItemIDs) > consts.ClassErrorBookMaxPushNum {
+		return &response.ERR_INVALID_ERROR_BOOK_ITEM
+	}
+	for _, itemID := range p.ItemIDs {
+		if itemID <= 0 {
+			return &response.ERR_INVALID_ERROR_BOOK_ITEM
+		}
+	}
+	if err := validateStudentGroups(p.StudentGroups); err != nil {
+		return err
+	}
+	return validatePublishStatus(p.Status, p.StudentGroups)
+}
+
+// PushErrorBookTaskResponse 推送共性错题结果
+type PushErrorBookTaskResponse struct {
+	QuestionNum int64 `json:"questionNum"` // 去重后的题目数
+}
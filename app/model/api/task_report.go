@@ -4,6 +4,7 @@ import (
 	"errors"
 	"gil_teacher/app/consts"
 	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/dto"
 	"gil_teacher/app/model/itl"
 )
 
@@ -321,3 +322,252 @@ type Subject struct {
 	SubjectKey  int64  `json:"subjectKey"`  // 学科 1 ~ 9
 	SubjectName string `json:"subjectName"` // 学科名称
 }
+
+/*************************************************************
+		                作业报告异步导出
+*************************************************************/
+// 提交导出任务请求，多个布置时打包为 zip
+type ExportJobSubmitRequest struct {
+	Items    []*dao_task.ExportJobItem `json:"items"`    // 导出的任务布置列表
+	Format   string                    `json:"format"`   // 导出格式 csv/xlsx，默认 csv
+	Fields   []string                  `json:"fields"`   // 导出字段，默认全部
+	SortBy   string                    `json:"sortBy"`   // 排序字段
+	SortType consts.SortType           `json:"sortType"` // 排序方式
+}
+
+func (r *ExportJobSubmitRequest) Validate() error {
+	if len(r.Items) == 0 {
+		return errors.New("items is required")
+	}
+	if len(r.Items) > consts.ExportJobMaxItems {
+		return errors.New("too many items")
+	}
+	for _, item := range r.Items {
+		if item == nil || item.TaskID <= 0 || item.AssignID <= 0 {
+			return errors.New("taskId and assignId is required")
+		}
+	}
+
+	if r.Format == "" {
+		r.Format = consts.ExportFormatCSV
+	}
+	if r.Format != consts.ExportFormatCSV && r.Format != consts.ExportFormatXLSX {
+		return errors.New("format is invalid")
+	}
+
+	if len(r.Fields) == 0 {
+		r.Fields = consts.ExportFields
+	}
+	if !consts.IsExportFields(r.Fields) {
+		return errors.New("fields is invalid")
+	}
+	r.SortBy, r.SortType = consts.SortHandler(r.SortBy, r.SortType)
+	return nil
+}
+
+// 导出任务状态
+type ExportJob struct {
+	JobID      int64  `json:"jobId"`      // 导出任务ID
+	Status     int64  `json:"status"`     // 任务状态 1 等待执行 2 执行中 3 成功 4 失败
+	Format     string `json:"format"`     // 导出格式
+	ItemCount  int64  `json:"itemCount"`  // 导出的布置数
+	FileName   string `json:"fileName"`   // 导出文件名，成功后返回
+	ErrorMsg   string `json:"errorMsg"`   // 失败原因
+	CreateTime int64  `json:"createTime"` // 提交时间
+	FinishTime int64  `json:"finishTime"` // 完成时间
+}
+
+// 导出文件下载地址
+type ExportJobURL struct {
+	JobID      int64  `json:"jobId"`      // 导出任务ID
+	FileName   string `json:"fileName"`   // 导出文件名
+	URL        string `json:"url"`        // 预签名下载地址
+	ExpireTime int64  `json:"expireTime"` // 下载地址过期时间
+}
+
+// 答题卡作答批量导入
+type AnswerCardImportRequest struct {
+	TaskID   int64                          `json:"taskId"`   // 任务ID
+	AssignID int64                          `json:"assignId"` // 任务布置ID
+	Answers  []*dto.AnswerCardStudentAnswer `json:"answers"`  // 学生作答列表
+}
+
+func (r *AnswerCardImportRequest) Validate() error {
+	if r.TaskID <= 0 || r.AssignID <= 0 {
+		return errors.New("taskId and assignId is required")
+	}
+	if len(r.Answers) == 0 {
+		return errors.New("answers is required")
+	}
+	if len(r.Answers) > consts.AnswerCardImportMaxRows {
+		return errors.New("too many answers")
+	}
+	for _, answer := range r.Answers {
+		if answer == nil {
+			return errors.New("answer is nil")
+		}
+	}
+	return nil
+}
+
+// 答题卡导入结果，存在错误的行不导入，其余行正常导入
+type AnswerCardImportResult struct {
+	StudentNum int64                    `json:"studentNum"` // 导入的学生数
+	AnswerNum  int64                    `json:"answerNum"`  // 导入的作答数
+	CorrectNum int64                    `json:"correctNum"` // 作答正确数
+	Errors     []*AnswerCardImportError `json:"errors"`     // 未导入的行
+}
+
+// 答题卡导入错误行
+type AnswerCardImportError struct {
+	Row       int64  `json:"row"`       // 行号，文件导入时为文件中的行号，批量导入时为数组下标加 1
+	StudentID int64  `json:"studentId"` // 学生ID
+	Reason    string `json:"reason"`    // 错误原因
+}
+
+/*************************************************************
+		                作业提问记录
+*************************************************************/
+// 作业提问记录查询，按知识点查询时 BizTreeNodeID 必填
+type TaskQuestionsQuery struct {
+	TaskID        int64 `form:"taskId"`        // 任务ID
+	AssignID      int64 `form:"assignId"`      // 任务布置ID
+	BizTreeNodeID int64 `form:"bizTreeNodeId"` // 业务树叶子节点ID，不关联知识点的题目为 0
+}
+
+func (r *TaskQuestionsQuery) Validate() error {
+	if r.TaskID <= 0 || r.AssignID <= 0 {
+		return errors.New("taskId and assignId is required")
+	}
+	if r.BizTreeNodeID < 0 {
+		return errors.New("bizTreeNodeId is invalid")
+	}
+	return nil
+}
+
+// 作业提问记录，按题目和知识点统计
+type TaskQuestionsResponse struct {
+	TaskID     int64                `json:"taskId"`     // 任务ID
+	AssignID   int64                `json:"assignId"`   // 任务布置ID
+	AskCount   int64                `json:"askCount"`   // 提问总次数
+	AskerCount int64                `json:"askerCount"` // 提问的学生数
+	Questions  []*QuestionAskReport `json:"questions"`  // 按题目统计，按提问次数倒序
+	Knowledges []*KnowledgeAskStat  `json:"knowledges"` // 按知识点统计，按提问次数倒序
+}
+
+// 单个知识点的提问记录
+type KnowledgeQuestionsResponse struct {
+	TaskID   int64 `json:"taskId"`   // 任务ID
+	AssignID int64 `json:"assignId"` // 任务布置ID
+	*KnowledgeAskStat
+	Questions []*QuestionAskReport `json:"questions"` // 知识点下各题目的提问记录，按提问次数倒序
+}
+
+// 知识点提问统计
+type KnowledgeAskStat struct {
+	BizTreeNodeID   int64    `json:"bizTreeNodeId"`   // 业务树叶子节点ID，不关联知识点的题目为 0
+	BizTreeNodeName string   `json:"bizTreeNodeName"` // 业务树叶子节点名称
+	AskCount        int64    `json:"askCount"`        // 提问次数
+	AskerCount      int64    `json:"askerCount"`      // 提问的学生数
+	QuestionIDs     []string `json:"questionIds"`     // 有提问的题目ID
+}
+
+// 单个题目的提问记录
+type QuestionAskReport struct {
+	QuestionID    string           `json:"questionId"`    // 题目ID
+	ResourceID    string           `json:"resourceId"`    // 题目所属资源ID
+	ResourceType  int64            `json:"resourceType"`  // 题目所属资源类型
+	BizTreeNodeID int64            `json:"bizTreeNodeId"` // 题目所属业务树叶子节点ID
+	AskCount      int64            `json:"askCount"`      // 提问次数
+	Askers        []*QuestionAsker `json:"askers"`        // 提问的学生，按提问次数倒序
+}
+
+// 提问的学生
+type QuestionAsker struct {
+	*Student
+	AskCount int64                 `json:"askCount"` // 提问次数
+	Sessions []*QuestionAskSession `json:"sessions"` // 提问会话，按开始时间排序
+}
+
+// 提问会话，通过 /session/messages?sessionId= 查看完整的消息记录
+type QuestionAskSession struct {
+	SessionID    string `json:"sessionId"`    // 会话ID
+	FirstMessage string `json:"firstMessage"` // 学生发送的第一条消息
+	MessageCount int64  `json:"messageCount"` // 学生发送的消息数
+	Closed       bool   `json:"closed"`       // 会话是否已关闭
+	StartTime    int64  `json:"startTime"`    // 会话开始时间
+}
+
+/*************************************************************
+		                班级共性错题本
+*************************************************************/
+// 班级共性错题本查询，不传 BizTreeNodeID 时查询全部知识点
+type ClassErrorBookQuery struct {
+	ClassID       int64  `form:"classId"`       // 班级ID
+	Subject       int64  `form:"subject"`       // 学科
+	BizTreeNodeID *int64 `form:"bizTreeNodeId"` // 业务树叶子节点ID，不关联知识点的题目为 0
+	Explained     int64  `form:"explained"`     // 讲解状态，0 全部，1 未讲解，2 已讲解
+	Page          int64  `form:"page"`
+	PageSize      int64  `form:"pageSize"`
+}
+
+func (r *ClassErrorBookQuery) Validate() error {
+	if r.ClassID <= 0 || r.Subject <= 0 {
+		return errors.New("classId and subject is required")
+	}
+	if r.BizTreeNodeID != nil && *r.BizTreeNodeID < 0 {
+		return errors.New("bizTreeNodeId is invalid")
+	}
+	if r.Explained < consts.ClassErrorBookFilterAll || r.Explained > consts.ClassErrorBookFilterExplained {
+		return errors.New("explained is invalid")
+	}
+	var err error
+	r.Page, r.PageSize, err = consts.PageHandler(r.Page, r.PageSize)
+	return err
+}
+
+// 班级共性错题本
+type ClassErrorBookResponse struct {
+	Knowledges []*ErrorBookKnowledge   `json:"knowledges"` // 按知识点统计，按错题数倒序，不受知识点筛选影响
+	Items      []*ErrorBookItem        `json:"items"`      // 错题，按最近出现时间倒序
+	PageInfo   *consts.ApiPageResponse `json:"pageInfo"`
+}
+
+// 知识点下的共性错题统计
+type ErrorBookKnowledge struct {
+	BizTreeNodeID    int64  `json:"bizTreeNodeId"`    // 业务树叶子节点ID，不关联知识点的题目为 0
+	BizTreeNodeName  string `json:"bizTreeNodeName"`  // 业务树叶子节点名称
+	ItemCount        int64  `json:"itemCount"`        // 错题数
+	UnexplainedCount int64  `json:"unexplainedCount"` // 未讲解的错题数
+}
+
+// 共性错题
+type ErrorBookItem struct {
+	*dao_task.ClassErrorBookItem
+	BizTreeNodeID          int64 `json:"bizTreeNodeId"`          // 题目所属业务树叶子节点ID
+	Explained              bool  `json:"explained"`              // 是否已讲解
+	RecurredAfterExplained bool  `json:"recurredAfterExplained"` // 讲解后是否再次出现
+}
+
+// 标记共性错题的讲解状态
+type ErrorBookExplainRequest struct {
+	ItemIDs   []int64 `json:"itemIds"`   // 共性错题ID
+	Explained bool    `json:"explained"` // true 标记已讲解，false 取消标记
+}
+
+func (r *ErrorBookExplainRequest) Validate() error {
+	if len(r.ItemIDs) == 0 || len(r.ItemIDs) > consts.API_MAX_PAGE_SIZE {
+		return errors.New("itemIds is invalid")
+	}
+	for _, itemID := range r.ItemIDs {
+		if itemID <= 0 {
+			return errors.New("itemIds is invalid")
+		}
+	}
+	return nil
+}
+
+// 标记讲解状态结果
+type ErrorBookExplainResponse struct {
+	UpdatedCount int64 `json:"updatedCount"` // 更新的错题数
+}
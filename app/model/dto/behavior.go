@@ -82,6 +82,49 @@ type CommunicationMessageDTO struct {
 	// ClassroomID    uint64    `json:"classroomId,omitempty"`
 }
 
+// CommunicationReadCursorDTO 用户在会话中的已读位置
+type CommunicationReadCursorDTO struct {
+	SessionID         string    `json:"sessionId"`
+	UserID            uint64    `json:"userId"`
+	UserType          string    `json:"userType"`
+	LastReadMessageID string    `json:"lastReadMessageId"`
+	LastReadTime      time.Time `json:"lastReadTime"` // 最后已读消息的发送时间
+}
+
+// CommunicationParticipantDTO 会话参与者
+type CommunicationParticipantDTO struct {
+	UserID   uint64 `json:"userId"`
+	UserType string `json:"userType"`
+}
+
+// CommunicationInboxSessionDTO 收件箱中的会话
+type CommunicationInboxSessionDTO struct {
+	Session      *CommunicationSessionDTO      `json:"session"`
+	LastMessage  *CommunicationMessageDTO      `json:"lastMessage"`
+	MessageCount int64                         `json:"messageCount"` // 会话消息总数
+	UnreadCount  int64                         `json:"unreadCount"`  // 未读消息数
+	Participants []CommunicationParticipantDTO `json:"participants"` // 会话发起人和发过消息的用户
+	ActiveTime   time.Time                     `json:"activeTime"`   // 最后一条消息的发送时间
+}
+
+// CommunicationInboxDTO 用户收件箱
+type CommunicationInboxDTO struct {
+	Sessions    []*CommunicationInboxSessionDTO `json:"sessions"`    // 按最后活跃时间倒序
+	Total       int64                           `json:"total"`       // 会话总数
+	UnreadCount int64                           `json:"unreadCount"` // 全部会话的未读消息总数
+}
+
+// AITutorAnswerDTO AI 答疑的一次回答，供教师审核
+type AITutorAnswerDTO struct {
+	SessionID  string                   `json:"sessionId"`          // 会话ID
+	QuestionID string                   `json:"questionId"`         // 题目ID
+	StudentID  uint64                   `json:"studentId"`          // 提问学生ID
+	Question   *CommunicationMessageDTO `json:"question"`           // 学生提问
+	Answer     *CommunicationMessageDTO `json:"answer"`             // AI 回答或转交提示
+	Escalated  bool                     `json:"escalated"`          // 是否已转交教师
+	Override   *CommunicationMessageDTO `json:"override,omitempty"` // 教师最近一次修正
+}
+
 // StudentLatestBehaviorDTO 学生最新行为DTO
 type StudentLatestBehaviorDTO struct {
 	StudentID      int64               `json:"studentId"`      // 学生ID
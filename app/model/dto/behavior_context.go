@@ -44,3 +44,59 @@ type BehaviorContext struct {
 	StayDuration    float64 `json:"stayDuration"`    // 停留时长(秒)
 	VideoStatus     string  `json:"videoStatus"`     // 视频状态
 }
+
+// StudentBehaviorContext 学生行为上下文，各版本的上下文解码后统一为该结构
+// json 字段名即入库的字段名，schemaVersion 为 2 的上下文按该结构严格解码
+type StudentBehaviorContext struct {
+	SchemaVersion     int64                 `json:"schemaVersion,omitempty"`       // 上下文结构版本
+	StudentName       string                `json:"student_name,omitempty"`        // 学生姓名
+	AvatarURL         string                `json:"avatar_url,omitempty"`          // 头像URL
+	PageName          string                `json:"page_name,omitempty"`           // 页面名称
+	Subject           string                `json:"subject,omitempty"`             // 学科
+	MaterialID        uint64                `json:"material_id,omitempty"`         // 学习材料ID
+	LearningType      string                `json:"learning_type,omitempty"`       // 学习类型
+	VideoStatus       string                `json:"video_status,omitempty"`        // 视频状态
+	LearningProgress  float64               `json:"learning_progress,omitempty"`   // 学习进度(%)
+	StayDuration      int64                 `json:"stay_duration,omitempty"`       // 停留时长(秒)
+	EarlyLearnCount   int64                 `json:"early_learn_count,omitempty"`   // 提前学习次数
+	QuestionCount     int64                 `json:"question_count,omitempty"`      // 提问次数
+	CorrectStreak     int64                 `json:"correct_streak,omitempty"`      // 连续答对次数
+	PageSwitchCount   int64                 `json:"page_switch_count,omitempty"`   // 频繁切换页面次数
+	OtherContentCount int64                 `json:"other_content_count,omitempty"` // 学习其他内容次数
+	PauseCount        int64                 `json:"pause_count,omitempty"`         // 停顿操作次数
+	WrongAnswers      int64                 `json:"wrong_answers,omitempty"`       // 答错题数
+	QuestionsInfo     *QuestionsInfoContext `json:"questions_info,omitempty"`      // 答题汇总
+	QuestionContent   string                `json:"question_content,omitempty"`    // 提问内容
+	IsCorrect         *int64                `json:"isCorrect,omitempty"`           // 单题是否答对，1答对 0答错
+	ChapterID         string                `json:"chapterId,omitempty"`           // 章节ID
+	QuestionID        string                `json:"questionId,omitempty"`          // 题目ID
+	QuestionType      string                `json:"questionType,omitempty"`        // 题目类型
+}
+
+// QuestionsInfoContext 学生行为上下文中的答题汇总
+type QuestionsInfoContext struct {
+	TotalQuestions int64 `json:"total_questions"` // 总题数
+	CorrectAnswers int64 `json:"correct_answers"` // 正确答题数
+	WrongAnswers   int64 `json:"wrong_answers"`   // 答错题数
+}
+
+// ViolationCount 频繁切换页面、学习其他内容和停顿操作的总次数
+func (c *StudentBehaviorContext) ViolationCount() int64 {
+	return c.PageSwitchCount + c.OtherContentCount + c.PauseCount
+}
+
+// TeacherBehaviorContext 教师行为上下文，各版本的上下文解码后统一为该结构
+// 作业点赞和提醒的上下文是纯文本，解码后放在 Message 中
+type TeacherBehaviorContext struct {
+	SchemaVersion int64  `json:"schemaVersion,omitempty"` // 上下文结构版本
+	StudentID     uint64 `json:"studentId,omitempty"`     // 表扬、关注、评价的学生ID
+	Message       string `json:"message,omitempty"`       // 推送给学生的文案或评价内容
+	BehaviorType  string `json:"behaviorType,omitempty"`  // 表扬类型，旧版字段，与 praiseType 相同
+	PraiseType    string `json:"praiseType,omitempty"`    // 表扬类型
+	AttentionType string `json:"attentionType,omitempty"` // 关注类型
+	ReminderCount int64  `json:"reminderCount,omitempty"` // 已提醒次数
+	PushTime      int64  `json:"pushTime,omitempty"`      // 推送时间(UTC秒数)
+	TaskID        int64  `json:"taskId,omitempty"`        // 任务ID
+	AssignID      int64  `json:"assignId,omitempty"`      // 任务布置ID
+	Content       any    `json:"content,omitempty"`       // 布置任务时的原始内容
+}
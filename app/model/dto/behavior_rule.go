@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"gil_teacher/app/consts"
+)
+
+// BehaviorRuleDocument 行为规则集配置文档
+type BehaviorRuleDocument struct {
+	RuleSets []*BehaviorRuleSet `json:"ruleSets"`
+}
+
+// BehaviorRuleSet 行为规则集，按学校和学科配置，未配置时使用上一级规则集
+// 查找顺序：学校+学科、学校、学科、全局、内置默认规则集
+type BehaviorRuleSet struct {
+	SchoolID          int64              `json:"schoolId"`          // 学校ID，0 表示全部学校
+	Subject           string             `json:"subject"`           // 学科，空表示全部学科
+	Version           int64              `json:"version"`           // 版本号，同一学校学科只能递增
+	PraiseChecks      []BehaviorRule     `json:"praiseChecks"`      // 值得表扬条件，命中任一规则即可表扬
+	AttentionChecks   []BehaviorRule     `json:"attentionChecks"`   // 需要关注条件，命中任一规则即需要关注
+	PraiseTypes       []BehaviorTypeRule `json:"praiseTypes"`       // 表扬类型评分规则
+	AttentionTypes    []BehaviorTypeRule `json:"attentionTypes"`    // 关注类型评分规则
+	PraiseMinScore    int64              `json:"praiseMinScore"`    // 表扬类型最高分不超过该值时视为没有明显最佳类型
+	AttentionMinScore int64              `json:"attentionMinScore"` // 关注类型最高分不超过该值时视为没有明显最佳类型
+	Tags              []BehaviorTagRule  `json:"tags"`              // 行为标签规则
+
+	LearningScore *LearningScoreModel `json:"learningScore,omitempty"` // 学习分模型，未配置时使用内置默认模型
+}
+
+// BehaviorRule 行为规则，全部条件满足时命中
+// 命中得分 = weight + unitWeight * floor(field / unit)
+type BehaviorRule struct {
+	Name       string                  `json:"name"`       // 规则名称
+	Conditions []BehaviorRuleCondition `json:"conditions"` // 条件列表
+	Weight     int64                   `json:"weight"`     // 命中时的固定得分
+	Field      string                  `json:"field"`      // 按字段值计分的字段
+	Unit       float64                 `json:"unit"`       // 计分单位，默认为 1
+	UnitWeight int64                   `json:"unitWeight"` // 每个计分单位的得分
+	Template   string                  `json:"template"`   // 命中说明，{字段名} 替换为字段值
+}
+
+// BehaviorRuleCondition 规则条件
+type BehaviorRuleCondition struct {
+	Field string                `json:"field"` // 字段名
+	Op    consts.BehaviorRuleOp `json:"op"`    // 运算符
+	Value any                   `json:"value"` // 比较值，数值或字符串，in 运算为数组
+}
+
+// BehaviorTypeRule 表扬或关注类型的评分规则
+type BehaviorTypeRule struct {
+	Type      string         `json:"type"`      // 标签类型
+	BaseScore int64          `json:"baseScore"` // 基础得分
+	Rules     []BehaviorRule `json:"rules"`     // 计分规则，命中的规则得分累加
+}
+
+// BehaviorTagRule 行为标签规则，计数字段不小于 minCount 时生成标签
+type BehaviorTagRule struct {
+	Type     string `json:"type"`     // 标签类型
+	Field    string `json:"field"`    // 计数字段
+	MinCount int64  `json:"minCount"` // 最小计数，默认为 1
+	Template string `json:"template"` // 标签文本，{字段名} 替换为字段值
+}
+
+// BehaviorRuleHit 命中的规则
+type BehaviorRuleHit struct {
+	Name    string `json:"name"`    // 规则名称
+	Score   int64  `json:"score"`   // 规则得分
+	Message string `json:"message"` // 命中说明
+}
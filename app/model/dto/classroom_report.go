@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"time"
+
+	"gil_teacher/app/consts"
+)
+
+// ClassroomReportDTO 课后课堂报告 DTO
+type ClassroomReportDTO struct {
+	ReportID    string    `json:"reportId"`    // 报告ID，由课堂ID和上课时间生成
+	SchoolID    uint64    `json:"schoolId"`    // 学校ID
+	ClassID     uint64    `json:"classId"`     // 班级ID
+	ClassroomID uint64    `json:"classroomId"` // 课堂ID
+	TeacherID   uint64    `json:"teacherId"`   // 教师ID
+	CourseID    uint64    `json:"courseId"`    // 课程ID
+	StartTime   time.Time `json:"startTime"`   // 上课时间
+	EndTime     time.Time `json:"endTime"`     // 下课时间
+	Content     string    `json:"content"`     // 报告内容JSON
+	CreateTime  time.Time `json:"createTime"`  // 生成时间
+	UpdateTime  time.Time `json:"updateTime"`  // 最近一次生成时间，和本次生成的学情统计时间一致
+}
+
+// ClassroomLearningStatsDTO 课后课堂报告中单个学生的学情统计 DTO
+type ClassroomLearningStatsDTO struct {
+	StudentID     uint64 `json:"studentId"`     // 学生ID
+	LearningScore int64  `json:"learningScore"` // 学习分
+	Summary       string `json:"summary"`       // 学情统计内容JSON
+}
+
+// ClassroomTeacherActionDTO 课堂内教师对学生的表扬/关注次数
+type ClassroomTeacherActionDTO struct {
+	StudentID    uint64              `json:"studentId"`    // 学生ID
+	BehaviorType consts.BehaviorType `json:"behaviorType"` // 行为类型
+	Count        int64               `json:"count"`        // 次数
+}
+
+// ClassroomCommunicationStatsDTO 课堂内沟通统计
+type ClassroomCommunicationStatsDTO struct {
+	SessionCount        int64 `json:"sessionCount"`        // 会话数
+	StudentMessageCount int64 `json:"studentMessageCount"` // 学生消息数
+	TeacherMessageCount int64 `json:"teacherMessageCount"` // 教师消息数
+	AIMessageCount      int64 `json:"aiMessageCount"`      // AI 消息数
+}
+
+// ClassroomLifecycleEventDTO 课堂生命周期事件，课堂开始、暂停、继续、结束时写入 kafka
+type ClassroomLifecycleEventDTO struct {
+	EventID            string                         `json:"eventId"`            // 事件ID
+	Event              consts.ClassroomLifecycleEvent `json:"event"`              // 事件类型
+	ClassroomID        int64                          `json:"classroomId"`        // 课堂ID
+	SchoolID           int64                          `json:"schoolId"`           // 学校ID
+	ClassID            int64                          `json:"classId"`            // 班级ID
+	CourseID           int64                          `json:"courseId"`           // 课程ID
+	TeacherID          int64                          `json:"teacherId"`          // 教师ID
+	Status             int64                          `json:"status"`             // 事件发生后的课堂状态
+	ScheduledStartTime int64                          `json:"scheduledStartTime"` // 课表计划上课时间
+	ScheduledEndTime   int64                          `json:"scheduledEndTime"`   // 课表计划下课时间
+	ActualStartTime    int64                          `json:"actualStartTime"`    // 实际开始上课时间
+	ActualEndTime      int64                          `json:"actualEndTime"`      // 实际结束上课时间
+	PausedDuration     int64                          `json:"pausedDuration"`     // 累计暂停时长(秒)
+	EventTime          int64                          `json:"eventTime"`          // 事件发生时间
+}
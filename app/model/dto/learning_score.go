@@ -0,0 +1,39 @@
+package dto
+
+// LearningScoreModel 学习分模型，配置在行为规则集中，课堂学习分和任务报告学习分使用同一模型
+// 学习分 = 各计分项得分之和，限制在 0 到 maxScore 之间并四舍五入取整
+type LearningScoreModel struct {
+	MaxScore   float64                  `json:"maxScore"`   // 学习分上限，0 表示不限制
+	Components []LearningScoreComponent `json:"components"` // 计分项
+}
+
+// LearningScoreComponent 学习分计分项
+// 得分 = min(field / unit, cap) * weight
+type LearningScoreComponent struct {
+	Name   string  `json:"name"`   // 计分项名称
+	Field  string  `json:"field"`  // 计分字段，可使用行为规则的数值字段
+	Unit   float64 `json:"unit"`   // 计分单位，默认为 1
+	Cap    float64 `json:"cap"`    // 最多计分的单位数，0 表示不限制
+	Weight float64 `json:"weight"` // 每个单位的得分，负数表示扣分，如专注度扣分
+}
+
+// LearningScoreInput 计算学习分的学生数据，课堂外的场景没有的数据填 0
+type LearningScoreInput struct {
+	StayDuration      int64 // 学习时长(秒)
+	TotalQuestions    int64 // 答题数
+	CorrectAnswers    int64 // 正确答题数
+	CorrectStreak     int64 // 连续答对次数
+	EarlyLearnCount   int64 // 提前学习次数
+	QuestionCount     int64 // 提问次数
+	PageSwitchCount   int64 // 切换页面次数
+	OtherContentCount int64 // 学习其他内容次数
+	PauseCount        int64 // 停顿操作次数
+}
+
+// LearningScoreItem 学习分明细，说明每个计分项的取值和得分
+type LearningScoreItem struct {
+	Name  string  `json:"name"`  // 计分项名称
+	Field string  `json:"field"` // 计分字段
+	Value float64 `json:"value"` // 字段值
+	Score float64 `json:"score"` // 得分，保留两位小数
+}
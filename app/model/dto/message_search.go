@@ -0,0 +1,29 @@
+package dto
+
+import "time"
+
+// MessageSearchDocumentDTO 会话消息检索文档，字段和索引映射一致
+type MessageSearchDocumentDTO struct {
+	MessageID   string    `json:"messageId"`   // 消息ID
+	SessionID   string    `json:"sessionId"`   // 会话ID
+	SessionType string    `json:"sessionType"` // 会话类型
+	SchoolID    uint64    `json:"schoolId"`    // 学校ID
+	ClassID     uint64    `json:"classId"`     // 班级ID，无法确定班级时为0
+	ClassroomID uint64    `json:"classroomId"` // 课堂ID，不是课堂中的会话时为0
+	CourseID    uint64    `json:"courseId"`    // 课程ID
+	TaskID      int64     `json:"taskId"`      // 作业题目提问的任务ID
+	AssignID    int64     `json:"assignId"`    // 作业题目提问的布置ID
+	QuestionID  string    `json:"questionId"`  // 作业题目提问的题目ID
+	UserID      uint64    `json:"userId"`      // 发送人ID
+	UserType    string    `json:"userType"`    // 发送人类型
+	MessageType string    `json:"messageType"` // 消息类型
+	AnswerTo    string    `json:"answerTo"`    // 回答的消息ID
+	Content     string    `json:"content"`     // 消息内容
+	CreatedAt   time.Time `json:"createdAt"`   // 发送时间
+}
+
+// MessageSearchHitDTO 会话消息检索结果
+type MessageSearchHitDTO struct {
+	*MessageSearchDocumentDTO
+	Highlights []string `json:"highlights"` // 命中关键词的高亮片段，消息内容在审核后发生变化时为空
+}
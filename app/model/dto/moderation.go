@@ -0,0 +1,50 @@
+package dto
+
+import "time"
+
+// ModerationRequestDTO 待审核内容
+type ModerationRequestDTO struct {
+	ContentType string    `json:"contentType"` // 内容类型
+	ContentID   string    `json:"contentId"`   // 内容ID，消息ID或反馈ID
+	ParentID    string    `json:"parentId"`    // 内容所属对象ID，消息为会话ID，反馈为班级ID
+	SchoolID    uint64    `json:"schoolId"`    // 学校ID
+	ClassID     uint64    `json:"classId"`     // 内容所属班级ID，无法确定时为0
+	UserID      uint64    `json:"userId"`      // 发布人ID
+	UserType    string    `json:"userType"`    // 发布人类型
+	Content     string    `json:"content"`     // 原始内容
+	CreatedAt   time.Time `json:"createdAt"`   // 发布时间
+}
+
+// ModerationVerdictDTO 审核结论
+type ModerationVerdictDTO struct {
+	Action        string   `json:"action"`        // 处理方式
+	Category      string   `json:"category"`      // 违规类别
+	Severity      string   `json:"severity"`      // 违规程度
+	Reason        string   `json:"reason"`        // 审核原因
+	Keywords      []string `json:"keywords"`      // 需要屏蔽的片段
+	MaskedContent string   `json:"maskedContent"` // 屏蔽后的内容，处理方式为 mask 时有值
+	Source        string   `json:"source"`        // 结论来源
+	PromptVersion string   `json:"promptVersion"` // 模型审核使用的 Prompt 版本
+}
+
+// ModerationAuditDTO 审核记录，同一内容的每次审核结论和教师审核都记录一条
+type ModerationAuditDTO struct {
+	AuditID       string    `json:"auditId"`       // 审核记录ID
+	ContentType   string    `json:"contentType"`   // 内容类型
+	ContentID     string    `json:"contentId"`     // 内容ID
+	ParentID      string    `json:"parentId"`      // 内容所属对象ID
+	SchoolID      uint64    `json:"schoolId"`      // 学校ID
+	ClassID       uint64    `json:"classId"`       // 内容所属班级ID
+	UserID        uint64    `json:"userId"`        // 发布人ID
+	UserType      string    `json:"userType"`      // 发布人类型
+	Content       string    `json:"content"`       // 原始内容
+	MaskedContent string    `json:"maskedContent"` // 屏蔽后的内容
+	Action        string    `json:"action"`        // 处理方式
+	Category      string    `json:"category"`      // 违规类别
+	Severity      string    `json:"severity"`      // 违规程度
+	Reason        string    `json:"reason"`        // 审核原因
+	Source        string    `json:"source"`        // 结论来源
+	PromptVersion string    `json:"promptVersion"` // 模型审核使用的 Prompt 版本
+	ReviewerID    uint64    `json:"reviewerId"`    // 审核教师ID，自动审核为0
+	CreatedAt     time.Time `json:"createdAt"`     // 审核时间
+}
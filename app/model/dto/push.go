@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"gil_teacher/app/consts"
+)
+
+// PushMessage 推送给学生端或教师端的实时消息
+// UserID 不为 0 时发送给指定用户，写入用户的离线消息，确认前重连会补发
+// UserID 为 0 时广播给订阅了 ClassroomID 的全部连接，不保留离线消息
+type PushMessage struct {
+	Seq         int64                        `json:"seq"`                   // 用户消息序号，用于确认和补发，广播消息为 0
+	UserType    consts.CommunicationUserType `json:"userType,omitempty"`    // 接收用户类型
+	UserID      int64                        `json:"userId,omitempty"`      // 接收用户ID
+	ClassroomID int64                        `json:"classroomId,omitempty"` // 课堂ID，作业相关消息为 0
+	Type        consts.PushMessageType       `json:"type"`                  // 消息类型
+	Content     string                       `json:"content"`               // 消息文案
+	Data        json.RawMessage              `json:"data,omitempty"`        // 业务数据
+	CreateTime  int64                        `json:"createTime"`            // 发送时间，毫秒
+}
@@ -9,4 +9,6 @@ type StudentLearningScoreDTO struct {
 	LearningTime  uint64 `json:"learningTime"`  // 学习时长(秒)
 	CorrectCount  uint64 `json:"correctCount"`  // 正确答题数
 	TotalCount    uint64 `json:"totalCount"`    // 总答题数
+
+	ScoreItems []LearningScoreItem `json:"scoreItems"` // 学习分明细
 }
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"gil_teacher/app/consts"
+)
+
+// StudentProfileQueryDTO 学生跨课堂行为画像查询条件
+type StudentProfileQueryDTO struct {
+	SchoolID  uint64    // 学校ID
+	ClassID   uint64    // 班级ID
+	StudentID uint64    // 学生ID
+	StartTime time.Time // 开始时间，包含
+	EndTime   time.Time // 结束时间，不包含
+}
+
+// StudentLessonStatsDTO 学生在一节课的课后学情统计
+type StudentLessonStatsDTO struct {
+	ReportID      string    `json:"reportId"`      // 课堂报告ID
+	ClassroomID   uint64    `json:"classroomId"`   // 课堂ID
+	CourseID      uint64    `json:"courseId"`      // 课程ID
+	StartTime     time.Time `json:"startTime"`     // 上课时间
+	LearningScore int64     `json:"learningScore"` // 学习分
+	Summary       string    `json:"summary"`       // 学情统计内容JSON
+}
+
+// StudentTeacherActionCountDTO 教师对学生按天、按类型的表扬/关注次数
+type StudentTeacherActionCountDTO struct {
+	Date         string              `json:"date"`         // 日期，北京时间 YYYY-MM-DD
+	BehaviorType consts.BehaviorType `json:"behaviorType"` // 教师行为类型
+	ActionType   string              `json:"actionType"`   // 表扬/关注类型，作业点赞和提醒为空
+	Count        int64               `json:"count"`        // 次数
+}
+
+// StudentBehaviorDayCountDTO 学生按天、按行为类型的行为统计
+type StudentBehaviorDayCountDTO struct {
+	Date              string              `json:"date"`              // 日期，北京时间 YYYY-MM-DD
+	BehaviorType      consts.BehaviorType `json:"behaviorType"`      // 行为类型，统一为小写
+	Count             int64               `json:"count"`             // 行为次数
+	CorrectCount      int64               `json:"correctCount"`      // 答对次数，仅答题行为
+	PageSwitchCount   int64               `json:"pageSwitchCount"`   // 频繁切换页面次数
+	OtherContentCount int64               `json:"otherContentCount"` // 学习其他内容次数
+	PauseCount        int64               `json:"pauseCount"`        // 停顿操作次数
+}
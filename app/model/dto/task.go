@@ -105,6 +105,15 @@ type ExportTaskReportQuery struct {
 	Fields       []string        `json:"fields"`       // 导出字段
 }
 
+// 查询学生历史错题，用于生成错题作业
+type WrongQuestionQuery struct {
+	Subject        int64   `json:"subject"`        // 学科
+	StudentIDs     []int64 `json:"studentIds"`     // 学生ID列表
+	AnswerTimeFrom int64   `json:"answerTimeFrom"` // 作答时间 >=，按作答事件时间 answer_time 比较
+	AnswerTimeTo   int64   `json:"answerTimeTo"`   // 作答时间 <，按作答事件时间 answer_time 比较
+	SourceTaskIDs  []int64 `json:"sourceTaskIds"`  // 来源任务ID列表，为空时不限制
+}
+
 // 作业报告结果格式
 type ExportTaskReportResult struct {
 	Meta []string   `json:"meta"` // csv 首行
@@ -117,3 +126,35 @@ type TaskAnswerStat struct {
 	ResourceIncorrectCount map[string]int64 `json:"resourceIncorrectCount"` // 资源错题数 resource_key -> incorrect_count
 	ResourceTotalCostTime  map[string]int64 `json:"resourceTotalCostTime"`  // 资源总用时 resource_key -> total_cost_time
 }
+
+// 学生作答事件，由学生端提交作答后写入 kafka，聚合生成任务报告
+type TaskAnswerEventDTO struct {
+	TaskID        int64  `json:"taskId"`        // 任务ID
+	AssignID      int64  `json:"assignId"`      // 任务布置ID
+	StudentID     int64  `json:"studentId"`     // 学生ID
+	ResourceID    string `json:"resourceId"`    // 资源ID
+	ResourceType  int64  `json:"resourceType"`  // 资源类型
+	QuestionID    string `json:"questionId"`    // 题目ID
+	AnswerContent string `json:"answerContent"` // 作答内容
+	Correctness   bool   `json:"correctness"`   // 是否正确
+	CostTime      int64  `json:"costTime"`      // 答题用时，秒
+	QuestionCount int64  `json:"questionCount"` // 资源题目总数，资源未记录子题目时用于计算完成进度
+	AnswerTime    int64  `json:"answerTime"`    // 作答时间，秒级时间戳，同一题目以最后一次作答为准
+}
+
+// 答题卡任务的答案，序列化后保存在 tbl_task.task_extra_info 中
+type AnswerCard struct {
+	Questions []*AnswerCardQuestion `json:"questions"` // 题目列表，题号按数组顺序从 1 开始
+}
+
+// 答题卡单个题目的答案
+type AnswerCardQuestion struct {
+	QuestionType   int64    `json:"questionType"`   // 题目类型，1 单选，2 多选，3 判断
+	CorrectOptions []string `json:"correctOptions"` // 正确选项，选择题为 A-Z，判断题为 T 或 F
+}
+
+// 学生的答题卡作答，导入时使用
+type AnswerCardStudentAnswer struct {
+	StudentID int64    `json:"studentId"` // 学生ID
+	Answers   []string `json:"answers"`   // 按题号顺序的作答，未作答为空字符串
+}
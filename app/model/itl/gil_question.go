@@ -76,9 +76,9 @@ type BizTreeNodeEntity struct {
 	BizTreeNodeName string `json:"bizTreeNodeName"` // 业务树节点名称
 	// BizTreeParentNodeId     int64                `json:"bizTreeParentNodeId"`     // 业务树父节点id // 教师端暂不使用
 	// BizTreeNodeSiblingOrder int64                `json:"bizTreeNodeSiblingOrder"` // 业务树节点兄弟节点顺序 // 教师端暂不使用
-	BizTreeNodeLevel int64  `json:"bizTreeNodeLevel"` // 业务树节点层级
-	BizTreeDetail    string `json:"bizTreeDetail"`    // 业务树详情
-	// BaseTreeNodeIDs        []int64              `json:"baseTreeNodeIds"`        // 关联基础树节点id列表 关联知识点 // 教师端暂不使用
+	BizTreeNodeLevel int64   `json:"bizTreeNodeLevel"` // 业务树节点层级
+	BizTreeDetail    string  `json:"bizTreeDetail"`    // 业务树详情
+	BaseTreeNodeIDs  []int64 `json:"baseTreeNodeIds"`  // 关联基础树节点id列表 关联知识点
 	// ShelfStatus            int64                `json:"shelfStatus"`            // 叶子上架状态 // 教师端暂不使用
 	// NoLeafNodeShelfOnCount int64                `json:"noLeafNodeShelfOnCount"` // 非叶子节点上架数量 // 教师端暂不使用
 	// NoLeafNodeTotalCount   int64                `json:"noLeafNodeTotalCount"`   // 非叶子节点全部数量 // 教师端暂不使用
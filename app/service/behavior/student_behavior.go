@@ -8,6 +8,7 @@ import (
 	"gil_teacher/app/model/dto"
 	"gil_teacher/app/utils"
 	"sort"
+	"time"
 )
 
 type StudentBehaviorService struct {
@@ -77,7 +78,7 @@ func (s *StudentBehaviorService) ExtractBehaviorFields(ctx context.Context, beha
 // GetClassroomLearningScores 获取课堂学习分数
 func (s *StudentBehaviorService) GetClassroomLearningScores(ctx context.Context, classroomID uint64) ([]*dto.StudentLearningScoreDTO, error) {
 	// 获取原始行为数据
-	behaviors, err := s.behaviorDao.GetClassLatestBehaviors(ctx, classroomID)
+	behaviors, err := s.behaviorDao.GetClassLatestBehaviors(ctx, classroomID, time.Time{}, time.Time{})
 	if err != nil {
 		s.logger.Error(ctx, "获取课堂学习分列表失败: %v", err)
 		return nil, err
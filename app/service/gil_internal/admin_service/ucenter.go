@@ -1,14 +1,14 @@
 package admin_service
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"strconv"
-	"strings"
-	"time"
 
 	"gil_teacher/app/conf"
 	"gil_teacher/app/consts"
@@ -17,96 +17,67 @@ import (
 	"gil_teacher/app/core/logger"
 	"gil_teacher/app/dao"
 	"gil_teacher/app/model/itl"
+	"gil_teacher/app/service/gil_internal/upstream"
 	"gil_teacher/app/utils"
 )
 
 // UcenterClient Ucenter API客户端
 type UcenterClient struct {
-	host   string            // API服务地址
-	client *http.Client      // HTTP客户端
-	cache  *dao.ApiRdbClient // 缓存客户端
-	log    *logger.ContextLogger
+	host     string            // API服务地址
+	upstream *upstream.Client  // 上游调用客户端
+	cache    *dao.ApiRdbClient // 缓存客户端
+	log      *logger.ContextLogger
 }
 
 // NewUcenterClient 创建Ucenter API客户端
 func NewUcenterClient(config *conf.Conf, cache *dao.ApiRdbClient, l *logger.ContextLogger) (*UcenterClient, error) {
 	return &UcenterClient{
-		host: config.Config.GilAdminAPI.UcenterHost,
-		client: &http.Client{
-			Timeout: consts.TeacherDefaultAPITimeout,
-		},
-		cache: cache,
-		log:   l,
+		host:     config.Config.GilAdminAPI.UcenterHost,
+		upstream: upstream.NewClient(consts.UpstreamUcenter, cache, l),
+		cache:    cache,
+		log:      l,
 	}, nil
 }
 
-// GetTeacherDetail 获取教师详细信息
-func (c *UcenterClient) GetTeacherDetail(ctx context.Context, token string, schoolID string) (*itl.TeacherDetailData, *response.Response) {
-	url := fmt.Sprintf("%s%s", c.host, consts.GetTeacherDetailAPI.Path)
-	c.log.Debug(ctx, "请求运营平台 API: %s, token: %s", url, token)
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, consts.GetTeacherDetailAPI.Method, url, nil)
+// doRequest 按接口策略请求运营平台，返回响应体
+func (c *UcenterClient) doRequest(ctx context.Context, api consts.GilAdminAPI, url string, header http.Header) ([]byte, error) {
+	c.log.Debug(ctx, "请求运营平台 API: %s", url)
+	body, err := c.upstream.Do(ctx, api.Policy, &upstream.Request{Method: api.Method, URL: url, Header: header})
 	if err != nil {
-		c.log.Error(ctx, "创建请求失败: %v", err)
-		return nil, &response.ERR_GIL_ADMIN
+		return nil, err
 	}
+	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 响应内容: %s", url, string(body))
+	return body, nil
+}
 
-	// 设置请求头
-	httputil.SetAuthorization(req, token)
-	req.Header.Set(consts.UcenterCustomHeaderUserTypeID, consts.UcenterCustomHeaderUserTypeIDValue)
-	req.Header.Set(consts.UcenterCustomHeaderOrganizationID, schoolID)
-
-	// 实现重试逻辑
-	var resp *http.Response
-	var lastErr error
-
-	for i := 0; i <= consts.DefaultMaxRetries; i++ {
-		if i > 0 {
-			time.Sleep(consts.TeacherDefaultRetryInterval)
-			c.log.Warn(ctx, "正在进行第%d次重试请求, URL: %s", i, url)
-		}
-
-		resp, err = c.client.Do(req)
-		if err == nil {
-			break
-		}
-		lastErr = err
+// 请求失败时区分 401 未授权错误
+func (c *UcenterClient) errorResponse(ctx context.Context, err error) *response.Response {
+	var statusErr *upstream.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized {
+		c.log.Warn(ctx, "运营平台 API 返回未授权错误: %v", err)
+		return &response.ERR_UNAUTHORIZED
 	}
+	c.log.Error(ctx, "请求运营平台 API 失败: %v", err)
+	return &response.ERR_GIL_ADMIN
+}
 
-	if err != nil {
-		c.log.Error(ctx, "发送请求失败（重试%d次后）: %v", consts.DefaultMaxRetries, lastErr)
-		return nil, &response.ERR_GIL_ADMIN
-	}
-	defer resp.Body.Close()
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		// 区分 401 未授权错误
-		if resp.StatusCode == http.StatusUnauthorized {
-			c.log.Warn(ctx, "运营平台 API 返回未授权错误: %d %s", resp.StatusCode, resp.Status)
-			return nil, &response.ERR_UNAUTHORIZED
-		}
-		c.log.Error(ctx, "运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-		return nil, &response.ERR_GIL_ADMIN
-	}
+// GetTeacherDetail 获取教师详细信息
+func (c *UcenterClient) GetTeacherDetail(ctx context.Context, token string, schoolID string) (*itl.TeacherDetailData, *response.Response) {
+	url := fmt.Sprintf("%s%s", c.host, consts.GetTeacherDetailAPI.Path)
+	header := http.Header{}
+	header.Set(httputil.HeaderAuthorization, token)
+	header.Set(consts.UcenterCustomHeaderUserTypeID, consts.UcenterCustomHeaderUserTypeIDValue)
+	header.Set(consts.UcenterCustomHeaderOrganizationID, schoolID)
 
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.doRequest(ctx, consts.GetTeacherDetailAPI, url, header)
 	if err != nil {
-		c.log.Error(ctx, "运营平台 API 读取响应失败: %v", err)
-		return nil, &response.ERR_GIL_ADMIN
+		return nil, c.errorResponse(ctx, err)
 	}
 
-	// 记录原始响应内容用于调试
-	bodyStr := string(body)
-	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 状态码: %d, 请求ID: %s, 响应内容: %s",
-		url, resp.StatusCode, req.Header.Get("X-Request-ID"), bodyStr)
-
 	// 解析响应
 	var apiResp itl.TeacherDetailResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, bodyStr)
+		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, string(body))
 		return nil, &response.ERR_GIL_ADMIN
 	}
 
@@ -122,50 +93,15 @@ func (c *UcenterClient) GetTeacherDetail(ctx context.Context, token string, scho
 // GetSchoolMaterial 获取学校的学科教材
 func (c *UcenterClient) GetSchoolMaterial(ctx context.Context, schoolID int64) ([]itl.GradeMaterial, *response.Response) {
 	url := fmt.Sprintf("%s%s?schoolId=%d", c.host, consts.GetSchoolMaterialAPI.Path, schoolID)
-	c.log.Debug(ctx, "请求运营平台 API: %s, schoolID: %d", url, schoolID)
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, consts.GetSchoolMaterialAPI.Method, url, nil)
-	if err != nil {
-		c.log.Error(ctx, "创建请求失败: %v", err)
-		return nil, &response.ERR_GIL_ADMIN
-	}
-
-	// 发送请求
-	resp, err := c.client.Do(req)
-	if err != nil {
-		c.log.Error(ctx, "发送请求失败: %v", err)
-		return nil, &response.ERR_GIL_ADMIN
-	}
-	defer resp.Body.Close()
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		// 区分 401 未授权错误
-		if resp.StatusCode == http.StatusUnauthorized {
-			c.log.Warn(ctx, "运营平台 API 返回未授权错误: %d %s", resp.StatusCode, resp.Status)
-			return nil, &response.ERR_UNAUTHORIZED
-		}
-		c.log.Error(ctx, "运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-		return nil, &response.ERR_GIL_ADMIN
-	}
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.doRequest(ctx, consts.GetSchoolMaterialAPI, url, nil)
 	if err != nil {
-		c.log.Error(ctx, "运营平台 API 读取响应失败: %v", err)
-		return nil, &response.ERR_GIL_ADMIN
+		return nil, c.errorResponse(ctx, err)
 	}
 
-	// 记录原始响应内容用于调试
-	bodyStr := string(body)
-	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 状态码: %d, 请求ID: %s, 响应内容: %s",
-		url, resp.StatusCode, req.Header.Get("X-Request-ID"), bodyStr)
-
 	// 解析响应
 	var apiResp itl.GetSchoolMaterialResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, bodyStr)
+		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, string(body))
 		return nil, &response.ERR_GIL_ADMIN
 	}
 
@@ -183,16 +119,19 @@ func (c *UcenterClient) GetSchoolMaterial(ctx context.Context, schoolID int64) (
 //	map[classID]*itl.ClassInfo
 func (c *UcenterClient) GetClassStudent(ctx context.Context, schoolID int64, classIDs []int64) (map[int64]*itl.ClassInfo, error) {
 	schoolClassMap := make(map[int64]*itl.ClassInfo)
-	// 从缓存中获取数据，按学校班级存储该班全部学生数据，如果数据不存在，则从运营平台获取
+	// 从缓存中获取数据，按学校班级存储该班全部学生数据，缓存中不存在的班级从运营平台获取
+	missingClassIDs := make([]int64, 0)
 	for _, classID := range classIDs {
-		// TODO 开发阶段，不读缓存
-		if true {
+		if _, exists := schoolClassMap[classID]; exists || slices.Contains(missingClassIDs, classID) {
 			continue
 		}
 
 		class, students, err := c.getClassCache(ctx, schoolID, classID)
 		if err != nil {
 			c.log.Error(ctx, "获取班级缓存数据失败: %v", err)
+		}
+		if err != nil || class == nil {
+			missingClassIDs = append(missingClassIDs, classID)
 			continue
 		}
 
@@ -204,64 +143,22 @@ func (c *UcenterClient) GetClassStudent(ctx context.Context, schoolID int64, cla
 	}
 
 	// 如果所有班级数据都从缓存中获取到了，直接返回
-	if len(schoolClassMap) == len(classIDs) {
+	if len(missingClassIDs) == 0 {
 		return schoolClassMap, nil
 	}
 
-	// 找出缓存中不存在的班级ID
-	missingClassIDs := make([]int64, 0)
-	for _, classID := range classIDs {
-		if _, exists := schoolClassMap[classID]; !exists {
-			missingClassIDs = append(missingClassIDs, classID)
-		}
-	}
-
-	// 将 []int64 转换为 []string
-	strIDs := make([]string, len(missingClassIDs))
-	for i, id := range missingClassIDs {
-		strIDs[i] = fmt.Sprintf("%d", id)
-	}
-	url := fmt.Sprintf("%s%s?classIDs=%s", c.host, consts.GetClassStudentAPI.Path, strings.Join(strIDs, ","))
-	c.log.Debug(ctx, "请求运营平台 API: %s", url)
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, consts.GetClassStudentAPI.Method, url, nil)
-	if err != nil {
-		c.log.Error(ctx, "创建请求失败: %v", err)
-		return nil, fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	// 发送请求
-	resp, err := c.client.Do(req)
-	if err != nil {
-		c.log.Error(ctx, "发送请求失败: %v", err)
-		return nil, fmt.Errorf("发送请求失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		c.log.Error(ctx, "运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-		return nil, fmt.Errorf("运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-	}
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	url := fmt.Sprintf("%s%s?classIDs=%s", c.host, consts.GetClassStudentAPI.Path, utils.Int64SliceToString(missingClassIDs))
+	body, err := c.doRequest(ctx, consts.GetClassStudentAPI, url, nil)
 	if err != nil {
-		c.log.Error(ctx, "运营平台 API 读取响应失败: %v", err)
-		return nil, fmt.Errorf("运营平台 API 读取响应失败: %w", err)
+		c.log.Error(ctx, "请求运营平台 API 失败: %v", err)
+		return nil, fmt.Errorf("请求运营平台 API 失败: %w", err)
 	}
 
-	// 记录原始响应内容用于调试
-	bodyStr := string(body)
-	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 状态码: %d, 请求ID: %s, 响应内容: %s",
-		url, resp.StatusCode, req.Header.Get("X-Request-ID"), bodyStr)
-
 	// 解析响应
 	var apiResp itl.GetClassStudentResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, bodyStr)
-		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, bodyStr)
+		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, string(body))
+		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, string(body))
 	}
 
 	// 检查API响应状态
@@ -270,23 +167,20 @@ func (c *UcenterClient) GetClassStudent(ctx context.Context, schoolID int64, cla
 		return nil, fmt.Errorf("运营平台 API 返回错误: %s", apiResp.Message)
 	}
 
-	schoolClassMap, err = c.setClassCache(ctx, schoolID, apiResp.Data)
-	if err != nil {
-		c.log.Error(ctx, "写入缓存失败: %v", err)
-		return nil, fmt.Errorf("写入缓存失败: %w", err)
+	for classID, classInfo := range c.setClassCache(ctx, schoolID, apiResp.Data) {
+		schoolClassMap[classID] = classInfo
 	}
 
 	return schoolClassMap, nil
 }
 
-// 从缓存中读取班级信息和班级学生信息
+// 从缓存中读取班级信息和班级学生信息，缓存不存在时返回 nil
 func (c *UcenterClient) getClassCache(ctx context.Context, schoolID int64, classID int64) (*itl.Class, []*itl.StudentInfo, error) {
 	// 班级信息
 	class := &itl.Class{}
 	classKey := consts.ClassInfoKey(schoolID, classID)
 	exists, err := c.cache.Get(ctx, classKey, class)
 	if err != nil {
-		c.log.Error(ctx, "获取班级缓存数据失败: %v", err)
 		return nil, nil, err
 	}
 	if !exists {
@@ -294,12 +188,11 @@ func (c *UcenterClient) getClassCache(ctx context.Context, schoolID int64, class
 		return nil, nil, nil
 	}
 
-	// 学生信息
-	students := make([]*itl.StudentInfo, 0)
+	// 学生信息，按学生ID存储在 hash 中
+	studentMap := make(map[int64]*itl.StudentInfo)
 	classStudentsKey := consts.ClassStudentKey(schoolID, classID)
-	exists, err = c.cache.HGetAll(ctx, classStudentsKey, &students)
+	exists, err = c.cache.HGetAll(ctx, classStudentsKey, &studentMap)
 	if err != nil {
-		c.log.Error(ctx, "获取学生缓存数据失败: %v", err)
 		return nil, nil, err
 	}
 	if !exists {
@@ -307,43 +200,49 @@ func (c *UcenterClient) getClassCache(ctx context.Context, schoolID int64, class
 		return nil, nil, nil
 	}
 
+	students := make([]*itl.StudentInfo, 0, len(studentMap))
+	for _, student := range studentMap {
+		students = append(students, student)
+	}
+	slices.SortFunc(students, func(a, b *itl.StudentInfo) int { return cmp.Compare(a.ID, b.ID) })
 	return class, students, nil
 }
 
-// 班级信息和学生信息写缓存
-func (c *UcenterClient) setClassCache(ctx context.Context, schoolID int64, classInfos []itl.ClassInfo) (map[int64]*itl.ClassInfo, error) {
+// 班级信息和学生信息写缓存，写缓存失败不影响返回结果
+//
+//	先写学生再写班级，读到班级信息时学生信息一定已写入；没有学生的班级不缓存
+func (c *UcenterClient) setClassCache(ctx context.Context, schoolID int64, classInfos []itl.ClassInfo) map[int64]*itl.ClassInfo {
 	schoolClassMap := make(map[int64]*itl.ClassInfo)
-	if len(classInfos) == 0 {
-		return schoolClassMap, nil
-	}
-
-	// 写入缓存
 	for _, classInfo := range classInfos {
 		schoolClassMap[classInfo.ClassID] = &classInfo
+		if len(classInfo.Students) == 0 {
+			continue
+		}
+
+		// 写入学生信息缓存
+		studentMap := make(map[int64]*itl.StudentInfo, len(classInfo.Students))
+		for _, student := range classInfo.Students {
+			studentMap[student.ID] = student
+		}
+		classStudentsKey := consts.ClassStudentKey(schoolID, classInfo.ClassID)
+		if err := c.cache.HSet(ctx, classStudentsKey, studentMap, consts.ClassStudentExpire); err != nil {
+			c.log.Error(ctx, "写入学生缓存失败: %v", err)
+			// 继续处理其他班级，不中断流程
+			continue
+		}
 
 		// 写入班级信息缓存
 		class := &itl.Class{
 			ID:   classInfo.ClassID,
 			Name: classInfo.ClassName,
 		}
-
 		classKey := consts.ClassInfoKey(schoolID, classInfo.ClassID)
 		if err := c.cache.Set(ctx, classKey, class, consts.ClassStudentExpire); err != nil {
 			c.log.Error(ctx, "写入班级缓存失败: %v", err)
-			// 继续处理其他班级，不中断流程
-			continue
-		}
-
-		// 写入学生信息缓存
-		classStudentsKey := consts.ClassStudentKey(schoolID, classInfo.ClassID)
-		if err := c.cache.HSet(ctx, classStudentsKey, classInfo.Students, consts.ClassStudentExpire); err != nil {
-			c.log.Error(ctx, "写入学生缓存失败: %v", err)
-			// 继续处理其他班级，不中断流程
-			continue
 		}
 	}
 
-	return schoolClassMap, nil
+	return schoolClassMap
 }
 
 // GetGradeClassInfo 获取年级班级信息
@@ -356,46 +255,17 @@ func (c *UcenterClient) GetGradeClassInfo(ctx context.Context, schoolID int64, g
 		url = fmt.Sprintf("%s&gradeIDs=%s", url, utils.Int64SliceToString(gradeID))
 	}
 
-	c.log.Debug(ctx, "请求运营平台 API: %s", url)
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, consts.GetGradeClassInfoAPI.Method, url, nil)
-	if err != nil {
-		c.log.Error(ctx, "创建请求失败: %v", err)
-		return nil, fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	// 发送请求
-	resp, err := c.client.Do(req)
+	body, err := c.doRequest(ctx, consts.GetGradeClassInfoAPI, url, nil)
 	if err != nil {
-		c.log.Error(ctx, "发送请求失败: %v", err)
-		return nil, fmt.Errorf("发送请求失败: %w", err)
+		c.log.Error(ctx, "请求运营平台 API 失败: %v", err)
+		return nil, fmt.Errorf("请求运营平台 API 失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		c.log.Error(ctx, "运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-		return nil, fmt.Errorf("运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-	}
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.log.Error(ctx, "运营平台 API 读取响应失败: %v", err)
-		return nil, fmt.Errorf("运营平台 API 读取响应失败: %w", err)
-	}
-
-	// 记录原始响应内容用于调试
-	bodyStr := string(body)
-	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 状态码: %d, 请求ID: %s, 响应内容: %s",
-		url, resp.StatusCode, req.Header.Get("X-Request-ID"), bodyStr)
 
 	// 解析响应
 	var apiResp itl.GetGradeClassInfoResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, bodyStr)
-		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, bodyStr)
+		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, string(body))
+		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, string(body))
 	}
 
 	// 检查API响应状态
@@ -410,46 +280,17 @@ func (c *UcenterClient) GetGradeClassInfo(ctx context.Context, schoolID int64, g
 // GetStudentInfoByID 通过学生ID查询学生信息，返回 map[学生ID]itl.StudentInfoData
 func (c *UcenterClient) GetStudentInfoByID(ctx context.Context, schoolID int64, studentIDs []int64) (map[int64]itl.StudentInfoData, error) {
 	url := fmt.Sprintf("%s%s?organizationId=%d&studentIDs=%s", c.host, consts.GetStudentInfoByIDAPI.Path, schoolID, utils.Int64SliceToString(studentIDs))
-	c.log.Debug(ctx, "请求运营平台 API: %s", url)
-
-	// 创建请求
-	req, err := http.NewRequestWithContext(ctx, consts.GetStudentInfoByIDAPI.Method, url, nil)
-	if err != nil {
-		c.log.Error(ctx, "创建请求失败: %v", err)
-		return nil, fmt.Errorf("创建请求失败: %w", err)
-	}
-
-	// 发送请求
-	resp, err := c.client.Do(req)
+	body, err := c.doRequest(ctx, consts.GetStudentInfoByIDAPI, url, nil)
 	if err != nil {
-		c.log.Error(ctx, "发送请求失败: %v", err)
-		return nil, fmt.Errorf("发送请求失败: %w", err)
+		c.log.Error(ctx, "请求运营平台 API 失败: %v", err)
+		return nil, fmt.Errorf("请求运营平台 API 失败: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// 检查HTTP状态码
-	if resp.StatusCode != http.StatusOK {
-		c.log.Error(ctx, "运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-		return nil, fmt.Errorf("运营平台 API 返回错误状态码: %d %s", resp.StatusCode, resp.Status)
-	}
-
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		c.log.Error(ctx, "运营平台 API 读取响应失败: %v", err)
-		return nil, fmt.Errorf("运营平台 API 读取响应失败: %w", err)
-	}
-
-	// 记录原始响应内容用于调试
-	bodyStr := string(body)
-	c.log.Debug(ctx, "收到运营平台 API 响应, URL: %s, 状态码: %d, 请求ID: %s, 响应内容: %s",
-		url, resp.StatusCode, req.Header.Get("X-Request-ID"), bodyStr)
 
 	// 解析响应
 	var apiResp itl.GetStudentInfoByIDResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, bodyStr)
-		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, bodyStr)
+		c.log.Error(ctx, "运营平台 API 解析响应失败: %v, 响应内容: %s", err, string(body))
+		return nil, fmt.Errorf("运营平台 API 解析响应失败: %w, 响应内容: %s", err, string(body))
 	}
 
 	// 检查API响应状态
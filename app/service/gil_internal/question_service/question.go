@@ -2,17 +2,17 @@
 package question_service
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"gil_teacher/app/conf"
 	"gil_teacher/app/consts"
 	"gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
 	"gil_teacher/app/model/api"
 	"gil_teacher/app/model/itl"
 	"gil_teacher/app/service/gil_internal/admin_service"
-	"io"
+	"gil_teacher/app/service/gil_internal/upstream"
 	"net/http"
 	"strconv"
 	"strings"
@@ -21,65 +21,42 @@ import (
 
 // Client 题库 HTTP 客户端
 type Client struct {
-	httpClient  *http.Client
+	upstream    *upstream.Client
 	host        string
 	log         *logger.ContextLogger
 	adminClient *admin_service.AdminClient
 }
 
 // NewClient 创建题库客户端
-func NewClient(c *conf.Conf, log *logger.ContextLogger, adminClient *admin_service.AdminClient) *Client {
+func NewClient(c *conf.Conf, log *logger.ContextLogger, adminClient *admin_service.AdminClient, cache *dao.ApiRdbClient) *Client {
 	return &Client{
-		httpClient: &http.Client{
-			Timeout: consts.QuestionAPIDefaultTimeout,
-		},
+		upstream:    upstream.NewClient(consts.UpstreamQuestion, cache, log),
 		host:        c.QuestionAPI.Host,
 		log:         log,
 		adminClient: adminClient,
 	}
 }
 
-// doRequest 执行 HTTP 请求
-func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	url := c.host + path
-
-	var bodyReader io.Reader
-	var bodyStr string
+// doRequest 按接口策略执行 HTTP 请求，path 为带查询参数的路径
+func (c *Client) doRequest(ctx context.Context, api consts.QuestionAPI, path string, body interface{}, result interface{}) error {
+	req := &upstream.Request{
+		Method: api.Method,
+		URL:    c.host + path,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+	}
 	if body != nil {
 		jsonBody, err := json.Marshal(body)
 		if err != nil {
 			return fmt.Errorf("序列化请求体失败: %v", err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
-		bodyStr = string(jsonBody)
-	}
-
-	c.log.Info(ctx, "题库 API 请求 - 方法: %s, URL: %s, 请求体: %s", method, url, bodyStr)
-
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-	if err != nil {
-		return fmt.Errorf("创建请求失败: %v", err)
+		req.Body = jsonBody
 	}
 
-	// 设置请求头
-	req.Header.Set("Content-Type", "application/json")
+	c.log.Info(ctx, "题库 API 请求 - 方法: %s, URL: %s, 请求体: %s", req.Method, req.URL, string(req.Body))
 
-	// 发送请求
-	resp, err := c.httpClient.Do(req)
+	respBody, err := c.upstream.Do(ctx, api.Policy, req)
 	if err != nil {
-		return fmt.Errorf("执行请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("读取响应体失败: %v", err)
-	}
-
-	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("请求失败，状态码: %d: %s", resp.StatusCode, string(respBody))
+		return err
 	}
 
 	// 打印响应数据（限制长度）
@@ -114,7 +91,7 @@ func (c *Client) GetBizTreeList(ctx context.Context, bizTreeType, phase, subject
 
 	err := c.doRequest(
 		ctx,
-		consts.QuestionAPIBizTreeList.Method,
+		consts.QuestionAPIBizTreeList,
 		consts.QuestionAPIBizTreeList.Path,
 		req,
 		&result,
@@ -143,7 +120,7 @@ func (c *Client) GetBizTreeDetail(ctx context.Context, bizTreeID int64) (*itl.Bi
 	path := fmt.Sprintf("%s?bizTreeId=%d", consts.QuestionAPIBizTreeDetail.Path, req.BizTreeID)
 	err := c.doRequest(
 		ctx,
-		consts.QuestionAPIBizTreeDetail.Method,
+		consts.QuestionAPIBizTreeDetail,
 		path,
 		nil,
 		&result,
@@ -248,7 +225,7 @@ func (c *Client) CheckQuestionSetExistByIDs(ctx context.Context, ids []int64) ([
 
 	result := &itl.CheckQuestionSetExistByIDsResponseBody{}
 
-	err := c.doRequest(ctx, consts.QuestionAPIPracticeListByID.Method, consts.QuestionAPIPracticeListByID.Path, req, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIPracticeListByID, consts.QuestionAPIPracticeListByID.Path, req, &result)
 	if err != nil {
 		c.log.Error(ctx, "根据ID获取巩固练习列表失败: %v", err)
 		return nil, err
@@ -267,7 +244,7 @@ func (c *Client) GetPracticeInfoByBizTreeNodeID(ctx context.Context, bizTreeNode
 		bizTreeNodeID,
 		consts.QuestionSceneCategoryPractice,
 	)
-	err := c.doRequest(ctx, consts.QuestionAPIGetQuestionSetInfo.Method, path, nil, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIGetQuestionSetInfo, path, nil, &result)
 	if err != nil {
 		c.log.Error(ctx, "获取巩固练习信息失败: %v", err)
 		return nil, err
@@ -294,7 +271,7 @@ func (c *Client) GetQuestionSetByID(ctx context.Context, questionSetID int64) (*
 		consts.QuestionAPIGetQuestionSetInfo.Path,
 		questionSetID,
 	)
-	err := c.doRequest(ctx, consts.QuestionAPIGetQuestionSetInfo.Method, path, nil, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIGetQuestionSetInfo, path, nil, &result)
 	if err != nil {
 		c.log.Error(ctx, "获取题集信息失败: %v", err)
 		return nil, err
@@ -475,7 +452,7 @@ func (c *Client) GetPracticeListByBizTreeNodeIDs(ctx context.Context, bizTreeNod
 func (c *Client) GetQuestionEnums(ctx context.Context) (*itl.QuestionEnumsData, error) {
 	result := &itl.QuestionEnumsResponseBody{}
 
-	err := c.doRequest(ctx, consts.QuestionAPIQuestionEnums.Method, consts.QuestionAPIQuestionEnums.Path, nil, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIQuestionEnums, consts.QuestionAPIQuestionEnums.Path, nil, &result)
 	if err != nil {
 		c.log.Error(ctx, "获取题目查询枚举值失败: %v", err)
 		return nil, err
@@ -505,7 +482,7 @@ func (c *Client) GetQuestionList(ctx context.Context, req *api.GetQuestionListRe
 	}
 	result := &itl.QuestionListResponseBody{}
 
-	err := c.doRequest(ctx, consts.QuestionAPIQuestionList.Method, consts.QuestionAPIQuestionList.Path, reqBody, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIQuestionList, consts.QuestionAPIQuestionList.Path, reqBody, &result)
 	if err != nil {
 		c.log.Error(ctx, "获取题目列表失败: %v", err)
 		return nil, err
@@ -537,7 +514,7 @@ func (c *Client) GetQuestionDetail(ctx context.Context, questionID string) (*itl
 	result := &itl.GetQuestionDetailResponseBody{}
 
 	path := fmt.Sprintf("%s?questionId=%s", consts.QuestionAPIQuestionDetail.Path, req.QuestionId)
-	err := c.doRequest(ctx, consts.QuestionAPIQuestionDetail.Method, path, nil, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIQuestionDetail, path, nil, &result)
 	if err != nil {
 		c.log.Error(ctx, "获取题目详情失败: %v", err)
 		return nil, err
@@ -568,7 +545,7 @@ func (c *Client) GetQuestionListByID(ctx context.Context, ids []string, needCont
 
 	result := &itl.GetQuestionListByIDResponseBody{}
 
-	err := c.doRequest(ctx, consts.QuestionAPIQuestionListByID.Method, consts.QuestionAPIQuestionListByID.Path, req, &result)
+	err := c.doRequest(ctx, consts.QuestionAPIQuestionListByID, consts.QuestionAPIQuestionListByID.Path, req, &result)
 	if err != nil {
 		c.log.Error(ctx, "根据ID获取题目列表失败: %v", err)
 		return nil, err
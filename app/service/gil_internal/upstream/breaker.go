@@ -0,0 +1,78 @@
+package upstream
+
+import (
+	"sync"
+	"time"
+)
+
+// 熔断器状态，取值和指标中的状态值一致
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+// 熔断器，连续失败达到阈值后熔断，熔断到期后放行一个探测请求，探测成功时恢复，失败时重新熔断
+type breaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openUntil time.Time
+	threshold int
+	openFor   time.Duration
+	onChange  func(breakerState)
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, openFor time.Duration, onChange func(breakerState)) *breaker {
+	return &breaker{threshold: threshold, openFor: openFor, onChange: onChange, now: time.Now}
+}
+
+// 是否放行请求，半开状态下只放行一个探测请求
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Before(b.openUntil) {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// 记录请求结果，只有上游不可用的错误计为失败
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.openFor)
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *breaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
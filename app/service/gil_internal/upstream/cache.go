@@ -0,0 +1,83 @@
+package upstream
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// 缓存的上游响应，FetchedAt 为从上游取回的时间，各级缓存按它判断是否过期
+type cacheEntry struct {
+	Body      []byte    `json:"body"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// 是否在有效期内
+func (e *cacheEntry) fresh(now time.Time, ttl time.Duration) bool {
+	return now.Sub(e.FetchedAt) < ttl
+}
+
+// 是否还能在上游失败时作为旧数据返回
+func (e *cacheEntry) usable(now time.Time, ttl, staleTTL time.Duration) bool {
+	return now.Sub(e.FetchedAt) < ttl+staleTTL
+}
+
+type lruItem struct {
+	key   string
+	entry *cacheEntry
+}
+
+// 进程内 LRU 缓存，超过容量时淘汰最久未访问的条目，过期判断由调用方按策略处理
+type lruCache struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+func newLRUCache(capacity int) *lruCache {
+	return &lruCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *lruCache) get(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruItem).entry, true
+}
+
+func (c *lruCache) set(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: entry})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (c *lruCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+		delete(c.items, key)
+	}
+}
@@ -0,0 +1,343 @@
+// 对接内部上游服务的公共调用层，提供超时、重试、熔断、请求合并和两级缓存
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/utils/prometheus"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCircuitOpen 上游接口熔断中，请求没有发出
+var ErrCircuitOpen = errors.New("上游服务熔断中")
+
+// StatusError 上游返回了非 200 状态码
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("请求失败，状态码: %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable 限流和服务端错误可以重试，其他状态码重试也不会成功
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// 请求本身有问题，不重试也不计入熔断
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Request 一次上游请求，URL 为完整地址
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// 二级缓存，由 dao.ApiRdbClient 实现
+type remoteCache interface {
+	Get(ctx context.Context, key string, dest any) (bool, error)
+	Set(ctx context.Context, key string, data any, ttlSec int64) error
+}
+
+// Client 上游调用客户端，每个上游一个实例，熔断器按接口区分
+type Client struct {
+	name       string
+	httpClient *http.Client
+	remote     remoteCache
+	local      *lruCache
+	group      singleflight.Group
+	mu         sync.Mutex
+	breakers   map[string]*breaker
+	log        *logger.ContextLogger
+	now        func() time.Time
+	sleep      func(time.Duration)
+}
+
+// NewClient 创建上游调用客户端，cache 为空时只使用进程内缓存
+func NewClient(name string, cache *dao.ApiRdbClient, log *logger.ContextLogger) *Client {
+	client := newClient(name, log)
+	if cache != nil {
+		client.remote = cache
+	}
+	return client
+}
+
+func newClient(name string, log *logger.ContextLogger) *Client {
+	return &Client{
+		name:       name,
+		httpClient: &http.Client{},
+		local:      newLRUCache(consts.UpstreamLocalCacheSize),
+		breakers:   make(map[string]*breaker),
+		log:        log,
+		now:        time.Now,
+		sleep:      time.Sleep,
+	}
+}
+
+// Do 按接口策略执行请求，返回 200 响应的响应体
+//
+//	配置了缓存的接口先查进程内缓存再查 Redis，相同请求并发时只发一次，
+//	上游不可用且有未超过 StaleTTL 的旧数据时返回旧数据
+func (c *Client) Do(ctx context.Context, policy consts.UpstreamPolicy, req *Request) ([]byte, error) {
+	key := c.cacheKey(policy, req)
+
+	var stale *cacheEntry
+	if policy.CacheTTL > 0 {
+		entry, fresh := c.lookup(ctx, policy, key)
+		if fresh {
+			return entry.Body, nil
+		}
+		stale = entry
+	}
+
+	// 合并的请求和发起方的取消无关，发起方提前返回时结果仍会写入缓存
+	ch := c.group.DoChan(key, func() (any, error) {
+		return c.fetch(context.WithoutCancel(ctx), policy, key, req)
+	})
+
+	var result singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result = <-ch:
+	}
+	if result.Err == nil {
+		return result.Val.([]byte), nil
+	}
+
+	if stale != nil && unavailable(result.Err) && stale.usable(c.now(), policy.CacheTTL, policy.StaleTTL) {
+		c.log.Warn(ctx, "[Upstream] %s/%s 不可用，返回 %s 缓存的旧数据: %v",
+			c.name, policy.Name, stale.FetchedAt.Format(time.DateTime), result.Err)
+		prometheus.UpstreamRequestCounter.WithLabelValues(c.name, policy.Name, "stale").Inc()
+		return stale.Body, nil
+	}
+	return nil, result.Err
+}
+
+// 经过熔断器发送请求，幂等接口在上游不可用时按指数退避加抖动重试
+func (c *Client) fetch(ctx context.Context, policy consts.UpstreamPolicy, key string, req *Request) ([]byte, error) {
+	b := c.breaker(policy.Name)
+	if !b.allow() {
+		prometheus.UpstreamRequestCounter.WithLabelValues(c.name, policy.Name, "circuit_open").Inc()
+		return nil, ErrCircuitOpen
+	}
+
+	attempts := 1
+	if policy.Idempotent {
+		attempts += policy.Retries
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			c.log.Warn(ctx, "[Upstream] %s/%s 第%d次重试, 上次错误: %v", c.name, policy.Name, i, lastErr)
+			prometheus.UpstreamRetryCounter.WithLabelValues(c.name, policy.Name).Inc()
+			c.sleep(retryDelay(i))
+		}
+
+		body, err := c.send(ctx, policy, req)
+		if err == nil {
+			b.record(false)
+			prometheus.UpstreamRequestCounter.WithLabelValues(c.name, policy.Name, "success").Inc()
+			if policy.CacheTTL > 0 && responseOK(body) {
+				c.store(ctx, policy, key, body)
+			}
+			return body, nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			break
+		}
+	}
+
+	b.record(retryable(lastErr))
+	prometheus.UpstreamRequestCounter.WithLabelValues(c.name, policy.Name, "error").Inc()
+	return nil, lastErr
+}
+
+// 发送一次请求，超时按接口策略设置
+func (c *Client) send(ctx context.Context, policy consts.UpstreamPolicy, req *Request) ([]byte, error) {
+	timeout := policy.Timeout
+	if timeout <= 0 {
+		timeout = consts.DefaultAPITimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var bodyReader io.Reader
+	if req.Body != nil {
+		bodyReader = bytes.NewReader(req.Body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bodyReader)
+	if err != nil {
+		return nil, &permanentError{err: fmt.Errorf("创建请求失败: %w", err)}
+	}
+	for name, values := range req.Header {
+		httpReq.Header[name] = values
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	prometheus.UpstreamRequestDuration.WithLabelValues(c.name, policy.Name).Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("执行请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应体失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return body, nil
+}
+
+// 查询两级缓存，命中有效数据时返回 true，否则返回可作为旧数据的条目
+func (c *Client) lookup(ctx context.Context, policy consts.UpstreamPolicy, key string) (*cacheEntry, bool) {
+	now := c.now()
+
+	var stale *cacheEntry
+	if entry, ok := c.local.get(key); ok {
+		if entry.fresh(now, policy.CacheTTL) {
+			prometheus.UpstreamCacheCounter.WithLabelValues(c.name, policy.Name, "local_hit").Inc()
+			return entry, true
+		}
+		if entry.usable(now, policy.CacheTTL, policy.StaleTTL) {
+			stale = entry
+		} else {
+			c.local.remove(key)
+		}
+	}
+
+	if c.remote != nil {
+		entry := &cacheEntry{}
+		exists, err := c.remote.Get(ctx, key, entry)
+		switch {
+		case err != nil:
+			c.log.Warn(ctx, "[Upstream] %s/%s 读取 Redis 缓存失败: %v", c.name, policy.Name, err)
+		case exists && entry.fresh(now, policy.CacheTTL):
+			c.local.set(key, entry)
+			prometheus.UpstreamCacheCounter.WithLabelValues(c.name, policy.Name, "redis_hit").Inc()
+			return entry, true
+		case exists && entry.usable(now, policy.CacheTTL, policy.StaleTTL):
+			if stale == nil || entry.FetchedAt.After(stale.FetchedAt) {
+				stale = entry
+			}
+		}
+	}
+
+	prometheus.UpstreamCacheCounter.WithLabelValues(c.name, policy.Name, "miss").Inc()
+	return stale, false
+}
+
+// 写入两级缓存，Redis 中保留到旧数据也不可用为止
+func (c *Client) store(ctx context.Context, policy consts.UpstreamPolicy, key string, body []byte) {
+	entry := &cacheEntry{Body: body, FetchedAt: c.now()}
+	c.local.set(key, entry)
+	if c.remote == nil {
+		return
+	}
+	ttlSec := int64(math.Ceil((policy.CacheTTL + policy.StaleTTL).Seconds()))
+	if err := c.remote.Set(ctx, key, entry, ttlSec); err != nil {
+		c.log.Warn(ctx, "[Upstream] %s/%s 写入 Redis 缓存失败: %v", c.name, policy.Name, err)
+	}
+}
+
+func (c *Client) breaker(endpoint string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[endpoint]
+	if !ok {
+		b = newBreaker(consts.UpstreamBreakerFailureThreshold, consts.UpstreamBreakerOpenDuration, func(state breakerState) {
+			prometheus.UpstreamBreakerState.WithLabelValues(c.name, endpoint).Set(float64(state))
+		})
+		b.now = c.now
+		c.breakers[endpoint] = b
+	}
+	return b
+}
+
+// 缓存键和请求合并键，包含方法、地址、请求头和请求体，请求头中的令牌不同时不会合并
+func (c *Client) cacheKey(policy consts.UpstreamPolicy, req *Request) string {
+	hash := sha1.New()
+	hash.Write([]byte(req.Method + "\n" + req.URL + "\n"))
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range req.Header[name] {
+			hash.Write([]byte(name + ":" + value + "\n"))
+		}
+	}
+	hash.Write(req.Body)
+	return fmt.Sprintf(consts.UpstreamCacheKeyFormat, c.name, policy.Name, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// 第 attempt 次重试前的等待时间，在指数退避间隔的一半到全部之间随机
+func retryDelay(attempt int) time.Duration {
+	delay := consts.UpstreamRetryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > consts.UpstreamRetryMaxDelay {
+		delay = consts.UpstreamRetryMaxDelay
+	}
+	return delay/2 + rand.N(delay/2)
+}
+
+// 请求失败是否因为上游不可用，这类错误会重试并计入熔断
+func retryable(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return false
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+	return true
+}
+
+// 上游不可用时可以返回旧数据
+func unavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || retryable(err)
+}
+
+// 业务码非 0 的响应不缓存，两个上游的响应都是 {code, message, data} 结构
+func responseOK(body []byte) bool {
+	var envelope struct {
+		Code int64 `json:"code"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return false
+	}
+	return envelope.Code == 0
+}
@@ -0,0 +1,265 @@
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	clogger "gil_teacher/app/core/logger"
+)
+
+type stubRemoteCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *stubRemoteCache) Get(ctx context.Context, key string, dest any) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.data[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(value, dest)
+}
+
+func (s *stubRemoteCache) Set(ctx context.Context, key string, data any, ttlSec int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	s.data[key] = value
+	return nil
+}
+
+// 按 statuses 依次返回状态码，用完后一直返回最后一个
+type stubUpstream struct {
+	server   *httptest.Server
+	hits     atomic.Int32
+	mu       sync.Mutex
+	statuses []int
+	body     string
+}
+
+func newStubUpstream(body string, statuses ...int) *stubUpstream {
+	s := &stubUpstream{statuses: statuses, body: body}
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		s.mu.Lock()
+		status := s.statuses[0]
+		if len(s.statuses) > 1 {
+			s.statuses = s.statuses[1:]
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(s.body))
+	}))
+	return s
+}
+
+func (s *stubUpstream) setStatuses(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses = statuses
+}
+
+func newTestClient(remote *stubRemoteCache) *Client {
+	client := newClient("test", clogger.NewContextLogger(log.DefaultLogger))
+	client.sleep = func(time.Duration) {}
+	if remote != nil {
+		client.remote = remote
+	}
+	return client
+}
+
+func TestClientRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := consts.UpstreamPolicy{Name: "retry", Timeout: time.Second, Idempotent: true, Retries: 2}
+
+	// 幂等接口服务端错误时重试
+	upstream := newStubUpstream(`{"code":0}`, http.StatusBadGateway, http.StatusInternalServerError, http.StatusOK)
+	defer upstream.server.Close()
+	body, err := newTestClient(nil).Do(ctx, policy, &Request{Method: http.MethodGet, URL: upstream.server.URL})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"code":0}`, string(body))
+	assert.Equal(t, int32(3), upstream.hits.Load())
+
+	// 非幂等接口不重试
+	upstream.setStatuses(http.StatusInternalServerError, http.StatusOK)
+	upstream.hits.Store(0)
+	_, err = newTestClient(nil).Do(ctx, consts.UpstreamPolicy{Name: "write", Retries: 2}, &Request{Method: http.MethodPost, URL: upstream.server.URL})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), upstream.hits.Load())
+
+	// 客户端错误不重试，调用方可以取到状态码
+	upstream.setStatuses(http.StatusUnauthorized)
+	upstream.hits.Store(0)
+	_, err = newTestClient(nil).Do(ctx, policy, &Request{Method: http.MethodGet, URL: upstream.server.URL})
+	var statusErr *StatusError
+	if assert.ErrorAs(t, err, &statusErr) {
+		assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	}
+	assert.Equal(t, int32(1), upstream.hits.Load())
+}
+
+func TestClientCache(t *testing.T) {
+	ctx := context.Background()
+	policy := consts.UpstreamPolicy{Name: "cache", Idempotent: true, CacheTTL: time.Minute, StaleTTL: time.Hour}
+	upstream := newStubUpstream(`{"code":0,"data":1}`, http.StatusOK)
+	defer upstream.server.Close()
+	req := &Request{Method: http.MethodGet, URL: upstream.server.URL}
+
+	remote := &stubRemoteCache{data: make(map[string][]byte)}
+	client := newTestClient(remote)
+	for range 3 {
+		body, err := client.Do(ctx, policy, req)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"code":0,"data":1}`, string(body))
+	}
+	assert.Equal(t, int32(1), upstream.hits.Load())
+
+	// 其他实例从 Redis 读取
+	body, err := newTestClient(remote).Do(ctx, policy, req)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"code":0,"data":1}`, string(body))
+	assert.Equal(t, int32(1), upstream.hits.Load())
+
+	// 请求头不同时不共用缓存
+	_, err = client.Do(ctx, policy, &Request{Method: http.MethodGet, URL: upstream.server.URL, Header: http.Header{"Authorization": {"token"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), upstream.hits.Load())
+
+	// 业务错误不缓存
+	failed := newStubUpstream(`{"code":300201,"message":"题集不存在"}`, http.StatusOK)
+	defer failed.server.Close()
+	for range 2 {
+		_, err := client.Do(ctx, policy, &Request{Method: http.MethodGet, URL: failed.server.URL})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), failed.hits.Load())
+}
+
+func TestClientStaleOnError(t *testing.T) {
+	ctx := context.Background()
+	policy := consts.UpstreamPolicy{Name: "stale", Idempotent: true, CacheTTL: time.Minute, StaleTTL: time.Hour}
+	upstream := newStubUpstream(`{"code":0}`, http.StatusOK)
+	defer upstream.server.Close()
+	req := &Request{Method: http.MethodGet, URL: upstream.server.URL}
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	client := newTestClient(&stubRemoteCache{data: make(map[string][]byte)})
+	client.now = func() time.Time { return now }
+	_, err := client.Do(ctx, policy, req)
+	assert.NoError(t, err)
+
+	// 缓存过期后上游不可用，返回旧数据
+	upstream.setStatuses(http.StatusServiceUnavailable)
+	now = now.Add(2 * time.Minute)
+	body, err := client.Do(ctx, policy, req)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"code":0}`, string(body))
+
+	// 上游明确拒绝时不返回旧数据
+	upstream.setStatuses(http.StatusBadRequest)
+	_, err = client.Do(ctx, policy, req)
+	assert.Error(t, err)
+
+	// 超过旧数据可用时长后返回错误
+	upstream.setStatuses(http.StatusServiceUnavailable)
+	now = now.Add(2 * time.Hour)
+	_, err = client.Do(ctx, policy, req)
+	assert.Error(t, err)
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	policy := consts.UpstreamPolicy{Name: "breaker"}
+	upstream := newStubUpstream(`{"code":0}`, http.StatusInternalServerError)
+	defer upstream.server.Close()
+	req := &Request{Method: http.MethodGet, URL: upstream.server.URL}
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	client := newTestClient(nil)
+	client.now = func() time.Time { return now }
+	for range consts.UpstreamBreakerFailureThreshold {
+		_, err := client.Do(ctx, policy, req)
+		assert.Error(t, err)
+	}
+
+	// 熔断期间不请求上游
+	_, err := client.Do(ctx, policy, req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(consts.UpstreamBreakerFailureThreshold), upstream.hits.Load())
+
+	// 其他接口不受影响
+	_, err = client.Do(ctx, consts.UpstreamPolicy{Name: "other"}, req)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+
+	// 探测失败时重新熔断
+	now = now.Add(consts.UpstreamBreakerOpenDuration)
+	_, err = client.Do(ctx, policy, req)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+	_, err = client.Do(ctx, policy, req)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// 探测成功时恢复
+	upstream.setStatuses(http.StatusOK)
+	now = now.Add(consts.UpstreamBreakerOpenDuration)
+	for range 2 {
+		_, err = client.Do(ctx, policy, req)
+		assert.NoError(t, err)
+	}
+}
+
+func TestClientSingleflight(t *testing.T) {
+	release, block := make(chan struct{}), make(chan struct{})
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			<-block
+			return
+		}
+		hits.Add(1)
+		<-release
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer server.Close()
+	defer close(block)
+
+	client := newTestClient(nil)
+	policy := consts.UpstreamPolicy{Name: "singleflight", Timeout: 5 * time.Second}
+	req := &Request{Method: http.MethodGet, URL: server.URL}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body, err := client.Do(context.Background(), policy, req)
+			assert.NoError(t, err)
+			assert.Equal(t, `{"code":0}`, string(body))
+		}()
+	}
+	assert.Eventually(t, func() bool { return hits.Load() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), hits.Load())
+
+	// 调用方超时后直接返回，不等待合并的请求
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Do(ctx, policy, &Request{Method: http.MethodGet, URL: server.URL + "/block"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
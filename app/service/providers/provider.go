@@ -22,6 +22,7 @@ var ServiceProviderSet = wire.NewSet(
 	task_service.NewTaskStatService,
 	task_service.NewTempSelectionService,
 	task_service.NewTaskResourceService,
+	task_service.NewWrongQuestionService,
 	resource_favorite.NewResourceFavoriteService,
 	question_service.NewClient,
 	admin_service.NewUcenterClient,
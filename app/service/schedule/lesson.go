@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/api"
+)
+
+// Lesson 课程表中带具体日期的一节课
+type Lesson struct {
+	Schedule    api.Schedule
+	ClassroomID int64     // 课堂ID，由课表ID或临时课表ID生成
+	StartTime   time.Time // 计划上课时间
+	EndTime     time.Time // 计划下课时间
+}
+
+// Lessons 展开课程表中的每节课，按星期缓存的课程通过 Dates 映射到具体日期，没有映射或时间无效的课跳过
+// 返回结果按下课时间和课堂ID排序
+func Lessons(scheduleResp *api.ScheduleResponse) []*Lesson {
+	lessons := make([]*Lesson, 0)
+	for day, schedules := range scheduleResp.Schedule {
+		date := day
+		if len(day) <= 2 {
+			date = scheduleResp.Dates[day]
+		}
+		if date == "" {
+			continue
+		}
+
+		for _, schedule := range schedules {
+			startTime, err := time.ParseInLocation(consts.TimeFormatDate+" "+consts.TimeFormatHHMMSS,
+				date+" "+schedule.ScheduleTplPeriodStartTime, consts.LocationShanghai)
+			if err != nil {
+				continue
+			}
+			endTime, err := time.ParseInLocation(consts.TimeFormatDate+" "+consts.TimeFormatHHMMSS,
+				date+" "+schedule.ScheduleTplPeriodEndTime, consts.LocationShanghai)
+			if err != nil || !endTime.After(startTime) {
+				continue
+			}
+
+			classroomID := schedule.ScheduleID
+			if schedule.IsTmp == 1 {
+				classroomID = consts.GenerateTempClassroomID(schedule.TmpScheduleID)
+			}
+			if classroomID == 0 {
+				continue
+			}
+			lessons = append(lessons, &Lesson{
+				Schedule:    schedule,
+				ClassroomID: classroomID,
+				StartTime:   startTime,
+				EndTime:     endTime,
+			})
+		}
+	}
+
+	sort.Slice(lessons, func(i, j int) bool {
+		if !lessons[i].EndTime.Equal(lessons[j].EndTime) {
+			return lessons[i].EndTime.Before(lessons[j].EndTime)
+		}
+		return lessons[i].ClassroomID < lessons[j].ClassroomID
+	})
+	return lessons
+}
+
+// FindLesson 从教师课程表缓存中查找课堂当前可以上的一节课
+// 允许在上课前 ClassroomStartEarly 内开始上课，下课后不能再开始，找不到时返回 nil
+func (s *ScheduleCacheService) FindLesson(ctx context.Context, schoolID, teacherID, classroomID int64, now time.Time) (*Lesson, error) {
+	var dataStr string
+	exists, err := s.redisClient.Get(ctx, consts.GetTeacherScheduleKey(schoolID, teacherID), &dataStr)
+	if err != nil {
+		if !exists {
+			return nil, nil
+		}
+		s.logger.Error(ctx, "[FindLesson] 从Redis获取课程表数据失败, teacherID:%d, error:%v", teacherID, err)
+		return nil, err
+	}
+
+	var scheduleResp api.ScheduleResponse
+	if err := json.Unmarshal([]byte(dataStr), &scheduleResp); err != nil {
+		s.logger.Error(ctx, "[FindLesson] 反序列化课程表数据失败, teacherID:%d, error:%v", teacherID, err)
+		return nil, err
+	}
+	return findLesson(&scheduleResp, classroomID, now), nil
+}
+
+func findLesson(scheduleResp *api.ScheduleResponse, classroomID int64, now time.Time) *Lesson {
+	for _, lesson := range Lessons(scheduleResp) {
+		if lesson.ClassroomID != classroomID {
+			continue
+		}
+		if !now.Before(lesson.StartTime.Add(-consts.ClassroomStartEarly)) && now.Before(lesson.EndTime) {
+			return lesson
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/model/api"
+)
+
+func TestFindLesson(t *testing.T) {
+	scheduleResp := &api.ScheduleResponse{
+		Schedule: map[string][]api.Schedule{
+			"1": {
+				{ScheduleID: 11, ClassID: 3, ScheduleTplPeriodStartTime: "09:00:00", ScheduleTplPeriodEndTime: "09:45:00"},
+			},
+			"2": {
+				{ScheduleID: 11, ClassID: 3, ScheduleTplPeriodStartTime: "09:00:00", ScheduleTplPeriodEndTime: "09:45:00"},
+				{IsTmp: 1, TmpScheduleID: 7, ScheduleTplPeriodStartTime: "10:00:00", ScheduleTplPeriodEndTime: "10:45:00"},
+			},
+		},
+		Dates: map[string]string{"1": "2026-10-12", "2": "2026-10-13"},
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, 10, 13, hour, minute, 0, 0, consts.LocationShanghai)
+	}
+
+	// 同一课堂每周都有课，只匹配当天的课
+	lesson := findLesson(scheduleResp, 11, at(8, 40))
+	if assert.NotNil(t, lesson) {
+		assert.Equal(t, at(9, 0).Unix(), lesson.StartTime.Unix())
+		assert.Equal(t, int64(3), lesson.Schedule.ClassID)
+	}
+
+	// 超过提前开课时间或已下课时不能开课
+	assert.Nil(t, findLesson(scheduleResp, 11, at(8, 29)))
+	assert.Nil(t, findLesson(scheduleResp, 11, at(9, 45)))
+
+	// 临时课按临时课堂ID匹配
+	lesson = findLesson(scheduleResp, consts.GenerateTempClassroomID(7), at(10, 10))
+	if assert.NotNil(t, lesson) {
+		assert.Equal(t, at(10, 45).Unix(), lesson.EndTime.Unix())
+	}
+	assert.Nil(t, findLesson(scheduleResp, 7, at(10, 10)))
+}
@@ -78,3 +78,12 @@ func (s *TaskAssignService) GetTaskAssignStudents(ctx context.Context, taskID in
 func (s *TaskAssignService) GetAssignStudents(ctx context.Context, assignIDs []int64) (map[int64][]int64, error) {
 	return s.taskStudentDAO.GetAssignStudents(ctx, assignIDs)
 }
+
+// 获取学生在指定任务布置中的分层序号，非分层作业或学生不在布置中时返回 0
+func (s *TaskAssignService) GetStudentTier(ctx context.Context, taskID int64, assignID int64, studentID int64) (int64, error) {
+	tiers, err := s.taskStudentDAO.GetStudentTiers(ctx, taskID, assignID, []int64{studentID})
+	if err != nil {
+		return 0, err
+	}
+	return tiers[studentID], nil
+}
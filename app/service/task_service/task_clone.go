@@ -0,0 +1,146 @@
+package task_service
+
+import (
+	"context"
+	"errors"
+
+	"gil_teacher/app/consts"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/utils"
+)
+
+// ErrTaskClone 错题作业的资源与原任务的学生绑定，不支持复制
+var ErrTaskClone = errors.New("该任务不支持复制")
+
+// CloneTask 复制任务的基本信息、分层和资源并布置给新的班级，返回新任务ID
+// 资源的子资源ID使用原任务保存的快照，不重新查询题库；分层作业按学生最近一次作业正确率和原分层的门槛重新分层
+func (s *TaskService) CloneTask(ctx context.Context, source *dao_task.Task, resources []*dao_task.TaskResource, tiers []*dao_task.TaskTier, reqBody *api.CloneTaskRequestBody) (int64, error) {
+	if source.TaskSubType == consts.TASK_TYPE_HOMEWORK_WRONG {
+		return 0, ErrTaskClone
+	}
+
+	studentTiers := make(map[int64]int64)
+	if len(tiers) > 0 {
+		var err error
+		studentTiers, err = s.resolveCloneStudentTiers(ctx, source.Subject, tiers, reqBody.StudentGroups)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	task := &dao_task.Task{
+		SchoolID:       reqBody.SchoolID,
+		Phase:          reqBody.Phase,
+		Subject:        source.Subject,
+		TaskType:       source.TaskType,
+		TaskSubType:    source.TaskSubType,
+		TaskName:       source.TaskName,
+		TeacherComment: source.TeacherComment,
+		TaskExtraInfo:  source.TaskExtraInfo,
+		Status:         consts.TASK_STATUS_PUBLISHED,
+		CreatorID:      reqBody.CreatorID,
+		UpdaterID:      reqBody.CreatorID,
+	}
+	if reqBody.TaskName != "" {
+		task.TaskName = reqBody.TaskName
+	}
+	if reqBody.TeacherComment != "" {
+		task.TeacherComment = reqBody.TeacherComment
+	}
+	if reqBody.Status == consts.TASK_STATUS_DRAFT || reqBody.Status == consts.TASK_STATUS_SCHEDULED {
+		task.Status = reqBody.Status
+	}
+	if task.Status == consts.TASK_STATUS_SCHEDULED {
+		for _, group := range reqBody.StudentGroups {
+			if task.PublishTime == 0 || group.StartTime < task.PublishTime {
+				task.PublishTime = group.StartTime
+			}
+		}
+	}
+
+	// 开启事务
+	tx := s.taskDAO.GetDB().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		s.log.Error(ctx, "开启事务失败: %v", tx.Error)
+		return 0, tx.Error
+	}
+
+	if err := tx.Create(task).Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "复制任务失败: %v", err)
+		return 0, err
+	}
+
+	if len(tiers) > 0 {
+		newTiers := make([]*dao_task.TaskTier, 0, len(tiers))
+		for _, tier := range tiers {
+			newTiers = append(newTiers, &dao_task.TaskTier{
+				TaskID:      task.TaskID,
+				TierNo:      tier.TierNo,
+				TierName:    tier.TierName,
+				MinAccuracy: tier.MinAccuracy,
+			})
+		}
+		if err := tx.Create(newTiers).Error; err != nil {
+			tx.Rollback()
+			s.log.Error(ctx, "复制任务分层失败: %v", err)
+			return 0, err
+		}
+	}
+
+	newResources := make([]*dao_task.TaskResource, 0, len(resources))
+	for _, resource := range resources {
+		newResources = append(newResources, &dao_task.TaskResource{
+			TaskID:         task.TaskID,
+			ResourceID:     resource.ResourceID,
+			ResourceSubIDs: resource.ResourceSubIDs,
+			ResourceType:   resource.ResourceType,
+			ResourceExtra:  resource.ResourceExtra,
+			TierNo:         resource.TierNo,
+		})
+	}
+	if len(newResources) > 0 {
+		if err := tx.Create(newResources).Error; err != nil {
+			tx.Rollback()
+			s.log.Error(ctx, "复制任务资源关联失败: %v", err)
+			return 0, err
+		}
+	}
+
+	if err := s.createTaskAssigns(ctx, tx, task.TaskID, reqBody.SchoolID, reqBody.StudentGroups, studentTiers); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "提交事务失败: %v", err)
+		return 0, err
+	}
+
+	s.log.Info(ctx, "[CloneTask] 复制任务成功, sourceTaskID:%d, taskID:%d, groupNum:%d", source.TaskID, task.TaskID, len(reqBody.StudentGroups))
+	return task.TaskID, nil
+}
+
+// 复制分层作业时按原分层的门槛自动分层
+func (s *TaskService) resolveCloneStudentTiers(ctx context.Context, subject int64, tiers []*dao_task.TaskTier, studentGroups []api.StudentGroup) (map[int64]int64, error) {
+	studentIDs := make([]int64, 0)
+	for _, group := range studentGroups {
+		studentIDs = append(studentIDs, group.StudentIDs...)
+	}
+	studentIDs = utils.RemoveDuplicateInt64(studentIDs)
+
+	accuracy, err := s.studentsReportDAO.FindStudentsLatestAccuracy(ctx, subject, studentIDs)
+	if err != nil {
+		s.log.Error(ctx, "获取学生最近一次作业正确率失败: %v", err)
+		return nil, err
+	}
+	// 分层按序号排序，与 TierNo 从 1 开始一一对应
+	apiTiers := make([]api.TaskTier, 0, len(tiers))
+	for _, tier := range tiers {
+		apiTiers = append(apiTiers, api.TaskTier{TierName: tier.TierName, MinAccuracy: tier.MinAccuracy})
+	}
+	return assignTiersByAccuracy(apiTiers, studentIDs, accuracy), nil
+}
@@ -0,0 +1,174 @@
+package task_service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gil_teacher/app/consts"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/utils"
+)
+
+// ErrTaskStatus 任务不存在或当前状态不支持该操作
+var ErrTaskStatus = errors.New("当前任务状态不支持该操作")
+
+// UpdateDraft 编辑草稿的名称、留言和资源，资源整体替换
+// 分层作业的分层数不能修改，答题卡和错题作业的资源由系统生成，不能修改
+func (s *TaskService) UpdateDraft(ctx context.Context, teacherID int64, reqBody *api.UpdateDraftRequestBody) error {
+	task, err := s.taskDAO.GetTaskByIDAndCreatorID(ctx, reqBody.TaskID, teacherID)
+	if err != nil {
+		return err
+	}
+	if task == nil || task.Status != consts.TASK_STATUS_DRAFT {
+		return ErrTaskStatus
+	}
+	if consts.IsAnswerCardTask(task.TaskSubType) || task.TaskSubType == consts.TASK_TYPE_HOMEWORK_WRONG {
+		return ErrTaskStatus
+	}
+	tiers, err := s.taskTierDAO.GetByTaskID(ctx, task.TaskID)
+	if err != nil {
+		return err
+	}
+	if len(tiers) != len(reqBody.TierResources) {
+		return ErrTaskStatus
+	}
+
+	resourceID2resourceSubIDs, err := s.resolveResourceSubIDs(ctx, reqBody.AllResources())
+	if err != nil {
+		return err
+	}
+	tierResources := [][]api.TaskResource{reqBody.Resources}
+	for _, tier := range reqBody.TierResources {
+		tierResources = append(tierResources, tier.Resources)
+	}
+	resources := buildTaskResources(task.TaskID, tierResources, resourceID2resourceSubIDs)
+
+	updates := map[string]any{"updater_id": teacherID}
+	if reqBody.TaskName != "" {
+		updates["task_name"] = reqBody.TaskName
+	}
+	if reqBody.TeacherComment != "" {
+		updates["teacher_comment"] = reqBody.TeacherComment
+	}
+
+	// 草稿没有作答数据，直接删除原资源后重新创建
+	tx := s.taskDAO.GetDB().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		s.log.Error(ctx, "开启事务失败: %v", tx.Error)
+		return tx.Error
+	}
+	result := tx.Model(&dao_task.Task{}).
+		Where("task_id = ? AND creator_id = ? AND status = ? AND deleted = 0", task.TaskID, teacherID, consts.TASK_STATUS_DRAFT).
+		Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "更新草稿失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrTaskStatus
+	}
+	if err := tx.Where("task_id = ?", task.TaskID).Delete(&dao_task.TaskResource{}).Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "删除草稿资源失败: %v", err)
+		return err
+	}
+	if err := tx.Create(resources).Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "创建草稿资源失败: %v", err)
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "提交事务失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// PublishTask 发布草稿，定时发布时全部布置的开始时间都需要晚于当前时间，在最早的开始时间自动发布
+func (s *TaskService) PublishTask(ctx context.Context, teacherID int64, reqBody *api.PublishTaskRequestBody) error {
+	updates := map[string]any{
+		"status":     consts.TASK_STATUS_PUBLISHED,
+		"updater_id": teacherID,
+	}
+	if reqBody.Status == consts.TASK_STATUS_SCHEDULED {
+		assigns, err := s.taskAssignDAO.GetTaskAssignInfo(ctx, reqBody.TaskID, 0)
+		if err != nil {
+			return err
+		}
+		var publishTime int64
+		for _, assign := range assigns {
+			if publishTime == 0 || assign.StartTime < publishTime {
+				publishTime = assign.StartTime
+			}
+		}
+		if publishTime <= time.Now().Unix() {
+			return ErrTaskStatus
+		}
+		updates["status"] = consts.TASK_STATUS_SCHEDULED
+		updates["publish_time"] = publishTime
+	}
+
+	ok, err := s.taskDAO.UpdateTaskStatus(ctx, reqBody.TaskID, teacherID, []int64{consts.TASK_STATUS_DRAFT}, updates)
+	if err != nil {
+		s.log.Error(ctx, "发布草稿失败: %v", err)
+		return err
+	}
+	if !ok {
+		return ErrTaskStatus
+	}
+	return nil
+}
+
+// ArchiveTasks 归档已发布的任务，归档后学生和报告中仍可查看，周期布置规则不再生成布置
+// 任一任务不是当前教师已发布的任务时全部不归档
+func (s *TaskService) ArchiveTasks(ctx context.Context, teacherID int64, taskIDs []int64) error {
+	taskIDs = utils.RemoveDuplicateInt64(taskIDs)
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	tx := s.taskDAO.GetDB().WithContext(ctx).Begin()
+	if tx.Error != nil {
+		s.log.Error(ctx, "开启事务失败: %v", tx.Error)
+		return tx.Error
+	}
+	result := tx.Model(&dao_task.Task{}).
+		Where("task_id IN ? AND creator_id = ? AND status = ? AND deleted = 0", taskIDs, teacherID, consts.TASK_STATUS_PUBLISHED).
+		Updates(map[string]any{
+			"status":     consts.TASK_STATUS_ARCHIVED,
+			"updater_id": teacherID,
+		})
+	if result.Error != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "归档任务失败, taskIDs: %v, err: %v", taskIDs, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(taskIDs)) {
+		tx.Rollback()
+		return ErrTaskStatus
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "提交事务失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetUnpublishedTasks 获取教师的草稿或定时发布的任务列表，按创建时间倒序
+func (s *TaskService) GetUnpublishedTasks(ctx context.Context, teacherID, schoolID int64, req *api.GetUnpublishedTaskListRequest) ([]*dao_task.Task, int64, error) {
+	conditions := map[string]any{
+		"creator_id": teacherID,
+		"school_id":  schoolID,
+		"status":     req.Status,
+	}
+	if req.Subject != 0 {
+		conditions["subject"] = req.Subject
+	}
+	return s.taskDAO.ListTasks(ctx, conditions, req.Page, req.PageSize)
+}
@@ -2,7 +2,10 @@ package task_service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"sort"
+	"strconv"
 
 	"gil_teacher/app/consts"
 	"gil_teacher/app/core/logger"
@@ -12,15 +15,22 @@ import (
 	"gil_teacher/app/model/dto"
 	"gil_teacher/app/service/gil_internal/question_service"
 	"gil_teacher/app/utils"
+
+	"gorm.io/gorm"
 )
 
+// ErrTierStudentMissing 手动分层时存在未指定分层的学生
+var ErrTierStudentMissing = errors.New("存在未分层的学生")
+
 // TaskService 任务服务
 type TaskService struct {
-	log           *logger.ContextLogger
-	taskDAO       dao_task.TaskDAO
-	taskAssignDAO dao_task.TaskAssignDAO
-	questionAPI   *question_service.Client
-	redisClient   *dao.ApiRdbClient
+	log               *logger.ContextLogger
+	taskDAO           dao_task.TaskDAO
+	taskAssignDAO     dao_task.TaskAssignDAO
+	taskTierDAO       dao_task.TaskTierDAO
+	studentsReportDAO dao_task.TaskStudentsReportDao
+	questionAPI       *question_service.Client
+	redisClient       *dao.ApiRdbClient
 }
 
 // NewTaskService 创建任务服务实例
@@ -28,15 +38,19 @@ func NewTaskService(
 	log *logger.ContextLogger,
 	taskDAO dao_task.TaskDAO,
 	taskAssignDAO dao_task.TaskAssignDAO,
+	taskTierDAO dao_task.TaskTierDAO,
+	studentsReportDAO dao_task.TaskStudentsReportDao,
 	questionAPI *question_service.Client,
 	redisClient *dao.ApiRdbClient,
 ) *TaskService {
 	return &TaskService{
-		log:           log,
-		taskDAO:       taskDAO,
-		taskAssignDAO: taskAssignDAO,
-		questionAPI:   questionAPI,
-		redisClient:   redisClient,
+		log:               log,
+		taskDAO:           taskDAO,
+		taskAssignDAO:     taskAssignDAO,
+		taskTierDAO:       taskTierDAO,
+		studentsReportDAO: studentsReportDAO,
+		questionAPI:       questionAPI,
+		redisClient:       redisClient,
 	}
 }
 
@@ -56,35 +70,38 @@ func (s *TaskService) RecordLastUsedBizTree(ctx context.Context, teacherID int64
 
 // CreateTask 创建任务
 func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskRequestBody) error {
-	// 如果资源类型为巩固练习，则将下面所有的题目 ID 记录到 resourceSubIDs
-	questionSetIDs := []int64{}
-	resourceID2resourceSubIDs := make(map[string][]string)
-	// 先遍历记录巩固练习的ID
-	for _, resource := range reqBody.Resources {
-		if resource.ResourceType == consts.RESOURCE_TYPE_PRACTICE {
-			questionSetIDs = append(questionSetIDs, utils.Atoi64(resource.ResourceID))
-			resourceID2resourceSubIDs[resource.ResourceID] = []string{}
+	resourceID2resourceSubIDs, err := s.resolveResourceSubIDs(ctx, reqBody.AllResources())
+	if err != nil {
+		return err
+	}
+
+	// 分层作业先计算每个学生的分层
+	studentTiers := make(map[int64]int64)
+	if reqBody.IsLayered() {
+		studentTiers, err = s.resolveStudentTiers(ctx, reqBody)
+		if err != nil {
+			return err
 		}
 	}
-	// 再向题库平台查询巩固练习下面的题目ID
-	if len(questionSetIDs) > 0 {
-		questionSetList, err := s.questionAPI.GetQuestionSetListByIDs(ctx, questionSetIDs)
+
+	// 答题卡任务的答案保存在任务额外信息中，题目作为答题卡资源的子题目，题号从 1 开始
+	tierResources := [][]api.TaskResource{reqBody.Resources}
+	taskExtraInfo := reqBody.TaskExtraInfo
+	if reqBody.IsAnswerCard() {
+		answerCard, err := json.Marshal(reqBody.AnswerCard)
 		if err != nil {
-			s.log.Error(ctx, "获取巩固练习下面的题目ID失败: %v", err)
 			return err
 		}
-		// 三级结构：题集-题组-题目
-		for _, questionSet := range questionSetList {
-			if questionSet != nil {
-				questionIDs := make([]string, 0)
-				for _, questionGroup := range questionSet.QuestionGroupStableInfoList {
-					for _, question := range questionGroup.QuestionInfoList {
-						questionIDs = append(questionIDs, question.QuestionId)
-					}
-				}
-				resourceID2resourceSubIDs[utils.I64ToStr(questionSet.QuestionSetId)] = questionIDs
-			}
+		taskExtraInfo = string(answerCard)
+		questionNos := make([]string, 0, len(reqBody.AnswerCard.Questions))
+		for i := range reqBody.AnswerCard.Questions {
+			questionNos = append(questionNos, strconv.Itoa(i+1))
 		}
+		resourceID2resourceSubIDs[consts.AnswerCardResourceID] = questionNos
+		tierResources[0] = []api.TaskResource{{
+			ResourceID:   consts.AnswerCardResourceID,
+			ResourceType: consts.RESOURCE_TYPE_ANSWER_CARD,
+		}}
 	}
 
 	// 1. 构建任务实体
@@ -93,12 +110,25 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 		Phase:          reqBody.Phase,
 		Subject:        reqBody.Subject,
 		TaskType:       reqBody.TaskType,
+		TaskSubType:    reqBody.TaskSubType,
 		TaskName:       reqBody.TaskName,
 		TeacherComment: reqBody.TeacherComment,
-		TaskExtraInfo:  reqBody.TaskExtraInfo,
+		TaskExtraInfo:  taskExtraInfo,
+		Status:         consts.TASK_STATUS_PUBLISHED,
 		CreatorID:      reqBody.CreatorID,
 		UpdaterID:      reqBody.UpdaterID,
 	}
+	// 草稿和定时发布的任务学生不可见，定时发布的任务在最早的布置开始时间发布
+	if reqBody.Status == consts.TASK_STATUS_DRAFT || reqBody.Status == consts.TASK_STATUS_SCHEDULED {
+		task.Status = reqBody.Status
+	}
+	if task.Status == consts.TASK_STATUS_SCHEDULED {
+		for _, group := range reqBody.StudentGroups {
+			if task.PublishTime == 0 || group.StartTime < task.PublishTime {
+				task.PublishTime = group.StartTime
+			}
+		}
+	}
 
 	// 开启事务
 	tx := s.taskDAO.GetDB().WithContext(ctx).Begin()
@@ -114,24 +144,30 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 		return err
 	}
 
-	// 创建任务资源关联
-	if len(reqBody.Resources) > 0 {
-		resources := make([]*dao_task.TaskResource, 0, len(reqBody.Resources))
-		for _, res := range reqBody.Resources {
-			resourceSubIDs := []string{}
-			if subIDs, ok := resourceID2resourceSubIDs[res.ResourceID]; ok {
-				resourceSubIDs = subIDs
-			}
-			resource := &dao_task.TaskResource{
-				TaskID:         task.TaskID,
-				ResourceID:     res.ResourceID,
-				ResourceSubIDs: resourceSubIDs,
-				ResourceType:   res.ResourceType,
-				ResourceExtra:  res.ResourceExtra,
-			}
-			resources = append(resources, resource)
+	// 创建分层作业的分层，分层序号从 1 开始
+	if len(reqBody.Tiers) > 0 {
+		tiers := make([]*dao_task.TaskTier, 0, len(reqBody.Tiers))
+		for i, tier := range reqBody.Tiers {
+			tiers = append(tiers, &dao_task.TaskTier{
+				TaskID:      task.TaskID,
+				TierNo:      int64(i + 1),
+				TierName:    tier.TierName,
+				MinAccuracy: tier.MinAccuracy,
+			})
 		}
+		if err := tx.Create(tiers).Error; err != nil {
+			tx.Rollback()
+			s.log.Error(ctx, "创建任务分层失败: %v", err)
+			return err
+		}
+	}
 
+	// 创建任务资源关联，共用资源的分层序号为 0
+	for _, tier := range reqBody.Tiers {
+		tierResources = append(tierResources, tier.Resources)
+	}
+	resources := buildTaskResources(task.TaskID, tierResources, resourceID2resourceSubIDs)
+	if len(resources) > 0 {
 		// 使用事务批量创建资源关联
 		if err := tx.Create(resources).Error; err != nil {
 			tx.Rollback()
@@ -140,12 +176,30 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 		}
 	}
 
+	// 创建任务学生群组关联和任务学生关联
+	if err := s.createTaskAssigns(ctx, tx, task.TaskID, reqBody.SchoolID, reqBody.StudentGroups, studentTiers); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		s.log.Error(ctx, "提交事务失败: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// 在事务中创建任务的学生群组关联和学生关联，studentTiers 为学生所在分层，非分层作业为空，出错时由调用方回滚事务
+func (s *TaskService) createTaskAssigns(ctx context.Context, tx *gorm.DB, taskID, schoolID int64, studentGroups []api.StudentGroup, studentTiers map[int64]int64) error {
 	// 创建任务学生群组关联
-	taskAssigns := make([]*dao_task.TaskAssign, 0, len(reqBody.StudentGroups))
-	for _, group := range reqBody.StudentGroups {
+	taskAssigns := make([]*dao_task.TaskAssign, 0, len(studentGroups))
+	for _, group := range studentGroups {
 		taskAssign := &dao_task.TaskAssign{
-			TaskID:    task.TaskID,
-			SchoolID:  reqBody.SchoolID,
+			TaskID:    taskID,
+			SchoolID:  schoolID,
 			GroupType: group.GroupType,
 			GroupID:   group.GroupID,
 			StartTime: group.StartTime,
@@ -157,7 +211,6 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 	// 批量创建任务学生群组关联
 	if len(taskAssigns) > 0 {
 		if err := tx.Create(taskAssigns).Error; err != nil {
-			tx.Rollback()
 			s.log.Error(ctx, "批量创建任务学生群组关联失败: %v", err)
 			return err
 		}
@@ -165,7 +218,7 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 
 	// 批量创建任务学生关联
 	taskStudents := make([]*dao_task.TaskStudent, 0)
-	for _, group := range reqBody.StudentGroups {
+	for _, group := range studentGroups {
 		// 找到对应的任务分配记录
 		var assignID int64
 		for _, assign := range taskAssigns {
@@ -179,8 +232,9 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 		for _, studentID := range group.StudentIDs {
 			taskStudents = append(taskStudents, &dao_task.TaskStudent{
 				AssignID:  assignID,
-				TaskID:    task.TaskID,
+				TaskID:    taskID,
 				StudentID: studentID,
+				TierNo:    studentTiers[studentID],
 			})
 		}
 	}
@@ -188,20 +242,137 @@ func (s *TaskService) CreateTask(ctx context.Context, reqBody *api.CreateTaskReq
 	// 批量创建任务学生关联
 	if len(taskStudents) > 0 {
 		if err := tx.Create(taskStudents).Error; err != nil {
-			tx.Rollback()
 			s.log.Error(ctx, "批量创建任务学生关联失败: %v", err)
 			return err
 		}
 	}
+	return nil
+}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		s.log.Error(ctx, "提交事务失败: %v", err)
-		return err
+// 如果资源类型为巩固练习，则将下面所有的题目 ID 记录到 resourceSubIDs，返回 map[resource_id][]sub_id
+func (s *TaskService) resolveResourceSubIDs(ctx context.Context, resources []api.TaskResource) (map[string][]string, error) {
+	questionSetIDs := []int64{}
+	resourceID2resourceSubIDs := make(map[string][]string)
+	// 先遍历记录巩固练习的ID
+	for _, resource := range resources {
+		if resource.ResourceType == consts.RESOURCE_TYPE_PRACTICE {
+			questionSetIDs = append(questionSetIDs, utils.Atoi64(resource.ResourceID))
+			resourceID2resourceSubIDs[resource.ResourceID] = []string{}
+		}
+	}
+	// 再向题库平台查询巩固练习下面的题目ID
+	if len(questionSetIDs) > 0 {
+		questionSetList, err := s.questionAPI.GetQuestionSetListByIDs(ctx, questionSetIDs)
+		if err != nil {
+			s.log.Error(ctx, "获取巩固练习下面的题目ID失败: %v", err)
+			return nil, err
+		}
+		// 三级结构：题集-题组-题目
+		for _, questionSet := range questionSetList {
+			if questionSet != nil {
+				questionIDs := make([]string, 0)
+				for _, questionGroup := range questionSet.QuestionGroupStableInfoList {
+					for _, question := range questionGroup.QuestionInfoList {
+						questionIDs = append(questionIDs, question.QuestionId)
+					}
+				}
+				resourceID2resourceSubIDs[utils.I64ToStr(questionSet.QuestionSetId)] = questionIDs
+			}
+		}
 	}
+	return resourceID2resourceSubIDs, nil
+}
 
-	return nil
+// 构建任务资源关联，tierResources 下标为分层序号，共用资源的分层序号为 0
+func buildTaskResources(taskID int64, tierResources [][]api.TaskResource, resourceID2resourceSubIDs map[string][]string) []*dao_task.TaskResource {
+	resources := make([]*dao_task.TaskResource, 0)
+	for tierNo, tierResource := range tierResources {
+		for _, res := range tierResource {
+			resourceSubIDs := []string{}
+			if subIDs, ok := resourceID2resourceSubIDs[res.ResourceID]; ok {
+				resourceSubIDs = subIDs
+			}
+			resource := &dao_task.TaskResource{
+				TaskID:         taskID,
+				ResourceID:     res.ResourceID,
+				ResourceSubIDs: resourceSubIDs,
+				ResourceType:   res.ResourceType,
+				ResourceExtra:  res.ResourceExtra,
+				TierNo:         int64(tierNo),
+				StudentIDs:     res.StudentIDs,
+			}
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// 计算分层作业每个学生的分层序号
+// 手动分层时布置的每个学生都需要指定分层；自动分层时按学生最近一次作业的正确率分层
+func (s *TaskService) resolveStudentTiers(ctx context.Context, reqBody *api.CreateTaskRequestBody) (map[int64]int64, error) {
+	studentIDs := make([]int64, 0)
+	for _, group := range reqBody.StudentGroups {
+		studentIDs = append(studentIDs, group.StudentIDs...)
+	}
+	studentIDs = utils.RemoveDuplicateInt64(studentIDs)
+
+	if reqBody.TierMode == consts.TASK_TIER_MODE_MANUAL {
+		studentTiers := make(map[int64]int64, len(studentIDs))
+		for i, tier := range reqBody.Tiers {
+			for _, studentID := range tier.StudentIDs {
+				studentTiers[studentID] = int64(i + 1)
+			}
+		}
+		for _, studentID := range studentIDs {
+			if _, ok := studentTiers[studentID]; !ok {
+				s.log.Warn(ctx, "学生 %d 未指定分层", studentID)
+				return nil, ErrTierStudentMissing
+			}
+		}
+		return studentTiers, nil
+	}
+
+	accuracy, err := s.studentsReportDAO.FindStudentsLatestAccuracy(ctx, reqBody.Subject, studentIDs)
+	if err != nil {
+		s.log.Error(ctx, "获取学生最近一次作业正确率失败: %v", err)
+		return nil, err
+	}
+	return assignTiersByAccuracy(reqBody.Tiers, studentIDs, accuracy), nil
+}
+
+// 按正确率自动分层：学生进入最低正确率不高于自己正确率的分层中门槛最高的一层，
+// 没有作业记录或低于全部门槛的学生进入门槛最低的一层
+func assignTiersByAccuracy(tiers []api.TaskTier, studentIDs []int64, accuracy map[int64]float64) map[int64]int64 {
+	tierNos := make([]int64, 0, len(tiers))
+	for i := range tiers {
+		tierNos = append(tierNos, int64(i+1))
+	}
+	// 门槛从高到低排序，门槛相同时保持分层顺序
+	sort.SliceStable(tierNos, func(i, j int) bool {
+		return tiers[tierNos[i]-1].MinAccuracy > tiers[tierNos[j]-1].MinAccuracy
+	})
+	lowestTier := tierNos[len(tierNos)-1]
+
+	studentTiers := make(map[int64]int64, len(studentIDs))
+	for _, studentID := range studentIDs {
+		studentTiers[studentID] = lowestTier
+		rate, ok := accuracy[studentID]
+		if !ok {
+			continue
+		}
+		for _, tierNo := range tierNos {
+			if rate >= tiers[tierNo-1].MinAccuracy {
+				studentTiers[studentID] = tierNo
+				break
+			}
+		}
+	}
+	return studentTiers
+}
+
+// GetTaskTiers 获取任务的分层列表，非分层作业返回空列表
+func (s *TaskService) GetTaskTiers(ctx context.Context, taskID int64) ([]*dao_task.TaskTier, error) {
+	return s.taskTierDAO.GetByTaskID(ctx, taskID)
 }
 
 // GetTaskByIDAndCreatorID 获取指定任务
@@ -233,6 +404,11 @@ func (s *TaskService) UpdateTaskAssign(ctx context.Context, assginID int64, upda
 		s.log.Error(ctx, "更新任务失败: %v", err)
 		return err
 	}
+	// 定时发布的任务按修改后的开始时间发布
+	if err := s.taskDAO.RefreshPublishTime(ctx, []int64{assginID}); err != nil {
+		s.log.Error(ctx, "更新任务发布时间失败: %v", err)
+		return err
+	}
 	return nil
 }
 
@@ -242,6 +418,10 @@ func (s *TaskService) DeleteTaskAssign(ctx context.Context, taskID int64, assign
 		s.log.Error(ctx, "删除任务分配失败: %v", err)
 		return err
 	}
+	if err := s.taskDAO.RefreshPublishTime(ctx, assignIDs); err != nil {
+		s.log.Error(ctx, "更新任务发布时间失败: %v", err)
+		return err
+	}
 	// 如果分配的对象全部被删除则任务也一并删除
 	count, err := s.taskAssignDAO.CountTaskAssignByTaskID(ctx, taskID)
 	if err != nil {
@@ -0,0 +1,148 @@
+package task_service
+
+import (
+	"context"
+	"errors"
+
+	"gil_teacher/app/consts"
+	"gil_teacher/app/core/logger"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/model/api"
+	"gil_teacher/app/model/dto"
+	"gil_teacher/app/utils"
+)
+
+// ErrNoWrongQuestion 布置的学生在指定范围内都没有错题
+var ErrNoWrongQuestion = errors.New("没有错题")
+
+// WrongQuestionService 错题作业服务
+// 按学生历史作答中的错题为每个学生生成个性化题目，合并为一个作业任务创建
+type WrongQuestionService struct {
+	log               *logger.ContextLogger
+	taskService       *TaskService
+	studentDetailsDAO dao_task.TaskStudentDetailsDao
+}
+
+// NewWrongQuestionService 创建错题作业服务实例
+func NewWrongQuestionService(
+	log *logger.ContextLogger,
+	taskService *TaskService,
+	studentDetailsDAO dao_task.TaskStudentDetailsDao,
+) *WrongQuestionService {
+	return &WrongQuestionService{
+		log:               log,
+		taskService:       taskService,
+		studentDetailsDAO: studentDetailsDAO,
+	}
+}
+
+// CreateWrongQuestionTask 生成并创建错题作业
+// 每道错题作为一个单题资源，记录答错该题的学生，没有错题的学生不布置
+func (s *WrongQuestionService) CreateWrongQuestionTask(ctx context.Context, schoolID, phase, teacherID int64, req *api.CreateWrongQuestionTaskRequest) (*api.CreateWrongQuestionTaskResponse, error) {
+	studentIDs := make([]int64, 0)
+	for _, group := range req.StudentGroups {
+		studentIDs = append(studentIDs, group.StudentIDs...)
+	}
+	studentIDs = utils.RemoveDuplicateInt64(studentIDs)
+	if len(studentIDs) == 0 {
+		return nil, ErrNoWrongQuestion
+	}
+
+	wrongQuestions, err := s.studentDetailsDAO.FindStudentWrongQuestions(ctx, &dto.WrongQuestionQuery{
+		Subject:        req.Subject,
+		StudentIDs:     studentIDs,
+		AnswerTimeFrom: req.AnswerTimeFrom,
+		AnswerTimeTo:   req.AnswerTimeTo,
+		SourceTaskIDs:  req.SourceTaskIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	studentQuestions := pickWrongQuestions(wrongQuestions, req.MaxQuestionNum)
+
+	// 同一道题只创建一个资源，按学生顺序、学生内的错题顺序排列
+	resources := make([]api.TaskResource, 0)
+	resourceIndex := make(map[string]int)
+	for _, studentID := range studentIDs {
+		for _, questionID := range studentQuestions[studentID] {
+			idx, ok := resourceIndex[questionID]
+			if !ok {
+				idx = len(resources)
+				resourceIndex[questionID] = idx
+				resources = append(resources, api.TaskResource{
+					ResourceID:   questionID,
+					ResourceType: consts.RESOURCE_TYPE_QUESTION,
+				})
+			}
+			resources[idx].StudentIDs = append(resources[idx].StudentIDs, studentID)
+		}
+	}
+	if len(resources) == 0 {
+		return nil, ErrNoWrongQuestion
+	}
+
+	// 没有错题的学生不布置，学生全部没有错题的班级不布置
+	result := &api.CreateWrongQuestionTaskResponse{
+		QuestionNum:       int64(len(resources)),
+		SkippedStudentIDs: make([]int64, 0),
+	}
+	studentGroups := make([]api.StudentGroup, 0, len(req.StudentGroups))
+	for _, group := range req.StudentGroups {
+		groupStudentIDs := make([]int64, 0, len(group.StudentIDs))
+		for _, studentID := range group.StudentIDs {
+			if len(studentQuestions[studentID]) == 0 {
+				result.SkippedStudentIDs = append(result.SkippedStudentIDs, studentID)
+				continue
+			}
+			groupStudentIDs = append(groupStudentIDs, studentID)
+		}
+		if len(groupStudentIDs) == 0 {
+			continue
+		}
+		group.StudentIDs = groupStudentIDs
+		studentGroups = append(studentGroups, group)
+		result.StudentNum += int64(len(groupStudentIDs))
+	}
+
+	err = s.taskService.CreateTask(ctx, &api.CreateTaskRequestBody{
+		SchoolID:       schoolID,
+		Phase:          phase,
+		Subject:        req.Subject,
+		TaskType:       consts.TASK_TYPE_HOMEWORK,
+		TaskSubType:    consts.TASK_TYPE_HOMEWORK_WRONG,
+		TaskName:       req.TaskName,
+		TeacherComment: req.TeacherComment,
+		CreatorID:      teacherID,
+		UpdaterID:      teacherID,
+		Resources:      resources,
+		StudentGroups:  studentGroups,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info(ctx, "[CreateWrongQuestionTask] 创建错题作业成功, teacherID:%d, studentNum:%d, questionNum:%d, skipped:%d",
+		teacherID, result.StudentNum, result.QuestionNum, len(result.SkippedStudentIDs))
+	return result, nil
+}
+
+// 按学生选取错题，wrongQuestions 已按学生分组并按优先级排序，每个学生最多选取 maxNum 道
+// 返回 map[student_id][]question_id
+func pickWrongQuestions(wrongQuestions []*dao_task.StudentWrongQuestion, maxNum int64) map[int64][]string {
+	studentQuestions := make(map[int64][]string)
+	picked := make(map[int64]map[string]struct{})
+	for _, question := range wrongQuestions {
+		if int64(len(studentQuestions[question.StudentID])) >= maxNum {
+			continue
+		}
+		if _, ok := picked[question.StudentID]; !ok {
+			picked[question.StudentID] = make(map[string]struct{})
+		}
+		if _, ok := picked[question.StudentID][question.QuestionID]; ok {
+			continue
+		}
+		picked[question.StudentID][question.QuestionID] = struct{}{}
+		studentQuestions[question.StudentID] = append(studentQuestions[question.StudentID], question.QuestionID)
+	}
+	return studentQuestions
+}
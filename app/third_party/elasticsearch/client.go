@@ -0,0 +1,244 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gil_teacher/app/conf"
+	"gil_teacher/app/core/logger"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// ErrNotConfigured 没有配置 Elasticsearch 地址
+var ErrNotConfigured = errors.New("Elasticsearch 未配置")
+
+// Client Elasticsearch 客户端，没有配置地址时所有操作返回 ErrNotConfigured
+type Client struct {
+	es  *elasticsearch.Client
+	log *logger.ContextLogger
+}
+
+// NewClient 创建 Elasticsearch 客户端，创建时不检查连接
+func NewClient(c *conf.Conf, log *logger.ContextLogger) (*Client, error) {
+	client := &Client{log: log}
+	if c.Elasticsearch == nil || c.Elasticsearch.EsURL == "" {
+		return client, nil
+	}
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{c.Elasticsearch.EsURL},
+		Username:  c.Elasticsearch.Username,
+		Password:  c.Elasticsearch.Password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create elasticsearch client failed: %w", err)
+	}
+	client.es = es
+	return client, nil
+}
+
+// Enabled 是否配置了 Elasticsearch
+func (c *Client) Enabled() bool {
+	return c != nil && c.es != nil
+}
+
+// BulkDocument 批量写入的文档，相同 ID 重复写入时覆盖
+type BulkDocument struct {
+	ID   string
+	Body any
+}
+
+// BulkItemError 批量写入中单个文档的失败信息
+type BulkItemError struct {
+	ID     string
+	Status int
+	Reason string
+}
+
+func (e *BulkItemError) Error() string {
+	return fmt.Sprintf("index document %s failed, status: %d, reason: %s", e.ID, e.Status, e.Reason)
+}
+
+// Retryable 限流和服务端错误可以重试，文档本身有问题时重试也不会成功
+func (e *BulkItemError) Retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// SearchHit 一条搜索结果
+type SearchHit struct {
+	ID        string              `json:"_id"`
+	Source    json.RawMessage     `json:"_source"`
+	Highlight map[string][]string `json:"highlight"`
+}
+
+// SearchResult 搜索结果，Total 为命中总数
+type SearchResult struct {
+	Total int64
+	Hits  []*SearchHit
+}
+
+// EnsureIndex 索引不存在时按 body 中的设置和映射创建，已存在时不修改
+func (c *Client) EnsureIndex(ctx context.Context, index string, body string) error {
+	if !c.Enabled() {
+		return ErrNotConfigured
+	}
+
+	res, err := c.es.Indices.Exists([]string{index}, c.es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("check index exists failed: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	res, err = c.es.Indices.Create(index,
+		c.es.Indices.Create.WithContext(ctx),
+		c.es.Indices.Create.WithBody(strings.NewReader(body)),
+	)
+	if err != nil {
+		return fmt.Errorf("create index failed: %w", err)
+	}
+	defer res.Body.Close()
+	// 多个消费者同时创建时，后创建的返回索引已存在
+	if res.IsError() {
+		if msg := responseError(res); !strings.Contains(msg, "resource_already_exists_exception") {
+			return fmt.Errorf("create index failed: %s", msg)
+		}
+	}
+	c.log.Info(ctx, "[Elasticsearch] 索引已就绪: %s", index)
+	return nil
+}
+
+// BulkIndex 批量写入文档，请求失败时返回 error，部分文档失败时返回失败的文档
+func (c *Client) BulkIndex(ctx context.Context, index string, docs []*BulkDocument) ([]*BulkItemError, error) {
+	if !c.Enabled() {
+		return nil, ErrNotConfigured
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		if err := encoder.Encode(map[string]any{"index": map[string]any{"_id": doc.ID}}); err != nil {
+			return nil, fmt.Errorf("encode bulk action failed: %w", err)
+		}
+		if err := encoder.Encode(doc.Body); err != nil {
+			return nil, fmt.Errorf("encode document %s failed: %w", doc.ID, err)
+		}
+	}
+
+	res, err := c.es.Bulk(&body, c.es.Bulk.WithContext(ctx), c.es.Bulk.WithIndex(index))
+	if err != nil {
+		return nil, fmt.Errorf("bulk index failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("bulk index failed: %s", responseError(res))
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string `json:"_id"`
+			Status int    `json:"status"`
+			Error  *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode bulk response failed: %w", err)
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+
+	var failures []*BulkItemError
+	for _, item := range result.Items {
+		for _, action := range item {
+			if action.Error == nil {
+				continue
+			}
+			failures = append(failures, &BulkItemError{
+				ID:     action.ID,
+				Status: action.Status,
+				Reason: action.Error.Type + ": " + action.Error.Reason,
+			})
+		}
+	}
+	return failures, nil
+}
+
+// Delete 删除文档，文档不存在时不返回错误
+func (c *Client) Delete(ctx context.Context, index string, id string) error {
+	if !c.Enabled() {
+		return ErrNotConfigured
+	}
+
+	res, err := c.es.Delete(index, id, c.es.Delete.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("delete document %s failed: %w", id, err)
+	}
+	defer res.Body.Close()
+	if res.IsError() && res.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("delete document %s failed: %s", id, responseError(res))
+	}
+	return nil
+}
+
+// Search 执行查询，query 为完整的查询请求体
+func (c *Client) Search(ctx context.Context, index string, query map[string]any) (*SearchResult, error) {
+	if !c.Enabled() {
+		return nil, ErrNotConfigured
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, fmt.Errorf("marshal query failed: %w", err)
+	}
+	res, err := c.es.Search(
+		c.es.Search.WithContext(ctx),
+		c.es.Search.WithIndex(index),
+		c.es.Search.WithBody(bytes.NewReader(body)),
+		c.es.Search.WithTrackTotalHits(true),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer res.Body.Close()
+	if res.IsError() {
+		return nil, fmt.Errorf("search failed: %s", responseError(res))
+	}
+
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []*SearchHit `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode search response failed: %w", err)
+	}
+	return &SearchResult{Total: result.Hits.Total.Value, Hits: result.Hits.Hits}, nil
+}
+
+func responseError(res *esapi.Response) string {
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(res.Body); err != nil {
+		return res.Status()
+	}
+	return res.Status() + " " + body.String()
+}
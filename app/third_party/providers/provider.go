@@ -2,6 +2,7 @@ package providers
 
 import (
 	"gil_teacher/app/third_party/alipay_service"
+	"gil_teacher/app/third_party/elasticsearch"
 	"gil_teacher/app/third_party/middlewares/auth"
 	"gil_teacher/app/third_party/sidx"
 	"gil_teacher/app/third_party/volc_ai"
@@ -16,4 +17,5 @@ var ThirdPartyProviderSet = wire.NewSet(
 	alipay_service.NewAlipayService,
 	zipkin_trace.NewZipkinTracer,
 	volc_ai.NewClient,
+	elasticsearch.NewClient,
 )
@@ -4,6 +4,7 @@ package volc_ai
 
 import (
 	"context"
+	"errors"
 	"gil_teacher/app/conf"
 	"gil_teacher/app/consts"
 	"gil_teacher/app/core/logger"
@@ -67,3 +68,47 @@ func (c *Client) CQC(ctx context.Context, content string) (bool, error) {
 	}
 	return false, nil
 }
+
+// 对话消息角色
+const (
+	ChatRoleSystem    = model.ChatMessageRoleSystem
+	ChatRoleUser      = model.ChatMessageRoleUser
+	ChatRoleAssistant = model.ChatMessageRoleAssistant
+)
+
+// ChatMessage 对话消息
+type ChatMessage struct {
+	Role    string
+	Content string
+}
+
+// ChatModel 对话模型，测试时可以替换为本地实现
+type ChatModel interface {
+	Chat(ctx context.Context, messages []ChatMessage) (string, error)
+}
+
+// Chat 多轮对话，返回模型的回复内容
+func (c *Client) Chat(ctx context.Context, messages []ChatMessage) (string, error) {
+	req := &model.CreateChatCompletionRequest{
+		Model:    c.model,
+		Messages: make([]*model.ChatCompletionMessage, 0, len(messages)),
+	}
+	for _, message := range messages {
+		req.Messages = append(req.Messages, &model.ChatCompletionMessage{
+			Role: message.Role,
+			Content: &model.ChatCompletionMessageContent{
+				StringValue: volcengine.String(message.Content),
+			},
+		})
+	}
+
+	resp, err := c.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		c.log.Error(ctx, "Chat 请求失败: %v", err)
+		return "", err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == nil || resp.Choices[0].Message.Content.StringValue == nil {
+		return "", errors.New("模型没有返回内容")
+	}
+	return *resp.Choices[0].Message.Content.StringValue, nil
+}
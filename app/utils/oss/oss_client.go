@@ -3,6 +3,7 @@ package oss
 import (
 	"fmt"
 	"gil_teacher/app/consts"
+	"io"
 	"strings"
 	"time"
 
@@ -234,3 +235,27 @@ func (oc *OSSClient) GetPresignedPutURL(objectKey string, expireTime time.Time)
 
 	return signedURL, nil
 }
+
+// PutObject 上传对象，objectKey 为相对 basePath 的路径
+func (oc *OSSClient) PutObject(objectKey string, reader io.Reader) error {
+	// 如果客户端未初始化，则初始化
+	if oc.client == nil || oc.bucket == nil {
+		if err := oc.initClient(); err != nil {
+			return fmt.Errorf("初始化OSS客户端失败: %w", err)
+		}
+	}
+
+	// 如果basePath不为空，则添加basePath前缀
+	if oc.basePath != "" {
+		basePath := oc.basePath
+		if !strings.HasSuffix(basePath, "/") {
+			basePath = basePath + "/"
+		}
+		objectKey = basePath + strings.TrimPrefix(objectKey, "/")
+	}
+
+	if err := oc.bucket.PutObject(objectKey, reader); err != nil {
+		return fmt.Errorf("上传OSS对象失败: %w", err)
+	}
+	return nil
+}
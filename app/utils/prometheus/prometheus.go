@@ -24,10 +24,85 @@ var (
 		},
 		[]string{"method", "path"},
 	)
+
+	KafkaConsumeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consume_messages_total",
+			Help: "Total number of consumed Kafka messages by result",
+		},
+		[]string{"group", "topic", "result"},
+	)
+
+	KafkaRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consume_retries_total",
+			Help: "Total number of Kafka message retries",
+		},
+		[]string{"group", "topic"},
+	)
+
+	KafkaDeadLetterCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_dead_letter_messages_total",
+			Help: "Total number of Kafka messages sent to dead-letter topics",
+		},
+		[]string{"group", "topic"},
+	)
+
+	KafkaConsumerLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Kafka consumer lag by partition",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	UpstreamRequestCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upstream_requests_total",
+			Help: "Total number of upstream calls by result",
+		},
+		[]string{"upstream", "endpoint", "result"},
+	)
+
+	UpstreamRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "upstream_request_duration_seconds",
+			Help:    "Upstream HTTP request duration distribution, one sample per attempt",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"upstream", "endpoint"},
+	)
+
+	UpstreamRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upstream_retries_total",
+			Help: "Total number of upstream request retries",
+		},
+		[]string{"upstream", "endpoint"},
+	)
+
+	UpstreamCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upstream_cache_lookups_total",
+			Help: "Total number of upstream cache lookups by result",
+		},
+		[]string{"upstream", "endpoint", "result"},
+	)
+
+	UpstreamBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "upstream_circuit_breaker_state",
+			Help: "Upstream circuit breaker state: 0 closed, 1 half-open, 2 open",
+		},
+		[]string{"upstream", "endpoint"},
+	)
 )
 
 func Init() {
-	prometheus.MustRegister(RequestCounter, RequestDuration)
+	prometheus.MustRegister(RequestCounter, RequestDuration,
+		KafkaConsumeCounter, KafkaRetryCounter, KafkaDeadLetterCounter, KafkaConsumerLag,
+		UpstreamRequestCounter, UpstreamRequestDuration, UpstreamRetryCounter, UpstreamCacheCounter, UpstreamBreakerState)
 }
 
 // PromMiddleware Gin 中间件：收集 Prometheus 指标
@@ -0,0 +1,319 @@
+// Package xlsx 轻量的 xlsx 读写工具，写入只支持导出场景需要的多 sheet、数值/文本单元格和百分比格式，读取只支持导入场景的首个工作表
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CellStyle 单元格样式，对应 styles.xml 中 cellXfs 的下标
+type CellStyle int
+
+const (
+	StyleDefault CellStyle = iota // 默认
+	StylePercent                  // 百分比，保留两位小数
+	StyleHeader                   // 表头，加粗
+	StyleDecimal                  // 数值，保留两位小数
+)
+
+// 单个 sheet 名称最大长度
+const maxSheetNameLen = 31
+
+// Cell 单元格，Value 支持 string、整数、浮点数，nil 为空单元格
+type Cell struct {
+	Value any
+	Style CellStyle
+}
+
+// Text 文本单元格
+func Text(v string) Cell { return Cell{Value: v} }
+
+// Int 整数单元格
+func Int(v int64) Cell { return Cell{Value: v} }
+
+// Float 小数单元格
+func Float(v float64) Cell { return Cell{Value: v, Style: StyleDecimal} }
+
+// Percent 百分比单元格，v 为 0-1 的小数
+func Percent(v float64) Cell { return Cell{Value: v, Style: StylePercent} }
+
+// Empty 空单元格
+func Empty() Cell { return Cell{} }
+
+// Sheet 工作表
+type Sheet struct {
+	Name   string
+	Widths []float64 // 列宽，可为空
+	rows   [][]Cell
+}
+
+// SetHeader 写入表头行
+func (s *Sheet) SetHeader(titles []string) {
+	row := make([]Cell, 0, len(titles))
+	for _, title := range titles {
+		row = append(row, Cell{Value: title, Style: StyleHeader})
+	}
+	s.rows = append(s.rows, row)
+}
+
+// AddRow 追加一行
+func (s *Sheet) AddRow(cells ...Cell) {
+	s.rows = append(s.rows, cells)
+}
+
+// Rows 返回全部行
+func (s *Sheet) Rows() [][]Cell {
+	return s.rows
+}
+
+// Workbook 工作簿
+type Workbook struct {
+	sheets []*Sheet
+}
+
+func NewWorkbook() *Workbook {
+	return &Workbook{}
+}
+
+// AddSheet 新增工作表，名称中的非法字符会被替换，重名时自动追加序号
+func (w *Workbook) AddSheet(name string) *Sheet {
+	name = w.uniqueName(sanitizeSheetName(name))
+	sheet := &Sheet{Name: name}
+	w.sheets = append(w.sheets, sheet)
+	return sheet
+}
+
+// Sheets 返回全部工作表
+func (w *Workbook) Sheets() []*Sheet {
+	return w.sheets
+}
+
+// Bytes 生成 xlsx 文件内容
+func (w *Workbook) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Write 将 xlsx 文件写入 writer
+func (w *Workbook) Write(writer io.Writer) error {
+	if len(w.sheets) == 0 {
+		w.AddSheet("Sheet1")
+	}
+
+	zw := zip.NewWriter(writer)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", w.contentTypes()},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", w.workbook()},
+		{"xl/_rels/workbook.xml.rels", w.workbookRels()},
+		{"xl/styles.xml", styles},
+	}
+	for _, f := range files {
+		if err := writeZipFile(zw, f.name, f.content); err != nil {
+			return err
+		}
+	}
+	for i, sheet := range w.sheets {
+		content, err := sheet.xml()
+		if err != nil {
+			return err
+		}
+		if err := writeZipFile(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name, content string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func (w *Workbook) uniqueName(name string) string {
+	exists := make(map[string]struct{}, len(w.sheets))
+	for _, sheet := range w.sheets {
+		exists[sheet.Name] = struct{}{}
+	}
+	if _, ok := exists[name]; !ok {
+		return name
+	}
+	for i := 2; ; i++ {
+		suffix := fmt.Sprintf("(%d)", i)
+		candidate := truncateRunes(name, maxSheetNameLen-len(suffix)) + suffix
+		if _, ok := exists[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = "Sheet"
+	}
+	return truncateRunes(name, maxSheetNameLen)
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func (w *Workbook) contentTypes() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (w *Workbook) workbook() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range w.sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(sheet.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (w *Workbook) workbookRels() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range w.sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(w.sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (s *Sheet) xml() (string, error) {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	if len(s.Widths) > 0 {
+		b.WriteString(`<cols>`)
+		for i, width := range s.Widths {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%s" customWidth="1"/>`, i+1, i+1, strconv.FormatFloat(width, 'f', -1, 64))
+		}
+		b.WriteString(`</cols>`)
+	}
+	b.WriteString(`<sheetData>`)
+	for r, row := range s.rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, cell := range row {
+			ref := ColumnName(c+1) + strconv.Itoa(r+1)
+			if err := writeCell(&b, ref, cell); err != nil {
+				return "", fmt.Errorf("sheet %s cell %s: %w", s.Name, ref, err)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String(), nil
+}
+
+func writeCell(b *strings.Builder, ref string, cell Cell) error {
+	style := ""
+	if cell.Style != StyleDefault {
+		style = fmt.Sprintf(` s="%d"`, cell.Style)
+	}
+
+	var number string
+	switch v := cell.Value.(type) {
+	case nil:
+		fmt.Fprintf(b, `<c r="%s"%s/>`, ref, style)
+		return nil
+	case string:
+		fmt.Fprintf(b, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(v))
+		return nil
+	case int:
+		number = strconv.Itoa(v)
+	case int32:
+		number = strconv.FormatInt(int64(v), 10)
+	case int64:
+		number = strconv.FormatInt(v, 10)
+	case float32:
+		number = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		number = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		fmt.Fprintf(b, `<c r="%s"%s t="b"><v>%s</v></c>`, ref, style, map[bool]string{true: "1", false: "0"}[v])
+		return nil
+	default:
+		return fmt.Errorf("unsupported cell value type %T", cell.Value)
+	}
+	fmt.Fprintf(b, `<c r="%s"%s><v>%s</v></c>`, ref, style, number)
+	return nil
+}
+
+// ColumnName 列序号（从 1 开始）转换为列名，如 1 -> A，27 -> AA
+func ColumnName(n int) string {
+	name := ""
+	for n > 0 {
+		n--
+		name = string(rune('A'+n%26)) + name
+		n /= 26
+	}
+	return name
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// cellXfs 顺序需要与 CellStyle 常量一致，numFmtId 10 为内置的 0.00% 格式，2 为内置的 0.00 格式
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="10" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="2" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
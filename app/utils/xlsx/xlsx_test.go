@@ -0,0 +1,84 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestColumnName(t *testing.T) {
+	testCases := map[int]string{1: "A", 26: "Z", 27: "AA", 52: "AZ", 703: "AAA"}
+	for n, expect := range testCases {
+		if got := ColumnName(n); got != expect {
+			t.Errorf("ColumnName(%d) = %s, want %s", n, got, expect)
+		}
+	}
+}
+
+func TestWorkbookWrite(t *testing.T) {
+	workbook := NewWorkbook()
+	sheet := workbook.AddSheet("学生/汇总")
+	sheet.SetHeader([]string{"姓名", "正确率"})
+	sheet.AddRow(Text("张三 <A&B>"), Percent(0.85))
+	workbook.AddSheet("学生_汇总")
+
+	content, err := workbook.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("zip.NewReader error: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s error: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	if !strings.Contains(files["xl/workbook.xml"], `name="学生_汇总(2)"`) {
+		t.Errorf("duplicate sheet name not renamed: %s", files["xl/workbook.xml"])
+	}
+	sheet1 := files["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet1, `<c r="B2" s="1"><v>0.85</v></c>`) {
+		t.Errorf("percent cell not written: %s", sheet1)
+	}
+	if !strings.Contains(sheet1, "张三 &lt;A&amp;B&gt;") {
+		t.Errorf("text cell not escaped: %s", sheet1)
+	}
+}
+
+func TestReadFirstSheet(t *testing.T) {
+	workbook := NewWorkbook()
+	sheet := workbook.AddSheet("导入")
+	sheet.SetHeader([]string{"学生ID", "1", "2"})
+	sheet.AddRow(Int(1001), Text("A"), Empty(), Text("AC"))
+	workbook.AddSheet("其它").AddRow(Text("ignored"))
+
+	content, err := workbook.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error: %v", err)
+	}
+	rows, err := ReadFirstSheet(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("ReadFirstSheet error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+	want := []string{"1001", "A", "", "AC"}
+	if strings.Join(rows[1], ",") != strings.Join(want, ",") {
+		t.Errorf("row 2 = %v, want %v", rows[1], want)
+	}
+	if rows[0][0] != "学生ID" {
+		t.Errorf("header = %v", rows[0])
+	}
+}
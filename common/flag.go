@@ -13,6 +13,7 @@ type CmdParams struct {
 	NacosConf         nacosx.NacosConf
 	ApiService        bool
 	FlagConf          string
+	ReplayDeadLetter  string // 需要重放的死信 topic，仅消费进程使用
 }
 
 var cmdParams = CmdParams{}
@@ -36,6 +37,7 @@ func parseFlag() {
 	flag.StringVar(&cmdParams.NacosConf.Group, "nacos_group", "DEFAULT_GROUP", "nacos group, eg: --nacos_group DEFAULT_GROUP")
 	flag.StringVar(&cmdParams.NacosConf.ServiceName, "nacos_service_name", "teacher-api", "nacos service name, eg: --nacos_service_name teacher-api")
 	flag.Uint64Var(&cmdParams.NacosConf.ServicePort, "nacos_service_port", 8280, "nacos service port, eg: --nacos_service_port 8280")
+	flag.StringVar(&cmdParams.ReplayDeadLetter, "replay_dlq", "", "replay dead-letter topic and exit, eg: --replay_dlq topic-student-behaviors-dlq")
 
 	flag.Parse()
 }
@@ -53,11 +53,20 @@ $$ language 'plpgsql';
 BEGIN;
 CREATE TABLE tbl_classroom (
     id BIGSERIAL PRIMARY KEY,
-    teacher_id VARCHAR(20) NOT NULL,
+    classroom_id BIGINT NOT NULL,
+    teacher_id BIGINT NOT NULL,
     school_id BIGINT NOT NULL,
     class_id BIGINT NOT NULL,
     course_id BIGINT NOT NULL,
+    schedule_id BIGINT NOT NULL DEFAULT 0,
+    tmp_schedule_id BIGINT NOT NULL DEFAULT 0,
     status BIGINT NOT NULL,
+    scheduled_start_time BIGINT NOT NULL DEFAULT 0,
+    scheduled_end_time BIGINT NOT NULL DEFAULT 0,
+    actual_start_time BIGINT NOT NULL DEFAULT 0,
+    actual_end_time BIGINT NOT NULL DEFAULT 0,
+    paused_at BIGINT NOT NULL DEFAULT 0,
+    paused_duration BIGINT NOT NULL DEFAULT 0,
     create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
     update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
 );
@@ -65,15 +74,25 @@ CREATE TABLE tbl_classroom (
 COMMENT ON TABLE tbl_classroom IS '课堂信息表，存储课堂ID、关联教师、学校、班级、课程、状态等信息';
 -- 字段注释
 COMMENT ON COLUMN tbl_classroom.id IS '课堂自增主键ID';
+COMMENT ON COLUMN tbl_classroom.classroom_id IS '课堂ID，由课表ID或临时课表ID生成，同一课表每周复用';
 COMMENT ON COLUMN tbl_classroom.teacher_id IS '授课教师ID';
 COMMENT ON COLUMN tbl_classroom.school_id IS '课堂所属学校ID';
 COMMENT ON COLUMN tbl_classroom.class_id IS '课堂关联班级ID';
 COMMENT ON COLUMN tbl_classroom.course_id IS '课程ID';
-COMMENT ON COLUMN tbl_classroom.status IS '课堂状态（未开始/进行中/已结束等）';
+COMMENT ON COLUMN tbl_classroom.schedule_id IS '关联课表ID，临时课为0';
+COMMENT ON COLUMN tbl_classroom.tmp_schedule_id IS '关联临时课表ID，非临时课为0';
+COMMENT ON COLUMN tbl_classroom.status IS '课堂状态（0未开始/1进行中/2已暂停/3已结束）';
+COMMENT ON COLUMN tbl_classroom.scheduled_start_time IS '课表计划上课时间';
+COMMENT ON COLUMN tbl_classroom.scheduled_end_time IS '课表计划下课时间';
+COMMENT ON COLUMN tbl_classroom.actual_start_time IS '实际开始上课时间';
+COMMENT ON COLUMN tbl_classroom.actual_end_time IS '实际结束上课时间';
+COMMENT ON COLUMN tbl_classroom.paused_at IS '最近一次暂停时间，未暂停为0';
+COMMENT ON COLUMN tbl_classroom.paused_duration IS '累计暂停时长(秒)';
 COMMENT ON COLUMN tbl_classroom.create_time IS '课堂创建时间';
 COMMENT ON COLUMN tbl_classroom.update_time IS '课堂信息更新时间';
 
 -- 创建索引
+CREATE UNIQUE INDEX uk_tbl_classroom_classroom_start ON tbl_classroom(classroom_id, scheduled_start_time);
 CREATE INDEX idx_tbl_classroom_teacher_id ON tbl_classroom(teacher_id);
 CREATE INDEX idx_tbl_classroom_school_id ON tbl_classroom(school_id);
 -- 创建更新时间触发器
@@ -92,9 +111,14 @@ CREATE TABLE tbl_classroom_feedback (
     user_id BIGINT NOT NULL,
     school_id BIGINT NOT NULL,
     class_id BIGINT,
+    classroom_id BIGINT NOT NULL DEFAULT 0,
     content TEXT,
     remark BIGINT,
     create_type BIGINT NOT NULL,
+    status BIGINT NOT NULL DEFAULT 0,
+    handler_id BIGINT NOT NULL DEFAULT 0,
+    handle_time BIGINT NOT NULL DEFAULT 0,
+    moderation_status BIGINT NOT NULL DEFAULT 0,
     create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
     update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
 );
@@ -105,15 +129,22 @@ COMMENT ON COLUMN tbl_classroom_feedback.id IS '反馈自增主键ID';
 COMMENT ON COLUMN tbl_classroom_feedback.user_id IS '反馈用户ID';
 COMMENT ON COLUMN tbl_classroom_feedback.school_id IS '反馈所属学校ID';
 COMMENT ON COLUMN tbl_classroom_feedback.class_id IS '反馈关联班级ID';
+COMMENT ON COLUMN tbl_classroom_feedback.classroom_id IS '反馈关联课堂ID，未关联课堂为0';
 COMMENT ON COLUMN tbl_classroom_feedback.content IS '反馈内容';
-COMMENT ON COLUMN tbl_classroom_feedback.remark IS '反馈备注标识';
-COMMENT ON COLUMN tbl_classroom_feedback.create_type IS '反馈创建类型';
+COMMENT ON COLUMN tbl_classroom_feedback.remark IS '反馈备注标识，客户端定义的反馈选项';
+COMMENT ON COLUMN tbl_classroom_feedback.create_type IS '反馈创建类型（1学生/2教师）';
+COMMENT ON COLUMN tbl_classroom_feedback.status IS '处理状态（0待处理/1已处理）';
+COMMENT ON COLUMN tbl_classroom_feedback.handler_id IS '处理反馈的教师ID';
+COMMENT ON COLUMN tbl_classroom_feedback.handle_time IS '反馈处理时间';
+COMMENT ON COLUMN tbl_classroom_feedback.moderation_status IS '内容审核状态（0待审核/1通过/2已屏蔽违规片段/3待教师审核/4不予展示）';
 COMMENT ON COLUMN tbl_classroom_feedback.create_time IS '反馈创建时间';
 COMMENT ON COLUMN tbl_classroom_feedback.update_time IS '反馈信息更新时间';
 
 -- 创建索引
 CREATE INDEX idx_tbl_classroom_feedback_user_id ON tbl_classroom_feedback(user_id);
 CREATE INDEX idx_tbl_classroom_feedback_school_id ON tbl_classroom_feedback(school_id);
+CREATE INDEX idx_tbl_classroom_feedback_class_time ON tbl_classroom_feedback(class_id, create_time);
+CREATE INDEX idx_tbl_classroom_feedback_moderation_pending ON tbl_classroom_feedback(create_time) WHERE moderation_status = 0;
 -- 创建更新时间触发器
 CREATE TRIGGER update_tbl_classroom_feedback_timestamp
     BEFORE UPDATE ON tbl_classroom_feedback
@@ -231,6 +262,8 @@ CREATE TABLE tbl_task (
     task_name VARCHAR(32) DEFAULT '',
     teacher_comment VARCHAR(256) DEFAULT '',
     task_extra_info TEXT,
+    status BIGINT NOT NULL DEFAULT 3,
+    publish_time BIGINT NOT NULL DEFAULT 0,
     deleted BIGINT NOT NULL DEFAULT 0,
     creator_id BIGINT NOT NULL,
     updater_id BIGINT NOT NULL,
@@ -248,7 +281,9 @@ COMMENT ON COLUMN tbl_task.task_type IS '任务类型';
 COMMENT ON COLUMN tbl_task.task_sub_type IS '任务子类型';
 COMMENT ON COLUMN tbl_task.task_name IS '任务名称';
 COMMENT ON COLUMN tbl_task.teacher_comment IS '老师留言';
-COMMENT ON COLUMN tbl_task.task_extra_info IS '任务额外信息';
+COMMENT ON COLUMN tbl_task.task_extra_info IS '任务额外信息，答题卡任务为答题卡答案 JSON';
+COMMENT ON COLUMN tbl_task.status IS '任务状态 1 草稿 2 定时发布 3 已发布 4 已归档';
+COMMENT ON COLUMN tbl_task.publish_time IS '定时发布时间，为最早的布置开始时间';
 COMMENT ON COLUMN tbl_task.deleted IS '任务是否删除标识';
 COMMENT ON COLUMN tbl_task.creator_id IS '任务创建者ID';
 COMMENT ON COLUMN tbl_task.updater_id IS '任务更新者ID';
@@ -257,6 +292,7 @@ COMMENT ON COLUMN tbl_task.update_time IS '任务信息更新时间';
 
 -- 创建索引
 CREATE INDEX idx_tbl_task_creator_id ON tbl_task(creator_id, subject, task_type);
+CREATE INDEX idx_tbl_task_status_publish_time ON tbl_task(status, publish_time);
 -- 创建更新时间触发器
 CREATE TRIGGER update_tbl_task_timestamp
     BEFORE UPDATE ON tbl_task
@@ -274,7 +310,9 @@ CREATE TABLE tbl_task_resource (
     resource_id VARCHAR(16) NOT NULL, -- 父资源ID，目前存AI课/题集（巩固练习）/试题/试卷ID
     resource_sub_ids text[], -- 子资源ID列表，当父资源为巩固练习时，这里记录巩固练习下面的题目ID
     resource_type BIGINT NOT NULL,
-    resource_extra TEXT
+    resource_extra TEXT,
+    tier_no BIGINT NOT NULL DEFAULT 0,
+    student_ids BIGINT[]
 );
 -- 表注释：任务与资源关联表，记录任务关联的素材资源
 COMMENT ON TABLE tbl_task_resource IS '任务与资源关联表，存储任务ID、资源ID、资源类型等关联信息';
@@ -285,6 +323,8 @@ COMMENT ON COLUMN tbl_task_resource.resource_id IS '资源ID';
 COMMENT ON COLUMN tbl_task_resource.resource_sub_ids IS '子资源ID列表';
 COMMENT ON COLUMN tbl_task_resource.resource_type IS '资源类型';
 COMMENT ON COLUMN tbl_task_resource.resource_extra IS '资源额外信息';
+COMMENT ON COLUMN tbl_task_resource.tier_no IS '分层序号，分层作业使用，0 为全部分层共用';
+COMMENT ON COLUMN tbl_task_resource.student_ids IS '资源所属学生ID列表，错题作业使用，为空时布置的全部学生可见';
 
 -- 创建索引
 -- CREATE INDEX idx_tbl_task_resource_resource_id ON tbl_task_resource(resource_id);
@@ -331,7 +371,8 @@ CREATE TABLE tbl_task_student (
     id BIGSERIAL PRIMARY KEY,
     assign_id BIGINT NOT NULL,
     task_id BIGINT NOT NULL,
-    student_id BIGINT NOT NULL
+    student_id BIGINT NOT NULL,
+    tier_no BIGINT NOT NULL DEFAULT 0
 );
 -- 表注释：任务与学生关联表
 COMMENT ON TABLE tbl_task_student IS '任务与学生关联表';
@@ -340,6 +381,7 @@ COMMENT ON COLUMN tbl_task_student.id IS '自增主键ID';
 COMMENT ON COLUMN tbl_task_student.assign_id IS '分配ID，关联任务分配表';
 COMMENT ON COLUMN tbl_task_student.task_id IS '任务ID，关联任务表';
 COMMENT ON COLUMN tbl_task_student.student_id IS '学生ID，关联学生表';
+COMMENT ON COLUMN tbl_task_student.tier_no IS '分层序号，分层作业使用，非分层作业为 0';
 
 -- 创建索引
 CREATE INDEX idx_tbl_task_student_assign_id ON tbl_task_student(assign_id);
@@ -347,6 +389,32 @@ CREATE INDEX idx_tbl_task_student_task_id ON tbl_task_student(task_id);
 CREATE INDEX idx_tbl_task_student_student_id ON tbl_task_student(student_id);
 COMMIT;
 
+-- --------------------------------
+-- 分层作业的分层表
+-- --------------------------------
+BEGIN;
+CREATE TABLE tbl_task_tier (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    tier_no BIGINT NOT NULL,
+    tier_name VARCHAR(32) NOT NULL,
+    min_accuracy NUMERIC(5,4) NOT NULL DEFAULT 0,
+    create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
+);
+-- 表注释：分层作业的分层定义，分层下的资源和学生通过 tier_no 关联
+COMMENT ON TABLE tbl_task_tier IS '分层作业的分层表';
+-- 字段注释
+COMMENT ON COLUMN tbl_task_tier.id IS '自增主键ID';
+COMMENT ON COLUMN tbl_task_tier.task_id IS '任务ID，关联任务表';
+COMMENT ON COLUMN tbl_task_tier.tier_no IS '分层序号，从 1 开始';
+COMMENT ON COLUMN tbl_task_tier.tier_name IS '分层名称';
+COMMENT ON COLUMN tbl_task_tier.min_accuracy IS '自动分层时进入该层的最低正确率';
+COMMENT ON COLUMN tbl_task_tier.create_time IS '创建时间';
+
+-- 创建唯一性约束
+CREATE UNIQUE INDEX idx_tbl_task_tier_unique ON tbl_task_tier(task_id, tier_no);
+COMMIT;
+
 -- =============================================
 -- 任务报告设置表
 -- =============================================
@@ -461,7 +529,7 @@ COMMENT ON COLUMN tbl_task_students_report.update_time IS '最后更新时间';
 CREATE INDEX idx_task_students_report_task_id ON tbl_task_students_report(task_id);
 CREATE INDEX idx_task_students_report_assign_id ON tbl_task_students_report(assign_id);
 CREATE INDEX idx_task_students_report_student_id ON tbl_task_students_report(student_id);
-CREATE INDEX idx_task_students_report_task_assign_student ON tbl_task_students_report(task_id, assign_id, student_id);
+CREATE UNIQUE INDEX idx_task_students_report_task_assign_student ON tbl_task_students_report(task_id, assign_id, student_id);
 
 -- 创建更新时间触发器
 CREATE TRIGGER update_tbl_task_students_report_timestamp
@@ -484,12 +552,15 @@ CREATE TABLE tbl_task_student_details (
     answer_content TEXT,
     correctness BOOLEAN,
     cost_time BIGINT DEFAULT 0,
+    answer_time BIGINT NOT NULL DEFAULT 0,
     create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
     update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
 );
 
 -- 创建唯一性约束
 CREATE UNIQUE INDEX idx_tbl_task_student_details_unique ON tbl_task_student_details(task_id, assign_id, student_id, resource_key, question_id);
+-- 错题作业按学生和作答时间范围查询历史错题
+CREATE INDEX idx_tbl_task_student_details_student_answer_time ON tbl_task_student_details(student_id, answer_time);
 
 -- 表注释
 COMMENT ON TABLE tbl_task_student_details IS '学生任务完成详情表';
@@ -503,6 +574,7 @@ COMMENT ON COLUMN tbl_task_student_details.student_id IS '学生ID';
 COMMENT ON COLUMN tbl_task_student_details.answer_content IS '作答内容或进度等';
 COMMENT ON COLUMN tbl_task_student_details.correctness IS '答案正确性标识';
 COMMENT ON COLUMN tbl_task_student_details.cost_time IS '答题用时';
+COMMENT ON COLUMN tbl_task_student_details.answer_time IS '作答时间（事件时间），乱序写入时只保留更晚的作答';
 COMMENT ON COLUMN tbl_task_student_details.create_time IS '创建时间';
 COMMENT ON COLUMN tbl_task_student_details.update_time IS '更新时间';
 
@@ -541,3 +613,173 @@ CREATE INDEX idx_tbl_teacher_temp_selection_teacher_id ON tbl_teacher_temp_selec
 CREATE UNIQUE INDEX idx_tbl_teacher_temp_selection_unique ON tbl_teacher_temp_selection(teacher_id, resource_id, resource_type);
 COMMIT;
 
+-- =============================================
+-- 作业报告导出任务表
+-- =============================================
+BEGIN;
+CREATE TABLE tbl_task_export_job (
+    id BIGSERIAL PRIMARY KEY,
+    school_id BIGINT NOT NULL,
+    teacher_id BIGINT NOT NULL,
+    format VARCHAR(8) NOT NULL,
+    params JSONB,
+    status BIGINT NOT NULL DEFAULT 1,
+    file_name VARCHAR(255) DEFAULT '',
+    object_key VARCHAR(255) DEFAULT '',
+    error_msg VARCHAR(512) DEFAULT '',
+    finish_time BIGINT DEFAULT 0,
+    lease_until BIGINT NOT NULL DEFAULT 0,
+    create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
+    update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
+);
+
+-- 表注释
+COMMENT ON TABLE tbl_task_export_job IS '作业报告异步导出任务表';
+-- 字段注释
+COMMENT ON COLUMN tbl_task_export_job.id IS '自增主键ID，即导出任务ID';
+COMMENT ON COLUMN tbl_task_export_job.school_id IS '学校ID';
+COMMENT ON COLUMN tbl_task_export_job.teacher_id IS '提交导出的教师ID';
+COMMENT ON COLUMN tbl_task_export_job.format IS '导出格式 csv/xlsx';
+COMMENT ON COLUMN tbl_task_export_job.params IS '导出参数，包含布置列表、导出字段、排序';
+COMMENT ON COLUMN tbl_task_export_job.status IS '任务状态 1 等待执行 2 执行中 3 成功 4 失败';
+COMMENT ON COLUMN tbl_task_export_job.file_name IS '导出文件名';
+COMMENT ON COLUMN tbl_task_export_job.object_key IS 'OSS 对象路径';
+COMMENT ON COLUMN tbl_task_export_job.error_msg IS '失败原因';
+COMMENT ON COLUMN tbl_task_export_job.finish_time IS '完成时间';
+COMMENT ON COLUMN tbl_task_export_job.lease_until IS '执行中任务的租约到期时间，执行协程定时续期，过期视为执行中断';
+COMMENT ON COLUMN tbl_task_export_job.create_time IS '创建时间';
+COMMENT ON COLUMN tbl_task_export_job.update_time IS '更新时间';
+
+-- 创建索引
+CREATE INDEX idx_tbl_task_export_job_teacher_id ON tbl_task_export_job(teacher_id);
+CREATE INDEX idx_tbl_task_export_job_status ON tbl_task_export_job(status, update_time);
+CREATE INDEX idx_tbl_task_export_job_lease ON tbl_task_export_job(status, lease_until);
+
+-- 创建更新时间触发器
+CREATE TRIGGER update_tbl_task_export_job_timestamp
+    BEFORE UPDATE ON tbl_task_export_job
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+COMMIT;
+
+-- =============================================
+-- 任务周期布置规则表
+-- =============================================
+BEGIN;
+CREATE TABLE tbl_task_recurrence (
+    id BIGSERIAL PRIMARY KEY,
+    task_id BIGINT NOT NULL,
+    school_id BIGINT NOT NULL,
+    creator_id BIGINT NOT NULL,
+    group_type BIGINT NOT NULL,
+    group_id BIGINT NOT NULL,
+    student_ids BIGINT[],
+    weekdays BIGINT[] NOT NULL,
+    start_offset BIGINT NOT NULL DEFAULT 0,
+    duration BIGINT NOT NULL,
+    start_date BIGINT NOT NULL,
+    end_date BIGINT NOT NULL,
+    next_run_time BIGINT NOT NULL,
+    last_assign_id BIGINT DEFAULT 0,
+    status BIGINT NOT NULL DEFAULT 1,
+    create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
+    update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
+);
+
+-- 表注释
+COMMENT ON TABLE tbl_task_recurrence IS '任务周期布置规则表，按规则在每个布置日为群组新增一次任务布置';
+-- 字段注释
+COMMENT ON COLUMN tbl_task_recurrence.id IS '自增主键ID，即规则ID';
+COMMENT ON COLUMN tbl_task_recurrence.task_id IS '任务ID，关联任务表';
+COMMENT ON COLUMN tbl_task_recurrence.school_id IS '学校ID';
+COMMENT ON COLUMN tbl_task_recurrence.creator_id IS '创建规则的教师ID';
+COMMENT ON COLUMN tbl_task_recurrence.group_type IS '群组类型';
+COMMENT ON COLUMN tbl_task_recurrence.group_id IS '群组ID，当群组类型是班级时为班级ID';
+COMMENT ON COLUMN tbl_task_recurrence.student_ids IS '创建规则时群组的学生，每次布置给这些学生';
+COMMENT ON COLUMN tbl_task_recurrence.weekdays IS '布置日，1-7 对应周一到周日';
+COMMENT ON COLUMN tbl_task_recurrence.start_offset IS '每次布置的开始时间，当天零点后的秒数';
+COMMENT ON COLUMN tbl_task_recurrence.duration IS '每次布置从开始到截止的秒数';
+COMMENT ON COLUMN tbl_task_recurrence.start_date IS '规则开始日期，当天零点';
+COMMENT ON COLUMN tbl_task_recurrence.end_date IS '规则结束日期，当天零点，当天仍会布置';
+COMMENT ON COLUMN tbl_task_recurrence.next_run_time IS '下一次布置的开始时间';
+COMMENT ON COLUMN tbl_task_recurrence.last_assign_id IS '最近一次生成的布置ID';
+COMMENT ON COLUMN tbl_task_recurrence.status IS '规则状态 1 生效中 2 已停止 3 已结束';
+COMMENT ON COLUMN tbl_task_recurrence.create_time IS '创建时间';
+COMMENT ON COLUMN tbl_task_recurrence.update_time IS '更新时间';
+
+-- 创建索引
+CREATE INDEX idx_tbl_task_recurrence_task_id ON tbl_task_recurrence(task_id);
+CREATE INDEX idx_tbl_task_recurrence_status ON tbl_task_recurrence(status, next_run_time);
+
+-- 创建更新时间触发器
+CREATE TRIGGER update_tbl_task_recurrence_timestamp
+    BEFORE UPDATE ON tbl_task_recurrence
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+COMMIT;
+
+-- =============================================
+-- 班级共性错题本表
+-- =============================================
+BEGIN;
+CREATE TABLE tbl_class_error_book (
+    id BIGSERIAL PRIMARY KEY,
+    school_id BIGINT NOT NULL,
+    class_id BIGINT NOT NULL,
+    subject BIGINT NOT NULL,
+    question_id VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(16) NOT NULL,
+    resource_type BIGINT NOT NULL,
+    first_task_id BIGINT NOT NULL,
+    first_assign_id BIGINT NOT NULL,
+    first_seen_time BIGINT NOT NULL,
+    last_task_id BIGINT NOT NULL,
+    last_assign_id BIGINT NOT NULL,
+    last_seen_time BIGINT NOT NULL,
+    assign_ids BIGINT[] NOT NULL DEFAULT '{}',
+    occurrence_count BIGINT NOT NULL DEFAULT 1,
+    answer_count BIGINT NOT NULL DEFAULT 0,
+    incorrect_count BIGINT NOT NULL DEFAULT 0,
+    correct_rate NUMERIC(5,4) NOT NULL DEFAULT 0,
+    explain_time BIGINT DEFAULT 0,
+    explainer_id BIGINT DEFAULT 0,
+    create_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT,
+    update_time BIGINT DEFAULT EXTRACT(EPOCH FROM CURRENT_TIMESTAMP)::BIGINT
+);
+
+-- 表注释
+COMMENT ON TABLE tbl_class_error_book IS '班级共性错题本，记录答题人数和正确率达到班级报告设置共性错题阈值的题目';
+-- 字段注释
+COMMENT ON COLUMN tbl_class_error_book.id IS '自增主键ID';
+COMMENT ON COLUMN tbl_class_error_book.school_id IS '学校ID';
+COMMENT ON COLUMN tbl_class_error_book.class_id IS '班级ID';
+COMMENT ON COLUMN tbl_class_error_book.subject IS '学科';
+COMMENT ON COLUMN tbl_class_error_book.question_id IS '题目ID';
+COMMENT ON COLUMN tbl_class_error_book.resource_id IS '最近一次出现时题目所属资源ID';
+COMMENT ON COLUMN tbl_class_error_book.resource_type IS '最近一次出现时题目所属资源类型';
+COMMENT ON COLUMN tbl_class_error_book.first_task_id IS '第一次出现的任务ID';
+COMMENT ON COLUMN tbl_class_error_book.first_assign_id IS '第一次出现的布置ID';
+COMMENT ON COLUMN tbl_class_error_book.first_seen_time IS '第一次出现的布置开始时间';
+COMMENT ON COLUMN tbl_class_error_book.last_task_id IS '最近一次出现的任务ID';
+COMMENT ON COLUMN tbl_class_error_book.last_assign_id IS '最近一次出现的布置ID';
+COMMENT ON COLUMN tbl_class_error_book.last_seen_time IS '最近一次出现的布置开始时间';
+COMMENT ON COLUMN tbl_class_error_book.assign_ids IS '出现过的布置ID';
+COMMENT ON COLUMN tbl_class_error_book.occurrence_count IS '出现次数，即出现过的布置数';
+COMMENT ON COLUMN tbl_class_error_book.answer_count IS '最近一次出现时的答题人数';
+COMMENT ON COLUMN tbl_class_error_book.incorrect_count IS '最近一次出现时的答错人数';
+COMMENT ON COLUMN tbl_class_error_book.correct_rate IS '最近一次出现时的正确率，0-1 的小数';
+COMMENT ON COLUMN tbl_class_error_book.explain_time IS '标记已讲解的时间，0 为未讲解';
+COMMENT ON COLUMN tbl_class_error_book.explainer_id IS '标记已讲解的教师ID';
+COMMENT ON COLUMN tbl_class_error_book.create_time IS '创建时间';
+COMMENT ON COLUMN tbl_class_error_book.update_time IS '更新时间';
+
+-- 创建索引
+CREATE UNIQUE INDEX uk_tbl_class_error_book_question ON tbl_class_error_book(school_id, class_id, subject, question_id);
+CREATE INDEX idx_tbl_class_error_book_last_seen ON tbl_class_error_book(class_id, subject, last_seen_time);
+
+-- 创建更新时间触发器
+CREATE TRIGGER update_tbl_class_error_book_timestamp
+    BEFORE UPDATE ON tbl_class_error_book
+    FOR EACH ROW
+    EXECUTE FUNCTION update_timestamp();
+COMMIT;
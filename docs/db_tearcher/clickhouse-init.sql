@@ -23,57 +23,48 @@ PRIMARY KEY (school_id, class_id, teacher_id)
 ORDER BY (school_id, class_id, teacher_id, classroom_id, communication_session_id, create_time)
 SETTINGS index_granularity = 8192;
 
--- -- =============================================
--- -- 课堂学情统计表
--- -- =============================================
--- CREATE TABLE tbl_classroom_learning_stats (
---     id UUID,
---     school_id UInt64,
---     class_id UInt64,
---     course_id UInt64,
---     classroom_id UUID,
---     summary JSONB,
---     report_time Datetime
--- ) ENGINE = MergeTree()
--- PARTITION BY toYYYYMM(report_time)
--- ORDER BY (school_id, classroom_id, report_time);
-
--- -- 表注释：课堂学情统计表，统计课堂学习情况相关数据
--- COMMENT ON TABLE tbl_classroom_learning_stats IS '课堂学情统计表，用于汇总课堂内学生学习情况、进度等统计信息，支持教学分析';
--- -- 字段注释
--- COMMENT ON COLUMN tbl_classroom_learning_stats.id IS '统计记录唯一标识UUID';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.school_id IS '学校ID';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.class_id IS '班级ID';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.course_id IS '课程ID';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.classroom_id IS '课堂ID';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.summary IS '学情统计内容（JSONB格式，包含学习数据等）';
--- COMMENT ON COLUMN tbl_classroom_learning_stats.report_time IS '统计上报时间';
-
--- -- =============================================
--- -- 课堂统计报表表
--- -- =============================================
--- CREATE TABLE tbl_classroom_report (
---     id UUID,
---     school_id UInt64,
---     class_id UInt64,
---     classroom_id UUID,
---     report_content String,
---     create_time Datetime,
---     update_time Datetime
--- ) ENGINE = MergeTree()
--- PARTITION BY toYYYYMM(create_time)
--- ORDER BY (school_id, classroom_id, create_time);
+-- =============================================
+-- 课堂学情统计表，课后报告生成时每个学生一条记录
+-- =============================================
+CREATE TABLE db_teacher.tbl_classroom_learning_stats
+(
+    id              UUID                             COMMENT '主键ID，由报告ID和学生ID生成，重复生成时覆盖',
+    report_id       UUID                             COMMENT '课堂报告ID',
+    school_id       UInt64                           COMMENT '学校ID',
+    class_id        UInt64                           COMMENT '班级ID',
+    course_id       UInt64                           COMMENT '课程ID',
+    classroom_id    UInt64                           COMMENT '课堂ID',
+    student_id      UInt64                           COMMENT '学生ID',
+    learning_score  Int64    DEFAULT 0               COMMENT '学习分',
+    summary         String                           COMMENT '学情统计内容JSON，包含学习时长、答题、行为标签等',
+    report_time     DateTime                         COMMENT '统计时间，和报告的 update_time 一致，重新生成报告后只读取最新一次的记录'
+)
+ENGINE = ReplacingMergeTree(report_time)
+PARTITION BY toYYYYMM(report_time)
+ORDER BY (school_id, report_id, student_id)
+SETTINGS index_granularity = 8192;
 
--- -- 表注释：课堂统计报表表，存储课堂相关统计报告内容
--- COMMENT ON TABLE tbl_classroom_report IS '课堂统计报表表，用于存储课堂教学过程中生成的统计报告，如教学质量分析报告等';
--- -- 字段注释
--- COMMENT ON COLUMN tbl_classroom_report.id IS '报告记录唯一标识UUID';
--- COMMENT ON COLUMN tbl_classroom_report.school_id IS '学校ID';
--- COMMENT ON COLUMN tbl_classroom_report.class_id IS '班级ID';
--- COMMENT ON COLUMN tbl_classroom_report.classroom_id IS '课堂ID';
--- COMMENT ON COLUMN tbl_classroom_report.report_content IS '报告内容文本';
--- COMMENT ON COLUMN tbl_classroom_report.create_time IS '报告创建时间';
--- COMMENT ON COLUMN tbl_classroom_report.update_time IS '报告更新时间';
+-- =============================================
+-- 课堂报告表，课程表中的一节课结束后生成
+-- =============================================
+CREATE TABLE db_teacher.tbl_classroom_report
+(
+    id              UUID                             COMMENT '报告ID，由课堂ID和上课时间生成，重复生成时覆盖',
+    school_id       UInt64                           COMMENT '学校ID',
+    class_id        UInt64                           COMMENT '班级ID',
+    classroom_id    UInt64                           COMMENT '课堂ID',
+    teacher_id      UInt64                           COMMENT '教师ID',
+    course_id       UInt64                           COMMENT '课程ID',
+    start_time      DateTime                         COMMENT '上课时间',
+    end_time        DateTime                         COMMENT '下课时间',
+    report_content  String                           COMMENT '报告内容JSON，包含学情汇总、表扬关注、学习分和沟通统计',
+    create_time     DateTime                         COMMENT '创建时间',
+    update_time     DateTime                         COMMENT '更新时间'
+)
+ENGINE = ReplacingMergeTree(update_time)
+PARTITION BY toYYYYMM(start_time)
+ORDER BY (school_id, class_id, start_time, id)
+SETTINGS index_granularity = 8192;
 
 -- =============================================
 -- 沟通记录主表
@@ -116,3 +107,49 @@ ENGINE = ReplacingMergeTree
 ORDER BY (message_id, session_id, user_id, user_type)
 SETTINGS index_granularity = 8192;
 
+
+-- =============================================
+-- 沟通会话已读位置表
+-- =============================================
+CREATE TABLE db_teacher.tbl_communication_read_cursors
+(
+    id                    UUID                            COMMENT '由会话ID和用户生成，每个用户在每个会话只保留一条',
+    session_id            UUID                            COMMENT '会话ID',
+    user_id               UInt64                          COMMENT '用户ID',
+    user_type             Enum8('student' = 1, 'teacher' = 2, 'ai' = 3) COMMENT '用户类别',
+    last_read_message_id  UUID                            COMMENT '最后已读消息ID',
+    last_read_time        DateTime                        COMMENT '最后已读消息的发送时间',
+    update_time           DateTime                        COMMENT '更新时间'
+)
+ENGINE = ReplacingMergeTree(update_time)
+ORDER BY (user_id, user_type, session_id, id)
+SETTINGS index_granularity = 8192;
+
+
+-- =============================================
+-- 内容审核记录表
+-- =============================================
+CREATE TABLE db_teacher.tbl_moderation_audits
+(
+    id              UUID                            COMMENT '审核记录ID，自动审核由内容生成，重复审核时覆盖',
+    content_type    LowCardinality(String)          COMMENT '内容类型：communication_message/classroom_feedback',
+    content_id      String                          COMMENT '内容ID，消息ID或反馈ID',
+    parent_id       String  DEFAULT ''              COMMENT '内容所属对象ID，消息为会话ID，反馈为班级ID',
+    school_id       UInt64                          COMMENT '学校ID',
+    class_id        UInt64  DEFAULT 0               COMMENT '内容所属班级ID，无法确定时为0',
+    user_id         UInt64                          COMMENT '发布人ID',
+    user_type       LowCardinality(String)          COMMENT '发布人类别',
+    content         String                          COMMENT '原始内容',
+    masked_content  String  DEFAULT ''              COMMENT '屏蔽违规片段后的内容',
+    action          LowCardinality(String)          COMMENT '处理方式：pass/mask/review/reject',
+    category        LowCardinality(String)          COMMENT '违规类别',
+    severity        LowCardinality(String)          COMMENT '违规程度：none/low/medium/high',
+    reason          String  DEFAULT ''              COMMENT '审核原因',
+    source          LowCardinality(String)          COMMENT '结论来源：model/dictionary/teacher',
+    prompt_version  String  DEFAULT ''              COMMENT '模型审核使用的 Prompt 版本',
+    reviewer_id     UInt64  DEFAULT 0               COMMENT '审核教师ID，自动审核为0',
+    created_at      DateTime                        COMMENT '审核时间'
+)
+ENGINE = ReplacingMergeTree
+ORDER BY (school_id, content_type, content_id, id)
+SETTINGS index_granularity = 8192;
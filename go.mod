@@ -25,6 +25,7 @@ require (
 	github.com/spf13/cast v1.7.1
 	github.com/volcengine/volcengine-go-sdk v1.1.3
 	gitlab.xiaoluxue.cn/be-app/gil_dict_sdk v0.0.2
+	golang.org/x/sync v0.11.0
 	google.golang.org/protobuf v1.36.5
 	gorm.io/driver/mysql v1.5.7
 	gorm.io/driver/postgres v1.5.11
@@ -116,7 +117,6 @@ require (
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/mod v0.23.0 // indirect
 	golang.org/x/net v0.35.0
-	golang.org/x/sync v0.11.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250212204824-5a70512c5d8b // indirect
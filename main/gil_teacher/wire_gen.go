@@ -13,6 +13,7 @@ import (
 	"gil_teacher/app/controller/grpc_server/user"
 	"gil_teacher/app/controller/http"
 	behavior3 "gil_teacher/app/controller/http_server/behavior"
+	push2 "gil_teacher/app/controller/http_server/push"
 	resource_favorite2 "gil_teacher/app/controller/http_server/resource_favorite"
 	"gil_teacher/app/controller/http_server/route"
 	schedule2 "gil_teacher/app/controller/http_server/schedule"
@@ -25,10 +26,13 @@ import (
 	"gil_teacher/app/core/zipkinx"
 	"gil_teacher/app/dao"
 	"gil_teacher/app/dao/behavior"
+	"gil_teacher/app/dao/classroom"
 	"gil_teacher/app/dao/live_room/impl"
 	providers3 "gil_teacher/app/dao/providers"
 	"gil_teacher/app/dao/task"
 	behavior2 "gil_teacher/app/domain/behavior"
+	classroom2 "gil_teacher/app/domain/classroom"
+	"gil_teacher/app/domain/push"
 	"gil_teacher/app/domain/task"
 	"gil_teacher/app/middleware"
 	"gil_teacher/app/server"
@@ -39,6 +43,7 @@ import (
 	"gil_teacher/app/service/resource_favorite"
 	"gil_teacher/app/service/schedule"
 	"gil_teacher/app/service/task_service"
+	elasticsearch2 "gil_teacher/app/third_party/elasticsearch"
 	"gil_teacher/app/third_party/volc_ai"
 	providers2 "gil_teacher/app/utils/providers"
 	"github.com/elastic/go-elasticsearch/v8"
@@ -95,11 +100,15 @@ func wireApp(ctx context.Context, serverConf *conf.Server, cnf *conf.Conf, data
 		cleanup()
 		return nil, nil, err
 	}
-	client := question_service.NewClient(cnf, contextLogger, adminClient)
 	apiRdbClient := dao.NewApiRedisClient(cnf, contextLogger)
-	taskService := task_service.NewTaskService(contextLogger, taskDAO, taskAssignDAO, client, apiRdbClient)
+	client := question_service.NewClient(cnf, contextLogger, adminClient, apiRdbClient)
+	taskTierDAO := dao_task.NewTaskTierDao(db, contextLogger)
+	taskStudentsReportDao := dao_task.NewTaskStudentsReportDao(db, contextLogger)
+	taskService := task_service.NewTaskService(contextLogger, taskDAO, taskAssignDAO, taskTierDAO, taskStudentsReportDao, client, apiRdbClient)
 	taskResourceDAO := dao_task.NewTaskResourceDAO(db)
 	taskResourceService := task_service.NewTaskResourceService(contextLogger, taskResourceDAO)
+	taskStudentDetailsDao := dao_task.NewTaskStudentDetailsDao(db, contextLogger)
+	wrongQuestionService := task_service.NewWrongQuestionService(contextLogger, taskService, taskStudentDetailsDao)
 	ucenterClient, err := admin_service.NewUcenterClient(cnf, apiRdbClient, contextLogger)
 	if err != nil {
 		cleanup3()
@@ -109,15 +118,17 @@ func wireApp(ctx context.Context, serverConf *conf.Server, cnf *conf.Conf, data
 	}
 	teacherMiddleware := middleware.NewTeacherMiddleware(contextLogger, ucenterClient)
 	volc_aiClient := volc_ai.NewClient(cnf, contextLogger)
-	taskController := controller_task.NewTaskController(contextLogger, taskService, taskResourceService, client, ucenterClient, teacherMiddleware, volc_aiClient)
+	taskRecurrenceDAO := dao_task.NewTaskRecurrenceDao(db, contextLogger)
+	taskScheduleHandler := task.NewTaskScheduleHandler(taskDAO, taskRecurrenceDAO, contextLogger)
+	classErrorBookDAO := dao_task.NewClassErrorBookDao(db, contextLogger)
+	classErrorBookHandler := task.NewClassErrorBookHandler(classErrorBookDAO, taskDAO, taskService, client, contextLogger)
+	taskController := controller_task.NewTaskController(contextLogger, taskService, taskResourceService, wrongQuestionService, taskScheduleHandler, classErrorBookHandler, client, ucenterClient, teacherMiddleware, volc_aiClient)
 	teacherTempSelectionDAO := dao_task.NewTeacherTempSelectionDAO(db)
 	tempSelectionService := task_service.NewTempSelectionService(contextLogger, teacherTempSelectionDAO)
 	tempSelectionController := controller_task.NewTempSelectionController(contextLogger, tempSelectionService, teacherMiddleware)
 	taskStudentDAO := dao_task.NewTaskStudentDao(db, contextLogger)
 	taskAssignService := task_service.NewTaskAssignService(taskAssignDAO, taskStudentDAO, contextLogger)
 	taskReportDAO := dao_task.NewTaskReportDAO(db, contextLogger)
-	taskStudentsReportDao := dao_task.NewTaskStudentsReportDao(db, contextLogger)
-	taskStudentDetailsDao := dao_task.NewTaskStudentDetailsDao(db, contextLogger)
 	taskReportSettingDao := dao_task.NewTaskReportSettingDao(db, contextLogger)
 	taskReportService := task_service.NewTaskStatService(taskReportDAO, taskStudentsReportDao, taskStudentDetailsDao, taskReportSettingDao, contextLogger)
 	taskAnswerService := task_service.NewTaskAnswerService(taskStudentDetailsDao, contextLogger)
@@ -130,9 +141,12 @@ func wireApp(ctx context.Context, serverConf *conf.Server, cnf *conf.Conf, data
 	}
 	behaviorDAO := behavior.NewBehaviorDAO(v2, contextLogger)
 	taskReportHandler := task.NewTaskReportHandler(taskService, taskResourceService, taskAssignService, taskReportService, ucenterClient, client, apiRdbClient, taskAnswerService, behaviorDAO, contextLogger)
-	behaviorHandler := behavior2.NewBehaviorHandler(behaviorDAO, apiRdbClient, contextLogger)
-	kafkaProducerClient, cleanup5, err := kafka.NewKafkaProducerClient(ctx, data, contextLogger)
+	pushPublisher := push.NewPushPublisher(apiRdbClient, contextLogger)
+	behaviorRuleStore, cleanup5 := behavior2.NewBehaviorRuleStore(data, contextLogger)
+	behaviorHandler := behavior2.NewBehaviorHandler(behaviorDAO, apiRdbClient, pushPublisher, behaviorRuleStore, contextLogger)
+	kafkaProducerClient, cleanup6, err := kafka.NewKafkaProducerClient(ctx, data, contextLogger)
 	if err != nil {
+		cleanup5()
 		cleanup4()
 		cleanup3()
 		cleanup2()
@@ -140,19 +154,54 @@ func wireApp(ctx context.Context, serverConf *conf.Server, cnf *conf.Conf, data
 		return nil, nil, err
 	}
 	behaviorProducer := behavior2.NewBehaviorProducer(behaviorHandler, kafkaProducerClient, contextLogger)
-	taskReportController := controller_task.NewTaskReportController(taskReportHandler, teacherMiddleware, contextLogger, behaviorProducer)
+	taskExportJobDAO := dao_task.NewTaskExportJobDao(db, contextLogger)
+	taskExportJobHandler, cleanup7 := task.NewTaskExportJobHandler(taskReportHandler, taskExportJobDAO, taskDAO, taskAssignDAO, ossClient, contextLogger)
+	classErrorBookCollector := task.NewClassErrorBookCollector(taskDAO, taskAssignDAO, taskReportSettingDao, classErrorBookDAO, contextLogger)
+	taskReportAggregator := task.NewTaskReportAggregator(taskDAO, taskResourceDAO, taskStudentDAO, taskReportDAO, taskStudentsReportDao, taskStudentDetailsDao, classErrorBookCollector, behaviorRuleStore, contextLogger)
+	answerCardHandler := task.NewAnswerCardHandler(taskService, taskStudentDAO, taskReportAggregator, contextLogger)
+	taskReportController := controller_task.NewTaskReportController(taskReportHandler, taskExportJobHandler, answerCardHandler, classErrorBookHandler, teacherMiddleware, contextLogger, behaviorProducer)
 	teacherController := teacher.NewTeacherController(contextLogger, ucenterClient, teacherMiddleware)
 	resourceFavoriteDAO := providers3.ResourceFavoriteDAOProvider(db)
 	resourceFavoriteService := resource_favorite.NewResourceFavoriteService(resourceFavoriteDAO)
 	resourceFavoriteController := resource_favorite2.NewResourceFavoriteController(resourceFavoriteService, teacherMiddleware, contextLogger)
 	sessionMessageHandler := behavior2.NewSessionMessageHandler(behaviorDAO, apiRdbClient, contextLogger)
-	behaviorController := behavior3.NewBehaviorController(behaviorHandler, sessionMessageHandler, behaviorProducer, teacherMiddleware, contextLogger)
+	classroomReportDAO := behavior.NewClassroomReportDAO(v2, contextLogger)
+	classroomReportHandler, cleanup8 := behavior2.NewClassroomReportHandler(behaviorHandler, classroomReportDAO, apiRdbClient, contextLogger)
+	studentProfileDAO := behavior.NewStudentProfileDAO(v2, contextLogger)
+	studentProfileHandler := behavior2.NewStudentProfileHandler(studentProfileDAO, contextLogger)
+	classroomDAO := dao_classroom.NewClassroomDAO(db, contextLogger)
 	scheduleCacheService := schedule.NewScheduleCacheService(apiRdbClient, contextLogger, config)
+	classroomHandler := classroom2.NewClassroomHandler(classroomDAO, scheduleCacheService, kafkaProducerClient, apiRdbClient, contextLogger, config)
+	classroomFeedbackDAO := dao_classroom.NewClassroomFeedbackDAO(db, contextLogger)
+	classroomFeedbackHandler := classroom2.NewClassroomFeedbackHandler(classroomFeedbackDAO, contextLogger)
+	aiTutor := behavior2.NewAITutor(behaviorDAO, taskDAO, taskAssignDAO, client, volc_aiClient, pushPublisher, contextLogger)
+	moderationAuditDAO := behavior.NewModerationAuditDAO(v2, contextLogger)
+	elasticsearchClient, err := elasticsearch2.NewClient(cnf, contextLogger)
+	if err != nil {
+		cleanup8()
+		cleanup7()
+		cleanup6()
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	messageSearcher := behavior2.NewMessageSearcher(behaviorDAO, taskAssignDAO, classroomDAO, elasticsearchClient, contextLogger)
+	contentModerator := behavior2.NewContentModerator(behaviorDAO, moderationAuditDAO, classroomFeedbackDAO, classroomDAO, taskAssignDAO, volc_aiClient, messageSearcher, contextLogger)
+	behaviorController := behavior3.NewBehaviorController(behaviorHandler, sessionMessageHandler, classroomReportHandler, studentProfileHandler, classroomHandler, classroomFeedbackHandler, behaviorProducer, aiTutor, contentModerator, messageSearcher, teacherMiddleware, contextLogger)
 	scheduleController := schedule2.NewScheduleController(scheduleCacheService, contextLogger, teacherMiddleware)
-	httpRouter := route.NewHttpRouter(dbTestController, uploadController, taskController, tempSelectionController, taskReportController, teacherController, resourceFavoriteController, behaviorController, scheduleController, teacherMiddleware)
+	pushGateway, cleanup9 := push.NewPushGateway(pushPublisher, apiRdbClient, contextLogger)
+	pushController := push2.NewPushController(pushGateway, classroomHandler, teacherMiddleware, contextLogger)
+	httpRouter := route.NewHttpRouter(dbTestController, uploadController, taskController, tempSelectionController, taskReportController, teacherController, resourceFavoriteController, behaviorController, scheduleController, pushController, teacherMiddleware)
 	httpServer := server.NewGinHttpServer(cnf, contextLogger, httpRouter, middlewareMiddleware)
 	app := server.NewServer(cnf, grpcServer, httpServer, contextLogger)
 	return app, func() {
+		cleanup9()
+		cleanup8()
+		cleanup7()
+		cleanup6()
 		cleanup5()
 		cleanup4()
 		cleanup3()
@@ -7,11 +7,32 @@ import (
 	"gil_teacher/common"
 	"github.com/gin-gonic/gin"
 
+	"gil_teacher/app/core/kafka"
 	clogger "gil_teacher/app/core/logger"
 	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/domain/task"
 	// "github.com/segmentio/kafka-go"
 )
 
+// consumerApp 消费进程需要的全部处理器
+type consumerApp struct {
+	behaviorHandler      *behavior.BehaviorHandler
+	taskReportAggregator *task.TaskReportAggregator
+	aiTutor              *behavior.AITutor
+	contentModerator     *behavior.ContentModerator
+	messageSearcher      *behavior.MessageSearcher
+}
+
+func newConsumerApp(behaviorHandler *behavior.BehaviorHandler, taskReportAggregator *task.TaskReportAggregator, aiTutor *behavior.AITutor, contentModerator *behavior.ContentModerator, messageSearcher *behavior.MessageSearcher) *consumerApp {
+	return &consumerApp{
+		behaviorHandler:      behaviorHandler,
+		taskReportAggregator: taskReportAggregator,
+		aiTutor:              aiTutor,
+		contentModerator:     contentModerator,
+		messageSearcher:      messageSearcher,
+	}
+}
+
 // go build -ldflags "-X main.Version=x.y.z"
 var (
 	// Version is the version of the compiled software.
@@ -78,17 +99,53 @@ func main() {
 	healthServer := healthx.NewHealthServer(cmdParams.Env, cmdParams.ScriptHealthzPort)
 	healthServer.Run()
 
-	behaviorHandler, cleanup, err := wireApp(bc.Server, bc, bc.Data, bc.Config, logger_)
+	app, cleanup, err := wireApp(bc.Server, bc, bc.Data, bc.Config, logger_)
 	if err != nil {
 		panic(err)
 	}
 	defer cleanup()
 
+	ctx := context.Background()
+	contextLogger := clogger.NewContextLogger(logger_)
+
+	// 死信生产者，创建失败时处理失败的批次不提交 offset，等待重放
+	deadLetter, cleanupDeadLetter, err := kafka.NewKafkaProducerClient(ctx, bc.Data, contextLogger)
+	if err != nil {
+		contextLogger.Error(ctx, "创建死信生产者失败, error:%v", err)
+		deadLetter = nil
+	}
+	if cleanupDeadLetter != nil {
+		defer cleanupDeadLetter()
+	}
+
+	// 重放死信后退出：go run ./main/gil_teacher_consumer --replay_dlq topic-student-behaviors-dlq
+	if cmdParams.ReplayDeadLetter != "" {
+		replayed, err := kafka.ReplayDeadLetters(ctx, bc.Data.Kafka, cmdParams.ReplayDeadLetter)
+		contextLogger.Info(ctx, "死信重放结束, topic:%s, replayed:%d, error:%v", cmdParams.ReplayDeadLetter, replayed, err)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
 	go func() {
-		behaviorConsumer := behavior.NewBehaviorConsumer(bc.Data.Kafka, clogger.NewContextLogger(logger_))
-		behaviorConsumer.Consume(context.Background(), behaviorHandler)
+		behaviorConsumer := behavior.NewBehaviorConsumer(bc.Data.Kafka, deadLetter, contextLogger)
+		behaviorConsumer.Consume(ctx, app.behaviorHandler)
 	}()
 
+	// 学生作答事件聚合，生成任务报告
+	go app.taskReportAggregator.Consume(ctx, bc.Data.Kafka, deadLetter)
+
+	// AI 答疑，回答学生提问，回答写回沟通 topic
+	go app.aiTutor.Consume(ctx, bc.Data.Kafka, deadLetter)
+
+	// 内容审核，审核沟通消息和课堂反馈，屏蔽或暂停展示违规内容
+	go app.contentModerator.Consume(ctx, bc.Data.Kafka, deadLetter)
+	go app.contentModerator.SweepPendingFeedback(ctx)
+
+	// 会话消息检索，写入 Elasticsearch 索引
+	go app.messageSearcher.Consume(ctx, bc.Data.Kafka, deadLetter)
+
 	// 阻塞主线程，防止程序退出
 	select {}
 }
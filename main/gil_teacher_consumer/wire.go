@@ -14,7 +14,10 @@ import (
 	cLog "gil_teacher/app/core/logger"
 	daoProvider "gil_teacher/app/dao/providers"
 	"gil_teacher/app/domain"
-	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/service/gil_internal/admin_service"
+	"gil_teacher/app/service/gil_internal/question_service"
+	esClient "gil_teacher/app/third_party/elasticsearch"
+	"gil_teacher/app/third_party/volc_ai"
 )
 
 // 不需要 http rpc 等服务
@@ -25,12 +28,19 @@ func wireApp(
 	data *conf.Data,
 	config *conf.Config,
 	logger log.Logger,
-) (*behavior.BehaviorHandler, func(), error) {
+) (*consumerApp, func(), error) {
 	panic(wire.Build(
+		newConsumerApp,
 		cLog.ProviderSet,
 		// middlewareProvider.ServerProviderSet,
 		daoProvider.RepoProviderSet,
 		domain.DomainProviderSet,
+		// AI 答疑需要题库和大模型
+		admin_service.NewAdminClient,
+		question_service.NewClient,
+		volc_ai.NewClient,
+		// 会话消息检索索引
+		esClient.NewClient,
 		// serviceProvider.ServiceProviderSet,
 		// coreProvider.CoreProviderSet,
 	))
@@ -11,7 +11,16 @@ import (
 	"gil_teacher/app/core/logger"
 	"gil_teacher/app/dao"
 	behavior2 "gil_teacher/app/dao/behavior"
+	"gil_teacher/app/dao/classroom"
+	"gil_teacher/app/dao/providers"
+	"gil_teacher/app/dao/task"
 	"gil_teacher/app/domain/behavior"
+	"gil_teacher/app/domain/push"
+	task2 "gil_teacher/app/domain/task"
+	"gil_teacher/app/service/gil_internal/admin_service"
+	"gil_teacher/app/service/gil_internal/question_service"
+	elasticsearch2 "gil_teacher/app/third_party/elasticsearch"
+	"gil_teacher/app/third_party/volc_ai"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/go-kratos/kratos/v2/log"
 )
@@ -20,7 +29,7 @@ import (
 
 // 不需要 http rpc 等服务
 // wireApp init kratos application.
-func wireApp(serverConf *conf.Server, cnf *conf.Conf, data *conf.Data, config *conf.Config, logger2 log.Logger) (*behavior.BehaviorHandler, func(), error) {
+func wireApp(serverConf *conf.Server, cnf *conf.Conf, data *conf.Data, config *conf.Config, logger2 log.Logger) (*consumerApp, func(), error) {
 	contextLogger := logger.NewContextLogger(logger2)
 	v, cleanup, err := dao.NewClickHouseRWClient(data, contextLogger)
 	if err != nil {
@@ -28,8 +37,53 @@ func wireApp(serverConf *conf.Server, cnf *conf.Conf, data *conf.Data, config *c
 	}
 	behaviorDAO := behavior2.NewBehaviorDAO(v, contextLogger)
 	apiRdbClient := dao.NewApiRedisClient(cnf, contextLogger)
-	behaviorHandler := behavior.NewBehaviorHandler(behaviorDAO, apiRdbClient, contextLogger)
-	return behaviorHandler, func() {
+	pushPublisher := push.NewPushPublisher(apiRdbClient, contextLogger)
+	behaviorRuleStore, cleanup2 := behavior.NewBehaviorRuleStore(data, contextLogger)
+	behaviorHandler := behavior.NewBehaviorHandler(behaviorDAO, apiRdbClient, pushPublisher, behaviorRuleStore, contextLogger)
+	postgreSQLClient, cleanup3, err := dao.NewPostgreSQLClient(data, logger2)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	db := providers.ProvidePostgreSQLDB(postgreSQLClient)
+	taskResourceDAO := dao_task.NewTaskResourceDAO(db)
+	taskStudentDAO := dao_task.NewTaskStudentDao(db, contextLogger)
+	taskReportDAO := dao_task.NewTaskReportDAO(db, contextLogger)
+	taskStudentsReportDao := dao_task.NewTaskStudentsReportDao(db, contextLogger)
+	taskStudentDetailsDao := dao_task.NewTaskStudentDetailsDao(db, contextLogger)
+	taskDAO := dao_task.NewTaskDAO(db)
+	taskAssignDAO := dao_task.NewTaskAssignDAO(db, contextLogger)
+	taskReportSettingDao := dao_task.NewTaskReportSettingDao(db, contextLogger)
+	classErrorBookDAO := dao_task.NewClassErrorBookDao(db, contextLogger)
+	classErrorBookCollector := task2.NewClassErrorBookCollector(taskDAO, taskAssignDAO, taskReportSettingDao, classErrorBookDAO, contextLogger)
+	taskReportAggregator := task2.NewTaskReportAggregator(taskDAO, taskResourceDAO, taskStudentDAO, taskReportDAO, taskStudentsReportDao, taskStudentDetailsDao, classErrorBookCollector, behaviorRuleStore, contextLogger)
+	adminClient, err := admin_service.NewAdminClient(cnf, contextLogger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	client := question_service.NewClient(cnf, contextLogger, adminClient, apiRdbClient)
+	volc_aiClient := volc_ai.NewClient(cnf, contextLogger)
+	aiTutor := behavior.NewAITutor(behaviorDAO, taskDAO, taskAssignDAO, client, volc_aiClient, pushPublisher, contextLogger)
+	moderationAuditDAO := behavior2.NewModerationAuditDAO(v, contextLogger)
+	classroomFeedbackDAO := dao_classroom.NewClassroomFeedbackDAO(db, contextLogger)
+	classroomDAO := dao_classroom.NewClassroomDAO(db, contextLogger)
+	client2, err := elasticsearch2.NewClient(cnf, contextLogger)
+	if err != nil {
+		cleanup3()
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	messageSearcher := behavior.NewMessageSearcher(behaviorDAO, taskAssignDAO, classroomDAO, client2, contextLogger)
+	contentModerator := behavior.NewContentModerator(behaviorDAO, moderationAuditDAO, classroomFeedbackDAO, classroomDAO, taskAssignDAO, volc_aiClient, messageSearcher, contextLogger)
+	mainConsumerApp := newConsumerApp(behaviorHandler, taskReportAggregator, aiTutor, contentModerator, messageSearcher)
+	return mainConsumerApp, func() {
+		cleanup3()
+		cleanup2()
 		cleanup()
 	}, nil
 }
@@ -5,10 +5,19 @@ import (
 	"fmt"
 	"log"
 
+	kratoslog "github.com/go-kratos/kratos/v2/log"
 	"github.com/xxl-job/xxl-job-executor-go"
 
+	"gil_teacher/app/conf"
+	clogger "gil_teacher/app/core/logger"
+	"gil_teacher/app/dao"
+	"gil_teacher/app/dao/providers"
+	dao_task "gil_teacher/app/dao/task"
+	"gil_teacher/app/domain/task"
+	baseCommon "gil_teacher/common"
 	"gil_teacher/script/demoTask1"
 	"gil_teacher/script/demoTask2"
+	"gil_teacher/script/taskSchedule"
 )
 
 const (
@@ -19,6 +28,23 @@ const (
 )
 
 func main() {
+	// log.Fatal 会直接退出进程，放在 run 返回之后，保证 run 中注册的清理函数先执行
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// 初始化并启动执行器，执行器退出后释放数据库连接
+func run() error {
+	// 初始化配置、日志和数据库，供需要访问业务数据的任务使用
+	bc, logger_, _ := baseCommon.InitBase(false)
+	scheduleHandler, cleanup, err := newTaskScheduleHandler(bc, logger_)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	taskSchedule.Init(scheduleHandler)
+
 	// 动态获取xxl-job-admin地址
 	exec := xxl.NewExecutor(
 		xxl.ServerAddr(XXL_ADMIN),
@@ -34,7 +60,20 @@ func main() {
 	//注册任务handler
 	exec.RegTask(demoTask1.Pattern, demoTask1.Task1)
 	exec.RegTask(demoTask2.Pattern, demoTask2.Task1)
-	log.Fatal(exec.Run())
+	exec.RegTask(taskSchedule.Pattern, taskSchedule.Run)
+	return exec.Run()
+}
+
+// 创建定时发布和周期布置处理器
+func newTaskScheduleHandler(bc *conf.Conf, logger_ kratoslog.Logger) (*task.TaskScheduleHandler, func(), error) {
+	contextLogger := clogger.NewContextLogger(logger_)
+	postgreSQLClient, cleanup, err := dao.NewPostgreSQLClient(bc.Data, logger_)
+	if err != nil {
+		return nil, nil, err
+	}
+	db := providers.ProvidePostgreSQLDB(postgreSQLClient)
+	handler := task.NewTaskScheduleHandler(dao_task.NewTaskDAO(db), dao_task.NewTaskRecurrenceDao(db, contextLogger), contextLogger)
+	return handler, cleanup, nil
 }
 
 // 自定义日志处理器
@@ -0,0 +1,35 @@
+package taskSchedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xxl-job/xxl-job-executor-go"
+
+	"gil_teacher/app/domain/task"
+	"gil_teacher/script/common"
+)
+
+const (
+	Pattern = "taskSchedule"
+)
+
+var handler *task.TaskScheduleHandler
+
+// Init 设置定时任务使用的处理器，需要在注册任务前调用
+func Init(h *task.TaskScheduleHandler) {
+	handler = h
+}
+
+// Run 发布到期的定时任务，并按周期布置规则生成到期的任务布置，建议每分钟执行一次
+func Run(ctx context.Context, param *xxl.RunReq) string {
+	result, err := handler.RunDue(ctx, time.Now().Unix())
+	if err != nil {
+		common.SendFeishuWebhook(fmt.Sprintf("taskSchedule failed: %v", err))
+		// 执行器只在 panic 时向调度中心回调失败
+		panic(err)
+	}
+	return fmt.Sprintf("published: %d, assigns: %d, skipped: %d, ended: %d, failed: %d",
+		result.PublishedTaskNum, result.AssignNum, result.SkippedNum, result.EndedRuleNum, result.FailedRuleNum)
+}